@@ -0,0 +1,139 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CyndiConfigSpec defines defaults applied to CyndiPipelines
+type CyndiConfigSpec struct {
+	PipelineDefaults `json:",inline"`
+
+	// Defaults applied to pipelines in the given namespaces. Take precedence over the cluster-wide defaults
+	// +optional
+	Namespaces []NamespaceDefaults `json:"namespaces,omitempty"`
+}
+
+// NamespaceDefaults defines defaults applied to CyndiPipelines in a namespace
+type NamespaceDefaults struct {
+	// +kubebuilder:validation:MinLength:=1
+	Namespace string `json:"namespace"`
+
+	PipelineDefaults `json:",inline"`
+}
+
+// PipelineDefaults are typed counterparts of keys of the cyndi ConfigMap
+type PipelineDefaults struct {
+	// Kafka topic hosts are consumed from (connector.topic)
+	// +optional
+	// +kubebuilder:validation:MinLength:=1
+	Topic *string `json:"topic,omitempty"`
+
+	// Kafka Connect cluster connectors are created in (connect.cluster)
+	// +optional
+	// +kubebuilder:validation:MinLength:=1
+	ConnectCluster *string `json:"connectCluster,omitempty"`
+
+	// Secret with credentials of the inventory database (inventory.dbSecret)
+	// +optional
+	// +kubebuilder:validation:MinLength:=1
+	InventoryDbSecret *string `json:"inventoryDbSecret,omitempty"`
+
+	// Template of the connector configuration (connector.config)
+	// +optional
+	// +kubebuilder:validation:MinLength:=1
+	ConnectorTemplate *string `json:"connectorTemplate,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	ConnectorTasksMax *int64 `json:"connectorTasksMax,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	ConnectorBatchSize *int64 `json:"connectorBatchSize,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	MaxAge *int64 `json:"maxAge,omitempty"`
+
+	// Template of the table definition (db.schema)
+	// +optional
+	// +kubebuilder:validation:MinLength:=1
+	DBSchema *string `json:"dbSchema,omitempty"`
+
+	// How often (in seconds) pipelines are reconciled (standard.interval)
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	StandardInterval *int64 `json:"standardInterval,omitempty"`
+
+	// Validation of valid pipelines
+	// +optional
+	Validation *ValidationDefaults `json:"validation,omitempty"`
+
+	// Validation during the initial sync
+	// +optional
+	InitValidation *ValidationDefaults `json:"initValidation,omitempty"`
+}
+
+// ValidationDefaults defines how pipelines are validated
+type ValidationDefaults struct {
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	Interval *int64 `json:"interval,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	AttemptsThreshold *int64 `json:"attemptsThreshold,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=100
+	PercentageThreshold *int64 `json:"percentageThreshold,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	CountThreshold *int64 `json:"countThreshold,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Enum:=all;any
+	ThresholdMode *string `json:"thresholdMode,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// CyndiConfig is the Schema for the cyndiconfigs API
+type CyndiConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CyndiConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CyndiConfigList contains a list of CyndiConfig
+type CyndiConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CyndiConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CyndiConfig{}, &CyndiConfigList{})
+}
@@ -36,6 +36,12 @@ type CyndiPipelineSpec struct {
 	// +kubebuilder:default:={}
 	AdditionalFilters []map[string]string `json:"additionalFilters,omitempty"`
 
+	// Take over the table backing the "inventory.hosts" view and its connector instead of starting with a new table
+	// e.g. when the pipeline is re-created after a disaster
+	// +optional
+	AdoptExisting bool `json:"adoptExisting,omitempty"`
+
+	// Kafka Connect cluster the connector is created in. Use namespace/name for a cluster in a different namespace
 	// +optional
 	// +kubebuilder:validation:MinLength:=1
 	ConnectCluster *string `json:"connectCluster,omitempty"`
@@ -49,10 +55,57 @@ type CyndiPipelineSpec struct {
 	// +kubebuilder:validation:Max:=100
 	ValidationThreshold *int64 `json:"validationThreshold,omitempty"`
 
+	// Maximum number of mismatched hosts for the pipeline to be considered valid
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	ValidationCountThreshold *int64 `json:"validationCountThreshold,omitempty"`
+
+	// How the percentage and count thresholds are combined when both are set
+	// +optional
+	// +kubebuilder:validation:Enum:=all;any
+	ValidationThresholdMode *string `json:"validationThresholdMode,omitempty"`
+
+	// How the hosts of HBI and of the application table are compared. Overrides validation.strategy of the configmap
+	// +optional
+	// +kubebuilder:validation:Enum:=ids;blockhash;counts;sampled
+	ValidationStrategy *string `json:"validationStrategy,omitempty"`
+
+	// How often (in seconds) the pipeline is validated once it has become valid
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	ValidationInterval *int64 `json:"validationInterval,omitempty"`
+
+	// How often (in seconds) the pipeline is validated during the initial sync
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	InitValidationInterval *int64 `json:"initValidationInterval,omitempty"`
+
+	// How long (in seconds) the initial sync may take before the pipeline is marked with the SyncDeadlineExceeded condition
+	// and init.timeout.action is applied. Overrides init.timeout, 0 disables the deadline
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	InitialSyncTimeout *int64 `json:"initialSyncTimeout,omitempty"`
+
 	// +optional
 	// +kubebuilder:validation:MinLength:=1
 	Topic *string `json:"topic,omitempty"`
 
+	// Topics hosts are consumed from, e.g. if host events are sharded by org across several topics. Takes precedence over topic
+	// +optional
+	// +kubebuilder:validation:MinItems:=1
+	Topics []TopicSource `json:"topics,omitempty"`
+
+	// Template of the consumer group of the connector (e.g. "cyndi-{{.Namespace}}-{{.PipelineVersion}}"). Overrides connector.consumer.group.template from the cyndi ConfigMap
+	// +optional
+	// +kubebuilder:validation:MinLength:=1
+	ConsumerGroupTemplate *string `json:"consumerGroupTemplate,omitempty"`
+
+	// Where the connector starts consuming if its consumer group has no committed offset or the committed offset is no longer retained.
+	// Overrides connector.auto.offset.reset from the cyndi ConfigMap
+	// +optional
+	// +kubebuilder:validation:Enum:=earliest;latest;none
+	AutoOffsetReset *string `json:"autoOffsetReset,omitempty"`
+
 	// +optional
 	// +kubebuilder:validation:MinLength:=1
 	DbSecret *string `json:"dbSecret,omitempty"`
@@ -61,13 +114,287 @@ type CyndiPipelineSpec struct {
 	// +kubebuilder:validation:MinLength:=1
 	InventoryDbSecret *string `json:"inventoryDbSecret,omitempty"`
 
+	// Secrets of the inventory databases if HBI is sharded across several databases. Takes precedence over inventoryDbSecret
+	// +optional
+	// +kubebuilder:validation:MinItems:=1
+	InventoryDbSecrets []string `json:"inventoryDbSecrets,omitempty"`
+
 	// +optional
 	// +kubebuilder:validation:MinLength:=0
 	DBTableIndexSQL string `json:"dbTableIndexSQL,omitempty"`
 
+	// Creates the table as a partitioned table
+	// +optional
+	DBTablePartitioning *TablePartitioning `json:"dbTablePartitioning,omitempty"`
+
+	// Storage parameters (e.g. fillfactor, autovacuum_vacuum_scale_factor) of the table
+	// Merged with (and taking precedence over) db.table.storage.parameters from the cyndi ConfigMap
+	// +optional
+	DBTableStorageParameters map[string]string `json:"dbTableStorageParameters,omitempty"`
+
+	// Compression method of the jsonb columns of the table (requires PostgreSQL 14)
+	// +optional
+	// +kubebuilder:validation:Enum:=pglz;lz4
+	DBTableCompression *string `json:"dbTableCompression,omitempty"`
+
+	// SQL dialect of the application database. Overrides db.dialect from the cyndi ConfigMap
+	// +optional
+	// +kubebuilder:validation:Enum:=postgres;cockroachdb
+	DBDialect *string `json:"dbDialect,omitempty"`
+
+	// How the operator and the sink connector authenticate with the application database. Defaults to the password of the database secret
+	// +optional
+	DBAuth *DBAuth `json:"dbAuth,omitempty"`
+
+	// Roles of the application database granted read access to the inventory.hosts view and the tables backing it
+	// Grants are applied to each new table so that they survive a refresh
+	// +optional
+	DBGrants *DBGrants `json:"dbGrants,omitempty"`
+
+	// Column of the inventory.hosts view exposing the staleness of each host (fresh, stale or culled) computed from stale_timestamp
+	// Changes are applied to the view without a refresh
+	// +optional
+	ViewStaleness *ViewStaleness `json:"viewStaleness,omitempty"`
+
+	// Template of the connector configuration. Overrides connector.config from the cyndi ConfigMap
+	// +optional
+	ConnectorTemplate *ConnectorTemplate `json:"connectorTemplate,omitempty"`
+
+	// Labels added to the connectors of the pipeline (e.g. to route alerts). Keys reserved by the operator and Strimzi cannot be used
+	// +optional
+	ConnectorLabels map[string]string `json:"connectorLabels,omitempty"`
+
+	// Annotations added to the connectors of the pipeline. Keys reserved by the operator and Strimzi cannot be used
+	// +optional
+	ConnectorAnnotations map[string]string `json:"connectorAnnotations,omitempty"`
+
+	// How host deletions are consumed and how rows are keyed. Overrides connector.delete.enabled, connector.tombstones,
+	// connector.pk.mode and connector.pk.fields from the cyndi ConfigMap
+	// +optional
+	Deletes *DeleteHandling `json:"deletes,omitempty"`
+
+	// Makes the operator manage a Debezium source connector publishing changes of the HBI database to the topic of the pipeline
+	// Each pipeline version gets a source connector (and replication slot) of its own, created and removed along with its sink connector
+	// +optional
+	ManageSourceConnector *SourceConnector `json:"manageSourceConnector,omitempty"`
+
 	// +optional
 	// +kubebuilder:validation:MinLength:=0
 	Refresh string `json:"refresh,omitempty"`
+
+	// Requests a refresh of the pipeline. A single refresh is started whenever it is set to a time later than status.lastResyncAt
+	// +optional
+	ResyncRequestedAt *metav1.Time `json:"resyncRequestedAt,omitempty"`
+
+	// Periods of time during which failed validations are not counted towards the refresh threshold
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// Periods of time (e.g. HBI low-traffic hours) during which host ids are compared. Only host counts are compared outside of them
+	// Ignored during the initial sync
+	// +optional
+	ValidationWindows []MaintenanceWindow `json:"validationWindows,omitempty"`
+
+	// Pipelines that need to be valid before the inventory.hosts view of this pipeline is switched to a new table
+	// +optional
+	DependsOn []PipelineReference `json:"dependsOn,omitempty"`
+
+	// Makes the pipeline a clone of another pipeline of the same namespace, e.g. to test a new connector template or column set against production data
+	// The clone replicates the same topics into tables of its own in the application database of the source pipeline and exposes them using a view other than inventory.hosts
+	// +optional
+	SourcePipeline *SourcePipeline `json:"sourcePipeline,omitempty"`
+}
+
+// PipelineReference identifies a CyndiPipeline, possibly in another namespace
+type PipelineReference struct {
+	// +kubebuilder:validation:MinLength:=1
+	Name string `json:"name"`
+
+	// Defaults to the namespace of the referencing pipeline
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// SourcePipeline identifies the pipeline a clone is made of. Settings of the application database, topics, Kafka Connect cluster
+// and HBI databases (dbSecret, dbDialect, dbAuth, topic, topics, connectCluster, inventoryDbSecret, inventoryDbSecrets) not set by the clone are inherited from it
+type SourcePipeline struct {
+	// Name of a pipeline in the namespace of the clone. It cannot be a clone itself
+	// +kubebuilder:validation:MinLength:=1
+	Name string `json:"name"`
+
+	// View of the inventory schema exposing the hosts of the clone. Defaults to <name of the clone>_hosts
+	// +optional
+	// +kubebuilder:validation:MaxLength:=63
+	// +kubebuilder:validation:Pattern:=`^[a-z_][a-z0-9_]*$`
+	ViewName *string `json:"viewName,omitempty"`
+}
+
+// TopicSource defines a topic hosts are consumed from
+type TopicSource struct {
+	// +kubebuilder:validation:MinLength:=1
+	Name string `json:"name"`
+
+	// SQL condition selecting the HBI hosts whose events are published to the topic. Used by validation
+	// Either all or none of the topics of a pipeline need to define it
+	// +optional
+	Where string `json:"where,omitempty"`
+}
+
+// MaintenanceWindow defines a recurring period of time
+type MaintenanceWindow struct {
+	// Cron expression (UTC) defining when the window starts
+	// +kubebuilder:validation:MinLength:=1
+	Schedule string `json:"schedule"`
+
+	// How long (in seconds) the window lasts
+	// +kubebuilder:validation:Minimum:=1
+	Duration int64 `json:"duration"`
+}
+
+// ConnectorTemplate defines the connector configuration template either inline or as a reference to a ConfigMap
+type ConnectorTemplate struct {
+	// +optional
+	// +kubebuilder:validation:MinLength:=1
+	Inline string `json:"inline,omitempty"`
+
+	// ConfigMap (in the namespace of the pipeline) holding the template
+	// +optional
+	ConfigMapRef *ConfigMapKeyReference `json:"configMapRef,omitempty"`
+}
+
+// ConfigMapKeyReference selects a key of a ConfigMap
+type ConfigMapKeyReference struct {
+	// +kubebuilder:validation:MinLength:=1
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:MinLength:=1
+	Key string `json:"key"`
+}
+
+// DBAuth selects an authentication plugin for managed PostgreSQL offerings that forbid password authentication
+type DBAuth struct {
+	// password (the password of the database secret), azure-ad (an Azure AD access token of the managed identity of the operator and the Connect cluster)
+	// or gcp-iam (Cloud SQL IAM database authentication using the service account of the operator and the Connect cluster)
+	// The user of the database secret needs to be the database user mapped to the identity
+	// +kubebuilder:validation:Enum:=password;azure-ad;gcp-iam
+	Type string `json:"type"`
+
+	// Client id of the user-assigned managed identity to authenticate as (azure-ad). The system-assigned identity is used if not set
+	// +optional
+	ClientID *string `json:"clientId,omitempty"`
+
+	// Connection name (project:region:instance) of the Cloud SQL instance (gcp-iam), required by the connector
+	// +optional
+	// +kubebuilder:validation:Pattern:=`^[^:]+:[^:]+:[^:]+$`
+	CloudSQLInstance *string `json:"cloudSqlInstance,omitempty"`
+}
+
+// DBGrants defines the roles granted SELECT on the inventory.hosts view and the tables backing it
+type DBGrants struct {
+	// Existing roles to grant SELECT to
+	// +optional
+	Roles []DBRole `json:"roles,omitempty"`
+
+	// Role (NOLOGIN) created if it does not exist and granted SELECT the same way. The application can grant it to its users
+	// +optional
+	ReadOnlyRole *DBRole `json:"readOnlyRole,omitempty"`
+}
+
+// +kubebuilder:validation:Pattern:=`^[a-z_][a-z0-9_]*$`
+// +kubebuilder:validation:MaxLength:=63
+type DBRole string
+
+// ViewStaleness defines the computed staleness column of the inventory.hosts view
+// A host is fresh until stale_timestamp + staleAfter, stale until stale_timestamp + culledAfter and culled afterwards
+type ViewStaleness struct {
+	// Name of the column. Defaults to staleness
+	// +optional
+	// +kubebuilder:validation:Pattern:=`^[a-z_][a-z0-9_]*$`
+	// +kubebuilder:validation:MaxLength:=63
+	Column *string `json:"column,omitempty"`
+
+	// Seconds after stale_timestamp a host becomes stale. Defaults to 0
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	StaleAfter *int64 `json:"staleAfter,omitempty"`
+
+	// Seconds after stale_timestamp a host becomes culled. Defaults to 1209600 (14 days, matching culled_timestamp)
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	CulledAfter *int64 `json:"culledAfter,omitempty"`
+}
+
+// SourceConnector defines the Debezium source connector capturing changes of the HBI database
+type SourceConnector struct {
+	// Prefix of the names of the replication slots and publications, followed by the pipeline version. Defaults to cyndi_<appName>
+	// +optional
+	// +kubebuilder:validation:Pattern:=`^[a-z_][a-z0-9_]*$`
+	// +kubebuilder:validation:MaxLength:=40
+	SlotNamePrefix *string `json:"slotNamePrefix,omitempty"`
+
+	// Tables of the HBI database whose changes are captured (table.include.list). Defaults to public.hosts
+	// +optional
+	TableIncludeList []string `json:"tableIncludeList,omitempty"`
+
+	// Makes the source connector periodically write to a heartbeat table of the HBI database (heartbeat.action.query)
+	// This keeps the replication slot advancing while the captured tables do not change, so the HBI database does not retain WAL for it
+	// +optional
+	Heartbeat *SourceHeartbeat `json:"heartbeat,omitempty"`
+}
+
+// SourceHeartbeat defines the heartbeat of a source connector
+type SourceHeartbeat struct {
+	// How often (in milliseconds) the heartbeat is written (heartbeat.interval.ms). Defaults to 60000
+	// +optional
+	// +kubebuilder:validation:Minimum:=1000
+	IntervalMs *int64 `json:"intervalMs,omitempty"`
+
+	// Schema-qualified table of the HBI database the heartbeat is written to. Created by the operator unless it exists. Defaults to public.cyndi_heartbeat
+	// +optional
+	// +kubebuilder:validation:Pattern:=`^[a-z_][a-z0-9_]*\.[a-z_][a-z0-9_]*$`
+	Table *string `json:"table,omitempty"`
+}
+
+// DeleteHandling defines how host deletions are propagated to the table
+type DeleteHandling struct {
+	// Whether tombstones delete rows of the table (delete.enabled). Requires pkMode record_key
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Whether delete events are converted into tombstones (convert) or the topic already carries tombstones, e.g. a compacted topic (native)
+	// +optional
+	// +kubebuilder:validation:Enum:=convert;native
+	Tombstones *string `json:"tombstones,omitempty"`
+
+	// Where the primary key of a row is taken from (pk.mode)
+	// +optional
+	// +kubebuilder:validation:Enum:=record_key;record_value
+	PKMode *string `json:"pkMode,omitempty"`
+
+	// Columns of the primary key (pk.fields). Need to match the primary key of the table
+	// +optional
+	// +kubebuilder:validation:MinItems:=1
+	PKFields []string `json:"pkFields,omitempty"`
+}
+
+type PartitioningStrategy string
+
+const (
+	// rows are distributed among the partitions based on the hash of the host id
+	PartitioningHash PartitioningStrategy = "hash"
+)
+
+// TablePartitioning defines how the table is split into partitions
+type TablePartitioning struct {
+	// +optional
+	// +kubebuilder:default:=hash
+	// +kubebuilder:validation:Enum:=hash
+	Strategy PartitioningStrategy `json:"strategy,omitempty"`
+
+	// Number of partitions
+	// +kubebuilder:validation:Minimum:=2
+	// +kubebuilder:validation:Maximum:=1024
+	Partitions int64 `json:"partitions"`
 }
 
 // CyndiPipelineStatus defines the observed state of CyndiPipeline
@@ -80,20 +407,409 @@ type CyndiPipelineStatus struct {
 	ConnectorName   string `json:"cyndiPipelineName"`
 	TableName       string `json:"tableName"`
 
+	// Number of pipeline versions (initial syncs) started for the pipeline, i.e. incremented on every refresh.
+	// Can be rendered into the names of tables and connectors (naming.template)
+	// +optional
+	SyncGeneration int64 `json:"syncGeneration,omitempty"`
+
 	CyndiConfigVersion string `json:"cyndiConfigVersion"`
 
 	// +optional
 	SpecHash string `json:"specHash"`
 
+	// Configuration resolved from the CyndiConfig, the cyndi ConfigMaps and the spec, as used by the last reconcile
+	// +optional
+	ActiveConfig *ActiveConfig `json:"activeConfig,omitempty"`
+
 	InitialSyncInProgress bool `json:"initialSyncInProgress"`
 
 	// Name of the database table that is currently backing the "inventory.hosts" view
 	// May differ from TableName e.g. during a refresh
 	ActiveTableName string `json:"activeTableName"`
 
+	// Table that backed the "inventory.hosts" view before the last cutover
+	// Kept (with its connector) for db.table.retention seconds so that the view can be rolled back to it. Empty once dropped
+	// +optional
+	PreviousTableName string `json:"previousTableName,omitempty"`
+
+	// The last time the "inventory.hosts" view was pointed to a different table
+	// +optional
+	LastCutoverTime *metav1.Time `json:"lastCutoverTime,omitempty"`
+
+	// Why the pipeline was last refreshed (i.e. a new pipeline version was started)
+	// +optional
+	LastRefreshReason string `json:"lastRefreshReason,omitempty"`
+
+	// The spec.resyncRequestedAt fulfilled by the last refresh
+	// +optional
+	LastResyncAt *metav1.Time `json:"lastResyncAt,omitempty"`
+
+	// The generation of the pipeline the status was last determined for (see ObservedGeneration of the conditions)
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	Conditions []metav1.Condition `json:"conditions"`
 
 	HostCount int64 `json:"hostCount"`
+
+	// Differences between the host counts of the application table and HBI (application - HBI) determined by recent validations, oldest first
+	// Recorded if validation.anomaly.enabled is set
+	// +optional
+	HostCountDeltas []int64 `json:"hostCountDeltas,omitempty"`
+
+	// Number of hosts found in the application table only (i.e. deleted in HBI) during the last validation comparing host ids
+	// +optional
+	LingeringHostCount int64 `json:"lingeringHostCount,omitempty"`
+
+	// Number of mismatched hosts of each category and a sample of their ids (validation.mismatched.ids.sample) found by the last validation comparing host ids
+	// +optional
+	MismatchedHosts *MismatchedHostsStatus `json:"mismatchedHosts,omitempty"`
+
+	// Host counts of the organizations with the most hosts compared during the last validation (if enabled by validation.org.counts.top)
+	// +optional
+	OrgCounts *OrgCountsStatus `json:"orgCounts,omitempty"`
+
+	// Host counts per reporter determined by the last validation (if enabled by validation.reporter.counts.enabled)
+	// +optional
+	ReporterCounts []ReporterHostCount `json:"reporterCounts,omitempty"`
+
+	// Host count of the HBI API (served by xjoin) compared during the last validation (if enabled by validation.xjoin.enabled)
+	// +optional
+	XjoinCrossCheck *XjoinCrossCheckStatus `json:"xjoinCrossCheck,omitempty"`
+
+	// Validation checks skipped during the last validation by cyndi.cloud.redhat.com/skip-<check>-validation annotations
+	// +optional
+	SkippedValidationChecks []string `json:"skippedValidationChecks,omitempty"`
+
+	// Consecutive successful validations of the table of a refresh while the view still points to the previous table (see validation.shadow.cycles)
+	// +optional
+	ShadowValidations int64 `json:"shadowValidations,omitempty"`
+
+	// The time the current initial sync started
+	// +optional
+	InitialSyncStarted *metav1.Time `json:"initialSyncStarted,omitempty"`
+
+	// The last time the host count of the table being seeded was seen growing during initial sync
+	// +optional
+	InitialSyncLastProgress *metav1.Time `json:"initialSyncLastProgress,omitempty"`
+
+	// Throughput of the initial sync estimated from the growth of the host count of the table between validations
+	// +optional
+	InitialSyncThroughput *ThroughputStatus `json:"initialSyncThroughput,omitempty"`
+
+	// Name of the ConfigMap holding ids of hosts that did not match during the last validation
+	// +optional
+	ValidationDiffConfigMap string `json:"validationDiffConfigMap,omitempty"`
+
+	// Number of reconcile attempts that failed in a row
+	// +optional
+	ConsecutiveFailures int64 `json:"consecutiveFailures,omitempty"`
+
+	// Reconciliation of a repeatedly failing pipeline is suspended until this time
+	// +optional
+	ThrottledUntil *metav1.Time `json:"throttledUntil,omitempty"`
+
+	// Indexes of the table being seeded are created once the initial load completes
+	// +optional
+	TableIndexesPending bool `json:"tableIndexesPending,omitempty"`
+
+	// Start time of the application database server when the (UNLOGGED) table being seeded was created
+	// Set only until the table is converted to LOGGED
+	// +optional
+	UnloggedTableServerStart *metav1.Time `json:"unloggedTableServerStart,omitempty"`
+
+	// Table of a new pipeline version recorded before the table is created and cleared once the pipeline version is registered
+	// A table left behind by an interrupted reconciliation (e.g. a crash of the operator) is adopted by the next attempt rather than orphaned
+	// +optional
+	PendingTableName string `json:"pendingTableName,omitempty"`
+
+	// The pipeline version is registered but its connector is yet to be created
+	// A missing connector is then created by the next attempt rather than reported as having disappeared
+	// +optional
+	ConnectorPending bool `json:"connectorPending,omitempty"`
+
+	// Version of the operator that created the connector of the pipeline version
+	// +optional
+	OperatorVersion string `json:"operatorVersion,omitempty"`
+
+	// Version (hash) of the connector template the connector of the pipeline version was rendered from
+	// +optional
+	ConnectorTemplateVersion string `json:"connectorTemplateVersion,omitempty"`
+
+	// Table whose rows are copied to the table of the next pipeline version
+	// Set when a refresh of a valid pipeline is initiated
+	// +optional
+	CloneSourceTable string `json:"cloneSourceTable,omitempty"`
+
+	// The table being seeded was cloned from the previously active table
+	// The connector skips events older than this time
+	// +optional
+	ClonedEventsSince *metav1.Time `json:"clonedEventsSince,omitempty"`
+
+	// Consumer group of the connector if it resumed from the offsets of the previous connector, was rendered from the consumer group template
+	// or was replaced by an offset reset. Empty if the connector uses the default consumer group of Kafka Connect
+	// +optional
+	ConsumerGroup string `json:"consumerGroup,omitempty"`
+
+	// The connector of the active table was stopped at this time so that its consumer group can be reused by the next pipeline version
+	// +optional
+	ConsumerGroupHandoverStarted *metav1.Time `json:"consumerGroupHandoverStarted,omitempty"`
+
+	// The last offset reset requested by the reset-offsets annotation
+	// +optional
+	OffsetReset *OffsetResetStatus `json:"offsetReset,omitempty"`
+
+	// Number of messages the connector had yet to consume during the last validation
+	// Set only if consumer group lag is monitored (validation.lag.prometheus.url)
+	// +optional
+	ConsumerLag *int64 `json:"consumerLag,omitempty"`
+
+	// The smoke test run after the table became active (if enabled by smoketest.enabled)
+	// +optional
+	SmokeTest *SmokeTestStatus `json:"smokeTest,omitempty"`
+
+	// The synthetic host used to measure replication latency (if enabled by canary.enabled)
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+
+	// Hash of the dbGrants last applied to the inventory.hosts view and the tables backing it
+	// +optional
+	DBGrantsHash string `json:"dbGrantsHash,omitempty"`
+
+	// Hash of the viewStaleness last applied to the inventory.hosts view
+	// +optional
+	ViewStalenessHash string `json:"viewStalenessHash,omitempty"`
+
+	// Replication slots created in the HBI database for source connectors of the pipeline (see manageSourceConnector) and not dropped yet
+	// +optional
+	SourceReplicationSlots []string `json:"sourceReplicationSlots,omitempty"`
+
+	// The topics of the pipeline as found by the last topic preflight check (see topic.preflight.enabled)
+	// +optional
+	Topics []TopicStatus `json:"topics,omitempty"`
+}
+
+// ThroughputStatus describes how fast the table being seeded grows
+type ThroughputStatus struct {
+	// Rows applied per minute between the last two validations
+	RowsPerMinute int64 `json:"rowsPerMinute"`
+
+	// Host count of the table the next estimate is based on
+	HostCount int64 `json:"hostCount"`
+
+	// Time the host count was determined
+	CountedAt metav1.Time `json:"countedAt"`
+
+	// Time the table is expected to reach the host count of HBI at the current throughput. Not set if the table does not grow
+	// +optional
+	EstimatedCompletion *metav1.Time `json:"estimatedCompletion,omitempty"`
+
+	// The throughput is below init.throughput.min
+	// +optional
+	BelowMinimum bool `json:"belowMinimum,omitempty"`
+}
+
+// TopicStatus describes a topic the connector consumes
+type TopicStatus struct {
+	Name string `json:"name"`
+
+	// Number of partitions of the topic, i.e. the number of connector tasks that can consume it in parallel
+	// +optional
+	Partitions int64 `json:"partitions,omitempty"`
+
+	// retention.ms of the topic. Not set if the default of the Kafka cluster applies
+	// +optional
+	RetentionMs *int64 `json:"retentionMs,omitempty"`
+}
+
+// MismatchedHostsStatus summarizes the hosts found in only one of the databases
+type MismatchedHostsStatus struct {
+	// Number of hosts missing in the application table
+	InHbiOnlyCount int64 `json:"inHbiOnlyCount"`
+
+	// Number of hosts that should not be in the application table
+	InAppOnlyCount int64 `json:"inAppOnlyCount"`
+
+	// Ids of some of the hosts missing in the application table
+	// +optional
+	InHbiOnly []string `json:"inHbiOnly,omitempty"`
+
+	// Ids of some of the hosts that should not be in the application table
+	// +optional
+	InAppOnly []string `json:"inAppOnly,omitempty"`
+}
+
+// OffsetResetStatus describes the replacement of the consumer group of a connector requested by the reset-offsets annotation
+type OffsetResetStatus struct {
+	// Value of the annotation the reset was done for (earliest or a RFC3339 timestamp)
+	Request string `json:"request"`
+
+	// Connector whose consumer group was replaced
+	ConnectorName string `json:"connectorName"`
+
+	// Consumer group the connector consumed from before the reset
+	// +optional
+	PreviousConsumerGroup string `json:"previousConsumerGroup,omitempty"`
+
+	// Time of the reset
+	Time metav1.Time `json:"time"`
+
+	// The connector skips events older than this time. Not set if reset to the earliest offset
+	// +optional
+	Since *metav1.Time `json:"since,omitempty"`
+}
+
+// OrgCountsStatus summarizes the comparison of host counts per organization
+type OrgCountsStatus struct {
+	// Number of organizations whose host counts were compared
+	Compared int64 `json:"compared"`
+
+	// Organizations whose host counts differ by more than the validation threshold
+	// +optional
+	Mismatched []OrgHostCount `json:"mismatched,omitempty"`
+}
+
+type OrgHostCount struct {
+	OrgID string `json:"orgId"`
+	HBI   int64  `json:"hbi"`
+	App   int64  `json:"app"`
+}
+
+type ReporterHostCount struct {
+	Reporter string `json:"reporter"`
+	HBI      int64  `json:"hbi"`
+	App      int64  `json:"app"`
+}
+
+// ActiveConfig is the effective configuration of a pipeline
+type ActiveConfig struct {
+	// Version of the merged cyndi ConfigMap keys triggering a refresh (see cyndiConfigVersion)
+	// +optional
+	ConfigMapVersion string `json:"configMapVersion,omitempty"`
+
+	// Topics consumed by the connector
+	// +optional
+	Topics []string `json:"topics,omitempty"`
+
+	// Kafka Connect cluster the connector runs in ([namespace/]name)
+	// +optional
+	ConnectCluster string `json:"connectCluster,omitempty"`
+
+	// Hash of the connector configuration template
+	// +optional
+	ConnectorTemplateHash string `json:"connectorTemplateHash,omitempty"`
+
+	// Hash of the script creating new tables (db.schema)
+	// +optional
+	TableSchemaHash string `json:"tableSchemaHash,omitempty"`
+
+	// Columns of the inventory.hosts view
+	// +optional
+	ViewColumns []string `json:"viewColumns,omitempty"`
+
+	// +optional
+	DBDialect string `json:"dbDialect,omitempty"`
+
+	// +optional
+	InventorySource string `json:"inventorySource,omitempty"`
+
+	// +optional
+	ValidationStrategy string `json:"validationStrategy,omitempty"`
+
+	// Validation of a pipeline that completed the initial sync
+	Validation ActiveValidationConfig `json:"validation"`
+
+	// Validation during the initial sync
+	InitValidation ActiveValidationConfig `json:"initValidation"`
+}
+
+// ActiveValidationConfig defines the effective validation thresholds
+type ActiveValidationConfig struct {
+	// Seconds between validations
+	Interval int64 `json:"interval"`
+	// Failed validations after which the pipeline is refreshed
+	AttemptsThreshold int64 `json:"attemptsThreshold"`
+	// Maximum percentage of mismatched hosts
+	PercentageThreshold int64 `json:"percentageThreshold"`
+	// Maximum number of mismatched hosts. Negative if disabled
+	CountThreshold int64 `json:"countThreshold"`
+	// How the percentage and count thresholds are combined
+	ThresholdMode string `json:"thresholdMode"`
+	// Maximum number of hosts lingering in the application table. Negative if disabled
+	LingeringThreshold int64 `json:"lingeringThreshold"`
+}
+
+// XjoinCrossCheckStatus relates the divergence of the application table from HBI to that of xjoin
+type XjoinCrossCheckStatus struct {
+	// Number of hosts reported by the HBI API
+	HostCount int64 `json:"hostCount"`
+
+	// Host count of the application table minus that of the HBI API
+	AppDivergence int64 `json:"appDivergence"`
+
+	// Host count of the HBI database minus that of the HBI API
+	HBIDivergence int64 `json:"hbiDivergence"`
+
+	// Which of the replicas of HBI diverges from it
+	Diagnosis XjoinDiagnosis `json:"diagnosis"`
+}
+
+// +kubebuilder:validation:Enum=Consistent;Pipeline;Xjoin;Inventory
+type XjoinDiagnosis string
+
+const (
+	// both the application table and xjoin match HBI
+	XjoinDiagnosisConsistent XjoinDiagnosis = "Consistent"
+	// only the application table diverges from HBI - a problem specific to the pipeline
+	XjoinDiagnosisPipeline XjoinDiagnosis = "Pipeline"
+	// only xjoin diverges from HBI
+	XjoinDiagnosisXjoin XjoinDiagnosis = "Xjoin"
+	// both diverge from HBI - likely a systemic problem of the inventory (e.g. events missing from the topic)
+	XjoinDiagnosisInventory XjoinDiagnosis = "Inventory"
+)
+
+// CanaryStatus describes the synthetic host published to the topic consumed by the connector
+type CanaryStatus struct {
+	// Id of the canary host on its way to the application table. Empty if none is
+	// +optional
+	HostID string `json:"hostId,omitempty"`
+
+	// +optional
+	PublishedAt *metav1.Time `json:"publishedAt,omitempty"`
+
+	// Number of seconds it took the last canary host to appear in the application table
+	// +optional
+	LastLatencySeconds *int64 `json:"lastLatencySeconds,omitempty"`
+
+	// The last time a canary host appeared in the application table or timed out
+	// +optional
+	LastCompletedAt *metav1.Time `json:"lastCompletedAt,omitempty"`
+}
+
+type SmokeTestResult string
+
+const (
+	SmokeTestPending   SmokeTestResult = "Pending"
+	SmokeTestSucceeded SmokeTestResult = "Succeeded"
+	SmokeTestFailed    SmokeTestResult = "Failed"
+)
+
+// SmokeTestStatus describes the Job querying the inventory.hosts view using the credentials of the application
+type SmokeTestStatus struct {
+	JobName string `json:"jobName"`
+
+	// Table the view pointed to when the smoke test was started
+	TableName string `json:"tableName"`
+
+	// +kubebuilder:validation:Enum:=Pending;Succeeded;Failed
+	Result SmokeTestResult `json:"result"`
+
+	// Output of the smoke test queries
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -107,6 +823,8 @@ type CyndiPipelineStatus struct {
 // +kubebuilder:printcolumn:name="Host Count",type="integer",JSONPath=".status.hostCount"
 // +kubebuilder:printcolumn:name="Initial sync",type=boolean,JSONPath=`.status.initialSyncInProgress`
 // +kubebuilder:printcolumn:name="Validation failure count",type=integer,JSONPath=`.status.validationFailedCount`
+// +kubebuilder:printcolumn:name="Last cutover",type=date,JSONPath=`.status.lastCutoverTime`,priority=1
+// +kubebuilder:printcolumn:name="Operator version",type=string,JSONPath=`.status.operatorVersion`,priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // CyndiPipeline is the Schema for the cyndipipelines API
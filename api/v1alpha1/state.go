@@ -19,8 +19,59 @@ const (
 	STATE_UNKNOWN      PipelineState = "UNKNOWN"
 )
 
-const tablePrefix = "hosts_v"
+// Tables are named hosts_<pipelineVersion>. Versions starting with a digit (1_<timestamp>, the default naming) are prefixed with v
+const (
+	tablePrefix       = "hosts_"
+	legacyTablePrefix = "hosts_v"
+	connectorPrefix   = "cyndi-"
+)
+
 const validConditionType = "Valid"
+const degradedConditionType = "Degraded"
+const throttledConditionType = "Throttled"
+const replicationHealthyConditionType = "ReplicationHealthy"
+const dependenciesReadyConditionType = "DependenciesReady"
+const driftedConditionType = "Drifted"
+const countAnomalyConditionType = "CountAnomaly"
+const topicReadyConditionType = "TopicReady"
+const syncDeadlineExceededConditionType = "SyncDeadlineExceeded"
+
+// Steps the reconciliation of a pipeline consists of. The outcome of each step is reported using its own condition (e.g. TableReady)
+type ReconcileStep string
+
+const (
+	STEP_SECRETS   ReconcileStep = "Secrets"
+	STEP_TABLE     ReconcileStep = "Table"
+	STEP_CONNECTOR ReconcileStep = "Connector"
+	STEP_VIEW      ReconcileStep = "View"
+	STEP_STATUS    ReconcileStep = "Status"
+)
+
+var ReconcileSteps = []ReconcileStep{STEP_SECRETS, STEP_TABLE, STEP_CONNECTOR, STEP_VIEW, STEP_STATUS}
+
+func (step ReconcileStep) conditionType() string {
+	return string(step) + "Ready"
+}
+
+// Conditions record the generation of the pipeline they were determined for, so that e.g. kubectl wait ignores conditions determined for a previous spec
+func (instance *CyndiPipeline) setCondition(conditionType string, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: instance.GetGeneration(),
+	})
+}
+
+// Records that the status (including its conditions) reflects the current generation of the pipeline. Called once the pipeline has been reconciled
+func (instance *CyndiPipeline) ObserveGeneration() {
+	instance.Status.ObservedGeneration = instance.GetGeneration()
+
+	for idx := range instance.Status.Conditions {
+		instance.Status.Conditions[idx].ObservedGeneration = instance.GetGeneration()
+	}
+}
 
 func (instance *CyndiPipeline) GetState() PipelineState {
 	switch {
@@ -41,8 +92,15 @@ func (instance *CyndiPipeline) GetState() PipelineState {
 
 func (instance *CyndiPipeline) TransitionToNew() error {
 	instance.ResetValid()
+	instance.ResetDegraded()
+	instance.ResetSyncDeadlineExceeded()
 	instance.Status.InitialSyncInProgress = false
 	instance.Status.PipelineVersion = ""
+	instance.Status.InitialSyncStarted = nil
+	instance.Status.InitialSyncLastProgress = nil
+	instance.Status.InitialSyncThroughput = nil
+	instance.Status.CloneSourceTable = ""
+	instance.Status.ConsumerGroupHandoverStarted = nil
 	return nil
 }
 
@@ -51,8 +109,20 @@ func (instance *CyndiPipeline) TransitionToInitialSync(pipelineVersion string) e
 		return err
 	}
 
+	now := metav1.Now()
+
 	instance.ResetValid()
+	instance.ResetDegraded()
+	instance.ResetSyncDeadlineExceeded()
 	instance.Status.InitialSyncInProgress = true
+	instance.Status.InitialSyncStarted = &now
+	instance.Status.InitialSyncLastProgress = &now
+	instance.Status.InitialSyncThroughput = nil
+	instance.Status.ClonedEventsSince = nil
+	instance.Status.ShadowValidations = 0
+	instance.Status.HostCountDeltas = nil
+	instance.Status.ConsumerGroup = ""
+	instance.Status.ConsumerGroupHandoverStarted = nil
 	instance.Status.PipelineVersion = pipelineVersion
 	instance.Status.ConnectorName = ConnectorName(pipelineVersion, instance.Spec.AppName)
 	instance.Status.TableName = TableName(pipelineVersion)
@@ -61,12 +131,7 @@ func (instance *CyndiPipeline) TransitionToInitialSync(pipelineVersion string) e
 }
 
 func (instance *CyndiPipeline) SetValid(status metav1.ConditionStatus, reason string, message string, hostCount int64) {
-	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
-		Type:    validConditionType,
-		Status:  status,
-		Reason:  reason,
-		Message: message,
-	})
+	instance.setCondition(validConditionType, status, reason, message)
 
 	instance.Status.HostCount = hostCount
 
@@ -81,6 +146,13 @@ func (instance *CyndiPipeline) SetValid(status metav1.ConditionStatus, reason st
 	}
 }
 
+// Records a failed validation without counting it towards the refresh threshold
+func (instance *CyndiPipeline) SetInvalidUncounted(reason string, message string, hostCount int64) {
+	instance.setCondition(validConditionType, metav1.ConditionFalse, reason, message)
+
+	instance.Status.HostCount = hostCount
+}
+
 func (instance *CyndiPipeline) ResetValid() {
 	instance.SetValid(metav1.ConditionUnknown, "New", "Validation not yet run", -1)
 }
@@ -99,6 +171,131 @@ func (instance *CyndiPipeline) GetValid() metav1.ConditionStatus {
 	return condition.Status
 }
 
+func (instance *CyndiPipeline) GetValidCondition() *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, validConditionType)
+}
+
+func (instance *CyndiPipeline) SetDegraded(status metav1.ConditionStatus, reason string, message string) {
+	instance.setCondition(degradedConditionType, status, reason, message)
+}
+
+func (instance *CyndiPipeline) ResetDegraded() {
+	meta.RemoveStatusCondition(&instance.Status.Conditions, degradedConditionType)
+}
+
+func (instance *CyndiPipeline) GetDegraded() *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, degradedConditionType)
+}
+
+func (instance *CyndiPipeline) IsDegraded() bool {
+	return meta.IsStatusConditionTrue(instance.Status.Conditions, degradedConditionType)
+}
+
+// Records a failed reconcile attempt. Reconciliation is suspended until the given time.
+func (instance *CyndiPipeline) SetThrottled(reason string, message string, until metav1.Time) {
+	instance.Status.ConsecutiveFailures++
+	instance.Status.ThrottledUntil = &until
+
+	instance.setCondition(throttledConditionType, metav1.ConditionTrue, reason, message)
+}
+
+func (instance *CyndiPipeline) ResetThrottled() {
+	instance.Status.ConsecutiveFailures = 0
+	instance.Status.ThrottledUntil = nil
+	meta.RemoveStatusCondition(&instance.Status.Conditions, throttledConditionType)
+}
+
+func (instance *CyndiPipeline) GetThrottled() *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, throttledConditionType)
+}
+
+// Records the health of the replication slots (and publications) of the source connector the pipeline depends on
+func (instance *CyndiPipeline) SetReplicationHealthy(status metav1.ConditionStatus, reason string, message string) {
+	instance.setCondition(replicationHealthyConditionType, status, reason, message)
+}
+
+func (instance *CyndiPipeline) ResetReplicationHealthy() {
+	meta.RemoveStatusCondition(&instance.Status.Conditions, replicationHealthyConditionType)
+}
+
+func (instance *CyndiPipeline) GetReplicationHealthy() *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, replicationHealthyConditionType)
+}
+
+// Records whether the pipelines the pipeline depends on (spec.dependsOn) allow its view to be switched to a new table
+func (instance *CyndiPipeline) SetDependenciesReady(status metav1.ConditionStatus, reason string, message string) {
+	instance.setCondition(dependenciesReadyConditionType, status, reason, message)
+}
+
+func (instance *CyndiPipeline) ResetDependenciesReady() {
+	meta.RemoveStatusCondition(&instance.Status.Conditions, dependenciesReadyConditionType)
+}
+
+func (instance *CyndiPipeline) GetDependenciesReady() *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, dependenciesReadyConditionType)
+}
+
+// Records whether the live connector, table and view differ from what the pipeline would render
+func (instance *CyndiPipeline) SetDrifted(status metav1.ConditionStatus, reason string, message string) {
+	instance.setCondition(driftedConditionType, status, reason, message)
+}
+
+func (instance *CyndiPipeline) ResetDrifted() {
+	meta.RemoveStatusCondition(&instance.Status.Conditions, driftedConditionType)
+}
+
+func (instance *CyndiPipeline) GetDrifted() *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, driftedConditionType)
+}
+
+// Records whether the difference between the host counts of the application table and HBI changed unusually (see validation.anomaly.enabled)
+func (instance *CyndiPipeline) SetCountAnomaly(status metav1.ConditionStatus, reason string, message string) {
+	instance.setCondition(countAnomalyConditionType, status, reason, message)
+}
+
+func (instance *CyndiPipeline) ResetCountAnomaly() {
+	meta.RemoveStatusCondition(&instance.Status.Conditions, countAnomalyConditionType)
+}
+
+func (instance *CyndiPipeline) GetCountAnomaly() *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, countAnomalyConditionType)
+}
+
+// Records whether the topics of the pipeline exist (see topic.preflight.enabled)
+func (instance *CyndiPipeline) SetTopicReady(status metav1.ConditionStatus, reason string, message string) {
+	instance.setCondition(topicReadyConditionType, status, reason, message)
+}
+
+func (instance *CyndiPipeline) ResetTopicReady() {
+	meta.RemoveStatusCondition(&instance.Status.Conditions, topicReadyConditionType)
+}
+
+func (instance *CyndiPipeline) GetTopicReady() *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, topicReadyConditionType)
+}
+
+// Records whether the pipeline failed to become valid within the initial sync timeout (see spec.initialSyncTimeout)
+func (instance *CyndiPipeline) SetSyncDeadlineExceeded(status metav1.ConditionStatus, reason string, message string) {
+	instance.setCondition(syncDeadlineExceededConditionType, status, reason, message)
+}
+
+func (instance *CyndiPipeline) ResetSyncDeadlineExceeded() {
+	meta.RemoveStatusCondition(&instance.Status.Conditions, syncDeadlineExceededConditionType)
+}
+
+func (instance *CyndiPipeline) GetSyncDeadlineExceeded() *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, syncDeadlineExceededConditionType)
+}
+
+// Records the outcome of a step of the reconciliation of the pipeline
+func (instance *CyndiPipeline) SetStepReady(step ReconcileStep, status metav1.ConditionStatus, reason string, message string) {
+	instance.setCondition(step.conditionType(), status, reason, message)
+}
+
+func (instance *CyndiPipeline) GetStepReady(step ReconcileStep) *metav1.Condition {
+	return meta.FindStatusCondition(instance.Status.Conditions, step.conditionType())
+}
+
 func (instance *CyndiPipeline) assertState(targetState PipelineState, validStates ...PipelineState) error {
 	for _, state := range validStates {
 		if instance.GetState() == state {
@@ -110,13 +307,34 @@ func (instance *CyndiPipeline) assertState(targetState PipelineState, validState
 }
 
 func TableName(pipelineVersion string) string {
-	return fmt.Sprintf("%s%s", tablePrefix, pipelineVersion)
+	if isLegacyPipelineVersion(pipelineVersion) {
+		return legacyTablePrefix + pipelineVersion
+	}
+
+	return tablePrefix + pipelineVersion
 }
 
 func TableNameToConnectorName(tableName string, appName string) string {
-	return ConnectorName(string(tableName[len(tablePrefix):len(tableName)]), appName)
+	return ConnectorName(TableNameToPipelineVersion(tableName), appName)
 }
 
+func TableNameToPipelineVersion(tableName string) string {
+	if strings.HasPrefix(tableName, legacyTablePrefix) && isLegacyPipelineVersion(tableName[len(legacyTablePrefix):]) {
+		return tableName[len(legacyTablePrefix):]
+	}
+
+	return strings.TrimPrefix(tableName, tablePrefix)
+}
+
+// Connectors of versions named using a naming template are named after the table, e.g. cyndi-advisor-3 for hosts_advisor_3
 func ConnectorName(pipelineVersion string, appName string) string {
-	return fmt.Sprintf("cyndi-%s-%s", appName, strings.Replace(pipelineVersion, "_", "-", 1))
+	if isLegacyPipelineVersion(pipelineVersion) {
+		return fmt.Sprintf("%s%s-%s", connectorPrefix, appName, strings.Replace(pipelineVersion, "_", "-", 1))
+	}
+
+	return connectorPrefix + strings.ReplaceAll(pipelineVersion, "_", "-")
+}
+
+func isLegacyPipelineVersion(pipelineVersion string) bool {
+	return pipelineVersion != "" && pipelineVersion[0] >= '0' && pipelineVersion[0] <= '9'
 }
@@ -1,5 +1,39 @@
 package v1alpha1
 
+import "reflect"
+
 func (instance *CyndiPipeline) GetUIDString() string {
 	return string(instance.GetUID())
 }
+
+/*
+ * Returns JSON names of the fields that differ from the previous spec but cannot be changed once the pipeline is created.
+ * These fields determine which application database and which resources belong to the pipeline - a new pipeline needs to be created instead.
+ */
+func (spec CyndiPipelineSpec) ImmutableFieldChanges(previous CyndiPipelineSpec) (result []string) {
+	if spec.AppName != previous.AppName {
+		result = append(result, "appName")
+	}
+
+	if !stringPtrEqual(spec.DbSecret, previous.DbSecret) {
+		result = append(result, "dbSecret")
+	}
+
+	if !stringPtrEqual(spec.DBDialect, previous.DBDialect) {
+		result = append(result, "dbDialect")
+	}
+
+	if !reflect.DeepEqual(spec.SourcePipeline, previous.SourcePipeline) {
+		result = append(result, "sourcePipeline")
+	}
+
+	return result
+}
+
+func stringPtrEqual(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
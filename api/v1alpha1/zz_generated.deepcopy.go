@@ -26,6 +26,192 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActiveConfig) DeepCopyInto(out *ActiveConfig) {
+	*out = *in
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ViewColumns != nil {
+		in, out := &in.ViewColumns, &out.ViewColumns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Validation = in.Validation
+	out.InitValidation = in.InitValidation
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveConfig.
+func (in *ActiveConfig) DeepCopy() *ActiveConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ActiveConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActiveValidationConfig) DeepCopyInto(out *ActiveValidationConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveValidationConfig.
+func (in *ActiveValidationConfig) DeepCopy() *ActiveValidationConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ActiveValidationConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryStatus) DeepCopyInto(out *CanaryStatus) {
+	*out = *in
+	if in.PublishedAt != nil {
+		in, out := &in.PublishedAt, &out.PublishedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LastLatencySeconds != nil {
+		in, out := &in.LastLatencySeconds, &out.LastLatencySeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.LastCompletedAt != nil {
+		in, out := &in.LastCompletedAt, &out.LastCompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryStatus.
+func (in *CanaryStatus) DeepCopy() *CanaryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectorTemplate) DeepCopyInto(out *ConnectorTemplate) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectorTemplate.
+func (in *ConnectorTemplate) DeepCopy() *ConnectorTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectorTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CyndiConfig) DeepCopyInto(out *CyndiConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CyndiConfig.
+func (in *CyndiConfig) DeepCopy() *CyndiConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CyndiConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CyndiConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CyndiConfigList) DeepCopyInto(out *CyndiConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CyndiConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CyndiConfigList.
+func (in *CyndiConfigList) DeepCopy() *CyndiConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(CyndiConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CyndiConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CyndiConfigSpec) DeepCopyInto(out *CyndiConfigSpec) {
+	*out = *in
+	in.PipelineDefaults.DeepCopyInto(&out.PipelineDefaults)
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceDefaults, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CyndiConfigSpec.
+func (in *CyndiConfigSpec) DeepCopy() *CyndiConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CyndiConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CyndiPipeline) DeepCopyInto(out *CyndiPipeline) {
 	*out = *in
@@ -116,11 +302,56 @@ func (in *CyndiPipelineSpec) DeepCopyInto(out *CyndiPipelineSpec) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.ValidationCountThreshold != nil {
+		in, out := &in.ValidationCountThreshold, &out.ValidationCountThreshold
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ValidationThresholdMode != nil {
+		in, out := &in.ValidationThresholdMode, &out.ValidationThresholdMode
+		*out = new(string)
+		**out = **in
+	}
+	if in.ValidationStrategy != nil {
+		in, out := &in.ValidationStrategy, &out.ValidationStrategy
+		*out = new(string)
+		**out = **in
+	}
+	if in.ValidationInterval != nil {
+		in, out := &in.ValidationInterval, &out.ValidationInterval
+		*out = new(int64)
+		**out = **in
+	}
+	if in.InitValidationInterval != nil {
+		in, out := &in.InitValidationInterval, &out.InitValidationInterval
+		*out = new(int64)
+		**out = **in
+	}
+	if in.InitialSyncTimeout != nil {
+		in, out := &in.InitialSyncTimeout, &out.InitialSyncTimeout
+		*out = new(int64)
+		**out = **in
+	}
 	if in.Topic != nil {
 		in, out := &in.Topic, &out.Topic
 		*out = new(string)
 		**out = **in
 	}
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]TopicSource, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConsumerGroupTemplate != nil {
+		in, out := &in.ConsumerGroupTemplate, &out.ConsumerGroupTemplate
+		*out = new(string)
+		**out = **in
+	}
+	if in.AutoOffsetReset != nil {
+		in, out := &in.AutoOffsetReset, &out.AutoOffsetReset
+		*out = new(string)
+		**out = **in
+	}
 	if in.DbSecret != nil {
 		in, out := &in.DbSecret, &out.DbSecret
 		*out = new(string)
@@ -131,6 +362,101 @@ func (in *CyndiPipelineSpec) DeepCopyInto(out *CyndiPipelineSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.InventoryDbSecrets != nil {
+		in, out := &in.InventoryDbSecrets, &out.InventoryDbSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DBTablePartitioning != nil {
+		in, out := &in.DBTablePartitioning, &out.DBTablePartitioning
+		*out = new(TablePartitioning)
+		**out = **in
+	}
+	if in.DBTableStorageParameters != nil {
+		in, out := &in.DBTableStorageParameters, &out.DBTableStorageParameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DBTableCompression != nil {
+		in, out := &in.DBTableCompression, &out.DBTableCompression
+		*out = new(string)
+		**out = **in
+	}
+	if in.DBDialect != nil {
+		in, out := &in.DBDialect, &out.DBDialect
+		*out = new(string)
+		**out = **in
+	}
+	if in.DBAuth != nil {
+		in, out := &in.DBAuth, &out.DBAuth
+		*out = new(DBAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DBGrants != nil {
+		in, out := &in.DBGrants, &out.DBGrants
+		*out = new(DBGrants)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ViewStaleness != nil {
+		in, out := &in.ViewStaleness, &out.ViewStaleness
+		*out = new(ViewStaleness)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectorTemplate != nil {
+		in, out := &in.ConnectorTemplate, &out.ConnectorTemplate
+		*out = new(ConnectorTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectorLabels != nil {
+		in, out := &in.ConnectorLabels, &out.ConnectorLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ConnectorAnnotations != nil {
+		in, out := &in.ConnectorAnnotations, &out.ConnectorAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Deletes != nil {
+		in, out := &in.Deletes, &out.Deletes
+		*out = new(DeleteHandling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManageSourceConnector != nil {
+		in, out := &in.ManageSourceConnector, &out.ManageSourceConnector
+		*out = new(SourceConnector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResyncRequestedAt != nil {
+		in, out := &in.ResyncRequestedAt, &out.ResyncRequestedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValidationWindows != nil {
+		in, out := &in.ValidationWindows, &out.ValidationWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]PipelineReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.SourcePipeline != nil {
+		in, out := &in.SourcePipeline, &out.SourcePipeline
+		*out = new(SourcePipeline)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CyndiPipelineSpec.
@@ -146,6 +472,19 @@ func (in *CyndiPipelineSpec) DeepCopy() *CyndiPipelineSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CyndiPipelineStatus) DeepCopyInto(out *CyndiPipelineStatus) {
 	*out = *in
+	if in.ActiveConfig != nil {
+		in, out := &in.ActiveConfig, &out.ActiveConfig
+		*out = new(ActiveConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastCutoverTime != nil {
+		in, out := &in.LastCutoverTime, &out.LastCutoverTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastResyncAt != nil {
+		in, out := &in.LastResyncAt, &out.LastResyncAt
+		*out = (*in).DeepCopy()
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -153,6 +492,97 @@ func (in *CyndiPipelineStatus) DeepCopyInto(out *CyndiPipelineStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.HostCountDeltas != nil {
+		in, out := &in.HostCountDeltas, &out.HostCountDeltas
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.MismatchedHosts != nil {
+		in, out := &in.MismatchedHosts, &out.MismatchedHosts
+		*out = new(MismatchedHostsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OrgCounts != nil {
+		in, out := &in.OrgCounts, &out.OrgCounts
+		*out = new(OrgCountsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReporterCounts != nil {
+		in, out := &in.ReporterCounts, &out.ReporterCounts
+		*out = make([]ReporterHostCount, len(*in))
+		copy(*out, *in)
+	}
+	if in.XjoinCrossCheck != nil {
+		in, out := &in.XjoinCrossCheck, &out.XjoinCrossCheck
+		*out = new(XjoinCrossCheckStatus)
+		**out = **in
+	}
+	if in.SkippedValidationChecks != nil {
+		in, out := &in.SkippedValidationChecks, &out.SkippedValidationChecks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InitialSyncStarted != nil {
+		in, out := &in.InitialSyncStarted, &out.InitialSyncStarted
+		*out = (*in).DeepCopy()
+	}
+	if in.InitialSyncLastProgress != nil {
+		in, out := &in.InitialSyncLastProgress, &out.InitialSyncLastProgress
+		*out = (*in).DeepCopy()
+	}
+	if in.InitialSyncThroughput != nil {
+		in, out := &in.InitialSyncThroughput, &out.InitialSyncThroughput
+		*out = new(ThroughputStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ThrottledUntil != nil {
+		in, out := &in.ThrottledUntil, &out.ThrottledUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.UnloggedTableServerStart != nil {
+		in, out := &in.UnloggedTableServerStart, &out.UnloggedTableServerStart
+		*out = (*in).DeepCopy()
+	}
+	if in.ClonedEventsSince != nil {
+		in, out := &in.ClonedEventsSince, &out.ClonedEventsSince
+		*out = (*in).DeepCopy()
+	}
+	if in.ConsumerGroupHandoverStarted != nil {
+		in, out := &in.ConsumerGroupHandoverStarted, &out.ConsumerGroupHandoverStarted
+		*out = (*in).DeepCopy()
+	}
+	if in.OffsetReset != nil {
+		in, out := &in.OffsetReset, &out.OffsetReset
+		*out = new(OffsetResetStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConsumerLag != nil {
+		in, out := &in.ConsumerLag, &out.ConsumerLag
+		*out = new(int64)
+		**out = **in
+	}
+	if in.SmokeTest != nil {
+		in, out := &in.SmokeTest, &out.SmokeTest
+		*out = new(SmokeTestStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(CanaryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SourceReplicationSlots != nil {
+		in, out := &in.SourceReplicationSlots, &out.SourceReplicationSlots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]TopicStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CyndiPipelineStatus.
@@ -164,3 +594,548 @@ func (in *CyndiPipelineStatus) DeepCopy() *CyndiPipelineStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DBAuth) DeepCopyInto(out *DBAuth) {
+	*out = *in
+	if in.ClientID != nil {
+		in, out := &in.ClientID, &out.ClientID
+		*out = new(string)
+		**out = **in
+	}
+	if in.CloudSQLInstance != nil {
+		in, out := &in.CloudSQLInstance, &out.CloudSQLInstance
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DBAuth.
+func (in *DBAuth) DeepCopy() *DBAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(DBAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DBGrants) DeepCopyInto(out *DBGrants) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]DBRole, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReadOnlyRole != nil {
+		in, out := &in.ReadOnlyRole, &out.ReadOnlyRole
+		*out = new(DBRole)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DBGrants.
+func (in *DBGrants) DeepCopy() *DBGrants {
+	if in == nil {
+		return nil
+	}
+	out := new(DBGrants)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeleteHandling) DeepCopyInto(out *DeleteHandling) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Tombstones != nil {
+		in, out := &in.Tombstones, &out.Tombstones
+		*out = new(string)
+		**out = **in
+	}
+	if in.PKMode != nil {
+		in, out := &in.PKMode, &out.PKMode
+		*out = new(string)
+		**out = **in
+	}
+	if in.PKFields != nil {
+		in, out := &in.PKFields, &out.PKFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeleteHandling.
+func (in *DeleteHandling) DeepCopy() *DeleteHandling {
+	if in == nil {
+		return nil
+	}
+	out := new(DeleteHandling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MismatchedHostsStatus) DeepCopyInto(out *MismatchedHostsStatus) {
+	*out = *in
+	if in.InHbiOnly != nil {
+		in, out := &in.InHbiOnly, &out.InHbiOnly
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InAppOnly != nil {
+		in, out := &in.InAppOnly, &out.InAppOnly
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MismatchedHostsStatus.
+func (in *MismatchedHostsStatus) DeepCopy() *MismatchedHostsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MismatchedHostsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceDefaults) DeepCopyInto(out *NamespaceDefaults) {
+	*out = *in
+	in.PipelineDefaults.DeepCopyInto(&out.PipelineDefaults)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceDefaults.
+func (in *NamespaceDefaults) DeepCopy() *NamespaceDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OffsetResetStatus) DeepCopyInto(out *OffsetResetStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Since != nil {
+		in, out := &in.Since, &out.Since
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OffsetResetStatus.
+func (in *OffsetResetStatus) DeepCopy() *OffsetResetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OffsetResetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrgCountsStatus) DeepCopyInto(out *OrgCountsStatus) {
+	*out = *in
+	if in.Mismatched != nil {
+		in, out := &in.Mismatched, &out.Mismatched
+		*out = make([]OrgHostCount, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrgCountsStatus.
+func (in *OrgCountsStatus) DeepCopy() *OrgCountsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OrgCountsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrgHostCount) DeepCopyInto(out *OrgHostCount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrgHostCount.
+func (in *OrgHostCount) DeepCopy() *OrgHostCount {
+	if in == nil {
+		return nil
+	}
+	out := new(OrgHostCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineDefaults) DeepCopyInto(out *PipelineDefaults) {
+	*out = *in
+	if in.Topic != nil {
+		in, out := &in.Topic, &out.Topic
+		*out = new(string)
+		**out = **in
+	}
+	if in.ConnectCluster != nil {
+		in, out := &in.ConnectCluster, &out.ConnectCluster
+		*out = new(string)
+		**out = **in
+	}
+	if in.InventoryDbSecret != nil {
+		in, out := &in.InventoryDbSecret, &out.InventoryDbSecret
+		*out = new(string)
+		**out = **in
+	}
+	if in.ConnectorTemplate != nil {
+		in, out := &in.ConnectorTemplate, &out.ConnectorTemplate
+		*out = new(string)
+		**out = **in
+	}
+	if in.ConnectorTasksMax != nil {
+		in, out := &in.ConnectorTasksMax, &out.ConnectorTasksMax
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ConnectorBatchSize != nil {
+		in, out := &in.ConnectorBatchSize, &out.ConnectorBatchSize
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DBSchema != nil {
+		in, out := &in.DBSchema, &out.DBSchema
+		*out = new(string)
+		**out = **in
+	}
+	if in.StandardInterval != nil {
+		in, out := &in.StandardInterval, &out.StandardInterval
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ValidationDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InitValidation != nil {
+		in, out := &in.InitValidation, &out.InitValidation
+		*out = new(ValidationDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineDefaults.
+func (in *PipelineDefaults) DeepCopy() *PipelineDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineReference) DeepCopyInto(out *PipelineReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineReference.
+func (in *PipelineReference) DeepCopy() *PipelineReference {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReporterHostCount) DeepCopyInto(out *ReporterHostCount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReporterHostCount.
+func (in *ReporterHostCount) DeepCopy() *ReporterHostCount {
+	if in == nil {
+		return nil
+	}
+	out := new(ReporterHostCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmokeTestStatus) DeepCopyInto(out *SmokeTestStatus) {
+	*out = *in
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmokeTestStatus.
+func (in *SmokeTestStatus) DeepCopy() *SmokeTestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SmokeTestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SourceConnector) DeepCopyInto(out *SourceConnector) {
+	*out = *in
+	if in.SlotNamePrefix != nil {
+		in, out := &in.SlotNamePrefix, &out.SlotNamePrefix
+		*out = new(string)
+		**out = **in
+	}
+	if in.TableIncludeList != nil {
+		in, out := &in.TableIncludeList, &out.TableIncludeList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Heartbeat != nil {
+		in, out := &in.Heartbeat, &out.Heartbeat
+		*out = new(SourceHeartbeat)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourceConnector.
+func (in *SourceConnector) DeepCopy() *SourceConnector {
+	if in == nil {
+		return nil
+	}
+	out := new(SourceConnector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SourceHeartbeat) DeepCopyInto(out *SourceHeartbeat) {
+	*out = *in
+	if in.IntervalMs != nil {
+		in, out := &in.IntervalMs, &out.IntervalMs
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Table != nil {
+		in, out := &in.Table, &out.Table
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourceHeartbeat.
+func (in *SourceHeartbeat) DeepCopy() *SourceHeartbeat {
+	if in == nil {
+		return nil
+	}
+	out := new(SourceHeartbeat)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SourcePipeline) DeepCopyInto(out *SourcePipeline) {
+	*out = *in
+	if in.ViewName != nil {
+		in, out := &in.ViewName, &out.ViewName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourcePipeline.
+func (in *SourcePipeline) DeepCopy() *SourcePipeline {
+	if in == nil {
+		return nil
+	}
+	out := new(SourcePipeline)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TablePartitioning) DeepCopyInto(out *TablePartitioning) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TablePartitioning.
+func (in *TablePartitioning) DeepCopy() *TablePartitioning {
+	if in == nil {
+		return nil
+	}
+	out := new(TablePartitioning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThroughputStatus) DeepCopyInto(out *ThroughputStatus) {
+	*out = *in
+	in.CountedAt.DeepCopyInto(&out.CountedAt)
+	if in.EstimatedCompletion != nil {
+		in, out := &in.EstimatedCompletion, &out.EstimatedCompletion
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThroughputStatus.
+func (in *ThroughputStatus) DeepCopy() *ThroughputStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ThroughputStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopicSource) DeepCopyInto(out *TopicSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopicSource.
+func (in *TopicSource) DeepCopy() *TopicSource {
+	if in == nil {
+		return nil
+	}
+	out := new(TopicSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopicStatus) DeepCopyInto(out *TopicStatus) {
+	*out = *in
+	if in.RetentionMs != nil {
+		in, out := &in.RetentionMs, &out.RetentionMs
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopicStatus.
+func (in *TopicStatus) DeepCopy() *TopicStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TopicStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValidationDefaults) DeepCopyInto(out *ValidationDefaults) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(int64)
+		**out = **in
+	}
+	if in.AttemptsThreshold != nil {
+		in, out := &in.AttemptsThreshold, &out.AttemptsThreshold
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PercentageThreshold != nil {
+		in, out := &in.PercentageThreshold, &out.PercentageThreshold
+		*out = new(int64)
+		**out = **in
+	}
+	if in.CountThreshold != nil {
+		in, out := &in.CountThreshold, &out.CountThreshold
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ThresholdMode != nil {
+		in, out := &in.ThresholdMode, &out.ThresholdMode
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValidationDefaults.
+func (in *ValidationDefaults) DeepCopy() *ValidationDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(ValidationDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ViewStaleness) DeepCopyInto(out *ViewStaleness) {
+	*out = *in
+	if in.Column != nil {
+		in, out := &in.Column, &out.Column
+		*out = new(string)
+		**out = **in
+	}
+	if in.StaleAfter != nil {
+		in, out := &in.StaleAfter, &out.StaleAfter
+		*out = new(int64)
+		**out = **in
+	}
+	if in.CulledAfter != nil {
+		in, out := &in.CulledAfter, &out.CulledAfter
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ViewStaleness.
+func (in *ViewStaleness) DeepCopy() *ViewStaleness {
+	if in == nil {
+		return nil
+	}
+	out := new(ViewStaleness)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XjoinCrossCheckStatus) DeepCopyInto(out *XjoinCrossCheckStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XjoinCrossCheckStatus.
+func (in *XjoinCrossCheckStatus) DeepCopy() *XjoinCrossCheckStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(XjoinCrossCheckStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -18,16 +18,16 @@ if docker buildx ls | grep -q "multiarchbuilder"; then
     echo "Using multiarchbuilder for buildx"
     # Multi-architecture build
     docker buildx use multiarchbuilder
-    docker buildx build --platform linux/amd64,linux/arm64 -t "${IMAGE}:${IMAGE_TAG}" --push .
+    docker buildx build --platform linux/amd64,linux/arm64 -t "${IMAGE}:${IMAGE_TAG}" --build-arg OPERATOR_VERSION="${IMAGE_TAG}" --push .
 else
     echo "Falling back to standard build and push"
     # Standard build and push
-    docker build -t "${IMAGE}:${IMAGE_TAG}" .
+    docker build -t "${IMAGE}:${IMAGE_TAG}" --build-arg OPERATOR_VERSION="${IMAGE_TAG}" .
     docker push "${IMAGE}:${IMAGE_TAG}"
 fi
 
 if [[ "$GIT_BRANCH" == "origin/security-compliance" ]]; then
-    docker build -t "${IMAGE}:${IMAGE_TAG}" .
+    docker build -t "${IMAGE}:${IMAGE_TAG}" --build-arg OPERATOR_VERSION="${IMAGE_TAG}" .
     docker  tag "${IMAGE}:${IMAGE_TAG}" "${IMAGE}:${SECURITY_COMPLIANCE_TAG}"
     docker  push "${IMAGE}:${SECURITY_COMPLIANCE_TAG}"
 fi
@@ -0,0 +1,103 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Validates a single pipeline and exits. Run by the validation CronJobs created by the operator if validation.mode is set to cronjob
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+)
+
+var (
+	scheme = runtime.NewScheme()
+	log    = ctrl.Log.WithName("validate")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(cyndi.AddToScheme(scheme))
+}
+
+func main() {
+	var namespace string
+	var name string
+	var logSQL bool
+
+	flag.StringVar(&namespace, "namespace", "", "Namespace of the CyndiPipeline to validate.")
+	flag.StringVar(&name, "name", "", "Name of the CyndiPipeline to validate.")
+	flag.BoolVar(&logSQL, "log-sql", false, "Log SQL statements executed against the databases.")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(os.Getenv("DEV_MODE") == "true")))
+	database.LogStatements = logSQL
+
+	if namespace == "" || name == "" {
+		fmt.Fprintln(os.Stderr, "--namespace and --name are required")
+		os.Exit(2)
+	}
+
+	if err := validate(types.NamespacedName{Namespace: namespace, Name: name}); err != nil {
+		log.Error(err, "Validation failed", "pipeline", namespace+"/"+name)
+		os.Exit(1)
+	}
+}
+
+func validate(pipeline types.NamespacedName) error {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	// events are emitted the same way as by the validation controller of the operator
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	defer broadcaster.Shutdown()
+
+	reconciler := controllers.NewValidationReconciler(c, clientset, scheme, log, broadcaster.NewRecorder(scheme, corev1.EventSource{Component: "validation"}), true)
+	reconciler.Standalone = true
+
+	_, err = reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: pipeline})
+	return err
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+The effective configuration of a pipeline (status.activeConfig), i.e. the result of merging the CyndiConfig, the cyndi ConfigMaps and the spec.
+Templates are only represented by their hashes to keep the status small.
+
+*/
+
+func activeConfig(c *config.CyndiConfiguration) (*cyndi.ActiveConfig, error) {
+	connectorTemplateHash, err := utils.SpecHash(c.ConnectorTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	tableSchemaHash, err := utils.SpecHash(c.DBTableInitScript)
+	if err != nil {
+		return nil, err
+	}
+
+	connectCluster := c.ConnectCluster
+	if c.ConnectClusterNamespace != "" {
+		connectCluster = fmt.Sprintf("%s/%s", c.ConnectClusterNamespace, c.ConnectCluster)
+	}
+
+	return &cyndi.ActiveConfig{
+		ConfigMapVersion:      c.ConfigMapVersion,
+		Topics:                strings.Split(c.Topic, ","),
+		ConnectCluster:        connectCluster,
+		ConnectorTemplateHash: connectorTemplateHash,
+		TableSchemaHash:       tableSchemaHash,
+		ViewColumns:           database.ViewColumns(c.ViewStaleness),
+		DBDialect:             string(c.DBDialect),
+		InventorySource:       string(c.InventorySource),
+		ValidationStrategy:    string(c.ValidationStrategy),
+		Validation:            activeValidationConfig(c.ValidationConfig),
+		InitValidation:        activeValidationConfig(c.ValidationConfigInit),
+	}, nil
+}
+
+func activeValidationConfig(v config.ValidationConfiguration) cyndi.ActiveValidationConfig {
+	return cyndi.ActiveValidationConfig{
+		Interval:            v.Interval,
+		AttemptsThreshold:   v.AttemptsThreshold,
+		PercentageThreshold: v.PercentageThreshold,
+		CountThreshold:      v.CountThreshold,
+		ThresholdMode:       string(v.ThresholdMode),
+		LingeringThreshold:  v.LingeringThreshold,
+	}
+}
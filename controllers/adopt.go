@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+/*
+
+Disaster recovery of pipelines. The state of a valid pipeline can be exported to a ConfigMap which, unlike the pipeline's status, survives the pipeline being re-created.
+A pipeline with spec.adoptExisting set takes over the table backing the inventory.hosts view and its connector when created instead of starting a new initial sync.
+The same applies to an inventory.hosts table created by the Cyndi setup that predates the operator.
+
+*/
+
+func stateConfigMapName(pipelineName string) string {
+	return fmt.Sprintf("%s-state", pipelineName)
+}
+
+/*
+ * Stores the state of a valid pipeline in a ConfigMap.
+ * The ConfigMap is deliberately not owned by the pipeline so that it is not garbage-collected together with it.
+ */
+func (i *ReconcileIteration) exportState() error {
+	connector, err := connect.GetConnector(i.ctx, i.Client, i.Instance.Status.ConnectorName, i.connectorNamespace())
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      stateConfigMapName(i.Instance.Name),
+			Namespace: i.Instance.Namespace,
+		},
+	}
+
+	_, err = controllerutil.CreateOrUpdate(i.ctx, i.Client, configMap, func() error {
+		configMap.Labels = utils.Merge(configMap.Labels, i.pipelineLabels())
+		configMap.Data = map[string]string{
+			"pipelineVersion":    i.Instance.Status.PipelineVersion,
+			"tableName":          i.Instance.Status.TableName,
+			"connectorName":      i.Instance.Status.ConnectorName,
+			"connectorSpecHash":  connector.GetAnnotations()[connect.AnnotationSpecHash],
+			"specHash":           i.Instance.Status.SpecHash,
+			"cyndiConfigVersion": i.Instance.Status.CyndiConfigVersion,
+			"consumerGroup":      i.Instance.Status.ConsumerGroup,
+			"hostCount":          strconv.FormatInt(i.Instance.Status.HostCount, 10),
+		}
+
+		if since := i.Instance.Status.ClonedEventsSince; since != nil {
+			configMap.Data["clonedEventsSince"] = since.Format(time.RFC3339)
+		}
+
+		if condition := i.Instance.GetValidCondition(); condition != nil {
+			configMap.Data["validSince"] = condition.LastTransitionTime.Format(time.RFC3339)
+			configMap.Data["validationMessage"] = condition.Message
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+/*
+ * Takes over the table backing the view of the pipeline and its connector.
+ * Returns false if there is nothing to adopt, in which case a new pipeline version should be created as usual.
+ */
+func (i *ReconcileIteration) adoptExisting() (adopted bool, err error) {
+	// a legacy table is left to the source pipeline of a clone
+	if i.Instance.Spec.SourcePipeline == nil {
+		if legacy, err := i.AppDb.IsLegacyHostsTable(); err != nil {
+			return false, err
+		} else if legacy {
+			return true, i.adoptLegacyTable()
+		}
+	}
+
+	table, err := i.AppDb.GetCurrentTable()
+	if err != nil || table == nil {
+		return false, err
+	}
+
+	connectorName := cyndi.TableNameToConnectorName(*table, i.Instance.Spec.AppName)
+
+	connector, err := connect.GetConnector(i.ctx, i.Client, connectorName, i.connectorNamespace())
+	if k8errors.IsNotFound(err) {
+		i.Log.Info("Not adopting table as its connector does not exist", "table", *table, "connector", connectorName)
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if err = connect.AdoptConnector(i.ctx, i.Client, connector, i.Instance, i.Scheme); err != nil {
+		return false, err
+	}
+
+	if err = i.Instance.TransitionToInitialSync(cyndi.TableNameToPipelineVersion(*table)); err != nil {
+		return false, err
+	}
+
+	// the exported state tells whether the table was created with the current configuration
+	i.Instance.Status.CyndiConfigVersion = i.config.ConfigMapVersion
+	i.Instance.Status.SpecHash = i.config.SpecHash
+
+	if state, err := utils.FetchConfigMap(i.Client, i.Instance.Namespace, stateConfigMapName(i.Instance.Name)); err == nil && state.Data["tableName"] == *table {
+		i.Instance.Status.CyndiConfigVersion = state.Data["cyndiConfigVersion"]
+		i.Instance.Status.SpecHash = state.Data["specHash"]
+
+		if since, err := time.Parse(time.RFC3339, state.Data["clonedEventsSince"]); err == nil {
+			clonedEventsSince := metav1.NewTime(since)
+			i.Instance.Status.ClonedEventsSince = &clonedEventsSince
+		}
+	} else if err != nil && !k8errors.IsNotFound(err) {
+		return false, err
+	}
+
+	// the connector keeps consuming using its consumer group
+	i.Instance.Status.ConsumerGroup, _, _ = unstructured.NestedString(connector.Object, "spec", "config", "consumer.override.group.id")
+
+	i.eventNormal("Adopted", "Adopted table %s and connector %s", *table, connectorName)
+	return true, nil
+}
+
+/*
+ * Registers the inventory.hosts table of the pre-operator setup as the table of a new pipeline version.
+ * The table is renamed and replaced with the view. A connector is created for the table which, like during a refresh, replays the topic.
+ * Validation then decides whether the table is kept or the pipeline is refreshed.
+ */
+func (i *ReconcileIteration) adoptLegacyTable() error {
+	const legacyTable = "hosts"
+
+	if missing, err := i.AppDb.GetMissingViewColumns(legacyTable); err != nil {
+		return err
+	} else if len(missing) > 0 {
+		return fmt.Errorf("Table inventory.%s cannot be adopted as it lacks columns %s", legacyTable, strings.Join(missing, ", "))
+	}
+
+	if err := i.checkPrimaryKey(legacyTable); err != nil {
+		return err
+	}
+
+	pipelineVersion, err := i.newPipelineVersion()
+	if err != nil {
+		return err
+	}
+
+	if err := i.Instance.TransitionToInitialSync(pipelineVersion); err != nil {
+		return err
+	}
+
+	i.Instance.Status.CyndiConfigVersion = i.config.ConfigMapVersion
+	i.Instance.Status.SpecHash = i.config.SpecHash
+	i.Instance.Status.UnloggedTableServerStart = nil
+	i.Instance.Status.TableIndexesPending = false
+
+	i.Log.Info("Adopting legacy table", "table", i.Instance.Status.TableName)
+	if err := i.AppDb.AdoptLegacyHostsTable(i.Instance.Status.TableName); err != nil {
+		return err
+	}
+
+	i.probeViewReplaced(nil, fmt.Sprintf("adopted table inventory.%s of the pre-operator setup", legacyTable))
+
+	if _, err := i.createConnector(i.Instance.Status.ConnectorName, false); err != nil {
+		return err
+	}
+
+	i.probeConnectorCreated(i.Instance.Status.ConnectorName)
+	i.eventNormal("Adopted", "Adopted table inventory.%s of the pre-operator setup as %s", legacyTable, i.Instance.Status.TableName)
+	return nil
+}
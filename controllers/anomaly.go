@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+Detection of unusual changes of the host counts. The validation thresholds tolerate a constant difference between the host counts of the
+application table and HBI, so a sudden drop of the application table (e.g. a bulk deletion applied to it only) may go unnoticed as long as it stays within them.
+With validation.anomaly.enabled set, the difference (application - HBI) of each validation is compared to those of the previous validations (validation.anomaly.window).
+A difference further from their mean than validation.anomaly.sigma standard deviations (and at least validation.anomaly.min.hosts hosts) is reported
+using the CountAnomaly condition. The validity of the pipeline is not affected.
+
+*/
+
+const (
+	reasonHostCountAnomaly = "HostCountAnomaly"
+	reasonHostCountNormal  = "HostCountNormal"
+)
+
+// Records the difference between the given host counts and reports it if unusual compared to the recorded ones
+func (i *ReconcileIteration) detectCountAnomaly(hbiHostCount int64, appHostCount int64) {
+	cfg := i.config.AnomalyConfig
+	if !cfg.Enabled {
+		i.Instance.Status.HostCountDeltas = nil
+		i.Instance.ResetCountAnomaly()
+		return
+	}
+
+	// the table being seeded closes the gap steadily, which says nothing about the differences once it is in sync
+	if i.Instance.Status.InitialSyncInProgress {
+		return
+	}
+
+	delta := appHostCount - hbiHostCount
+	history := i.Instance.Status.HostCountDeltas
+
+	if int64(len(history)) >= cfg.Window {
+		mean, stddev := utils.MeanStdDev(history)
+		deviation := math.Abs(float64(delta) - mean)
+
+		if deviation >= float64(cfg.MinHosts) && deviation > float64(cfg.Sigma)*stddev {
+			message := fmt.Sprintf("Host count difference (application - HBI) of %d deviates by %.0f hosts from the mean (%.1f) of the last %d validations (standard deviation %.1f)",
+				delta, deviation, mean, len(history), stddev)
+
+			if condition := i.Instance.GetCountAnomaly(); condition == nil || condition.Status != metav1.ConditionTrue {
+				i.eventWarning("HostCountAnomaly", "%s", message)
+			}
+
+			i.Instance.SetCountAnomaly(metav1.ConditionTrue, reasonHostCountAnomaly, message)
+		} else {
+			i.Instance.SetCountAnomaly(metav1.ConditionFalse, reasonHostCountNormal, fmt.Sprintf("Host count difference (application - HBI) of %d is within the usual range", delta))
+		}
+	}
+
+	history = append(history, delta)
+	if excess := int64(len(history)) - cfg.Window; excess > 0 {
+		history = history[excess:]
+	}
+
+	i.Instance.Status.HostCountDeltas = history
+}
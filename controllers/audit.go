@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+)
+
+/*
+
+Audit trail of destructive actions. Every action is recorded as a Kubernetes Event and, if enabled, in the audit table of the application database.
+
+*/
+
+type auditAction string
+
+const (
+	auditTableDropped     auditAction = "TableDropped"
+	auditViewReplaced     auditAction = "ViewReplaced"
+	auditConnectorDeleted auditAction = "ConnectorDeleted"
+)
+
+// Failures to write the audit table are logged only so that they do not block the pipeline
+func (i *ReconcileIteration) audit(action auditAction, target string, reason string) {
+	i.Log.Info("Audit", "action", action, "target", target, "reason", reason)
+	i.eventNormal(string(action), "%s %s: %s", action, target, reason)
+
+	if !i.config.AuditTableEnabled || i.AppDb == nil {
+		return
+	}
+
+	record := database.AuditRecord{
+		Timestamp: time.Now().UTC(),
+		Pipeline:  i.pipelineName(),
+		Action:    string(action),
+		Target:    target,
+		Reason:    reason,
+	}
+
+	if err := i.AppDb.RecordAudit(record); err != nil {
+		i.Log.Error(err, "Error recording audit record", "action", action, "target", target)
+	}
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+
+Exponential backoff and circuit breaker for pipelines that keep failing to reconcile (e.g. because the application database is down).
+
+*/
+
+const (
+	reasonReconcileFailing = "ReconcileFailing"
+	reasonCircuitOpen      = "CircuitOpen"
+)
+
+// Returns how long the reconciliation of the pipeline remains suspended (if at all)
+func (i *ReconcileIteration) getThrottledFor(now time.Time) time.Duration {
+	until := i.Instance.Status.ThrottledUntil
+
+	// never block the finalizer
+	if until == nil || i.Instance.GetDeletionTimestamp() != nil {
+		return 0
+	}
+
+	return until.Sub(now)
+}
+
+/*
+ * Records the outcome of a reconcile attempt of the pipeline controller.
+ * After a failure the next attempt is delayed exponentially and, after too many consecutive failures, the circuit opens.
+ * The error has already been reported at this point. It is swallowed so that the delay computed here applies instead of the default rate limiting.
+ */
+func (i *ReconcileIteration) applyBackoff(result reconcile.Result, err error) (reconcile.Result, error) {
+	if err == nil || errors.IsConflict(err) {
+		return result, err
+	}
+
+	backoff := i.getBackoffConfig()
+
+	// the failed attempt may have left the status partially modified - only the throttling fields and step conditions are persisted
+	instance, fetchErr := utils.FetchCyndiPipeline(i.Client, client.ObjectKeyFromObject(i.Instance))
+	if fetchErr != nil {
+		return result, err
+	}
+
+	// the outcome of the individual steps is persisted so that the failure does not mask the state of the other steps
+	i.copyStepConditions(instance)
+
+	// unavailable infrastructure is reported separately from other failures
+	if reason := degradedReason(err); reason != "" {
+		i.markDegraded(instance, reason, err.Error())
+	}
+
+	failures := instance.Status.ConsecutiveFailures + 1
+	delay, circuitOpen := backoff.Delay(failures)
+	until := metav1.NewTime(time.Now().Add(delay))
+
+	if circuitOpen {
+		previous := instance.GetThrottled()
+		if previous == nil || previous.Reason != reasonCircuitOpen {
+			i.eventWarning(reasonCircuitOpen, "Reconciliation suspended after %d consecutive failures", failures)
+		}
+
+		instance.SetThrottled(reasonCircuitOpen, fmt.Sprintf("Reconcile failed %d times in a row, suspended until %s: %s", failures, until.Format(time.RFC3339), err.Error()), until)
+	} else {
+		instance.SetThrottled(reasonReconcileFailing, fmt.Sprintf("Reconcile failed %d times in a row, retrying at %s: %s", failures, until.Format(time.RFC3339), err.Error()), until)
+	}
+
+	if updateErr := i.Client.Status().Update(i.ctx, instance); updateErr != nil {
+		i.Log.Error(updateErr, "Error recording reconcile failure")
+		return result, err
+	}
+
+	metrics.PipelineDegraded(instance)
+	i.Log.Info("Throttling pipeline", "failures", failures, "delay", delay, "circuitOpen", circuitOpen)
+	return reconcile.Result{RequeueAfter: delay}, nil
+}
+
+// Falls back to defaults if the configuration could not be loaded (which may be the reason of the failure)
+func (i *ReconcileIteration) getBackoffConfig() config.BackoffConfiguration {
+	if i.config != nil {
+		return i.config.BackoffConfig
+	}
+
+	defaults, _ := config.BuildCyndiConfig(nil, nil)
+	return defaults.BackoffConfig
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+Block-hash validation (validation.strategy=blockhash). Both databases compute digests of blocks of host ids.
+Blocks whose digests differ are split into smaller blocks until they are small enough (validation.block.size) for their ids to be fetched and compared.
+The operator therefore only transfers ids of the parts of the table that are out of sync.
+
+*/
+
+// Statistics of a block-hash comparison
+type blockComparison struct {
+	blocksCompared int
+	idsFetched     int
+}
+
+func (i *ReconcileIteration) getHbiIdBlocks(source database.BlockHashSource, prefix string) ([]database.IdBlock, error) {
+	var blockSets [][]database.IdBlock
+	for _, filters := range i.hbiFilters() {
+		blocks, err := source.GetIdBlocks(inventoryTableName, i.Instance.Spec.InsightsOnly, filters, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		blockSets = append(blockSets, blocks)
+	}
+
+	return database.MergeIdBlocks(blockSets...), nil
+}
+
+// Compares the host ids of the blocks starting with the given prefix, drilling into blocks that differ
+func (i *ReconcileIteration) compareIdBlocks(source database.BlockHashSource, appTable string, prefix string, stats *blockComparison, result *idComparison) error {
+	hbiBlocks, err := i.getHbiIdBlocks(source, prefix)
+	if err != nil {
+		return err
+	}
+
+	appBlocks, err := i.AppDb.GetIdBlocks(appTable, false, []map[string]string{}, prefix)
+	if err != nil {
+		return err
+	}
+
+	hbi := map[string]database.IdBlock{}
+	for _, block := range hbiBlocks {
+		hbi[block.Prefix] = block
+	}
+
+	app := map[string]database.IdBlock{}
+	for _, block := range appBlocks {
+		app[block.Prefix] = block
+	}
+
+	for _, block := range database.MergeIdBlocks(hbiBlocks, appBlocks) {
+		stats.blocksCompared++
+
+		hbiBlock, inHbi := hbi[block.Prefix]
+		appBlock, inApp := app[block.Prefix]
+		if inHbi && inApp && hbiBlock.Equal(appBlock) {
+			continue
+		}
+
+		if (hbiBlock.Count <= i.config.ValidationBlockSize && appBlock.Count <= i.config.ValidationBlockSize) || len(block.Prefix) >= database.MaxIdBlockPrefixLength {
+			err = i.compareBlockIds(appTable, block.Prefix, stats, result)
+		} else {
+			err = i.compareIdBlocks(source, appTable, block.Prefix, stats, result)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Fetches and compares the host ids of a single block
+func (i *ReconcileIteration) compareBlockIds(appTable string, prefix string, stats *blockComparison, result *idComparison) error {
+	filter := database.IdPrefixFilter(prefix)
+
+	hbiIds, err := i.getHbiHostIds(filter)
+	if err != nil {
+		return err
+	}
+
+	appIds, err := i.AppDb.GetHostIds(appTable, false, []map[string]string{filter})
+	if err != nil {
+		return err
+	}
+
+	stats.idsFetched += len(hbiIds) + len(appIds)
+
+	for _, id := range utils.Difference(hbiIds, appIds) {
+		result.addInHbiOnly(id)
+	}
+
+	for _, id := range utils.Difference(appIds, hbiIds) {
+		result.addInAppOnly(id)
+	}
+
+	return nil
+}
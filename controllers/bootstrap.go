@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"fmt"
+)
+
+/*
+
+Bootstrap of application databases (db.bootstrap.enabled). The inventory schema, extensions and roles the pipeline depends on are created
+the first time a pipeline using the database is reconciled. Databases are only bootstrapped once per operator process as the bootstrap
+statements are idempotent but not free.
+
+*/
+
+func (i *ReconcileIteration) appDbKey() string {
+	return fmt.Sprintf("%s:%s/%s", i.AppDBParams.Host, i.AppDBParams.Port, i.AppDBParams.Name)
+}
+
+func (i *ReconcileIteration) bootstrapAppDb() error {
+	if !i.config.DBBootstrapEnabled || i.bootstrapped == nil {
+		return nil
+	}
+
+	key := i.appDbKey()
+	if _, done := i.bootstrapped.Load(key); done {
+		return nil
+	}
+
+	if i.skipMutation("Not bootstrapping the database") {
+		return nil
+	}
+
+	i.Log.Info("Bootstrapping database", "database", key, "extensions", i.config.DBBootstrapExtensions)
+	if err := i.AppDb.Bootstrap(i.config.DBBootstrapExtensions); err != nil {
+		return err
+	}
+
+	i.bootstrapped.Store(key, true)
+	return nil
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/kafka"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+
+	"github.com/google/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+End-to-end replication latency measured using a synthetic ("canary") host.
+The host is published (through Kafka Bridge) to a topic consumed by the connector the same way HBI publishes host events.
+The time it takes the host to appear in the application table is recorded in the cyndi_replication_latency_seconds histogram.
+The host is removed using a delete event afterwards.
+
+*/
+
+const (
+	// canary hosts belong to an org that does not exist so that they are never visible to users of the application
+	canaryOrgID    = "cyndi-canary"
+	canaryReporter = "cyndi-operator"
+
+	// how often (in seconds) the pipeline is reconciled while a canary host is on its way
+	canaryPollInterval int64 = 10
+)
+
+type canaryHostEvent struct {
+	Type             string                 `json:"type"`
+	Timestamp        string                 `json:"timestamp"`
+	Host             map[string]interface{} `json:"host"`
+	PlatformMetadata map[string]interface{} `json:"platform_metadata"`
+}
+
+type canaryDeleteEvent struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	Timestamp string `json:"timestamp"`
+}
+
+func newCanaryHostEvent(pipelineName string, id string, insightsID string, now time.Time) canaryHostEvent {
+	timestamp := now.UTC().Format(time.RFC3339Nano)
+
+	return canaryHostEvent{
+		Type:      "created",
+		Timestamp: timestamp,
+		Host: map[string]interface{}{
+			"id":                     id,
+			"org_id":                 canaryOrgID,
+			"display_name":           "cyndi-canary-" + pipelineName,
+			"insights_id":            insightsID,
+			"created":                timestamp,
+			"updated":                timestamp,
+			"stale_timestamp":        now.Add(24 * time.Hour).UTC().Format(time.RFC3339Nano),
+			"reporter":               canaryReporter,
+			"tags":                   []interface{}{},
+			"system_profile":         map[string]interface{}{},
+			"per_reporter_staleness": map[string]interface{}{},
+			"groups":                 []interface{}{},
+		},
+		PlatformMetadata: map[string]interface{}{},
+	}
+}
+
+/*
+ * Publishes a new canary host once the previous one arrived (or timed out) and the canary interval elapsed.
+ * While a canary host is on its way the application table is checked for it on each reconcile.
+ */
+func (i *ReconcileIteration) runCanary() error {
+	cfg := i.config.CanaryConfig
+	status := i.Instance.Status.Canary
+	now := time.Now()
+
+	if status != nil && status.HostID != "" {
+		return i.checkCanary(status, now)
+	}
+
+	if status != nil && status.LastCompletedAt != nil && now.Sub(status.LastCompletedAt.Time) < time.Duration(cfg.Interval)*time.Second {
+		return nil
+	}
+
+	if i.skipMutation("Not publishing canary host") {
+		return nil
+	}
+
+	id := uuid.New().String()
+	insightsID := uuid.New().String()
+	event := newCanaryHostEvent(i.Instance.Name, id, insightsID, now)
+
+	if err := kafka.Produce(i.ctx, cfg.BridgeURL, cfg.Topic, id, event, map[string]string{"event_type": "created", "insights_id": insightsID}); err != nil {
+		return err
+	}
+
+	i.debug("Canary host published", "host", id, "topic", cfg.Topic)
+
+	if status == nil {
+		status = &cyndi.CanaryStatus{}
+		i.Instance.Status.Canary = status
+	}
+
+	published := metav1.NewTime(now)
+	status.HostID = id
+	status.PublishedAt = &published
+	i.pollCanary()
+	return nil
+}
+
+func (i *ReconcileIteration) checkCanary(status *cyndi.CanaryStatus, now time.Time) error {
+	exists, err := i.AppDb.HostExists(i.Instance.Status.TableName, status.HostID)
+	if err != nil {
+		return err
+	}
+
+	latency := now.Sub(status.PublishedAt.Time)
+
+	if exists {
+		seconds := int64(latency.Seconds())
+		status.LastLatencySeconds = &seconds
+		metrics.ReplicationLatency(i.Instance, latency)
+		i.debug("Canary host arrived", "host", status.HostID, "latency", latency)
+	} else if latency < time.Duration(i.config.CanaryConfig.Timeout)*time.Second {
+		i.pollCanary()
+		return nil
+	} else {
+		i.eventWarning("CanaryTimedOut", "Canary host %s did not appear in %s within %d seconds", status.HostID, i.Instance.Status.TableName, i.config.CanaryConfig.Timeout)
+		metrics.CanaryTimedOut(i.Instance)
+	}
+
+	// a canary host that timed out is removed as well in case it arrives later
+	if !i.skipMutation("Not removing canary host", "host", status.HostID) {
+		deleteEvent := canaryDeleteEvent{Type: "delete", ID: status.HostID, Timestamp: now.UTC().Format(time.RFC3339Nano)}
+		if err = kafka.Produce(i.ctx, i.config.CanaryConfig.BridgeURL, i.config.CanaryConfig.Topic, status.HostID, deleteEvent, map[string]string{"event_type": "delete"}); err != nil {
+			return err
+		}
+	}
+
+	completed := metav1.NewTime(now)
+	status.HostID = ""
+	status.PublishedAt = nil
+	status.LastCompletedAt = &completed
+	return nil
+}
+
+// the standard reconcile interval is too coarse to measure latency
+func (i *ReconcileIteration) pollCanary() {
+	i.GetRequeueInterval = func(i *ReconcileIteration) int64 {
+		if i.config.StandardInterval < canaryPollInterval {
+			return i.config.StandardInterval
+		}
+
+		return canaryPollInterval
+	}
+}
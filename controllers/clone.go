@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+Cloning shortens the refresh of a pipeline whose data is mostly correct (e.g. when the refresh was triggered by a configuration change).
+Instead of replaying the entire topic into an empty table, rows of the previously active table are copied to the new table
+and the connector only applies events produced shortly before the copy was made.
+
+*/
+
+/*
+ * Copies rows of the active table to the table being seeded if enabled and the active table belonged to a valid pipeline.
+ * Unless the connector resumes from the offsets of the previous connector, it is set up to skip events that are older than the copy.
+ */
+func (i *ReconcileIteration) cloneActiveTable(resumingOffsets bool) (cloned bool, err error) {
+	source := i.Instance.Status.CloneSourceTable
+	i.Instance.Status.CloneSourceTable = ""
+
+	if !i.config.DBTableCloneEnabled || source == "" {
+		return false, nil
+	}
+
+	active, err := i.AppDb.GetCurrentTable()
+	if err != nil {
+		return false, err
+	} else if active == nil || *active != source {
+		i.Log.Info("Not cloning table as it is no longer active", "table", source)
+		return false, nil
+	}
+
+	// events produced shortly before the copy is made may not have been applied to the source table yet
+	since := metav1.NewTime(time.Now().Add(-time.Duration(i.config.DBTableCloneMargin) * time.Second))
+
+	count, err := i.AppDb.CloneTable(i.Instance.Status.TableName, source)
+	if err != nil {
+		// not fatal - the connector populates the table from scratch instead
+		i.Log.Error(err, "Failed to clone table", "table", source)
+		return false, nil
+	}
+
+	if resumingOffsets {
+		i.eventNormal("TableCloned", "Copied %d hosts from %s to %s. Resuming from the offsets of the previous connector", count, source, i.Instance.Status.TableName)
+		return true, nil
+	}
+
+	i.Instance.Status.ClonedEventsSince = &since
+	i.eventNormal("TableCloned", "Copied %d hosts from %s to %s. Applying events since %s", count, source, i.Instance.Status.TableName, since.Format(time.RFC3339))
+	return true, nil
+}
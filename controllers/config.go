@@ -3,10 +3,14 @@ package controllers
 import (
 	"fmt"
 
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
 	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/connect"
 	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 /*
@@ -18,24 +22,94 @@ Code for loading configuration, secrets, etc.
 const configMapName = "cyndi"
 const globalConfigNamespace = "cyndi"
 
-func (i *ReconcileIteration) parseConfig() (err error) {
+// Name of the cluster-scoped CyndiConfig resource
+const cyndiConfigName = "cyndi"
+
+/*
+ * Configuration is layered as follows (later sources take precedence):
+ * CyndiConfig defaults, CyndiConfig namespace defaults, the global cyndi ConfigMap, the cyndi ConfigMap of the pipeline's namespace and finally the pipeline's spec.
+ */
+func buildPipelineConfig(c client.Client, instance *cyndi.CyndiPipeline) (*config.CyndiConfiguration, error) {
+	data, err := loadConfigData(c, instance.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := config.BuildCyndiConfig(instance, data)
+	if err != nil {
+		return result, fmt.Errorf("Error parsing %s configmap in %s: %w", configMapName, instance.Namespace, err)
+	}
+
+	return result, nil
+}
+
+// Merges the configuration layers below the pipeline's spec. Only the global layers are merged if the namespace is empty
+func loadConfigData(c client.Client, namespace string) (map[string]string, error) {
 	configMaps := []map[string]string{}
 
-	for _, namespace := range []string{globalConfigNamespace, i.Instance.Namespace} {
-		if cyndiConfig, err := utils.FetchConfigMap(i.Client, namespace, configMapName); err != nil {
+	if cyndiConfig, err := utils.FetchCyndiConfig(c, cyndiConfigName); err != nil {
+		// the CyndiConfig CRD may not be installed
+		if !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return nil, err
+		}
+	} else {
+		configMaps = append(configMaps, config.CyndiConfigData(cyndiConfig, namespace))
+	}
+
+	namespaces := []string{globalConfigNamespace}
+	if namespace != "" {
+		namespaces = append(namespaces, namespace)
+	}
+
+	for _, ns := range namespaces {
+		if cyndiConfig, err := utils.FetchConfigMap(c, ns, configMapName); err != nil {
 			if !errors.IsNotFound(err) {
-				return err
+				return nil, err
 			}
 		} else if cyndiConfig != nil {
 			configMaps = append(configMaps, (*cyndiConfig).Data)
 		}
 	}
 
-	i.config, err = config.BuildCyndiConfig(i.Instance, utils.Merge(configMaps...))
+	return utils.Merge(configMaps...), nil
+}
+
+func (i *ReconcileIteration) parseConfig() (err error) {
+	if i.config, err = buildPipelineConfig(i.Client, i.Instance); err != nil {
+		return err
+	}
+
+	if template := i.Instance.Spec.ConnectorTemplate; template != nil && template.ConfigMapRef != nil {
+		if i.config.ConnectorTemplate, err = loadConnectorTemplate(i.Client, i.Instance.Namespace, *template.ConfigMapRef); err != nil {
+			return err
+		}
+	}
+
+	if i.config.SourceConnectorTemplate != "" {
+		if err = connect.ValidateSourceTemplate(i.config.SourceConnectorTemplate); err != nil {
+			return err
+		}
+	}
 
-	if err != nil {
-		return fmt.Errorf("Error parsing %s configmap in %s: %w", configMapName, i.Instance.Namespace, err)
+	// the sink connector is not known when the template of a pipeline is validated by the webhook
+	if err = connect.ValidateTemplateSink(i.config.ConnectorTemplate, i.connectorSink()); err != nil {
+		return err
 	}
 
+	i.Instance.Status.ActiveConfig, err = activeConfig(i.config)
 	return err
 }
+
+func loadConnectorTemplate(c client.Client, namespace string, ref cyndi.ConfigMapKeyReference) (string, error) {
+	configMap, err := utils.FetchConfigMap(c, namespace, ref.Name)
+	if err != nil {
+		return "", fmt.Errorf("Error loading connector template from %s configmap: %w", ref.Name, err)
+	}
+
+	template, ok := configMap.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("Key %s missing from %s configmap", ref.Key, ref.Name)
+	}
+
+	return template, nil
+}
@@ -1,12 +1,17 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
 
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
 	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
 
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -15,8 +20,119 @@ const (
 	validationInterval            = "validation.interval"
 	validationAttemptsThreshold   = "validation.attempts.threshold"
 	validationPercentageThreshold = "validation.percentage.threshold"
+	validationCountThreshold      = "validation.count.threshold"
+	validationThresholdMode       = "validation.threshold.mode"
+	validationLingeringThreshold  = "validation.lingering.threshold"
+	initialSyncStuckTimeout       = "init.stuck.timeout"
+	initialSyncStuckAction        = "init.stuck.action"
+	initialSyncThroughputMin      = "init.throughput.min"
+	initialSyncTimeout            = "init.timeout"
+	initialSyncTimeoutAction      = "init.timeout.action"
+	validationDiffEnabled         = "validation.diff.enabled"
+	validationDiffMaxIds          = "validation.diff.max.ids"
+	validationStrategy            = "validation.strategy"
+	validationBlockSize           = "validation.block.size"
+	validationSamplePercentage    = "validation.sample.percentage"
+	validationMemoryBudget        = "validation.memory.budget"
+	validationHBIMinCount         = "validation.hbi.min.count"
+	validationOrgCountsTop        = "validation.org.counts.top"
+	validationReporterCounts      = "validation.reporter.counts.enabled"
+	validationXjoinEnabled        = "validation.xjoin.enabled"
+	validationSkipMaxDuration     = "validation.skip.max.duration"
+	validationShadowCycles        = "validation.shadow.cycles"
+	validationAnomalyEnabled      = "validation.anomaly.enabled"
+	validationAnomalyWindow       = "validation.anomaly.window"
+	validationAnomalySigma        = "validation.anomaly.sigma"
+	validationAnomalyMinHosts     = "validation.anomaly.min.hosts"
+	validationSlowQueryThreshold  = "validation.slow.query.threshold"
+	validationMismatchedIdsMax    = "validation.mismatched.ids.max"
+	validationMismatchedIdsSample = "validation.mismatched.ids.sample"
+	validationMismatchedIdsLog    = "validation.mismatched.ids.log"
+	validationMode                = "validation.mode"
+	validationCronJobImage        = "validation.cronjob.image"
+	validationCronJobSchedule     = "validation.cronjob.schedule"
+	validationCronJobSA           = "validation.cronjob.service.account"
+	validationCronJobNodeSelector = "validation.cronjob.node.selector"
+	validationCronJobTolerations  = "validation.cronjob.tolerations"
+	validationCronJobMemoryLimit  = "validation.cronjob.memory.limit"
+	viewSwitchMaxShrink           = "view.switch.max.shrink"
+	monitoringDashboardEnabled    = "monitoring.dashboard.enabled"
+	monitoringRulesEnabled        = "monitoring.rules.enabled"
+	monitoringLabels              = "monitoring.labels"
+	alertmanagerURL               = "alertmanager.url"
+	alertmanagerSilenceDuration   = "alertmanager.silence.duration"
+	auditTableEnabled             = "audit.table.enabled"
+	driftReportEnabled            = "drift.report.enabled"
+	backoffBaseInterval           = "backoff.base.interval"
+	backoffMaxInterval            = "backoff.max.interval"
+	circuitFailureThreshold       = "circuit.failure.threshold"
+	circuitOpenInterval           = "circuit.open.interval"
+	dbRetryAttempts               = "db.retry.attempts"
+	dbRetryBaseInterval           = "db.retry.base.interval.ms"
+	dbTableUnlogged               = "db.table.unlogged"
+	dbTableStorageParameters      = "db.table.storage.parameters"
+	dbTableCompression            = "db.table.compression"
+	dbTableCloneEnabled           = "db.table.clone.enabled"
+	dbTableCloneMargin            = "db.table.clone.margin"
+	dbTableRetention              = "db.table.retention"
+	dbBootstrapEnabled            = "db.bootstrap.enabled"
+	dbBootstrapExtensions         = "db.bootstrap.extensions"
+	refreshStrategy               = "refresh.strategy"
+	refreshHandoverGracePeriod    = "refresh.handover.grace.period"
+	connectorFlavor               = "connector.flavor"
+	connectorSourceConfig         = "connector.source.config"
+	connectorSourceClass          = "connector.source.class"
+	connectorSourceEnvPrefix      = "connector.source.env.prefix"
+	sourceReplicationSlots        = "source.replication.slots"
+	sourcePublications            = "source.publications"
+	sourceSlotLagThreshold        = "source.slot.lag.threshold"
+	sourceSlotRetentionThreshold  = "source.slot.retention.threshold"
+	connectorClass                = "connector.class"
+	connectorKeyMapping           = "connector.key.mapping"
+	connectorRequiredKeys         = "connector.required.keys"
+	connectorDeleteEnabled        = "connector.delete.enabled"
+	connectorTombstones           = "connector.tombstones"
+	connectorPKMode               = "connector.pk.mode"
+	connectorPKFields             = "connector.pk.fields"
+	connectorConsumerGroup        = "connector.consumer.group.template"
+	connectorAutoOffsetReset      = "connector.auto.offset.reset"
+	stateExportEnabled            = "state.export.enabled"
+	dbDialect                     = "db.dialect"
+	dbColumnTypeId                = "db.column.type.id"
+	dbColumnTypeTimestamp         = "db.column.type.timestamp"
+	dbDisplayNameSearch           = "db.displayname.search"
+	inventorySource               = "inventory.source"
+	inventoryAPIURL               = "inventory.api.url"
+	inventoryAPISecret            = "inventory.api.secret"
+	validationLagPrometheusURL    = "validation.lag.prometheus.url"
+	validationLoadMaxActive       = "validation.load.max.active"
+	validationLoadHealthURL       = "validation.load.health.url"
+	validationLoadDeferInterval   = "validation.load.defer.interval"
+	smokeTestEnabled              = "smoketest.enabled"
+	smokeTestImage                = "smoketest.image"
+	canaryEnabled                 = "canary.enabled"
+	canaryBridgeURL               = "canary.bridge.url"
+	canaryTopic                   = "canary.topic"
+	canaryInterval                = "canary.interval"
+	canaryTimeout                 = "canary.timeout"
+	networkPolicyEnabled          = "networkpolicy.enabled"
+	topicPreflightEnabled         = "topic.preflight.enabled"
+	namingTemplate                = "naming.template"
 )
 
+var (
+	// e.g. fillfactor or toast.autovacuum_enabled
+	storageParameterName  = regexp.MustCompile(`^[a-z_]+(\.[a-z_]+)?$`)
+	storageParameterValue = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+	viewColumnName        = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+	envVariablePrefix     = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+)
+
+var compressionMethods = []string{"pglz", "lz4"}
+
+// prefixes of the label and annotation keys of connectors set by the operator and by Strimzi
+var reservedConnectorKeyPrefixes = []string{"cyndi/", "cyndi.cloud.redhat.com/", "strimzi.io/"}
+
 // These keys are excluded when computing a ConfigMap hash.
 // Therefore, if they change that won't trigger a pipeline refresh
 var keysIgnoredByRefresh = []string{
@@ -24,16 +140,125 @@ var keysIgnoredByRefresh = []string{
 	validationInterval,
 	validationAttemptsThreshold,
 	validationPercentageThreshold,
+	validationCountThreshold,
+	validationThresholdMode,
+	validationLingeringThreshold,
 	fmt.Sprintf("init.%s", validationInterval),
 	fmt.Sprintf("init.%s", validationAttemptsThreshold),
 	fmt.Sprintf("init.%s", validationPercentageThreshold),
+	fmt.Sprintf("init.%s", validationCountThreshold),
+	fmt.Sprintf("init.%s", validationThresholdMode),
+	fmt.Sprintf("init.%s", validationLingeringThreshold),
+	initialSyncStuckTimeout,
+	initialSyncStuckAction,
+	initialSyncThroughputMin,
+	initialSyncTimeout,
+	initialSyncTimeoutAction,
+	validationDiffEnabled,
+	validationDiffMaxIds,
+	validationStrategy,
+	validationBlockSize,
+	validationSamplePercentage,
+	validationMemoryBudget,
+	validationHBIMinCount,
+	validationOrgCountsTop,
+	validationReporterCounts,
+	validationXjoinEnabled,
+	validationSkipMaxDuration,
+	validationShadowCycles,
+	validationAnomalyEnabled,
+	validationAnomalyWindow,
+	validationAnomalySigma,
+	validationAnomalyMinHosts,
+	validationSlowQueryThreshold,
+	validationMismatchedIdsMax,
+	validationMismatchedIdsSample,
+	validationMismatchedIdsLog,
+	validationMode,
+	validationCronJobImage,
+	validationCronJobSchedule,
+	validationCronJobSA,
+	validationCronJobNodeSelector,
+	validationCronJobTolerations,
+	validationCronJobMemoryLimit,
+	viewSwitchMaxShrink,
+	validationLagPrometheusURL,
+	validationLoadMaxActive,
+	validationLoadHealthURL,
+	validationLoadDeferInterval,
+	monitoringDashboardEnabled,
+	monitoringRulesEnabled,
+	monitoringLabels,
+	alertmanagerURL,
+	alertmanagerSilenceDuration,
+	auditTableEnabled,
+	driftReportEnabled,
+	backoffBaseInterval,
+	backoffMaxInterval,
+	circuitFailureThreshold,
+	circuitOpenInterval,
+	dbRetryAttempts,
+	dbRetryBaseInterval,
+	dbTableUnlogged,
+	dbTableCloneEnabled,
+	dbTableCloneMargin,
+	dbTableRetention,
+	dbBootstrapEnabled,
+	dbBootstrapExtensions,
+	refreshStrategy,
+	refreshHandoverGracePeriod,
+	stateExportEnabled,
+	smokeTestEnabled,
+	smokeTestImage,
+	canaryEnabled,
+	canaryBridgeURL,
+	canaryTopic,
+	canaryInterval,
+	canaryTimeout,
+	networkPolicyEnabled,
+	topicPreflightEnabled,
+	sourceReplicationSlots,
+	sourcePublications,
+	sourceSlotLagThreshold,
+	sourceSlotRetentionThreshold,
+	namingTemplate,
+}
+
+// Returns a copy of the spec with fields that should not trigger a pipeline refresh left out
+func specIgnoredByRefresh(spec cyndi.CyndiPipelineSpec) cyndi.CyndiPipelineSpec {
+	result := spec.DeepCopy()
+	result.ValidationInterval = nil
+	result.InitValidationInterval = nil
+	result.MaintenanceWindows = nil
+	result.ValidationWindows = nil
+	result.ValidationCountThreshold = nil
+	result.ValidationThresholdMode = nil
+	result.ValidationStrategy = nil
+	result.AdoptExisting = false
+	// applied without a refresh
+	result.ValidationThreshold = nil
+	result.InventoryDbSecret = nil
+	result.InventoryDbSecrets = nil
+	result.DBGrants = nil
+	result.ViewStaleness = nil
+	result.ConnectorLabels = nil
+	result.ConnectorAnnotations = nil
+	result.DependsOn = nil
+	result.InitialSyncTimeout = nil
+	// triggers a refresh of its own (see status.lastResyncAt)
+	result.ResyncRequestedAt = nil
+	return *result
 }
 
 func BuildCyndiConfig(instance *cyndi.CyndiPipeline, cm map[string]string) (*CyndiConfiguration, error) {
 	var err error
 	config := &CyndiConfiguration{}
 
-	if instance != nil && instance.Spec.Topic != nil {
+	if instance != nil && len(instance.Spec.Topics) > 0 {
+		if err = parseTopics(config, instance.Spec.Topics); err != nil {
+			return config, err
+		}
+	} else if instance != nil && instance.Spec.Topic != nil {
 		config.Topic = *instance.Spec.Topic
 	} else {
 		config.Topic = getStringValue(cm, "connector.topic", defaultTopic)
@@ -45,19 +270,106 @@ func BuildCyndiConfig(instance *cyndi.CyndiPipeline, cm map[string]string) (*Cyn
 		config.ConnectCluster = getStringValue(cm, "connect.cluster", defaultConnectCluster)
 	}
 
+	// a Connect cluster in a different namespace is referenced as namespace/name
+	if parts := strings.Split(config.ConnectCluster, "/"); len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+		config.ConnectClusterNamespace, config.ConnectCluster = parts[0], parts[1]
+	} else if len(parts) != 1 {
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.ConnectCluster, "connect.cluster")
+	}
+
 	if instance != nil && instance.Spec.InventoryDbSecret != nil {
 		config.InventoryDbSecret = *instance.Spec.InventoryDbSecret
 	} else {
 		config.InventoryDbSecret = getStringValue(cm, "inventory.dbSecret", defaultInventoryDbSecret)
 	}
 
+	// HBI sharded across several databases
+	config.InventoryDbSecrets = []string{config.InventoryDbSecret}
+
+	if instance != nil && len(instance.Spec.InventoryDbSecrets) > 0 {
+		config.InventoryDbSecrets = instance.Spec.InventoryDbSecrets
+	} else if value := getStringValue(cm, "inventory.dbSecrets", ""); value != "" && (instance == nil || instance.Spec.InventoryDbSecret == nil) {
+		config.InventoryDbSecrets = nil
+
+		for _, secret := range strings.Split(value, ",") {
+			if secret = strings.TrimSpace(secret); secret != "" {
+				config.InventoryDbSecrets = append(config.InventoryDbSecrets, secret)
+			}
+		}
+
+		if len(config.InventoryDbSecrets) == 0 {
+			return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, value, "inventory.dbSecrets")
+		}
+	}
+
+	config.InventoryDbSecret = config.InventoryDbSecrets[0]
+
+	config.InventorySource = InventorySource(getStringValue(cm, inventorySource, string(defaultInventorySource)))
+
+	switch config.InventorySource {
+	case InventorySourceDatabase:
+	case InventorySourceAPI:
+		// SQL filters cannot be translated to API queries
+		if instance != nil && len(instance.Spec.AdditionalFilters) > 0 {
+			return config, fmt.Errorf(`"%s" is not supported by the %s inventory source`, "additionalFilters", config.InventorySource)
+		} else if len(config.TopicFilters) > 0 {
+			return config, fmt.Errorf(`"%s" is not supported by the %s inventory source`, "topics[].where", config.InventorySource)
+		}
+	default:
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.InventorySource, inventorySource)
+	}
+
+	config.InventoryAPIURL = getStringValue(cm, inventoryAPIURL, "")
+	config.InventoryAPISecret = getStringValue(cm, inventoryAPISecret, "")
+
+	if config.ValidationXjoinEnabled, err = getBoolValue(cm, validationXjoinEnabled, defaultValidationXjoinEnabled); err != nil {
+		return config, err
+	}
+
+	if (config.InventorySource == InventorySourceAPI || config.ValidationXjoinEnabled) && config.InventoryAPIURL == "" {
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.InventoryAPIURL, inventoryAPIURL)
+	}
+
+	if err = parseValidationStrategy(config, instance, cm); err != nil {
+		return config, err
+	}
+
+	if err = parseSourceConnector(config, instance, cm); err != nil {
+		return config, err
+	}
+
+	if err = parseSourceHealth(config, cm); err != nil {
+		return config, err
+	}
+
 	if config.TopicReplicationFactor, err = getIntValue(cm, "connector.topic.replication.factor", defaultTopicReplicationFactor); err != nil {
 		return config, err
 	}
 
 	config.DeadLetterQueueTopicName = getStringValue(cm, "connector.deadletterqueue.topic.name", defaultDeadLetterQueueTopicName)
 
-	config.ConnectorTemplate = getStringValue(cm, "connector.config", defaultConnectorTemplate)
+	if instance != nil && instance.Spec.ConnectorTemplate != nil && instance.Spec.ConnectorTemplate.Inline != "" {
+		config.ConnectorTemplate = instance.Spec.ConnectorTemplate.Inline
+	} else {
+		// a template referenced by the spec is loaded by the caller
+		config.ConnectorTemplate = getStringValue(cm, "connector.config", defaultConnectorTemplate)
+	}
+
+	config.ConnectorFlavor = ConnectorFlavor(getStringValue(cm, connectorFlavor, string(defaultConnectorFlavor)))
+
+	switch config.ConnectorFlavor {
+	case ConnectorFlavorConfluent, ConnectorFlavorCloudNative:
+	default:
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.ConnectorFlavor, connectorFlavor)
+	}
+
+	if err = parseConnectorClass(config, cm); err != nil {
+		return config, err
+	}
+
+	if err = parseConnectorMetadata(config, instance); err != nil {
+		return config, err
+	}
 
 	if config.ConnectorTasksMax, err = getIntValue(cm, "connector.tasks.max", defaultConnectorTasksMax); err != nil {
 		return config, err
@@ -75,14 +387,127 @@ func BuildCyndiConfig(instance *cyndi.CyndiPipeline, cm map[string]string) (*Cyn
 
 	config.ConnectorAllowlistSystemProfile = getStringValue(cm, "connector.allowlist.sp", defaultAllowlistSystemProfile)
 
+	if err = parseDeleteHandling(config, instance, cm); err != nil {
+		return config, err
+	}
+
+	if err = parseConsumerGroup(config, instance, cm); err != nil {
+		return config, err
+	}
+
+	if instance != nil && instance.Spec.DBDialect != nil {
+		config.DBDialect = DBDialect(*instance.Spec.DBDialect)
+	} else {
+		config.DBDialect = DBDialect(getStringValue(cm, dbDialect, string(defaultDBDialect)))
+	}
+
+	switch config.DBDialect {
+	case DBDialectPostgres, DBDialectCockroach:
+	default:
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.DBDialect, dbDialect)
+	}
+
+	if err = parseDBAuth(config, instance); err != nil {
+		return config, err
+	}
+
 	if instance != nil && instance.Spec.DBTableIndexSQL != "" {
 		config.DBTableIndexSQL = instance.Spec.DBTableIndexSQL
+	} else if config.DBDialect == DBDialectCockroach {
+		config.DBTableIndexSQL = defaultCockroachDBTableIndexSQL
 	} else {
 		config.DBTableIndexSQL = defaultDBTableIndexSQL
 	}
 
 	config.DBTableInitScript = getStringValue(cm, "db.schema", defaultDBTableInitScript)
 
+	config.DBColumnTypes.Id = IdColumnType(getStringValue(cm, dbColumnTypeId, string(defaultDBColumnTypeId)))
+
+	switch config.DBColumnTypes.Id {
+	case IdColumnTypeUUID, IdColumnTypeText:
+	default:
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.DBColumnTypes.Id, dbColumnTypeId)
+	}
+
+	config.DBColumnTypes.Timestamp = TimestampColumnType(getStringValue(cm, dbColumnTypeTimestamp, string(defaultDBColumnTypeTimestamp)))
+
+	switch config.DBColumnTypes.Timestamp {
+	case TimestampColumnTypeTZ, TimestampColumnTypeNoTZ:
+	default:
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.DBColumnTypes.Timestamp, dbColumnTypeTimestamp)
+	}
+
+	config.DBDisplayNameSearch = DisplayNameSearch(getStringValue(cm, dbDisplayNameSearch, string(defaultDBDisplayNameSearch)))
+
+	switch config.DBDisplayNameSearch {
+	case DisplayNameSearchExact, DisplayNameSearchCitext, DisplayNameSearchLowerIndex:
+	default:
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.DBDisplayNameSearch, dbDisplayNameSearch)
+	}
+
+	if config.DBTableUnlogged, err = getBoolValue(cm, dbTableUnlogged, defaultDBTableUnlogged); err != nil {
+		return config, err
+	}
+
+	if config.DBTableCloneEnabled, err = getBoolValue(cm, dbTableCloneEnabled, defaultDBTableCloneEnabled); err != nil {
+		return config, err
+	}
+
+	if config.DBTableCloneMargin, err = getIntValue(cm, dbTableCloneMargin, defaultDBTableCloneMargin); err != nil {
+		return config, err
+	}
+
+	if config.DBTableRetention, err = getIntValue(cm, dbTableRetention, defaultDBTableRetention); err != nil {
+		return config, err
+	} else if config.DBTableRetention < 0 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.DBTableRetention, dbTableRetention)
+	}
+
+	if config.DBBootstrapEnabled, err = getBoolValue(cm, dbBootstrapEnabled, defaultDBBootstrapEnabled); err != nil {
+		return config, err
+	}
+
+	config.DBBootstrapExtensions = nil
+	for _, extension := range strings.Split(getStringValue(cm, dbBootstrapExtensions, ""), ",") {
+		if extension = strings.TrimSpace(extension); extension != "" {
+			config.DBBootstrapExtensions = append(config.DBBootstrapExtensions, extension)
+		}
+	}
+
+	config.RefreshStrategy = RefreshStrategy(getStringValue(cm, refreshStrategy, string(defaultRefreshStrategy)))
+
+	switch config.RefreshStrategy {
+	case RefreshStrategyFull, RefreshStrategyOffsets:
+	default:
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.RefreshStrategy, refreshStrategy)
+	}
+
+	if config.RefreshHandoverGracePeriod, err = getIntValue(cm, refreshHandoverGracePeriod, defaultRefreshHandoverGracePeriod); err != nil {
+		return config, err
+	}
+
+	if config.DBTableStorageParameters, err = getStorageParameters(instance, cm); err != nil {
+		return config, err
+	}
+
+	if config.ViewStaleness, err = getViewStaleness(instance); err != nil {
+		return config, err
+	}
+
+	if instance != nil && instance.Spec.DBTableCompression != nil {
+		config.DBTableCompression = *instance.Spec.DBTableCompression
+	} else {
+		config.DBTableCompression = getStringValue(cm, dbTableCompression, defaultDBTableCompression)
+	}
+
+	if config.DBTableCompression != "" && !utils.ContainsString(compressionMethods, config.DBTableCompression) {
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.DBTableCompression, dbTableCompression)
+	}
+
+	if err = checkDialectFeatures(config, instance); err != nil {
+		return config, err
+	}
+
 	if config.StandardInterval, err = getIntValue(cm, reconcileInterval, defaultStandardInterval); err != nil {
 		return config, err
 	}
@@ -95,12 +520,184 @@ func BuildCyndiConfig(instance *cyndi.CyndiPipeline, cm map[string]string) (*Cyn
 		return config, err
 	}
 
+	if config.InitialSyncStuckTimeout, err = getIntValue(cm, initialSyncStuckTimeout, defaultInitialSyncStuckTimeout); err != nil {
+		return config, err
+	}
+
+	config.InitialSyncStuckAction = InitialSyncStuckAction(getStringValue(cm, initialSyncStuckAction, string(defaultInitialSyncStuckAction)))
+
+	switch config.InitialSyncStuckAction {
+	case StuckActionNone, StuckActionRestartConnector, StuckActionRefresh:
+	default:
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.InitialSyncStuckAction, initialSyncStuckAction)
+	}
+
+	if config.InitialSyncThroughputMin, err = getIntValue(cm, initialSyncThroughputMin, defaultInitialSyncThroughputMin); err != nil {
+		return config, err
+	} else if config.InitialSyncThroughputMin < 0 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.InitialSyncThroughputMin, initialSyncThroughputMin)
+	}
+
+	if instance != nil && instance.Spec.InitialSyncTimeout != nil {
+		config.InitialSyncTimeout = *instance.Spec.InitialSyncTimeout
+	} else if config.InitialSyncTimeout, err = getIntValue(cm, initialSyncTimeout, defaultInitialSyncTimeout); err != nil {
+		return config, err
+	}
+
+	config.InitialSyncTimeoutAction = InitialSyncTimeoutAction(getStringValue(cm, initialSyncTimeoutAction, string(defaultInitialSyncTimeoutAction)))
+
+	switch config.InitialSyncTimeoutAction {
+	case TimeoutActionNone, TimeoutActionRestartConnector, TimeoutActionAbort:
+	default:
+		return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, config.InitialSyncTimeoutAction, initialSyncTimeoutAction)
+	}
+
+	if config.ValidationDiffEnabled, err = getBoolValue(cm, validationDiffEnabled, defaultValidationDiffEnabled); err != nil {
+		return config, err
+	}
+
+	if config.ValidationDiffMaxIds, err = getIntValue(cm, validationDiffMaxIds, defaultValidationDiffMaxIds); err != nil {
+		return config, err
+	}
+
+	if config.ValidationHBIMinCount, err = getIntValue(cm, validationHBIMinCount, defaultValidationHBIMinCount); err != nil {
+		return config, err
+	} else if config.ValidationHBIMinCount < 0 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationHBIMinCount, validationHBIMinCount)
+	}
+
+	if config.ValidationOrgCountsTop, err = getIntValue(cm, validationOrgCountsTop, defaultValidationOrgCountsTop); err != nil {
+		return config, err
+	} else if config.ValidationOrgCountsTop < 0 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationOrgCountsTop, validationOrgCountsTop)
+	}
+
+	if config.ValidationReporterCounts, err = getBoolValue(cm, validationReporterCounts, defaultValidationReporterCounts); err != nil {
+		return config, err
+	}
+
+	if config.ValidationShadowCycles, err = getIntValue(cm, validationShadowCycles, defaultValidationShadowCycles); err != nil {
+		return config, err
+	} else if config.ValidationShadowCycles < 1 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationShadowCycles, validationShadowCycles)
+	}
+
+	if config.ValidationSlowQueryThreshold, err = getIntValue(cm, validationSlowQueryThreshold, defaultValidationSlowQueryThreshold); err != nil {
+		return config, err
+	} else if config.ValidationSlowQueryThreshold < 0 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationSlowQueryThreshold, validationSlowQueryThreshold)
+	}
+
+	if config.ValidationSkipMaxDuration, err = getIntValue(cm, validationSkipMaxDuration, defaultValidationSkipMaxDuration); err != nil {
+		return config, err
+	} else if config.ValidationSkipMaxDuration <= 0 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationSkipMaxDuration, validationSkipMaxDuration)
+	}
+
+	if config.ViewSwitchMaxShrink, err = getIntValue(cm, viewSwitchMaxShrink, defaultViewSwitchMaxShrink); err != nil {
+		return config, err
+	} else if config.ViewSwitchMaxShrink < 0 || config.ViewSwitchMaxShrink > 100 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ViewSwitchMaxShrink, viewSwitchMaxShrink)
+	}
+
+	config.ValidationLagPrometheusURL = getStringValue(cm, validationLagPrometheusURL, "")
+
+	if config.ValidationLoadMaxActive, err = getIntValue(cm, validationLoadMaxActive, defaultValidationLoadMaxActive); err != nil {
+		return config, err
+	} else if config.ValidationLoadMaxActive < 0 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationLoadMaxActive, validationLoadMaxActive)
+	}
+
+	config.ValidationLoadHealthURL = getStringValue(cm, validationLoadHealthURL, "")
+
+	if config.ValidationLoadDeferInterval, err = getIntValue(cm, validationLoadDeferInterval, defaultValidationLoadDeferInterval); err != nil {
+		return config, err
+	} else if config.ValidationLoadDeferInterval <= 0 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationLoadDeferInterval, validationLoadDeferInterval)
+	}
+
+	if config.SmokeTestEnabled, err = getBoolValue(cm, smokeTestEnabled, defaultSmokeTestEnabled); err != nil {
+		return config, err
+	}
+
+	config.SmokeTestImage = getStringValue(cm, smokeTestImage, defaultSmokeTestImage)
+
+	if config.NetworkPolicyEnabled, err = getBoolValue(cm, networkPolicyEnabled, defaultNetworkPolicyEnabled); err != nil {
+		return config, err
+	}
+
+	if config.TopicPreflightEnabled, err = getBoolValue(cm, topicPreflightEnabled, defaultTopicPreflightEnabled); err != nil {
+		return config, err
+	}
+
+	if config.MonitoringDashboardEnabled, err = getBoolValue(cm, monitoringDashboardEnabled, defaultMonitoringDashboardEnabled); err != nil {
+		return config, err
+	}
+
+	if config.MonitoringRulesEnabled, err = getBoolValue(cm, monitoringRulesEnabled, defaultMonitoringRulesEnabled); err != nil {
+		return config, err
+	}
+
+	if value := getStringValue(cm, monitoringLabels, ""); value != "" {
+		if err = json.Unmarshal([]byte(value), &config.MonitoringLabels); err != nil {
+			return config, fmt.Errorf(`"%s" is not a valid value for "%s"`, value, monitoringLabels)
+		}
+	}
+
+	config.AlertmanagerURL = getStringValue(cm, alertmanagerURL, "")
+
+	if config.AlertmanagerSilenceDuration, err = getIntValue(cm, alertmanagerSilenceDuration, defaultAlertmanagerSilenceDuration); err != nil {
+		return config, err
+	} else if config.AlertmanagerSilenceDuration <= 0 {
+		return config, fmt.Errorf(`"%d" is not a valid value for "%s"`, config.AlertmanagerSilenceDuration, alertmanagerSilenceDuration)
+	}
+
+	if config.AuditTableEnabled, err = getBoolValue(cm, auditTableEnabled, defaultAuditTableEnabled); err != nil {
+		return config, err
+	}
+
+	if config.DriftReportEnabled, err = getBoolValue(cm, driftReportEnabled, defaultDriftReportEnabled); err != nil {
+		return config, err
+	}
+
+	if config.StateExportEnabled, err = getBoolValue(cm, stateExportEnabled, defaultStateExportEnabled); err != nil {
+		return config, err
+	}
+
+	if config.BackoffConfig, err = getBackoffConfig(cm); err != nil {
+		return config, err
+	}
+
+	if config.AnomalyConfig, err = getAnomalyConfig(cm); err != nil {
+		return config, err
+	}
+
+	if config.MismatchedIdsConfig, err = getMismatchedIdsConfig(cm); err != nil {
+		return config, err
+	}
+
+	if config.DBRetryConfig, err = getDBRetryConfig(cm); err != nil {
+		return config, err
+	}
+
+	if config.CanaryConfig, err = getCanaryConfig(cm, config.Topic); err != nil {
+		return config, err
+	}
+
+	if err = parseValidationMode(config, cm); err != nil {
+		return config, err
+	}
+
+	if config.NamingTemplate, err = getNamingTemplate(cm); err != nil {
+		return config, err
+	}
+
 	config.SSLMode = getStringValue(cm, "db.ssl.mode", defaultSSLMode)
 	config.SSLRootCert = getStringValue(cm, "db.ssl.root.cert", defaultSSLRootCert)
 
 	config.ConfigMapVersion = utils.ConfigMapHash(cm, keysIgnoredByRefresh...)
 	if instance != nil {
-		config.SpecHash, err = utils.SpecHash(instance.Spec)
+		config.SpecHash, err = utils.SpecHash(specIgnoredByRefresh(instance.Spec))
 		if err != nil {
 			return config, err
 		}
@@ -109,6 +706,90 @@ func BuildCyndiConfig(instance *cyndi.CyndiPipeline, cm map[string]string) (*Cyn
 	return config, err
 }
 
+// The template needs to render different names for different pipelines and for subsequent versions of a pipeline
+func getNamingTemplate(cm map[string]string) (string, error) {
+	value := getStringValue(cm, namingTemplate, "")
+	if value == "" {
+		return "", nil
+	}
+
+	samples := []utils.NamingData{
+		{PipelineName: "advisor", AppName: "advisor", Namespace: "advisor", Generation: 1},
+		{PipelineName: "advisor", AppName: "advisor", Namespace: "advisor", Generation: 2},
+		{PipelineName: "compliance", AppName: "compliance", Namespace: "advisor", Generation: 1},
+	}
+
+	names := map[string]bool{}
+	for _, sample := range samples {
+		name, err := utils.RenderTableName(value, sample)
+		if err != nil {
+			return "", fmt.Errorf(`"%s" is not a valid value for "%s": %w`, value, namingTemplate, err)
+		}
+
+		names[name] = true
+	}
+
+	if len(names) < len(samples) {
+		return "", fmt.Errorf(`"%s" is not a valid value for "%s": names need to include the pipeline (or app) name and the generation`, value, namingTemplate)
+	}
+
+	return value, nil
+}
+
+func parseConsumerGroup(config *CyndiConfiguration, instance *cyndi.CyndiPipeline, cm map[string]string) (err error) {
+	template := getStringValue(cm, connectorConsumerGroup, "")
+	if instance != nil && instance.Spec.ConsumerGroupTemplate != nil {
+		template = *instance.Spec.ConsumerGroupTemplate
+	}
+
+	if config.ConnectorConsumerGroupTemplate, err = getConsumerGroupTemplate(template); err != nil {
+		return err
+	}
+
+	if instance != nil && instance.Spec.AutoOffsetReset != nil {
+		config.ConnectorAutoOffsetReset = AutoOffsetReset(*instance.Spec.AutoOffsetReset)
+	} else {
+		config.ConnectorAutoOffsetReset = AutoOffsetReset(getStringValue(cm, connectorAutoOffsetReset, string(defaultConnectorAutoOffsetReset)))
+	}
+
+	switch config.ConnectorAutoOffsetReset {
+	case "", AutoOffsetResetEarliest, AutoOffsetResetLatest, AutoOffsetResetNone:
+	default:
+		return fmt.Errorf(`"%s" is not a valid value for "%s"`, config.ConnectorAutoOffsetReset, connectorAutoOffsetReset)
+	}
+
+	return nil
+}
+
+// Like table names, consumer groups need to differ between pipelines and pipeline versions as the offsets committed by one would be resumed from by another
+func getConsumerGroupTemplate(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	samples := []utils.ConsumerGroupData{
+		{PipelineName: "advisor", AppName: "advisor", Namespace: "advisor", PipelineVersion: "1_1", ConnectorName: "cyndi-advisor-1-1"},
+		{PipelineName: "advisor", AppName: "advisor", Namespace: "advisor", PipelineVersion: "1_2", ConnectorName: "cyndi-advisor-1-2"},
+		{PipelineName: "compliance", AppName: "compliance", Namespace: "advisor", PipelineVersion: "1_1", ConnectorName: "cyndi-compliance-1-1"},
+	}
+
+	groups := map[string]bool{}
+	for _, sample := range samples {
+		group, err := utils.RenderConsumerGroup(value, sample)
+		if err != nil {
+			return "", fmt.Errorf(`"%s" is not a valid value for "%s": %w`, value, connectorConsumerGroup, err)
+		}
+
+		groups[group] = true
+	}
+
+	if len(groups) < len(samples) {
+		return "", fmt.Errorf(`"%s" is not a valid value for "%s": consumer groups need to include the pipeline (or app) name and the pipeline version`, value, connectorConsumerGroup)
+	}
+
+	return value, nil
+}
+
 func getStringValue(cm map[string]string, key string, defaultValue string) string {
 	if cm == nil {
 		return defaultValue
@@ -137,13 +818,38 @@ func getIntValue(cm map[string]string, key string, defaultValue int64) (int64, e
 	return defaultValue, nil
 }
 
+func getBoolValue(cm map[string]string, key string, defaultValue bool) (bool, error) {
+	if cm == nil {
+		return defaultValue, nil
+	}
+
+	if value, ok := cm[key]; ok {
+		if parsed, err := strconv.ParseBool(value); err != nil {
+			return false, fmt.Errorf(`"%s" is not a valid value for "%s"`, value, key)
+		} else {
+			return parsed, nil
+		}
+	}
+
+	return defaultValue, nil
+}
+
 func getValidationConfig(instance *cyndi.CyndiPipeline, cm map[string]string, prefix string, defaultValue ValidationConfiguration) (ValidationConfiguration, error) {
 	var (
 		err    error
 		result = ValidationConfiguration{}
 	)
 
-	if result.Interval, err = getIntValue(cm, fmt.Sprintf("%s%s", prefix, validationInterval), defaultValue.Interval); err != nil {
+	var specInterval *int64
+	if instance != nil && prefix == "" {
+		specInterval = instance.Spec.ValidationInterval
+	} else if instance != nil && prefix == "init." {
+		specInterval = instance.Spec.InitValidationInterval
+	}
+
+	if specInterval != nil {
+		result.Interval = *specInterval
+	} else if result.Interval, err = getIntValue(cm, fmt.Sprintf("%s%s", prefix, validationInterval), defaultValue.Interval); err != nil {
 		return result, err
 	}
 
@@ -157,9 +863,335 @@ func getValidationConfig(instance *cyndi.CyndiPipeline, cm map[string]string, pr
 		return result, err
 	}
 
+	if instance != nil && instance.Spec.ValidationCountThreshold != nil {
+		result.CountThreshold = *instance.Spec.ValidationCountThreshold
+	} else if result.CountThreshold, err = getIntValue(cm, fmt.Sprintf("%s%s", prefix, validationCountThreshold), defaultValue.CountThreshold); err != nil {
+		return result, err
+	}
+
+	if result.LingeringThreshold, err = getIntValue(cm, fmt.Sprintf("%s%s", prefix, validationLingeringThreshold), defaultValue.LingeringThreshold); err != nil {
+		return result, err
+	}
+
+	modeKey := fmt.Sprintf("%s%s", prefix, validationThresholdMode)
+	if instance != nil && instance.Spec.ValidationThresholdMode != nil {
+		result.ThresholdMode = ThresholdMode(*instance.Spec.ValidationThresholdMode)
+	} else {
+		result.ThresholdMode = ThresholdMode(getStringValue(cm, modeKey, string(defaultValue.ThresholdMode)))
+	}
+
+	switch result.ThresholdMode {
+	case ThresholdModeAll, ThresholdModeAny:
+	default:
+		return result, fmt.Errorf(`"%s" is not a valid value for "%s"`, result.ThresholdMode, modeKey)
+	}
+
 	return result, err
 }
 
+// The connector subscribes to all the topics. If the topics define which hosts they carry, validation counts hosts of each topic separately.
+func parseTopics(config *CyndiConfiguration, topics []cyndi.TopicSource) error {
+	names := make([]string, len(topics))
+	config.TopicFilters = nil
+
+	for i, topic := range topics {
+		names[i] = topic.Name
+
+		if topic.Where != "" {
+			config.TopicFilters = append(config.TopicFilters, topic.Where)
+		}
+	}
+
+	if len(config.TopicFilters) > 0 && len(config.TopicFilters) != len(topics) {
+		return fmt.Errorf("Either all or none of the topics need to define a where condition")
+	}
+
+	config.Topic = strings.Join(names, ",")
+	return nil
+}
+
+func parseDeleteHandling(config *CyndiConfiguration, instance *cyndi.CyndiPipeline, cm map[string]string) (err error) {
+	var spec cyndi.DeleteHandling
+	if instance != nil && instance.Spec.Deletes != nil {
+		spec = *instance.Spec.Deletes
+	}
+
+	if spec.Enabled != nil {
+		config.ConnectorDeleteEnabled = *spec.Enabled
+	} else if config.ConnectorDeleteEnabled, err = getBoolValue(cm, connectorDeleteEnabled, defaultConnectorDeleteEnabled); err != nil {
+		return err
+	}
+
+	if spec.Tombstones != nil {
+		config.ConnectorTombstones = TombstoneMode(*spec.Tombstones)
+	} else {
+		config.ConnectorTombstones = TombstoneMode(getStringValue(cm, connectorTombstones, string(defaultConnectorTombstones)))
+	}
+
+	switch config.ConnectorTombstones {
+	case TombstonesConvert, TombstonesNative:
+	default:
+		return fmt.Errorf(`"%s" is not a valid value for "%s"`, config.ConnectorTombstones, connectorTombstones)
+	}
+
+	if spec.PKMode != nil {
+		config.ConnectorPKMode = *spec.PKMode
+	} else {
+		config.ConnectorPKMode = getStringValue(cm, connectorPKMode, defaultConnectorPKMode)
+	}
+
+	switch config.ConnectorPKMode {
+	case PKModeRecordKey, PKModeRecordValue:
+	default:
+		return fmt.Errorf(`"%s" is not a valid value for "%s"`, config.ConnectorPKMode, connectorPKMode)
+	}
+
+	// the JDBC sink connector can only delete rows identified by the record key
+	if config.ConnectorDeleteEnabled && config.ConnectorPKMode != PKModeRecordKey {
+		return fmt.Errorf(`"%s" requires "%s" to be "%s"`, connectorDeleteEnabled, connectorPKMode, PKModeRecordKey)
+	}
+
+	if len(spec.PKFields) > 0 {
+		config.ConnectorPKFields = spec.PKFields
+	} else {
+		value := getStringValue(cm, connectorPKFields, defaultConnectorPKFields)
+		config.ConnectorPKFields = nil
+
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				config.ConnectorPKFields = append(config.ConnectorPKFields, field)
+			}
+		}
+
+		if len(config.ConnectorPKFields) == 0 {
+			return fmt.Errorf(`"%s" is not a valid value for "%s"`, value, connectorPKFields)
+		}
+	}
+
+	return nil
+}
+
+// The source connector captures changes of a single HBI database
+func parseSourceConnector(config *CyndiConfiguration, instance *cyndi.CyndiPipeline, cm map[string]string) error {
+	// depend on the plugins and environment variables of the Kafka Connect image
+	config.SourceConnectorClass = getStringValue(cm, connectorSourceClass, defaultSourceConnectorClass)
+	config.SourceDBEnvPrefix = getStringValue(cm, connectorSourceEnvPrefix, defaultSourceDBEnvPrefix)
+	if !envVariablePrefix.MatchString(config.SourceDBEnvPrefix) {
+		return fmt.Errorf(`"%s" is not a valid value for "%s"`, config.SourceDBEnvPrefix, connectorSourceEnvPrefix)
+	}
+
+	if instance == nil || instance.Spec.ManageSourceConnector == nil {
+		return nil
+	}
+
+	spec := instance.Spec.ManageSourceConnector
+	if config.InventorySource != InventorySourceDatabase {
+		return fmt.Errorf(`"%s" is not supported by the %s inventory source`, "manageSourceConnector", config.InventorySource)
+	} else if len(config.InventoryDbSecrets) != 1 {
+		return fmt.Errorf(`"%s" is not supported with a sharded inventory database`, "manageSourceConnector")
+	}
+
+	config.SourceConnectorTemplate = getStringValue(cm, connectorSourceConfig, defaultSourceConnectorTemplate)
+
+	config.SourceTables = spec.TableIncludeList
+	if len(config.SourceTables) == 0 {
+		config.SourceTables = []string{defaultSourceTable}
+	}
+
+	// slot names are limited to 63 characters
+	if spec.SlotNamePrefix != nil {
+		config.SourceSlotNamePrefix = *spec.SlotNamePrefix
+	} else {
+		prefix := "cyndi_" + strings.ToLower(strings.ReplaceAll(instance.Spec.AppName, "-", "_"))
+		config.SourceSlotNamePrefix = prefix[:utils.Min(len(prefix), 40)]
+	}
+
+	config.SourceHeartbeatTable, config.SourceHeartbeatInterval = "", 0
+	if spec.Heartbeat != nil {
+		config.SourceHeartbeatTable = defaultSourceHeartbeatTable
+		if spec.Heartbeat.Table != nil {
+			config.SourceHeartbeatTable = *spec.Heartbeat.Table
+		}
+
+		config.SourceHeartbeatInterval = defaultSourceHeartbeatInterval
+		if spec.Heartbeat.IntervalMs != nil {
+			config.SourceHeartbeatInterval = *spec.Heartbeat.IntervalMs
+		}
+	}
+
+	return nil
+}
+
+// Authentication plugin of the application database (spec.dbAuth)
+func parseDBAuth(config *CyndiConfiguration, instance *cyndi.CyndiPipeline) error {
+	config.DBAuth = DBAuthConfiguration{Type: DBAuthPassword}
+	if instance == nil || instance.Spec.DBAuth == nil {
+		return nil
+	}
+
+	spec := instance.Spec.DBAuth
+	config.DBAuth.Type = DBAuthType(spec.Type)
+	if spec.ClientID != nil {
+		config.DBAuth.ClientID = *spec.ClientID
+	}
+
+	if spec.CloudSQLInstance != nil {
+		config.DBAuth.CloudSQLInstance = *spec.CloudSQLInstance
+	}
+
+	switch config.DBAuth.Type {
+	case DBAuthPassword, DBAuthAzureAD:
+	case DBAuthGCPIAM:
+		if config.DBAuth.CloudSQLInstance == "" {
+			return fmt.Errorf("dbAuth.cloudSqlInstance is required by the %s authentication", DBAuthGCPIAM)
+		}
+	default:
+		return fmt.Errorf(`"%s" is not a valid value for "dbAuth.type"`, spec.Type)
+	}
+
+	return nil
+}
+
+// How the validation controller compares hosts. Block hashes are computed by the databases and blocks of ids are selected using filters
+// so neither is available with the API
+func parseValidationStrategy(config *CyndiConfiguration, instance *cyndi.CyndiPipeline, cm map[string]string) (err error) {
+	config.ValidationStrategy = ValidationStrategy(getStringValue(cm, validationStrategy, string(defaultValidationStrategy)))
+	if instance != nil && instance.Spec.ValidationStrategy != nil {
+		config.ValidationStrategy = ValidationStrategy(*instance.Spec.ValidationStrategy)
+	}
+
+	switch config.ValidationStrategy {
+	case ValidationStrategyIds, ValidationStrategyCounts:
+	case ValidationStrategyBlockHash, ValidationStrategySampled:
+		if config.InventorySource != InventorySourceDatabase {
+			return fmt.Errorf(`"%s" is not supported by the %s inventory source`, validationStrategy, config.InventorySource)
+		}
+	default:
+		return fmt.Errorf(`"%s" is not a valid value for "%s"`, config.ValidationStrategy, validationStrategy)
+	}
+
+	if config.ValidationBlockSize, err = getIntValue(cm, validationBlockSize, defaultValidationBlockSize); err != nil {
+		return err
+	} else if config.ValidationBlockSize <= 0 {
+		return fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationBlockSize, validationBlockSize)
+	}
+
+	if config.ValidationMemoryBudget, err = getIntValue(cm, validationMemoryBudget, defaultValidationMemoryBudget); err != nil {
+		return err
+	} else if config.ValidationMemoryBudget <= 0 {
+		return fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationMemoryBudget, validationMemoryBudget)
+	}
+
+	if config.ValidationSamplePercentage, err = getIntValue(cm, validationSamplePercentage, defaultValidationSamplePercentage); err != nil {
+		return err
+	} else if config.ValidationSamplePercentage < 1 || config.ValidationSamplePercentage > 100 {
+		return fmt.Errorf(`"%d" is not a valid value for "%s"`, config.ValidationSamplePercentage, validationSamplePercentage)
+	}
+
+	return nil
+}
+
+// Replication slots and publications checked by the validation controller in addition to those of the managed source connector
+func parseSourceHealth(config *CyndiConfiguration, cm map[string]string) (err error) {
+	config.SourceReplicationSlots = nil
+	for _, slot := range strings.Split(getStringValue(cm, sourceReplicationSlots, ""), ",") {
+		if slot = strings.TrimSpace(slot); slot != "" {
+			config.SourceReplicationSlots = append(config.SourceReplicationSlots, slot)
+		}
+	}
+
+	config.SourcePublications = nil
+	for _, publication := range strings.Split(getStringValue(cm, sourcePublications, ""), ",") {
+		if publication = strings.TrimSpace(publication); publication != "" {
+			config.SourcePublications = append(config.SourcePublications, publication)
+		}
+	}
+
+	if len(config.SourceReplicationSlots) > 0 || len(config.SourcePublications) > 0 {
+		if config.InventorySource != InventorySourceDatabase {
+			return fmt.Errorf(`"%s" is not supported by the %s inventory source`, sourceReplicationSlots, config.InventorySource)
+		} else if len(config.InventoryDbSecrets) != 1 {
+			return fmt.Errorf(`"%s" is not supported with a sharded inventory database`, sourceReplicationSlots)
+		}
+	}
+
+	if config.SourceSlotLagThreshold, err = getIntValue(cm, sourceSlotLagThreshold, defaultSourceSlotLagThreshold); err != nil {
+		return err
+	} else if config.SourceSlotLagThreshold <= 0 {
+		return fmt.Errorf(`"%d" is not a valid value for "%s"`, config.SourceSlotLagThreshold, sourceSlotLagThreshold)
+	}
+
+	if config.SourceSlotRetentionThreshold, err = getIntValue(cm, sourceSlotRetentionThreshold, defaultSourceSlotRetentionThreshold); err != nil {
+		return err
+	} else if config.SourceSlotRetentionThreshold <= 0 {
+		return fmt.Errorf(`"%d" is not a valid value for "%s"`, config.SourceSlotRetentionThreshold, sourceSlotRetentionThreshold)
+	}
+
+	return nil
+}
+
+// Labels and annotations of the pipeline's connectors. The keys used by the operator cannot be overridden
+func parseConnectorMetadata(config *CyndiConfiguration, instance *cyndi.CyndiPipeline) error {
+	if instance == nil {
+		return nil
+	}
+
+	validateKeys := func(field string, values map[string]string) error {
+		for key, value := range values {
+			for _, prefix := range reservedConnectorKeyPrefixes {
+				if strings.HasPrefix(key, prefix) {
+					return fmt.Errorf(`"%s" is a reserved key and cannot be used in "%s"`, key, field)
+				}
+			}
+
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return fmt.Errorf(`"%s" is not a valid key for "%s": %s`, key, field, strings.Join(errs, ", "))
+			}
+
+			if field == "connectorLabels" {
+				if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+					return fmt.Errorf(`"%s" is not a valid value for "%s.%s": %s`, value, field, key, strings.Join(errs, ", "))
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if err := validateKeys("connectorLabels", instance.Spec.ConnectorLabels); err != nil {
+		return err
+	}
+
+	if err := validateKeys("connectorAnnotations", instance.Spec.ConnectorAnnotations); err != nil {
+		return err
+	}
+
+	config.ConnectorLabels = instance.Spec.ConnectorLabels
+	config.ConnectorAnnotations = instance.Spec.ConnectorAnnotations
+	return nil
+}
+
+// A sink connector other than the one of the flavor, e.g. a fork of it using (mostly) the same configuration keys
+func parseConnectorClass(config *CyndiConfiguration, cm map[string]string) error {
+	config.ConnectorClass = getStringValue(cm, connectorClass, "")
+
+	config.ConnectorKeyMapping = map[string]string{}
+	if value := getStringValue(cm, connectorKeyMapping, ""); value != "" {
+		if err := json.Unmarshal([]byte(value), &config.ConnectorKeyMapping); err != nil {
+			return fmt.Errorf(`"%s" is not a valid value for "%s"`, value, connectorKeyMapping)
+		}
+	}
+
+	config.ConnectorRequiredKeys = nil
+	for _, key := range strings.Split(getStringValue(cm, connectorRequiredKeys, ""), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			config.ConnectorRequiredKeys = append(config.ConnectorRequiredKeys, key)
+		}
+	}
+
+	return nil
+}
+
 func LoadDBSecret(config *CyndiConfiguration, c client.Client, namespace string, name string) (DBParams, error) {
 	secret, err := utils.FetchSecret(c, namespace, name)
 
@@ -172,7 +1204,276 @@ func LoadDBSecret(config *CyndiConfiguration, c client.Client, namespace string,
 	if config != nil {
 		params.SSLMode = config.SSLMode
 		params.SSLRootCert = config.SSLRootCert
+		params.Retry = config.DBRetryConfig
 	}
 
 	return params, err
 }
+
+// Loads credentials of the HBI REST API. No credentials are sent if the secret name is empty
+func LoadAPISecret(config *CyndiConfiguration, c client.Client, namespace string) (APIParams, error) {
+	params := APIParams{URL: config.InventoryAPIURL}
+
+	if config.InventoryAPISecret == "" {
+		return params, nil
+	}
+
+	secret, err := utils.FetchSecret(c, namespace, config.InventoryAPISecret)
+	if err != nil {
+		return params, err
+	}
+
+	params.Token, params.Identity = ParseAPISecret(secret)
+	return params, nil
+}
+
+func getBackoffConfig(cm map[string]string) (result BackoffConfiguration, err error) {
+	if result.BaseInterval, err = getIntValue(cm, backoffBaseInterval, defaultBackoffConfig.BaseInterval); err != nil {
+		return
+	}
+
+	if result.MaxInterval, err = getIntValue(cm, backoffMaxInterval, defaultBackoffConfig.MaxInterval); err != nil {
+		return
+	}
+
+	if result.CircuitFailureThreshold, err = getIntValue(cm, circuitFailureThreshold, defaultBackoffConfig.CircuitFailureThreshold); err != nil {
+		return
+	}
+
+	result.CircuitOpenInterval, err = getIntValue(cm, circuitOpenInterval, defaultBackoffConfig.CircuitOpenInterval)
+	return
+}
+
+func getAnomalyConfig(cm map[string]string) (result AnomalyConfiguration, err error) {
+	if result.Enabled, err = getBoolValue(cm, validationAnomalyEnabled, defaultAnomalyConfig.Enabled); err != nil {
+		return
+	}
+
+	if result.Window, err = getIntValue(cm, validationAnomalyWindow, defaultAnomalyConfig.Window); err != nil {
+		return
+	} else if result.Window < 2 {
+		return result, fmt.Errorf(`"%d" is not a valid value for "%s"`, result.Window, validationAnomalyWindow)
+	}
+
+	if result.Sigma, err = getIntValue(cm, validationAnomalySigma, defaultAnomalyConfig.Sigma); err != nil {
+		return
+	} else if result.Sigma < 1 {
+		return result, fmt.Errorf(`"%d" is not a valid value for "%s"`, result.Sigma, validationAnomalySigma)
+	}
+
+	if result.MinHosts, err = getIntValue(cm, validationAnomalyMinHosts, defaultAnomalyConfig.MinHosts); err != nil {
+		return
+	} else if result.MinHosts < 0 {
+		return result, fmt.Errorf(`"%d" is not a valid value for "%s"`, result.MinHosts, validationAnomalyMinHosts)
+	}
+
+	return
+}
+
+func getMismatchedIdsConfig(cm map[string]string) (result MismatchedIdsConfiguration, err error) {
+	if result.Max, err = getIntValue(cm, validationMismatchedIdsMax, defaultMismatchedIdsConfig.Max); err != nil {
+		return
+	} else if result.Max < 0 {
+		return result, fmt.Errorf(`"%d" is not a valid value for "%s"`, result.Max, validationMismatchedIdsMax)
+	}
+
+	if result.Sample, err = getIntValue(cm, validationMismatchedIdsSample, defaultMismatchedIdsConfig.Sample); err != nil {
+		return
+	} else if result.Sample < 0 || result.Sample > result.Max {
+		return result, fmt.Errorf(`"%d" is not a valid value for "%s"`, result.Sample, validationMismatchedIdsSample)
+	}
+
+	result.Log, err = getBoolValue(cm, validationMismatchedIdsLog, defaultMismatchedIdsConfig.Log)
+	return
+}
+
+func getDBRetryConfig(cm map[string]string) (result DBRetryConfiguration, err error) {
+	if result.Attempts, err = getIntValue(cm, dbRetryAttempts, defaultDBRetryConfig.Attempts); err != nil {
+		return
+	}
+
+	if result.Attempts < 0 {
+		return result, fmt.Errorf(`"%d" is not a valid value for "%s"`, result.Attempts, dbRetryAttempts)
+	}
+
+	if result.BaseInterval, err = getIntValue(cm, dbRetryBaseInterval, defaultDBRetryConfig.BaseInterval); err != nil {
+		return
+	}
+
+	if result.BaseInterval <= 0 {
+		return result, fmt.Errorf(`"%d" is not a valid value for "%s"`, result.BaseInterval, dbRetryBaseInterval)
+	}
+
+	return
+}
+
+// The canary host is published to one of the topics the connector consumes (the first one by default)
+func getCanaryConfig(cm map[string]string, topics string) (result CanaryConfiguration, err error) {
+	if result.Enabled, err = getBoolValue(cm, canaryEnabled, defaultCanaryConfig.Enabled); err != nil {
+		return
+	}
+
+	if result.Interval, err = getIntValue(cm, canaryInterval, defaultCanaryConfig.Interval); err != nil {
+		return
+	}
+
+	if result.Timeout, err = getIntValue(cm, canaryTimeout, defaultCanaryConfig.Timeout); err != nil {
+		return
+	}
+
+	result.BridgeURL = getStringValue(cm, canaryBridgeURL, defaultCanaryConfig.BridgeURL)
+	result.Topic = getStringValue(cm, canaryTopic, strings.Split(topics, ",")[0])
+
+	if !result.Enabled {
+		return
+	}
+
+	if result.BridgeURL == "" {
+		return result, fmt.Errorf(`"%s" is not a valid value for "%s"`, result.BridgeURL, canaryBridgeURL)
+	}
+
+	if !utils.ContainsString(strings.Split(topics, ","), result.Topic) {
+		return result, fmt.Errorf(`"%s" is not a valid value for "%s"`, result.Topic, canaryTopic)
+	}
+
+	return
+}
+
+// Validation runs in CronJobs so that its memory spikes cannot take the operator down and so that it can be scheduled on dedicated nodes
+func parseValidationMode(config *CyndiConfiguration, cm map[string]string) (err error) {
+	config.ValidationMode = ValidationMode(getStringValue(cm, validationMode, string(defaultValidationMode)))
+
+	switch config.ValidationMode {
+	case ValidationModeController:
+		return nil
+	case ValidationModeCronJob:
+	default:
+		return fmt.Errorf(`"%s" is not a valid value for "%s"`, config.ValidationMode, validationMode)
+	}
+
+	result := &config.ValidationCronJobConfig
+	if result.Image = getStringValue(cm, validationCronJobImage, ""); result.Image == "" {
+		return fmt.Errorf(`"%s" is not a valid value for "%s"`, result.Image, validationCronJobImage)
+	}
+
+	// validated by the API server when the CronJob is created
+	result.Schedule = getStringValue(cm, validationCronJobSchedule, defaultValidationCronJobSchedule)
+	result.ServiceAccount = getStringValue(cm, validationCronJobSA, defaultValidationCronJobSA)
+
+	if value := getStringValue(cm, validationCronJobNodeSelector, ""); value != "" {
+		if err = json.Unmarshal([]byte(value), &result.NodeSelector); err != nil {
+			return fmt.Errorf(`"%s" is not a valid value for "%s"`, value, validationCronJobNodeSelector)
+		}
+	}
+
+	if value := getStringValue(cm, validationCronJobTolerations, ""); value != "" {
+		if err = json.Unmarshal([]byte(value), &result.Tolerations); err != nil {
+			return fmt.Errorf(`"%s" is not a valid value for "%s"`, value, validationCronJobTolerations)
+		}
+	}
+
+	if value := getStringValue(cm, validationCronJobMemoryLimit, ""); value != "" {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return fmt.Errorf(`"%s" is not a valid value for "%s"`, value, validationCronJobMemoryLimit)
+		}
+
+		result.MemoryLimit = &quantity
+	}
+
+	return nil
+}
+
+// Parameters defined in the spec take precedence over those defined in the ConfigMap
+func getStorageParameters(instance *cyndi.CyndiPipeline, cm map[string]string) (map[string]string, error) {
+	result := map[string]string{}
+
+	if value := getStringValue(cm, dbTableStorageParameters, ""); value != "" {
+		if err := json.Unmarshal([]byte(value), &result); err != nil {
+			return nil, fmt.Errorf(`"%s" is not a valid value for "%s"`, value, dbTableStorageParameters)
+		}
+	}
+
+	if instance != nil {
+		for key, value := range instance.Spec.DBTableStorageParameters {
+			result[key] = value
+		}
+	}
+
+	// the parameters end up in DDL statements
+	for key, value := range result {
+		if !storageParameterName.MatchString(key) || !storageParameterValue.MatchString(value) {
+			return nil, fmt.Errorf(`"%s = %s" is not a valid value for "%s"`, key, value, dbTableStorageParameters)
+		}
+	}
+
+	return result, nil
+}
+
+func getViewStaleness(instance *cyndi.CyndiPipeline) (*ViewStalenessConfiguration, error) {
+	if instance == nil || instance.Spec.ViewStaleness == nil {
+		return nil, nil
+	}
+
+	spec := instance.Spec.ViewStaleness
+	result := &ViewStalenessConfiguration{
+		Column:      defaultViewStalenessColumn,
+		StaleAfter:  defaultViewStaleAfter,
+		CulledAfter: defaultViewCulledAfter,
+	}
+
+	if spec.Column != nil {
+		result.Column = *spec.Column
+	}
+
+	if spec.StaleAfter != nil {
+		result.StaleAfter = *spec.StaleAfter
+	}
+
+	if spec.CulledAfter != nil {
+		result.CulledAfter = *spec.CulledAfter
+	}
+
+	// the column name ends up in the view definition
+	if !viewColumnName.MatchString(result.Column) {
+		return nil, fmt.Errorf(`"%s" is not a valid value for "viewStaleness.column"`, result.Column)
+	}
+
+	if result.StaleAfter < 0 {
+		return nil, fmt.Errorf(`"%d" is not a valid value for "viewStaleness.staleAfter"`, result.StaleAfter)
+	}
+
+	if result.CulledAfter < result.StaleAfter {
+		return nil, fmt.Errorf(`"%d" is not a valid value for "viewStaleness.culledAfter"`, result.CulledAfter)
+	}
+
+	return result, nil
+}
+
+// CockroachDB lacks UNLOGGED tables, declarative partitioning and column compression
+func checkDialectFeatures(config *CyndiConfiguration, instance *cyndi.CyndiPipeline) error {
+	if config.DBDialect != DBDialectCockroach {
+		return nil
+	}
+
+	unsupported := func(key string) error {
+		return fmt.Errorf(`"%s" is not supported by the %s dialect`, key, config.DBDialect)
+	}
+
+	switch {
+	case config.DBTableUnlogged:
+		return unsupported(dbTableUnlogged)
+	case config.DBTableCompression != "":
+		return unsupported(dbTableCompression)
+	case config.DBDisplayNameSearch == DisplayNameSearchCitext:
+		return unsupported(dbDisplayNameSearch)
+	case instance != nil && instance.Spec.DBTablePartitioning != nil:
+		return unsupported("dbTablePartitioning")
+	case config.ValidationStrategy == ValidationStrategyBlockHash:
+		return unsupported(validationStrategy)
+	case config.DBAuth.Type != DBAuthPassword:
+		return unsupported("dbAuth")
+	}
+
+	return nil
+}
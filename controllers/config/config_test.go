@@ -3,10 +3,12 @@ package config
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/RedHatInsights/cyndi-operator/test"
 
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -30,9 +32,52 @@ func assertDefaults(config *CyndiConfiguration) {
 	Expect(config.ValidationConfig).To(Equal(defaultValidationConfig))
 	Expect(config.ValidationConfigInit).To(Equal(defaultValidationConfigInit))
 	Expect(config.InventoryDbSecret).To(Equal(defaultInventoryDbSecret))
+	Expect(config.InventoryDbSecrets).To(Equal([]string{defaultInventoryDbSecret}))
+	Expect(config.InventorySource).To(Equal(InventorySourceDatabase))
 	Expect(config.TopicReplicationFactor).To(Equal(defaultTopicReplicationFactor))
 	Expect(config.DeadLetterQueueTopicName).To(Equal(defaultDeadLetterQueueTopicName))
-
+	Expect(config.InitialSyncStuckTimeout).To(Equal(defaultInitialSyncStuckTimeout))
+	Expect(config.InitialSyncStuckAction).To(Equal(defaultInitialSyncStuckAction))
+	Expect(config.InitialSyncThroughputMin).To(Equal(defaultInitialSyncThroughputMin))
+	Expect(config.InitialSyncTimeout).To(Equal(defaultInitialSyncTimeout))
+	Expect(config.InitialSyncTimeoutAction).To(Equal(defaultInitialSyncTimeoutAction))
+	Expect(config.ValidationDiffEnabled).To(Equal(defaultValidationDiffEnabled))
+	Expect(config.ValidationDiffMaxIds).To(Equal(defaultValidationDiffMaxIds))
+	Expect(config.ValidationLagPrometheusURL).To(BeEmpty())
+	Expect(config.ValidationSkipMaxDuration).To(Equal(defaultValidationSkipMaxDuration))
+	Expect(config.SmokeTestEnabled).To(BeFalse())
+	Expect(config.SmokeTestImage).To(Equal(defaultSmokeTestImage))
+	Expect(config.NetworkPolicyEnabled).To(BeFalse())
+	Expect(config.TopicPreflightEnabled).To(BeFalse())
+	Expect(config.MonitoringDashboardEnabled).To(Equal(defaultMonitoringDashboardEnabled))
+	Expect(config.MonitoringRulesEnabled).To(Equal(defaultMonitoringRulesEnabled))
+	Expect(config.MonitoringLabels).To(BeEmpty())
+	Expect(config.AuditTableEnabled).To(Equal(defaultAuditTableEnabled))
+	Expect(config.DriftReportEnabled).To(Equal(defaultDriftReportEnabled))
+	Expect(config.StateExportEnabled).To(Equal(defaultStateExportEnabled))
+	Expect(config.DBDialect).To(Equal(DBDialectPostgres))
+	Expect(config.BackoffConfig).To(Equal(defaultBackoffConfig))
+	Expect(config.DBRetryConfig).To(Equal(defaultDBRetryConfig))
+	Expect(config.CanaryConfig.Enabled).To(BeFalse())
+	Expect(config.CanaryConfig.Interval).To(Equal(defaultCanaryConfig.Interval))
+	Expect(config.CanaryConfig.Timeout).To(Equal(defaultCanaryConfig.Timeout))
+	Expect(config.DBTableUnlogged).To(BeFalse())
+	Expect(config.DBTableCloneEnabled).To(BeFalse())
+	Expect(config.DBTableCloneMargin).To(Equal(defaultDBTableCloneMargin))
+	Expect(config.RefreshStrategy).To(Equal(RefreshStrategyFull))
+	Expect(config.RefreshHandoverGracePeriod).To(Equal(defaultRefreshHandoverGracePeriod))
+	Expect(config.DBTableStorageParameters).To(BeEmpty())
+	Expect(config.DBTableCompression).To(Equal(""))
+	Expect(config.ConnectorFlavor).To(Equal(ConnectorFlavorConfluent))
+	Expect(config.ConnectorClass).To(BeEmpty())
+	Expect(config.ConnectorKeyMapping).To(BeEmpty())
+	Expect(config.ConnectorRequiredKeys).To(BeEmpty())
+	Expect(config.ConnectorDeleteEnabled).To(BeTrue())
+	Expect(config.ConnectorTombstones).To(Equal(TombstonesConvert))
+	Expect(config.ConnectorPKMode).To(Equal(PKModeRecordKey))
+	Expect(config.ConnectorPKFields).To(Equal([]string{"id"}))
+	Expect(config.ConnectorConsumerGroupTemplate).To(BeEmpty())
+	Expect(config.ConnectorAutoOffsetReset).To(BeEmpty())
 }
 
 var _ = Describe("Config", func() {
@@ -73,6 +118,61 @@ var _ = Describe("Config", func() {
 				"inventory.dbSecret":                   "some-secret",
 				"connector.topic.replication.factor":   "2",
 				"connector.deadletterqueue.topic.name": "some-topic",
+				"init.stuck.timeout":                   "600",
+				"init.stuck.action":                    "restartConnector",
+				"init.throughput.min":                  "5000",
+				"init.timeout":                         "7200",
+				"init.timeout.action":                  "abort",
+				"validation.diff.enabled":              "true",
+				"validation.diff.max.ids":              "20",
+				"validation.strategy":                  "blockhash",
+				"validation.block.size":                "500",
+				"validation.sample.percentage":         "25",
+				"validation.memory.budget":             "1048576",
+				"validation.count.threshold":           "100",
+				"validation.threshold.mode":            "any",
+				"init.validation.count.threshold":      "1000",
+				"init.validation.threshold.mode":       "all",
+				"validation.lingering.threshold":       "10",
+				"init.validation.lingering.threshold":  "100",
+				"monitoring.dashboard.enabled":         "true",
+				"monitoring.rules.enabled":             "true",
+				"monitoring.labels":                    `{"app": "grafana"}`,
+				"audit.table.enabled":                  "true",
+				"drift.report.enabled":                 "true",
+				"validation.shadow.cycles":             "3",
+				"validation.anomaly.enabled":           "true",
+				"validation.anomaly.window":            "20",
+				"validation.anomaly.sigma":             "4",
+				"validation.anomaly.min.hosts":         "10",
+				"validation.slow.query.threshold":      "30",
+				"validation.mismatched.ids.max":        "5000",
+				"validation.mismatched.ids.sample":     "5",
+				"validation.mismatched.ids.log":        "true",
+				"state.export.enabled":                 "true",
+				"backoff.base.interval":                "1",
+				"backoff.max.interval":                 "30",
+				"circuit.failure.threshold":            "4",
+				"circuit.open.interval":                "300",
+				"db.retry.attempts":                    "5",
+				"db.retry.base.interval.ms":            "50",
+				"db.table.unlogged":                    "true",
+				"db.table.clone.enabled":               "true",
+				"db.table.clone.margin":                "600",
+				"refresh.strategy":                     "offsets",
+				"refresh.handover.grace.period":        "30",
+				"db.table.storage.parameters":          `{"fillfactor": "70", "toast.autovacuum_enabled": "off"}`,
+				"db.table.compression":                 "lz4",
+				"connector.flavor":                     "cloud-native",
+				"connector.class":                      "com.example.JdbcSinkConnector",
+				"connector.key.mapping":                `{"batch.size": "sink.batch.size", "auto.create": ""}`,
+				"connector.required.keys":              "sink.batch.size, connection.url",
+				"connector.delete.enabled":             "false",
+				"connector.tombstones":                 "native",
+				"connector.pk.mode":                    "record_value",
+				"connector.pk.fields":                  "id, org_id",
+				"connector.consumer.group.template":    "cyndi-{{.Namespace}}-{{.PipelineVersion}}",
+				"connector.auto.offset.reset":          "none",
 			},
 		}
 
@@ -97,6 +197,52 @@ var _ = Describe("Config", func() {
 		Expect(config.InventoryDbSecret).To(Equal("some-secret"))
 		Expect(config.TopicReplicationFactor).To(Equal(int64(2)))
 		Expect(config.DeadLetterQueueTopicName).To(Equal("some-topic"))
+		Expect(config.InitialSyncStuckTimeout).To(Equal(int64(600)))
+		Expect(config.InitialSyncStuckAction).To(Equal(StuckActionRestartConnector))
+		Expect(config.InitialSyncThroughputMin).To(Equal(int64(5000)))
+		Expect(config.InitialSyncTimeout).To(Equal(int64(7200)))
+		Expect(config.InitialSyncTimeoutAction).To(Equal(TimeoutActionAbort))
+		Expect(config.ValidationDiffEnabled).To(BeTrue())
+		Expect(config.ValidationDiffMaxIds).To(Equal(int64(20)))
+		Expect(config.ValidationStrategy).To(Equal(ValidationStrategyBlockHash))
+		Expect(config.ValidationBlockSize).To(Equal(int64(500)))
+		Expect(config.ValidationSamplePercentage).To(Equal(int64(25)))
+		Expect(config.ValidationMemoryBudget).To(Equal(int64(1048576)))
+		Expect(config.ValidationConfig.CountThreshold).To(Equal(int64(100)))
+		Expect(config.ValidationConfig.ThresholdMode).To(Equal(ThresholdModeAny))
+		Expect(config.ValidationConfigInit.CountThreshold).To(Equal(int64(1000)))
+		Expect(config.ValidationConfigInit.ThresholdMode).To(Equal(ThresholdModeAll))
+		Expect(config.ValidationConfig.LingeringThreshold).To(Equal(int64(10)))
+		Expect(config.ValidationConfigInit.LingeringThreshold).To(Equal(int64(100)))
+		Expect(config.MonitoringDashboardEnabled).To(BeTrue())
+		Expect(config.MonitoringRulesEnabled).To(BeTrue())
+		Expect(config.MonitoringLabels).To(Equal(map[string]string{"app": "grafana"}))
+		Expect(config.AuditTableEnabled).To(BeTrue())
+		Expect(config.DriftReportEnabled).To(BeTrue())
+		Expect(config.ValidationShadowCycles).To(Equal(int64(3)))
+		Expect(config.AnomalyConfig).To(Equal(AnomalyConfiguration{Enabled: true, Window: 20, Sigma: 4, MinHosts: 10}))
+		Expect(config.ValidationSlowQueryThreshold).To(Equal(int64(30)))
+		Expect(config.MismatchedIdsConfig).To(Equal(MismatchedIdsConfiguration{Max: 5000, Sample: 5, Log: true}))
+		Expect(config.StateExportEnabled).To(BeTrue())
+		Expect(config.BackoffConfig).To(Equal(BackoffConfiguration{BaseInterval: 1, MaxInterval: 30, CircuitFailureThreshold: 4, CircuitOpenInterval: 300}))
+		Expect(config.DBRetryConfig).To(Equal(DBRetryConfiguration{Attempts: 5, BaseInterval: 50}))
+		Expect(config.DBTableUnlogged).To(BeTrue())
+		Expect(config.DBTableCloneEnabled).To(BeTrue())
+		Expect(config.DBTableCloneMargin).To(Equal(int64(600)))
+		Expect(config.RefreshStrategy).To(Equal(RefreshStrategyOffsets))
+		Expect(config.RefreshHandoverGracePeriod).To(Equal(int64(30)))
+		Expect(config.DBTableStorageParameters).To(Equal(map[string]string{"fillfactor": "70", "toast.autovacuum_enabled": "off"}))
+		Expect(config.DBTableCompression).To(Equal("lz4"))
+		Expect(config.ConnectorFlavor).To(Equal(ConnectorFlavorCloudNative))
+		Expect(config.ConnectorClass).To(Equal("com.example.JdbcSinkConnector"))
+		Expect(config.ConnectorKeyMapping).To(Equal(map[string]string{"batch.size": "sink.batch.size", "auto.create": ""}))
+		Expect(config.ConnectorRequiredKeys).To(Equal([]string{"sink.batch.size", "connection.url"}))
+		Expect(config.ConnectorDeleteEnabled).To(BeFalse())
+		Expect(config.ConnectorTombstones).To(Equal(TombstonesNative))
+		Expect(config.ConnectorPKMode).To(Equal(PKModeRecordValue))
+		Expect(config.ConnectorPKFields).To(Equal([]string{"id", "org_id"}))
+		Expect(config.ConnectorConsumerGroupTemplate).To(Equal("cyndi-{{.Namespace}}-{{.PipelineVersion}}"))
+		Expect(config.ConnectorAutoOffsetReset).To(Equal(AutoOffsetResetNone))
 	})
 
 	DescribeTable("Errors on invalid value",
@@ -121,8 +267,177 @@ var _ = Describe("Config", func() {
 		Entry("init.validation.interval", "init.validation.interval"),
 		Entry("init.validation.attempts.threshold", "init.validation.attempts.threshold"),
 		Entry("init.validation.percentage.threshold", "init.validation.percentage.threshold"),
+		Entry("init.stuck.timeout", "init.stuck.timeout"),
+		Entry("init.stuck.action", "init.stuck.action"),
+		Entry("init.throughput.min", "init.throughput.min"),
+		Entry("init.timeout", "init.timeout"),
+		Entry("init.timeout.action", "init.timeout.action"),
+		Entry("validation.diff.enabled", "validation.diff.enabled"),
+		Entry("canary.enabled", "canary.enabled"),
+		Entry("networkpolicy.enabled", "networkpolicy.enabled"),
+		Entry("topic.preflight.enabled", "topic.preflight.enabled"),
+		Entry("canary.interval", "canary.interval"),
+		Entry("validation.diff.max.ids", "validation.diff.max.ids"),
+		Entry("validation.strategy", "validation.strategy"),
+		Entry("validation.block.size", "validation.block.size"),
+		Entry("validation.sample.percentage", "validation.sample.percentage"),
+		Entry("validation.memory.budget", "validation.memory.budget"),
+		Entry("validation.hbi.min.count", "validation.hbi.min.count"),
+		Entry("validation.skip.max.duration", "validation.skip.max.duration"),
+		Entry("validation.shadow.cycles", "validation.shadow.cycles"),
+		Entry("validation.anomaly.enabled", "validation.anomaly.enabled"),
+		Entry("validation.anomaly.window", "validation.anomaly.window"),
+		Entry("validation.anomaly.sigma", "validation.anomaly.sigma"),
+		Entry("validation.anomaly.min.hosts", "validation.anomaly.min.hosts"),
+		Entry("validation.slow.query.threshold", "validation.slow.query.threshold"),
+		Entry("validation.mismatched.ids.max", "validation.mismatched.ids.max"),
+		Entry("validation.mismatched.ids.sample", "validation.mismatched.ids.sample"),
+		Entry("validation.mismatched.ids.log", "validation.mismatched.ids.log"),
+		Entry("validation.org.counts.top", "validation.org.counts.top"),
+		Entry("validation.xjoin.enabled", "validation.xjoin.enabled"),
+		Entry("view.switch.max.shrink", "view.switch.max.shrink"),
+		Entry("validation.count.threshold", "validation.count.threshold"),
+		Entry("validation.threshold.mode", "validation.threshold.mode"),
+		Entry("init.validation.count.threshold", "init.validation.count.threshold"),
+		Entry("validation.lingering.threshold", "validation.lingering.threshold"),
+		Entry("connector.flavor", "connector.flavor"),
+		Entry("connector.key.mapping", "connector.key.mapping"),
+		Entry("source.slot.lag.threshold", "source.slot.lag.threshold"),
+		Entry("source.slot.retention.threshold", "source.slot.retention.threshold"),
+		Entry("connector.delete.enabled", "connector.delete.enabled"),
+		Entry("connector.tombstones", "connector.tombstones"),
+		Entry("connector.pk.mode", "connector.pk.mode"),
+		Entry("connector.auto.offset.reset", "connector.auto.offset.reset"),
+		Entry("init.validation.lingering.threshold", "init.validation.lingering.threshold"),
+		Entry("init.validation.threshold.mode", "init.validation.threshold.mode"),
+		Entry("monitoring.dashboard.enabled", "monitoring.dashboard.enabled"),
+		Entry("monitoring.rules.enabled", "monitoring.rules.enabled"),
+		Entry("monitoring.labels", "monitoring.labels"),
+		Entry("audit.table.enabled", "audit.table.enabled"),
+		Entry("drift.report.enabled", "drift.report.enabled"),
+		Entry("state.export.enabled", "state.export.enabled"),
+		Entry("backoff.base.interval", "backoff.base.interval"),
+		Entry("backoff.max.interval", "backoff.max.interval"),
+		Entry("circuit.failure.threshold", "circuit.failure.threshold"),
+		Entry("circuit.open.interval", "circuit.open.interval"),
+		Entry("db.retry.attempts", "db.retry.attempts"),
+		Entry("db.retry.base.interval.ms", "db.retry.base.interval.ms"),
+		Entry("db.table.unlogged", "db.table.unlogged"),
+		Entry("db.table.clone.enabled", "db.table.clone.enabled"),
+		Entry("db.table.clone.margin", "db.table.clone.margin"),
+		Entry("db.table.retention", "db.table.retention"),
+		Entry("db.bootstrap.enabled", "db.bootstrap.enabled"),
+		Entry("refresh.strategy", "refresh.strategy"),
+		Entry("refresh.handover.grace.period", "refresh.handover.grace.period"),
+		Entry("db.table.storage.parameters", "db.table.storage.parameters"),
+		Entry("db.table.compression", "db.table.compression"),
+		Entry("db.dialect", "db.dialect"),
+		Entry("inventory.source", "inventory.source"),
+		Entry("smoketest.enabled", "smoketest.enabled"),
 	)
 
+	It("Accepts a naming template", func() {
+		config, err := BuildCyndiConfig(nil, map[string]string{"naming.template": "hosts_{{.PipelineName}}_{{.Generation}}"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.NamingTemplate).To(Equal("hosts_{{.PipelineName}}_{{.Generation}}"))
+	})
+
+	It("Rejects a naming template not rendering unique names", func() {
+		_, err := BuildCyndiConfig(nil, map[string]string{"naming.template": "hosts_{{.PipelineName}}"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix(`"hosts_{{.PipelineName}}" is not a valid value for "naming.template"`))
+
+		_, err = BuildCyndiConfig(nil, map[string]string{"naming.template": "hosts_{{.Unknown}}"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Rejects a consumer group template not rendering unique consumer groups", func() {
+		_, err := BuildCyndiConfig(nil, map[string]string{"connector.consumer.group.template": "cyndi-{{.AppName}}"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix(`"cyndi-{{.AppName}}" is not a valid value for "connector.consumer.group.template"`))
+	})
+
+	It("Overrides the consumer group template and auto.offset.reset by the spec", func() {
+		template, reset := "cyndi-{{.ConnectorName}}", "latest"
+		instance := &cyndi.CyndiPipeline{Spec: cyndi.CyndiPipelineSpec{AppName: "advisor", ConsumerGroupTemplate: &template, AutoOffsetReset: &reset}}
+
+		config, err := BuildCyndiConfig(instance, map[string]string{"connector.auto.offset.reset": "none"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.ConnectorConsumerGroupTemplate).To(Equal(template))
+		Expect(config.ConnectorAutoOffsetReset).To(Equal(AutoOffsetResetLatest))
+	})
+
+	It("Rejects a view switch shrink above 100 percent", func() {
+		_, err := BuildCyndiConfig(nil, map[string]string{"view.switch.max.shrink": "101"})
+		Expect(err).To(MatchError(`"101" is not a valid value for "view.switch.max.shrink"`))
+	})
+
+	Describe("CyndiConfig", func() {
+		var (
+			topic     = "platform.inventory.events.v2"
+			cluster   = "connect-cluster-01"
+			maxAge    = int64(30)
+			tasksMax  = int64(4)
+			interval  = int64(600)
+			threshold = int64(10)
+			mode      = "any"
+		)
+
+		cyndiConfig := &cyndi.CyndiConfig{
+			Spec: cyndi.CyndiConfigSpec{
+				PipelineDefaults: cyndi.PipelineDefaults{
+					Topic:             &topic,
+					ConnectorTasksMax: &tasksMax,
+					MaxAge:            &maxAge,
+					Validation: &cyndi.ValidationDefaults{
+						PercentageThreshold: &threshold,
+						ThresholdMode:       &mode,
+					},
+					InitValidation: &cyndi.ValidationDefaults{
+						Interval: &interval,
+					},
+				},
+				Namespaces: []cyndi.NamespaceDefaults{{
+					Namespace: "advisor",
+					PipelineDefaults: cyndi.PipelineDefaults{
+						ConnectCluster: &cluster,
+						MaxAge:         &interval,
+					},
+				}},
+			},
+		}
+
+		It("Translates defaults to ConfigMap keys", func() {
+			config, err := BuildCyndiConfig(nil, CyndiConfigData(cyndiConfig, "patch"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Topic).To(Equal(topic))
+			Expect(config.ConnectCluster).To(Equal(defaultConnectCluster))
+			Expect(config.ConnectorTasksMax).To(Equal(tasksMax))
+			Expect(config.ConnectorMaxAge).To(Equal(maxAge))
+			Expect(config.ValidationConfig.PercentageThreshold).To(Equal(threshold))
+			Expect(config.ValidationConfig.ThresholdMode).To(Equal(ThresholdModeAny))
+			Expect(config.ValidationConfig.Interval).To(Equal(defaultValidationConfig.Interval))
+			Expect(config.ValidationConfigInit.Interval).To(Equal(interval))
+		})
+
+		It("Applies namespace defaults", func() {
+			config, err := BuildCyndiConfig(nil, CyndiConfigData(cyndiConfig, "advisor"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Topic).To(Equal(topic))
+			Expect(config.ConnectCluster).To(Equal(cluster))
+			Expect(config.ConnectorMaxAge).To(Equal(interval))
+		})
+
+		It("Is overridden by the ConfigMap", func() {
+			config, err := BuildCyndiConfig(nil, utils.Merge(CyndiConfigData(cyndiConfig, "advisor"), map[string]string{"connector.max.age": "7"}))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ConnectorMaxAge).To(Equal(int64(7)))
+		})
+	})
+
 	Describe("Override config on CR level", func() {
 		It("Overrides ConnectCluster", func() {
 			cm := &corev1.ConfigMap{
@@ -143,6 +458,103 @@ var _ = Describe("Config", func() {
 			Expect(config.ConnectCluster).To(Equal("cluster02"))
 		})
 
+		It("Overrides the initial sync timeout", func() {
+			timeout := int64(0)
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					InitialSyncTimeout: &timeout,
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, map[string]string{"init.timeout": "3600"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.InitialSyncTimeout).To(Equal(int64(0)))
+		})
+
+		It("Overrides ConnectCluster in a different namespace", func() {
+			value := "kafka/cluster02"
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					ConnectCluster: &value,
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, map[string]string{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ConnectCluster).To(Equal("cluster02"))
+			Expect(config.ConnectClusterNamespace).To(Equal("kafka"))
+		})
+
+		It("Overrides Topic with a list of topics", func() {
+			topic := "platform.inventory.events"
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					Topic: &topic,
+					Topics: []cyndi.TopicSource{
+						{Name: "platform.inventory.events.shard1", Where: "org_id < '5'"},
+						{Name: "platform.inventory.events.shard2", Where: "org_id >= '5'"},
+					},
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, map[string]string{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Topic).To(Equal("platform.inventory.events.shard1,platform.inventory.events.shard2"))
+			Expect(config.TopicFilters).To(Equal([]string{"org_id < '5'", "org_id >= '5'"}))
+		})
+
+		It("Errors if only some of the topics define a where condition", func() {
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					Topics: []cyndi.TopicSource{
+						{Name: "platform.inventory.events.shard1", Where: "org_id < '5'"},
+						{Name: "platform.inventory.events.shard2"},
+					},
+				},
+			}
+
+			_, err := BuildCyndiConfig(&pipeline, map[string]string{})
+			Expect(err).To(MatchError("Either all or none of the topics need to define a where condition"))
+		})
+
+		It("Overrides delete handling", func() {
+			enabled := false
+			tombstones := "native"
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					Deletes: &cyndi.DeleteHandling{
+						Enabled:    &enabled,
+						Tombstones: &tombstones,
+						PKFields:   []string{"org_id", "id"},
+					},
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, map[string]string{"connector.delete.enabled": "true"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ConnectorDeleteEnabled).To(BeFalse())
+			Expect(config.ConnectorTombstones).To(Equal(TombstonesNative))
+			Expect(config.ConnectorPKMode).To(Equal(PKModeRecordKey))
+			Expect(config.ConnectorPKFields).To(Equal([]string{"org_id", "id"}))
+		})
+
+		It("Requires record_key pk.mode for deletes", func() {
+			_, err := BuildCyndiConfig(nil, map[string]string{"connector.pk.mode": "record_value"})
+			Expect(err).To(MatchError(`"connector.delete.enabled" requires "connector.pk.mode" to be "record_key"`))
+		})
+
+		It("Errors on an invalid ConnectCluster reference", func() {
+			value := "kafka/cluster02/extra"
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					ConnectCluster: &value,
+				},
+			}
+
+			_, err := BuildCyndiConfig(&pipeline, map[string]string{})
+			Expect(err).To(MatchError(`"kafka/cluster02/extra" is not a valid value for "connect.cluster"`))
+		})
+
 		It("Overrides InventoryDbSecret", func() {
 			cm := &corev1.ConfigMap{
 				Data: map[string]string{
@@ -162,6 +574,357 @@ var _ = Describe("Config", func() {
 			Expect(config.InventoryDbSecret).To(Equal("pipeline-secret-name"))
 		})
 
+		It("Overrides InventoryDbSecrets", func() {
+			cm := &corev1.ConfigMap{
+				Data: map[string]string{
+					"inventory.dbSecrets": "cm-shard-1, cm-shard-2",
+				},
+			}
+
+			config, err := BuildCyndiConfig(nil, cm.Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.InventoryDbSecrets).To(Equal([]string{"cm-shard-1", "cm-shard-2"}))
+			Expect(config.InventoryDbSecret).To(Equal("cm-shard-1"))
+
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					InventoryDbSecrets: []string{"shard-1", "shard-2", "shard-3"},
+				},
+			}
+
+			config, err = BuildCyndiConfig(&pipeline, cm.Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.InventoryDbSecrets).To(Equal([]string{"shard-1", "shard-2", "shard-3"}))
+		})
+
+		It("Configures the HBI API as the inventory source", func() {
+			cm := map[string]string{
+				"inventory.source":     "api",
+				"inventory.api.url":    "http://host-inventory-service:8080/api/inventory/v1",
+				"inventory.api.secret": "host-inventory-api",
+			}
+
+			config, err := BuildCyndiConfig(nil, cm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.InventorySource).To(Equal(InventorySourceAPI))
+			Expect(config.InventoryAPIURL).To(Equal("http://host-inventory-service:8080/api/inventory/v1"))
+			Expect(config.InventoryAPISecret).To(Equal("host-inventory-api"))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"inventory.source": "api"})
+			Expect(err).To(MatchError(`"" is not a valid value for "inventory.api.url"`))
+
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					AdditionalFilters: []map[string]string{{"where": "true"}},
+				},
+			}
+
+			_, err = BuildCyndiConfig(&pipeline, cm)
+			Expect(err).To(MatchError(`"additionalFilters" is not supported by the api inventory source`))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"inventory.source": "api", "inventory.api.url": "http://localhost:8080", "validation.strategy": "blockhash"})
+			Expect(err).To(MatchError(`"validation.strategy" is not supported by the api inventory source`))
+		})
+
+		It("Selects the authentication of the application database", func() {
+			config, err := BuildCyndiConfig(&cyndi.CyndiPipeline{}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBAuth).To(Equal(DBAuthConfiguration{Type: DBAuthPassword}))
+
+			instance := "project:region:instance"
+			pipeline := cyndi.CyndiPipeline{Spec: cyndi.CyndiPipelineSpec{DBAuth: &cyndi.DBAuth{Type: "gcp-iam", CloudSQLInstance: &instance}}}
+			config, err = BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBAuth).To(Equal(DBAuthConfiguration{Type: DBAuthGCPIAM, CloudSQLInstance: instance}))
+
+			pipeline.Spec.DBAuth.CloudSQLInstance = nil
+			_, err = BuildCyndiConfig(&pipeline, nil)
+			Expect(err).To(MatchError("dbAuth.cloudSqlInstance is required by the gcp-iam authentication"))
+
+			pipeline.Spec.DBAuth = &cyndi.DBAuth{Type: "azure-ad"}
+			_, err = BuildCyndiConfig(&pipeline, map[string]string{"db.dialect": "cockroachdb"})
+			Expect(err).To(MatchError(`"dbAuth" is not supported by the cockroachdb dialect`))
+		})
+
+		It("Selects the validation strategy of the pipeline", func() {
+			strategy := "counts"
+			pipeline := cyndi.CyndiPipeline{Spec: cyndi.CyndiPipelineSpec{ValidationStrategy: &strategy}}
+
+			config, err := BuildCyndiConfig(&pipeline, map[string]string{"validation.strategy": "blockhash"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ValidationStrategy).To(Equal(ValidationStrategyCounts))
+
+			strategy = "sampled"
+			_, err = BuildCyndiConfig(&pipeline, map[string]string{"inventory.source": "api", "inventory.api.url": "http://localhost:8080"})
+			Expect(err).To(MatchError(`"validation.strategy" is not supported by the api inventory source`))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"validation.sample.percentage": "101"})
+			Expect(err).To(MatchError(`"101" is not a valid value for "validation.sample.percentage"`))
+		})
+
+		It("Requires the HBI API for the xjoin cross-check", func() {
+			config, err := BuildCyndiConfig(nil, map[string]string{"validation.xjoin.enabled": "true", "inventory.api.url": "http://localhost:8080"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.InventorySource).To(Equal(InventorySourceDatabase))
+			Expect(config.ValidationXjoinEnabled).To(BeTrue())
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"validation.xjoin.enabled": "true"})
+			Expect(err).To(MatchError(`"" is not a valid value for "inventory.api.url"`))
+		})
+
+		It("Configures the source connector", func() {
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					AppName:               "compliance-ssg",
+					ManageSourceConnector: &cyndi.SourceConnector{},
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.SourceConnectorTemplate).To(Equal(defaultSourceConnectorTemplate))
+			Expect(config.SourceTables).To(Equal([]string{"public.hosts"}))
+			Expect(config.SourceSlotNamePrefix).To(Equal("cyndi_compliance_ssg"))
+
+			prefix := "hbi_slot"
+			pipeline.Spec.ManageSourceConnector = &cyndi.SourceConnector{SlotNamePrefix: &prefix, TableIncludeList: []string{"hbi.hosts"}}
+			config, err = BuildCyndiConfig(&pipeline, map[string]string{"connector.source.config": "{}"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.SourceConnectorTemplate).To(Equal("{}"))
+			Expect(config.SourceTables).To(Equal([]string{"hbi.hosts"}))
+			Expect(config.SourceSlotNamePrefix).To(Equal("hbi_slot"))
+
+			_, err = BuildCyndiConfig(&pipeline, map[string]string{"inventory.dbSecrets": "hbi-1,hbi-2"})
+			Expect(err).To(MatchError(`"manageSourceConnector" is not supported with a sharded inventory database`))
+
+			config, err = BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.SourceConnectorTemplate).To(BeEmpty())
+			Expect(config.SourceConnectorClass).To(Equal(defaultSourceConnectorClass))
+			Expect(config.SourceDBEnvPrefix).To(Equal(defaultSourceDBEnvPrefix))
+
+			config, err = BuildCyndiConfig(&pipeline, map[string]string{"connector.source.class": "com.example.PostgresConnector", "connector.source.env.prefix": "HBI"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.SourceConnectorClass).To(Equal("com.example.PostgresConnector"))
+			Expect(config.SourceDBEnvPrefix).To(Equal("HBI"))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"connector.source.env.prefix": "hbi-db"})
+			Expect(err).To(MatchError(`"hbi-db" is not a valid value for "connector.source.env.prefix"`))
+		})
+
+		It("Reports environment-specific settings", func() {
+			cm := map[string]string{"connector.topic": "onprem.inventory.events", "connect.cluster": "kafka/connect", "smoketest.image": "mirror.example.com/postgresql"}
+			config, err := BuildCyndiConfig(nil, cm)
+			Expect(err).ToNot(HaveOccurred())
+
+			settings := EnvironmentSettings(config, cm)
+			Expect(settings).To(ContainElements(
+				EnvironmentSetting{Key: "connector.topic", Value: "onprem.inventory.events"},
+				EnvironmentSetting{Key: "connect.cluster", Value: "kafka/connect"},
+				EnvironmentSetting{Key: "smoketest.image", Value: "mirror.example.com/postgresql"},
+				EnvironmentSetting{Key: "inventory.dbSecret", Value: defaultInventoryDbSecret, Default: true},
+				EnvironmentSetting{Key: "connector.source.class", Value: defaultSourceConnectorClass, Default: true},
+			))
+		})
+
+		It("Validates connector labels and annotations", func() {
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					ConnectorLabels:      map[string]string{"team": "inventory"},
+					ConnectorAnnotations: map[string]string{"example.com/alerts": "inventory alerts"},
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ConnectorLabels).To(Equal(map[string]string{"team": "inventory"}))
+			Expect(config.ConnectorAnnotations).To(Equal(map[string]string{"example.com/alerts": "inventory alerts"}))
+
+			pipeline.Spec.ConnectorLabels = map[string]string{"strimzi.io/cluster": "other"}
+			_, err = BuildCyndiConfig(&pipeline, nil)
+			Expect(err).To(MatchError(`"strimzi.io/cluster" is a reserved key and cannot be used in "connectorLabels"`))
+
+			pipeline.Spec.ConnectorLabels = map[string]string{"team": "inventory alerts"}
+			_, err = BuildCyndiConfig(&pipeline, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Configures the database bootstrap", func() {
+			config, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBBootstrapEnabled).To(BeFalse())
+			Expect(config.DBBootstrapExtensions).To(BeEmpty())
+
+			config, err = BuildCyndiConfig(nil, map[string]string{
+				"db.bootstrap.enabled":    "true",
+				"db.bootstrap.extensions": "pg_trgm, uuid-ossp",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBBootstrapEnabled).To(BeTrue())
+			Expect(config.DBBootstrapExtensions).To(Equal([]string{"pg_trgm", "uuid-ossp"}))
+		})
+
+		It("Configures replication health checks", func() {
+			config, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.SourceReplicationSlots).To(BeEmpty())
+			Expect(config.SourceSlotLagThreshold).To(Equal(defaultSourceSlotLagThreshold))
+			Expect(config.SourceSlotRetentionThreshold).To(Equal(defaultSourceSlotRetentionThreshold))
+
+			cm := map[string]string{
+				"source.replication.slots":        "debezium, debezium_2",
+				"source.publications":             "dbz_publication",
+				"source.slot.lag.threshold":       "1024",
+				"source.slot.retention.threshold": "4096",
+			}
+
+			config, err = BuildCyndiConfig(nil, cm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.SourceReplicationSlots).To(Equal([]string{"debezium", "debezium_2"}))
+			Expect(config.SourcePublications).To(Equal([]string{"dbz_publication"}))
+			Expect(config.SourceSlotLagThreshold).To(Equal(int64(1024)))
+			Expect(config.SourceSlotRetentionThreshold).To(Equal(int64(4096)))
+
+			cm["inventory.dbSecrets"] = "hbi-1,hbi-2"
+			_, err = BuildCyndiConfig(nil, cm)
+			Expect(err).To(MatchError(`"source.replication.slots" is not supported with a sharded inventory database`))
+		})
+
+		It("Configures load shedding", func() {
+			config, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ValidationLoadMaxActive).To(Equal(int64(0)))
+			Expect(config.ValidationLoadHealthURL).To(BeEmpty())
+			Expect(config.ValidationLoadDeferInterval).To(Equal(defaultValidationLoadDeferInterval))
+
+			cm := map[string]string{
+				"validation.load.max.active":     "50",
+				"validation.load.health.url":     "http://host-inventory-service:8000/health",
+				"validation.load.defer.interval": "600",
+			}
+
+			config, err = BuildCyndiConfig(nil, cm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ValidationLoadMaxActive).To(Equal(int64(50)))
+			Expect(config.ValidationLoadHealthURL).To(Equal("http://host-inventory-service:8000/health"))
+			Expect(config.ValidationLoadDeferInterval).To(Equal(int64(600)))
+
+			cm["validation.load.defer.interval"] = "0"
+			_, err = BuildCyndiConfig(nil, cm)
+			Expect(err).To(MatchError(`"0" is not a valid value for "validation.load.defer.interval"`))
+		})
+
+		It("Configures validation in CronJobs", func() {
+			config, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ValidationMode).To(Equal(ValidationModeController))
+
+			cm := map[string]string{
+				"validation.mode":                  "cronjob",
+				"validation.cronjob.image":         "quay.io/cloudservices/cyndi-operator:latest",
+				"validation.cronjob.node.selector": `{"node-role.kubernetes.io/validation": ""}`,
+				"validation.cronjob.tolerations":   `[{"key": "dedicated", "operator": "Equal", "value": "validation", "effect": "NoSchedule"}]`,
+				"validation.cronjob.memory.limit":  "4Gi",
+			}
+
+			config, err = BuildCyndiConfig(nil, cm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ValidationMode).To(Equal(ValidationModeCronJob))
+			Expect(config.ValidationCronJobConfig.Image).To(Equal("quay.io/cloudservices/cyndi-operator:latest"))
+			Expect(config.ValidationCronJobConfig.Schedule).To(Equal(defaultValidationCronJobSchedule))
+			Expect(config.ValidationCronJobConfig.ServiceAccount).To(Equal(defaultValidationCronJobSA))
+			Expect(config.ValidationCronJobConfig.NodeSelector).To(Equal(map[string]string{"node-role.kubernetes.io/validation": ""}))
+			Expect(config.ValidationCronJobConfig.Tolerations).To(HaveLen(1))
+			Expect(config.ValidationCronJobConfig.Tolerations[0].Value).To(Equal("validation"))
+			Expect(config.ValidationCronJobConfig.MemoryLimit.String()).To(Equal("4Gi"))
+
+			cm["validation.cronjob.memory.limit"] = "lots"
+			_, err = BuildCyndiConfig(nil, cm)
+			Expect(err).To(MatchError(`"lots" is not a valid value for "validation.cronjob.memory.limit"`))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"validation.mode": "cronjob"})
+			Expect(err).To(MatchError(`"" is not a valid value for "validation.cronjob.image"`))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"validation.mode": "sidecar"})
+			Expect(err).To(MatchError(`"sidecar" is not a valid value for "validation.mode"`))
+		})
+
+		It("Configures alert silencing", func() {
+			config, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.AlertmanagerURL).To(BeEmpty())
+			Expect(config.AlertmanagerSilenceDuration).To(Equal(defaultAlertmanagerSilenceDuration))
+
+			cm := map[string]string{
+				"alertmanager.url":              "http://alertmanager-operated:9093",
+				"alertmanager.silence.duration": "600",
+			}
+
+			config, err = BuildCyndiConfig(nil, cm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.AlertmanagerURL).To(Equal("http://alertmanager-operated:9093"))
+			Expect(config.AlertmanagerSilenceDuration).To(Equal(int64(600)))
+
+			cm["alertmanager.silence.duration"] = "-1"
+			_, err = BuildCyndiConfig(nil, cm)
+			Expect(err).To(MatchError(`"-1" is not a valid value for "alertmanager.silence.duration"`))
+		})
+
+		It("Configures the canary", func() {
+			cm := map[string]string{
+				"canary.enabled":    "true",
+				"canary.bridge.url": "http://kafka-bridge:8080",
+				"canary.interval":   "300",
+			}
+
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					Topics: []cyndi.TopicSource{{Name: "platform.inventory.events"}, {Name: "platform.inventory.canary"}},
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, cm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.CanaryConfig).To(Equal(CanaryConfiguration{
+				Enabled:   true,
+				BridgeURL: "http://kafka-bridge:8080",
+				Topic:     "platform.inventory.events",
+				Interval:  300,
+				Timeout:   defaultCanaryConfig.Timeout,
+			}))
+
+			cm["canary.topic"] = "platform.inventory.canary"
+			config, err = BuildCyndiConfig(&pipeline, cm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.CanaryConfig.Topic).To(Equal("platform.inventory.canary"))
+
+			cm["canary.topic"] = "platform.inventory.other"
+			_, err = BuildCyndiConfig(&pipeline, cm)
+			Expect(err).To(MatchError(`"platform.inventory.other" is not a valid value for "canary.topic"`))
+
+			_, err = BuildCyndiConfig(&pipeline, map[string]string{"canary.enabled": "true"})
+			Expect(err).To(MatchError(`"" is not a valid value for "canary.bridge.url"`))
+		})
+
+		It("Prefers InventoryDbSecret of the pipeline over sharded secrets of the ConfigMap", func() {
+			cm := &corev1.ConfigMap{
+				Data: map[string]string{
+					"inventory.dbSecrets": "cm-shard-1,cm-shard-2",
+				},
+			}
+
+			value := "pipeline-secret-name"
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					InventoryDbSecret: &value,
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, cm.Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.InventoryDbSecrets).To(Equal([]string{"pipeline-secret-name"}))
+		})
+
 		It("Overrides MaxAge", func() {
 			cm := &corev1.ConfigMap{
 				Data: map[string]string{
@@ -202,6 +965,97 @@ var _ = Describe("Config", func() {
 			Expect(config.ValidationConfigInit.PercentageThreshold).To(Equal(int64(7)))
 		})
 
+		It("Overrides count threshold and threshold mode", func() {
+			cm := &corev1.ConfigMap{
+				Data: map[string]string{
+					"validation.count.threshold":      "5",
+					"init.validation.count.threshold": "6",
+				},
+			}
+
+			count := int64(10)
+			mode := "any"
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					ValidationCountThreshold: &count,
+					ValidationThresholdMode:  &mode,
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, cm.Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ValidationConfig.CountThreshold).To(Equal(int64(10)))
+			Expect(config.ValidationConfig.ThresholdMode).To(Equal(ThresholdModeAny))
+			Expect(config.ValidationConfigInit.CountThreshold).To(Equal(int64(10)))
+			Expect(config.ValidationConfigInit.ThresholdMode).To(Equal(ThresholdModeAny))
+		})
+
+		It("Overrides validation intervals", func() {
+			cm := &corev1.ConfigMap{
+				Data: map[string]string{
+					"validation.interval":      "600",
+					"init.validation.interval": "60",
+				},
+			}
+
+			interval := int64(1800)
+			initInterval := int64(10)
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					ValidationInterval:     &interval,
+					InitValidationInterval: &initInterval,
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, cm.Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ValidationConfig.Interval).To(Equal(int64(1800)))
+			Expect(config.ValidationConfigInit.Interval).To(Equal(int64(10)))
+		})
+
+		It("Does not change spec hash when validation intervals change", func() {
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{AppName: "app"},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			interval := int64(1800)
+			pipeline.Spec.ValidationInterval = &interval
+			pipeline.Spec.InitValidationInterval = &interval
+
+			config2, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config2.SpecHash).To(Equal(config.SpecHash))
+		})
+
+		It("Does not change spec hash when fields applied in place change", func() {
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{AppName: "app"},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			threshold := int64(10)
+			secret := "host-inventory-read-only-db"
+			pipeline.Spec.ValidationThreshold = &threshold
+			pipeline.Spec.InventoryDbSecret = &secret
+			pipeline.Spec.InventoryDbSecrets = []string{secret}
+			pipeline.Spec.DBGrants = &cyndi.DBGrants{Roles: []cyndi.DBRole{"reporting"}}
+
+			config2, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config2.SpecHash).To(Equal(config.SpecHash))
+
+			pipeline.Spec.InsightsOnly = true
+
+			config3, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config3.SpecHash).ToNot(Equal(config.SpecHash))
+		})
+
 		It("Overrides DBTableIndexSQL", func() {
 			cm := &corev1.ConfigMap{
 				Data: map[string]string{
@@ -221,6 +1075,140 @@ var _ = Describe("Config", func() {
 			Expect(config.DBTableIndexSQL).To(Equal(value))
 		})
 
+		It("Overrides DBDialect", func() {
+			cm := &corev1.ConfigMap{
+				Data: map[string]string{
+					"db.dialect": "postgres",
+				},
+			}
+
+			value := "cockroachdb"
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					DBDialect: &value,
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, cm.Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBDialect).To(Equal(DBDialectCockroach))
+			Expect(config.DBTableIndexSQL).To(Equal(defaultCockroachDBTableIndexSQL))
+		})
+
+		It("Configures the staleness column of the view", func() {
+			pipeline := cyndi.CyndiPipeline{}
+
+			config, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ViewStaleness).To(BeNil())
+
+			pipeline.Spec.ViewStaleness = &cyndi.ViewStaleness{}
+			config, err = BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*config.ViewStaleness).To(Equal(ViewStalenessConfiguration{
+				Column:      defaultViewStalenessColumn,
+				StaleAfter:  defaultViewStaleAfter,
+				CulledAfter: defaultViewCulledAfter,
+			}))
+
+			column := "host_staleness"
+			staleAfter := int64(3600)
+			pipeline.Spec.ViewStaleness = &cyndi.ViewStaleness{Column: &column, StaleAfter: &staleAfter}
+			config2, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config2.ViewStaleness.Column).To(Equal(column))
+			Expect(config2.ViewStaleness.StaleAfter).To(Equal(staleAfter))
+			Expect(config2.SpecHash).To(Equal(config.SpecHash))
+
+			culledAfter := int64(60)
+			pipeline.Spec.ViewStaleness.CulledAfter = &culledAfter
+			_, err = BuildCyndiConfig(&pipeline, nil)
+			Expect(err).To(MatchError(`"60" is not a valid value for "viewStaleness.culledAfter"`))
+		})
+
+		It("Configures column types", func() {
+			config, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBColumnTypes).To(Equal(ColumnTypes{Id: IdColumnTypeUUID, Timestamp: TimestampColumnTypeTZ}))
+			Expect(config.DBColumnTypes.IdSQLType()).To(Equal("uuid"))
+			Expect(config.DBColumnTypes.TimestampSQLType()).To(Equal("timestamp with time zone"))
+
+			config, err = BuildCyndiConfig(nil, map[string]string{"db.column.type.id": "text", "db.column.type.timestamp": "timestamp"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBColumnTypes.IdSQLType()).To(Equal(`text COLLATE "C"`))
+			Expect(config.DBColumnTypes.TimestampSQLType()).To(Equal("timestamp without time zone"))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"db.column.type.id": "varchar"})
+			Expect(err).To(MatchError(`"varchar" is not a valid value for "db.column.type.id"`))
+		})
+
+		It("Configures case-insensitive searches of display_name", func() {
+			config, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBDisplayNameSearch).To(Equal(DisplayNameSearchExact))
+			Expect(config.DBDisplayNameSearch.SQLType()).To(Equal("character varying(200)"))
+
+			config, err = BuildCyndiConfig(nil, map[string]string{"db.displayname.search": "citext"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBDisplayNameSearch.SQLType()).To(Equal("citext"))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"db.displayname.search": "citext", "db.dialect": "cockroachdb"})
+			Expect(err).To(MatchError(`"db.displayname.search" is not supported by the cockroachdb dialect`))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"db.displayname.search": "ilike"})
+			Expect(err).To(MatchError(`"ilike" is not a valid value for "db.displayname.search"`))
+		})
+
+		It("Rejects features not supported by CockroachDB", func() {
+			_, err := BuildCyndiConfig(nil, map[string]string{"db.dialect": "cockroachdb", "db.table.unlogged": "true"})
+			Expect(err).To(MatchError(`"db.table.unlogged" is not supported by the cockroachdb dialect`))
+
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					DBTablePartitioning: &cyndi.TablePartitioning{Strategy: cyndi.PartitioningHash, Partitions: 8},
+				},
+			}
+
+			_, err = BuildCyndiConfig(&pipeline, map[string]string{"db.dialect": "cockroachdb"})
+			Expect(err).To(MatchError(`"dbTablePartitioning" is not supported by the cockroachdb dialect`))
+
+			_, err = BuildCyndiConfig(nil, map[string]string{"db.dialect": "cockroachdb", "validation.strategy": "blockhash"})
+			Expect(err).To(MatchError(`"validation.strategy" is not supported by the cockroachdb dialect`))
+		})
+
+		It("Merges storage parameters", func() {
+			cm := &corev1.ConfigMap{
+				Data: map[string]string{
+					"db.table.storage.parameters": `{"fillfactor": "70", "autovacuum_vacuum_scale_factor": "0.05"}`,
+					"db.table.compression":        "pglz",
+				},
+			}
+
+			compression := "lz4"
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					DBTableStorageParameters: map[string]string{"fillfactor": "50"},
+					DBTableCompression:       &compression,
+				},
+			}
+
+			config, err := BuildCyndiConfig(&pipeline, cm.Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.DBTableStorageParameters).To(Equal(map[string]string{"fillfactor": "50", "autovacuum_vacuum_scale_factor": "0.05"}))
+			Expect(config.DBTableCompression).To(Equal("lz4"))
+		})
+
+		It("Rejects storage parameters that are not plain identifiers", func() {
+			pipeline := cyndi.CyndiPipeline{
+				Spec: cyndi.CyndiPipelineSpec{
+					DBTableStorageParameters: map[string]string{"fillfactor": "50); DROP TABLE inventory.hosts; --"},
+				},
+			}
+
+			_, err := BuildCyndiConfig(&pipeline, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
 		It("Overrides Topic", func() {
 			cm := &corev1.ConfigMap{
 				Data: map[string]string{
@@ -241,6 +1229,45 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	DescribeTable("Combines thresholds",
+		func(countThreshold int64, mode ThresholdMode, ratio float64, count int64, expected bool) {
+			config := ValidationConfiguration{PercentageThreshold: 5, CountThreshold: countThreshold, ThresholdMode: mode}
+			Expect(config.IsWithinThreshold(ratio, count)).To(Equal(expected))
+		},
+		Entry("percentage only - within", int64(-1), ThresholdModeAll, 0.05, int64(500), true),
+		Entry("percentage only - above", int64(-1), ThresholdModeAll, 0.06, int64(1), false),
+		Entry("all - both within", int64(10), ThresholdModeAll, 0.01, int64(10), true),
+		Entry("all - count above", int64(10), ThresholdModeAll, 0.01, int64(11), false),
+		Entry("all - percentage above", int64(10), ThresholdModeAll, 0.5, int64(5), false),
+		Entry("any - count within", int64(10), ThresholdModeAny, 0.5, int64(5), true),
+		Entry("any - percentage within", int64(10), ThresholdModeAny, 0.01, int64(5000), true),
+		Entry("any - both above", int64(10), ThresholdModeAny, 0.5, int64(5000), false),
+		Entry("no mismatch", int64(0), ThresholdModeAll, 0.0, int64(0), true),
+		Entry("all - both at threshold", int64(10), ThresholdModeAll, 0.05, int64(10), true),
+		Entry("all - count just above", int64(10), ThresholdModeAll, 0.05, int64(11), false),
+		Entry("all - percentage just above", int64(10), ThresholdModeAll, 0.0501, int64(10), false),
+		Entry("all - zero count threshold", int64(0), ThresholdModeAll, 0.01, int64(1), false),
+		Entry("any - count at threshold", int64(10), ThresholdModeAny, 0.5, int64(10), true),
+		Entry("any - percentage at threshold", int64(10), ThresholdModeAny, 0.05, int64(5000), true),
+		Entry("any - both just above", int64(10), ThresholdModeAny, 0.0501, int64(11), false),
+		Entry("any - zero count threshold", int64(0), ThresholdModeAny, 0.5, int64(0), true),
+		Entry("percentage only - ignores mode", int64(-1), ThresholdModeAny, 0.06, int64(0), false),
+	)
+
+	DescribeTable("Computes backoff delay",
+		func(failures int64, expected time.Duration, expectedOpen bool) {
+			config := BackoffConfiguration{BaseInterval: 5, MaxInterval: 60, CircuitFailureThreshold: 10, CircuitOpenInterval: 1800}
+			delay, open := config.Delay(failures)
+			Expect(delay).To(Equal(expected))
+			Expect(open).To(Equal(expectedOpen))
+		},
+		Entry("first failure", int64(1), 5*time.Second, false),
+		Entry("second failure", int64(2), 10*time.Second, false),
+		Entry("fourth failure", int64(4), 40*time.Second, false),
+		Entry("capped", int64(9), 60*time.Second, false),
+		Entry("circuit open", int64(10), 1800*time.Second, true),
+	)
+
 	It("Computes ConfigMap version", func() {
 		cm := &corev1.ConfigMap{
 			Data: map[string]string{
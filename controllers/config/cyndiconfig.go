@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+The CyndiConfig resource provides typed defaults equivalent to keys of the cyndi ConfigMap.
+Its fields are translated to ConfigMap keys so that both sources go through the same parsing and refresh detection.
+
+*/
+
+// Returns ConfigMap data equivalent to the cluster-wide defaults merged with defaults of the given namespace
+func CyndiConfigData(config *cyndi.CyndiConfig, namespace string) map[string]string {
+	if config == nil {
+		return nil
+	}
+
+	result := pipelineDefaultsData(config.Spec.PipelineDefaults)
+
+	for _, override := range config.Spec.Namespaces {
+		if override.Namespace == namespace {
+			result = utils.Merge(result, pipelineDefaultsData(override.PipelineDefaults))
+		}
+	}
+
+	return result
+}
+
+func pipelineDefaultsData(defaults cyndi.PipelineDefaults) map[string]string {
+	result := map[string]string{}
+
+	setString(result, "connector.topic", defaults.Topic)
+	setString(result, "connect.cluster", defaults.ConnectCluster)
+	setString(result, "inventory.dbSecret", defaults.InventoryDbSecret)
+	setString(result, "connector.config", defaults.ConnectorTemplate)
+	setInt(result, "connector.tasks.max", defaults.ConnectorTasksMax)
+	setInt(result, "connector.batch.size", defaults.ConnectorBatchSize)
+	setInt(result, "connector.max.age", defaults.MaxAge)
+	setString(result, "db.schema", defaults.DBSchema)
+	setInt(result, reconcileInterval, defaults.StandardInterval)
+	setValidationDefaults(result, "", defaults.Validation)
+	setValidationDefaults(result, "init.", defaults.InitValidation)
+
+	return result
+}
+
+func setValidationDefaults(data map[string]string, prefix string, defaults *cyndi.ValidationDefaults) {
+	if defaults == nil {
+		return
+	}
+
+	setInt(data, fmt.Sprintf("%s%s", prefix, validationInterval), defaults.Interval)
+	setInt(data, fmt.Sprintf("%s%s", prefix, validationAttemptsThreshold), defaults.AttemptsThreshold)
+	setInt(data, fmt.Sprintf("%s%s", prefix, validationPercentageThreshold), defaults.PercentageThreshold)
+	setInt(data, fmt.Sprintf("%s%s", prefix, validationCountThreshold), defaults.CountThreshold)
+	setString(data, fmt.Sprintf("%s%s", prefix, validationThresholdMode), defaults.ThresholdMode)
+}
+
+func setString(data map[string]string, key string, value *string) {
+	if value != nil {
+		data[key] = *value
+	}
+}
+
+func setInt(data map[string]string, key string, value *int64) {
+	if value != nil {
+		data[key] = strconv.FormatInt(*value, 10)
+	}
+}
@@ -11,17 +11,23 @@ const defaultConnectorTemplate = `{
 	"key.converter": "org.apache.kafka.connect.storage.StringConverter",
 	"value.converter": "org.apache.kafka.connect.json.JsonConverter",
 	"value.converter.schemas.enable": false,
-	"connection.url": "jdbc:postgresql://{{.DBHostname}}:{{.DBPort}}/{{.DBName}}?sslmode={{.SSLMode}}&sslrootcert={{.SSLRootCert}}",
+	{{ if .ConsumerGroup }}
+	"consumer.override.group.id": "{{.ConsumerGroup}}",
+	{{ end }}
+	{{ if .AutoOffsetReset }}
+	"consumer.override.auto.offset.reset": "{{.AutoOffsetReset}}",
+	{{ end }}
+	"connection.url": "jdbc:postgresql://{{.DBHostname}}:{{.DBPort}}/{{.DBName}}?sslmode={{.SSLMode}}&sslrootcert={{.SSLRootCert}}{{.DBAuthParameters}}",
 	"connection.user": "{{.DBUser}}",
 	"connection.password": "{{.DBPassword}}",
 	"dialect.name": "EnhancedPostgreSqlDatabaseDialect",
 	"auto.create": false,
 	"insert.mode": "upsert",
-	"delete.enabled": true,
+	"delete.enabled": {{.DeleteEnabled}},
 	"batch.size": "{{.BatchSize}}",
 	"table.name.format": "inventory.{{.TableName}}",
-	"pk.mode": "record_key",
-	"pk.fields": "id",
+	"pk.mode": "{{.PKMode}}",
+	"pk.fields": "{{.PKFields}}",
 	"fields.whitelist": "account,org_id,display_name,tags,updated,created,stale_timestamp,system_profile,insights_id,reporter,per_reporter_staleness,groups",
 
 	{{ range $element := .AdditionalFilters }}
@@ -33,17 +39,19 @@ const defaultConnectorTemplate = `{
 	{{ end }}
 
 	{{ if eq .InsightsOnly "true" }}
-	"transforms": "timestampFilter,insightsFilter,{{ range $element := .AdditionalFilters }}{{ $element.name }},{{ end }}deleteToTombstone,extractHost,systemProfileFilter,systemProfileToJson,tagsToJson,perReporterStalenessToJson,groupsToJson,injectSchemaKey,injectSchemaValue",
+	"transforms": "timestampFilter,insightsFilter,{{ range $element := .AdditionalFilters }}{{ $element.name }},{{ end }}{{ if eq .DeleteEnabled "false" }}deleteFilter,{{ else if eq .Tombstones "convert" }}deleteToTombstone,{{ end }}extractHost,systemProfileFilter,systemProfileToJson,tagsToJson,perReporterStalenessToJson,groupsToJson,injectSchemaKey,injectSchemaValue",
 	"transforms.insightsFilter.type":"com.redhat.insights.kafka.connect.transforms.Filter",
 	"transforms.insightsFilter.if": "!!record.headers().lastWithName('insights_id').value()",
 	{{ else  }}
-	"transforms": "timestampFilter,{{ range $element := .AdditionalFilters }}{{ $element.name }},{{ end }}deleteToTombstone,extractHost,systemProfileFilter,systemProfileToJson,tagsToJson,perReporterStalenessToJson,groupsToJson,injectSchemaKey,injectSchemaValue",
+	"transforms": "timestampFilter,{{ range $element := .AdditionalFilters }}{{ $element.name }},{{ end }}{{ if eq .DeleteEnabled "false" }}deleteFilter,{{ else if eq .Tombstones "convert" }}deleteToTombstone,{{ end }}extractHost,systemProfileFilter,systemProfileToJson,tagsToJson,perReporterStalenessToJson,groupsToJson,injectSchemaKey,injectSchemaValue",
 	{{ end }}
 
 	"transforms.timestampFilter.type":"com.redhat.insights.kafka.connect.transforms.Filter",
-	"transforms.timestampFilter.if": "(Date.now() - record.timestamp()) < {{.MaxAge}} * 24 * 60 * 60 * 1000",
+	"transforms.timestampFilter.if": "(Date.now() - record.timestamp()) < {{.MaxAge}} * 24 * 60 * 60 * 1000{{ if .MinTimestamp }} && record.timestamp() >= {{.MinTimestamp}}{{ end }}",
 	"transforms.deleteToTombstone.type":"com.redhat.insights.kafka.connect.transforms.DropIf$Value",
 	"transforms.deleteToTombstone.if": "'delete'.equals(record.headers().lastWithName('event_type').value())",
+	"transforms.deleteFilter.type":"com.redhat.insights.kafka.connect.transforms.Filter",
+	"transforms.deleteFilter.if": "record.value() != null && !'delete'.equals(record.headers().lastWithName('event_type').value())",
 	"transforms.extractHost.type":"org.apache.kafka.connect.transforms.ExtractField$Value",
 	"transforms.extractHost.field":"host",
 	"transforms.systemProfileFilter.type": "com.redhat.insights.kafka.connect.transforms.FilterFields$Value",
@@ -62,9 +70,9 @@ const defaultConnectorTemplate = `{
 	"transforms.groupsToJson.originalField": "groups",
 	"transforms.groupsToJson.destinationField": "groups",
 	"transforms.injectSchemaKey.type": "com.redhat.insights.kafka.connect.transforms.InjectSchema$Key",
-	"transforms.injectSchemaKey.schema": "{\"type\":\"string\",\"optional\":false, \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=uuid\"}",
+	"transforms.injectSchemaKey.schema": "{\"type\":\"string\",\"optional\":false, \"name\": \"com.redhat.cloud.inventory.syndication.pgtype={{.IdType}}\"}",
 	"transforms.injectSchemaValue.type": "com.redhat.insights.kafka.connect.transforms.InjectSchema$Value",
-	"transforms.injectSchemaValue.schema": "{\"type\":\"struct\",\"fields\":[{\"type\":\"string\",\"optional\":true,\"field\":\"account\"},{\"type\":\"string\",\"optional\":true,\"field\":\"org_id\"},{\"type\":\"string\",\"optional\":false,\"field\":\"display_name\"},{\"type\":\"string\",\"optional\":false,\"field\":\"tags\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=jsonb\"},{\"type\":\"string\",\"optional\":false,\"field\":\"updated\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=timestamptz\"},{\"type\":\"string\",\"optional\":false,\"field\":\"created\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=timestamptz\"},{\"type\":\"string\",\"optional\":false,\"field\":\"stale_timestamp\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=timestamptz\"},{\"type\":\"string\",\"optional\":false,\"field\":\"system_profile\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=jsonb\"},{\"type\":\"string\",\"optional\":true,\"field\":\"insights_id\"},{\"type\":\"string\",\"optional\":false,\"field\":\"reporter\"},{\"type\":\"string\",\"optional\":false,\"field\":\"per_reporter_staleness\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=jsonb\"},{\"type\":\"string\",\"optional\":true,\"field\":\"groups\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=jsonb\"}],\"optional\":false}",
+	"transforms.injectSchemaValue.schema": "{\"type\":\"struct\",\"fields\":[{\"type\":\"string\",\"optional\":true,\"field\":\"account\"},{\"type\":\"string\",\"optional\":true,\"field\":\"org_id\"},{\"type\":\"string\",\"optional\":false,\"field\":\"display_name\"},{\"type\":\"string\",\"optional\":false,\"field\":\"tags\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=jsonb\"},{\"type\":\"string\",\"optional\":false,\"field\":\"updated\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype={{.TimestampType}}\"},{\"type\":\"string\",\"optional\":false,\"field\":\"created\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype={{.TimestampType}}\"},{\"type\":\"string\",\"optional\":false,\"field\":\"stale_timestamp\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype={{.TimestampType}}\"},{\"type\":\"string\",\"optional\":false,\"field\":\"system_profile\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=jsonb\"},{\"type\":\"string\",\"optional\":true,\"field\":\"insights_id\"},{\"type\":\"string\",\"optional\":false,\"field\":\"reporter\"},{\"type\":\"string\",\"optional\":false,\"field\":\"per_reporter_staleness\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=jsonb\"},{\"type\":\"string\",\"optional\":true,\"field\":\"groups\", \"name\": \"com.redhat.cloud.inventory.syndication.pgtype=jsonb\"}],\"optional\":false}",
 
 	"errors.tolerance": "all",
 	"errors.deadletterqueue.topic.name": "{{.DeadLetterQueueTopicName}}",
@@ -79,12 +87,95 @@ const defaultConnectorTemplate = `{
 	"connection.backoff.ms": 10000
 }`
 
+// Debezium source connector publishing changes of the HBI tables (see spec.manageSourceConnector)
+const defaultSourceConnectorTemplate = `{
+	"connector.class": "{{.Class}}",
+	"tasks.max": "1",
+	"database.hostname": "{{.DBHostname}}",
+	"database.port": "{{.DBPort}}",
+	"database.user": "{{.DBUser}}",
+	"database.password": "{{.DBPassword}}",
+	"database.dbname": "{{.DBName}}",
+	"database.sslmode": "{{.SSLMode}}",
+	"topic.prefix": "{{.SlotName}}",
+	"plugin.name": "pgoutput",
+	"slot.name": "{{.SlotName}}",
+	"publication.name": "{{.SlotName}}",
+	"publication.autocreate.mode": "filtered",
+	"table.include.list": "{{.TableIncludeList}}",
+	"snapshot.mode": "initial",
+	{{ if .HeartbeatActionQuery }}
+	"heartbeat.interval.ms": "{{.HeartbeatInterval}}",
+	"heartbeat.action.query": {{ toJson .HeartbeatActionQuery }},
+	"predicates": "isHeartbeat",
+	"predicates.isHeartbeat.type": "org.apache.kafka.connect.transforms.predicates.TopicNameMatches",
+	"predicates.isHeartbeat.pattern": {{ toJson .HeartbeatTopicPattern }},
+	"transforms": "dropHeartbeat,unwrap,extractKey,route",
+	"transforms.dropHeartbeat.type": "org.apache.kafka.connect.transforms.Filter",
+	"transforms.dropHeartbeat.predicate": "isHeartbeat",
+	{{ else }}
+	"transforms": "unwrap,extractKey,route",
+	{{ end }}
+	"key.converter": "org.apache.kafka.connect.json.JsonConverter",
+	"key.converter.schemas.enable": false,
+	"value.converter": "org.apache.kafka.connect.json.JsonConverter",
+	"value.converter.schemas.enable": false,
+	"transforms.unwrap.type": "io.debezium.transforms.ExtractNewRecordState",
+	"transforms.unwrap.drop.tombstones": false,
+	"transforms.extractKey.type": "org.apache.kafka.connect.transforms.ExtractField$Key",
+	"transforms.extractKey.field": "id",
+	"transforms.route.type": "org.apache.kafka.connect.transforms.RegexRouter",
+	"transforms.route.regex": ".*",
+	"transforms.route.replacement": "{{.Topic}}"
+}`
+
+const defaultSourceTable = "public.hosts"
+
+const defaultSourceHeartbeatTable = "public.cyndi_heartbeat"
+const defaultSourceHeartbeatInterval int64 = 60 * 1000
+
+// the Kafka Connect image needs to provide the Debezium PostgreSQL plugin and INVENTORY_DB_* environment variables with the credentials of HBI
+const defaultSourceConnectorClass = "io.debezium.connector.postgresql.PostgresConnector"
+const defaultSourceDBEnvPrefix = "INVENTORY"
+
+const defaultSourceSlotLagThreshold int64 = 256 * 1024 * 1024
+const defaultSourceSlotRetentionThreshold int64 = 4 * 1024 * 1024 * 1024
+
+const defaultConnectorFlavor = ConnectorFlavorConfluent
 const defaultConnectorTasksMax int64 = 16
 const defaultConnectorBatchSize int64 = 100
 const defaultConnectorMaxAge int64 = 45
 const defaultTopicReplicationFactor int64 = 1
 const defaultDeadLetterQueueTopicName = "platform.cyndi.dlq"
 const defaultAllowlistSystemProfile = "sap_system,sap_sids"
+const defaultConnectorDeleteEnabled = true
+const defaultConnectorTombstones = TombstonesConvert
+const defaultConnectorPKMode = PKModeRecordKey
+const defaultConnectorPKFields = "id"
+
+// the default of Kafka Connect (earliest for sink connectors) applies
+const defaultConnectorAutoOffsetReset AutoOffsetReset = ""
+
+const defaultDBTableUnlogged = false
+
+const defaultDBTableCloneEnabled = false
+const defaultDBTableCloneMargin int64 = 3600
+
+// the previous table is dropped right after a cutover
+const defaultDBTableRetention int64 = 0
+
+const defaultDBBootstrapEnabled = false
+
+// the boundaries of culled_timestamp
+const defaultViewStalenessColumn = "staleness"
+const defaultViewStaleAfter int64 = 0
+const defaultViewCulledAfter int64 = 14 * 24 * 3600
+
+const defaultRefreshStrategy = RefreshStrategyFull
+const defaultRefreshHandoverGracePeriod int64 = 60
+
+// use the default compression method of the database
+const defaultDBTableCompression = ""
 
 const defaultSSLMode = "disable"
 const defaultSSLRootCert = "none"
@@ -92,22 +183,35 @@ const defaultSSLRootCert = "none"
 // initially every host may not include org_id
 const defaultDBTableInitScript = `
 CREATE TABLE inventory.{{.TableName}} (
-	id uuid PRIMARY KEY,
+	id {{.IdType}} PRIMARY KEY,
 	account character varying(10),
-	display_name character varying(200) NOT NULL,
+	display_name {{.DisplayNameType}} NOT NULL,
 	tags jsonb NOT NULL,
-	updated timestamp with time zone NOT NULL,
-	created timestamp with time zone NOT NULL,
-	stale_timestamp timestamp with time zone NOT NULL,
+	updated {{.TimestampType}} NOT NULL,
+	created {{.TimestampType}} NOT NULL,
+	stale_timestamp {{.TimestampType}} NOT NULL,
 	system_profile jsonb NOT NULL,
-	insights_id uuid,
+	insights_id {{.IdType}},
 	reporter character varying(255) NOT NULL,
 	per_reporter_staleness jsonb NOT NULL,
 	org_id character varying(36),
 	groups jsonb
-);
+){{ if .Partitioned }} PARTITION BY HASH (id){{ end }};
 `
 
+const defaultDBDialect = DBDialectPostgres
+
+// the types of HBI
+const defaultDBColumnTypeId = IdColumnTypeUUID
+const defaultDBColumnTypeTimestamp = TimestampColumnTypeTZ
+
+const defaultDBDisplayNameSearch = DisplayNameSearchExact
+
+const defaultInventorySource = InventorySourceDatabase
+
+const defaultSmokeTestEnabled = false
+const defaultSmokeTestImage = "registry.redhat.io/rhel8/postgresql-12"
+
 const defaultDBTableIndexSQL = `
 CREATE INDEX {{.TableName}}_account_index ON inventory.{{.TableName}}
 (account);
@@ -143,16 +247,135 @@ CREATE INDEX {{.TableName}}_groups_index ON inventory.{{.TableName}}
 USING GIN (groups JSONB_PATH_OPS);
 `
 
+// CockroachDB uses inverted indexes instead of GIN indexes and does not support jsonb_path_ops
+const defaultCockroachDBTableIndexSQL = `
+CREATE INDEX {{.TableName}}_account_index ON inventory.{{.TableName}}
+(account);
+
+CREATE INDEX {{.TableName}}_org_id_index ON inventory.{{.TableName}}
+(org_id);
+
+CREATE INDEX {{.TableName}}_display_name_index ON inventory.{{.TableName}}
+(display_name);
+
+CREATE INVERTED INDEX {{.TableName}}_tags_index ON inventory.{{.TableName}}
+(tags);
+
+CREATE INDEX {{.TableName}}_stale_timestamp_index ON
+inventory.{{.TableName}} (stale_timestamp);
+
+CREATE INVERTED INDEX {{.TableName}}_system_profile_index ON inventory.{{.TableName}}
+(system_profile);
+
+CREATE INDEX {{.TableName}}_insights_id_index ON
+inventory.{{.TableName}} (insights_id);
+
+CREATE INDEX {{.TableName}}_insights_reporter_index ON
+inventory.{{.TableName}} (reporter);
+
+CREATE INVERTED INDEX {{.TableName}}_per_reporter_staleness_index ON inventory.{{.TableName}}
+(per_reporter_staleness);
+
+CREATE INDEX {{.TableName}}_org_id_id_index ON inventory.{{.TableName}}
+(org_id,id);
+`
+
 const defaultStandardInterval int64 = 120
 
 var defaultValidationConfig = ValidationConfiguration{
 	Interval:            60 * 30,
 	AttemptsThreshold:   3,
 	PercentageThreshold: 5,
+	CountThreshold:      -1,
+	ThresholdMode:       ThresholdModeAll,
+	LingeringThreshold:  -1,
 }
 
 var defaultValidationConfigInit = ValidationConfiguration{
 	Interval:            60,
 	AttemptsThreshold:   30,
 	PercentageThreshold: 5,
+	CountThreshold:      -1,
+	ThresholdMode:       ThresholdModeAll,
+	LingeringThreshold:  -1,
+}
+
+const defaultInitialSyncStuckTimeout int64 = 60 * 60
+const defaultInitialSyncStuckAction = StuckActionNone
+const defaultInitialSyncThroughputMin int64 = 0
+const defaultInitialSyncTimeout int64 = 0
+const defaultInitialSyncTimeoutAction = TimeoutActionNone
+
+const defaultValidationDiffEnabled = false
+const defaultValidationDiffMaxIds int64 = 1000
+
+const defaultValidationStrategy = ValidationStrategyIds
+const defaultValidationBlockSize int64 = 10000
+const defaultValidationSamplePercentage int64 = 10
+const defaultValidationMemoryBudget int64 = 64 * 1024 * 1024
+const defaultValidationHBIMinCount int64 = 1
+const defaultValidationOrgCountsTop int64 = 0
+const defaultValidationReporterCounts = false
+const defaultValidationXjoinEnabled = false
+const defaultValidationSkipMaxDuration int64 = 7 * 24 * 3600
+const defaultValidationShadowCycles int64 = 1
+const defaultValidationSlowQueryThreshold int64 = 0
+
+const defaultValidationMode = ValidationModeController
+const defaultValidationCronJobSchedule = "*/5 * * * *"
+
+// the service account of the operator (see config/rbac)
+const defaultValidationCronJobSA = "cyndi-operator-controller-manager"
+
+// the load of HBI is not checked
+const defaultValidationLoadMaxActive int64 = 0
+const defaultValidationLoadDeferInterval int64 = 300
+
+const defaultViewSwitchMaxShrink int64 = 50
+
+const defaultMonitoringDashboardEnabled = false
+const defaultMonitoringRulesEnabled = false
+
+// long enough for the initial sync of most pipelines
+const defaultAlertmanagerSilenceDuration int64 = 2 * 3600
+
+const defaultAuditTableEnabled = false
+
+const defaultDriftReportEnabled = false
+
+const defaultStateExportEnabled = false
+
+const defaultNetworkPolicyEnabled = false
+
+const defaultTopicPreflightEnabled = false
+
+var defaultCanaryConfig = CanaryConfiguration{
+	Enabled:  false,
+	Interval: 60 * 15,
+	Timeout:  60 * 10,
+}
+
+var defaultDBRetryConfig = DBRetryConfiguration{
+	Attempts:     3,
+	BaseInterval: 100,
+}
+
+var defaultAnomalyConfig = AnomalyConfiguration{
+	Enabled:  false,
+	Window:   10,
+	Sigma:    3,
+	MinHosts: 100,
+}
+
+var defaultMismatchedIdsConfig = MismatchedIdsConfiguration{
+	Max:    100000,
+	Sample: 10,
+	Log:    false,
+}
+
+var defaultBackoffConfig = BackoffConfiguration{
+	BaseInterval:            5,
+	MaxInterval:             60 * 10,
+	CircuitFailureThreshold: 10,
+	CircuitOpenInterval:     60 * 30,
 }
@@ -0,0 +1,48 @@
+package config
+
+/*
+
+Settings whose defaults are specific to the environment the operator was developed for: names of topics, secrets and the Kafka Connect cluster,
+connector classes (i.e. plugins of the Kafka Connect image) and images. All of them are defaults in code that can be overridden
+in the cyndi ConfigMap (or CyndiConfig). Disconnected and on-prem installs typically need to override most of them, so the operator
+reports at startup which of them are in effect.
+
+*/
+
+type EnvironmentSetting struct {
+	Key   string
+	Value string
+	// true if the value is the default in code rather than configured
+	Default bool
+}
+
+// Environment-specific settings of the given configuration built from the given ConfigMap data
+func EnvironmentSettings(config *CyndiConfiguration, cm map[string]string) []EnvironmentSetting {
+	connectCluster := config.ConnectCluster
+	if config.ConnectClusterNamespace != "" {
+		connectCluster = config.ConnectClusterNamespace + "/" + connectCluster
+	}
+
+	values := []struct {
+		key   string
+		value string
+	}{
+		{"connector.topic", config.Topic},
+		{"connect.cluster", connectCluster},
+		{"inventory.dbSecret", config.InventoryDbSecret},
+		{"connector.deadletterqueue.topic.name", config.DeadLetterQueueTopicName},
+		{connectorFlavor, string(config.ConnectorFlavor)},
+		{connectorClass, config.ConnectorClass},
+		{connectorSourceClass, config.SourceConnectorClass},
+		{connectorSourceEnvPrefix, config.SourceDBEnvPrefix},
+		{smokeTestImage, config.SmokeTestImage},
+	}
+
+	result := make([]EnvironmentSetting, len(values))
+	for idx, v := range values {
+		_, configured := cm[v.key]
+		result[idx] = EnvironmentSetting{Key: v.key, Value: v.value, Default: !configured}
+	}
+
+	return result
+}
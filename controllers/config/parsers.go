@@ -38,6 +38,11 @@ func ParseDBSecret(secret *corev1.Secret) (DBParams, error) {
 	return dbParams, nil
 }
 
+// Both keys of the API secret are optional
+func ParseAPISecret(secret *corev1.Secret) (token string, identity string) {
+	return string(secret.Data["api.token"]), string(secret.Data["api.identity"])
+}
+
 func readSecretValue(secret *corev1.Secret, key string) (string, error) {
 	value := secret.Data[key]
 	if value == nil || string(value) == "" {
@@ -1,5 +1,12 @@
 package config
 
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
 type DBParams struct {
 	Name        string
 	Host        string
@@ -8,31 +15,423 @@ type DBParams struct {
 	Password    string
 	SSLMode     string
 	SSLRootCert string
+
+	// how operations failing with a transient error are retried
+	Retry DBRetryConfiguration
+	// how the database is authenticated with, the password above if not set
+	Auth DBAuthConfiguration
 }
 
+type APIParams struct {
+	// base URL of the HBI REST API, e.g. http://host-inventory-service:8080/api/inventory/v1
+	URL string
+	// bearer token and/or x-rh-identity header sent with the requests (both optional)
+	Token    string
+	Identity string
+}
+
+type ThresholdMode string
+
+const (
+	// both thresholds need to be met for the pipeline to be valid
+	ThresholdModeAll ThresholdMode = "all"
+	// meeting either of the thresholds is enough for the pipeline to be valid
+	ThresholdModeAny ThresholdMode = "any"
+)
+
 type ValidationConfiguration struct {
 	Interval            int64
 	AttemptsThreshold   int64
 	PercentageThreshold int64
+	// Maximum number of mismatched hosts. A negative value disables the count threshold
+	CountThreshold int64
+	ThresholdMode  ThresholdMode
+	// Maximum number of hosts present in the application table only (e.g. hosts deleted in HBI). A negative value disables the check
+	LingeringThreshold int64
+}
+
+// Determines whether the given mismatch is within the configured thresholds
+func (v ValidationConfiguration) IsWithinThreshold(mismatchRatio float64, mismatchCount int64) bool {
+	withinPercentage := (mismatchRatio * 100) <= float64(v.PercentageThreshold)
+
+	if v.CountThreshold < 0 {
+		return withinPercentage
+	}
+
+	withinCount := mismatchCount <= v.CountThreshold
+
+	if v.ThresholdMode == ThresholdModeAny {
+		return withinPercentage || withinCount
+	}
+
+	return withinPercentage && withinCount
+}
+
+// Controls how often a pipeline that keeps failing to reconcile is retried
+type BackoffConfiguration struct {
+	// Delay (in seconds) after the first failure. The delay doubles with each consecutive failure.
+	BaseInterval int64
+	// Upper bound (in seconds) of the delay
+	MaxInterval int64
+	// Number of consecutive failures after which the circuit opens
+	CircuitFailureThreshold int64
+	// Delay (in seconds) used while the circuit is open
+	CircuitOpenInterval int64
+}
+
+// Controls the detection of unusual changes of the difference between the host counts of the application table and HBI
+type AnomalyConfiguration struct {
+	Enabled bool
+	// Number of past validations the difference is compared to. Detection starts once that many have been recorded
+	Window int64
+	// Number of standard deviations from the mean of past differences above which a difference is unusual
+	Sigma int64
+	// Deviations from the mean by fewer hosts are never unusual, e.g. while the difference has been constant so far
+	MinHosts int64
+}
+
+// Controls how many ids of mismatched hosts validation keeps. Hosts beyond the limits are counted only
+type MismatchedIdsConfiguration struct {
+	// Number of ids of each category (inHbiOnly, inAppOnly) kept in memory, e.g. for the validation diff
+	Max int64
+	// Number of ids of each category recorded in status.mismatchedHosts
+	Sample int64
+	// If enabled, ids that are not kept are logged at the debug level, in pages
+	Log bool
+}
+
+// Controls how database operations failing with a transient error (e.g. a serialization failure) are retried before the error is surfaced
+type DBRetryConfiguration struct {
+	// Number of retries after the first attempt. 0 disables retries
+	Attempts int64
+	// Delay (in milliseconds) before the first retry. The delay doubles with each retry and is randomized by up to half of its value
+	BaseInterval int64
+}
+
+type ValidationMode string
+
+const (
+	// pipelines are validated by the validation controller of the operator
+	ValidationModeController ValidationMode = "controller"
+	// pipelines are validated by a CronJob (one per pipeline) running the validation binary
+	ValidationModeCronJob ValidationMode = "cronjob"
+)
+
+type ValidationCronJobConfiguration struct {
+	// Image providing the validation binary (/validate), usually the image of the operator
+	Image string
+	// Cron schedule of the validation
+	Schedule string
+	// Service account the validation runs as. Needs the permissions of the operator to read pipelines and secrets and update the status of pipelines
+	ServiceAccount string
+	// Schedules the validation on dedicated nodes
+	NodeSelector map[string]string
+	Tolerations  []corev1.Toleration
+	// Memory limit of the validation container, none if nil
+	MemoryLimit *resource.Quantity
+}
+
+type CanaryConfiguration struct {
+	// If enabled, a synthetic host is periodically published to the topic consumed by the connector to measure replication latency
+	Enabled bool
+	// Strimzi Kafka Bridge the canary host is published through
+	BridgeURL string
+	// Topic the canary host is published to. Needs to be one of the topics of the pipeline
+	Topic string
+	// How often (in seconds) a canary host is published
+	Interval int64
+	// How long (in seconds) to wait for the canary host to appear in the application table
+	Timeout int64
+}
+
+// Computed staleness column of the inventory.hosts view (see spec.viewStaleness)
+type ViewStalenessConfiguration struct {
+	Column string
+	// Seconds after stale_timestamp a host becomes stale
+	StaleAfter int64
+	// Seconds after stale_timestamp a host becomes culled
+	CulledAfter int64
+}
+
+// Returns the delay before the next attempt after the given number of consecutive failures and whether the circuit is open
+func (b BackoffConfiguration) Delay(failures int64) (delay time.Duration, circuitOpen bool) {
+	if b.CircuitFailureThreshold > 0 && failures >= b.CircuitFailureThreshold {
+		return time.Duration(b.CircuitOpenInterval) * time.Second, true
+	}
+
+	seconds := b.BaseInterval
+	for n := int64(1); n < failures && seconds < b.MaxInterval; n++ {
+		seconds *= 2
+	}
+
+	if seconds > b.MaxInterval {
+		seconds = b.MaxInterval
+	}
+
+	return time.Duration(seconds) * time.Second, false
+}
+
+type TombstoneMode string
+
+const (
+	// delete events are converted into tombstones by the connector
+	TombstonesConvert TombstoneMode = "convert"
+	// the topic already carries tombstones (e.g. a compacted topic)
+	TombstonesNative TombstoneMode = "native"
+)
+
+// Where a connector starts consuming if its consumer group has no committed offset or the committed offset is no longer retained
+type AutoOffsetReset string
+
+const (
+	AutoOffsetResetEarliest AutoOffsetReset = "earliest"
+	AutoOffsetResetLatest   AutoOffsetReset = "latest"
+	// the connector fails rather than skip events lost to the retention of the topic
+	AutoOffsetResetNone AutoOffsetReset = "none"
+)
+
+type InventorySource string
+
+const (
+	// host counts and host ids are queried from the inventory database(s)
+	InventorySourceDatabase InventorySource = "database"
+	// host counts and host ids are fetched from the HBI REST API
+	InventorySourceAPI InventorySource = "api"
+)
+
+type DBDialect string
+
+const (
+	DBDialectPostgres DBDialect = "postgres"
+	// speaks the PostgreSQL protocol but lacks some of its DDL (partitioning, UNLOGGED tables, GIN indexes, ...)
+	DBDialectCockroach DBDialect = "cockroachdb"
+)
+
+type DBAuthType string
+
+const (
+	DBAuthPassword DBAuthType = "password"
+	// an Azure AD access token of the managed identity is used as the password
+	DBAuthAzureAD DBAuthType = "azure-ad"
+	// an OAuth access token of the service account is used as the password (Cloud SQL IAM database authentication)
+	DBAuthGCPIAM DBAuthType = "gcp-iam"
+)
+
+type DBAuthConfiguration struct {
+	Type DBAuthType
+	// client id of the user-assigned managed identity (azure-ad)
+	ClientID string
+	// connection name of the Cloud SQL instance (gcp-iam)
+	CloudSQLInstance string
 }
 
+// Type of the host id columns (id, insights_id) of the application table
+type IdColumnType string
+
+const (
+	IdColumnTypeUUID IdColumnType = "uuid"
+	// ids are stored as text compared bytewise (COLLATE "C") so that they sort like uuids
+	IdColumnTypeText IdColumnType = "text"
+)
+
+// Type of the timestamp columns (created, updated, stale_timestamp) of the application table
+type TimestampColumnType string
+
+const (
+	TimestampColumnTypeTZ TimestampColumnType = "timestamptz"
+	// timestamps are stored in UTC without a time zone
+	TimestampColumnTypeNoTZ TimestampColumnType = "timestamp"
+)
+
+// Column types of the application table, applied to the table definition, the connector configuration and the queries of validation
+type ColumnTypes struct {
+	Id        IdColumnType
+	Timestamp TimestampColumnType
+}
+
+// SQL type of the id columns
+func (c ColumnTypes) IdSQLType() string {
+	if c.Id == IdColumnTypeText {
+		return `text COLLATE "C"`
+	}
+
+	return "uuid"
+}
+
+// SQL type of the timestamp columns
+func (c ColumnTypes) TimestampSQLType() string {
+	if c.Timestamp == TimestampColumnTypeNoTZ {
+		return "timestamp without time zone"
+	}
+
+	return "timestamp with time zone"
+}
+
+// How the display_name column supports case-insensitive searches
+type DisplayNameSearch string
+
+const (
+	// display_name is a varchar column with a plain index
+	DisplayNameSearchExact DisplayNameSearch = "exact"
+	// display_name is a citext column, i.e. comparisons (and the plain index) are case-insensitive
+	DisplayNameSearchCitext DisplayNameSearch = "citext"
+	// an index on lower(display_name) is created along with the other indexes
+	DisplayNameSearchLowerIndex DisplayNameSearch = "lower-index"
+)
+
+// SQL type of the display_name column
+func (s DisplayNameSearch) SQLType() string {
+	if s == DisplayNameSearchCitext {
+		return "citext"
+	}
+
+	return "character varying(200)"
+}
+
+type ConnectorFlavor string
+
+const (
+	// io.confluent.connect.jdbc.JdbcSinkConnector
+	ConnectorFlavorConfluent ConnectorFlavor = "confluent"
+	// io.debezium.connector.jdbc.JdbcSinkConnector shipped with newer (cloud-native) Connect images
+	ConnectorFlavorCloudNative ConnectorFlavor = "cloud-native"
+)
+
+const PKModeRecordKey = "record_key"
+const PKModeRecordValue = "record_value"
+
+type RefreshStrategy string
+
+const (
+	// the topic is consumed from the beginning
+	RefreshStrategyFull RefreshStrategy = "full"
+	// the connector resumes from the offsets of the previous connector (requires a cloned table)
+	RefreshStrategyOffsets RefreshStrategy = "offsets"
+)
+
+type ValidationStrategy string
+
+const (
+	// host ids are fetched from both databases and compared by the operator
+	ValidationStrategyIds ValidationStrategy = "ids"
+	// both databases compute digests of ranges of host ids, only ids of ranges that differ are fetched
+	ValidationStrategyBlockHash ValidationStrategy = "blockhash"
+	// only host counts are compared, the pipeline may become valid without its host ids being compared
+	ValidationStrategyCounts ValidationStrategy = "counts"
+	// host ids of a random sample of ranges of ids are compared, mismatches are extrapolated to the whole table
+	ValidationStrategySampled ValidationStrategy = "sampled"
+)
+
+type InitialSyncStuckAction string
+
+const (
+	StuckActionNone             InitialSyncStuckAction = "none"
+	StuckActionRestartConnector InitialSyncStuckAction = "restartConnector"
+	StuckActionRefresh          InitialSyncStuckAction = "refresh"
+)
+
+type InitialSyncTimeoutAction string
+
+const (
+	TimeoutActionNone             InitialSyncTimeoutAction = "none"
+	TimeoutActionRestartConnector InitialSyncTimeoutAction = "restartConnector"
+	// the view keeps pointing to the previous table, whose pipeline version becomes current again
+	TimeoutActionAbort InitialSyncTimeoutAction = "abort"
+)
+
 type CyndiConfiguration struct {
+	// comma-separated list of topics
 	Topic string
+	// SQL conditions selecting the HBI hosts carried by each of the topics. Empty unless the pipeline consumes sharded topics
+	TopicFilters []string
 
 	ConnectCluster                  string
+	ConnectClusterNamespace         string // empty if the Connect cluster runs in the namespace of the pipeline
 	ConnectorTemplate               string
+	ConnectorFlavor                 ConnectorFlavor // sink connector implementation the template is rendered for
+	ConnectorClass                  string          // overrides the connector class of the flavor
+	ConnectorKeyMapping             map[string]string
+	ConnectorRequiredKeys           []string
+	ConnectorLabels                 map[string]string // added to the connectors of the pipeline
+	ConnectorAnnotations            map[string]string
 	ConnectorTasksMax               int64
 	ConnectorBatchSize              int64
 	ConnectorMaxAge                 int64
 	ConnectorAllowlistSystemProfile string
 	TopicReplicationFactor          int64
 	DeadLetterQueueTopicName        string
+	ConnectorDeleteEnabled          bool
+	ConnectorTombstones             TombstoneMode
+	ConnectorPKMode                 string
+	ConnectorPKFields               []string
+	// template of the consumer group of the connectors, empty for the default one of Kafka Connect (connect-<connector name>)
+	ConnectorConsumerGroupTemplate string
+	// auto.offset.reset of the connectors, empty for the default one of Kafka Connect
+	ConnectorAutoOffsetReset AutoOffsetReset
+
+	// Debezium source connector managed if spec.manageSourceConnector is set. The template is empty otherwise
+	SourceConnectorTemplate string
+	SourceTables            []string
+	SourceSlotNamePrefix    string
+	SourceConnectorClass    string
+	// prefix of the environment variables of the Kafka Connect cluster holding the credentials of the HBI database (e.g. INVENTORY_DB_HOSTNAME)
+	SourceDBEnvPrefix string
+	// schema-qualified heartbeat table written to every SourceHeartbeatInterval milliseconds. Empty if spec.manageSourceConnector.heartbeat is not set
+	SourceHeartbeatTable    string
+	SourceHeartbeatInterval int64
+	// slots (and publications) of a source connector managed elsewhere whose health is checked
+	SourceReplicationSlots []string
+	SourcePublications     []string
+	// bytes of unconfirmed (lag) and retained WAL above which a replication slot is considered unhealthy
+	SourceSlotLagThreshold       int64
+	SourceSlotRetentionThreshold int64
 
 	// the secret for the inventory DB we should connect to when validating
 	InventoryDbSecret string
+	// secrets of all the inventory DBs if HBI is sharded (contains just InventoryDbSecret otherwise)
+	InventoryDbSecrets []string
+	// where validation reads HBI hosts from
+	InventorySource InventorySource
+	// HBI REST API used if InventorySource is "api" (or ValidationXjoinEnabled is set) and the (optional) secret holding its credentials
+	InventoryAPIURL    string
+	InventoryAPISecret string
+	// The host count of the application table is cross-checked against the HBI API (served by xjoin) during validation
+	ValidationXjoinEnabled bool
+
+	// template of the names of the tables (and connectors) of new pipeline versions. Empty for the default naming (hosts_v1_<timestamp>)
+	NamingTemplate string
 
+	// SQL dialect of the application database
+	DBDialect DBDialect
+	// how the application database is authenticated with (spec.dbAuth)
+	DBAuth            DBAuthConfiguration
 	DBTableInitScript string
 	DBTableIndexSQL   string
+	// types of the id and timestamp columns of the application table
+	DBColumnTypes ColumnTypes
+	// how display_name is created to support case-insensitive searches
+	DBDisplayNameSearch DisplayNameSearch
+	// If enabled, new tables are UNLOGGED during the initial sync
+	DBTableUnlogged bool
+	// If enabled, rows of the active table are copied to the new table when the pipeline is refreshed
+	DBTableCloneEnabled bool
+	// How far back (in seconds) from the time of cloning the connector applies events to a cloned table
+	DBTableCloneMargin int64
+	// How long (in seconds) the previous table is kept after a cutover so that the view can be rolled back to it
+	DBTableRetention int64
+	// If enabled, the inventory schema, DBBootstrapExtensions and the cyndi_admin and cyndi_reader roles are created in the application database unless they exist
+	DBBootstrapEnabled    bool
+	DBBootstrapExtensions []string
+	// How the connector of a refreshed pipeline consumes the topic
+	RefreshStrategy RefreshStrategy
+	// How long (in seconds) to wait for the previous connector to stop before its consumer group is reused
+	RefreshHandoverGracePeriod int64
+	// Storage parameters (e.g. fillfactor) set on new tables
+	DBTableStorageParameters map[string]string
+	// Compression method of the jsonb columns of new tables
+	DBTableCompression string
+	// Computed staleness column of the inventory.hosts view. Nil if the view does not expose one
+	ViewStaleness *ViewStalenessConfiguration
 
 	// How often the Reconcile function should run even if there is no event
 	StandardInterval int64
@@ -40,6 +439,104 @@ type CyndiConfiguration struct {
 	ValidationConfig     ValidationConfiguration
 	ValidationConfigInit ValidationConfiguration
 
+	// How host ids are compared during validation
+	ValidationStrategy ValidationStrategy
+	// Number of hosts up to which the ids of a range that differs are fetched instead of digests of smaller ranges
+	ValidationBlockSize int64
+	// Percentage of the ranges of host ids compared by the sampled validation strategy
+	ValidationSamplePercentage int64
+	// Approximate memory (in bytes) the comparison of host ids may use. Determines the size of the chunks ids are read in
+	ValidationMemoryBudget int64
+	// Pipelines do not become valid while HBI holds fewer hosts (e.g. credentials of an empty database). 0 disables the check
+	ValidationHBIMinCount int64
+	// Number of organizations (with the most hosts in HBI) whose host counts are compared during validation. 0 disables the comparison
+	ValidationOrgCountsTop int64
+	// If enabled, host counts per reporter are determined during validation. The breakdown does not affect the validity of the pipeline
+	ValidationReporterCounts bool
+	// How far ahead (in seconds) the expiry of an annotation skipping a validation check may be. Annotations expiring later are ignored
+	ValidationSkipMaxDuration int64
+	// Number of consecutive successful validations the table of a refresh needs to pass before the view is switched to it
+	ValidationShadowCycles int64
+	// Queries of validation running longer (in seconds) have their plan (EXPLAIN) logged and reported in an event. 0 disables the capture
+	ValidationSlowQueryThreshold int64
+	// The view is not switched to a table holding this many percent fewer hosts than the active table. 100 disables the check
+	ViewSwitchMaxShrink int64
+
+	// How long (in seconds) the host count may stay the same during initial sync before the pipeline is considered stuck
+	// 0 disables the detection
+	InitialSyncStuckTimeout int64
+	// What to do with a pipeline whose initial sync is stuck
+	InitialSyncStuckAction InitialSyncStuckAction
+	// Rows per minute below which the initial sync is reported as slow, e.g. the host count of HBI divided by the minutes the sync may take
+	// 0 disables the warning
+	InitialSyncThroughputMin int64
+	// How long (in seconds) the initial sync may take before the pipeline is marked with the SyncDeadlineExceeded condition
+	// 0 disables the deadline
+	InitialSyncTimeout int64
+	// What to do with a pipeline whose initial sync exceeded its deadline
+	InitialSyncTimeoutAction InitialSyncTimeoutAction
+
+	// If enabled, ids of mismatched hosts found during validation are stored in a ConfigMap
+	ValidationDiffEnabled bool
+	// Maximum number of ids stored in the diff ConfigMap for each category
+	ValidationDiffMaxIds int64
+
+	// Prometheus holding consumer group lag exported by Kafka Exporter. If set, failed validation is not counted while the lag explains the mismatch
+	ValidationLagPrometheusURL string
+
+	// Validation is postponed while more queries than this are running in the HBI database. 0 disables the check
+	ValidationLoadMaxActive int64
+	// Health endpoint of HBI. Validation is postponed while it does not respond with 2xx
+	ValidationLoadHealthURL string
+	// How long (in seconds) validation is postponed while HBI is under pressure
+	ValidationLoadDeferInterval int64
+
+	// If enabled, a GrafanaDashboard is maintained in each namespace with pipelines
+	MonitoringDashboardEnabled bool
+	// If enabled, PrometheusRules alerting on unhealthy pipelines are maintained in each namespace with pipelines
+	MonitoringRulesEnabled bool
+	// Labels added to the monitoring resources (e.g. to match the dashboard selector of a Grafana instance)
+	MonitoringLabels map[string]string
+	// Alertmanager in which the drift alerts of a pipeline are silenced while the operator refreshes it. Empty disables silencing
+	AlertmanagerURL string
+	// How long (in seconds) the drift alerts of a refreshed pipeline are silenced
+	AlertmanagerSilenceDuration int64
+
+	// If enabled, destructive actions are additionally recorded in an append-only table in the application database
+	AuditTableEnabled bool
+
+	// If enabled, differences between the live connector, table and view and what the pipeline would render are reported using the Drifted condition
+	DriftReportEnabled bool
+
+	// If enabled, a Job querying the inventory.hosts view using the credentials of the application is run whenever the view is switched to a new table
+	SmokeTestEnabled bool
+	// Image of the smoke test Job (needs to provide psql)
+	SmokeTestImage string
+
+	// If enabled, egress NetworkPolicies allowing the operator and the Connect cluster to reach the databases of each pipeline are maintained
+	NetworkPolicyEnabled bool
+
+	// If enabled, a new pipeline version is only started once the topics exist. Only the KafkaTopic resources of the Kafka cluster are consulted,
+	// topics not managed by the Strimzi Topic Operator are reported as missing
+	TopicPreflightEnabled bool
+
+	// If enabled, the state of valid pipelines is exported to a ConfigMap that outlives the pipeline (see spec.adoptExisting)
+	StateExportEnabled bool
+
+	BackoffConfig BackoffConfiguration
+
+	AnomalyConfig AnomalyConfiguration
+
+	MismatchedIdsConfig MismatchedIdsConfiguration
+
+	DBRetryConfig DBRetryConfiguration
+
+	CanaryConfig CanaryConfiguration
+
+	// Whether pipelines are validated by the operator or by CronJobs
+	ValidationMode          ValidationMode
+	ValidationCronJobConfig ValidationCronJobConfiguration
+
 	ConfigMapVersion string
 
 	SpecHash string
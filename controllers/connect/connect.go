@@ -5,18 +5,28 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"text/template"
 
+	"github.com/Masterminds/sprig/v3"
+
 	. "github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/faults"
+	"github.com/RedHatInsights/cyndi-operator/controllers/tracing"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
@@ -27,16 +37,36 @@ const (
 	LabelMaxAge         = "cyndi/maxAge"
 	LabelStrimziCluster = "strimzi.io/cluster"
 	LabelOwner          = "cyndi/owner"
+	// identify the owning pipeline of a connector in a different namespace, which cannot carry an owner reference
+	LabelOwnerName      = "cyndi/ownerName"
+	LabelOwnerNamespace = "cyndi/ownerNamespace"
+	// name of the pipeline, set on every resource created for it (e.g. kubectl get kafkaconnectors,jobs -l cyndi.cloud.redhat.com/pipeline=advisor)
+	LabelPipeline = "cyndi.cloud.redhat.com/pipeline"
+	// version of the operator and of the connector template the connector was rendered by
+	// (e.g. kubectl get kafkaconnectors -l 'cyndi/operatorVersion!=<version>' lists connectors rendered by other versions of the operator)
+	LabelOperatorVersion = "cyndi/operatorVersion"
+	LabelTemplateVersion = "cyndi/templateVersion"
 )
 
 const failed = "FAILED"
 
+const annotationRestart = "strimzi.io/restart"
+
+// Hash of the spec the connector was created with. Allows telling changes of the desired configuration from out-of-band modifications.
+const AnnotationSpecHash = "cyndi/specHash"
+
 var connectorGVK = schema.GroupVersionKind{
 	Group:   "kafka.strimzi.io",
 	Kind:    "KafkaConnector",
 	Version: "v1beta2",
 }
 
+var connectClusterGVK = schema.GroupVersionKind{
+	Group:   "kafka.strimzi.io",
+	Kind:    "KafkaConnect",
+	Version: "v1beta2",
+}
+
 var connectorsGVK = schema.GroupVersionKind{
 	Group:   "kafka.strimzi.io",
 	Kind:    "KafkaConnectorList",
@@ -58,14 +88,60 @@ type ConnectorConfiguration struct {
 	AllowlistSystemProfile   string
 	TopicReplicationFactor   int64
 	DeadLetterQueueTopicName string
+	// Events older than this (milliseconds since epoch) are skipped. 0 disables the filter.
+	MinTimestamp int64
+	// Consumer group to use instead of the default one of the connector
+	ConsumerGroup string
+	// Where the connector starts consuming without a (retained) committed offset. The default of Kafka Connect applies if empty
+	AutoOffsetReset AutoOffsetReset
+	// sink connector implementation, the default one if empty
+	Sink          Sink
+	DeleteEnabled bool
+	Tombstones    TombstoneMode
+	PKMode        string
+	PKFields      []string
+	// types of the columns of the table, those of HBI if not set
+	ColumnTypes ColumnTypes
+	// propagated from the pipeline
+	Labels      map[string]string
+	Annotations map[string]string
+	// version of the operator rendering the connector, not recorded if empty
+	OperatorVersion string
+}
+
+// Version of a connector template, recorded in the cyndi/templateVersion label of the connectors rendered from it
+func TemplateVersion(template string) (string, error) {
+	return utils.SpecHash(template)
+}
+
+// Records the versions of the operator and of the template a connector is rendered by in its labels
+func setVersionLabels(labels map[string]interface{}, template string, operatorVersion string) error {
+	templateVersion, err := TemplateVersion(template)
+	if err != nil {
+		return err
+	}
+
+	labels[LabelTemplateVersion] = templateVersion
+
+	// a version that is not a valid label value (e.g. too long) is only recorded in the status of the pipeline
+	if operatorVersion != "" && len(validation.IsValidLabelValue(operatorVersion)) == 0 {
+		labels[LabelOperatorVersion] = operatorVersion
+	}
+
+	return nil
+}
+
+// Name of the consumer group Kafka Connect uses for a sink connector by default
+func ConsumerGroup(connectorName string) string {
+	return "connect-" + connectorName
 }
 
-func CheckIfConnectorExists(c client.Client, name string, namespace string) (bool, error) {
+func CheckIfConnectorExists(ctx context.Context, c client.Client, name string, namespace string) (bool, error) {
 	if name == "" {
 		return false, nil
 	}
 
-	if _, err := GetConnector(c, name, namespace); err != nil && errors.IsNotFound(err) {
+	if _, err := GetConnector(ctx, c, name, namespace); err != nil && errors.IsNotFound(err) {
 		return false, nil
 	} else if err == nil {
 		return true, nil
@@ -74,32 +150,20 @@ func CheckIfConnectorExists(c client.Client, name string, namespace string) (boo
 	}
 }
 
-func newConnectorResource(name string, namespace string, config ConnectorConfiguration) (*unstructured.Unstructured, error) {
+/*
+ * Renders the connector configuration template into a JSON object.
+ * Besides the built-in functions of text/template, templates may use sprig functions (https://masterminds.github.io/sprig/).
+ */
+func renderTemplate(config ConnectorConfiguration) (interface{}, error) {
 	m := make(map[string]interface{})
+	m["AppName"] = config.AppName
 	m["TableName"] = config.TableName
 	m["Topic"] = config.Topic
 
 	appNameFormatted := strings.ReplaceAll(config.AppName, "-", "_")
 	appNameFormatted = strings.ToUpper(appNameFormatted)
 
-	ephemeral, err := strconv.ParseBool(os.Getenv("EPHEMERAL"))
-	if err != nil {
-		ephemeral = false
-	}
-
-	if ephemeral {
-		m["DBPort"] = config.DB.Port
-		m["DBHostname"] = config.DB.Host
-		m["DBName"] = config.DB.Name
-		m["DBUser"] = config.DB.User
-		m["DBPassword"] = config.DB.Password
-	} else {
-		m["DBPort"] = fmt.Sprintf("${env:%s_DB_PORT}", appNameFormatted)
-		m["DBHostname"] = fmt.Sprintf("${env:%s_DB_HOSTNAME}", appNameFormatted)
-		m["DBName"] = fmt.Sprintf("${env:%s_DB_NAME}", appNameFormatted)
-		m["DBUser"] = fmt.Sprintf("${env:%s_DB_USERNAME}", appNameFormatted)
-		m["DBPassword"] = fmt.Sprintf("${env:%s_DB_PASSWORD}", appNameFormatted)
-	}
+	setDBValues(m, config.DB, appNameFormatted)
 
 	m["AdditionalFilters"] = config.AdditionalFilters
 
@@ -110,10 +174,94 @@ func newConnectorResource(name string, namespace string, config ConnectorConfigu
 	m["AllowlistSP"] = config.AllowlistSystemProfile
 	m["SSLMode"] = config.DB.SSLMode
 	m["SSLRootCert"] = config.DB.SSLRootCert
+	setDBAuthValues(m, config.DB.Auth)
 	m["TopicReplicationFactor"] = strconv.FormatInt(config.TopicReplicationFactor, 10)
 	m["DeadLetterQueueTopicName"] = config.DeadLetterQueueTopicName
+	m["ConsumerGroup"] = config.ConsumerGroup
+	m["AutoOffsetReset"] = string(config.AutoOffsetReset)
+	m["DeleteEnabled"] = strconv.FormatBool(config.DeleteEnabled)
+	m["Tombstones"] = string(config.Tombstones)
+	m["PKMode"] = config.PKMode
+	m["PKFields"] = strings.Join(config.PKFields, ",")
+	m["MinTimestamp"] = ""
+	if config.MinTimestamp > 0 {
+		m["MinTimestamp"] = strconv.FormatInt(config.MinTimestamp, 10)
+	}
+
+	// pgtype hints of the JDBC sink, i.e. the names of the PostgreSQL types the values are cast to
+	m["IdType"] = string(IdColumnTypeUUID)
+	if config.ColumnTypes.Id != "" {
+		m["IdType"] = string(config.ColumnTypes.Id)
+	}
+
+	m["TimestampType"] = string(TimestampColumnTypeTZ)
+	if config.ColumnTypes.Timestamp != "" {
+		m["TimestampType"] = string(config.ColumnTypes.Timestamp)
+	}
+
+	return executeTemplate(config.Template, m)
+}
+
+/*
+ * Sets the connection details of a database. Outside of ephemeral environments these are references to environment variables
+ * (<prefix>_DB_HOSTNAME, ...) of the Kafka Connect cluster.
+ */
+func setDBValues(m map[string]interface{}, db DBParams, prefix string) {
+	ephemeral, err := strconv.ParseBool(os.Getenv("EPHEMERAL"))
+	if err != nil {
+		ephemeral = false
+	}
+
+	if ephemeral {
+		m["DBPort"] = db.Port
+		m["DBHostname"] = db.Host
+		m["DBName"] = db.Name
+		m["DBUser"] = db.User
+		m["DBPassword"] = db.Password
+	} else {
+		m["DBPort"] = fmt.Sprintf("${env:%s_DB_PORT}", prefix)
+		m["DBHostname"] = fmt.Sprintf("${env:%s_DB_HOSTNAME}", prefix)
+		m["DBName"] = fmt.Sprintf("${env:%s_DB_NAME}", prefix)
+		m["DBUser"] = fmt.Sprintf("${env:%s_DB_USERNAME}", prefix)
+		m["DBPassword"] = fmt.Sprintf("${env:%s_DB_PASSWORD}", prefix)
+	}
+}
+
+// JDBC authentication plugins of the token authentications of the application database (spec.dbAuth, see database/auth.go)
+const (
+	azureAuthenticationPlugin = "com.azure.identity.extensions.jdbc.postgresql.AzurePostgresqlAuthenticationPlugin"
+	cloudSQLSocketFactory     = "com.google.cloud.sql.postgres.SocketFactory"
+)
 
-	tmpl, err := template.New("configTemplate").Parse(config.Template)
+/*
+ * Sets the parameters appended to the JDBC connection URL (DBAuthParameters) selecting the authentication plugin of the database.
+ * The plugins need to be installed in the Kafka Connect cluster.
+ */
+func setDBAuthValues(m map[string]interface{}, auth DBAuthConfiguration) {
+	params := url.Values{}
+
+	switch auth.Type {
+	case DBAuthAzureAD:
+		params.Set("authenticationPluginClassName", azureAuthenticationPlugin)
+		if auth.ClientID != "" {
+			params.Set("azure.clientId", auth.ClientID)
+		}
+	case DBAuthGCPIAM:
+		params.Set("socketFactory", cloudSQLSocketFactory)
+		params.Set("cloudSqlInstance", auth.CloudSQLInstance)
+		params.Set("enableIamAuth", "true")
+		// the socket factory encrypts the connection itself
+		m["SSLMode"] = "disable"
+	}
+
+	m["DBAuthParameters"] = ""
+	if len(params) > 0 {
+		m["DBAuthParameters"] = "&" + params.Encode()
+	}
+}
+
+func executeTemplate(text string, m map[string]interface{}) (interface{}, error) {
+	tmpl, err := template.New("configTemplate").Funcs(sprig.TxtFuncMap()).Parse(text)
 	if err != nil {
 		return nil, err
 	}
@@ -132,31 +280,121 @@ func newConnectorResource(name string, namespace string, config ConnectorConfigu
 		return nil, err
 	}
 
+	return configTemplateInterface, nil
+}
+
+// Renders the template using sample values. Fails if the template does not render to a JSON object.
+func ValidateTemplate(connectorTemplate string) error {
+	_, err := renderSampleTemplate(connectorTemplate)
+	return err
+}
+
+// Like ValidateTemplate, also failing if the template cannot be used with the given sink connector.
+func ValidateTemplateSink(connectorTemplate string, sink Sink) error {
+	rendered, err := renderSampleTemplate(connectorTemplate)
+	if err != nil {
+		return err
+	}
+
+	config, err := sinkConfig(rendered, sink)
+	if err == nil {
+		err = sink.validate(config)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Invalid connector template: %w", err)
+	}
+
+	return nil
+}
+
+func renderSampleTemplate(connectorTemplate string) (map[string]interface{}, error) {
+	rendered, err := renderTemplate(ConnectorConfiguration{
+		AppName:                  "sample",
+		AdditionalFilters:        []map[string]string{},
+		Topic:                    "platform.inventory.events",
+		TableName:                "hosts_v1_1",
+		TasksMax:                 1,
+		BatchSize:                100,
+		MaxAge:                   45,
+		Template:                 connectorTemplate,
+		TopicReplicationFactor:   1,
+		DeadLetterQueueTopicName: "platform.cyndi.dlq",
+		DeleteEnabled:            true,
+		Tombstones:               TombstonesConvert,
+		PKMode:                   PKModeRecordKey,
+		PKFields:                 []string{"id"},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid connector template: %w", err)
+	}
+
+	object, ok := rendered.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid connector template: does not render to a JSON object")
+	}
+
+	return object, nil
+}
+
+func newConnectorResource(name string, namespace string, config ConnectorConfiguration) (*unstructured.Unstructured, error) {
+	rendered, err := renderTemplate(config)
+	if err != nil {
+		return nil, err
+	}
+
+	configObject, ok := rendered.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Connector template does not render to a JSON object")
+	}
+
+	if configObject, err = sinkConfig(configObject, config.Sink); err != nil {
+		return nil, err
+	}
+
+	spec := map[string]interface{}{
+		"tasksMax": config.TasksMax,
+		"class":    config.Sink.class(),
+		"config":   configObject,
+		"pause":    false,
+	}
+
+	specHash, err := utils.SpecHash(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]interface{}{
+		LabelStrimziCluster: config.Cluster,
+		LabelAppName:        config.AppName,
+		LabelInsightsOnly:   strconv.FormatBool(config.InsightsOnly),
+		LabelMaxAge:         strconv.FormatInt(config.MaxAge, 10),
+	}
+
+	if err = setVersionLabels(labels, config.Template, config.OperatorVersion); err != nil {
+		return nil, err
+	}
+
 	u := &unstructured.Unstructured{}
 	u.Object = map[string]interface{}{
 		"metadata": map[string]interface{}{
 			"name":      name,
 			"namespace": namespace,
-			"labels": map[string]interface{}{
-				LabelStrimziCluster: config.Cluster,
-				LabelAppName:        config.AppName,
-				LabelInsightsOnly:   strconv.FormatBool(config.InsightsOnly),
-				LabelMaxAge:         strconv.FormatInt(config.MaxAge, 10),
+			"labels":    labels,
+			"annotations": map[string]interface{}{
+				AnnotationSpecHash: specHash,
 			},
 		},
-		"spec": map[string]interface{}{
-			"tasksMax": config.TasksMax,
-			"class":    "io.confluent.connect.jdbc.JdbcSinkConnector",
-			"config":   configTemplateInterface,
-			"pause":    false,
-		},
+		"spec": spec,
 	}
 
 	u.SetGroupVersionKind(connectorGVK)
+	SetMetadata(u, config.Labels, config.Annotations)
 	return u, nil
 }
 
-func CreateConnector(c client.Client, name string, namespace string, config ConnectorConfiguration, owner metav1.Object, ownerScheme *runtime.Scheme, dryRun bool) (*unstructured.Unstructured, error) {
+func CreateConnector(ctx context.Context, c client.Client, name string, namespace string, config ConnectorConfiguration, owner metav1.Object, ownerScheme *runtime.Scheme, dryRun bool) (*unstructured.Unstructured, error) {
 	connector, err := newConnectorResource(name, namespace, config)
 
 	if err != nil {
@@ -164,57 +402,172 @@ func CreateConnector(c client.Client, name string, namespace string, config Conn
 	}
 
 	if owner != nil {
-		if err := controllerutil.SetControllerReference(owner, connector, ownerScheme); err != nil {
+		if err := setOwner(connector, owner, ownerScheme); err != nil {
 			return nil, err
 		}
-
-		labels := connector.GetLabels()
-		labels[LabelOwner] = string(owner.GetUID())
-		connector.SetLabels(labels)
 	}
 
 	if dryRun {
 		return connector, nil
 	}
 
-	return connector, c.Create(context.TODO(), connector)
+	if err = faults.Check(faults.ConnectorCreate); err != nil {
+		return nil, err
+	}
+
+	ctx, span := startSpan(ctx, "CreateConnector", name, namespace)
+	err = c.Create(ctx, connector)
+	tracing.End(span, err)
+
+	return connector, err
+}
+
+func setOwner(connector *unstructured.Unstructured, owner metav1.Object, ownerScheme *runtime.Scheme) error {
+	labels := connector.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	labels[LabelOwner] = string(owner.GetUID())
+	labels[LabelPipeline] = owner.GetName()
+	delete(labels, LabelOwnerName)
+	delete(labels, LabelOwnerNamespace)
+
+	// cross-namespace owner references are not allowed - such connectors are removed using the finalizer of the owner
+	if owner.GetNamespace() == connector.GetNamespace() {
+		if err := controllerutil.SetControllerReference(owner, connector, ownerScheme); err != nil {
+			return err
+		}
+	} else {
+		labels[LabelOwnerName] = owner.GetName()
+		labels[LabelOwnerNamespace] = owner.GetNamespace()
+	}
+
+	connector.SetLabels(labels)
+	return nil
+}
+
+/*
+ * Makes the given pipeline the owner of an existing connector, e.g. one created for a previous incarnation of the pipeline.
+ * Owner references to the previous owner are removed.
+ */
+func AdoptConnector(ctx context.Context, c client.Client, connector *unstructured.Unstructured, owner metav1.Object, ownerScheme *runtime.Scheme) (err error) {
+	ctx, span := startSpan(ctx, "AdoptConnector", connector.GetName(), connector.GetNamespace())
+	defer func() { tracing.End(span, err) }()
+
+	connector.SetOwnerReferences(nil)
+
+	if err = setOwner(connector, owner, ownerScheme); err != nil {
+		return err
+	}
+
+	return c.Update(ctx, connector)
 }
 
 // TODO move to k8s?
-func GetConnector(c client.Client, name string, namespace string) (*unstructured.Unstructured, error) {
+func GetConnector(ctx context.Context, c client.Client, name string, namespace string) (*unstructured.Unstructured, error) {
+	ctx, span := startSpan(ctx, "GetConnector", name, namespace)
 	connector := EmptyConnector()
-	err := c.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: namespace}, connector)
+	err := c.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, connector)
+	tracing.End(span, err)
 	return connector, err
 }
 
-func GetConnectorsForOwner(c client.Client, namespace string, owner string) (*unstructured.UnstructuredList, error) {
+// Lists connectors of the given owner. An empty namespace lists connectors in all namespaces.
+func GetConnectorsForOwner(ctx context.Context, c client.Client, namespace string, owner string) (*unstructured.UnstructuredList, error) {
 	connectors := &unstructured.UnstructuredList{}
 	connectors.SetGroupVersionKind(connectorsGVK)
 
-	err := c.List(context.TODO(), connectors, client.InNamespace(namespace), client.MatchingLabels{LabelOwner: owner})
+	err := c.List(ctx, connectors, client.InNamespace(namespace), client.MatchingLabels{LabelOwner: owner})
 	return connectors, err
 }
 
+func startSpan(ctx context.Context, operation string, name string, namespace string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, operation, attribute.String("connector.name", name), attribute.String("connector.namespace", namespace))
+}
+
 func EmptyConnector() *unstructured.Unstructured {
 	connector := &unstructured.Unstructured{}
 	connector.SetGroupVersionKind(connectorGVK)
 	return connector
 }
 
+func GetConnectCluster(ctx context.Context, c client.Client, name string, namespace string) (*unstructured.Unstructured, error) {
+	cluster := EmptyConnectCluster()
+	err := c.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, cluster)
+	return cluster, err
+}
+
+func EmptyConnectCluster() *unstructured.Unstructured {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(connectClusterGVK)
+	return cluster
+}
+
 /*
  * Delete the given connector. This operation is idempotent i.e. it silently ignores if the connector does not exist.
  */
-func DeleteConnector(c client.Client, name string, namespace string) error {
+func DeleteConnector(ctx context.Context, c client.Client, name string, namespace string) error {
+	ctx, span := startSpan(ctx, "DeleteConnector", name, namespace)
+
 	connector := &unstructured.Unstructured{}
 	connector.SetName(name)
 	connector.SetNamespace(namespace)
 	connector.SetGroupVersionKind(connectorGVK)
 
-	if err := c.Delete(context.TODO(), connector); err != nil && !errors.IsNotFound(err) {
+	err := c.Delete(ctx, connector)
+	if errors.IsNotFound(err) {
+		err = nil
+	}
+
+	tracing.End(span, err)
+	return err
+}
+
+/*
+ * Overwrites the spec of the given connector with the spec of the desired one.
+ */
+func RestoreConnector(ctx context.Context, c client.Client, connector *unstructured.Unstructured, desired *unstructured.Unstructured) (err error) {
+	ctx, span := startSpan(ctx, "RestoreConnector", connector.GetName(), connector.GetNamespace())
+	defer func() { tracing.End(span, err) }()
+
+	annotations := connector.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[AnnotationSpecHash] = desired.GetAnnotations()[AnnotationSpecHash]
+	connector.SetAnnotations(annotations)
+	connector.Object["spec"] = desired.Object["spec"]
+
+	if err = faults.Check(faults.ConnectorUpdate); err != nil {
 		return err
 	}
 
-	return nil
+	return c.Update(ctx, connector)
+}
+
+/*
+ * Asks Strimzi to restart the given connector.
+ */
+func RestartConnector(ctx context.Context, c client.Client, name string, namespace string) (err error) {
+	ctx, span := startSpan(ctx, "RestartConnector", name, namespace)
+	defer func() { tracing.End(span, err) }()
+
+	connector, err := GetConnector(ctx, c, name, namespace)
+	if err != nil {
+		return err
+	}
+
+	annotations := connector.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[annotationRestart] = "true"
+	connector.SetAnnotations(annotations)
+
+	return c.Update(ctx, connector)
 }
 
 func IsFailed(connector *unstructured.Unstructured) bool {
@@ -238,3 +591,25 @@ func IsFailed(connector *unstructured.Unstructured) bool {
 
 	return false
 }
+
+/*
+ * Returns true and the message of the Ready condition if the given Kafka Connect cluster reports not being ready.
+ * A cluster without a Ready condition (e.g. not yet reconciled by Strimzi) is not considered not ready.
+ */
+func IsNotReady(cluster *unstructured.Unstructured) (bool, string) {
+	conditions, ok, err := unstructured.NestedSlice(cluster.UnstructuredContent(), "status", "conditions")
+	if !ok || err != nil {
+		return false, ""
+	}
+
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+
+		if ok && conditionMap["type"] == "Ready" && conditionMap["status"] != "True" {
+			message, _ := conditionMap["message"].(string)
+			return true, message
+		}
+	}
+
+	return false, ""
+}
@@ -3,6 +3,7 @@ package connect
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -36,19 +38,28 @@ var dbParams = DBParams{
 
 func sampleConnectorConfig() ConnectorConfiguration {
 	return ConnectorConfiguration{
-		AppName:      "advisor",
-		InsightsOnly: true,
-		Cluster:      "cluster01",
-		Topic:        "platform.inventory.events",
-		TableName:    "table",
-		DB:           dbParams,
-		TasksMax:     8,
-		BatchSize:    3000,
-		MaxAge:       45,
-		Template:     "{}",
+		AppName:       "advisor",
+		InsightsOnly:  true,
+		Cluster:       "cluster01",
+		Topic:         "platform.inventory.events",
+		TableName:     "table",
+		DB:            dbParams,
+		TasksMax:      8,
+		BatchSize:     3000,
+		MaxAge:        45,
+		Template:      "{}",
+		DeleteEnabled: true,
+		Tombstones:    TombstonesConvert,
+		PKMode:        PKModeRecordKey,
+		PKFields:      []string{"id"},
 	}
 }
 
+func defaultTemplate() string {
+	config, _ := BuildCyndiConfig(nil, nil)
+	return config.ConnectorTemplate
+}
+
 var _ = Describe("Connect", func() {
 	var namespace string
 
@@ -98,10 +109,10 @@ var _ = Describe("Connect", func() {
 				Template:     "{}",
 			}
 
-			_, err := CreateConnector(test.Client, connectorName, namespace, config, nil, nil, false)
+			_, err := CreateConnector(context.TODO(), test.Client, connectorName, namespace, config, nil, nil, false)
 			Expect(err).ToNot(HaveOccurred())
 
-			connector, err := GetConnector(test.Client, connectorName, namespace)
+			connector, err := GetConnector(context.TODO(), test.Client, connectorName, namespace)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(connector.GetName()).To(Equal(connectorName))
 
@@ -144,10 +155,10 @@ var _ = Describe("Connect", func() {
 				Template:     configTemplate,
 			}
 
-			_, err := CreateConnector(test.Client, connectorName, namespace, config, nil, nil, false)
+			_, err := CreateConnector(context.TODO(), test.Client, connectorName, namespace, config, nil, nil, false)
 			Expect(err).ToNot(HaveOccurred())
 
-			connector, err := GetConnector(test.Client, connectorName, namespace)
+			connector, err := GetConnector(context.TODO(), test.Client, connectorName, namespace)
 			Expect(err).ToNot(HaveOccurred())
 
 			spec, ok, err := unstructured.NestedMap(connector.UnstructuredContent(), "spec")
@@ -169,6 +180,212 @@ var _ = Describe("Connect", func() {
 			Expect(spec["config"]).To(HaveKeyWithValue("transforms", "false"))
 		})
 
+		It("Skips events older than the minimum timestamp", func() {
+			cyndiConfig, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			config := sampleConnectorConfig()
+			config.Template = cyndiConfig.ConnectorTemplate
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-03", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			filter, _, err := unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "transforms.timestampFilter.if")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter).To(Equal("(Date.now() - record.timestamp()) < 45 * 24 * 60 * 60 * 1000"))
+
+			config.MinTimestamp = 1600000000000
+			connector, err = CreateConnector(context.TODO(), test.Client, "advisor-03", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			filter, _, err = unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "transforms.timestampFilter.if")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter).To(Equal("(Date.now() - record.timestamp()) < 45 * 24 * 60 * 60 * 1000 && record.timestamp() >= 1600000000000"))
+		})
+
+		It("Casts values to the column types of the table", func() {
+			cyndiConfig, err := BuildCyndiConfig(nil, map[string]string{"db.column.type.id": "text", "db.column.type.timestamp": "timestamp"})
+			Expect(err).ToNot(HaveOccurred())
+
+			config := sampleConnectorConfig()
+			config.Template = cyndiConfig.ConnectorTemplate
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-03", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			schema, _, err := unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "transforms.injectSchemaKey.schema")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(schema).To(ContainSubstring("pgtype=uuid"))
+
+			config.ColumnTypes = cyndiConfig.DBColumnTypes
+			connector, err = CreateConnector(context.TODO(), test.Client, "advisor-03", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			schema, _, err = unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "transforms.injectSchemaKey.schema")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(schema).To(ContainSubstring("pgtype=text"))
+
+			schema, _, err = unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "transforms.injectSchemaValue.schema")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(schema).To(ContainSubstring("pgtype=timestamp\""))
+			Expect(schema).ToNot(ContainSubstring("pgtype=timestamptz"))
+		})
+
+		It("Selects the authentication plugin of the database", func() {
+			config := sampleConnectorConfig()
+			config.Template = defaultTemplate()
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-03", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			connectionUrl, _, err := unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "connection.url")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connectionUrl).To(HaveSuffix("&sslrootcert="))
+
+			config.DB.Auth = DBAuthConfiguration{Type: DBAuthGCPIAM, CloudSQLInstance: "project:region:instance"}
+			connector, err = CreateConnector(context.TODO(), test.Client, "advisor-03", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			connectionUrl, _, err = unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "connection.url")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connectionUrl).To(ContainSubstring("?sslmode=disable&"))
+			Expect(connectionUrl).To(ContainSubstring("&cloudSqlInstance=project%3Aregion%3Ainstance&enableIamAuth=true&socketFactory=com.google.cloud.sql.postgres.SocketFactory"))
+
+			config.DB.Auth = DBAuthConfiguration{Type: DBAuthAzureAD, ClientID: "client"}
+			connector, err = CreateConnector(context.TODO(), test.Client, "advisor-03", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			connectionUrl, _, err = unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "connection.url")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connectionUrl).To(HaveSuffix("&authenticationPluginClassName=com.azure.identity.extensions.jdbc.postgresql.AzurePostgresqlAuthenticationPlugin&azure.clientId=client"))
+		})
+
+		It("Handles deletes according to the configuration", func() {
+			config := sampleConnectorConfig()
+			config.Template = defaultTemplate()
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-06", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+			spec := connector.Object["spec"].(map[string]interface{})
+			Expect(spec["config"]).To(HaveKeyWithValue("delete.enabled", true))
+			Expect(spec["config"]).To(HaveKeyWithValue("pk.mode", "record_key"))
+			Expect(spec["config"]).To(HaveKeyWithValue("pk.fields", "id"))
+			Expect(spec["config"]).To(HaveKeyWithValue("transforms", ContainSubstring("deleteToTombstone,")))
+
+			// the topic carries tombstones already
+			config.Tombstones = TombstonesNative
+			connector, err = CreateConnector(context.TODO(), test.Client, "advisor-06", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+			spec = connector.Object["spec"].(map[string]interface{})
+			Expect(spec["config"]).To(HaveKeyWithValue("transforms", Not(ContainSubstring("deleteToTombstone,"))))
+			Expect(spec["config"]).To(HaveKeyWithValue("transforms", Not(ContainSubstring("deleteFilter,"))))
+
+			config.DeleteEnabled = false
+			config.PKMode = PKModeRecordValue
+			config.PKFields = []string{"id", "org_id"}
+			connector, err = CreateConnector(context.TODO(), test.Client, "advisor-06", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+			spec = connector.Object["spec"].(map[string]interface{})
+			Expect(spec["config"]).To(HaveKeyWithValue("delete.enabled", false))
+			Expect(spec["config"]).To(HaveKeyWithValue("pk.mode", "record_value"))
+			Expect(spec["config"]).To(HaveKeyWithValue("pk.fields", "id,org_id"))
+			Expect(spec["config"]).To(HaveKeyWithValue("transforms", ContainSubstring("deleteFilter,")))
+		})
+
+		It("Translates the configuration to the keys of the cloud-native flavor", func() {
+			config := sampleConnectorConfig()
+			config.Template = defaultTemplate()
+			config.Sink = Sink{Flavor: ConnectorFlavorCloudNative}
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-07", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+			spec := connector.Object["spec"].(map[string]interface{})
+			Expect(spec).To(HaveKeyWithValue("class", "io.debezium.connector.jdbc.JdbcSinkConnector"))
+			Expect(spec["config"]).To(HaveKeyWithValue("connector.class", "io.debezium.connector.jdbc.JdbcSinkConnector"))
+			Expect(spec["config"]).To(HaveKeyWithValue("connection.username", "${env:ADVISOR_DB_USERNAME}"))
+			Expect(spec["config"]).To(HaveKeyWithValue("primary.key.mode", "record_key"))
+			Expect(spec["config"]).To(HaveKeyWithValue("primary.key.fields", "id"))
+			Expect(spec["config"]).To(HaveKey("field.include.list"))
+			Expect(spec["config"]).To(HaveKeyWithValue("delete.enabled", true))
+			Expect(spec["config"]).ToNot(HaveKey("connection.user"))
+			Expect(spec["config"]).ToNot(HaveKey("pk.mode"))
+			Expect(spec["config"]).ToNot(HaveKey("dialect.name"))
+		})
+
+		It("Maps keys for a custom connector class", func() {
+			config := sampleConnectorConfig()
+			config.Template = defaultTemplate()
+			config.Sink = Sink{
+				Class:      "io.aiven.connect.jdbc.JdbcSinkConnector",
+				KeyMapping: map[string]string{"fields.whitelist": "fields.include", "dialect.name": ""},
+			}
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-08", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+			spec := connector.Object["spec"].(map[string]interface{})
+			Expect(spec).To(HaveKeyWithValue("class", "io.aiven.connect.jdbc.JdbcSinkConnector"))
+			Expect(spec["config"]).To(HaveKeyWithValue("connector.class", "io.aiven.connect.jdbc.JdbcSinkConnector"))
+			Expect(spec["config"]).To(HaveKey("fields.include"))
+			Expect(spec["config"]).ToNot(HaveKey("fields.whitelist"))
+			Expect(spec["config"]).ToNot(HaveKey("dialect.name"))
+			Expect(spec["config"]).To(HaveKeyWithValue("pk.mode", "record_key"))
+		})
+
+		It("Overrides the consumer group", func() {
+			cyndiConfig, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			config := sampleConnectorConfig()
+			config.Template = cyndiConfig.ConnectorTemplate
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-04", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.UnstructuredContent()["spec"]).ToNot(HaveKey("consumer.override.group.id"))
+
+			config.ConsumerGroup = ConsumerGroup("cyndi-advisor-1-1")
+			connector, err = CreateConnector(context.TODO(), test.Client, "advisor-04", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			group, _, err := unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "consumer.override.group.id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(group).To(Equal("connect-cyndi-advisor-1-1"))
+		})
+
+		It("Sets auto.offset.reset", func() {
+			cyndiConfig, err := BuildCyndiConfig(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			config := sampleConnectorConfig()
+			config.Template = cyndiConfig.ConnectorTemplate
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-04", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+			connectorConfig, _, err := unstructured.NestedMap(connector.UnstructuredContent(), "spec", "config")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connectorConfig).ToNot(HaveKey("consumer.override.auto.offset.reset"))
+
+			config.AutoOffsetReset = AutoOffsetResetNone
+			connector, err = CreateConnector(context.TODO(), test.Client, "advisor-04", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			reset, _, err := unstructured.NestedString(connector.UnstructuredContent(), "spec", "config", "consumer.override.auto.offset.reset")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reset).To(Equal("none"))
+		})
+
+		It("Renders sprig functions", func() {
+			config := sampleConnectorConfig()
+			config.Template = `{"topics": "{{ .Topic | upper }}", "name": "{{ .AppName | default "none" | replace "-" "_" }}"}`
+			config.AppName = "advisor-backend"
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-05", namespace, config, nil, nil, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			spec := connector.UnstructuredContent()["spec"].(map[string]interface{})
+			Expect(spec["config"]).To(HaveKeyWithValue("topics", "PLATFORM.INVENTORY.EVENTS"))
+			Expect(spec["config"]).To(HaveKeyWithValue("name", "advisor_backend"))
+		})
+
 		It("Sets the controller reference", func() {
 			const connectorName = "advisor-01"
 			var config = ConnectorConfiguration{
@@ -195,24 +412,69 @@ var _ = Describe("Connect", func() {
 				},
 			}
 
-			_, err := CreateConnector(test.Client, connectorName, namespace, config, pipeline, scheme.Scheme, false)
+			_, err := CreateConnector(context.TODO(), test.Client, connectorName, namespace, config, pipeline, scheme.Scheme, false)
 			Expect(err).ToNot(HaveOccurred())
 
-			connector, err := GetConnector(test.Client, connectorName, namespace)
+			connector, err := GetConnector(context.TODO(), test.Client, connectorName, namespace)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(connector.GetName()).To(Equal(connectorName))
 			Expect(connector.GetLabels()[LabelOwner]).To(Equal(pipeline.GetUIDString()))
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(LabelPipeline, "pipeline-01"))
 
 			references := connector.GetOwnerReferences()
 			Expect(references).To(HaveLen(1))
 			Expect(references[0].Name).To(Equal("pipeline-01"))
 		})
+
+		It("Labels the owner of a connector in a different namespace", func() {
+			pipeline := &cyndi.CyndiPipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pipeline-01",
+					Namespace: namespace,
+					UID:       "969f1d71-4187-432f-99aa-5accf8dc3fef",
+				},
+			}
+
+			connector, err := CreateConnector(context.TODO(), test.Client, "advisor-01", "kafka", sampleConnectorConfig(), pipeline, scheme.Scheme, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetNamespace()).To(Equal("kafka"))
+			Expect(connector.GetOwnerReferences()).To(BeEmpty())
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(LabelOwner, pipeline.GetUIDString()))
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(LabelOwnerName, "pipeline-01"))
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(LabelOwnerNamespace, namespace))
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(LabelPipeline, "pipeline-01"))
+		})
+	})
+
+	Context("Restore Connector", func() {
+		It("Restores a modified connector", func() {
+			const connectorName = "advisor-01"
+
+			desired, err := CreateConnector(context.TODO(), test.Client, connectorName, namespace, sampleConnectorConfig(), nil, nil, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(desired.GetAnnotations()[AnnotationSpecHash]).ToNot(BeEmpty())
+
+			connector, err := GetConnector(context.TODO(), test.Client, connectorName, namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unstructured.SetNestedField(connector.Object, int64(1), "spec", "tasksMax")).To(Succeed())
+			Expect(unstructured.SetNestedField(connector.Object, true, "spec", "pause")).To(Succeed())
+			Expect(test.Client.Update(context.TODO(), connector)).To(Succeed())
+
+			err = RestoreConnector(context.TODO(), test.Client, connector, desired)
+			Expect(err).ToNot(HaveOccurred())
+
+			connector, err = GetConnector(context.TODO(), test.Client, connectorName, namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.Object["spec"]).To(HaveKeyWithValue("tasksMax", desired.Object["spec"].(map[string]interface{})["tasksMax"]))
+			Expect(connector.Object["spec"]).To(HaveKeyWithValue("pause", false))
+			Expect(connector.GetAnnotations()).To(HaveKeyWithValue(AnnotationSpecHash, desired.GetAnnotations()[AnnotationSpecHash]))
+		})
 	})
 
 	Context("List Connectors", func() {
 		It("Lists 0 connectors in an empty namespace", func() {
 			pipeline := createPipeline("test-01")
-			connectors, err := GetConnectorsForOwner(test.Client, namespace, pipeline.GetUIDString())
+			connectors, err := GetConnectorsForOwner(context.TODO(), test.Client, namespace, pipeline.GetUIDString())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(connectors.Items).To(HaveLen(0))
 		})
@@ -229,22 +491,22 @@ var _ = Describe("Connect", func() {
 				compliancePipeline = createPipeline("compliance")
 			)
 
-			_, err := CreateConnector(test.Client, "advisor-01", namespace, advisorConfig, advisorPipeline, scheme.Scheme, false)
+			_, err := CreateConnector(context.TODO(), test.Client, "advisor-01", namespace, advisorConfig, advisorPipeline, scheme.Scheme, false)
 			Expect(err).ToNot(HaveOccurred())
-			_, err = CreateConnector(test.Client, "advisor-02", namespace, advisorConfig, advisorPipeline, scheme.Scheme, false)
+			_, err = CreateConnector(context.TODO(), test.Client, "advisor-02", namespace, advisorConfig, advisorPipeline, scheme.Scheme, false)
 			Expect(err).ToNot(HaveOccurred())
-			_, err = CreateConnector(test.Client, "patch-01", namespace, patchConfig, patchPipeline, scheme.Scheme, false)
+			_, err = CreateConnector(context.TODO(), test.Client, "patch-01", namespace, patchConfig, patchPipeline, scheme.Scheme, false)
 			Expect(err).ToNot(HaveOccurred())
 
-			advisorConnectors, err := GetConnectorsForOwner(test.Client, namespace, advisorPipeline.GetUIDString())
+			advisorConnectors, err := GetConnectorsForOwner(context.TODO(), test.Client, namespace, advisorPipeline.GetUIDString())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(advisorConnectors.Items).To(HaveLen(2))
 
-			patchConnectors, err := GetConnectorsForOwner(test.Client, namespace, patchPipeline.GetUIDString())
+			patchConnectors, err := GetConnectorsForOwner(context.TODO(), test.Client, namespace, patchPipeline.GetUIDString())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(patchConnectors.Items).To(HaveLen(1))
 
-			complianceConnectors, err := GetConnectorsForOwner(test.Client, namespace, compliancePipeline.GetUIDString())
+			complianceConnectors, err := GetConnectorsForOwner(context.TODO(), test.Client, namespace, compliancePipeline.GetUIDString())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(complianceConnectors.Items).To(HaveLen(0))
 		})
@@ -253,16 +515,16 @@ var _ = Describe("Connect", func() {
 	Context("Delete Connector", func() {
 		It("Deletes a connector", func() {
 			name := "connector-to-be-deleted-01"
-			_, err := CreateConnector(test.Client, name, namespace, sampleConnectorConfig(), nil, nil, false)
+			_, err := CreateConnector(context.TODO(), test.Client, name, namespace, sampleConnectorConfig(), nil, nil, false)
 			Expect(err).ToNot(HaveOccurred())
 
-			_, err = GetConnector(test.Client, name, namespace)
+			_, err = GetConnector(context.TODO(), test.Client, name, namespace)
 			Expect(err).ToNot(HaveOccurred())
 
-			err = DeleteConnector(test.Client, name, namespace)
+			err = DeleteConnector(context.TODO(), test.Client, name, namespace)
 			Expect(err).ToNot(HaveOccurred())
 
-			_, err = GetConnector(test.Client, name, namespace)
+			_, err = GetConnector(context.TODO(), test.Client, name, namespace)
 			Expect(err).To(HaveOccurred())
 			Expect(errors.IsNotFound(err)).To(BeTrue())
 		})
@@ -270,8 +532,202 @@ var _ = Describe("Connect", func() {
 		It("Does not fail on non-existing connector", func() {
 			name := "connector-does-not-exist"
 
-			err := DeleteConnector(test.Client, name, namespace)
+			err := DeleteConnector(context.TODO(), test.Client, name, namespace)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	DescribeTable("Validates templates",
+		func(template string, valid bool) {
+			err := ValidateTemplate(template)
+
+			if valid {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("default", defaultTemplate(), true),
+		Entry("sprig functions", `{"topics": "{{ .Topic | quote | trimAll "\"" }}"}`, true),
+		Entry("syntax error", `{"topics": "{{ .Topic }"}`, false),
+		Entry("unknown function", `{"topics": "{{ .Topic | shout }}"}`, false),
+		Entry("invalid JSON", `{"topics": {{ .Topic }}}`, false),
+		Entry("not an object", `["{{ .Topic }}"]`, false),
+		Entry("empty", ``, false),
+	)
+
+	DescribeTable("Validates templates of connector flavors",
+		func(template string, flavor ConnectorFlavor, valid bool) {
+			err := ValidateTemplateSink(template, Sink{Flavor: flavor})
+
+			if valid {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("default template, confluent", defaultTemplate(), ConnectorFlavorConfluent, true),
+		Entry("default template, cloud-native", defaultTemplate(), ConnectorFlavorCloudNative, true),
+		Entry("cloud-native keys, cloud-native", `{"topics": "{{ .Topic }}", "connection.url": "jdbc:postgresql://db/app", "table.name.format": "{{ .TableName }}", "primary.key.mode": "record_key"}`, ConnectorFlavorCloudNative, true),
+		Entry("cloud-native keys, confluent", `{"primary.key.mode": "record_key"}`, ConnectorFlavorConfluent, false),
+		Entry("both key sets", `{"pk.mode": "record_key", "primary.key.mode": "record_key"}`, ConnectorFlavorCloudNative, false),
+		Entry("unknown flavor", `{"topics": "{{ .Topic }}"}`, ConnectorFlavor("unknown"), false),
+		Entry("invalid template", `{"topics": "{{ .Topic }"}`, ConnectorFlavorConfluent, false),
+	)
+
+	DescribeTable("Validates keys required by the sink connector",
+		func(template string, sink Sink, valid bool) {
+			err := ValidateTemplateSink(template, sink)
+
+			if valid {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("default template", defaultTemplate(), Sink{}, true),
+		Entry("missing key of a known class", `{"topics": "{{ .Topic }}", "connection.url": "jdbc:postgresql://db/app", "table.name.format": "{{ .TableName }}"}`, Sink{}, false),
+		Entry("unknown class", `{"topics": "{{ .Topic }}"}`, Sink{Class: "com.example.CyndiSinkConnector"}, true),
+		Entry("unknown class without topics", `{"table": "{{ .TableName }}"}`, Sink{Class: "com.example.CyndiSinkConnector"}, false),
+		Entry("configured required key", `{"topics": "{{ .Topic }}"}`, Sink{Class: "com.example.CyndiSinkConnector", RequiredKeys: []string{"table"}}, false),
+		Entry("mapped required key", `{"topics": "{{ .Topic }}", "table.name.format": "{{ .TableName }}"}`, Sink{Class: "com.example.CyndiSinkConnector", KeyMapping: map[string]string{"table.name.format": "table"}, RequiredKeys: []string{"table"}}, true),
+		Entry("mapping to an existing key", defaultTemplate(), Sink{KeyMapping: map[string]string{"batch.size": "tasks.max"}}, false),
+	)
+
+	Describe("Source connector", func() {
+		It("Renders the default template", func() {
+			cyndiConfig, err := BuildCyndiConfig(&cyndi.CyndiPipeline{Spec: cyndi.CyndiPipelineSpec{AppName: "advisor", ManageSourceConnector: &cyndi.SourceConnector{}}}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ValidateSourceTemplate(cyndiConfig.SourceConnectorTemplate)).To(Succeed())
+
+			connector, err := newSourceConnectorResource("cyndi-advisor-1-1-source", namespace, SourceConnectorConfiguration{
+				AppName:          "advisor",
+				Cluster:          "cluster01",
+				Topic:            "platform.inventory.events",
+				SlotName:         "cyndi_advisor_1_1",
+				TableIncludeList: []string{"public.hosts", "public.groups"},
+				DB:               dbParams,
+				Template:         cyndiConfig.SourceConnectorTemplate,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			spec := connector.Object["spec"].(map[string]interface{})
+			Expect(spec).To(HaveKeyWithValue("class", "io.debezium.connector.postgresql.PostgresConnector"))
+			Expect(spec).To(HaveKeyWithValue("tasksMax", int64(1)))
+			Expect(spec["config"]).To(HaveKeyWithValue("slot.name", "cyndi_advisor_1_1"))
+			Expect(spec["config"]).To(HaveKeyWithValue("publication.name", "cyndi_advisor_1_1"))
+			Expect(spec["config"]).To(HaveKeyWithValue("table.include.list", "public.hosts,public.groups"))
+			Expect(spec["config"]).To(HaveKeyWithValue("transforms.route.replacement", "platform.inventory.events"))
+			Expect(spec["config"]).To(HaveKeyWithValue("database.hostname", "${env:INVENTORY_DB_HOSTNAME}"))
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(LabelStrimziCluster, "cluster01"))
+		})
+
+		It("Uses the configured class and environment variables", func() {
+			cyndiConfig, err := BuildCyndiConfig(&cyndi.CyndiPipeline{Spec: cyndi.CyndiPipelineSpec{AppName: "advisor", ManageSourceConnector: &cyndi.SourceConnector{}}}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			connector, err := newSourceConnectorResource("cyndi-advisor-1-1-source", namespace, SourceConnectorConfiguration{
+				AppName:          "advisor",
+				Cluster:          "cluster01",
+				Topic:            "platform.inventory.events",
+				SlotName:         "cyndi_advisor_1_1",
+				TableIncludeList: []string{"public.hosts"},
+				DB:               dbParams,
+				Template:         cyndiConfig.SourceConnectorTemplate,
+				Class:            "com.example.PostgresConnector",
+				EnvPrefix:        "HBI",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			spec := connector.Object["spec"].(map[string]interface{})
+			Expect(spec).To(HaveKeyWithValue("class", "com.example.PostgresConnector"))
+			Expect(spec["config"]).To(HaveKeyWithValue("connector.class", "com.example.PostgresConnector"))
+			Expect(spec["config"]).To(HaveKeyWithValue("database.hostname", "${env:HBI_DB_HOSTNAME}"))
+		})
+
+		It("Writes a heartbeat", func() {
+			cyndiConfig, err := BuildCyndiConfig(&cyndi.CyndiPipeline{Spec: cyndi.CyndiPipelineSpec{AppName: "advisor", ManageSourceConnector: &cyndi.SourceConnector{Heartbeat: &cyndi.SourceHeartbeat{}}}}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cyndiConfig.SourceHeartbeatTable).To(Equal("public.cyndi_heartbeat"))
+
+			query := `INSERT INTO "public"."cyndi_heartbeat" (slot_name, ts) VALUES ('cyndi_advisor_1_1', now())`
+			connector, err := newSourceConnectorResource("cyndi-advisor-1-1-source", namespace, SourceConnectorConfiguration{
+				AppName:              "advisor",
+				Cluster:              "cluster01",
+				Topic:                "platform.inventory.events",
+				SlotName:             "cyndi_advisor_1_1",
+				TableIncludeList:     []string{"public.hosts"},
+				HeartbeatActionQuery: query,
+				HeartbeatTable:       cyndiConfig.SourceHeartbeatTable,
+				HeartbeatInterval:    cyndiConfig.SourceHeartbeatInterval,
+				DB:                   dbParams,
+				Template:             cyndiConfig.SourceConnectorTemplate,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			connectorConfig := connector.Object["spec"].(map[string]interface{})["config"]
+			Expect(connectorConfig).To(HaveKeyWithValue("heartbeat.action.query", query))
+			Expect(connectorConfig).To(HaveKeyWithValue("heartbeat.interval.ms", "60000"))
+			Expect(connectorConfig).To(HaveKeyWithValue("table.include.list", "public.hosts,public.cyndi_heartbeat"))
+			Expect(connectorConfig).To(HaveKeyWithValue("transforms", "dropHeartbeat,unwrap,extractKey,route"))
+
+			pattern := regexp.MustCompile("^(" + connectorConfig.(map[string]interface{})["predicates.isHeartbeat.pattern"].(string) + ")$")
+			Expect(pattern.MatchString("__debezium-heartbeat.cyndi_advisor_1_1")).To(BeTrue())
+			Expect(pattern.MatchString("cyndi_advisor_1_1.public.cyndi_heartbeat")).To(BeTrue())
+			Expect(pattern.MatchString("cyndi_advisor_1_1.public.hosts")).To(BeFalse())
+		})
+
+		It("Rejects an invalid template", func() {
+			Expect(ValidateSourceTemplate(`["{{ .SlotName }}"]`)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Metadata", func() {
+		It("Records the versions of the operator and of the template", func() {
+			config := sampleConnectorConfig()
+			config.OperatorVersion = "abc1234"
+
+			connector, err := newConnectorResource("advisor-01", namespace, config)
+			Expect(err).ToNot(HaveOccurred())
+
+			templateVersion, err := TemplateVersion(config.Template)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(LabelOperatorVersion, "abc1234"))
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(LabelTemplateVersion, templateVersion))
+
+			// not a valid label value
+			config.OperatorVersion = "v1.0.0+build/1"
+			connector, err = newConnectorResource("advisor-01", namespace, config)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetLabels()).ToNot(HaveKey(LabelOperatorVersion))
+		})
+
+		It("Propagates labels and annotations of the pipeline", func() {
+			config := sampleConnectorConfig()
+			config.Labels = map[string]string{"team": "inventory"}
+			config.Annotations = map[string]string{"alerts": "inventory-alerts"}
+
+			connector, err := newConnectorResource("advisor-01", namespace, config)
 			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetLabels()).To(HaveKeyWithValue("team", "inventory"))
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(LabelAppName, config.AppName))
+			Expect(connector.GetAnnotations()).To(HaveKeyWithValue("alerts", "inventory-alerts"))
+			Expect(connector.GetAnnotations()).To(HaveKey(AnnotationSpecHash))
+
+			Expect(SetMetadata(connector, config.Labels, config.Annotations)).To(BeFalse())
+		})
+
+		It("Removes keys no longer propagated", func() {
+			connector := EmptyConnector()
+			connector.SetLabels(map[string]string{"owner": "someone-else"})
+
+			Expect(SetMetadata(connector, map[string]string{"team": "inventory", "tier": "1"}, nil)).To(BeTrue())
+			Expect(SetMetadata(connector, map[string]string{"team": "advisor"}, nil)).To(BeTrue())
+			Expect(connector.GetLabels()).To(Equal(map[string]string{"owner": "someone-else", "team": "advisor"}))
+
+			Expect(SetMetadata(connector, nil, nil)).To(BeTrue())
+			Expect(connector.GetLabels()).To(Equal(map[string]string{"owner": "someone-else"}))
+			Expect(connector.GetAnnotations()).To(BeEmpty())
 		})
 	})
 
@@ -349,4 +805,79 @@ var _ = Describe("Connect", func() {
 			Expect(IsFailed(connector)).To(BeTrue())
 		})
 	})
+
+	Describe("IsNotReady", func() {
+		cluster := func(conditions ...interface{}) *unstructured.Unstructured {
+			return &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"status": map[string]interface{}{
+						"conditions": conditions,
+					},
+				},
+			}
+		}
+
+		It("Does not consider a cluster without conditions to be not ready", func() {
+			notReady, _ := IsNotReady(EmptyConnectCluster())
+			Expect(notReady).To(BeFalse())
+		})
+
+		It("Correctly recognizes a ready cluster", func() {
+			notReady, _ := IsNotReady(cluster(map[string]interface{}{"type": "Ready", "status": "True"}))
+			Expect(notReady).To(BeFalse())
+		})
+
+		It("Correctly flags a cluster that is not ready", func() {
+			notReady, message := IsNotReady(cluster(
+				map[string]interface{}{"type": "Warning", "status": "True"},
+				map[string]interface{}{"type": "NotReady", "status": "True"},
+				map[string]interface{}{"type": "Ready", "status": "False", "message": "Deployment is not ready"},
+			))
+			Expect(notReady).To(BeTrue())
+			Expect(message).To(Equal("Deployment is not ready"))
+		})
+	})
+
+	Describe("Topics", func() {
+		DescribeTable("Finds the Kafka cluster of the Connect cluster",
+			func(servers string, name string, namespace string) {
+				cluster := EmptyConnectCluster()
+				cluster.SetName("connect")
+				cluster.SetNamespace("cyndi")
+				Expect(unstructured.SetNestedField(cluster.Object, servers, "spec", "bootstrapServers")).To(Succeed())
+
+				clusterName, clusterNamespace, err := KafkaClusterOf(cluster)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(clusterName).To(Equal(name))
+				Expect(clusterNamespace).To(Equal(namespace))
+			},
+			Entry("same namespace", "platform-mq-kafka-bootstrap:9092", "platform-mq", "cyndi"),
+			Entry("other namespace", "platform-mq-kafka-bootstrap.kafka.svc:9093", "platform-mq", "kafka"),
+			Entry("fully qualified", "platform-mq-kafka-bootstrap.kafka.svc.cluster.local:9093,other:9092", "platform-mq", "kafka"),
+		)
+
+		It("Rejects bootstrap servers of other Kafka clusters", func() {
+			cluster := EmptyConnectCluster()
+			Expect(unstructured.SetNestedField(cluster.Object, "kafka.example.com:9092", "spec", "bootstrapServers")).To(Succeed())
+
+			_, _, err := KafkaClusterOf(cluster)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Reads partitions and retention of a topic", func() {
+			item := unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "platform-inventory-events"},
+				"spec": map[string]interface{}{
+					"topicName":  "platform.inventory.events",
+					"partitions": int64(16),
+					"config":     map[string]interface{}{"retention.ms": "604800000"},
+				},
+			}}
+
+			topic := parseTopic(item)
+			Expect(topic.Name).To(Equal("platform.inventory.events"))
+			Expect(topic.Partitions).To(Equal(int64(16)))
+			Expect(*topic.RetentionMs).To(Equal(int64(604800000)))
+		})
+	})
 })
@@ -0,0 +1,100 @@
+package connect
+
+import (
+	"fmt"
+
+	. "github.com/RedHatInsights/cyndi-operator/controllers/config"
+)
+
+/*
+
+Connector flavors - sink connector implementations a connector can be created for.
+Connector templates (including the default one) use the configuration keys of the Confluent JDBC sink connector.
+For other flavors the keys of the rendered configuration are translated to the key set of the flavor.
+A template may also use the keys of its flavor directly but cannot use keys of a different flavor.
+
+*/
+
+type flavorKeys struct {
+	class string
+	// keys named differently by this flavor
+	renamed map[string]string
+	// keys without an equivalent in this flavor (the flavor behaves as configured by the default template), left out of the configuration
+	dropped []string
+}
+
+var flavors = map[ConnectorFlavor]flavorKeys{
+	ConnectorFlavorConfluent: {
+		class: "io.confluent.connect.jdbc.JdbcSinkConnector",
+	},
+	ConnectorFlavorCloudNative: {
+		class: "io.debezium.connector.jdbc.JdbcSinkConnector",
+		renamed: map[string]string{
+			"connection.user":  "connection.username",
+			"pk.mode":          "primary.key.mode",
+			"pk.fields":        "primary.key.fields",
+			"fields.whitelist": "field.include.list",
+		},
+		dropped: []string{"dialect.name", "auto.create", "connection.attempts", "connection.backoff.ms"},
+	},
+}
+
+// Returns the flavor the given key is specific to, if any
+func flavorOfKey(key string) ConnectorFlavor {
+	for flavor, keys := range flavors {
+		for _, renamed := range keys.renamed {
+			if renamed == key {
+				return flavor
+			}
+		}
+	}
+
+	return ""
+}
+
+// the zero value stands for the default flavor
+func flavorOrDefault(flavor ConnectorFlavor) ConnectorFlavor {
+	if flavor == "" {
+		return ConnectorFlavorConfluent
+	}
+
+	return flavor
+}
+
+// Translates the rendered configuration to the key set of the given flavor
+func translateConfig(config map[string]interface{}, flavor ConnectorFlavor) (map[string]interface{}, error) {
+	flavor = flavorOrDefault(flavor)
+
+	keys, ok := flavors[flavor]
+	if !ok {
+		return nil, fmt.Errorf("Unknown connector flavor %s", flavor)
+	}
+
+	dropped := make(map[string]bool)
+	for _, key := range keys.dropped {
+		dropped[key] = true
+	}
+
+	result := make(map[string]interface{})
+	for key, value := range config {
+		if owner := flavorOfKey(key); owner != "" && owner != flavor {
+			return nil, fmt.Errorf(`Key "%s" of the %s connector flavor is not supported by the %s connector flavor`, key, owner, flavor)
+		}
+
+		if dropped[key] {
+			continue
+		}
+
+		if renamed, ok := keys.renamed[key]; ok {
+			if _, ok := config[renamed]; ok {
+				return nil, fmt.Errorf(`Keys "%s" and "%s" cannot be used together`, key, renamed)
+			}
+
+			key = renamed
+		}
+
+		result[key] = value
+	}
+
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package connect
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/faults"
+	"github.com/RedHatInsights/cyndi-operator/controllers/tracing"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+
+Labels and annotations propagated from the pipeline (spec.connectorLabels, spec.connectorAnnotations) to its connectors.
+The keys propagated are recorded on the connector so that keys removed from the pipeline are removed from the connector as well
+while labels and annotations added by others are left alone.
+
+*/
+
+const (
+	annotationPropagatedLabels      = "cyndi/propagatedLabels"
+	annotationPropagatedAnnotations = "cyndi/propagatedAnnotations"
+)
+
+func sortedKeys(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// Applies the desired values on top of the current ones, removing the previously propagated keys that are no longer desired
+func propagate(current map[string]string, desired map[string]string, previous string) map[string]string {
+	result := utils.Merge(current)
+
+	for _, key := range strings.Split(previous, ",") {
+		if _, ok := desired[key]; !ok {
+			delete(result, key)
+		}
+	}
+
+	return utils.Merge(result, desired)
+}
+
+// Sets the given labels and annotations on the connector. Returns false if the connector already has them
+func SetMetadata(connector *unstructured.Unstructured, labels map[string]string, annotations map[string]string) bool {
+	currentLabels := utils.Merge(connector.GetLabels())
+	currentAnnotations := utils.Merge(connector.GetAnnotations())
+
+	newLabels := propagate(currentLabels, labels, currentAnnotations[annotationPropagatedLabels])
+	newAnnotations := propagate(currentAnnotations, annotations, currentAnnotations[annotationPropagatedAnnotations])
+
+	for key, values := range map[string]map[string]string{annotationPropagatedLabels: labels, annotationPropagatedAnnotations: annotations} {
+		if len(values) > 0 {
+			newAnnotations[key] = sortedKeys(values)
+		} else {
+			delete(newAnnotations, key)
+		}
+	}
+
+	if reflect.DeepEqual(currentLabels, newLabels) && reflect.DeepEqual(currentAnnotations, newAnnotations) {
+		return false
+	}
+
+	connector.SetLabels(newLabels)
+	connector.SetAnnotations(newAnnotations)
+	return true
+}
+
+// Persists the labels and annotations set using SetMetadata
+func UpdateMetadata(ctx context.Context, c client.Client, connector *unstructured.Unstructured) (err error) {
+	ctx, span := startSpan(ctx, "UpdateConnectorMetadata", connector.GetName(), connector.GetNamespace())
+	defer func() { tracing.End(span, err) }()
+
+	if err = faults.Check(faults.ConnectorUpdate); err != nil {
+		return err
+	}
+
+	return c.Update(ctx, connector)
+}
@@ -0,0 +1,106 @@
+package connect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/RedHatInsights/cyndi-operator/controllers/config"
+)
+
+/*
+
+The sink connector a connector is created for.
+Besides the flavors, any sink connector class can be used. Keys of the configuration translated for the flavor can be further renamed (or left out) using a key mapping.
+When validating a template, the resulting configuration is checked for keys the connector cannot work without.
+
+*/
+
+const connectorClassKey = "connector.class"
+
+type Sink struct {
+	Flavor ConnectorFlavor
+	// overrides the class of the flavor
+	Class string
+	// renames keys of the configuration translated for the flavor; keys mapped to "" are left out
+	KeyMapping map[string]string
+	// keys required in addition to those known to be required by the class
+	RequiredKeys []string
+}
+
+// every sink connector consumes topics
+var commonRequiredKeys = []string{"topics"}
+
+// keys required by known sink connector classes, e.g. upserts need the primary key to be configured
+var requiredKeys = map[string][]string{
+	"io.confluent.connect.jdbc.JdbcSinkConnector":  {"connection.url", "table.name.format", "pk.mode"},
+	"io.aiven.connect.jdbc.JdbcSinkConnector":      {"connection.url", "table.name.format", "pk.mode"},
+	"io.debezium.connector.jdbc.JdbcSinkConnector": {"connection.url", "table.name.format", "primary.key.mode"},
+}
+
+func (s Sink) class() string {
+	if s.Class != "" {
+		return s.Class
+	}
+
+	return flavors[flavorOrDefault(s.Flavor)].class
+}
+
+// Builds the configuration of the sink connector from the rendered template
+func sinkConfig(rendered map[string]interface{}, sink Sink) (map[string]interface{}, error) {
+	translated, err := translateConfig(rendered, sink.Flavor)
+	if err != nil {
+		return nil, err
+	}
+
+	class := sink.class()
+	result := make(map[string]interface{})
+
+	for key, value := range translated {
+		if mapped, ok := sink.KeyMapping[key]; ok {
+			if mapped == "" {
+				continue
+			}
+
+			key = mapped
+		}
+
+		if _, ok := result[key]; ok {
+			return nil, fmt.Errorf(`Key "%s" is defined more than once after mapping the keys for %s`, key, class)
+		}
+
+		result[key] = value
+	}
+
+	if _, ok := result[connectorClassKey]; ok {
+		result[connectorClassKey] = class
+	}
+
+	return result, nil
+}
+
+// Fails if the configuration lacks keys required by the class of the sink connector
+func (s Sink) validate(config map[string]interface{}) error {
+	class := s.class()
+
+	missing := map[string]bool{}
+	for _, keys := range [][]string{commonRequiredKeys, requiredKeys[class], s.RequiredKeys} {
+		for _, key := range keys {
+			if _, ok := config[key]; !ok {
+				missing[key] = true
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		keys := make([]string, 0, len(missing))
+		for key := range missing {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		return fmt.Errorf("Keys %s required by %s are missing from the connector configuration", strings.Join(keys, ", "), class)
+	}
+
+	return nil
+}
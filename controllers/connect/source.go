@@ -0,0 +1,186 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	. "github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/tracing"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+
+Debezium source connector publishing changes of the HBI database to the topic consumed by the sink connector.
+The connector of each pipeline version uses a replication slot of its own so that the sink connector of a new pipeline version
+receives a complete snapshot of the HBI tables.
+With a heartbeat, the connector also captures the heartbeat table it writes to so that its replication slot advances while the HBI tables do not change.
+Change events of the heartbeat table and heartbeat records are dropped before they reach the topic.
+
+*/
+
+// used unless configured otherwise (see connector.source.class and connector.source.env.prefix)
+const defaultSourceConnectorClass = "io.debezium.connector.postgresql.PostgresConnector"
+
+// prefix of the environment variables of the Kafka Connect cluster holding the credentials of the HBI database
+const defaultSourceDBEnvPrefix = "INVENTORY"
+
+type SourceConnectorConfiguration struct {
+	AppName string
+	Cluster string
+	// topic the changes are published to
+	Topic string
+	// name of the replication slot (and of the publication)
+	SlotName         string
+	TableIncludeList []string
+	// heartbeat.action.query written to the schema-qualified HeartbeatTable every HeartbeatInterval milliseconds. No heartbeat if empty
+	HeartbeatActionQuery string
+	HeartbeatTable       string
+	HeartbeatInterval    int64
+	DB                   DBParams
+	Template             string
+	Class                string
+	EnvPrefix            string
+	// propagated from the pipeline
+	Labels      map[string]string
+	Annotations map[string]string
+	// version of the operator rendering the connector, not recorded if empty
+	OperatorVersion string
+}
+
+func SourceConnectorName(sinkConnectorName string) string {
+	return sinkConnectorName + "-source"
+}
+
+func (config SourceConnectorConfiguration) class() string {
+	if config.Class != "" {
+		return config.Class
+	}
+
+	return defaultSourceConnectorClass
+}
+
+func renderSourceTemplate(config SourceConnectorConfiguration) (interface{}, error) {
+	m := make(map[string]interface{})
+	m["AppName"] = config.AppName
+	m["Topic"] = config.Topic
+	m["SlotName"] = config.SlotName
+	m["Class"] = config.class()
+	m["TableIncludeList"] = strings.Join(config.TableIncludeList, ",")
+	m["HeartbeatActionQuery"] = config.HeartbeatActionQuery
+	m["HeartbeatInterval"] = config.HeartbeatInterval
+	m["HeartbeatTopicPattern"] = ""
+
+	if config.HeartbeatActionQuery != "" {
+		tables := append([]string{}, config.TableIncludeList...)
+		m["TableIncludeList"] = strings.Join(append(tables, config.HeartbeatTable), ",")
+		// heartbeat records and change events of the heartbeat table (topics are named <topic.prefix>.<schema>.<table>)
+		m["HeartbeatTopicPattern"] = fmt.Sprintf("__debezium-heartbeat\\..*|%s", regexp.QuoteMeta(config.SlotName+"."+config.HeartbeatTable))
+	}
+	m["SSLMode"] = config.DB.SSLMode
+	m["SSLRootCert"] = config.DB.SSLRootCert
+
+	envPrefix := config.EnvPrefix
+	if envPrefix == "" {
+		envPrefix = defaultSourceDBEnvPrefix
+	}
+
+	setDBValues(m, config.DB, envPrefix)
+
+	return executeTemplate(config.Template, m)
+}
+
+// Renders the source connector template using sample values. Fails if the template does not render to a JSON object.
+func ValidateSourceTemplate(connectorTemplate string) error {
+	rendered, err := renderSourceTemplate(SourceConnectorConfiguration{
+		AppName:          "sample",
+		Topic:            "platform.inventory.events",
+		SlotName:         "cyndi_sample_1_1",
+		TableIncludeList: []string{"public.hosts"},
+		// renders the heartbeat part of the template as well
+		HeartbeatActionQuery: `INSERT INTO "public"."cyndi_heartbeat" (slot_name, ts) VALUES ('cyndi_sample_1_1', now())`,
+		HeartbeatTable:       "public.cyndi_heartbeat",
+		HeartbeatInterval:    60000,
+		Template:             connectorTemplate,
+	})
+
+	if err != nil {
+		return fmt.Errorf("Invalid source connector template: %w", err)
+	}
+
+	if _, ok := rendered.(map[string]interface{}); !ok {
+		return fmt.Errorf("Invalid source connector template: does not render to a JSON object")
+	}
+
+	return nil
+}
+
+func newSourceConnectorResource(name string, namespace string, config SourceConnectorConfiguration) (*unstructured.Unstructured, error) {
+	rendered, err := renderSourceTemplate(config)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := map[string]interface{}{
+		// a replication slot is consumed by a single task
+		"tasksMax": int64(1),
+		"class":    config.class(),
+		"config":   rendered,
+		"pause":    false,
+	}
+
+	specHash, err := utils.SpecHash(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]interface{}{
+		LabelStrimziCluster: config.Cluster,
+		LabelAppName:        config.AppName,
+	}
+
+	if err = setVersionLabels(labels, config.Template, config.OperatorVersion); err != nil {
+		return nil, err
+	}
+
+	u := &unstructured.Unstructured{}
+	u.Object = map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+			"labels":    labels,
+			"annotations": map[string]interface{}{
+				AnnotationSpecHash: specHash,
+			},
+		},
+		"spec": spec,
+	}
+
+	u.SetGroupVersionKind(connectorGVK)
+	SetMetadata(u, config.Labels, config.Annotations)
+	return u, nil
+}
+
+func CreateSourceConnector(ctx context.Context, c client.Client, name string, namespace string, config SourceConnectorConfiguration, owner metav1.Object, ownerScheme *runtime.Scheme) (*unstructured.Unstructured, error) {
+	connector, err := newSourceConnectorResource(name, namespace, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = setOwner(connector, owner, ownerScheme); err != nil {
+		return nil, err
+	}
+
+	ctx, span := startSpan(ctx, "CreateSourceConnector", name, namespace)
+	err = c.Create(ctx, connector)
+	tracing.End(span, err)
+
+	return connector, err
+}
@@ -0,0 +1,97 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+
+Topics of the Kafka cluster a Connect cluster consumes from, as described by the KafkaTopic resources of the Strimzi Topic Operator.
+The Kafka cluster is identified by the bootstrap servers of the Connect cluster, which Strimzi names <cluster>-kafka-bootstrap[.<namespace>.svc].
+This spares the operator speaking the Kafka protocol (and holding credentials of the Kafka cluster) just to look up topics.
+
+*/
+
+const labelStrimziCluster = "strimzi.io/cluster"
+
+var kafkaTopicsGVK = schema.GroupVersionKind{
+	Group:   "kafka.strimzi.io",
+	Kind:    "KafkaTopicList",
+	Version: "v1beta2",
+}
+
+// e.g. platform-mq-kafka-bootstrap.platform-mq.svc:9092
+var strimziBootstrapServer = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?)-kafka-bootstrap(\.([a-z0-9]([-a-z0-9]*[a-z0-9])?))?(\.svc(\.[a-z0-9.-]+)?)?(:[0-9]+)?$`)
+
+type Topic struct {
+	Name       string
+	Partitions int64
+	// retention.ms set on the topic, nil if the default of the Kafka cluster applies
+	RetentionMs *int64
+}
+
+// Returns the name and the namespace of the Strimzi Kafka cluster the given Connect cluster connects to
+func KafkaClusterOf(connectCluster *unstructured.Unstructured) (name string, namespace string, err error) {
+	servers, _, err := unstructured.NestedString(connectCluster.UnstructuredContent(), "spec", "bootstrapServers")
+	if err != nil {
+		return "", "", err
+	}
+
+	server := strings.TrimSpace(strings.Split(servers, ",")[0])
+	match := strimziBootstrapServer.FindStringSubmatch(server)
+	if match == nil {
+		return "", "", fmt.Errorf("Bootstrap server %s of Connect cluster %s is not a Strimzi Kafka cluster", server, connectCluster.GetName())
+	}
+
+	namespace = match[4]
+	if namespace == "" {
+		namespace = connectCluster.GetNamespace()
+	}
+
+	return match[1], namespace, nil
+}
+
+// Returns the topics of the given Kafka cluster by name
+func GetTopics(ctx context.Context, c client.Client, cluster string, namespace string) (map[string]Topic, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(kafkaTopicsGVK)
+
+	if err := c.List(ctx, list, client.InNamespace(namespace), client.MatchingLabels{labelStrimziCluster: cluster}); err != nil {
+		return nil, err
+	}
+
+	topics := make(map[string]Topic, len(list.Items))
+	for _, item := range list.Items {
+		topic := parseTopic(item)
+		topics[topic.Name] = topic
+	}
+
+	return topics, nil
+}
+
+func parseTopic(item unstructured.Unstructured) Topic {
+	topic := Topic{Name: item.GetName()}
+
+	// resource names cannot hold every topic name (e.g. uppercase letters)
+	if name, _, _ := unstructured.NestedString(item.Object, "spec", "topicName"); name != "" {
+		topic.Name = name
+	}
+
+	topic.Partitions, _, _ = unstructured.NestedInt64(item.Object, "spec", "partitions")
+
+	if value, ok, _ := unstructured.NestedFieldNoCopy(item.Object, "spec", "config", "retention.ms"); ok {
+		if retention, err := strconv.ParseInt(fmt.Sprint(value), 10, 64); err == nil {
+			topic.RetentionMs = &retention
+		}
+	}
+
+	return topic
+}
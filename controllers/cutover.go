@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"fmt"
+)
+
+/*
+
+Two-phase cutover of the inventory.hosts view.
+Once the view points to a new table, hosts are read through the view (the host count and a sample host) within the same reconciliation.
+If that fails the view is pointed back to the previous table so that the application never reads from a broken view.
+
+*/
+
+/*
+ * Verifies the view after it has been pointed to the table of the current pipeline version.
+ * Returns true if the verification failed and the view was rolled back to the previous table (the pipeline is then marked as Degraded).
+ * Without a previous table there is nothing to roll back to and the verification error is returned instead.
+ */
+func (i *ReconcileIteration) verifyViewOrRollback(previous *string) (bool, error) {
+	count, err := i.AppDb.VerifyView()
+	if err == nil {
+		i.debug("View verified", "table", i.Instance.Status.TableName, "hosts", count)
+		return false, nil
+	}
+
+	if previous == nil {
+		return false, fmt.Errorf("Failed to read hosts through the view %w", err)
+	}
+
+	i.Log.Error(err, "View verification failed, rolling back", "table", i.Instance.Status.TableName, "previous", *previous)
+	if rollbackErr := i.AppDb.UpdateView(*previous); rollbackErr != nil {
+		return false, fmt.Errorf("Failed to roll back view to %s after failed verification (%s) %w", *previous, err.Error(), rollbackErr)
+	}
+
+	i.audit(auditViewReplaced, *previous, fmt.Sprintf("rolled back as reading hosts from %s through the view failed", i.Instance.Status.TableName))
+	i.markDegraded(i.Instance, reasonViewVerificationFailed, fmt.Sprintf("Reading hosts from %s through the view failed: %s", i.Instance.Status.TableName, err.Error()))
+	return true, nil
+}
@@ -21,15 +21,20 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,6 +52,7 @@ import (
 	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
 	"github.com/RedHatInsights/cyndi-operator/controllers/database"
 	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+	"github.com/RedHatInsights/cyndi-operator/controllers/tracing"
 	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
 )
 
@@ -57,10 +63,30 @@ type CyndiPipelineReconciler struct {
 	Scheme    *runtime.Scheme
 	Log       logr.Logger
 	Recorder  record.EventRecorder
+
+	// if true connectors and databases are never modified - the state of pipelines is only reported
+	ReadOnly bool
+
+	// Namespace the operator runs in. NetworkPolicies of the operator are not maintained if empty
+	OperatorNamespace string
+
+	// HBI host counts and ids shared by the pipelines. Nil disables caching
+	HostCache *database.HostCache
+
+	// Create the application and inventory databases of a pipeline from the database secrets. PostgreSQL is used if nil.
+	// Tests replace the databases with in-memory ones (see database.MemoryDatabase)
+	NewAppDatabase       func(params *config.DBParams) database.ApplicationDatabase
+	NewInventoryDatabase func(params *config.DBParams) database.Database
+
+	// application databases bootstrapped by this process (see bootstrapAppDb)
+	bootstrapped sync.Map
 }
 
 const cyndipipelineFinalizer = "cyndi.cloud.redhat.com/finalizer"
 
+// generations tried when looking for an unused table name (see newPipelineVersion)
+const maxNamingAttempts = 100
+
 var log = logf.Log.WithName("controller_cyndipipeline")
 
 func (r *CyndiPipelineReconciler) setup(reqLogger logr.Logger, request ctrl.Request, ctx context.Context) (ReconcileIteration, error) {
@@ -86,28 +112,72 @@ func (r *CyndiPipelineReconciler) setup(reqLogger logr.Logger, request ctrl.Requ
 		Clientset:        r.Clientset,
 		Scheme:           r.Scheme,
 		AppDb:            &database.AppDatabase{},
-		Log:              reqLogger,
+		Log:              reqLogger.WithValues(logFieldState, instance.GetState()),
 		Now:              time.Now().Format(time.RFC3339),
 		GetRequeueInterval: func(Instance *ReconcileIteration) int64 {
 			return i.config.StandardInterval
 		},
 		Recorder: r.Recorder,
 		ctx:      ctx,
+		readOnly: r.ReadOnly,
+
+		operatorNamespace: r.OperatorNamespace,
+		hostCache:         r.HostCache,
+		bootstrapped:      &r.bootstrapped,
+
+		newInventoryDatabase: r.NewInventoryDatabase,
 	}
 
-	if err = i.parseConfig(); err != nil {
+	// do not connect to the databases while the pipeline is throttled
+	if i.throttledFor = i.getThrottledFor(time.Now()); i.throttledFor > 0 {
+		return i, nil
+	}
+
+	if err = i.inheritSourcePipeline(); err != nil {
 		return i, err
 	}
 
-	if i.HBIDBParams, err = config.LoadDBSecret(i.config, i.Client, i.Instance.Namespace, i.config.InventoryDbSecret); err != nil {
+	if err = i.parseConfig(); err != nil {
 		return i, err
 	}
 
+	// the HBI API is also used to cross-check the pipeline against xjoin
+	if i.config.InventorySource == config.InventorySourceAPI || i.config.ValidationXjoinEnabled {
+		if i.HBIAPIParams, err = config.LoadAPISecret(i.config, i.Client, i.Instance.Namespace); err != nil {
+			return i, err
+		}
+	}
+
+	if i.config.InventorySource != config.InventorySourceAPI {
+		for _, secret := range i.config.InventoryDbSecrets {
+			params, err := config.LoadDBSecret(i.config, i.Client, i.Instance.Namespace, secret)
+			if err != nil {
+				return i, err
+			}
+
+			i.HBIDBParams = append(i.HBIDBParams, params)
+		}
+	}
+
 	if i.AppDBParams, err = config.LoadDBSecret(i.config, i.Client, i.Instance.Namespace, utils.AppDbSecretName(i.Instance.Spec)); err != nil {
 		return i, err
 	}
 
-	i.AppDb = database.NewAppDatabase(&i.AppDBParams, reqLogger)
+	i.AppDBParams.Auth = i.config.DBAuth
+
+	if r.NewAppDatabase != nil {
+		i.AppDb = r.NewAppDatabase(&i.AppDBParams)
+	} else {
+		appDb := database.NewAppDatabase(&i.AppDBParams, i.Log)
+		appDb.Dialect = database.GetDialect(i.config.DBDialect)
+		appDb.Staleness = i.config.ViewStaleness
+		appDb.ColumnTypes = i.config.DBColumnTypes
+		appDb.DisplayNameSearch = i.config.DBDisplayNameSearch
+		i.AppDb = appDb
+	}
+
+	i.AppDb.SetContext(ctx)
+	i.AppDb.SetView(i.viewName())
 
 	if err = i.AppDb.Connect(); err != nil {
 		return i, err
@@ -118,17 +188,27 @@ func (r *CyndiPipelineReconciler) setup(reqLogger logr.Logger, request ctrl.Requ
 
 // +kubebuilder:rbac:groups=cyndi.cloud.redhat.com,resources=cyndipipelines;cyndipipelines/status;cyndipipelines/finalizers,verbs=*
 // +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkaconnectors;kafkaconnectors/finalizers,verbs=*
-// +kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkaconnects,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkatopics,verbs=get;list
+// +kubebuilder:rbac:groups=cyndi.cloud.redhat.com,resources=cyndiconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 func (r *CyndiPipelineReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
+	ctx, span := tracing.Start(ctx, "CyndiPipeline.Reconcile", requestAttributes(request)...)
+	result, err := r.reconcilePipeline(ctx, request)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *CyndiPipelineReconciler) reconcilePipeline(ctx context.Context, request ctrl.Request) (result ctrl.Result, err error) {
 
 	//capture errors until the finalizer completed
 	var setupErrors []error
 
-	reqLogger := log.WithValues("Pipeline", request.Name, "Namespace", request.Namespace)
+	reqLogger := newRequestLogger(log, request)
 	reqLogger.Info("Reconciling CyndiPipeline")
 
+	start := time.Now()
 	i, err := r.setup(reqLogger, request, ctx)
 	defer i.Close()
 
@@ -142,6 +222,19 @@ func (r *CyndiPipelineReconciler) Reconcile(ctx context.Context, request ctrl.Re
 		return reconcile.Result{}, nil
 	}
 
+	if i.throttledFor > 0 {
+		i.debug("Pipeline throttled", "delay", i.throttledFor)
+		return reconcile.Result{RequeueAfter: i.throttledFor}, nil
+	}
+
+	i.recordStep(cyndi.STEP_SECRETS, time.Since(start), err)
+
+	// persisted with the status if this attempt succeeds
+	i.Instance.ResetThrottled()
+	defer func() {
+		result, err = i.applyBackoff(result, err)
+	}()
+
 	// remove any stale dependencies
 	// if we're shutting down this removes all dependencies
 	setupErrors = append(setupErrors, i.deleteStaleDependencies()...)
@@ -161,6 +254,11 @@ func (r *CyndiPipelineReconciler) Reconcile(ctx context.Context, request ctrl.Re
 			return reconcile.Result{}, setupErrors[0]
 		}
 
+		if err = i.reconcileMonitoring(); err != nil {
+			// not fatal - the monitoring resources only cover pipelines that are left
+			i.Log.Error(err, "Failed to update monitoring resources")
+		}
+
 		if err = i.removeFinalizer(); err != nil {
 			return reconcile.Result{}, i.error(err, "Error removing finalizer")
 		}
@@ -174,58 +272,278 @@ func (r *CyndiPipelineReconciler) Reconcile(ctx context.Context, request ctrl.Re
 		return reconcile.Result{}, setupErrors[0]
 	}
 
+	i.clearDegraded(reasonDatabaseUnavailable)
+
+	if err = i.runStep(cyndi.STEP_TABLE, i.bootstrapAppDb); err != nil {
+		return reconcile.Result{}, i.error(err, "Error bootstrapping database")
+	}
+
 	metrics.InitLabels(i.Instance)
 
+	if err = i.reconcileMonitoring(); err != nil {
+		// not fatal - monitoring resources do not affect the pipeline itself
+		i.Log.Error(err, "Failed to update monitoring resources")
+	}
+
+	if err = i.reconcileNetworkPolicies(); err != nil {
+		// not fatal - the pipeline may not depend on the policies (e.g. if egress is not restricted)
+		i.Log.Error(err, "Failed to update NetworkPolicies")
+	}
+
+	if err = i.reconcileValidationCronJob(); err != nil {
+		// not fatal - the pipeline is validated by the next run of the current CronJob (if any)
+		i.Log.Error(err, "Failed to update validation CronJob")
+	}
+
 	// STATE_NEW
 	if i.Instance.GetState() == cyndi.STATE_NEW {
 		if err := i.addFinalizer(); err != nil {
 			return reconcile.Result{}, i.error(err, "Error adding finalizer")
 		}
 
+		if i.skipMutation("Not starting a new pipeline version") {
+			return i.updateStatusAndRequeue()
+		}
+
+		// only a newly created pipeline adopts existing resources - a refreshed one starts from scratch
+		if i.Instance.Spec.AdoptExisting && i.Instance.Status.TableName == "" {
+			if adopted, err := i.adoptExisting(); err != nil {
+				return reconcile.Result{}, i.error(err, "Error adopting existing table")
+			} else if adopted {
+				i.Log.Info("Transitioning to InitialSync")
+				return i.updateStatusAndRequeue()
+			}
+		}
+
+		// checked before the connector of the active table is stopped to hand over its consumer group
+		if ready, err := i.checkTopics(); err != nil {
+			return reconcile.Result{}, i.error(err, "Error checking topics")
+		} else if !ready {
+			return i.updateStatusAndRequeue()
+		}
+
+		if done, err := i.handOverConsumerGroup(); err != nil {
+			return reconcile.Result{}, i.error(err, "Error handing over consumer group")
+		} else if !done {
+			result, err := i.updateStatusAndRequeue()
+			if err == nil {
+				result.RequeueAfter = time.Duration(i.config.RefreshHandoverGracePeriod) * time.Second
+			}
+
+			return result, err
+		}
+
+		consumerGroup := i.getHandedOverConsumerGroup()
+
 		i.Instance.Status.CyndiConfigVersion = i.config.ConfigMapVersion
 		i.Instance.Status.SpecHash = i.config.SpecHash
 
-		pipelineVersion := fmt.Sprintf("1_%s", strconv.FormatInt(time.Now().UnixNano(), 10))
+		pipelineVersion, resumed, err := i.startPipelineVersion()
+		if err != nil {
+			return reconcile.Result{}, i.error(err, "Error naming pipeline version")
+		}
+
 		i.Instance.TransitionToInitialSync(pipelineVersion)
 		i.probeStartingInitialSync()
 
-		err = i.AppDb.CreateTable(cyndi.TableName(pipelineVersion), i.config.DBTableInitScript+i.config.DBTableIndexSQL)
+		err = i.runStep(cyndi.STEP_TABLE, func() error {
+			adopted, err := i.createOrAdoptTable(cyndi.TableName(pipelineVersion), resumed)
+			if err != nil {
+				return i.error(err, "Error creating table")
+			}
+
+			// rows may have been copied to the adopted table already - the connector populates it instead
+			if adopted {
+				i.Instance.Status.CloneSourceTable = ""
+			} else if cloned, err := i.cloneActiveTable(consumerGroup != ""); err != nil {
+				return i.error(err, "Error cloning active table")
+			} else if cloned {
+				i.Instance.Status.ConsumerGroup = consumerGroup
+			}
+
+			if err := i.registerPipelineVersion(); err != nil {
+				return i.error(err, "Error registering pipeline version")
+			}
+
+			return nil
+		})
 		if err != nil {
-			return reconcile.Result{}, i.error(err, "Error creating table")
+			return reconcile.Result{}, err
 		}
 
-		_, err = i.createConnector(cyndi.ConnectorName(pipelineVersion, i.Instance.Spec.AppName), false)
+		err = i.runStep(cyndi.STEP_CONNECTOR, func() (err error) {
+			// a consumer group handed over by the previous connector takes precedence
+			if i.Instance.Status.ConsumerGroup == "" {
+				if i.Instance.Status.ConsumerGroup, err = i.templatedConsumerGroup(pipelineVersion); err != nil {
+					return i.error(err, "Error rendering consumer group")
+				}
+			}
+
+			connectorName := cyndi.ConnectorName(pipelineVersion, i.Instance.Spec.AppName)
+			if _, err := i.createConnector(connectorName, false); err != nil {
+				return i.error(err, "Error creating connector")
+			}
+
+			i.Instance.Status.ConnectorPending = false
+			i.probeConnectorCreated(connectorName)
+
+			if err := i.reconcileSourceConnector(); err != nil {
+				return i.error(err, "Error creating source connector")
+			}
+
+			return nil
+		})
 		if err != nil {
-			return reconcile.Result{}, i.error(err, "Error creating connector")
+			return reconcile.Result{}, err
 		}
 
 		i.Log.Info("Transitioning to InitialSync")
 		return i.updateStatusAndRequeue()
 	}
 
-	problem, err := i.checkForDeviation()
-	if err != nil {
-		return reconcile.Result{}, i.error(err, "Error checking for state deviation")
-	} else if problem != nil {
-		i.probeStateDeviationRefresh(problem.Error())
-		i.Instance.TransitionToNew()
+	// a missing connector is re-created rather than refreshing the pipeline
+	connectorMissing := false
+	connectorErr := i.runStep(cyndi.STEP_CONNECTOR, func() (err error) {
+		if connectorMissing, err = i.recreateMissingConnector(); err != nil {
+			return i.error(err, "Error re-creating connector")
+		} else if connectorMissing {
+			return nil
+		}
+
+		i.clearDegraded(reasonConnectorMissing)
+
+		if err = i.reconcileSourceConnector(); err != nil {
+			return i.error(err, "Error creating source connector")
+		}
+
+		if err = i.reconcileConnectorMetadata(); err != nil {
+			return i.error(err, "Error updating connector metadata")
+		}
+
+		return nil
+	})
+
+	// the state of the pipeline can only be assessed once its connector is in place
+	if connectorErr == nil && !connectorMissing {
+		if rolledBack, err := i.rollbackIfRequested(); err != nil {
+			return reconcile.Result{}, i.error(err, "Error rolling back view")
+		} else if rolledBack {
+			return i.updateStatusAndRequeue()
+		}
+
+		if reset, err := i.resetOffsetsIfRequested(); err != nil {
+			return reconcile.Result{}, i.error(err, "Error resetting offsets")
+		} else if reset {
+			return i.updateStatusAndRequeue()
+		}
+
+		// reported before checkForDeviation corrects (or refreshes) anything
+		if err := i.reportDrift(); err != nil {
+			// not fatal - the report is informational only
+			i.Log.Error(err, "Failed to report drift")
+		}
+
+		problem, err := i.checkForDeviation()
+		if err != nil {
+			return reconcile.Result{}, i.error(err, "Error checking for state deviation")
+		} else if problem != nil && i.readOnly {
+			i.probeStateDeviationReported(problem.Error())
+		} else if problem != nil {
+			i.probeStateDeviationRefresh(problem.Error())
+			valid := i.Instance.GetState() == cyndi.STATE_VALID
+			i.Instance.TransitionToNew()
+
+			if valid {
+				// data of a valid pipeline can be reused by the next pipeline version
+				i.Instance.Status.CloneSourceTable = i.Instance.Status.TableName
+			}
+			return i.updateStatusAndRequeue()
+		}
+	}
+
+	// the table and the view are reconciled even if the connector step failed
+	_ = i.runStep(cyndi.STEP_TABLE, func() error {
+		if err := i.reconcileDBGrants(); err != nil {
+			return i.error(err, "Error applying grants")
+		}
+
+		return nil
+	})
+
+	viewUpdated := false
+	_ = i.runStep(cyndi.STEP_VIEW, func() (err error) {
+		if err = i.reconcileViewStaleness(); err != nil {
+			return i.error(err, "Error updating the staleness column")
+		}
+
+		if i.Instance.GetState() == cyndi.STATE_VALID && connectorErr == nil && !connectorMissing {
+			if viewUpdated, err = i.recreateViewIfNeeded(); err != nil {
+				return i.error(err, "Error updating hosts view")
+			}
+		}
+
+		return nil
+	})
+
+	if err = i.stepError(); err != nil {
+		return reconcile.Result{}, err
+	} else if connectorMissing {
 		return i.updateStatusAndRequeue()
 	}
 
+	if aborted, err := i.checkInitialSyncDeadline(); err != nil {
+		return reconcile.Result{}, i.error(err, "Error escalating exceeded initial sync deadline")
+	} else if aborted {
+		return i.updateStatusAndRequeue()
+	}
+
+	// STATE_INITIAL_SYNC
+	if i.Instance.GetState() == cyndi.STATE_INITIAL_SYNC {
+		if refreshed, err := i.checkInitialSyncStuck(); err != nil {
+			return reconcile.Result{}, i.error(err, "Error remediating stuck initial sync")
+		} else if refreshed {
+			return i.updateStatusAndRequeue()
+		}
+	}
+
 	// STATE_VALID
 	if i.Instance.GetState() == cyndi.STATE_VALID {
-		if updated, err := i.recreateViewIfNeeded(); err != nil {
-			return reconcile.Result{}, i.error(err, "Error updating hosts view")
-		} else if updated {
+		if viewUpdated {
 			i.eventNormal("ValidationSucceeded", "Pipeline became valid. inventory.hosts view now points to %s", i.Instance.Status.TableName)
+
+			if i.config.SmokeTestEnabled && !i.skipMutation("Not starting smoke test") {
+				if err = i.startSmokeTest(); err != nil {
+					// not fatal - the smoke test only verifies the application's access to the view
+					i.Log.Error(err, "Failed to start smoke test")
+				}
+			}
+		}
+
+		if err = i.checkSmokeTest(); err != nil {
+			i.Log.Error(err, "Failed to check smoke test")
+		}
+
+		if i.config.CanaryConfig.Enabled {
+			if err = i.runCanary(); err != nil {
+				// not fatal - the canary only measures replication latency
+				i.Log.Error(err, "Canary failed")
+			}
+		}
+
+		if i.config.StateExportEnabled {
+			if err = i.exportState(); err != nil {
+				// not fatal - the exported state is only needed to re-create the pipeline
+				i.Log.Error(err, "Failed to export pipeline state")
+			}
 		}
 
 		return i.updateStatusAndRequeue()
 	}
 
 	// invalid pipeline - either STATE_INITIAL_SYNC or STATE_INVALID
-	if i.Instance.GetValid() == metav1.ConditionFalse {
-		if i.Instance.Status.ValidationFailedCount >= i.getValidationConfig().AttemptsThreshold {
+	// a pipeline suspended while its Connect cluster is not ready would not fare any better after a refresh
+	if i.Instance.GetValid() == metav1.ConditionFalse && !isDegradedBy(i.Instance, reasonConnectClusterNotReady) {
+		if i.Instance.Status.ValidationFailedCount >= i.getValidationConfig().AttemptsThreshold && !i.skipMutation("Not refreshing invalid pipeline") {
 
 			// This pipeline never became valid.
 			if i.Instance.GetState() == cyndi.STATE_INITIAL_SYNC {
@@ -250,6 +568,11 @@ func (i *ReconcileIteration) deleteStaleDependencies() (errors []error) {
 		tablesToKeep     []string
 	)
 
+	reason := "no longer used by the pipeline"
+	if i.Instance.GetState() == cyndi.STATE_REMOVED {
+		reason = "pipeline removed"
+	}
+
 	if i.Instance.GetState() != cyndi.STATE_REMOVED && i.Instance.Status.PipelineVersion != "" {
 		connectorsToKeep = append(connectorsToKeep, cyndi.ConnectorName(i.Instance.Status.PipelineVersion, i.Instance.Spec.AppName))
 		tablesToKeep = append(tablesToKeep, cyndi.TableName(i.Instance.Status.PipelineVersion))
@@ -263,29 +586,88 @@ func (i *ReconcileIteration) deleteStaleDependencies() (errors []error) {
 		tablesToKeep = append(tablesToKeep, *currentTable)
 	}
 
-	connectors, err := connect.GetConnectorsForOwner(i.Client, i.Instance.Namespace, i.Instance.GetUIDString())
+	// the table backing the view before the last cutover is kept for the retention period
+	if retained := i.retainedTable(); retained != "" && i.Instance.GetState() != cyndi.STATE_REMOVED {
+		connectorsToKeep = append(connectorsToKeep, cyndi.TableNameToConnectorName(retained, i.Instance.Spec.AppName))
+		tablesToKeep = append(tablesToKeep, retained)
+	}
+
+	// the table of an interrupted pipeline version is adopted by the next attempt (see startPipelineVersion)
+	if pending := i.Instance.Status.PendingTableName; pending != "" && i.Instance.GetState() != cyndi.STATE_REMOVED {
+		connectorsToKeep = append(connectorsToKeep, cyndi.TableNameToConnectorName(pending, i.Instance.Spec.AppName))
+		tablesToKeep = append(tablesToKeep, pending)
+	}
+
+	// source connectors are kept and removed along with their sink connectors
+	for _, name := range connectorsToKeep {
+		connectorsToKeep = append(connectorsToKeep, connect.SourceConnectorName(name))
+	}
+
+	// connectors may live in the namespace of a Connect cluster used previously
+	connectors, err := connect.GetConnectorsForOwner(i.ctx, i.Client, "", i.Instance.GetUIDString())
 	if err != nil {
 		errors = append(errors, err)
 	} else {
 		for _, connector := range connectors.Items {
-			if !utils.ContainsString(connectorsToKeep, connector.GetName()) {
-				i.Log.Info("Removing stale connector", "connector", connector.GetName())
-				if err = connect.DeleteConnector(i.Client, connector.GetName(), i.Instance.Namespace); err != nil {
+			if !utils.ContainsString(connectorsToKeep, connector.GetName()) && !i.skipMutation("Not removing stale connector", "connector", connector.GetName()) {
+				i.Log.Info("Removing stale connector", "connector", connector.GetName(), "namespace", connector.GetNamespace())
+				if err = connect.DeleteConnector(i.ctx, i.Client, connector.GetName(), connector.GetNamespace()); err != nil {
 					errors = append(errors, err)
+				} else {
+					i.probeConnectorDeleted(connector.GetName(), reason)
 				}
 			}
 		}
 	}
 
+	if err = i.dropStaleReplicationSlots(connectorsToKeep); err != nil {
+		errors = append(errors, err)
+	} else if i.Instance.GetState() == cyndi.STATE_REMOVED && len(i.Instance.Status.SourceReplicationSlots) > 0 && !i.readOnly {
+		// keeps the finalizer until the removed source connectors released their slots
+		errors = append(errors, fmt.Errorf("Replication slots %s are still in use", strings.Join(i.Instance.Status.SourceReplicationSlots, ", ")))
+	}
+
+	if i.Instance.GetState() == cyndi.STATE_REMOVED && !i.skipMutation("Not removing NetworkPolicies") {
+		if err = i.deleteNetworkPolicies(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if i.Instance.GetState() == cyndi.STATE_REMOVED && !i.skipMutation("Not removing validation CronJobs") {
+		if err = i.deleteValidationCronJobs(nil); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if i.Instance.GetState() == cyndi.STATE_REMOVED {
+		if err = i.deletePipelineMetadata(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
 	tables, err := i.AppDb.GetCyndiTables()
+	if err == nil {
+		tables, err = i.ownedTables(tables, tablesToKeep)
+	}
+
 	if err != nil {
 		errors = append(errors, err)
 	} else {
+		if !utils.ContainsString(tables, i.Instance.Status.PreviousTableName) {
+			i.Instance.Status.PreviousTableName = ""
+		}
+
 		for _, table := range tables {
-			if !utils.ContainsString(tablesToKeep, table) {
+			if !utils.ContainsString(tablesToKeep, table) && !i.skipMutation("Not removing stale table", "table", table) {
 				i.Log.Info("Removing stale table", "table", table)
 				if err = i.AppDb.DeleteTable(table); err != nil {
 					errors = append(errors, err)
+				} else {
+					i.probeTableDropped(table, reason)
+
+					if table == i.Instance.Status.PreviousTableName {
+						i.Instance.Status.PreviousTableName = ""
+					}
 				}
 			}
 		}
@@ -299,12 +681,27 @@ func (r *CyndiPipelineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Named("cyndi-controller").
 		For(&cyndi.CyndiPipeline{}).
 		Owns(connect.EmptyConnector()).
+		Owns(&batchv1.Job{}).
+		// connectors in the namespace of a Connect cluster elsewhere are not covered by Owns()
+		Watches(&source.Kind{Type: connect.EmptyConnector()}, handler.EnqueueRequestsFromMapFunc(func(connector client.Object) []reconcile.Request {
+			labels := connector.GetLabels()
+			if labels[connect.LabelOwnerNamespace] == "" || labels[connect.LabelOwnerName] == "" {
+				return nil
+			}
+
+			return []reconcile.Request{{
+				NamespacedName: types.NamespacedName{
+					Namespace: labels[connect.LabelOwnerNamespace],
+					Name:      labels[connect.LabelOwnerName],
+				},
+			}}
+		})).
 		// trigger Reconcile if "cyndi" ConfigMap changes
 		Watches(&source.Kind{Type: &v1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(func(configMap client.Object) []reconcile.Request {
 			var requests []reconcile.Request
 
 			if configMap.GetName() != configMapName {
-				return requests
+				return r.pipelinesReferencingTemplate(configMap)
 			}
 
 			// cyndi configmap changed - let's Reconcile all CyndiPipelines in the given namespace
@@ -326,21 +723,143 @@ func (r *CyndiPipelineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			r.Log.Info("Cyndi ConfigMap changed. Reconciling CyndiPipelines", "namespace", configMap.GetNamespace(), "pipelines", requests)
 			return requests
 		})).
+		// credentials and Connect clusters are shared by pipelines - pick up their changes without waiting for the resync interval
+		Watches(&source.Kind{Type: &v1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.pipelinesReferencingSecret), builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Watches(&source.Kind{Type: connect.EmptyConnectCluster()}, handler.EnqueueRequestsFromMapFunc(r.pipelinesUsingConnectCluster), builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		// pipelines waiting for a pipeline they depend on to become valid
+		Watches(&source.Kind{Type: &cyndi.CyndiPipeline{}}, handler.EnqueueRequestsFromMapFunc(r.pipelinesDependingOn)).
+		// trigger Reconcile of all CyndiPipelines if the CyndiConfig changes
+		Watches(&source.Kind{Type: &cyndi.CyndiConfig{}}, handler.EnqueueRequestsFromMapFunc(func(cyndiConfig client.Object) []reconcile.Request {
+			var requests []reconcile.Request
+
+			if cyndiConfig.GetName() != cyndiConfigName {
+				return requests
+			}
+
+			pipelines, err := utils.FetchCyndiPipelines(r.Client, "")
+			if err != nil {
+				r.Log.Error(err, "Failed to fetch CyndiPipelines")
+				return requests
+			}
+
+			for _, pipeline := range pipelines.Items {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Namespace: pipeline.GetNamespace(),
+						Name:      pipeline.GetName(),
+					},
+				})
+			}
+
+			r.Log.Info("CyndiConfig changed. Reconciling CyndiPipelines", "pipelines", requests)
+			return requests
+		})).
 		Complete(r)
 }
 
+// Pipelines whose connector template is stored in the given ConfigMap
+func (r *CyndiPipelineReconciler) pipelinesReferencingTemplate(configMap client.Object) (requests []reconcile.Request) {
+	pipelines, err := utils.FetchCyndiPipelines(r.Client, configMap.GetNamespace())
+	if err != nil {
+		r.Log.Error(err, "Failed to fetch CyndiPipelines", "namespace", configMap.GetNamespace())
+		return
+	}
+
+	for _, pipeline := range pipelines.Items {
+		template := pipeline.Spec.ConnectorTemplate
+		if pipeline.GetNamespace() == configMap.GetNamespace() && template != nil && template.ConfigMapRef != nil && template.ConfigMapRef.Name == configMap.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: pipeline.GetNamespace(),
+					Name:      pipeline.GetName(),
+				},
+			})
+		}
+	}
+
+	return
+}
+
+// Pipelines using the given secret as the application database, HBI database or HBI API secret
+func (r *CyndiPipelineReconciler) pipelinesReferencingSecret(secret client.Object) []reconcile.Request {
+	requests := r.pipelinesMatching(secret.GetNamespace(), func(pipeline *cyndi.CyndiPipeline, cfg *config.CyndiConfiguration) bool {
+		if pipeline.GetNamespace() != secret.GetNamespace() {
+			return false
+		}
+
+		return utils.AppDbSecretName(pipeline.Spec) == secret.GetName() ||
+			utils.ContainsString(cfg.InventoryDbSecrets, secret.GetName()) ||
+			cfg.InventoryAPISecret == secret.GetName()
+	})
+
+	if len(requests) > 0 {
+		r.Log.Info("Secret changed. Reconciling CyndiPipelines", "secret", secret.GetName(), "namespace", secret.GetNamespace(), "pipelines", requests)
+	}
+
+	return requests
+}
+
+// Pipelines whose connectors run in the given Kafka Connect cluster
+func (r *CyndiPipelineReconciler) pipelinesUsingConnectCluster(cluster client.Object) []reconcile.Request {
+	requests := r.pipelinesMatching("", func(pipeline *cyndi.CyndiPipeline, cfg *config.CyndiConfiguration) bool {
+		namespace := cfg.ConnectClusterNamespace
+		if namespace == "" {
+			namespace = pipeline.GetNamespace()
+		}
+
+		return cfg.ConnectCluster == cluster.GetName() && namespace == cluster.GetNamespace()
+	})
+
+	if len(requests) > 0 {
+		r.Log.Info("Connect cluster changed. Reconciling CyndiPipelines", "cluster", cluster.GetName(), "namespace", cluster.GetNamespace(), "pipelines", requests)
+	}
+
+	return requests
+}
+
+// Pipelines (in the given namespace or in all namespaces if empty) whose configuration matches the given predicate
+func (r *CyndiPipelineReconciler) pipelinesMatching(namespace string, matches func(*cyndi.CyndiPipeline, *config.CyndiConfiguration) bool) (requests []reconcile.Request) {
+	pipelines, err := utils.FetchCyndiPipelines(r.Client, namespace)
+	if err != nil {
+		r.Log.Error(err, "Failed to fetch CyndiPipelines", "namespace", namespace)
+		return
+	}
+
+	for idx := range pipelines.Items {
+		pipeline := &pipelines.Items[idx]
+
+		// a pipeline with an invalid configuration is reconciled periodically anyway
+		cfg, err := buildPipelineConfig(r.Client, pipeline)
+		if err != nil || !matches(pipeline, cfg) {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: pipeline.GetNamespace(),
+				Name:      pipeline.GetName(),
+			},
+		})
+	}
+
+	return
+}
+
+// Finalizers are patched rather than updated so that the operator never writes the spec of the pipeline (owned by the user or a GitOps tool)
 func (i *ReconcileIteration) addFinalizer() error {
 	if !utils.ContainsString(i.Instance.GetFinalizers(), cyndipipelineFinalizer) {
+		patch := client.MergeFrom(i.Instance.DeepCopy())
 		controllerutil.AddFinalizer(i.Instance, cyndipipelineFinalizer)
-		return i.Client.Update(i.ctx, i.Instance)
+		return i.Client.Patch(i.ctx, i.Instance, patch)
 	}
 
 	return nil
 }
 
 func (i *ReconcileIteration) removeFinalizer() error {
+	patch := client.MergeFrom(i.Instance.DeepCopy())
 	controllerutil.RemoveFinalizer(i.Instance, cyndipipelineFinalizer)
-	return i.Client.Update(i.ctx, i.Instance)
+	return i.Client.Patch(i.ctx, i.Instance, patch)
 }
 
 func (i *ReconcileIteration) createConnector(name string, dryRun bool) (*unstructured.Unstructured, error) {
@@ -359,9 +878,67 @@ func (i *ReconcileIteration) createConnector(name string, dryRun bool) (*unstruc
 		AllowlistSystemProfile:   i.config.ConnectorAllowlistSystemProfile,
 		TopicReplicationFactor:   i.config.TopicReplicationFactor,
 		DeadLetterQueueTopicName: i.config.DeadLetterQueueTopicName,
+		ConsumerGroup:            i.Instance.Status.ConsumerGroup,
+		AutoOffsetReset:          i.config.ConnectorAutoOffsetReset,
+		Sink:                     i.connectorSink(),
+		DeleteEnabled:            i.config.ConnectorDeleteEnabled,
+		Tombstones:               i.config.ConnectorTombstones,
+		PKMode:                   i.config.ConnectorPKMode,
+		PKFields:                 i.config.ConnectorPKFields,
+		ColumnTypes:              i.config.DBColumnTypes,
+		Labels:                   i.config.ConnectorLabels,
+		Annotations:              i.config.ConnectorAnnotations,
+		OperatorVersion:          OperatorVersion,
+	}
+
+	if since := i.Instance.Status.ClonedEventsSince; since != nil {
+		connectorConfig.MinTimestamp = since.UnixNano() / int64(time.Millisecond)
+	}
+
+	i.applyOffsetReset(&connectorConfig)
+
+	connector, err := connect.CreateConnector(i.ctx, i.Client, name, i.connectorNamespace(), connectorConfig, i.Instance, i.Scheme, dryRun)
+	if err != nil || dryRun {
+		return connector, err
 	}
 
-	return connect.CreateConnector(i.Client, name, i.Instance.Namespace, connectorConfig, i.Instance, i.Scheme, dryRun)
+	// tells pipelines running connectors rendered by a previous version of the operator or of the template apart
+	i.Instance.Status.OperatorVersion = OperatorVersion
+	i.Instance.Status.ConnectorTemplateVersion = connector.GetLabels()[connect.LabelTemplateVersion]
+	return connector, nil
+}
+
+// Propagates changes of spec.connectorLabels and spec.connectorAnnotations to the existing connectors of the pipeline
+func (i *ReconcileIteration) reconcileConnectorMetadata() error {
+	connectors, err := connect.GetConnectorsForOwner(i.ctx, i.Client, i.connectorNamespace(), i.Instance.GetUIDString())
+	if err != nil {
+		return err
+	}
+
+	for idx := range connectors.Items {
+		connector := &connectors.Items[idx]
+
+		if !connect.SetMetadata(connector, i.config.ConnectorLabels, i.config.ConnectorAnnotations) || i.skipMutation("Not updating connector metadata", "connector", connector.GetName()) {
+			continue
+		}
+
+		if err = connect.UpdateMetadata(i.ctx, i.Client, connector); err != nil {
+			return err
+		}
+
+		i.debug("Updated connector metadata", "connector", connector.GetName())
+	}
+
+	return nil
+}
+
+func (i *ReconcileIteration) connectorSink() connect.Sink {
+	return connect.Sink{
+		Flavor:       i.config.ConnectorFlavor,
+		Class:        i.config.ConnectorClass,
+		KeyMapping:   i.config.ConnectorKeyMapping,
+		RequiredKeys: i.config.ConnectorRequiredKeys,
+	}
 }
 
 func (i *ReconcileIteration) recreateViewIfNeeded() (bool, error) {
@@ -371,11 +948,40 @@ func (i *ReconcileIteration) recreateViewIfNeeded() (bool, error) {
 	}
 
 	if table == nil || *table != i.Instance.Status.TableName {
+		if i.skipMutation("Not updating view", "table", i.Instance.Status.TableName) {
+			return false, nil
+		}
+
+		if waiting, err := i.waitForDependencies(); err != nil || waiting {
+			return false, err
+		}
+
+		if table != nil {
+			if refused, err := i.refuseShrunkTable(*table); err != nil || refused {
+				return false, err
+			}
+		}
+
+		if err = i.prepareTableForView(); err != nil {
+			return false, err
+		}
+
 		i.Log.Info("Updating view", "table", i.Instance.Status.TableName)
 		if err = i.AppDb.UpdateView(i.Instance.Status.TableName); err != nil {
 			return false, err
 		}
 
+		if err = i.applyDBGrants(i.viewName()); err != nil {
+			return false, err
+		}
+
+		if rolledBack, err := i.verifyViewOrRollback(table); err != nil || rolledBack {
+			return false, err
+		}
+
+		i.clearDegraded(reasonTableShrunk, reasonViewVerificationFailed)
+		i.recordCutover(table)
+		i.probeViewReplaced(table, "pipeline is valid")
 		return true, nil
 	}
 
@@ -391,6 +997,10 @@ func (i *ReconcileIteration) checkForDeviation() (problem error, err error) {
 		return fmt.Errorf("Spec changed. New hash is %s", i.config.SpecHash), nil
 	}
 
+	if i.resyncRequested() {
+		return fmt.Errorf("Resync requested at %s", i.Instance.Spec.ResyncRequestedAt.Format(time.RFC3339)), nil
+	}
+
 	dbTableExists, err := i.AppDb.CheckIfTableExists(i.Instance.Status.TableName)
 	if err != nil {
 		return nil, err
@@ -398,17 +1008,25 @@ func (i *ReconcileIteration) checkForDeviation() (problem error, err error) {
 		return fmt.Errorf("Database table %s not found", i.Instance.Status.TableName), nil
 	}
 
-	connector, err := connect.GetConnector(i.Client, i.Instance.Status.ConnectorName, i.Instance.Namespace)
+	if problem, err := i.checkUnloggedTable(); err != nil || problem != nil {
+		return problem, err
+	}
+
+	connector, err := connect.GetConnector(i.ctx, i.Client, i.Instance.Status.ConnectorName, i.connectorNamespace())
 	if err != nil {
 		if k8errors.IsNotFound(err) {
-			return fmt.Errorf("Connector %s not found in %s", i.Instance.Status.ConnectorName, i.Instance.Namespace), nil
+			// not a reason for a refresh - the pipeline is Degraded until the connector is re-created
+			return nil, nil
 		}
 
 		return nil, err
 
 	}
 
-	if connect.IsFailed(connector) {
+	failed := connect.IsFailed(connector)
+	metrics.ConnectorFailed(i.Instance, failed)
+
+	if failed {
 		return fmt.Errorf("Connector %s is in the FAILED state", i.Instance.Status.ConnectorName), nil
 	}
 
@@ -434,20 +1052,67 @@ func (i *ReconcileIteration) checkForDeviation() (problem error, err error) {
 		return nil, err
 	}
 
-	currentConnectorConfig, _, err1 := unstructured.NestedMap(connector.UnstructuredContent(), "spec", "config")
-	newConnectorConfig, _, err2 := unstructured.NestedMap(newConnector.UnstructuredContent(), "spec", "config")
+	if connector.GetAnnotations()[connect.AnnotationSpecHash] != newConnector.GetAnnotations()[connect.AnnotationSpecHash] {
+		// the connector of the pipeline version fallen back to predates the desired configuration of the aborted refresh
+		if i.isRefreshAborted() {
+			return checkConnectorUnmodified(connector)
+		}
+
+		// the desired configuration changed (or the connector predates the hash) - a new pipeline version is needed
+		currentConnectorConfig, _, err1 := unstructured.NestedMap(connector.UnstructuredContent(), "spec", "config")
+		newConnectorConfig, _, err2 := unstructured.NestedMap(newConnector.UnstructuredContent(), "spec", "config")
+
+		if err1 == nil && err2 == nil {
+			diff := cmp.Diff(currentConnectorConfig, newConnectorConfig, NumberNormalizer)
+
+			if len(diff) > 0 {
+				return fmt.Errorf("Connector configuration has changed: %s", diff), nil
+			}
+		}
+
+		return nil, nil
+	}
+
+	// the desired configuration is unchanged so any difference is an out-of-band modification of the connector
+	currentSpec, _, err1 := unstructured.NestedMap(connector.UnstructuredContent(), "spec")
+	newSpec, _, err2 := unstructured.NestedMap(newConnector.UnstructuredContent(), "spec")
 
 	if err1 == nil && err2 == nil {
-		diff := cmp.Diff(currentConnectorConfig, newConnectorConfig, NumberNormalizer)
+		if diff := cmp.Diff(currentSpec, newSpec, NumberNormalizer); len(diff) > 0 {
+			if i.readOnly {
+				return fmt.Errorf("Connector %s was modified outside of the operator: %s", connector.GetName(), diff), nil
+			}
+
+			if err = connect.RestoreConnector(i.ctx, i.Client, connector, newConnector); err != nil {
+				return nil, err
+			}
 
-		if len(diff) > 0 {
-			return fmt.Errorf("Connector configuration has changed: %s", diff), nil
+			i.probeConnectorRestored(connector.GetName(), diff)
 		}
 	}
 
 	return nil, nil
 }
 
+// Compares the spec of the connector with the spec it was created from (see connect.AnnotationSpecHash)
+func checkConnectorUnmodified(connector *unstructured.Unstructured) (problem error, err error) {
+	spec, _, err := unstructured.NestedMap(connector.UnstructuredContent(), "spec")
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := utils.SpecHash(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if hash != connector.GetAnnotations()[connect.AnnotationSpecHash] {
+		return fmt.Errorf("Connector %s was modified outside of the operator", connector.GetName()), nil
+	}
+
+	return nil, nil
+}
+
 /*
  * Should be called when a refreshed pipeline failed to become valid.
  * This method will either keep the old invalid table "active" (i.e. used by the view) or update the view to the new (also invalid) table.
@@ -466,24 +1131,22 @@ func (i *ReconcileIteration) updateViewIfHealthier() error {
 		}
 
 		// no need to close this as that's done in ReconcileIteration.Close()
-		i.InventoryDb = database.NewBaseDatabase(&i.HBIDBParams, i.Log)
-
-		if err = i.InventoryDb.Connect(); err != nil {
+		if err = i.connectInventory(); err != nil {
 			return err
 		}
 
-		hbiHostCount, err := i.InventoryDb.CountHosts(inventoryTableName, i.Instance.Spec.InsightsOnly, i.Instance.Spec.AdditionalFilters)
+		hbiHostCount, err := i.countHbiHosts()
 		if err != nil {
 			return fmt.Errorf("Failed to get host count from inventory %w", err)
 		}
 
-		activeTable := utils.AppFullTableName(*table)
+		activeTable := database.AppTable(*table)
 		activeTableHostCount, err := i.AppDb.CountHosts(activeTable, false, []map[string]string{})
 		if err != nil {
 			return fmt.Errorf("Failed to get host count from active table %w", err)
 		}
 
-		appTable := utils.AppFullTableName(i.Instance.Status.TableName)
+		appTable := database.AppTable(i.Instance.Status.TableName)
 		latestTableHostCount, err := i.AppDb.CountHosts(appTable, false, []map[string]string{})
 		if err != nil {
 			return fmt.Errorf("Failed to get host count from application table %w", err)
@@ -494,13 +1157,69 @@ func (i *ReconcileIteration) updateViewIfHealthier() error {
 		}
 	}
 
+	if waiting, err := i.waitForDependencies(); err != nil || waiting {
+		return err
+	}
+
+	if err = i.prepareTableForView(); err != nil {
+		return err
+	}
+
 	if err = i.AppDb.UpdateView(i.Instance.Status.TableName); err != nil {
 		return err
 	}
 
+	if err = i.applyDBGrants(i.viewName()); err != nil {
+		return err
+	}
+
+	if rolledBack, err := i.verifyViewOrRollback(table); err != nil || rolledBack {
+		return err
+	}
+
+	i.recordCutover(table)
+	i.probeViewReplaced(table, "refreshed table is closer to the inventory than the active one")
 	return nil
 }
 
+/*
+ * Returns the version of a new pipeline version, which determines the names of its table and connector, and increments the sync generation.
+ * By default the version is 1_<timestamp>. With a naming template the version is the rendered table name (without the hosts_ prefix).
+ * The generation is increased further until the rendered name is not used by an existing table (e.g. of a re-created pipeline).
+ */
+func (i *ReconcileIteration) newPipelineVersion() (string, error) {
+	i.Instance.Status.SyncGeneration++
+
+	if i.config.NamingTemplate == "" {
+		return fmt.Sprintf("1_%s", strconv.FormatInt(time.Now().UnixNano(), 10)), nil
+	}
+
+	tables, err := i.AppDb.GetCyndiTables()
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < maxNamingAttempts; attempt++ {
+		table, err := utils.RenderTableName(i.config.NamingTemplate, utils.NamingData{
+			PipelineName: i.Instance.Name,
+			AppName:      i.Instance.Spec.AppName,
+			Namespace:    i.Instance.Namespace,
+			Generation:   i.Instance.Status.SyncGeneration,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		if !utils.ContainsString(tables, table) {
+			return cyndi.TableNameToPipelineVersion(table), nil
+		}
+
+		i.Instance.Status.SyncGeneration++
+	}
+
+	return "", fmt.Errorf("Naming template did not render an unused table name in %d attempts", maxNamingAttempts)
+}
+
 func NewCyndiReconciler(client client.Client, clientset *kubernetes.Clientset, scheme *runtime.Scheme, log logr.Logger, recorder record.EventRecorder) *CyndiPipelineReconciler {
 	return &CyndiPipelineReconciler{
 		Client:    client,
@@ -20,19 +20,24 @@ import (
 	"context"
 	"fmt"
 	logr "github.com/go-logr/logr/testing"
+	"strconv"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/spf13/viper"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
 
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
@@ -40,7 +45,9 @@ import (
 	. "github.com/RedHatInsights/cyndi-operator/controllers/config"
 	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
 	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/faults"
 	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+	cynditesting "github.com/RedHatInsights/cyndi-operator/pkg/testing"
 	"github.com/RedHatInsights/cyndi-operator/test"
 	// +kubebuilder:scaffold:imports
 )
@@ -50,84 +57,29 @@ import (
  */
 
 func getDBParams() DBParams {
-	options := viper.New()
-	options.SetDefault("DBHostHBI", "localhost")
-	options.SetDefault("DBPort", "5432")
-	options.SetDefault("DBUser", "postgres")
-	options.SetDefault("DBPass", "postgres")
-	options.SetDefault("DBName", "test")
-	options.AutomaticEnv()
-
-	return DBParams{
-		Host:     options.GetString("DBHostHBI"),
-		Port:     options.GetString("DBPort"),
-		Name:     options.GetString("DBName"),
-		User:     options.GetString("DBUser"),
-		Password: options.GetString("DBPass"),
-	}
+	return cynditesting.DBParamsFromEnv()
 }
 
 func createPipeline(namespacedName types.NamespacedName, specs ...*cyndi.CyndiPipelineSpec) {
-	var (
-		ctx  = context.Background()
-		spec *cyndi.CyndiPipelineSpec
-	)
-
-	Expect(len(specs) <= 1).To(BeTrue())
-
-	if len(specs) == 1 {
-		spec = specs[0]
-	} else {
-		spec = &cyndi.CyndiPipelineSpec{}
-	}
-
-	spec.AppName = namespacedName.Name
-
-	pipeline := cyndi.CyndiPipeline{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      namespacedName.Name,
-			Namespace: namespacedName.Namespace,
-		},
-		Spec: *spec,
-	}
-
-	err := test.Client.Create(ctx, &pipeline)
+	err := cynditesting.CreatePipeline(test.Client, namespacedName, specs...)
 	Expect(err).ToNot(HaveOccurred())
 }
 
 func createDbSecret(namespace string, name string, params DBParams) {
-	secret := &corev1.Secret{
-		Type: corev1.SecretTypeOpaque,
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-		},
-		Data: map[string][]byte{
-			"db.host":     []byte(params.Host),
-			"db.port":     []byte(params.Port),
-			"db.name":     []byte(params.Name),
-			"db.user":     []byte(params.User),
-			"db.password": []byte(params.Password),
-		},
-	}
-
-	err := test.Client.Create(context.TODO(), secret)
+	err := cynditesting.CreateDbSecret(test.Client, namespace, name, params)
 	Expect(err).ToNot(HaveOccurred())
 }
 
 func createConfigMap(namespace string, name string, data map[string]string) {
-	configMap := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-		},
-		Data: data,
-	}
-
-	err := test.Client.Create(context.TODO(), configMap)
+	err := cynditesting.CreateConfigMap(test.Client, namespace, name, data)
 	Expect(err).ToNot(HaveOccurred())
 }
 
+// inventory.hosts as created by the Cyndi setup that predates the operator
+const legacyHostsColumns = `id uuid PRIMARY KEY, account character varying(10), org_id character varying(36), display_name character varying(200) NOT NULL,
+	tags jsonb NOT NULL, updated timestamp with time zone NOT NULL, created timestamp with time zone NOT NULL, stale_timestamp timestamp with time zone NOT NULL,
+	system_profile jsonb NOT NULL, insights_id uuid, reporter character varying(255) NOT NULL, per_reporter_staleness jsonb NOT NULL, groups jsonb`
+
 func newCyndiReconciler() *CyndiPipelineReconciler {
 	return NewCyndiReconciler(test.Client, test.Clientset, scheme.Scheme, logf.Log.WithName("test"), record.NewFakeRecorder(10))
 }
@@ -163,6 +115,19 @@ func setPipelineValid(namespacedName types.NamespacedName, valid bool, fns ...fu
 	Expect(err).ToNot(HaveOccurred())
 }
 
+// Drains the events recorded so far
+func recordedEvents(recorder record.EventRecorder) (events []string) {
+	fake, _ := recorder.(*record.FakeRecorder)
+	for {
+		select {
+		case event := <-fake.Events:
+			events = append(events, event)
+		default:
+			return
+		}
+	}
+}
+
 func TestControllers(t *testing.T) {
 	test.Setup(t, "Controllers")
 }
@@ -182,6 +147,26 @@ var _ = Describe("Pipeline operations", func() {
 		return
 	}
 
+	// failures are not returned to the controller but recorded in the Throttled condition instead
+	var reconcileFailing = func() (result ctrl.Result, condition *metav1.Condition) {
+		result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: namespacedName})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+		condition = getPipeline(namespacedName).GetThrottled()
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		return
+	}
+
+	// makes the next reconcile attempt of a throttled pipeline go through
+	var expireThrottling = func() {
+		pipeline := getPipeline(namespacedName)
+		past := metav1.NewTime(time.Now().Add(-time.Second))
+		pipeline.Status.ThrottledUntil = &past
+		Expect(test.Client.Status().Update(context.TODO(), pipeline)).To(Succeed())
+	}
+
 	BeforeEach(func() {
 		namespacedName = types.NamespacedName{
 			Name:      "test-pipeline-01",
@@ -220,7 +205,7 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(pipeline.GetValid()).To(Equal(metav1.ConditionUnknown))
 			Expect(pipeline.Status.ActiveTableName).To(Equal(""))
 
-			connector, err := connect.GetConnector(test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(connector.GetLabels()["cyndi/appName"]).To(Equal(namespacedName.Name))
 			Expect(connector.GetLabels()["cyndi/insightsOnly"]).To(Equal("false"))
@@ -231,13 +216,190 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(exists).To(BeTrue())
 		})
 
+		It("Bootstraps the application database", func() {
+			_, err := db.Exec(`DROP SCHEMA "inventory" CASCADE`)
+			Expect(err).ToNot(HaveOccurred())
+
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"db.bootstrap.enabled": "true"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+
+			exists, err := db.CheckIfTableExists(pipeline.Status.TableName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("Exposes the active configuration", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{
+				"connector.topic":                 "platform.inventory.events",
+				"validation.percentage.threshold": "7",
+				"init.validation.count.threshold": "100",
+			})
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.Status.ActiveConfig).ToNot(BeNil())
+			Expect(pipeline.Status.ActiveConfig.ConfigMapVersion).To(Equal(pipeline.Status.CyndiConfigVersion))
+			Expect(pipeline.Status.ActiveConfig.Topics).To(Equal([]string{"platform.inventory.events"}))
+			Expect(pipeline.Status.ActiveConfig.ConnectCluster).To(Equal("xjoin-kafka-connect-strimzi"))
+			Expect(pipeline.Status.ActiveConfig.ConnectorTemplateHash).ToNot(BeEmpty())
+			Expect(pipeline.Status.ActiveConfig.ViewColumns).To(ContainElement("culled_timestamp"))
+			Expect(pipeline.Status.ActiveConfig.Validation.PercentageThreshold).To(Equal(int64(7)))
+			Expect(pipeline.Status.ActiveConfig.InitValidation.CountThreshold).To(Equal(int64(100)))
+		})
+
+		It("Names tables and connectors using the naming template", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"naming.template": "hosts_{{.PipelineName}}_{{.Generation}}"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.Status.SyncGeneration).To(Equal(int64(1)))
+			Expect(pipeline.Status.TableName).To(Equal(utils.SanitizeTableName(namespacedName.Name + "_1")))
+			Expect(pipeline.Status.ConnectorName).To(Equal(cyndi.TableNameToConnectorName(pipeline.Status.TableName, pipeline.Spec.AppName)))
+
+			_, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			exists, err := db.CheckIfTableExists(pipeline.Status.TableName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("Names the consumer group of the connector using the consumer group template", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{
+				"connector.consumer.group.template": "cyndi-{{.Namespace}}-{{.PipelineVersion}}",
+				"connector.auto.offset.reset":       "none",
+			})
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.Status.ConsumerGroup).To(Equal("cyndi-" + namespacedName.Namespace + "-" + pipeline.Status.PipelineVersion))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			connectorConfig, _, err := unstructured.NestedMap(connector.UnstructuredContent(), "spec", "config")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connectorConfig).To(HaveKeyWithValue("consumer.override.group.id", pipeline.Status.ConsumerGroup))
+			Expect(connectorConfig).To(HaveKeyWithValue("consumer.override.auto.offset.reset", "none"))
+		})
+
+		It("Does not create a connector or table in read-only mode", func() {
+			createPipeline(namespacedName)
+			r.ReadOnly = true
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+
+			tables, err := db.GetCyndiTables()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tables).To(BeEmpty())
+		})
+
+		It("Adopts the table and connector of a re-created pipeline", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"state.export.enabled": "true"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			tableName := pipeline.Status.TableName
+			Expect(pipeline.Status.ActiveTableName).To(Equal(tableName))
+
+			state, err := utils.FetchConfigMap(test.Client, namespacedName.Namespace, namespacedName.Name+"-state")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state.Data["tableName"]).To(Equal(tableName))
+
+			// the pipeline disappears without its finalizer running
+			pipeline.SetFinalizers(nil)
+			Expect(test.Client.Update(context.TODO(), pipeline)).To(Succeed())
+			Expect(test.Client.Delete(context.TODO(), pipeline)).To(Succeed())
+
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{AdoptExisting: true})
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.Status.TableName).To(Equal(tableName))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetLabels()[connect.LabelOwner]).To(Equal(pipeline.GetUIDString()))
+			Expect(connector.GetOwnerReferences()).To(HaveLen(1))
+			Expect(connector.GetOwnerReferences()[0].UID).To(Equal(pipeline.GetUID()))
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.Status.TableName).To(Equal(tableName))
+		})
+
+		It("Adopts the hosts table of the pre-operator setup", func() {
+			_, err := db.Exec(fmt.Sprintf("CREATE TABLE inventory.hosts (%s)", legacyHostsColumns))
+			Expect(err).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{AdoptExisting: true})
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.Status.ActiveTableName).To(Equal(pipeline.Status.TableName))
+
+			legacy, err := db.IsLegacyHostsTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(legacy).To(BeFalse())
+
+			_, err = connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Fails to adopt a hosts table lacking columns", func() {
+			_, err := db.Exec("CREATE TABLE inventory.hosts (id uuid PRIMARY KEY, account character varying(10))")
+			Expect(err).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{AdoptExisting: true})
+			_, condition := reconcileFailing()
+			Expect(condition.Message).To(ContainSubstring("cannot be adopted"))
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+		})
+
+		It("Creates a partitioned table", func() {
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{
+				DBTablePartitioning: &cyndi.TablePartitioning{Strategy: cyndi.PartitioningHash, Partitions: 8},
+			})
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+
+			exists, err := db.CheckIfTableExists(database.PartitionName(pipeline.Status.TableName, 7))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+
+			tables, err := db.GetCyndiTables()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tables).To(Equal([]string{pipeline.Status.TableName}))
+		})
+
 		It("Considers configmap configuration", func() {
 			createPipeline(namespacedName)
 			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"connect.cluster": "test01"})
 			reconcile()
 
 			pipeline := getPipeline(namespacedName)
-			connector, err := connect.GetConnector(test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(connector.GetLabels()["cyndi/appName"]).To(Equal(namespacedName.Name))
 			Expect(connector.GetLabels()["cyndi/insightsOnly"]).To(Equal("false"))
@@ -300,7 +462,7 @@ var _ = Describe("Pipeline operations", func() {
 			reconcile()
 
 			pipeline = getPipeline(namespacedName)
-			connectors, err := connect.GetConnectorsForOwner(test.Client, namespacedName.Namespace, pipeline.GetUIDString())
+			connectors, err := connect.GetConnectorsForOwner(context.TODO(), test.Client, namespacedName.Namespace, pipeline.GetUIDString())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(connectors.Items).To(HaveLen(1))
 			Expect(connectors.Items[0].GetName()).To(Equal(pipeline.Status.ConnectorName))
@@ -331,109 +493,103 @@ var _ = Describe("Pipeline operations", func() {
 		})
 	})
 
-	Describe("InitialSync -> Valid", func() {
-		It("Creates the hosts view", func() {
+	Describe("Unlogged tables", func() {
+		var isUnlogged = func(table string) bool {
+			rows, err := db.RunQuery(fmt.Sprintf("SELECT relpersistence = 'u' FROM pg_catalog.pg_class WHERE oid = 'inventory.%s'::regclass", table))
+			Expect(err).ToNot(HaveOccurred())
+			defer rows.Close()
+
+			var unlogged bool
+			Expect(rows.Next()).To(BeTrue())
+			Expect(rows.Scan(&unlogged)).To(Succeed())
+			return unlogged
+		}
+
+		BeforeEach(func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"db.table.unlogged": "true"})
+		})
+
+		It("Converts the table to LOGGED before switching the view", func() {
 			createPipeline(namespacedName)
 			reconcile()
 
 			pipeline := getPipeline(namespacedName)
+			Expect(isUnlogged(pipeline.Status.TableName)).To(BeTrue())
+			Expect(pipeline.Status.UnloggedTableServerStart).ToNot(BeNil())
+
 			setPipelineValid(namespacedName, true)
 			reconcile()
 
 			pipeline = getPipeline(namespacedName)
-			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
-			Expect(pipeline.Status.InitialSyncInProgress).To(BeFalse())
-			Expect(pipeline.Status.ActiveTableName).ToNot(Equal(""))
-
-			viewExists, err := db.CheckIfTableExists("hosts")
-			Expect(err).ToNot(HaveOccurred())
-			Expect(viewExists).To(BeTrue())
+			Expect(pipeline.Status.ActiveTableName).To(Equal(pipeline.Status.TableName))
+			Expect(isUnlogged(pipeline.Status.TableName)).To(BeFalse())
+			Expect(pipeline.Status.UnloggedTableServerStart).To(BeNil())
 		})
 
-		It("Triggers refresh if pipeline fails to become valid for too long", func() {
+		It("Restarts the initial sync if the database restarted", func() {
 			createPipeline(namespacedName)
 			reconcile()
 
-			setPipelineValid(namespacedName, false, func(pipeline *cyndi.CyndiPipeline) {
-				pipeline.Status.ValidationFailedCount = 31
-			})
+			pipeline := getPipeline(namespacedName)
+			restarted := metav1.NewTime(pipeline.Status.UnloggedTableServerStart.Add(-time.Hour))
+			pipeline.Status.UnloggedTableServerStart = &restarted
+			Expect(test.Client.Status().Update(context.TODO(), pipeline)).To(Succeed())
 
 			reconcile()
 
-			pipeline := getPipeline(namespacedName)
+			pipeline = getPipeline(namespacedName)
 			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
-			Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
-			Expect(pipeline.Status.PipelineVersion).To(Equal(""))
 		})
 	})
 
-	Describe("Invalid -> New", func() {
-		It("Triggers refresh if pipeline in invalid for too long", func() {
+	Describe("InitialSync -> Valid", func() {
+		It("Creates indexes once the initial sync completes", func() {
+			var countIndexes = func(table string) (count int64) {
+				rows, err := db.RunQuery(fmt.Sprintf("SELECT count(*) FROM pg_indexes WHERE schemaname = 'inventory' AND tablename = '%s'", table))
+				Expect(err).ToNot(HaveOccurred())
+				defer rows.Close()
+
+				Expect(rows.Next()).To(BeTrue())
+				Expect(rows.Scan(&count)).To(Succeed())
+				return
+			}
+
 			createPipeline(namespacedName)
 			reconcile()
 
 			pipeline := getPipeline(namespacedName)
-			setPipelineValid(namespacedName, true)
-			reconcile()
-
-			setPipelineValid(namespacedName, false, func(pipeline *cyndi.CyndiPipeline) {
-				pipeline.Status.ValidationFailedCount = 6
-			})
+			Expect(pipeline.Status.TableIndexesPending).To(BeTrue())
+			// primary key only
+			Expect(countIndexes(pipeline.Status.TableName)).To(Equal(int64(1)))
 
+			setPipelineValid(namespacedName, true)
 			reconcile()
 
 			pipeline = getPipeline(namespacedName)
-			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
-			Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
-			Expect(pipeline.Status.PipelineVersion).To(Equal(""))
+			Expect(pipeline.Status.TableIndexesPending).To(BeFalse())
+			Expect(countIndexes(pipeline.Status.TableName)).To(BeNumerically(">", 1))
 		})
 
-		Context("In a refresh", func() {
-			It("Keeps the old table active until the new one is valid", func() {
-				createPipeline(namespacedName)
-				reconcile()
-
-				pipeline := getPipeline(namespacedName)
-				setPipelineValid(namespacedName, true)
-				reconcile()
-
-				pipeline = getPipeline(namespacedName)
-				activeTableName := pipeline.Status.ActiveTableName
-				setPipelineValid(namespacedName, false, func(pipeline *cyndi.CyndiPipeline) {
-					pipeline.Status.ValidationFailedCount = 6
-				})
-
-				reconcile()
-
-				pipeline = getPipeline(namespacedName)
-				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
-				Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
-				Expect(pipeline.Status.PipelineVersion).To(Equal(""))
-				Expect(pipeline.Status.ActiveTableName).To(Equal(activeTableName))
-				reconcile()
-
-				pipeline = getPipeline(namespacedName)
-				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
-				// while the new table is being seeded, the old one should still remain active (backing the hosts view)
-				Expect(pipeline.Status.ActiveTableName).To(Equal(activeTableName))
+		It("Creates the hosts view", func() {
+			createPipeline(namespacedName)
+			reconcile()
 
-				table, err := db.GetCurrentTable()
-				Expect(err).ToNot(HaveOccurred())
-				Expect(*table).To(Equal(pipeline.Status.ActiveTableName))
+			pipeline := getPipeline(namespacedName)
+			setPipelineValid(namespacedName, true)
+			reconcile()
 
-				setPipelineValid(namespacedName, true)
-				reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.Status.InitialSyncInProgress).To(BeFalse())
+			Expect(pipeline.Status.ActiveTableName).ToNot(Equal(""))
 
-				// at this point the new table should be active
-				pipeline = getPipeline(namespacedName)
-				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
-				Expect(pipeline.Status.ActiveTableName).ToNot(Equal(activeTableName))
-			})
+			viewExists, err := db.CheckIfTableExists("hosts")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(viewExists).To(BeTrue())
 		})
-	})
 
-	Describe("Valid -> New", func() {
-		It("Triggers refresh if configmap is created", func() {
+		It("Smoke-tests the hosts view once it is switched", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"smoketest.enabled": "true"})
 			createPipeline(namespacedName)
 			reconcile()
 
@@ -441,39 +597,641 @@ var _ = Describe("Pipeline operations", func() {
 			reconcile()
 
 			pipeline := getPipeline(namespacedName)
-			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
-			pipelineVersion := pipeline.Status.PipelineVersion
-			tableName := pipeline.Status.TableName
-			Expect(pipeline.Status.CyndiConfigVersion).To(Equal("-1"))
-
-			// now add a new configmap
-			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"connect.cluster": "override"})
+			Expect(pipeline.Status.ActiveTableName).To(Equal(pipeline.Status.TableName))
+			Expect(pipeline.Status.SmokeTest).ToNot(BeNil())
+			Expect(pipeline.Status.SmokeTest.Result).To(Equal(cyndi.SmokeTestPending))
+			Expect(pipeline.Status.SmokeTest.TableName).To(Equal(pipeline.Status.TableName))
+
+			job := &batchv1.Job{}
+			Expect(test.Client.Get(context.TODO(), client.ObjectKey{Namespace: namespacedName.Namespace, Name: pipeline.Status.SmokeTest.JobName}, job)).To(Succeed())
+			Expect(job.Name).To(Equal(smokeTestJobName(pipeline.Name, pipeline.Status.PipelineVersion)))
+			Expect(job.Labels).To(HaveKeyWithValue(labelSmokeTest, pipeline.Name))
+			Expect(job.Spec.Template.Spec.Containers[0].Args).To(ContainElement(ContainSubstring(`FROM "inventory"."hosts"`)))
+
+			job.Status.Succeeded = 1
+			Expect(test.Client.Status().Update(context.TODO(), job)).To(Succeed())
 			reconcile()
 
 			pipeline = getPipeline(namespacedName)
-			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
-			Expect(pipeline.Status.InitialSyncInProgress).To(BeFalse())
-			Expect(pipeline.GetValid()).To(Equal(metav1.ConditionUnknown))
-			Expect(pipeline.Status.PipelineVersion).ToNot(Equal(pipelineVersion))
-
-			// ensure the view still points to the old table while the new one is being synchronized
-			table, err := db.GetCurrentTable()
-			Expect(err).ToNot(HaveOccurred())
-			Expect(*table).To(Equal(tableName))
-			Expect(*table).To(Equal(pipeline.Status.ActiveTableName))
+			Expect(pipeline.Status.SmokeTest.Result).To(Equal(cyndi.SmokeTestSucceeded))
+			Expect(pipeline.Status.SmokeTest.CompletedAt).ToNot(BeNil())
+			Expect(recordedEvents(r.Recorder)).To(ContainElement(HavePrefix("Normal SmokeTestSucceeded")))
 		})
 
-		It("Triggers refresh if configmap changes", func() {
-			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"connect.cluster": "value1"})
-
-			createPipeline(namespacedName)
+		It("Switches the view once the pipelines it depends on are valid", func() {
+			dependency := types.NamespacedName{Namespace: namespacedName.Namespace, Name: "dependency"}
+			createPipeline(dependency)
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{DependsOn: []cyndi.PipelineReference{{Name: dependency.Name}}})
 			reconcile()
 
 			setPipelineValid(namespacedName, true)
 			reconcile()
 
 			pipeline := getPipeline(namespacedName)
-			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.GetDependenciesReady().Status).To(Equal(metav1.ConditionFalse))
+			Expect(pipeline.GetDependenciesReady().Message).To(ContainSubstring(dependency.String()))
+
+			viewExists, err := db.CheckIfTableExists("hosts")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(viewExists).To(BeFalse())
+
+			Expect(r.pipelinesDependingOn(pipeline)).To(BeEmpty())
+			dependencyPipeline := getPipeline(dependency)
+			Expect(r.pipelinesDependingOn(dependencyPipeline)).To(Equal([]ctrl.Request{{NamespacedName: namespacedName}}))
+
+			setPipelineValid(dependency, true)
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetDependenciesReady().Status).To(Equal(metav1.ConditionTrue))
+			Expect(pipeline.Status.ActiveTableName).To(Equal(pipeline.Status.TableName))
+		})
+
+		It("Exposes the hosts of a clone using a view of its own", func() {
+			createPipeline(namespacedName)
+			reconcile()
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			// the clone inherits the application database secret of its source pipeline
+			clone := types.NamespacedName{Namespace: namespacedName.Namespace, Name: "clone"}
+			createPipeline(clone, &cyndi.CyndiPipelineSpec{SourcePipeline: &cyndi.SourcePipeline{Name: namespacedName.Name}})
+
+			var reconcileClone = func() {
+				_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: clone})
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			reconcileClone()
+			setPipelineValid(clone, true)
+			reconcileClone()
+
+			clonePipeline := getPipeline(clone)
+			Expect(clonePipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(clonePipeline.Spec.DbSecret).To(BeNil())
+
+			db.SetView("clone_hosts")
+			table, err := db.GetCurrentTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*table).To(Equal(clonePipeline.Status.TableName))
+
+			db.SetView(database.DefaultView)
+			table, err = db.GetCurrentTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*table).To(Equal(getPipeline(namespacedName).Status.ActiveTableName))
+		})
+
+		It("Reports the outcome of each reconcile step", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			for _, step := range []cyndi.ReconcileStep{cyndi.STEP_SECRETS, cyndi.STEP_TABLE, cyndi.STEP_CONNECTOR} {
+				Expect(pipeline.GetStepReady(step).Status).To(Equal(metav1.ConditionTrue))
+			}
+			Expect(pipeline.GetStepReady(cyndi.STEP_VIEW)).To(BeNil())
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetStepReady(cyndi.STEP_VIEW).Status).To(Equal(metav1.ConditionTrue))
+		})
+
+		It("Records the generation the conditions were determined for", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.Status.ObservedGeneration).To(Equal(pipeline.Generation))
+			for _, condition := range pipeline.Status.Conditions {
+				Expect(condition.ObservedGeneration).To(Equal(pipeline.Generation))
+			}
+
+			// applied in place, i.e. the conditions still hold once reconciled
+			threshold := int64(10)
+			pipeline.Spec.ValidationThreshold = &threshold
+			Expect(test.Client.Update(context.TODO(), pipeline)).To(Succeed())
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.Generation).To(Equal(int64(2)))
+			Expect(pipeline.Status.ObservedGeneration).To(Equal(pipeline.Generation))
+			Expect(pipeline.GetValidCondition().ObservedGeneration).To(Equal(pipeline.Generation))
+		})
+
+		It("Adds the staleness column to the view without a refresh", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline, err := utils.FetchCyndiPipeline(test.Client, namespacedName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			tableName := pipeline.Status.TableName
+
+			pipeline.Spec.ViewStaleness = &cyndi.ViewStaleness{}
+			Expect(test.Client.Update(context.TODO(), pipeline)).To(Succeed())
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.Status.TableName).To(Equal(tableName))
+			Expect(pipeline.Status.ViewStalenessHash).ToNot(BeEmpty())
+
+			rows, err := db.RunQuery("SELECT staleness FROM inventory.hosts")
+			Expect(err).ToNot(HaveOccurred())
+			rows.Close()
+		})
+
+		It("Triggers refresh if pipeline fails to become valid for too long", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, false, func(pipeline *cyndi.CyndiPipeline) {
+				pipeline.Status.ValidationFailedCount = 31
+			})
+
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+			Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
+			Expect(pipeline.Status.PipelineVersion).To(Equal(""))
+		})
+	})
+
+	Describe("Stuck initial sync", func() {
+		// the host count of the table being seeded last grew two hours ago
+		var stallInitialSync = func() {
+			pipeline := getPipeline(namespacedName)
+			lastProgress := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			pipeline.Status.InitialSyncLastProgress = &lastProgress
+			Expect(test.Client.Status().Update(context.TODO(), pipeline)).To(Succeed())
+		}
+
+		It("Marks a stuck initial sync as Degraded", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"init.stuck.timeout": "600"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			stallInitialSync()
+			recordedEvents(r.Recorder)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.GetDegraded()).ToNot(BeNil())
+			Expect(pipeline.GetDegraded().Status).To(Equal(metav1.ConditionTrue))
+			Expect(pipeline.GetDegraded().Reason).To(Equal(reasonInitialSyncStuck))
+			Expect(recordedEvents(r.Recorder)).To(ContainElement(HavePrefix("Warning InitialSyncStuck")))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetAnnotations()).ToNot(HaveKey("strimzi.io/restart"))
+		})
+
+		It("Leaves an initial sync that makes progress alone", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"init.stuck.timeout": "600", "init.stuck.action": "refresh"})
+			createPipeline(namespacedName)
+			reconcile()
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.GetDegraded()).To(BeNil())
+		})
+
+		It("Restarts the connector of a stuck initial sync", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"init.stuck.timeout": "600", "init.stuck.action": "restartConnector"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			stallInitialSync()
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.GetDegraded().Reason).To(Equal(reasonInitialSyncStuck))
+			// the restarted connector gets another full timeout
+			Expect(pipeline.Status.InitialSyncLastProgress.Time).To(BeTemporally(">", time.Now().Add(-time.Minute)))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetAnnotations()).To(HaveKeyWithValue("strimzi.io/restart", "true"))
+		})
+
+		It("Refreshes a stuck initial sync", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"init.stuck.timeout": "600", "init.stuck.action": "refresh"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipelineVersion := getPipeline(namespacedName).Status.PipelineVersion
+			stallInitialSync()
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+			Expect(pipeline.Status.LastRefreshReason).To(Equal("Initial sync is stuck"))
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.Status.PipelineVersion).ToNot(Equal(pipelineVersion))
+		})
+	})
+
+	Describe("Initial sync deadline", func() {
+		// the initial sync started two hours ago
+		var exceedDeadline = func() {
+			pipeline := getPipeline(namespacedName)
+			started := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			pipeline.Status.InitialSyncStarted = &started
+			Expect(test.Client.Status().Update(context.TODO(), pipeline)).To(Succeed())
+		}
+
+		It("Marks an initial sync exceeding its deadline once", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"init.timeout": "600"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			exceedDeadline()
+			recordedEvents(r.Recorder)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.GetSyncDeadlineExceeded()).ToNot(BeNil())
+			Expect(pipeline.GetSyncDeadlineExceeded().Status).To(Equal(metav1.ConditionTrue))
+			Expect(pipeline.GetSyncDeadlineExceeded().Reason).To(Equal(reasonSyncDeadlineExceeded))
+			Expect(recordedEvents(r.Recorder)).To(ContainElement(HavePrefix("Warning SyncDeadlineExceeded")))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetAnnotations()).ToNot(HaveKey("strimzi.io/restart"))
+
+			reconcile()
+			Expect(getPipeline(namespacedName).GetSyncDeadlineExceeded().Reason).To(Equal(reasonSyncDeadlineExceeded))
+			Expect(recordedEvents(r.Recorder)).ToNot(ContainElement(HavePrefix("Warning SyncDeadlineExceeded")))
+		})
+
+		It("Restarts the connector of an initial sync exceeding its deadline once", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"init.timeout": "600", "init.timeout.action": "restartConnector"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			exceedDeadline()
+			recordedEvents(r.Recorder)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.GetSyncDeadlineExceeded().Reason).To(Equal(reasonSyncDeadlineExceeded))
+			Expect(recordedEvents(r.Recorder)).To(ContainElements(HavePrefix("Normal RestartingConnector"), HavePrefix("Warning SyncDeadlineExceeded")))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetAnnotations()).To(HaveKeyWithValue("strimzi.io/restart", "true"))
+
+			reconcile()
+			Expect(recordedEvents(r.Recorder)).ToNot(ContainElement(HavePrefix("Normal RestartingConnector")))
+		})
+
+		It("Does not abort the first initial sync of a pipeline", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"init.timeout": "600", "init.timeout.action": "abort"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipelineVersion := getPipeline(namespacedName).Status.PipelineVersion
+			exceedDeadline()
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.Status.PipelineVersion).To(Equal(pipelineVersion))
+			Expect(pipeline.GetSyncDeadlineExceeded().Reason).To(Equal(reasonSyncDeadlineExceeded))
+		})
+
+		// Refreshes a valid pipeline for a changed connector configuration and lets the refresh exceed its deadline
+		var abortRefresh = func() (validVersion string, validTable string, abandonedTable string) {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"init.timeout": "600", "init.timeout.action": "abort"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.Status.ActiveTableName).To(Equal(pipeline.Status.TableName))
+			validVersion, validTable = pipeline.Status.PipelineVersion, pipeline.Status.TableName
+
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["connector.batch.size"] = "50"
+			Expect(test.Client.Update(context.TODO(), configMap)).To(Succeed())
+			reconcile()
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			abandonedTable = pipeline.Status.TableName
+			Expect(abandonedTable).ToNot(Equal(validTable))
+
+			exceedDeadline()
+			recordedEvents(r.Recorder)
+			reconcile()
+			return
+		}
+
+		It("Falls back to the table backing the view once without refreshing it again", func() {
+			validVersion, validTable, abandonedTable := abortRefresh()
+
+			// the version backing the view becomes current and valid again without being validated from scratch
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.Status.PipelineVersion).To(Equal(validVersion))
+			Expect(pipeline.Status.TableName).To(Equal(validTable))
+			Expect(pipeline.Status.InitialSyncStarted).To(BeNil())
+			Expect(pipeline.GetSyncDeadlineExceeded().Reason).To(Equal(reasonRefreshAborted))
+			Expect(pipeline.Status.LastRefreshReason).To(Equal("Refresh aborted as the initial sync exceeded its deadline"))
+			Expect(recordedEvents(r.Recorder)).To(ContainElements(HavePrefix("Warning SyncDeadlineExceeded"), HavePrefix("Warning RefreshAborted")))
+
+			// the connector configuration the refresh was started for does not trigger another refresh
+			reconcile()
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.Status.PipelineVersion).To(Equal(validVersion))
+			Expect(pipeline.GetSyncDeadlineExceeded().Reason).To(Equal(reasonRefreshAborted))
+			Expect(recordedEvents(r.Recorder)).ToNot(ContainElement(HavePrefix("Warning RefreshAborted")))
+
+			exists, err := db.CheckIfTableExists(abandonedTable)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+
+			// another change of the spec does
+			pipeline.Spec.DBTableIndexSQL = "update test again"
+			Expect(test.Client.Update(context.Background(), pipeline)).To(Succeed())
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+			Expect(pipeline.GetSyncDeadlineExceeded()).To(BeNil())
+		})
+
+		It("Refreshes a pipeline whose connector is modified after its refresh was aborted", func() {
+			validVersion, _, _ := abortRefresh()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.Status.PipelineVersion).To(Equal(validVersion))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unstructured.SetNestedField(connector.Object, "100", "spec", "config", "batch.size")).To(Succeed())
+			Expect(test.Client.Update(context.TODO(), connector)).To(Succeed())
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+			Expect(pipeline.Status.LastRefreshReason).To(ContainSubstring("was modified outside of the operator"))
+		})
+	})
+
+	Describe("Invalid -> New", func() {
+		It("Triggers refresh if pipeline in invalid for too long", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			setPipelineValid(namespacedName, false, func(pipeline *cyndi.CyndiPipeline) {
+				pipeline.Status.ValidationFailedCount = 6
+			})
+
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+			Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
+			Expect(pipeline.Status.PipelineVersion).To(Equal(""))
+		})
+
+		Context("In a refresh", func() {
+			It("Keeps the old table active until the new one is valid", func() {
+				createPipeline(namespacedName)
+				reconcile()
+
+				pipeline := getPipeline(namespacedName)
+				setPipelineValid(namespacedName, true)
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				activeTableName := pipeline.Status.ActiveTableName
+				setPipelineValid(namespacedName, false, func(pipeline *cyndi.CyndiPipeline) {
+					pipeline.Status.ValidationFailedCount = 6
+				})
+
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+				Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
+				Expect(pipeline.Status.PipelineVersion).To(Equal(""))
+				Expect(pipeline.Status.ActiveTableName).To(Equal(activeTableName))
+				Expect(pipeline.Status.LastRefreshReason).To(Equal("Pipeline failed to become valid within the given threshold"))
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+				// while the new table is being seeded, the old one should still remain active (backing the hosts view)
+				Expect(pipeline.Status.ActiveTableName).To(Equal(activeTableName))
+
+				table, err := db.GetCurrentTable()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(*table).To(Equal(pipeline.Status.ActiveTableName))
+
+				setPipelineValid(namespacedName, true)
+				reconcile()
+
+				// at this point the new table should be active
+				pipeline = getPipeline(namespacedName)
+				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+				Expect(pipeline.Status.ActiveTableName).ToNot(Equal(activeTableName))
+			})
+
+			It("Refuses to switch the view to a much smaller table", func() {
+				createPipeline(namespacedName)
+				reconcile()
+
+				setPipelineValid(namespacedName, true)
+				reconcile()
+
+				pipeline := getPipeline(namespacedName)
+				activeTableName := pipeline.Status.ActiveTableName
+				seedTable(db, database.AppTable(activeTableName), false,
+					"3ed2bee8-9d88-4e85-8b4f-d4e0ee5ddb3a", "1c9a6c6e-a5b6-4bb7-b36e-1e0a8ae6e2b6", "a19b3fc1-d1ff-4c1b-8ad8-93cb1d0e9c6b")
+
+				setPipelineValid(namespacedName, false, func(pipeline *cyndi.CyndiPipeline) {
+					pipeline.Status.ValidationFailedCount = 6
+				})
+				reconcile()
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				seedTable(db, database.AppTable(pipeline.Status.TableName), false, "3ed2bee8-9d88-4e85-8b4f-d4e0ee5ddb3a")
+				setPipelineValid(namespacedName, true)
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				Expect(pipeline.Status.ActiveTableName).To(Equal(activeTableName))
+				Expect(pipeline.GetDegraded().Reason).To(Equal(reasonTableShrunk))
+
+				pipeline.SetAnnotations(map[string]string{annotationAllowShrink: pipeline.Status.TableName})
+				err := test.Client.Update(context.TODO(), pipeline)
+				Expect(err).ToNot(HaveOccurred())
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				Expect(pipeline.Status.ActiveTableName).To(Equal(pipeline.Status.TableName))
+				Expect(pipeline.GetDegraded().Reason).To(Equal(reasonRecovered))
+			})
+
+			It("Retains the previous table and rolls the view back to it", func() {
+				createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"db.table.retention": "3600"})
+				createPipeline(namespacedName)
+				reconcile()
+
+				setPipelineValid(namespacedName, true)
+				reconcile()
+
+				pipeline := getPipeline(namespacedName)
+				previousTableName := pipeline.Status.ActiveTableName
+				Expect(pipeline.Status.PreviousTableName).To(Equal(""))
+				Expect(pipeline.Status.LastCutoverTime).ToNot(BeNil())
+
+				setPipelineValid(namespacedName, false, func(pipeline *cyndi.CyndiPipeline) {
+					pipeline.Status.ValidationFailedCount = 6
+				})
+				reconcile()
+				reconcile()
+
+				setPipelineValid(namespacedName, true)
+				reconcile()
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				newTableName := pipeline.Status.TableName
+				Expect(pipeline.Status.ActiveTableName).To(Equal(newTableName))
+				Expect(pipeline.Status.PreviousTableName).To(Equal(previousTableName))
+
+				exists, err := db.CheckIfTableExists(previousTableName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(exists).To(BeTrue())
+
+				pipeline.SetAnnotations(map[string]string{annotationRollbackTo: previousTableName})
+				Expect(test.Client.Update(context.TODO(), pipeline)).To(Succeed())
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+				Expect(pipeline.Status.TableName).To(Equal(previousTableName))
+				Expect(pipeline.Status.PreviousTableName).To(Equal(newTableName))
+
+				table, err := db.GetCurrentTable()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(*table).To(Equal(previousTableName))
+			})
+
+			It("Replaces the consumer group of the connector if requested", func() {
+				createPipeline(namespacedName)
+				reconcile()
+
+				setPipelineValid(namespacedName, true)
+				reconcile()
+
+				pipeline := getPipeline(namespacedName)
+				connectorName := pipeline.Status.ConnectorName
+				since := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+				pipeline.SetAnnotations(map[string]string{annotationResetOffsets: since.Format(time.RFC3339)})
+				Expect(test.Client.Update(context.TODO(), pipeline)).To(Succeed())
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+				Expect(pipeline.Status.ConnectorName).To(Equal(connectorName))
+				Expect(pipeline.Status.ConsumerGroup).To(HavePrefix(connect.ConsumerGroup(connectorName) + "-reset-"))
+				Expect(pipeline.Status.OffsetReset).ToNot(BeNil())
+				Expect(pipeline.Status.OffsetReset.PreviousConsumerGroup).To(Equal(connect.ConsumerGroup(connectorName)))
+				Expect(pipeline.Status.OffsetReset.Since.Time.Equal(since)).To(BeTrue())
+
+				connector, err := connect.GetConnector(context.TODO(), test.Client, connectorName, namespacedName.Namespace)
+				Expect(err).ToNot(HaveOccurred())
+
+				connectorConfig, _, err := unstructured.NestedMap(connector.UnstructuredContent(), "spec", "config")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(connectorConfig).To(HaveKeyWithValue("consumer.override.group.id", pipeline.Status.ConsumerGroup))
+				Expect(connectorConfig).To(HaveKeyWithValue("consumer.override.auto.offset.reset", "earliest"))
+				Expect(connectorConfig["transforms.timestampFilter.if"]).To(ContainSubstring(strconv.FormatInt(since.UnixNano()/int64(time.Millisecond), 10)))
+
+				// the request is not repeated
+				consumerGroup := pipeline.Status.ConsumerGroup
+				reconcile()
+
+				pipeline = getPipeline(namespacedName)
+				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+				Expect(pipeline.Status.ConsumerGroup).To(Equal(consumerGroup))
+			})
+		})
+	})
+
+	Describe("Valid -> New", func() {
+		It("Triggers refresh if configmap is created", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			pipelineVersion := pipeline.Status.PipelineVersion
+			tableName := pipeline.Status.TableName
+			Expect(pipeline.Status.CyndiConfigVersion).To(Equal("-1"))
+
+			// now add a new configmap
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"connect.cluster": "override"})
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+			Expect(pipeline.Status.InitialSyncInProgress).To(BeFalse())
+			Expect(pipeline.GetValid()).To(Equal(metav1.ConditionUnknown))
+			Expect(pipeline.Status.PipelineVersion).ToNot(Equal(pipelineVersion))
+
+			// ensure the view still points to the old table while the new one is being synchronized
+			table, err := db.GetCurrentTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*table).To(Equal(tableName))
+			Expect(*table).To(Equal(pipeline.Status.ActiveTableName))
+		})
+
+		It("Triggers refresh if configmap changes", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"connect.cluster": "value1"})
+
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
 			configMap := getConfigMap(namespacedName.Namespace)
 			pipelineVersion := pipeline.Status.PipelineVersion
 			tableName := pipeline.Status.TableName
@@ -494,14 +1252,78 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(pipeline.GetValid()).To(Equal(metav1.ConditionUnknown))
 			Expect(pipeline.Status.PipelineVersion).ToNot(Equal(pipelineVersion))
 
-			// ensure the view still points to the old table while the new one is being synchronized
-			table, err := db.GetCurrentTable()
-			Expect(err).ToNot(HaveOccurred())
-			Expect(*table).To(Equal(tableName))
-			Expect(*table).To(Equal(pipeline.Status.ActiveTableName))
+			// ensure the view still points to the old table while the new one is being synchronized
+			table, err := db.GetCurrentTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*table).To(Equal(tableName))
+			Expect(*table).To(Equal(pipeline.Status.ActiveTableName))
+		})
+
+		It("Triggers refresh if spec changes", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			pipelineVersion := pipeline.Status.PipelineVersion
+			tableName := pipeline.Status.TableName
+			Expect(pipeline.Status.CyndiConfigVersion).To(Equal("-1"))
+
+			// now change the DBTableIndexSQL field in the spec
+			pipeline, err := utils.FetchCyndiPipeline(test.Client, namespacedName)
+			Expect(err).ToNot(HaveOccurred())
+			pipeline.Spec.DBTableIndexSQL = "update test"
+			err = test.Client.Update(context.Background(), pipeline)
+			Expect(err).ToNot(HaveOccurred())
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+			Expect(pipeline.Status.InitialSyncInProgress).To(BeFalse())
+			Expect(pipeline.GetValid()).To(Equal(metav1.ConditionUnknown))
+			Expect(pipeline.Status.PipelineVersion).ToNot(Equal(pipelineVersion))
+
+			// ensure the view still points to the old table while the new one is being synchronized
+			table, err := db.GetCurrentTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*table).To(Equal(tableName))
+			Expect(*table).To(Equal(pipeline.Status.ActiveTableName))
+		})
+
+		It("Applies validation intervals without a refresh", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			pipelineVersion := pipeline.Status.PipelineVersion
+
+			interval, initInterval := int64(1800), int64(30)
+			pipeline.Spec.ValidationInterval = &interval
+			pipeline.Spec.InitValidationInterval = &initInterval
+			Expect(test.Client.Update(context.Background(), pipeline)).To(Succeed())
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.Status.PipelineVersion).To(Equal(pipelineVersion))
+
+			// fields that are not applied in place still trigger a refresh
+			pipeline.Spec.DBTableIndexSQL = "update test"
+			Expect(test.Client.Update(context.Background(), pipeline)).To(Succeed())
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
 		})
 
-		It("Triggers refresh if spec changes", func() {
+		It("Triggers a single refresh if a resync is requested", func() {
 			createPipeline(namespacedName)
 			reconcile()
 
@@ -511,28 +1333,25 @@ var _ = Describe("Pipeline operations", func() {
 			pipeline := getPipeline(namespacedName)
 			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
 			pipelineVersion := pipeline.Status.PipelineVersion
-			tableName := pipeline.Status.TableName
-			Expect(pipeline.Status.CyndiConfigVersion).To(Equal("-1"))
 
-			// now change the DBTableIndexSQL field in the spec
-			pipeline, err := utils.FetchCyndiPipeline(test.Client, namespacedName)
-			Expect(err).ToNot(HaveOccurred())
-			pipeline.Spec.DBTableIndexSQL = "update test"
-			err = test.Client.Update(context.Background(), pipeline)
-			Expect(err).ToNot(HaveOccurred())
+			requestedAt := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+			pipeline.Spec.ResyncRequestedAt = &requestedAt
+			Expect(test.Client.Update(context.Background(), pipeline)).To(Succeed())
 			reconcile()
 
 			pipeline = getPipeline(namespacedName)
 			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
-			Expect(pipeline.Status.InitialSyncInProgress).To(BeFalse())
-			Expect(pipeline.GetValid()).To(Equal(metav1.ConditionUnknown))
-			Expect(pipeline.Status.PipelineVersion).ToNot(Equal(pipelineVersion))
+			Expect(pipeline.Status.LastRefreshReason).To(HavePrefix("State deviation: Resync requested at"))
+			Expect(pipeline.Status.LastResyncAt.Equal(&requestedAt)).To(BeTrue())
 
-			// ensure the view still points to the old table while the new one is being synchronized
-			table, err := db.GetCurrentTable()
-			Expect(err).ToNot(HaveOccurred())
-			Expect(*table).To(Equal(tableName))
-			Expect(*table).To(Equal(pipeline.Status.ActiveTableName))
+			// the request has been fulfilled
+			reconcile()
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.Status.PipelineVersion).ToNot(Equal(pipelineVersion))
 		})
 
 		It("Triggers refresh if database secret changes", func() {
@@ -584,7 +1403,7 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
 		})
 
-		It("Triggers refresh if connector disappears", func() {
+		It("Re-creates a connector that disappears", func() {
 			createPipeline(namespacedName)
 			reconcile()
 
@@ -595,13 +1414,25 @@ var _ = Describe("Pipeline operations", func() {
 			tableName := pipeline.Status.ActiveTableName
 			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
 
-			err := connect.DeleteConnector(test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			err := connect.DeleteConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
 			Expect(err).ToNot(HaveOccurred())
 			reconcile()
 
 			pipeline = getPipeline(namespacedName)
-			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
 			Expect(pipeline.Status.ActiveTableName).To(Equal(tableName))
+			Expect(pipeline.GetDegraded().Status).To(Equal(metav1.ConditionTrue))
+			Expect(pipeline.GetDegraded().Reason).To(Equal("ConnectorMissing"))
+
+			exists, err := connect.CheckIfConnectorExists(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.IsDegraded()).To(BeFalse())
 		})
 
 		It("Triggers refresh if connector configuration disagrees", func() {
@@ -623,6 +1454,102 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
 		})
 
+		It("Restores connector modified outside of the operator", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unstructured.SetNestedField(connector.Object, "100", "spec", "config", "batch.size")).To(Succeed())
+			Expect(test.Client.Update(context.TODO(), connector)).To(Succeed())
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+
+			connector, err = connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			batchSize, _, _ := unstructured.NestedString(connector.Object, "spec", "config", "batch.size")
+			Expect(batchSize).ToNot(Equal("100"))
+		})
+
+		It("Only reports a modified connector in read-only mode", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unstructured.SetNestedField(connector.Object, "100", "spec", "config", "batch.size")).To(Succeed())
+			Expect(test.Client.Update(context.TODO(), connector)).To(Succeed())
+
+			r.ReadOnly = true
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+
+			connector, err = connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			batchSize, _, _ := unstructured.NestedString(connector.Object, "spec", "config", "batch.size")
+			Expect(batchSize).To(Equal("100"))
+		})
+
+		It("Reports a drifted connector using the Drifted condition", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"drift.report.enabled": "true"})
+			createPipeline(namespacedName)
+			reconcile()
+
+			setPipelineValid(namespacedName, true)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetDrifted()).ToNot(BeNil())
+			Expect(pipeline.GetDrifted().Status).To(Equal(metav1.ConditionFalse))
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unstructured.SetNestedField(connector.Object, "100", "spec", "config", "batch.size")).To(Succeed())
+			Expect(test.Client.Update(context.TODO(), connector)).To(Succeed())
+
+			r.ReadOnly = true
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
+			Expect(pipeline.GetDrifted().Status).To(Equal(metav1.ConditionTrue))
+			Expect(pipeline.GetDrifted().Reason).To(Equal("ConnectorDrifted"))
+			Expect(pipeline.GetDrifted().Message).To(ContainSubstring("batch.size"))
+		})
+
+		It("Creates a validation CronJob if validation runs in CronJobs", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{
+				"validation.mode":          "cronjob",
+				"validation.cronjob.image": "quay.io/cloudservices/cyndi-operator:latest",
+			})
+			createPipeline(namespacedName)
+			reconcile()
+
+			cronJobs := &batchv1beta1.CronJobList{}
+			Expect(test.Client.List(context.TODO(), cronJobs, client.HasLabels{labelValidation})).To(Succeed())
+			Expect(cronJobs.Items).To(HaveLen(1))
+
+			container := cronJobs.Items[0].Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("quay.io/cloudservices/cyndi-operator:latest"))
+			Expect(container.Args).To(Equal([]string{"--namespace", namespacedName.Namespace, "--name", namespacedName.Name}))
+		})
+
 		It("Triggers refresh if connect cluster changes", func() {
 			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"connect.cluster": "cluster01"})
 			createPipeline(namespacedName)
@@ -645,7 +1572,7 @@ var _ = Describe("Pipeline operations", func() {
 			reconcile()
 
 			pipeline = getPipeline(namespacedName)
-			connector, err := connect.GetConnector(test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
 			Expect(connector.GetLabels()["strimzi.io/cluster"]).To(Equal(cluster))
 		})
 
@@ -671,7 +1598,7 @@ var _ = Describe("Pipeline operations", func() {
 			reconcile()
 
 			pipeline = getPipeline(namespacedName)
-			connector, err := connect.GetConnector(test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
 			Expect(connector.GetLabels()["cyndi/maxAge"]).To(Equal("5"))
 		})
 	})
@@ -702,7 +1629,7 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(errors.IsNotFound(err)).To(BeTrue())
 
-			connectors, err := connect.GetConnectorsForOwner(test.Client, namespacedName.Namespace, pipeline.GetUIDString())
+			connectors, err := connect.GetConnectorsForOwner(context.TODO(), test.Client, namespacedName.Namespace, pipeline.GetUIDString())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(connectors.Items).To(BeEmpty())
 
@@ -737,7 +1664,7 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(errors.IsNotFound(err)).To(BeTrue())
 
-			connectors, err := connect.GetConnectorsForOwner(test.Client, namespacedName.Namespace, pipeline.GetUIDString())
+			connectors, err := connect.GetConnectorsForOwner(context.TODO(), test.Client, namespacedName.Namespace, pipeline.GetUIDString())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(connectors.Items).To(BeEmpty())
 
@@ -753,9 +1680,13 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			createPipeline(namespacedName)
-			_, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: namespacedName})
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal(`secrets "test-pipeline-01-db" not found`))
+			_, condition := reconcileFailing()
+			Expect(condition.Message).To(HaveSuffix(`secrets "test-pipeline-01-db" not found`))
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetStepReady(cyndi.STEP_SECRETS).Status).To(Equal(metav1.ConditionFalse))
+			Expect(pipeline.GetStepReady(cyndi.STEP_TABLE).Status).To(Equal(metav1.ConditionUnknown))
+			Expect(pipeline.GetStepReady(cyndi.STEP_CONNECTOR).Reason).To(Equal("Blocked"))
 
 			recorder, _ := r.Recorder.(*record.FakeRecorder)
 			Expect(recorder.Events).To(HaveLen(4))
@@ -771,21 +1702,23 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			createPipeline(namespacedName)
-			_, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: namespacedName})
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(HavePrefix(`Error connecting to localhost:55432/test as postgres`))
+			_, condition := reconcileFailing()
+			Expect(condition.Message).To(ContainSubstring(`Error connecting to localhost:55432/test as postgres`))
+
+			degraded := getPipeline(namespacedName).GetDegraded()
+			Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degraded.Reason).To(Equal("DatabaseUnavailable"))
 
 			recorder, _ := r.Recorder.(*record.FakeRecorder)
-			Expect(recorder.Events).To(HaveLen(4))
+			Expect(recorder.Events).To(HaveLen(5))
 		})
 
 		It("Fails if the configmap is misconfigured", func() {
 			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"standard.interval": "abcd"})
 			createPipeline(namespacedName)
 
-			_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: namespacedName})
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal(fmt.Sprintf(`Error parsing cyndi configmap in %s: "abcd" is not a valid value for "standard.interval"`, namespacedName.Namespace)))
+			_, condition := reconcileFailing()
+			Expect(condition.Message).To(HaveSuffix(fmt.Sprintf(`Error parsing cyndi configmap in %s: "abcd" is not a valid value for "standard.interval"`, namespacedName.Namespace)))
 
 			recorder, _ := r.Recorder.(*record.FakeRecorder)
 			Expect(recorder.Events).To(HaveLen(4))
@@ -796,9 +1729,8 @@ var _ = Describe("Pipeline operations", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			createPipeline(namespacedName)
-			_, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: namespacedName})
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(HavePrefix("Error executing query"))
+			_, condition := reconcileFailing()
+			Expect(condition.Message).To(ContainSubstring("Error executing query"))
 
 			recorder, _ := r.Recorder.(*record.FakeRecorder)
 			Expect(recorder.Events).To(HaveLen(2))
@@ -813,12 +1745,283 @@ var _ = Describe("Pipeline operations", func() {
 			_, err := db.Exec("CREATE TABLE inventory.hosts ()")
 			Expect(err).ToNot(HaveOccurred())
 
-			_, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: namespacedName})
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(HavePrefix("Error executing query"))
+			_, condition := reconcileFailing()
+			Expect(condition.Message).To(ContainSubstring("Error executing query"))
+
+			// the failure of the view does not mask the state of the other steps
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetStepReady(cyndi.STEP_VIEW).Status).To(Equal(metav1.ConditionFalse))
+			Expect(pipeline.GetStepReady(cyndi.STEP_CONNECTOR).Status).To(Equal(metav1.ConditionTrue))
 
 			recorder, _ := r.Recorder.(*record.FakeRecorder)
 			Expect(recorder.Events).To(HaveLen(2))
 		})
+
+	})
+
+	Describe("Backoff", func() {
+		BeforeEach(func() {
+			appDbSecret, err := utils.FetchSecret(test.Client, namespacedName.Namespace, utils.AppDefaultDbSecretName(namespacedName.Name))
+			Expect(err).ToNot(HaveOccurred())
+			err = test.Client.Delete(context.TODO(), appDbSecret)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Backs off exponentially", func() {
+			createPipeline(namespacedName)
+
+			result, condition := reconcileFailing()
+			Expect(result.RequeueAfter).To(Equal(5 * time.Second))
+			Expect(condition.Reason).To(Equal("ReconcileFailing"))
+			Expect(getPipeline(namespacedName).Status.ConsecutiveFailures).To(Equal(int64(1)))
+
+			// still throttled - the attempt is skipped
+			result = reconcile()
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(getPipeline(namespacedName).Status.ConsecutiveFailures).To(Equal(int64(1)))
+
+			expireThrottling()
+			result, _ = reconcileFailing()
+			Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+			Expect(getPipeline(namespacedName).Status.ConsecutiveFailures).To(Equal(int64(2)))
+		})
+
+		It("Opens the circuit after too many failures", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{
+				"circuit.failure.threshold": "2",
+				"circuit.open.interval":     "900",
+			})
+			createPipeline(namespacedName)
+
+			reconcileFailing()
+			expireThrottling()
+
+			result, condition := reconcileFailing()
+			Expect(result.RequeueAfter).To(Equal(900 * time.Second))
+			Expect(condition.Reason).To(Equal("CircuitOpen"))
+		})
+
+		It("Resets once the pipeline reconciles successfully", func() {
+			createPipeline(namespacedName)
+			reconcileFailing()
+
+			createDbSecret(namespacedName.Namespace, utils.AppDefaultDbSecretName(namespacedName.Name), dbParams)
+			expireThrottling()
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetThrottled()).To(BeNil())
+			Expect(pipeline.Status.ConsecutiveFailures).To(Equal(int64(0)))
+			Expect(pipeline.Status.ThrottledUntil).To(BeNil())
+		})
+	})
+
+	Describe("In-memory databases", func() {
+		var memoryDb *database.MemoryDatabase
+
+		BeforeEach(func() {
+			memoryDb = database.NewMemoryDatabase()
+			r.NewAppDatabase = func(params *DBParams) database.ApplicationDatabase { return memoryDb }
+			r.NewInventoryDatabase = func(params *DBParams) database.Database { return memoryDb }
+		})
+
+		It("Creates the table of a new pipeline", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+
+			exists, err := memoryDb.CheckIfTableExists(pipeline.Status.TableName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("Records the state of the pipeline in the metadata table", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			metadata, err := memoryDb.GetPipelineMetadata(namespacedName.String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadata).ToNot(BeNil())
+			Expect(metadata.OperatorVersion).To(Equal(OperatorVersion))
+			Expect(metadata.ConfigHash).ToNot(BeEmpty())
+			Expect(metadata.LastCutover).To(BeNil())
+		})
+
+		It("Records the versions the connector was rendered by", func() {
+			createPipeline(namespacedName)
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.Status.OperatorVersion).To(Equal(OperatorVersion))
+			Expect(pipeline.Status.ConnectorTemplateVersion).ToNot(BeEmpty())
+
+			connector, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(connect.LabelOperatorVersion, OperatorVersion))
+			Expect(connector.GetLabels()).To(HaveKeyWithValue(connect.LabelTemplateVersion, pipeline.Status.ConnectorTemplateVersion))
+		})
+
+		It("Does not remove tables of other pipelines sharing the database", func() {
+			memoryDb.AddHosts("hosts_other")
+			Expect(memoryDb.RegisterTable("hosts_other", "other/pipeline")).To(Succeed())
+			// without an owner but the database is shared
+			memoryDb.AddHosts("hosts_unowned")
+
+			createPipeline(namespacedName)
+			reconcile()
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			owners, err := memoryDb.GetTableOwners()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(owners[pipeline.Status.TableName]).To(Equal(namespacedName.String()))
+
+			tables, err := memoryDb.GetCyndiTables()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tables).To(ConsistOf("hosts_other", "hosts_unowned", pipeline.Status.TableName))
+		})
+
+		It("Reports a failure injected into the application database", func() {
+			memoryDb.Fail("CreateTable", fmt.Errorf("disk full"))
+			createPipeline(namespacedName)
+
+			_, condition := reconcileFailing()
+			Expect(condition.Message).To(ContainSubstring("disk full"))
+			Expect(getPipeline(namespacedName).GetStepReady(cyndi.STEP_TABLE).Status).To(Equal(metav1.ConditionFalse))
+
+			memoryDb.Fail("CreateTable", nil)
+			expireThrottling()
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetStepReady(cyndi.STEP_TABLE).Status).To(Equal(metav1.ConditionTrue))
+			Expect(pipeline.Status.ConsecutiveFailures).To(Equal(int64(0)))
+		})
+	})
+
+	Describe("Fault injection", func() {
+		AfterEach(func() {
+			faults.Reset()
+		})
+
+		It("Recovers from a failure after the table was created", func() {
+			faults.Inject(faults.TableCreated, faults.Fault{Times: 1, Err: fmt.Errorf("crash")})
+			createPipeline(namespacedName)
+
+			_, condition := reconcileFailing()
+			Expect(condition.Message).To(ContainSubstring("injected fault at table.created"))
+
+			expireThrottling()
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+
+			exists, err := db.CheckIfTableExists(pipeline.Status.TableName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("Adopts the table of an interrupted pipeline version", func() {
+			faults.Inject(faults.TableCreated, faults.Fault{Times: 1, Err: fmt.Errorf("crash")})
+			createPipeline(namespacedName)
+			reconcileFailing()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_NEW))
+			pending := pipeline.Status.PendingTableName
+			Expect(pending).ToNot(BeEmpty())
+
+			expireThrottling()
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.Status.TableName).To(Equal(pending))
+			Expect(pipeline.Status.PendingTableName).To(BeEmpty())
+
+			tables, err := db.GetCyndiTables()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tables).To(Equal([]string{pending}))
+		})
+
+		It("Creates the connector of an interrupted pipeline version", func() {
+			faults.Inject(faults.ConnectorCreate, faults.Fault{Times: 1, Err: fmt.Errorf("crash")})
+			createPipeline(namespacedName)
+			reconcileFailing()
+
+			// the table is registered before the connector is created
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.Status.ConnectorPending).To(BeTrue())
+
+			expireThrottling()
+			reconcile()
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.Status.ConnectorPending).To(BeFalse())
+			Expect(pipeline.IsDegraded()).To(BeFalse())
+
+			_, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Recovers from a database error on the Nth query", func() {
+			faults.Inject(faults.DBQuery, faults.Fault{Nth: 3, Times: 1, Err: fmt.Errorf("connection lost")})
+			createPipeline(namespacedName)
+
+			reconcileFailing()
+			Expect(faults.Passes(faults.DBQuery)).To(BeNumerically(">=", 3))
+
+			expireThrottling()
+			reconcile()
+			Expect(getPipeline(namespacedName).GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+		})
+
+		It("Recovers from a failure to create the connector", func() {
+			faults.Inject(faults.ConnectorCreate, faults.Fault{Times: 1, Err: fmt.Errorf("apiserver unavailable")})
+			createPipeline(namespacedName)
+
+			reconcileFailing()
+			Expect(getPipeline(namespacedName).GetStepReady(cyndi.STEP_CONNECTOR).Status).To(Equal(metav1.ConditionFalse))
+
+			expireThrottling()
+			reconcile()
+
+			pipeline := getPipeline(namespacedName)
+			Expect(pipeline.GetStepReady(cyndi.STEP_CONNECTOR).Status).To(Equal(metav1.ConditionTrue))
+
+			_, err := connect.GetConnector(context.TODO(), test.Client, pipeline.Status.ConnectorName, namespacedName.Namespace)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("Watches", func() {
+		It("Maps a secret to the pipelines using it", func() {
+			createPipeline(namespacedName)
+
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "host-inventory-db", Namespace: namespacedName.Namespace}}
+			Expect(r.pipelinesReferencingSecret(secret)).To(Equal([]ctrl.Request{{NamespacedName: namespacedName}}))
+
+			secret.Name = utils.AppDefaultDbSecretName(namespacedName.Name)
+			Expect(r.pipelinesReferencingSecret(secret)).To(HaveLen(1))
+
+			secret.Name = "unrelated"
+			Expect(r.pipelinesReferencingSecret(secret)).To(BeEmpty())
+		})
+
+		It("Maps a Connect cluster to the pipelines using it", func() {
+			createConfigMap(namespacedName.Namespace, "cyndi", map[string]string{"connect.cluster": "cluster01"})
+			createPipeline(namespacedName)
+
+			cluster := connect.EmptyConnectCluster()
+			cluster.SetName("cluster01")
+			cluster.SetNamespace(namespacedName.Namespace)
+			Expect(r.pipelinesUsingConnectCluster(cluster)).To(Equal([]ctrl.Request{{NamespacedName: namespacedName}}))
+
+			cluster.SetNamespace(test.UniqueNamespace())
+			Expect(r.pipelinesUsingConnectCluster(cluster)).To(BeEmpty())
+		})
 	})
 })
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/tracing"
+)
+
+/*
+
+HBI hosts fetched from the HBI REST API instead of the inventory database.
+Used in deployments where the operator cannot be granted credentials of the inventory database.
+The API cannot evaluate SQL filters so only pipelines without additional filters can be validated this way.
+
+*/
+
+const (
+	apiPageSize = 100
+	apiTimeout  = 60 * time.Second
+)
+
+// hosts of all the staleness states are present in the inventory database
+var apiStaleness = []string{"fresh", "stale", "stale_warning"}
+
+type APISource struct {
+	Params *config.APIParams
+	client *http.Client
+	// parent context of the spans created for requests
+	ctx context.Context
+	// host counts and ids shared with other pipelines (see UseCache)
+	cache *HostCache
+}
+
+type hostsResponse struct {
+	Total   int64 `json:"total"`
+	Results []struct {
+		ID string `json:"id"`
+	} `json:"results"`
+}
+
+func NewAPISource(params *config.APIParams) HostSource {
+	return &APISource{Params: params}
+}
+
+func (s *APISource) Connect() error {
+	s.client = &http.Client{Timeout: apiTimeout}
+	return nil
+}
+
+func (s *APISource) Close() error {
+	return nil
+}
+
+func (s *APISource) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// The table is not used as the API only exposes HBI hosts
+func (s *APISource) CountHosts(table string, insightsOnly bool, additionalFilters []map[string]string) (int64, error) {
+	// filters are not supported by the API
+	if len(additionalFilters) > 0 {
+		return s.countHosts(insightsOnly, additionalFilters)
+	}
+
+	return s.cache.count(s.cacheKey("count", insightsOnly), func() (int64, error) {
+		return s.countHosts(insightsOnly, additionalFilters)
+	})
+}
+
+func (s *APISource) countHosts(insightsOnly bool, additionalFilters []map[string]string) (int64, error) {
+	response, err := s.getHosts(insightsOnly, additionalFilters, 1, 1)
+	if err != nil {
+		return -1, err
+	}
+
+	return response.Total, nil
+}
+
+func (s *APISource) GetHostIds(table string, insightsOnly bool, additionalFilters []map[string]string) ([]string, error) {
+	if len(additionalFilters) > 0 {
+		return s.getHostIds(insightsOnly, additionalFilters)
+	}
+
+	return s.cache.ids(s.cacheKey("ids", insightsOnly), func() ([]string, error) {
+		return s.getHostIds(insightsOnly, additionalFilters)
+	})
+}
+
+func (s *APISource) getHostIds(insightsOnly bool, additionalFilters []map[string]string) ([]string, error) {
+	var ids []string
+
+	for page := 1; ; page++ {
+		response, err := s.getHosts(insightsOnly, additionalFilters, page, apiPageSize)
+		if err != nil {
+			return ids, err
+		}
+
+		for _, host := range response.Results {
+			ids = append(ids, host.ID)
+		}
+
+		if len(response.Results) < apiPageSize || int64(len(ids)) >= response.Total {
+			break
+		}
+	}
+
+	// same order as the ids read from a database
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *APISource) getHosts(insightsOnly bool, additionalFilters []map[string]string, page int, perPage int) (result hostsResponse, err error) {
+	for _, filter := range additionalFilters {
+		if filter["where"] != "" {
+			return result, errors.New("SQL filters cannot be applied to hosts fetched from the HBI API")
+		}
+	}
+
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(perPage))
+	query.Set("order_by", "updated")
+	query.Set("order_how", "ASC")
+
+	for _, staleness := range apiStaleness {
+		query.Add("staleness", staleness)
+	}
+
+	if insightsOnly {
+		query.Set("registered_with", "insights")
+	}
+
+	requestURL := fmt.Sprintf("%s/hosts?%s", strings.TrimSuffix(s.Params.URL, "/"), query.Encode())
+
+	_, span := tracing.Start(s.ctx, "inventory api", semconv.HTTPMethodKey.String(http.MethodGet), semconv.HTTPURLKey.String(requestURL))
+	defer func() { tracing.End(span, err) }()
+
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return result, err
+	}
+
+	if s.Params.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+s.Params.Token)
+	}
+
+	if s.Params.Identity != "" {
+		request.Header.Set("x-rh-identity", s.Params.Identity)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return result, ConnectionError{fmt.Errorf("Error fetching hosts from %s: %w", s.Params.URL, err)}
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("Error fetching hosts from %s: %s", s.Params.URL, response.Status)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&result)
+	return result, err
+}
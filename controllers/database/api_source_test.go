@@ -0,0 +1,92 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type apiHost struct {
+	ID string `json:"id"`
+}
+
+// Serves the given hosts the way the HBI API pages them
+func newHBIAPIServer(requests *[]*http.Request, ids ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+
+		results := []apiHost{}
+		for idx := (page - 1) * perPage; idx < len(ids) && idx < page*perPage; idx++ {
+			results = append(results, apiHost{ID: ids[idx]})
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"total":   len(ids),
+			"results": results,
+		})
+	}))
+}
+
+var _ = Describe("HBI API source", func() {
+	var (
+		requests []*http.Request
+		server   *httptest.Server
+		source   HostSource
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		ids := make([]string, 150)
+		for idx := range ids {
+			// served in reverse order to verify the ids get sorted
+			ids[idx] = fmt.Sprintf("%04d", 1000-idx)
+		}
+
+		server = newHBIAPIServer(&requests, ids...)
+		source = NewAPISource(&config.APIParams{URL: server.URL + "/api/inventory/v1/", Identity: "eyJpZGVudGl0eSI6e319"})
+		Expect(source.Connect()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Counts hosts", func() {
+		count, err := source.CountHosts("public.hosts", true, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(int64(150)))
+
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].URL.Path).To(Equal("/api/inventory/v1/hosts"))
+		Expect(requests[0].URL.Query().Get("registered_with")).To(Equal("insights"))
+		Expect(requests[0].URL.Query()["staleness"]).To(Equal([]string{"fresh", "stale", "stale_warning"}))
+		Expect(requests[0].Header.Get("x-rh-identity")).To(Equal("eyJpZGVudGl0eSI6e319"))
+	})
+
+	It("Gets host ids of all the pages", func() {
+		ids, err := source.GetHostIds("public.hosts", false, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids).To(HaveLen(150))
+		Expect(ids[0]).To(Equal("0851"))
+		Expect(ids[149]).To(Equal("1000"))
+
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[0].URL.Query().Get("registered_with")).To(BeEmpty())
+	})
+
+	It("Rejects SQL filters", func() {
+		_, err := source.CountHosts("public.hosts", false, []map[string]string{{"where": "true"}})
+		Expect(err).To(HaveOccurred())
+		Expect(requests).To(BeEmpty())
+	})
+})
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/go-logr/logr"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
+
+	"github.com/jackc/pgx"
 
 	"github.com/RedHatInsights/cyndi-operator/controllers/config"
 	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
@@ -13,9 +17,16 @@ import (
 
 type AppDatabase struct {
 	BaseDatabase
+	Dialect Dialect
+	// computed staleness column of the inventory.hosts view, nil if the view does not expose one
+	Staleness *config.ViewStalenessConfiguration
+	// how display_name supports case-insensitive searches, exact matches only if not set
+	DisplayNameSearch config.DisplayNameSearch
+	// view of the inventory schema hosts are exposed through (see SetView)
+	View string
 }
 
-const viewTemplate = `CREATE OR REPLACE VIEW inventory.hosts AS SELECT
+const viewTemplate = `CREATE OR REPLACE VIEW %[5]s AS SELECT
 	id,
 	account,
 	display_name,
@@ -30,8 +41,67 @@ const viewTemplate = `CREATE OR REPLACE VIEW inventory.hosts AS SELECT
 	reporter,
 	per_reporter_staleness,
 	org_id,
-	groups
-FROM inventory.%[1]s`
+	groups%[4]s
+FROM %[1]s`
+
+// Computed staleness column appended to the view (see config.ViewStalenessConfiguration)
+const stalenessColumnTemplate = `,
+	CASE
+		WHEN %[4]s < stale_timestamp + INTERVAL '%[2]d seconds' THEN 'fresh'
+		WHEN %[4]s < stale_timestamp + INTERVAL '%[3]d seconds' THEN 'stale'
+		ELSE 'culled'
+	END AS %[1]s`
+
+// Columns of the table the inventory.hosts view is built from
+var viewColumns = []string{
+	"id",
+	"account",
+	"display_name",
+	"created",
+	"updated",
+	"stale_timestamp",
+	"tags",
+	"system_profile",
+	"insights_id",
+	"reporter",
+	"per_reporter_staleness",
+	"org_id",
+	"groups",
+}
+
+// Columns the inventory.hosts view exposes (see viewTemplate), not including the staleness column
+var viewOutputColumns = []string{
+	"id",
+	"account",
+	"display_name",
+	"created",
+	"updated",
+	"stale_timestamp",
+	"stale_warning_timestamp",
+	"culled_timestamp",
+	"tags",
+	"system_profile",
+	"insights_id",
+	"reporter",
+	"per_reporter_staleness",
+	"org_id",
+	"groups",
+}
+
+// Returns the columns of the inventory.hosts view with the given staleness column
+func ViewColumns(staleness *config.ViewStalenessConfiguration) []string {
+	columns := append([]string{}, viewOutputColumns...)
+	if staleness != nil {
+		columns = append(columns, staleness.Column)
+	}
+
+	return columns
+}
+
+// View hosts are exposed through unless the pipeline is a clone (spec.sourcePipeline)
+const DefaultView = "hosts"
+
+const hostsView = InventorySchema + "." + DefaultView
 
 const cullingStaleWarningOffset = "7"
 const cullingCulledOffset = "14"
@@ -43,10 +113,26 @@ func NewAppDatabase(config *config.DBParams, log logr.Logger) *AppDatabase {
 		BaseDatabase: BaseDatabase{
 			Config: config,
 			Log:    log,
+			Role:   RoleApp,
 		},
+		Dialect: postgresDialect{},
+		View:    DefaultView,
 	}
 }
 
+func (db *AppDatabase) SetView(view string) {
+	db.View = view
+}
+
+// Returns the quoted name of the view hosts are exposed through
+func (db *AppDatabase) view() string {
+	if db.View == "" {
+		return AppTable(DefaultView)
+	}
+
+	return AppTable(db.View)
+}
+
 func (db *AppDatabase) Connect() (err error) {
 	err = db.BaseDatabase.Connect()
 
@@ -69,8 +155,8 @@ func (db *AppDatabase) CheckIfTableExists(tableName string) (bool, error) {
 	}
 
 	query := fmt.Sprintf(
-		"SELECT exists (SELECT FROM information_schema.tables WHERE table_schema = 'inventory' AND table_name = '%s')",
-		tableName)
+		"SELECT exists (SELECT 1 FROM information_schema.tables WHERE table_schema = 'inventory' AND table_name = %s)",
+		quoteLiteral(tableName))
 	rows, err := db.RunQuery(query)
 
 	if err != nil {
@@ -92,8 +178,81 @@ func (db *AppDatabase) CheckIfTableExists(tableName string) (bool, error) {
 }
 
 func (db *AppDatabase) CreateTable(tableName string, script string) error {
-	m := make(map[string]string)
-	m["TableName"] = tableName
+	return db.execTemplate(tableName, script, false)
+}
+
+/*
+ * Creates a table hash-partitioned by host id along with the given number of partitions.
+ * The script needs to declare the partitioning scheme if .Partitioned is set (see the default db.schema).
+ */
+func (db *AppDatabase) CreatePartitionedTable(tableName string, script string, partitions int64) error {
+	if err := db.execTemplate(tableName, script, true); err != nil {
+		return err
+	}
+
+	for remainder := int64(0); remainder < partitions; remainder++ {
+		query := fmt.Sprintf(
+			"CREATE TABLE %s PARTITION OF %s FOR VALUES WITH (MODULUS %d, REMAINDER %d)",
+			AppTable(PartitionName(tableName, remainder)), AppTable(tableName), partitions, remainder)
+
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Runs the index definitions (a template of the table name) against the table
+func (db *AppDatabase) CreateIndexes(tableName string, script string) error {
+	if err := db.execTemplate(tableName, script, false); err != nil {
+		return err
+	}
+
+	// created regardless of the index definitions so that a custom db.table.index.sql does not need to define it
+	if db.DisplayNameSearch == config.DisplayNameSearchLowerIndex {
+		_, err := db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (lower(display_name))",
+			QuoteIdentifier(tableName+"_display_name_lower_index"), AppTable(tableName)))
+		return err
+	}
+
+	return nil
+}
+
+// Returns the (sorted) columns of the primary key of the given table
+func (db *AppDatabase) GetPrimaryKey(tableName string) ([]string, error) {
+	rows, err := db.RunQuery(db.Dialect.PrimaryKeyQuery(tableName))
+	if err != nil {
+		return nil, err
+	}
+
+	return scanStrings(rows)
+}
+
+func (db *AppDatabase) AnalyzeTable(tableName string) error {
+	_, err := db.Exec(fmt.Sprintf("ANALYZE %s", AppTable(tableName)))
+	return err
+}
+
+func PartitionName(tableName string, remainder int64) string {
+	return fmt.Sprintf("%s_p%d", tableName, remainder)
+}
+
+func (db *AppDatabase) execTemplate(tableName string, script string, partitioned bool) error {
+	// the name is rendered unquoted
+	if err := ValidatePlainIdentifier(tableName); err != nil {
+		return err
+	}
+
+	m := map[string]interface{}{
+		"TableName":     tableName,
+		"Partitioned":   partitioned,
+		"IdType":        db.ColumnTypes.IdSQLType(),
+		"TimestampType": db.ColumnTypes.TimestampSQLType(),
+		// the citext extension needs to exist (see EnsureExtension)
+		"DisplayNameType": db.DisplayNameSearch.SQLType(),
+	}
+
 	tmpl, err := template.New("dbSchema").Parse(script)
 	if err != nil {
 		return err
@@ -118,26 +277,352 @@ func (db *AppDatabase) DeleteTable(tableName string) error {
 		return nil
 	}
 
-	query := fmt.Sprintf("DROP table %s CASCADE", utils.AppFullTableName(tableName))
-	_, err = db.Exec(query)
-	return err
+	query := fmt.Sprintf("DROP table %s CASCADE", AppTable(tableName))
+	if _, err = db.Exec(query); err != nil {
+		return err
+	}
+
+	return db.unregisterTable(tableName)
+}
+
+/*
+ * Copies all rows of the source table into the (empty) target table.
+ * Only columns present in both tables are copied so that a table created using a different db.schema can still be used as the source.
+ * Returns the number of rows copied.
+ */
+func (db *AppDatabase) CloneTable(tableName string, sourceTableName string) (int64, error) {
+	rows, err := db.RunQuery(fmt.Sprintf(`SELECT t.column_name FROM information_schema.columns t
+		JOIN information_schema.columns s ON s.table_schema = t.table_schema AND s.table_name = %s AND s.column_name = t.column_name
+		WHERE t.table_schema = 'inventory' AND t.table_name = %s
+		ORDER BY t.ordinal_position`,
+		quoteLiteral(sourceTableName), quoteLiteral(tableName)))
+	if err != nil {
+		return 0, err
+	}
+
+	columns, err := scanStrings(rows)
+	if err != nil {
+		return 0, err
+	} else if len(columns) == 0 {
+		return 0, fmt.Errorf("Tables %s and %s have no columns in common", tableName, sourceTableName)
+	}
+
+	for i, column := range columns {
+		columns[i] = QuoteIdentifier(column)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %[1]s (%[3]s) SELECT %[3]s FROM %[2]s",
+		AppTable(tableName), AppTable(sourceTableName), strings.Join(columns, ", "))
+
+	result, err := db.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// Tables are UNLOGGED ('u') or permanent ('p')
+const (
+	persistenceUnlogged  = "u"
+	persistencePermanent = "p"
+)
+
+// Converts the table (or all partitions of a partitioned table) to UNLOGGED
+func (db *AppDatabase) SetTableUnlogged(tableName string) error {
+	return db.setTablePersistence(tableName, persistencePermanent, "UNLOGGED")
+}
+
+// Converts the table (or all partitions of a partitioned table) to LOGGED
+func (db *AppDatabase) SetTableLogged(tableName string) error {
+	return db.setTablePersistence(tableName, persistenceUnlogged, "LOGGED")
+}
+
+func (db *AppDatabase) setTablePersistence(tableName string, from string, to string) error {
+	tables, err := db.getTableRelations(tableName, fmt.Sprintf("c.relpersistence = '%s'", from))
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err = db.Exec(fmt.Sprintf("ALTER TABLE %s SET %s", AppTable(table), to)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sets storage parameters (e.g. fillfactor) of the table (or all partitions of a partitioned table)
+func (db *AppDatabase) SetStorageParameters(tableName string, parameters map[string]string) error {
+	if len(parameters) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	assignments := make([]string, len(keys))
+	for i, key := range keys {
+		assignments[i] = fmt.Sprintf("%s = %s", key, parameters[key])
+	}
+
+	tables, err := db.getTableRelations(tableName, "")
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err = db.Exec(fmt.Sprintf("ALTER TABLE %s SET (%s)", AppTable(table), strings.Join(assignments, ", "))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sets the compression method (pglz or lz4) of the jsonb columns of the table
+func (db *AppDatabase) SetJSONCompression(tableName string, method string) error {
+	rows, err := db.RunQuery(fmt.Sprintf(
+		"SELECT column_name FROM information_schema.columns WHERE table_schema = 'inventory' AND table_name = %s AND data_type = 'jsonb' ORDER BY column_name",
+		quoteLiteral(tableName)))
+	if err != nil {
+		return err
+	}
+
+	columns, err := scanStrings(rows)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		query := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET COMPRESSION %s", AppTable(tableName), QuoteIdentifier(column), method)
+		if _, err = db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+ * Returns the name of the table or, in case of a partitioned table, names of its partitions.
+ * Storage properties of partitioned tables themselves cannot be changed - only of their partitions.
+ */
+func (db *AppDatabase) getTableRelations(tableName string, condition string) ([]string, error) {
+	fullTableName := quoteLiteral(AppTable(tableName))
+
+	if condition != "" {
+		condition = "AND " + condition
+	}
+
+	query := fmt.Sprintf(`SELECT c.relname FROM pg_catalog.pg_class c
+		WHERE c.relkind = 'r' %[2]s
+		AND (c.oid = %[1]s::regclass OR c.oid IN (SELECT inhrelid FROM pg_catalog.pg_inherits WHERE inhparent = %[1]s::regclass))
+		ORDER BY c.relname`,
+		fullTableName, condition)
+
+	rows, err := db.RunQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanStrings(rows)
+}
+
+// Reads all rows of a single string column and closes the rows
+func scanStrings(rows *pgx.Rows) (result []string, err error) {
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		result = append(result, value)
+	}
+
+	return result, rows.Err()
+}
+
+// Returns the time the database server was (re)started
+func (db *AppDatabase) GetServerStartTime() (start time.Time, err error) {
+	rows, err := db.RunQuery("SELECT pg_postmaster_start_time()")
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	rows.Next()
+	err = rows.Scan(&start)
+	return
+}
+
+func (db *AppDatabase) viewDefinition(tableName string) string {
+	staleness := ""
+	if db.Staleness != nil {
+		staleness = fmt.Sprintf(stalenessColumnTemplate, QuoteIdentifier(db.Staleness.Column), db.Staleness.StaleAfter, db.Staleness.CulledAfter, db.now())
+	}
+
+	return fmt.Sprintf(viewTemplate, AppTable(tableName), cullingStaleWarningOffset, cullingCulledOffset, staleness, db.view())
+}
+
+// Current time comparable with the timestamps of the table. Timestamps without a time zone are stored in UTC
+func (db *AppDatabase) now() string {
+	if db.ColumnTypes.Timestamp == config.TimestampColumnTypeNoTZ {
+		return "(NOW() AT TIME ZONE 'UTC')"
+	}
+
+	return "NOW()"
 }
 
 func (db *AppDatabase) UpdateView(tableName string) error {
-	if _, err := db.Exec(fmt.Sprintf(viewTemplate, tableName, cullingStaleWarningOffset, cullingCulledOffset)); err != nil {
+	if _, err := db.Exec(db.viewDefinition(tableName)); err != nil {
 		return err
 	}
 
-	if _, err := db.Exec(`GRANT SELECT ON inventory.hosts TO cyndi_reader`); err != nil {
+	if _, err := db.Exec(fmt.Sprintf("GRANT SELECT ON %s TO cyndi_reader", db.view())); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+/*
+ * Re-creates the view (inventory.hosts unless the pipeline is a clone) on top of the given table.
+ * Unlike UpdateView this allows columns of the view to be renamed or removed. Grants on the view other than that of cyndi_reader are lost.
+ */
+func (db *AppDatabase) ReplaceView(tableName string) error {
+	// a multi-statement query runs in a single transaction so the view does not disappear for the application
+	query := fmt.Sprintf("DROP VIEW IF EXISTS %s; %s; GRANT SELECT ON %s TO cyndi_reader", db.view(), db.viewDefinition(tableName), db.view())
+	_, err := db.Exec(query)
+	return err
+}
+
+// Reads hosts through the view (the host count and a sample host) the way the application does
+func (db *AppDatabase) VerifyView() (int64, error) {
+	count, err := db.CountHosts(db.view(), false, []map[string]string{})
+	if err != nil {
+		return -1, err
+	}
+
+	rows, err := db.RunQuery(fmt.Sprintf("SELECT * FROM %s LIMIT 1", db.view()))
+	if err != nil {
+		return -1, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err == nil && count > 0 {
+			err = fmt.Errorf("no host returned by %s holding %d hosts", db.view(), count)
+		}
+
+		return count, err
+	}
+
+	// decoding all the columns catches errors in the expressions of the view
+	if _, err = rows.Values(); err != nil {
+		return -1, err
+	}
+
+	return count, nil
+}
+
+// Creates the given NOLOGIN role unless it exists
+func (db *AppDatabase) CreateRole(role string) error {
+	_, err := db.Exec(db.Dialect.CreateRoleStatement(role))
+	return err
+}
+
+// Grants SELECT on the given tables (or views) of the inventory schema to the given roles
+func (db *AppDatabase) GrantSelect(roles []string, tableNames ...string) error {
+	if len(roles) == 0 || len(tableNames) == 0 {
+		return nil
+	}
+
+	grantees := make([]string, len(roles))
+	for i, role := range roles {
+		grantees[i] = QuoteIdentifier(role)
+	}
+
+	tables := make([]string, len(tableNames))
+	for i, tableName := range tableNames {
+		tables[i] = AppTable(tableName)
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", QuoteIdentifier(InventorySchema), strings.Join(grantees, ", "))); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(fmt.Sprintf("GRANT SELECT ON %s TO %s", strings.Join(tables, ", "), strings.Join(grantees, ", ")))
+	return err
+}
+
+// Returns true if inventory.hosts is a table rather than a view, e.g. one created by the Cyndi setup that predates the operator
+func (db *AppDatabase) IsLegacyHostsTable() (bool, error) {
+	rows, err := db.RunQuery(
+		"SELECT 1 FROM information_schema.tables WHERE table_schema = 'inventory' AND table_name = 'hosts' AND table_type = 'BASE TABLE'")
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+// Returns the columns needed by the inventory.hosts view that the given table lacks
+func (db *AppDatabase) GetMissingViewColumns(tableName string) ([]string, error) {
+	rows, err := db.RunQuery(fmt.Sprintf(
+		"SELECT column_name FROM information_schema.columns WHERE table_schema = 'inventory' AND table_name = %s", quoteLiteral(tableName)))
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, column := range viewColumns {
+		if !utils.ContainsString(columns, column) {
+			missing = append(missing, column)
+		}
+	}
+
+	return missing, nil
+}
+
+// Renames the legacy inventory.hosts table to the given name and replaces it with the view pointing to the renamed table
+func (db *AppDatabase) AdoptLegacyHostsTable(tableName string) error {
+	// statements of a single simple query run in a single transaction
+	query := fmt.Sprintf("ALTER TABLE inventory.hosts RENAME TO %s; %s; GRANT SELECT ON inventory.hosts TO cyndi_reader",
+		QuoteIdentifier(tableName), db.viewDefinition(tableName))
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// Returns true if the host with the given id is present in the given table
+func (db *AppDatabase) HostExists(tableName string, id string) (bool, error) {
+	rows, err := db.RunQuery(fmt.Sprintf("SELECT 1 FROM %s WHERE id = %s", AppTable(tableName), quoteLiteral(id)))
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
 func (db *AppDatabase) GetCurrentTable() (table *string, err error) {
-	query := "SELECT table_name FROM information_schema.view_table_usage WHERE view_schema = 'inventory' AND view_name = 'hosts' LIMIT 1;"
-	rows, err := db.RunQuery(query)
+	rows, err := db.RunQuery(db.Dialect.CurrentTableQuery(db.View))
 
 	if err != nil {
 		return nil, err
@@ -156,8 +641,7 @@ func (db *AppDatabase) GetCurrentTable() (table *string, err error) {
 }
 
 func (db *AppDatabase) GetCyndiTables() (tables []string, err error) {
-	query := "SELECT table_name FROM information_schema.tables WHERE table_schema = 'inventory' AND table_type = 'BASE TABLE' AND table_name LIKE 'hosts_%' ORDER BY table_name"
-	rows, err := db.RunQuery(query)
+	rows, err := db.RunQuery(db.Dialect.CyndiTablesQuery())
 
 	if err != nil {
 		return tables, err
@@ -189,7 +673,7 @@ func (db *AppDatabase) rotatePassword() (err error) {
 
 	defer db.BaseDatabase.Close()
 
-	query := fmt.Sprintf("ALTER ROLE %s WITH PASSWORD '%s'", db.Config.User, newPassword)
+	query := fmt.Sprintf("ALTER ROLE %s WITH PASSWORD %s", QuoteIdentifier(db.Config.User), quoteLiteral(newPassword))
 	_, err = db.Exec(query)
 	return
 }
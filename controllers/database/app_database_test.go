@@ -1,6 +1,9 @@
 package database
 
 import (
+	"fmt"
+	"time"
+
 	. "github.com/RedHatInsights/cyndi-operator/controllers/config"
 	logr "github.com/go-logr/logr/testing"
 
@@ -69,6 +72,50 @@ var _ = Describe("Application Database", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("maintains the metadata of pipelines", func() {
+			metadata, err := db.GetPipelineMetadata("test/advisor")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadata).To(BeNil())
+
+			cutover := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			expected := PipelineMetadata{
+				Pipeline:        "test/advisor",
+				ActiveTable:     TestTable,
+				OperatorVersion: "abc1234",
+				LastCutover:     &cutover,
+				ConfigHash:      "123456",
+			}
+
+			Expect(db.UpdatePipelineMetadata(expected)).To(Succeed())
+			expected.ActiveTable = ""
+			Expect(db.UpdatePipelineMetadata(expected)).To(Succeed())
+
+			metadata, err = db.GetPipelineMetadata("test/advisor")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadata.Equal(expected)).To(BeTrue())
+
+			Expect(db.DeletePipelineMetadata("test/advisor")).To(Succeed())
+			metadata, err = db.GetPipelineMetadata("test/advisor")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadata).To(BeNil())
+		})
+
+		It("records the owners of tables", func() {
+			Expect(db.CreateTable(TestTable, config.DBTableInitScript)).To(Succeed())
+			Expect(db.RegisterTable(TestTable, "test/advisor")).To(Succeed())
+			// a table owned already keeps its owner
+			Expect(db.RegisterTable(TestTable, "test/other")).To(Succeed())
+
+			owners, err := db.GetTableOwners()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(owners).To(Equal(map[string]string{TestTable: "test/advisor"}))
+
+			Expect(db.DeleteTable(TestTable)).To(Succeed())
+			owners, err = db.GetTableOwners()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(owners).To(BeEmpty())
+		})
+
 		It("noops if the table does not exist", func() {
 			err := db.DeleteTable(TestTable)
 			Expect(err).ToNot(HaveOccurred())
@@ -86,6 +133,68 @@ var _ = Describe("Application Database", func() {
 			rows.Close()
 		})
 
+		It("should list the columns of the view", func() {
+			err := db.CreateTable(TestTable, config.DBTableInitScript)
+			Expect(err).ToNot(HaveOccurred())
+
+			db.Staleness = &ViewStalenessConfiguration{Column: "staleness", CulledAfter: 60}
+			Expect(db.UpdateView(TestTable)).To(Succeed())
+
+			rows, err := db.RunQuery("SELECT column_name FROM information_schema.columns WHERE table_schema = 'inventory' AND table_name = 'hosts' ORDER BY ordinal_position")
+			Expect(err).ToNot(HaveOccurred())
+			columns, err := scanStrings(rows)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(columns).To(Equal(ViewColumns(db.Staleness)))
+		})
+
+		It("should verify the view", func() {
+			err := db.CreateTable(TestTable, config.DBTableInitScript)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = db.UpdateView(TestTable)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = db.Exec(fmt.Sprintf(`INSERT INTO inventory.%s (id, account, org_id, display_name, tags, updated, created, stale_timestamp, system_profile, reporter, per_reporter_staleness) VALUES ('3ed2bee8-9d88-4e85-8b4f-d4e0ee5ddb3a', '000001', 'test01', 'test01', '{}', NOW(), NOW(), NOW(), '{}', 'puptoo', '{}')`, TestTable))
+			Expect(err).ToNot(HaveOccurred())
+
+			count, err := db.VerifyView()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(int64(1)))
+		})
+
+		It("should expose the staleness column", func() {
+			err := db.CreateTable(TestTable, config.DBTableInitScript)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = db.Exec(fmt.Sprintf(`INSERT INTO inventory.%s (id, account, org_id, display_name, tags, updated, created, stale_timestamp, system_profile, reporter, per_reporter_staleness) VALUES ('3ed2bee8-9d88-4e85-8b4f-d4e0ee5ddb3a', '000001', 'test01', 'test01', '{}', NOW(), NOW(), NOW() - INTERVAL '1 day', '{}', 'puptoo', '{}')`, TestTable))
+			Expect(err).ToNot(HaveOccurred())
+
+			db.Staleness = &ViewStalenessConfiguration{Column: "host_staleness", StaleAfter: 0, CulledAfter: 7 * 24 * 3600}
+			Expect(db.UpdateView(TestTable)).To(Succeed())
+
+			rows, err := db.RunQuery("SELECT host_staleness FROM inventory.hosts")
+			Expect(err).ToNot(HaveOccurred())
+
+			var staleness string
+			rows.Next()
+			Expect(rows.Scan(&staleness)).To(Succeed())
+			rows.Close()
+			Expect(staleness).To(Equal("stale"))
+
+			// removing the column requires the view to be replaced
+			db.Staleness = nil
+			Expect(db.UpdateView(TestTable)).ToNot(Succeed())
+			Expect(db.ReplaceView(TestTable)).To(Succeed())
+
+			_, err = db.RunQuery("SELECT host_staleness FROM inventory.hosts")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail to verify a missing view", func() {
+			_, err := db.VerifyView()
+			Expect(err).To(HaveOccurred())
+		})
+
 		It("should be able to fetch the current table", func() {
 			err := db.CreateTable(TestTable, config.DBTableInitScript)
 			Expect(err).ToNot(HaveOccurred())
@@ -130,5 +239,220 @@ var _ = Describe("Application Database", func() {
 			Expect(tables[1]).To(Equal("hosts_v1_2"))
 			Expect(tables[2]).To(Equal("hosts_v1_3"))
 		})
+
+		It("should be able to create a partitioned table", func() {
+			err := db.CreatePartitionedTable("hosts_v1_1", config.DBTableInitScript+config.DBTableIndexSQL, 4)
+			Expect(err).ToNot(HaveOccurred())
+
+			exists, err := db.CheckIfTableExists(PartitionName("hosts_v1_1", 3))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+
+			rows, err := db.RunQuery("SELECT count(*) FROM pg_catalog.pg_inherits WHERE inhparent = 'inventory.hosts_v1_1'::regclass")
+			Expect(err).ToNot(HaveOccurred())
+			var partitions int64
+			rows.Next()
+			Expect(rows.Scan(&partitions)).To(Succeed())
+			rows.Close()
+			Expect(partitions).To(Equal(int64(4)))
+
+			// partitions are not listed as separate tables
+			tables, err := db.GetCyndiTables()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tables).To(Equal([]string{"hosts_v1_1"}))
+
+			err = db.DeleteTable("hosts_v1_1")
+			Expect(err).ToNot(HaveOccurred())
+
+			exists, err = db.CheckIfTableExists(PartitionName("hosts_v1_1", 3))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should set storage parameters", func() {
+			err := db.CreateTable(TestTable, config.DBTableInitScript)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = db.SetStorageParameters(TestTable, map[string]string{"fillfactor": "70", "autovacuum_vacuum_scale_factor": "0.05"})
+			Expect(err).ToNot(HaveOccurred())
+
+			rows, err := db.RunQuery(fmt.Sprintf("SELECT array_to_string(reloptions, ',') FROM pg_catalog.pg_class WHERE oid = 'inventory.%s'::regclass", TestTable))
+			Expect(err).ToNot(HaveOccurred())
+			options, err := scanStrings(rows)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options).To(Equal([]string{"autovacuum_vacuum_scale_factor=0.05,fillfactor=70"}))
+		})
+
+		It("should support case-insensitive searches of display_name", func() {
+			Expect(db.EnsureExtension("citext")).To(Succeed())
+
+			db.DisplayNameSearch = DisplayNameSearchCitext
+			Expect(db.CreateTable(TestTable, config.DBTableInitScript)).To(Succeed())
+			_, err := db.Exec(fmt.Sprintf("INSERT INTO inventory.%s (id, display_name, tags, updated, created, stale_timestamp, system_profile, reporter, per_reporter_staleness) VALUES ('%s', 'Host.Example.com', '{}', now(), now(), now(), '{}', 'puptoo', '{}')", TestTable, "5ae6d4c9-4cfe-4e05-bc1c-3d2aa80ea6a1"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(db.countHosts(fmt.Sprintf("SELECT count(*) FROM inventory.%s WHERE display_name = 'host.example.com'", TestTable))).To(Equal(int64(1)))
+
+			db.DisplayNameSearch = DisplayNameSearchLowerIndex
+			Expect(db.CreateIndexes(TestTable, config.DBTableIndexSQL)).To(Succeed())
+
+			rows, err := db.RunQuery(fmt.Sprintf("SELECT indexname FROM pg_catalog.pg_indexes WHERE schemaname = 'inventory' AND indexname = '%s_display_name_lower_index'", TestTable))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scanStrings(rows)).To(HaveLen(1))
+		})
+
+		It("should return the primary key of a table", func() {
+			err := db.CreateTable(TestTable, config.DBTableInitScript)
+			Expect(err).ToNot(HaveOccurred())
+
+			primaryKey, err := db.GetPrimaryKey(TestTable)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(primaryKey).To(Equal([]string{"id"}))
+
+			_, err = db.Exec("CREATE TABLE inventory.hosts_v1_2 (id uuid, org_id character varying(36), PRIMARY KEY (org_id, id))")
+			Expect(err).ToNot(HaveOccurred())
+
+			primaryKey, err = db.GetPrimaryKey("hosts_v1_2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(primaryKey).To(Equal([]string{"id", "org_id"}))
+		})
+
+		It("should adopt the legacy hosts table", func() {
+			legacy, err := db.IsLegacyHostsTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(legacy).To(BeFalse())
+
+			err = db.CreateTable("hosts", config.DBTableInitScript)
+			Expect(err).ToNot(HaveOccurred())
+
+			legacy, err = db.IsLegacyHostsTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(legacy).To(BeTrue())
+
+			missing, err := db.GetMissingViewColumns("hosts")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(BeEmpty())
+
+			err = db.AdoptLegacyHostsTable(TestTable)
+			Expect(err).ToNot(HaveOccurred())
+
+			legacy, err = db.IsLegacyHostsTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(legacy).To(BeFalse())
+
+			table, err := db.GetCurrentTable()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*table).To(Equal(TestTable))
+		})
+
+		It("should list columns missing for the view", func() {
+			_, err := db.Exec("CREATE TABLE inventory.hosts (id uuid PRIMARY KEY, account character varying(10), display_name character varying(200), tags jsonb)")
+			Expect(err).ToNot(HaveOccurred())
+
+			missing, err := db.GetMissingViewColumns("hosts")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(Equal([]string{"created", "updated", "stale_timestamp", "system_profile", "insights_id", "reporter", "per_reporter_staleness", "org_id", "groups"}))
+		})
+
+		It("should clone a table", func() {
+			err := db.CreateTable("hosts_v1_1", config.DBTableInitScript)
+			Expect(err).ToNot(HaveOccurred())
+
+			// a source table created using an older schema lacking some of the columns
+			_, err = db.Exec("CREATE TABLE inventory.hosts_v1_2 (id uuid PRIMARY KEY, account character varying(10), org_id character varying(36), display_name character varying(200) NOT NULL, tags jsonb NOT NULL, updated timestamp with time zone NOT NULL, created timestamp with time zone NOT NULL, stale_timestamp timestamp with time zone NOT NULL, system_profile jsonb NOT NULL, reporter character varying(255) NOT NULL, per_reporter_staleness jsonb NOT NULL, obsolete integer)")
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, id := range []string{"3b8c0b37-6a4b-4f4d-9b0e-1d1c5c8e1e01", "3b8c0b37-6a4b-4f4d-9b0e-1d1c5c8e1e02"} {
+				_, err = db.Exec(fmt.Sprintf(`INSERT INTO inventory.hosts_v1_2 (id, account, org_id, display_name, tags, updated, created, stale_timestamp, system_profile, reporter, per_reporter_staleness) VALUES ('%s', '000001', 'test01', 'test01', '{}', NOW(), NOW(), NOW(), '{}', 'puptoo', '{}')`, id))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			count, err := db.CloneTable("hosts_v1_1", "hosts_v1_2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(int64(2)))
+
+			count, err = db.CountHosts("inventory.hosts_v1_1", false, []map[string]string{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(int64(2)))
+		})
+
+		It("should grant SELECT to a created role", func() {
+			err := db.CreateTable(TestTable, config.DBTableInitScript)
+			Expect(err).ToNot(HaveOccurred())
+
+			// creating the role again is a noop
+			for i := 0; i < 2; i++ {
+				Expect(db.CreateRole("cyndi_test_reader")).To(Succeed())
+			}
+
+			err = db.GrantSelect([]string{"cyndi_test_reader"}, TestTable)
+			Expect(err).ToNot(HaveOccurred())
+
+			rows, err := db.RunQuery(fmt.Sprintf("SELECT has_table_privilege('cyndi_test_reader', 'inventory.%s', 'SELECT')", TestTable))
+			Expect(err).ToNot(HaveOccurred())
+
+			var granted bool
+			rows.Next()
+			Expect(rows.Scan(&granted)).To(Succeed())
+			rows.Close()
+			Expect(granted).To(BeTrue())
+		})
+
+		It("should bootstrap the database", func() {
+			_, err := db.Exec(`DROP SCHEMA "inventory" CASCADE`)
+			Expect(err).ToNot(HaveOccurred())
+
+			// bootstrapping again is a noop
+			for i := 0; i < 2; i++ {
+				Expect(db.Bootstrap([]string{"pg_trgm"})).To(Succeed())
+			}
+
+			rows, err := db.RunQuery(`SELECT
+				EXISTS (SELECT FROM pg_catalog.pg_namespace WHERE nspname = 'inventory'),
+				EXISTS (SELECT FROM pg_catalog.pg_extension WHERE extname = 'pg_trgm'),
+				has_schema_privilege('cyndi_admin', 'inventory', 'CREATE'),
+				has_schema_privilege('cyndi_reader', 'inventory', 'USAGE')`)
+			Expect(err).ToNot(HaveOccurred())
+
+			var schema, extension, admin, reader bool
+			rows.Next()
+			Expect(rows.Scan(&schema, &extension, &admin, &reader)).To(Succeed())
+			rows.Close()
+			Expect([]bool{schema, extension, admin, reader}).To(Equal([]bool{true, true, true, true}))
+		})
+
+		It("should record audit records", func() {
+			for _, target := range []string{"hosts_v1_1", "hosts_v1_2"} {
+				err := db.RecordAudit(AuditRecord{
+					Timestamp: time.Now(),
+					Pipeline:  "test/advisor",
+					Action:    "TableDropped",
+					Target:    target,
+					Reason:    "it's stale",
+				})
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			rows, err := db.RunQuery("SELECT target, reason FROM inventory.cyndi_audit ORDER BY id")
+			Expect(err).ToNot(HaveOccurred())
+
+			var targets []string
+			for rows.Next() {
+				var target, reason string
+				Expect(rows.Scan(&target, &reason)).To(Succeed())
+				Expect(reason).To(Equal("it's stale"))
+				targets = append(targets, target)
+			}
+			rows.Close()
+
+			Expect(targets).To(Equal([]string{"hosts_v1_1", "hosts_v1_2"}))
+		})
+
+		It("should not allow audit records to be deleted", func() {
+			err := db.RecordAudit(AuditRecord{Timestamp: time.Now(), Pipeline: "test/advisor", Action: "TableDropped", Target: "hosts_v1_1", Reason: "stale"})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = db.Exec("DELETE FROM inventory.cyndi_audit")
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
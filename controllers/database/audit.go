@@ -0,0 +1,67 @@
+package database
+
+import (
+	"time"
+)
+
+/*
+
+Append-only audit trail of destructive actions (table drops, view replacements, connector deletions) taken by the operator.
+
+*/
+
+var auditTableName = AppTable("cyndi_audit")
+
+const auditTableDefinition = `
+CREATE TABLE IF NOT EXISTS inventory.cyndi_audit (
+	id bigserial PRIMARY KEY,
+	created timestamptz NOT NULL,
+	pipeline varchar(255) NOT NULL,
+	action varchar(64) NOT NULL,
+	target varchar(255) NOT NULL,
+	reason text NOT NULL
+);
+`
+
+// Creates the audit table if needed. UPDATE and DELETE are rejected by a trigger so that records cannot be altered.
+const auditTableScript = auditTableDefinition + `
+
+CREATE OR REPLACE FUNCTION inventory.cyndi_audit_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'cyndi_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER cyndi_audit_append_only BEFORE UPDATE OR DELETE ON inventory.cyndi_audit
+	FOR EACH ROW EXECUTE PROCEDURE inventory.cyndi_audit_append_only();
+`
+
+type AuditRecord struct {
+	Timestamp time.Time
+	// namespace/name of the CyndiPipeline
+	Pipeline string
+	Action   string
+	// name of the table, view or connector affected
+	Target string
+	Reason string
+}
+
+func (db *AppDatabase) RecordAudit(record AuditRecord) error {
+	exists, err := db.CheckIfTableExists("cyndi_audit")
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if _, err = db.Exec(db.Dialect.AuditTableScript()); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.exec(
+		"INSERT INTO "+auditTableName+" (created, pipeline, action, target, reason) VALUES ($1, $2, $3, $4, $5)",
+		record.Timestamp, record.Pipeline, record.Action, record.Target, record.Reason,
+	)
+
+	return err
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+)
+
+/*
+
+Authentication plugins for managed PostgreSQL offerings that forbid password authentication (spec.dbAuth).
+Instead of the password of the database secret, the operator connects using an access token of the identity it runs as:
+* azure-ad - a token of the managed identity obtained from the Azure Instance Metadata Service or, with workload identity
+  (AZURE_FEDERATED_TOKEN_FILE), exchanged for the federated token of the service account
+* gcp-iam - a token of the service account obtained from the metadata server (also served by GKE workload identity)
+Tokens are cached until shortly before they expire as every reconcile loop opens connections of its own.
+The sink connector authenticates using the equivalent plugins of the JDBC driver (see connect.setDBValues).
+
+*/
+
+// endpoints issuing the tokens, variables so that tests can replace them
+var (
+	azureIMDSEndpoint   = "http://169.254.169.254/metadata/identity/oauth2/token"
+	azureAuthorityHost  = "https://login.microsoftonline.com/"
+	gcpMetadataEndpoint = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+const (
+	azureDBResource  = "https://ossrdbms-aad.database.windows.net"
+	gcpSQLLoginScope = "https://www.googleapis.com/auth/sqlservice.login"
+	// tokens are renewed this long before they expire
+	tokenExpiryMargin = 5 * time.Minute
+)
+
+var tokenClient = &http.Client{Timeout: 30 * time.Second}
+
+type cachedToken struct {
+	value   string
+	expires time.Time
+}
+
+var tokenCache = struct {
+	lock   sync.Mutex
+	tokens map[config.DBAuthConfiguration]cachedToken
+}{tokens: map[config.DBAuthConfiguration]cachedToken{}}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	// a string in responses of the Azure Instance Metadata Service
+	ExpiresIn json.Number `json:"expires_in"`
+}
+
+// Returns the password the database is connected with
+func dbPassword(params *config.DBParams) (string, error) {
+	switch params.Auth.Type {
+	case "", config.DBAuthPassword:
+		return params.Password, nil
+	case config.DBAuthAzureAD, config.DBAuthGCPIAM:
+		return dbAuthToken(params.Auth)
+	default:
+		return "", fmt.Errorf("Unknown database authentication %s", params.Auth.Type)
+	}
+}
+
+func dbAuthToken(auth config.DBAuthConfiguration) (string, error) {
+	tokenCache.lock.Lock()
+	defer tokenCache.lock.Unlock()
+
+	if token, ok := tokenCache.tokens[auth]; ok && time.Now().Before(token.expires) {
+		return token.value, nil
+	}
+
+	var request *http.Request
+	var err error
+
+	switch auth.Type {
+	case config.DBAuthAzureAD:
+		request, err = azureTokenRequest(auth.ClientID)
+	case config.DBAuthGCPIAM:
+		request, err = gcpTokenRequest()
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	token, err := fetchToken(request)
+	if err != nil {
+		return "", fmt.Errorf("Error obtaining a %s token for the database: %w", auth.Type, err)
+	}
+
+	tokenCache.tokens[auth] = token
+	return token.value, nil
+}
+
+// Uses workload identity if configured, the managed identity of the node otherwise
+func azureTokenRequest(clientID string) (*http.Request, error) {
+	if clientID == "" {
+		clientID = os.Getenv("AZURE_CLIENT_ID")
+	}
+
+	if tokenFile := os.Getenv("AZURE_FEDERATED_TOKEN_FILE"); tokenFile != "" {
+		assertion, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		authority := azureAuthorityHost
+		if host := os.Getenv("AZURE_AUTHORITY_HOST"); host != "" {
+			authority = host
+		}
+
+		form := url.Values{
+			"grant_type":            {"client_credentials"},
+			"client_id":             {clientID},
+			"scope":                 {azureDBResource + "/.default"},
+			"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+			"client_assertion":      {strings.TrimSpace(string(assertion))},
+		}
+
+		endpoint := strings.TrimSuffix(authority, "/") + "/" + os.Getenv("AZURE_TENANT_ID") + "/oauth2/v2.0/token"
+		request, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return request, nil
+	}
+
+	query := url.Values{"api-version": {"2018-02-01"}, "resource": {azureDBResource}}
+	if clientID != "" {
+		query.Set("client_id", clientID)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, azureIMDSEndpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Metadata", "true")
+	return request, nil
+}
+
+func gcpTokenRequest() (*http.Request, error) {
+	request, err := http.NewRequest(http.MethodGet, gcpMetadataEndpoint+"?"+url.Values{"scopes": {gcpSQLLoginScope}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Metadata-Flavor", "Google")
+	return request, nil
+}
+
+func fetchToken(request *http.Request) (token cachedToken, err error) {
+	response, err := tokenClient.Do(request)
+	if err != nil {
+		return token, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		return token, fmt.Errorf("token endpoint responded with %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result tokenResponse
+	if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return token, err
+	}
+
+	if result.AccessToken == "" {
+		return token, fmt.Errorf("token endpoint responded without a token")
+	}
+
+	expiresIn, err := result.ExpiresIn.Int64()
+	if err != nil {
+		return token, fmt.Errorf("invalid token expiry %s: %w", result.ExpiresIn, err)
+	}
+
+	return cachedToken{value: result.AccessToken, expires: time.Now().Add(time.Duration(expiresIn)*time.Second - tokenExpiryMargin)}, nil
+}
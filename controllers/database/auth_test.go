@@ -0,0 +1,65 @@
+package database
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Database authentication", func() {
+	var (
+		requests []*http.Request
+		server   *httptest.Server
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		tokenCache.tokens = map[config.DBAuthConfiguration]cachedToken{}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			// the Azure Instance Metadata Service returns the expiry as a string
+			fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": "3599"}`, len(requests))
+		}))
+
+		azureIMDSEndpoint = server.URL
+		gcpMetadataEndpoint = server.URL
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Uses the password of the secret by default", func() {
+		Expect(dbPassword(&config.DBParams{Password: "secret"})).To(Equal("secret"))
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("Uses an Azure AD token of the managed identity", func() {
+		params := &config.DBParams{Password: "secret", Auth: config.DBAuthConfiguration{Type: config.DBAuthAzureAD, ClientID: "client"}}
+		Expect(dbPassword(params)).To(Equal("token-1"))
+
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Header.Get("Metadata")).To(Equal("true"))
+		Expect(requests[0].URL.Query().Get("resource")).To(Equal(azureDBResource))
+		Expect(requests[0].URL.Query().Get("client_id")).To(Equal("client"))
+
+		// cached until shortly before it expires
+		Expect(dbPassword(params)).To(Equal("token-1"))
+		Expect(requests).To(HaveLen(1))
+	})
+
+	It("Uses a token of the GCP service account", func() {
+		params := &config.DBParams{Auth: config.DBAuthConfiguration{Type: config.DBAuthGCPIAM, CloudSQLInstance: "project:region:instance"}}
+		Expect(dbPassword(params)).To(Equal("token-1"))
+
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Header.Get("Metadata-Flavor")).To(Equal("Google"))
+		Expect(requests[0].URL.Query().Get("scopes")).To(Equal(gcpSQLLoginScope))
+	})
+})
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+
+Digests of blocks of host ids used to validate big tables without transferring all the host ids to the operator.
+A block consists of the hosts whose id starts with a given prefix (of the hexadecimal representation of the uuid).
+The digest of a block is the sum of (60-bit) hashes of its ids. Unlike a hash of the sorted ids, the digests of the shards of a block can be added up.
+
+*/
+
+// Blocks are not split beyond the first group of the uuid so that a block maps to a range of ids
+const MaxIdBlockPrefixLength = 8
+
+type IdBlock struct {
+	Prefix string
+	Count  int64
+	Digest *big.Int
+}
+
+// Host sources able to compute the digests of id blocks
+type BlockHashSource interface {
+	// Returns the digests of the blocks of hosts whose id starts with the given prefix, one for each prefix longer by one character
+	GetIdBlocks(table string, insightsOnly bool, additionalFilters []map[string]string, prefix string) ([]IdBlock, error)
+}
+
+func (block IdBlock) Equal(other IdBlock) bool {
+	return block.Count == other.Count && block.Digest.Cmp(other.Digest) == 0
+}
+
+// Filter selecting the hosts whose id starts with the given prefix. Uses a range of ids so that the primary key index can be used
+func IdPrefixFilter(prefix string) map[string]string {
+	return map[string]string{"where": fmt.Sprintf("id BETWEEN %s AND %s", quoteLiteral(uuidBound(prefix, "0")), quoteLiteral(uuidBound(prefix, "f")))}
+}
+
+// Pads the prefix to a full uuid
+func uuidBound(prefix string, padding string) string {
+	hex := prefix + strings.Repeat(padding, 32-len(prefix))
+	return fmt.Sprintf("%s-%s-%s-%s-%s", hex[0:8], hex[8:12], hex[12:16], hex[16:20], hex[20:32])
+}
+
+func (db *BaseDatabase) idBlockQuery(table string, insightsOnly bool, additionalFilters []map[string]string, prefix string) string {
+	filters := additionalFilters
+	if prefix != "" {
+		filters = append(append([]map[string]string{}, additionalFilters...), IdPrefixFilter(prefix))
+	}
+
+	return fmt.Sprintf(
+		`SELECT substr(id::text, 1, %d) AS block, count(*), sum(('x' || substr(md5(id::text), 1, 15))::bit(60)::bigint)::text FROM %s %s GROUP BY block ORDER BY block`,
+		len(prefix)+1, table, db.getWhereClause(insightsOnly, filters))
+}
+
+func (db *BaseDatabase) GetIdBlocks(table string, insightsOnly bool, additionalFilters []map[string]string, prefix string) ([]IdBlock, error) {
+	if len(prefix) >= MaxIdBlockPrefixLength {
+		return nil, fmt.Errorf("id block prefix %s is too long", prefix)
+	}
+
+	query := db.idBlockQuery(table, insightsOnly, additionalFilters, prefix)
+	defer db.checkSlowQuery(query, time.Now())
+
+	rows, err := db.runQuery(QueryTypeIds, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var blocks []IdBlock
+	for rows.Next() {
+		var block IdBlock
+		var digest string
+
+		if err = rows.Scan(&block.Prefix, &block.Count, &digest); err != nil {
+			return nil, err
+		}
+
+		var ok bool
+		if block.Digest, ok = new(big.Int).SetString(digest, 10); !ok {
+			return nil, fmt.Errorf("invalid digest of id block %s: %s", block.Prefix, digest)
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks, rows.Err()
+}
+
+func (db *ShardedDatabase) GetIdBlocks(table string, insightsOnly bool, additionalFilters []map[string]string, prefix string) ([]IdBlock, error) {
+	var shardBlocks [][]IdBlock
+	for _, shard := range db.Shards {
+		source, ok := shard.(BlockHashSource)
+		if !ok {
+			return nil, fmt.Errorf("shard does not support id blocks")
+		}
+
+		blocks, err := source.GetIdBlocks(table, insightsOnly, additionalFilters, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		shardBlocks = append(shardBlocks, blocks)
+	}
+
+	return MergeIdBlocks(shardBlocks...), nil
+}
+
+// Adds up the blocks of the same prefix. Used for HBI sharded across databases or topics
+func MergeIdBlocks(blockSets ...[]IdBlock) []IdBlock {
+	merged := map[string]*IdBlock{}
+	for _, blocks := range blockSets {
+		for _, block := range blocks {
+			if existing, ok := merged[block.Prefix]; ok {
+				existing.Count += block.Count
+				existing.Digest.Add(existing.Digest, block.Digest)
+			} else {
+				merged[block.Prefix] = &IdBlock{Prefix: block.Prefix, Count: block.Count, Digest: new(big.Int).Set(block.Digest)}
+			}
+		}
+	}
+
+	result := make([]IdBlock, 0, len(merged))
+	for _, block := range merged {
+		result = append(result, *block)
+	}
+
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].Prefix < result[b].Prefix
+	})
+
+	return result
+}
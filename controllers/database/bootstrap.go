@@ -0,0 +1,66 @@
+package database
+
+import (
+	"fmt"
+)
+
+/*
+
+Bootstrap of a new application database (db.bootstrap.enabled), replacing the SQL runbook run when an application is onboarded.
+Everything is created only unless it exists so the bootstrap can be repeated safely.
+
+*/
+
+const (
+	// role granted all privileges on the inventory schema
+	AdminRole = "cyndi_admin"
+	// role granted SELECT on the inventory.hosts view, to be granted to the application
+	ReaderRole = "cyndi_reader"
+)
+
+// Creates the inventory schema, the given extensions and the admin and reader roles unless they exist
+func (db *AppDatabase) Bootstrap(extensions []string) error {
+	statements := []string{fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", QuoteIdentifier(InventorySchema))}
+
+	for _, extension := range extensions {
+		statements = append(statements, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", QuoteIdentifier(extension)))
+	}
+
+	statements = append(statements,
+		db.Dialect.CreateRoleStatement(AdminRole),
+		db.Dialect.CreateRoleStatement(ReaderRole),
+		fmt.Sprintf("GRANT ALL PRIVILEGES ON SCHEMA %s TO %s", QuoteIdentifier(InventorySchema), QuoteIdentifier(AdminRole)),
+		fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", QuoteIdentifier(InventorySchema), QuoteIdentifier(ReaderRole)),
+	)
+
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Creates the extension unless it exists. Creating an extension may require privileges the operator lacks, in which case the extension needs to be created by an administrator
+func (db *AppDatabase) EnsureExtension(extension string) error {
+	rows, err := db.RunQuery("SELECT EXISTS (SELECT FROM pg_catalog.pg_extension WHERE extname = " + quoteLiteral(extension) + ")")
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	rows.Next()
+	err = rows.Scan(&exists)
+	rows.Close()
+
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err = db.Exec(fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", QuoteIdentifier(extension))); err != nil {
+		return fmt.Errorf("Extension %s does not exist in the application database and could not be created: %w", extension, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+
+Short-lived cache of HBI host counts and ids shared by the pipelines validated by the operator.
+Pipelines in different namespaces often validate against the same HBI database using the same filters.
+Results are keyed by the source (database or API) and the query so that these pipelines cost a single set of HBI queries per TTL.
+Ids streamed in chunks (validation.strategy=ids) are not cached as that would defeat the memory budget.
+
+*/
+
+type HostCache struct {
+	ttl     time.Duration
+	lock    sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Returns a cache keeping results for the given duration. Returns nil (no caching) if the duration is not positive
+func NewHostCache(ttl time.Duration) *HostCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &HostCache{ttl: ttl, entries: map[string]cacheEntry{}}
+}
+
+// Makes the source (and its shards) read host counts and ids through the cache
+func UseCache(source HostSource, cache *HostCache) {
+	switch s := source.(type) {
+	case *BaseDatabase:
+		s.cache = cache
+	case *ShardedDatabase:
+		for _, shard := range s.Shards {
+			UseCache(shard, cache)
+		}
+	case *APISource:
+		s.cache = cache
+	}
+}
+
+// Returns the cached value or loads (and caches) it. Errors are not cached
+func (c *HostCache) get(key string, load func() (interface{}, error)) (interface{}, error) {
+	if c == nil {
+		return load()
+	}
+
+	now := time.Now()
+
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	c.lock.Unlock()
+
+	if ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// expired entries are removed on write to keep the cache from growing
+	for existing, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, existing)
+		}
+	}
+
+	c.entries[key] = cacheEntry{value: value, expires: now.Add(c.ttl)}
+	return value, nil
+}
+
+func (c *HostCache) count(key string, load func() (int64, error)) (int64, error) {
+	value, err := c.get(key, func() (interface{}, error) {
+		count, err := load()
+		return count, err
+	})
+
+	if err != nil {
+		return -1, err
+	}
+
+	return value.(int64), nil
+}
+
+// The cached ids are shared by the callers which therefore must not modify them
+func (c *HostCache) ids(key string, load func() ([]string, error)) ([]string, error) {
+	value, err := c.get(key, func() (interface{}, error) {
+		ids, err := load()
+		return ids, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]string), nil
+}
+
+func (db *BaseDatabase) cacheKey(query string) string {
+	return fmt.Sprintf("db:%s:%s/%s:%s", db.Config.Host, db.Config.Port, db.Config.Name, query)
+}
+
+func (s *APISource) cacheKey(operation string, insightsOnly bool) string {
+	return fmt.Sprintf("api:%s:%s:%t", strings.TrimSuffix(s.Params.URL, "/"), operation, insightsOnly)
+}
@@ -1,21 +1,40 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/go-logr/logr"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/RedHatInsights/cyndi-operator/controllers/config"
 	. "github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/faults"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+	"github.com/RedHatInsights/cyndi-operator/controllers/tracing"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
 )
 
 type BaseDatabase struct {
 	Config     *DBParams
 	connection *pgx.Conn
 	Log        logr.Logger
+	// which of the databases (hbi or app) this is, used to label metrics
+	Role string
+	// host counts and ids shared with other pipelines (see UseCache)
+	cache *HostCache
+	// parent context of the spans created for queries
+	ctx context.Context
+	// types of the columns of the host table, those of HBI if not set
+	ColumnTypes config.ColumnTypes
+	// queries running longer have their plan captured (see CaptureSlowQueries)
+	slowQueryThreshold time.Duration
+	slowQueries        []SlowQuery
 }
 
 const connectionStringTemplate = "postgresql://%s:%s@%s:%s/%s?sslmode=%s&sslrootcert=%s"
@@ -24,17 +43,85 @@ func NewBaseDatabase(config *config.DBParams, log logr.Logger) Database {
 	return &BaseDatabase{
 		Config: config,
 		Log:    log,
+		Role:   RoleHBI,
 	}
 }
 
 func (db *BaseDatabase) Connect() (err error) {
-	if db.connection, err = GetConnection(db.Config); err != nil {
-		return fmt.Errorf("Error connecting to %s:%s/%s as %s : %s", db.Config.Host, db.Config.Port, db.Config.Name, db.Config.User, err)
+	err = db.withRetry(func() (err error) {
+		db.connection, err = GetConnection(db.Config)
+		return err
+	})
+
+	if err != nil {
+		metrics.DBError(db.Config.Name, errorTypeConnection)
+		return ConnectionError{fmt.Errorf("Error connecting to %s:%s/%s as %s : %s", db.Config.Host, db.Config.Port, db.Config.Name, db.Config.User, err)}
 	}
 
 	return nil
 }
 
+func (db *BaseDatabase) SetContext(ctx context.Context) {
+	db.ctx = ctx
+}
+
+func (db *BaseDatabase) startSpan(name string, query string) trace.Span {
+	_, span := tracing.Start(db.ctx, name,
+		semconv.DBSystemPostgreSQL,
+		semconv.DBNameKey.String(db.Config.Name),
+		semconv.NetPeerNameKey.String(db.Config.Host),
+		semconv.DBStatementKey.String(RedactCredentials(query)),
+		semconv.DBOperationKey.String(queryOperation(query)),
+	)
+
+	return span
+}
+
+// Roles of the databases used to label metrics
+const (
+	RoleHBI = "hbi"
+	RoleApp = "app"
+)
+
+// Types of queries used to label the query duration metric
+const (
+	QueryTypeCount      = "count"
+	QueryTypeIds        = "ids"
+	QueryTypeDDL        = "ddl"
+	QueryTypeViewSwitch = "view-switch"
+	QueryTypeOther      = "other"
+)
+
+var ddlOperations = []string{"CREATE", "ALTER", "DROP", "GRANT", "ANALYZE", "DO"}
+
+// Derives the type of queries run through RunQuery/Exec. Host counts and ids are labeled by the methods reading them
+func classifyQuery(query string) string {
+	operation := queryOperation(query)
+
+	switch {
+	case strings.Contains(strings.ToUpper(query), " VIEW "):
+		return QueryTypeViewSwitch
+	case utils.ContainsString(ddlOperations, operation):
+		return QueryTypeDDL
+	default:
+		return QueryTypeOther
+	}
+}
+
+// Records the time it took the server to answer (not including reading the rows)
+func (db *BaseDatabase) recordDuration(queryType string, start time.Time) {
+	metrics.DBQuery(db.Role, queryType, time.Since(start))
+}
+
+// Returns the first keyword of the query (e.g. SELECT)
+func queryOperation(query string) string {
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+
+	return ""
+}
+
 func (db *BaseDatabase) Close() error {
 	if db.connection != nil {
 		return db.connection.Close()
@@ -44,32 +131,66 @@ func (db *BaseDatabase) Close() error {
 }
 
 func (db *BaseDatabase) RunQuery(query string) (*pgx.Rows, error) {
-	if db.Log != nil {
-		db.Log.Info("DB Query", "query", query)
-	}
+	return db.runQuery(classifyQuery(query), query)
+}
+
+func (db *BaseDatabase) runQuery(queryType string, query string) (*pgx.Rows, error) {
+	db.logStatement(queryType, query, nil)
 
 	if db.connection == nil {
 		return nil, errors.New("cannot run query because there is no database connection")
 	}
 
-	rows, err := db.connection.Query(query)
+	span := db.startSpan("RunQuery", query)
+	start := time.Now()
+	var rows *pgx.Rows
+	err := db.withRetry(func() (err error) {
+		if err = faults.Check(faults.DBQuery); err != nil {
+			return err
+		}
+
+		rows, err = db.connection.Query(query)
+		db.recordError(err)
+		return err
+	})
+	db.recordDuration(queryType, start)
+	tracing.End(span, err)
 
 	if err != nil {
-		return nil, fmt.Errorf("Error executing query %s, %w", query, err)
+		return nil, fmt.Errorf("Error executing query %s, %w", RedactCredentials(query), err)
 	}
 
 	return rows, nil
 }
 
 func (db *BaseDatabase) Exec(query string) (result pgx.CommandTag, err error) {
+	return db.exec(query)
+}
+
+// Executes a parameterized query ($1, $2, ...)
+func (db *BaseDatabase) exec(query string, args ...interface{}) (result pgx.CommandTag, err error) {
+	db.logStatement(classifyQuery(query), query, args)
+
 	if db.connection == nil {
 		return result, errors.New("cannot run query because there is no database connection")
 	}
 
-	result, err = db.connection.Exec(query)
+	span := db.startSpan("Exec", query)
+	start := time.Now()
+	err = db.withRetry(func() (err error) {
+		if err = faults.Check(faults.DBQuery); err != nil {
+			return err
+		}
+
+		result, err = db.connection.Exec(query, args...)
+		db.recordError(err)
+		return err
+	})
+	db.recordDuration(classifyQuery(query), start)
+	tracing.End(span, err)
 
 	if err != nil {
-		return result, fmt.Errorf("Error executing query %s, %w", query, err)
+		return result, fmt.Errorf("Error executing query %s, %w", RedactCredentials(query), err)
 	}
 
 	return result, nil
@@ -106,7 +227,15 @@ func (db *BaseDatabase) hostCountQuery(table string, insightsOnly bool, addition
 func (db *BaseDatabase) CountHosts(table string, insightsOnly bool, additionalFilters []map[string]string) (int64, error) {
 	// TODO: add modified_on filter
 	// waiting on https://issues.redhat.com/browse/RHCLOUD-9545
-	rows, err := db.RunQuery(db.hostCountQuery(table, insightsOnly, additionalFilters))
+	query := db.hostCountQuery(table, insightsOnly, additionalFilters)
+	return db.cache.count(db.cacheKey(query), func() (int64, error) {
+		return db.countHosts(query)
+	})
+}
+
+func (db *BaseDatabase) countHosts(query string) (int64, error) {
+	defer db.checkSlowQuery(query, time.Now())
+	rows, err := db.runQuery(QueryTypeCount, query)
 
 	if err != nil {
 		return -1, err
@@ -131,14 +260,31 @@ func (db *BaseDatabase) CountHosts(table string, insightsOnly bool, additionalFi
 	return response, err
 }
 
+// Expression host ids are sorted (and compared) by. Ids stored as text are compared bytewise so that they sort like the uuids of HBI
+func (db *BaseDatabase) idSortKey() string {
+	if db.ColumnTypes.Id == config.IdColumnTypeText {
+		return `id::text COLLATE "C"`
+	}
+
+	return "id"
+}
+
 func (db *BaseDatabase) hostIdQuery(table string, insightsOnly bool, additionalFilters []map[string]string) string {
-	return fmt.Sprintf(`SELECT id FROM %s %s ORDER BY id`, table, db.getWhereClause(insightsOnly, additionalFilters))
+	return fmt.Sprintf(`SELECT id FROM %s %s ORDER BY %s`, table, db.getWhereClause(insightsOnly, additionalFilters), db.idSortKey())
 }
 
 func (db *BaseDatabase) GetHostIds(table string, insightsOnly bool, additionalFilters []map[string]string) ([]string, error) {
 	// TODO: add modified_on filter
 	// waiting on https://issues.redhat.com/browse/RHCLOUD-9545
-	rows, err := db.RunQuery(db.hostIdQuery(table, insightsOnly, additionalFilters))
+	query := db.hostIdQuery(table, insightsOnly, additionalFilters)
+	return db.cache.ids(db.cacheKey(query), func() ([]string, error) {
+		return db.getHostIds(query)
+	})
+}
+
+func (db *BaseDatabase) getHostIds(query string) ([]string, error) {
+	defer db.checkSlowQuery(query, time.Now())
+	rows, err := db.runQuery(QueryTypeIds, query)
 
 	var ids []string
 
@@ -177,6 +323,11 @@ func GetConnection(params *DBParams) (connection *pgx.Conn, err error) {
 	if config, err := pgx.ParseConnectionString(connStr); err != nil {
 		return nil, err
 	} else {
+		// the password is replaced with an access token, which is set after parsing as it is not escaped in the connection string
+		if config.Password, err = dbPassword(params); err != nil {
+			return nil, err
+		}
+
 		if connection, err = pgx.Connect(config); err != nil {
 			return nil, err
 		} else {
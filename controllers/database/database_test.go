@@ -1,13 +1,21 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	logr "github.com/go-logr/logr/testing"
+	"math/big"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
 	"github.com/RedHatInsights/cyndi-operator/test"
+	"github.com/jackc/pgx"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -66,6 +74,43 @@ var _ = Describe("Database", func() {
 			rows.Close()
 		})
 
+		It("Drops a replication slot that does not exist", func() {
+			dropped, err := DropReplicationSlot(db, "cyndi_test_1_1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dropped).To(BeTrue())
+		})
+
+		It("Reports a replication slot that does not exist", func() {
+			status, err := GetReplicationSlotStatus(db, "cyndi_test_1_1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Exists).To(BeFalse())
+
+			exists, err := PublicationExists(db, "cyndi_test_1_1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+
+		It("Maintains the heartbeat of a replication slot", func() {
+			Expect(CreateHeartbeatTable(db, "public", "cyndi_heartbeat")).To(Succeed())
+			// the table is left as is if it exists
+			Expect(CreateHeartbeatTable(db, "public", "cyndi_heartbeat")).To(Succeed())
+
+			for n := 0; n < 2; n++ {
+				_, err := db.Exec(HeartbeatQuery("public", "cyndi_heartbeat", "cyndi_test_1_1"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(DeleteHeartbeat(db, "public", "cyndi_heartbeat", "cyndi_test_1_1")).To(Succeed())
+
+			rows, err := db.RunQuery(`SELECT 1 FROM "public"."cyndi_heartbeat"`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rows.Next()).To(BeFalse())
+			rows.Close()
+
+			_, err = db.Exec(`DROP TABLE "public"."cyndi_heartbeat"`)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
 		Describe("Counting hosts", func() {
 			It("Counts all hosts", func() {
 				seedHbiTable(db, TestTable, false, "374e613b-ee69-49e4-b0e8-3886f1f512ef", "56d7bb17-b6f6-40a8-a37b-55432efc990a")
@@ -134,5 +179,394 @@ var _ = Describe("Database", func() {
 				Expect(ids[2]).To(Equal("a77d5711-b670-4ead-97e1-c091624c5f22"))
 			})
 		})
+
+		Describe("Streaming host ids", func() {
+			It("Reads host ids in chunks", func() {
+				seedHbiTable(db, TestTable, false, "a77d5711-b670-4ead-97e1-c091624c5f22", "2c201892-f907-414c-ad85-a455f71a90c0", "8dbfff32-b59e-40e5-b784-bdcbff7d8ac4")
+
+				iterator := db.(HostIdStreamer).StreamHostIds(TestTable, false, []map[string]string{}, 2)
+
+				var ids []string
+				for {
+					id, ok, err := iterator.Next()
+					Expect(err).ToNot(HaveOccurred())
+					if !ok {
+						break
+					}
+
+					ids = append(ids, id)
+				}
+
+				Expect(ids).To(Equal([]string{"2c201892-f907-414c-ad85-a455f71a90c0", "8dbfff32-b59e-40e5-b784-bdcbff7d8ac4", "a77d5711-b670-4ead-97e1-c091624c5f22"}))
+			})
+		})
+
+		Describe("Computing id blocks", func() {
+			It("Computes digests of blocks of host ids", func() {
+				seedHbiTable(db, TestTable, false, "a77d5711-b670-4ead-97e1-c091624c5f22", "a8dbff32-b59e-40e5-b784-bdcbff7d8ac4", "2c201892-f907-414c-ad85-a455f71a90c0")
+
+				blocks, err := db.(BlockHashSource).GetIdBlocks(TestTable, false, []map[string]string{}, "")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(blocks).To(HaveLen(2))
+				Expect(blocks[0].Prefix).To(Equal("2"))
+				Expect(blocks[0].Count).To(Equal(int64(1)))
+				Expect(blocks[1].Prefix).To(Equal("a"))
+				Expect(blocks[1].Count).To(Equal(int64(2)))
+
+				nested, err := db.(BlockHashSource).GetIdBlocks(TestTable, false, []map[string]string{}, "a")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(nested).To(HaveLen(2))
+				Expect(nested[0].Prefix).To(Equal("a7"))
+				Expect(nested[1].Prefix).To(Equal("a8"))
+				Expect(new(big.Int).Add(nested[0].Digest, nested[1].Digest).Cmp(blocks[1].Digest)).To(BeZero())
+
+				ids, err := db.GetHostIds(TestTable, false, []map[string]string{IdPrefixFilter("a7")})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ids).To(Equal([]string{"a77d5711-b670-4ead-97e1-c091624c5f22"}))
+			})
+		})
+
+		Describe("Capturing slow queries", func() {
+			It("Captures the plan of queries running longer than the threshold", func() {
+				seedHbiTable(db, TestTable, false, "374e613b-ee69-49e4-b0e8-3886f1f512ef")
+
+				CaptureSlowQueries(db, time.Nanosecond)
+
+				_, err := db.CountHosts(TestTable, false, []map[string]string{})
+				Expect(err).ToNot(HaveOccurred())
+
+				queries := db.(SlowQuerySource).TakeSlowQueries()
+				Expect(queries).To(HaveLen(1))
+				Expect(queries[0].Query).To(ContainSubstring("SELECT count(*)"))
+				Expect(queries[0].Plan).To(ContainSubstring("Aggregate"))
+				Expect(db.(SlowQuerySource).TakeSlowQueries()).To(BeEmpty())
+			})
+
+			It("Does not capture queries by default", func() {
+				_, err := db.CountHosts(TestTable, false, []map[string]string{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(db.(SlowQuerySource).TakeSlowQueries()).To(BeEmpty())
+			})
+		})
+	})
+})
+
+var _ = DescribeTable("Classifying queries",
+	func(query string, expected string) {
+		Expect(classifyQuery(query)).To(Equal(expected))
+	},
+	Entry("view switch", (&AppDatabase{}).viewDefinition("hosts_v1_1"), QueryTypeViewSwitch),
+	Entry("table creation", "CREATE TABLE inventory.hosts_v1_1 (id uuid PRIMARY KEY)", QueryTypeDDL),
+	Entry("table removal", "DROP table inventory.hosts_v1_1 CASCADE", QueryTypeDDL),
+	Entry("grant", "GRANT SELECT ON inventory.hosts TO cyndi_reader", QueryTypeDDL),
+	Entry("catalog query", "SELECT table_name FROM information_schema.view_table_usage WHERE view_schema = 'inventory'", QueryTypeOther),
+)
+
+var _ = Describe("Column types", func() {
+	It("Sorts ids stored as text like uuids", func() {
+		db := BaseDatabase{}
+		Expect(db.hostIdQuery("inventory.hosts_v1_1", false, nil)).To(Equal("SELECT id FROM inventory.hosts_v1_1  ORDER BY id"))
+
+		db.ColumnTypes.Id = config.IdColumnTypeText
+		Expect(db.hostIdQuery("inventory.hosts_v1_1", false, nil)).To(Equal(`SELECT id FROM inventory.hosts_v1_1  ORDER BY id::text COLLATE "C"`))
+		Expect(db.hostIdChunkQuery("inventory.hosts_v1_1", false, nil, "a", 10)).To(Equal(
+			`SELECT id FROM inventory.hosts_v1_1 WHERE (id::text COLLATE "C" > 'a') ORDER BY id::text COLLATE "C" LIMIT 10`))
+	})
+
+	It("Compares timestamps without a time zone in UTC", func() {
+		db := AppDatabase{Staleness: &config.ViewStalenessConfiguration{Column: "staleness", CulledAfter: 60}}
+		Expect(db.viewDefinition("hosts_v1_1")).To(ContainSubstring("WHEN NOW() < stale_timestamp"))
+
+		db.ColumnTypes.Timestamp = config.TimestampColumnTypeNoTZ
+		Expect(db.viewDefinition("hosts_v1_1")).To(ContainSubstring("WHEN (NOW() AT TIME ZONE 'UTC') < stale_timestamp"))
+	})
+})
+
+var _ = Describe("Merging host id iterators", func() {
+	It("Merges ordered iterators", func() {
+		iterator := MergeHostIdIterators(NewSliceIdIterator([]string{"c", "a", "e"}), NewSliceIdIterator([]string{"b", "f"}), NewSliceIdIterator(nil))
+
+		var ids []string
+		for {
+			id, ok, err := iterator.Next()
+			Expect(err).ToNot(HaveOccurred())
+			if !ok {
+				break
+			}
+
+			ids = append(ids, id)
+		}
+
+		Expect(ids).To(Equal([]string{"a", "b", "c", "e", "f"}))
+	})
+})
+
+var _ = Describe("Merging id blocks", func() {
+	It("Adds up blocks of the same prefix", func() {
+		merged := MergeIdBlocks(
+			[]IdBlock{{Prefix: "a", Count: 1, Digest: big.NewInt(5)}, {Prefix: "c", Count: 2, Digest: big.NewInt(1)}},
+			[]IdBlock{{Prefix: "b", Count: 1, Digest: big.NewInt(3)}, {Prefix: "a", Count: 2, Digest: big.NewInt(7)}},
+		)
+
+		Expect(merged).To(HaveLen(3))
+		Expect(merged[0]).To(Equal(IdBlock{Prefix: "a", Count: 3, Digest: big.NewInt(12)}))
+		Expect(merged[1]).To(Equal(IdBlock{Prefix: "b", Count: 1, Digest: big.NewInt(3)}))
+		Expect(merged[2]).To(Equal(IdBlock{Prefix: "c", Count: 2, Digest: big.NewInt(1)}))
+	})
+})
+
+var _ = Describe("Host counts per organization", func() {
+	It("Adds up counts of the same organization", func() {
+		merged := MergeOrgCounts(map[string]int64{"a": 1, "b": 4}, map[string]int64{"a": 2, "c": 3})
+		Expect(merged).To(Equal(map[string]int64{"a": 3, "b": 4, "c": 3}))
+	})
+
+	It("Selects the organizations with the most hosts", func() {
+		Expect(TopOrgs(map[string]int64{"a": 3, "b": 4, "c": 3, "d": 1}, 3)).To(Equal([]string{"b", "a", "c"}))
+		Expect(TopOrgs(map[string]int64{"a": 3}, 3)).To(Equal([]string{"a"}))
+	})
+
+	It("Counts hosts per reporter", func() {
+		db := BaseDatabase{}
+		Expect(db.reporterCountQuery("public.hosts", false, []map[string]string{{"where": "org_id IS NOT NULL"}})).To(Equal(
+			"SELECT COALESCE(reporter, ''), count(*) FROM public.hosts WHERE (org_id IS NOT NULL) GROUP BY 1"))
+	})
+
+	It("Counts hosts of the given organizations", func() {
+		db := BaseDatabase{}
+		Expect(db.orgCountQuery("public.hosts", false, nil, nil, 10)).To(Equal(
+			"SELECT org_id, count(*) FROM public.hosts WHERE (org_id IS NOT NULL) GROUP BY org_id ORDER BY count(*) DESC, org_id LIMIT 10"))
+		Expect(db.orgCountQuery("public.hosts", false, nil, []string{"a", "b'"}, 10)).To(Equal(
+			"SELECT org_id, count(*) FROM public.hosts WHERE (org_id IN ('a', 'b''')) GROUP BY org_id"))
+	})
+})
+
+var _ = DescribeTable("Classifying errors",
+	func(err error, expected string) {
+		Expect(classifyError(err)).To(Equal(expected))
+	},
+	Entry("connection failure", pgx.PgError{Code: "08006"}, "connection"),
+	Entry("unique violation", pgx.PgError{Code: "23505"}, "integrity"),
+	Entry("undefined table", fmt.Errorf("Error executing query: %w", pgx.PgError{Code: "42P01"}), "syntax"),
+	Entry("query canceled", pgx.PgError{Code: "57014"}, "interrupted"),
+	Entry("internal error", pgx.PgError{Code: "XX000"}, "server"),
+	Entry("client error", errors.New("no connection"), "client"),
+)
+
+var _ = DescribeTable("Redacting credentials",
+	func(query string, expected string) {
+		Expect(RedactCredentials(query)).To(Equal(expected))
+	},
+	Entry("role password", "ALTER ROLE \"cyndi\" WITH PASSWORD 'it''s secret'", "ALTER ROLE \"cyndi\" WITH PASSWORD '<redacted>'"),
+	Entry("connection string", "SELECT dblink_connect('host=db password=secret dbname=hbi')", "SELECT dblink_connect('host=db password=<redacted> dbname=hbi')"),
+	Entry("connection URI", "SELECT dblink_connect('postgresql://cyndi:secret@db/hbi')", "SELECT dblink_connect('postgresql://cyndi:<redacted>@db/hbi')"),
+	Entry("DDL", "CREATE TABLE inventory.hosts_v1_1 (id uuid PRIMARY KEY)", "CREATE TABLE inventory.hosts_v1_1 (id uuid PRIMARY KEY)"),
+)
+
+var _ = DescribeTable("Detecting transient errors",
+	func(err error, expected bool) {
+		Expect(isTransientError(err)).To(Equal(expected))
+	},
+	Entry("serialization failure", pgx.PgError{Code: "40001"}, true),
+	Entry("deadlock", fmt.Errorf("Error executing query: %w", pgx.PgError{Code: "40P01"}), true),
+	Entry("too many connections", pgx.PgError{Code: "53300"}, true),
+	Entry("connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true),
+	Entry("dead connection", pgx.ErrDeadConn, true),
+	Entry("unique violation", pgx.PgError{Code: "23505"}, false),
+	Entry("undefined table", pgx.PgError{Code: "42P01"}, false),
+	Entry("client error", errors.New("no connection"), false),
+)
+
+var _ = Describe("Retrying transient errors", func() {
+	var db *BaseDatabase
+
+	BeforeEach(func() {
+		db = &BaseDatabase{Config: &config.DBParams{Name: "test", Retry: config.DBRetryConfiguration{Attempts: 2, BaseInterval: 1}}}
+	})
+
+	It("Retries until the operation succeeds", func() {
+		attempts := 0
+		err := db.withRetry(func() error {
+			if attempts++; attempts < 3 {
+				return pgx.PgError{Code: "40001"}
+			}
+			return nil
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("Surfaces the error once the retries are exhausted", func() {
+		attempts := 0
+		err := db.withRetry(func() error {
+			attempts++
+			return pgx.PgError{Code: "53300"}
+		})
+
+		Expect(err).To(MatchError(pgx.PgError{Code: "53300"}))
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("Does not retry other errors", func() {
+		attempts := 0
+		err := db.withRetry(func() error {
+			attempts++
+			return pgx.PgError{Code: "23505"}
+		})
+
+		Expect(err).To(HaveOccurred())
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("Randomizes the delay", func() {
+		for retry := int64(0); retry < 4; retry++ {
+			delay := retryDelay(100, retry)
+			Expect(delay).To(BeNumerically(">=", (50*time.Millisecond)<<retry))
+			Expect(delay).To(BeNumerically("<=", (100*time.Millisecond)<<retry))
+		}
+	})
+})
+
+var _ = Describe("Identifiers", func() {
+	It("Quotes schema-qualified table names", func() {
+		Expect(AppTable("hosts_v1_1")).To(Equal(`"inventory"."hosts_v1_1"`))
+		Expect(AppTable(`Hosts"; DROP TABLE hosts; --`)).To(Equal(`"inventory"."Hosts""; DROP TABLE hosts; --"`))
+		Expect(QualifiedTable("public", "hosts")).To(Equal(`"public"."hosts"`))
+		Expect(quoteLiteral("it's")).To(Equal(`'it''s'`))
+	})
+
+	DescribeTable("Validating plain identifiers",
+		func(name string, valid bool) {
+			if valid {
+				Expect(ValidatePlainIdentifier(name)).To(Succeed())
+			} else {
+				Expect(ValidatePlainIdentifier(name)).ToNot(Succeed())
+			}
+		},
+		Entry("table name", "hosts_v1_1615392382", true),
+		Entry("upper case", "Hosts_v1_1", false),
+		Entry("quote", `hosts"`, false),
+		Entry("statement", "hosts; DROP TABLE hosts", false),
+		Entry("leading digit", "1_hosts", false),
+		Entry("too long", strings.Repeat("h", 64), false),
+	)
+})
+
+var _ = Describe("Host cache", func() {
+	It("Shares results until they expire", func() {
+		cache := NewHostCache(50 * time.Millisecond)
+
+		loads := 0
+		load := func() (int64, error) {
+			loads++
+			return 42, nil
+		}
+
+		for n := 0; n < 3; n++ {
+			count, err := cache.count("db:hbi:5432/hbi:SELECT count(*) FROM hosts", load)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(int64(42)))
+		}
+
+		Expect(loads).To(Equal(1))
+
+		_, err := cache.count("db:hbi:5432/hbi:SELECT count(*) FROM hosts WHERE (true)", load)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loads).To(Equal(2))
+
+		time.Sleep(60 * time.Millisecond)
+		_, err = cache.count("db:hbi:5432/hbi:SELECT count(*) FROM hosts", load)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loads).To(Equal(3))
+	})
+
+	It("Does not cache errors", func() {
+		cache := NewHostCache(time.Minute)
+
+		_, err := cache.ids("api:http://hbi:true", func() ([]string, error) {
+			return nil, errors.New("unavailable")
+		})
+		Expect(err).To(HaveOccurred())
+
+		ids, err := cache.ids("api:http://hbi:true", func() ([]string, error) {
+			return []string{"a"}, nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids).To(Equal([]string{"a"}))
+	})
+
+	It("Is disabled by a zero TTL", func() {
+		Expect(NewHostCache(0)).To(BeNil())
+
+		var cache *HostCache
+		count, err := cache.count("key", func() (int64, error) { return 1, nil })
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(int64(1)))
+	})
+})
+
+var _ = Describe("In-memory database", func() {
+	var db *MemoryDatabase
+
+	BeforeEach(func() {
+		db = NewMemoryDatabase()
+		db.AddHosts(`"public"."hosts"`,
+			MemoryHost{ID: "3b8c0b5e-5ca4-4e64-a8b4-1a7d1d3b4c5e", OrgID: "org1", InsightsID: "7597d33e-a1a6-4fda-ad1e-b86b73c722fd"},
+			MemoryHost{ID: "1f9a7e1c-2d7b-4e4f-9c1e-0b6f8e6c2a1d", OrgID: "org1"},
+			MemoryHost{ID: "a2c4e6f8-0b1d-4f3a-8c5e-7d9f1b3a5c7e", OrgID: "org2"},
+		)
+	})
+
+	It("Counts and lists hosts", func() {
+		count, err := db.CountHosts(`"public"."hosts"`, false, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(int64(3)))
+
+		count, err = db.CountHosts(`"public"."hosts"`, true, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(int64(1)))
+
+		ids, err := db.GetHostIds(`"public"."hosts"`, false, []map[string]string{IdPrefixFilter("1")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids).To(Equal([]string{"1f9a7e1c-2d7b-4e4f-9c1e-0b6f8e6c2a1d"}))
+
+		counts, err := db.CountHostsByOrg(`"public"."hosts"`, false, nil, nil, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(counts).To(Equal(map[string]int64{"org1": 2}))
+	})
+
+	It("Computes the digests of id blocks", func() {
+		blocks, err := db.GetIdBlocks(`"public"."hosts"`, false, nil, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blocks).To(HaveLen(3))
+		Expect(blocks[0].Prefix).To(Equal("1"))
+		Expect(blocks[0].Count).To(Equal(int64(1)))
+	})
+
+	It("Points the hosts view to a table", func() {
+		Expect(db.CreateTable("hosts_v1_1", "")).To(Succeed())
+		Expect(db.CloneTable("hosts_v1_1", `"public"."hosts"`)).To(Equal(int64(3)))
+		Expect(db.UpdateView("hosts_v1_1")).To(Succeed())
+
+		table, err := db.GetCurrentTable()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*table).To(Equal("hosts_v1_1"))
+		Expect(db.VerifyView()).To(Equal(int64(3)))
+		Expect(db.Grants(hostsView)).To(Equal([]string{ReaderRole}))
+
+		Expect(db.DeleteTable("hosts_v1_1")).To(Succeed())
+		table, err = db.GetCurrentTable()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(table).To(BeNil())
+	})
+
+	It("Fails operations on demand", func() {
+		db.Fail("CreateTable", errors.New("disk full"))
+		Expect(db.CreateTable("hosts_v1_1", "")).To(MatchError("disk full"))
+
+		db.Fail("CreateTable", nil)
+		Expect(db.CreateTable("hosts_v1_1", "")).To(Succeed())
+		Expect(db.Calls()).To(Equal([]string{"CreateTable", "CreateTable"}))
 	})
 })
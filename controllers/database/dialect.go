@@ -0,0 +1,96 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+)
+
+/*
+
+SQL dialects of the application database. CockroachDB speaks the PostgreSQL wire protocol, however its catalog differs and it lacks some features (e.g. triggers).
+Queries that differ between the databases are defined by the dialect, everything else is shared.
+
+*/
+
+type Dialect interface {
+	// Query returning the name of the table the given view (of the inventory schema) selects from
+	CurrentTableQuery(view string) string
+	// Query returning the names of the hosts_* tables (excluding partitions)
+	CyndiTablesQuery() string
+	// Query returning the (sorted) columns of the primary key of the given table
+	PrimaryKeyQuery(tableName string) string
+	// Script creating the audit table
+	AuditTableScript() string
+	// Statement creating the given (NOLOGIN) role unless it exists
+	CreateRoleStatement(role string) string
+}
+
+func GetDialect(name config.DBDialect) Dialect {
+	if name == config.DBDialectCockroach {
+		return cockroachDialect{}
+	}
+
+	return postgresDialect{}
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) CurrentTableQuery(view string) string {
+	return fmt.Sprintf("SELECT table_name FROM information_schema.view_table_usage WHERE view_schema = 'inventory' AND view_name = %s LIMIT 1;", quoteLiteral(view))
+}
+
+func (postgresDialect) CyndiTablesQuery() string {
+	// partitions of partitioned tables are dropped together with their parent and are therefore not listed
+	return `SELECT c.relname FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'inventory' AND c.relkind IN ('r', 'p') AND NOT c.relispartition AND c.relname LIKE 'hosts_%' ORDER BY c.relname`
+}
+
+func (postgresDialect) PrimaryKeyQuery(tableName string) string {
+	return fmt.Sprintf(`SELECT a.attname FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = %s::regclass AND i.indisprimary
+		ORDER BY a.attname`,
+		quoteLiteral(AppTable(tableName)))
+}
+
+func (postgresDialect) AuditTableScript() string {
+	return auditTableScript
+}
+
+// CREATE ROLE does not support IF NOT EXISTS
+func (postgresDialect) CreateRoleStatement(role string) string {
+	return fmt.Sprintf(`DO $$ BEGIN
+		IF NOT EXISTS (SELECT FROM pg_catalog.pg_roles WHERE rolname = %s) THEN CREATE ROLE %s NOLOGIN; END IF;
+	END $$`, quoteLiteral(role), QuoteIdentifier(role))
+}
+
+type cockroachDialect struct{}
+
+// information_schema.view_table_usage is not populated by CockroachDB
+func (cockroachDialect) CurrentTableQuery(view string) string {
+	return fmt.Sprintf(`SELECT regexp_extract(view_definition, 'hosts_[a-z0-9_]+') FROM information_schema.views
+		WHERE table_schema = 'inventory' AND table_name = %s LIMIT 1;`, quoteLiteral(view))
+}
+
+func (cockroachDialect) CyndiTablesQuery() string {
+	return `SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'inventory' AND table_type = 'BASE TABLE' AND table_name LIKE 'hosts_%' ORDER BY table_name`
+}
+
+func (cockroachDialect) PrimaryKeyQuery(tableName string) string {
+	return fmt.Sprintf(`SELECT k.column_name FROM information_schema.table_constraints c
+		JOIN information_schema.key_column_usage k ON k.constraint_schema = c.constraint_schema AND k.constraint_name = c.constraint_name AND k.table_name = c.table_name
+		WHERE c.table_schema = 'inventory' AND c.table_name = %s AND c.constraint_type = 'PRIMARY KEY'
+		ORDER BY k.column_name`,
+		quoteLiteral(tableName))
+}
+
+// triggers are not available so the audit table is not protected against modifications
+func (cockroachDialect) AuditTableScript() string {
+	return auditTableDefinition
+}
+
+func (cockroachDialect) CreateRoleStatement(role string) string {
+	return fmt.Sprintf("CREATE ROLE IF NOT EXISTS %s NOLOGIN", QuoteIdentifier(role))
+}
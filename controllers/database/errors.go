@@ -0,0 +1,71 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+)
+
+// Categories of database errors reported by the cyndi_db_errors_total metric
+const (
+	errorTypeConnection  = "connection"
+	errorTypeIntegrity   = "integrity"
+	errorTypeSyntax      = "syntax"
+	errorTypeResources   = "resources"
+	errorTypeInterrupted = "interrupted"
+	errorTypeServer      = "server"
+	errorTypeClient      = "client"
+)
+
+// SQLSTATE classes (first two characters of the code) mapped to error categories
+var sqlStateClasses = map[string]string{
+	"08": errorTypeConnection,
+	"23": errorTypeIntegrity,
+	"42": errorTypeSyntax,
+	"53": errorTypeResources,
+	"57": errorTypeInterrupted,
+}
+
+// Returns the category of the given error based on its SQLSTATE code
+func classifyError(err error) string {
+	var pgErr pgx.PgError
+	if !errors.As(err, &pgErr) {
+		return errorTypeClient
+	}
+
+	if len(pgErr.Code) >= 2 {
+		if errorType, ok := sqlStateClasses[pgErr.Code[:2]]; ok {
+			return errorType
+		}
+	}
+
+	return errorTypeServer
+}
+
+// Error establishing a connection to a database (or an API serving as a source of hosts)
+type ConnectionError struct {
+	error
+}
+
+func (e ConnectionError) Unwrap() error {
+	return e.error
+}
+
+// Determines whether the error was caused by an unavailable database
+func IsConnectionError(err error) bool {
+	var connectionErr ConnectionError
+	if errors.As(err, &connectionErr) {
+		return true
+	}
+
+	var pgErr pgx.PgError
+	return errors.As(err, &pgErr) && classifyError(pgErr) == errorTypeConnection
+}
+
+func (db *BaseDatabase) recordError(err error) {
+	if err != nil {
+		metrics.DBError(db.Config.Name, classifyError(err))
+	}
+}
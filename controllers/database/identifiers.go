@@ -0,0 +1,53 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx"
+)
+
+/*
+
+Identifiers and literals of the statements built by the operator.
+Names of tables, schemas, roles and columns are always quoted (and schema-qualified where applicable) so that they neither depend on the search_path nor can inject SQL.
+Table names are also rendered into user-provided scripts (db.schema, db.table.index.sql) where they cannot be quoted. These are checked to be plain lower-case identifiers.
+
+*/
+
+// Schema of the tables and the view maintained by the operator in the application database
+const InventorySchema = "inventory"
+
+// PostgreSQL truncates longer identifiers
+const maxIdentifierLength = 63
+
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Returns the quoted identifier, e.g. "hosts_v1_1"
+func QuoteIdentifier(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// Returns the quoted schema-qualified name of a table, e.g. "public"."hosts"
+func QualifiedTable(schema string, name string) string {
+	return pgx.Identifier{schema, name}.Sanitize()
+}
+
+// Returns the quoted name of a table (or view) of the inventory schema of the application database
+func AppTable(name string) string {
+	return QualifiedTable(InventorySchema, name)
+}
+
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// Checks that the name can be used unquoted, i.e. it keeps its case and cannot inject SQL
+func ValidatePlainIdentifier(name string) error {
+	if len(name) > maxIdentifierLength || !plainIdentifier.MatchString(name) {
+		return fmt.Errorf("%s is not a valid identifier", name)
+	}
+
+	return nil
+}
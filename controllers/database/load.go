@@ -0,0 +1,49 @@
+package database
+
+/*
+
+Load of the HBI database, checked before validation runs its heavy queries so that validation can be postponed while HBI is under pressure.
+
+*/
+
+// Host sources able to report their load
+type LoadSource interface {
+	// Returns the number of queries (other than the operator's own) currently running
+	ActiveQueries() (int64, error)
+}
+
+const activeQueriesQuery = "SELECT count(*) FROM pg_stat_activity WHERE state = 'active' AND pid <> pg_backend_pid()"
+
+func (db *BaseDatabase) ActiveQueries() (count int64, err error) {
+	rows, err := db.RunQuery(activeQueriesQuery)
+	if err != nil {
+		return -1, err
+	}
+
+	defer rows.Close()
+
+	rows.Next()
+	err = rows.Scan(&count)
+	return
+}
+
+// The busiest shard determines the load
+func (db *ShardedDatabase) ActiveQueries() (max int64, err error) {
+	for _, shard := range db.Shards {
+		source, ok := shard.(LoadSource)
+		if !ok {
+			continue
+		}
+
+		count, err := source.ActiveQueries()
+		if err != nil {
+			return -1, err
+		}
+
+		if count > max {
+			max = count
+		}
+	}
+
+	return max, nil
+}
@@ -0,0 +1,870 @@
+package database
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+In-memory database used by unit tests of the reconciliation in place of the application database and HBI.
+Tables only hold the ids (and the org_id and insights_id) of hosts. Scripts and statements are not run - CreateTable creates an empty table regardless of db.schema.
+Any operation can be made to fail (see Fail) so that every transition of a pipeline, including the handling of each failure, can be tested without a live PostgreSQL.
+
+*/
+
+var errMemoryStatement = errors.New("statements cannot be run against an in-memory database")
+
+// the only additional filter understood by the in-memory database (see IdPrefixFilter)
+var idRangeFilter = regexp.MustCompile(`^id BETWEEN '([0-9a-f-]+)' AND '([0-9a-f-]+)'$`)
+
+type MemoryHost struct {
+	ID    string
+	OrgID string
+	// hosts with an insights_id pass the insightsOnly filter
+	InsightsID string
+	Reporter   string
+}
+
+type MemoryDatabase struct {
+	lock sync.Mutex
+
+	// hosts of each table, keyed by the unqualified table name
+	tables map[string][]MemoryHost
+	// tables the views point to, keyed by the unqualified view name
+	views map[string]string
+	// view maintained by the view operations (see SetView)
+	view string
+	// pipelines owning the tables (see RegisterTable)
+	owners map[string]string
+	// rows of the cyndi_metadata table, keyed by pipeline
+	metadata map[string]PipelineMetadata
+	// tables (and the inventory.hosts view) and the roles granted SELECT on them
+	grants       map[string][]string
+	roles        map[string]bool
+	bootstrapped bool
+	audit        []AuditRecord
+	connected    bool
+
+	failures map[string]error
+	calls    []string
+
+	ServerStartTime time.Time
+}
+
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{
+		tables:          map[string][]MemoryHost{},
+		views:           map[string]string{},
+		view:            DefaultView,
+		owners:          map[string]string{},
+		metadata:        map[string]PipelineMetadata{},
+		grants:          map[string][]string{},
+		roles:           map[string]bool{},
+		failures:        map[string]error{},
+		ServerStartTime: time.Now(),
+	}
+}
+
+// Makes the given operation (the name of the method, e.g. CreateTable) fail with the given error. A nil error makes the operation succeed again
+func (db *MemoryDatabase) Fail(operation string, err error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err == nil {
+		delete(db.failures, operation)
+	} else {
+		db.failures[operation] = err
+	}
+}
+
+// Returns the operations called so far, in order
+func (db *MemoryDatabase) Calls() []string {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return append([]string{}, db.calls...)
+}
+
+// Adds hosts to the given table, creating the table if needed
+func (db *MemoryDatabase) AddHosts(table string, hosts ...MemoryHost) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	name := memoryTableName(table)
+	db.tables[name] = append(db.tables[name], hosts...)
+}
+
+// Removes the hosts with the given ids from the given table
+func (db *MemoryDatabase) RemoveHosts(table string, ids ...string) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	name := memoryTableName(table)
+	var remaining []MemoryHost
+
+	for _, host := range db.tables[name] {
+		if !utils.ContainsString(ids, host.ID) {
+			remaining = append(remaining, host)
+		}
+	}
+
+	db.tables[name] = remaining
+}
+
+// Returns the roles granted SELECT on the given table (or the hosts view)
+func (db *MemoryDatabase) Grants(table string) []string {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return append([]string{}, db.grants[memoryTableName(table)]...)
+}
+
+// Returns the audit records written so far
+func (db *MemoryDatabase) AuditRecords() []AuditRecord {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return append([]AuditRecord{}, db.audit...)
+}
+
+func (db *MemoryDatabase) IsBootstrapped() bool {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.bootstrapped
+}
+
+func (db *MemoryDatabase) IsConnected() bool {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.connected
+}
+
+// Records the call of the operation and returns the error it is set to fail with. Must be called with the lock held
+func (db *MemoryDatabase) call(operation string) error {
+	db.calls = append(db.calls, operation)
+	return db.failures[operation]
+}
+
+// "inventory"."hosts_v1_1" and hosts_v1_1 both refer to the hosts_v1_1 table
+func memoryTableName(table string) string {
+	table = strings.ReplaceAll(table, `"`, "")
+	return strings.TrimPrefix(table, InventorySchema+".")
+}
+
+func relationNotFound(table string) error {
+	return fmt.Errorf(`relation "%s" does not exist`, table)
+}
+
+// Returns the hosts of the given table (or the table the given view points to). Must be called with the lock held
+func (db *MemoryDatabase) hosts(table string, insightsOnly bool, additionalFilters []map[string]string) ([]MemoryHost, error) {
+	name := memoryTableName(table)
+
+	if viewTable, isView := db.views[name]; isView {
+		name = viewTable
+	}
+
+	hosts, exists := db.tables[name]
+	if !exists {
+		return nil, relationNotFound(table)
+	}
+
+	var result []MemoryHost
+	for _, host := range hosts {
+		if insightsOnly && host.InsightsID == "" {
+			continue
+		}
+
+		matches, err := matchesFilters(host, additionalFilters)
+		if err != nil {
+			return nil, err
+		} else if matches {
+			result = append(result, host)
+		}
+	}
+
+	return result, nil
+}
+
+func matchesFilters(host MemoryHost, additionalFilters []map[string]string) (bool, error) {
+	for _, filter := range additionalFilters {
+		bounds := idRangeFilter.FindStringSubmatch(filter["where"])
+		if bounds == nil {
+			return false, fmt.Errorf("filter %s is not supported by the in-memory database", filter["where"])
+		}
+
+		if host.ID < bounds[1] || host.ID > bounds[2] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func sortedIds(hosts []MemoryHost) []string {
+	ids := make([]string, len(hosts))
+	for n, host := range hosts {
+		ids[n] = host.ID
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+// Must be called with the lock held
+func (db *MemoryDatabase) requireTable(tableName string) error {
+	if _, exists := db.tables[memoryTableName(tableName)]; !exists {
+		return relationNotFound(AppTable(tableName))
+	}
+
+	return nil
+}
+
+func (db *MemoryDatabase) Connect() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("Connect"); err != nil {
+		return err
+	}
+
+	db.connected = true
+	return nil
+}
+
+func (db *MemoryDatabase) Close() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.connected = false
+	return db.call("Close")
+}
+
+func (db *MemoryDatabase) SetContext(ctx context.Context) {}
+
+func (db *MemoryDatabase) RunQuery(query string) (*pgx.Rows, error) {
+	return nil, errMemoryStatement
+}
+
+func (db *MemoryDatabase) Exec(query string) (result pgx.CommandTag, err error) {
+	return result, errMemoryStatement
+}
+
+func (db *MemoryDatabase) CountHosts(table string, insightsOnly bool, additionalFilters []map[string]string) (int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("CountHosts"); err != nil {
+		return -1, err
+	}
+
+	hosts, err := db.hosts(table, insightsOnly, additionalFilters)
+	if err != nil {
+		return -1, err
+	}
+
+	return int64(len(hosts)), nil
+}
+
+func (db *MemoryDatabase) GetHostIds(table string, insightsOnly bool, additionalFilters []map[string]string) ([]string, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("GetHostIds"); err != nil {
+		return nil, err
+	}
+
+	hosts, err := db.hosts(table, insightsOnly, additionalFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedIds(hosts), nil
+}
+
+func (db *MemoryDatabase) StreamHostIds(table string, insightsOnly bool, additionalFilters []map[string]string, chunkSize int) HostIdIterator {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("StreamHostIds"); err != nil {
+		return &errorIdIterator{err: err}
+	}
+
+	hosts, err := db.hosts(table, insightsOnly, additionalFilters)
+	if err != nil {
+		return &errorIdIterator{err: err}
+	}
+
+	return NewSliceIdIterator(sortedIds(hosts))
+}
+
+func (db *MemoryDatabase) GetIdBlocks(table string, insightsOnly bool, additionalFilters []map[string]string, prefix string) ([]IdBlock, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("GetIdBlocks"); err != nil {
+		return nil, err
+	}
+
+	if len(prefix) >= MaxIdBlockPrefixLength {
+		return nil, fmt.Errorf("id block prefix %s is too long", prefix)
+	}
+
+	hosts, err := db.hosts(table, insightsOnly, additionalFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := map[string]*IdBlock{}
+	for _, host := range hosts {
+		if !strings.HasPrefix(host.ID, prefix) || len(host.ID) <= len(prefix) {
+			continue
+		}
+
+		blockPrefix := host.ID[:len(prefix)+1]
+		if blocks[blockPrefix] == nil {
+			blocks[blockPrefix] = &IdBlock{Prefix: blockPrefix, Digest: new(big.Int)}
+		}
+
+		hash, err := idHash(host.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks[blockPrefix].Count++
+		blocks[blockPrefix].Digest.Add(blocks[blockPrefix].Digest, hash)
+	}
+
+	result := make([]IdBlock, 0, len(blocks))
+	for _, block := range blocks {
+		result = append(result, *block)
+	}
+
+	sort.Slice(result, func(a, b int) bool { return result[a].Prefix < result[b].Prefix })
+	return result, nil
+}
+
+// The 60-bit hash of an id computed by the database (see idBlockQuery)
+func idHash(id string) (*big.Int, error) {
+	sum := md5.Sum([]byte(id))
+	value, err := strconv.ParseUint(hex.EncodeToString(sum[:])[:15], 16, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetUint64(value), nil
+}
+
+func (db *MemoryDatabase) CountHostsByOrg(table string, insightsOnly bool, additionalFilters []map[string]string, orgIds []string, limit int64) (map[string]int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("CountHostsByOrg"); err != nil {
+		return nil, err
+	}
+
+	hosts, err := db.hosts(table, insightsOnly, additionalFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{}
+	for _, host := range hosts {
+		if host.OrgID != "" && (len(orgIds) == 0 || utils.ContainsString(orgIds, host.OrgID)) {
+			counts[host.OrgID]++
+		}
+	}
+
+	if len(orgIds) > 0 {
+		return counts, nil
+	}
+
+	top := map[string]int64{}
+	for _, orgId := range TopOrgs(counts, limit) {
+		top[orgId] = counts[orgId]
+	}
+
+	return top, nil
+}
+
+func (db *MemoryDatabase) CountHostsByReporter(table string, insightsOnly bool, additionalFilters []map[string]string) (map[string]int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("CountHostsByReporter"); err != nil {
+		return nil, err
+	}
+
+	hosts, err := db.hosts(table, insightsOnly, additionalFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{}
+	for _, host := range hosts {
+		counts[host.Reporter]++
+	}
+
+	return counts, nil
+}
+
+func (db *MemoryDatabase) Bootstrap(extensions []string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("Bootstrap"); err != nil {
+		return err
+	}
+
+	db.roles[AdminRole] = true
+	db.roles[ReaderRole] = true
+	db.bootstrapped = true
+	return nil
+}
+
+func (db *MemoryDatabase) EnsureExtension(extension string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.call("EnsureExtension")
+}
+
+func (db *MemoryDatabase) CheckIfTableExists(tableName string) (bool, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("CheckIfTableExists"); err != nil {
+		return false, err
+	}
+
+	_, exists := db.tables[memoryTableName(tableName)]
+	return tableName != "" && exists, nil
+}
+
+func (db *MemoryDatabase) createTable(operation string, tableName string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call(operation); err != nil {
+		return err
+	}
+
+	if err := ValidatePlainIdentifier(tableName); err != nil {
+		return err
+	}
+
+	if _, exists := db.tables[tableName]; exists {
+		return fmt.Errorf(`relation "%s" already exists`, tableName)
+	}
+
+	db.tables[tableName] = []MemoryHost{}
+	return nil
+}
+
+func (db *MemoryDatabase) CreateTable(tableName string, script string) error {
+	return db.createTable("CreateTable", tableName)
+}
+
+func (db *MemoryDatabase) CreatePartitionedTable(tableName string, script string, partitions int64) error {
+	return db.createTable("CreatePartitionedTable", tableName)
+}
+
+// Operations altering a table that only need the table to exist
+func (db *MemoryDatabase) alterTable(operation string, tableName string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call(operation); err != nil {
+		return err
+	}
+
+	return db.requireTable(tableName)
+}
+
+func (db *MemoryDatabase) CreateIndexes(tableName string, script string) error {
+	return db.alterTable("CreateIndexes", tableName)
+}
+
+func (db *MemoryDatabase) AnalyzeTable(tableName string) error {
+	return db.alterTable("AnalyzeTable", tableName)
+}
+
+func (db *MemoryDatabase) SetTableUnlogged(tableName string) error {
+	return db.alterTable("SetTableUnlogged", tableName)
+}
+
+func (db *MemoryDatabase) SetTableLogged(tableName string) error {
+	return db.alterTable("SetTableLogged", tableName)
+}
+
+func (db *MemoryDatabase) SetStorageParameters(tableName string, parameters map[string]string) error {
+	return db.alterTable("SetStorageParameters", tableName)
+}
+
+func (db *MemoryDatabase) SetJSONCompression(tableName string, method string) error {
+	return db.alterTable("SetJSONCompression", tableName)
+}
+
+func (db *MemoryDatabase) GetPrimaryKey(tableName string) ([]string, error) {
+	if err := db.alterTable("GetPrimaryKey", tableName); err != nil {
+		return nil, err
+	}
+
+	return []string{"id"}, nil
+}
+
+// Dropping the table a view points to drops the view as well (CASCADE)
+func (db *MemoryDatabase) DeleteTable(tableName string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("DeleteTable"); err != nil {
+		return err
+	}
+
+	tableName = memoryTableName(tableName)
+	delete(db.tables, tableName)
+	delete(db.owners, tableName)
+	delete(db.grants, tableName)
+
+	for view, table := range db.views {
+		if table == tableName {
+			delete(db.views, view)
+			delete(db.grants, view)
+		}
+	}
+
+	return nil
+}
+
+func (db *MemoryDatabase) CloneTable(tableName string, sourceTableName string) (int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("CloneTable"); err != nil {
+		return 0, err
+	}
+
+	for _, table := range []string{tableName, sourceTableName} {
+		if err := db.requireTable(table); err != nil {
+			return 0, err
+		}
+	}
+
+	source := db.tables[memoryTableName(sourceTableName)]
+	db.tables[memoryTableName(tableName)] = append(db.tables[memoryTableName(tableName)], source...)
+	return int64(len(source)), nil
+}
+
+func (db *MemoryDatabase) GetServerStartTime() (time.Time, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.ServerStartTime, db.call("GetServerStartTime")
+}
+
+// Must be called with the lock held
+func (db *MemoryDatabase) pointView(tableName string) error {
+	if err := db.requireTable(tableName); err != nil {
+		return err
+	}
+
+	db.views[db.view] = memoryTableName(tableName)
+	db.grantSelect(ReaderRole, db.view)
+	return nil
+}
+
+func (db *MemoryDatabase) SetView(view string) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.view = view
+}
+
+// Must be called with the lock held
+func (db *MemoryDatabase) grantSelect(role string, table string) {
+	if !utils.ContainsString(db.grants[table], role) {
+		db.grants[table] = append(db.grants[table], role)
+	}
+}
+
+func (db *MemoryDatabase) UpdateView(tableName string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("UpdateView"); err != nil {
+		return err
+	}
+
+	return db.pointView(tableName)
+}
+
+func (db *MemoryDatabase) ReplaceView(tableName string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("ReplaceView"); err != nil {
+		return err
+	}
+
+	return db.pointView(tableName)
+}
+
+func (db *MemoryDatabase) VerifyView() (int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("VerifyView"); err != nil {
+		return -1, err
+	}
+
+	if _, exists := db.views[db.view]; !exists {
+		return -1, relationNotFound(AppTable(db.view))
+	}
+
+	hosts, err := db.hosts(db.view, false, nil)
+	if err != nil {
+		return -1, err
+	}
+
+	return int64(len(hosts)), nil
+}
+
+func (db *MemoryDatabase) CreateRole(role string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("CreateRole"); err != nil {
+		return err
+	}
+
+	db.roles[role] = true
+	return nil
+}
+
+func (db *MemoryDatabase) GrantSelect(roles []string, tableNames ...string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("GrantSelect"); err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		if !db.roles[role] {
+			return fmt.Errorf(`role "%s" does not exist`, role)
+		}
+	}
+
+	for _, tableName := range tableNames {
+		for _, role := range roles {
+			db.grantSelect(role, memoryTableName(tableName))
+		}
+	}
+
+	return nil
+}
+
+// inventory.hosts is a legacy table if a table of that name was added (see AddHosts)
+func (db *MemoryDatabase) IsLegacyHostsTable() (bool, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("IsLegacyHostsTable"); err != nil {
+		return false, err
+	}
+
+	_, exists := db.tables[memoryTableName(hostsView)]
+	return exists, nil
+}
+
+// Tables of the in-memory database have all the columns of the view
+func (db *MemoryDatabase) GetMissingViewColumns(tableName string) ([]string, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("GetMissingViewColumns"); err != nil {
+		return nil, err
+	}
+
+	if _, exists := db.tables[memoryTableName(tableName)]; !exists {
+		return append([]string{}, viewColumns...), nil
+	}
+
+	return nil, nil
+}
+
+func (db *MemoryDatabase) AdoptLegacyHostsTable(tableName string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("AdoptLegacyHostsTable"); err != nil {
+		return err
+	}
+
+	legacyTable := memoryTableName(hostsView)
+	hosts, exists := db.tables[legacyTable]
+	if !exists {
+		return relationNotFound(hostsView)
+	}
+
+	delete(db.tables, legacyTable)
+	db.tables[memoryTableName(tableName)] = hosts
+	return db.pointView(tableName)
+}
+
+func (db *MemoryDatabase) HostExists(tableName string, id string) (bool, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("HostExists"); err != nil {
+		return false, err
+	}
+
+	hosts, err := db.hosts(tableName, false, nil)
+	if err != nil {
+		return false, err
+	}
+
+	for _, host := range hosts {
+		if host.ID == id {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (db *MemoryDatabase) GetCurrentTable() (*string, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("GetCurrentTable"); err != nil {
+		return nil, err
+	}
+
+	table, exists := db.views[db.view]
+	if !exists {
+		return nil, nil
+	}
+
+	return &table, nil
+}
+
+func (db *MemoryDatabase) GetCyndiTables() ([]string, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("GetCyndiTables"); err != nil {
+		return nil, err
+	}
+
+	var tables []string
+	for table := range db.tables {
+		if strings.HasPrefix(table, "hosts_") {
+			tables = append(tables, table)
+		}
+	}
+
+	sort.Strings(tables)
+	return tables, nil
+}
+
+func (db *MemoryDatabase) RecordAudit(record AuditRecord) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("RecordAudit"); err != nil {
+		return err
+	}
+
+	db.audit = append(db.audit, record)
+	return nil
+}
+
+func (db *MemoryDatabase) RegisterTable(tableName string, pipeline string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("RegisterTable"); err != nil {
+		return err
+	}
+
+	if _, owned := db.owners[tableName]; !owned {
+		db.owners[tableName] = pipeline
+	}
+
+	return nil
+}
+
+func (db *MemoryDatabase) GetTableOwners() (map[string]string, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("GetTableOwners"); err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]string, len(db.owners))
+	for table, pipeline := range db.owners {
+		owners[table] = pipeline
+	}
+
+	return owners, nil
+}
+
+func (db *MemoryDatabase) UpdatePipelineMetadata(metadata PipelineMetadata) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("UpdatePipelineMetadata"); err != nil {
+		return err
+	}
+
+	db.metadata[metadata.Pipeline] = metadata
+	return nil
+}
+
+func (db *MemoryDatabase) GetPipelineMetadata(pipeline string) (*PipelineMetadata, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("GetPipelineMetadata"); err != nil {
+		return nil, err
+	}
+
+	metadata, found := db.metadata[pipeline]
+	if !found {
+		return nil, nil
+	}
+
+	return &metadata, nil
+}
+
+func (db *MemoryDatabase) DeletePipelineMetadata(pipeline string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.call("DeletePipelineMetadata"); err != nil {
+		return err
+	}
+
+	delete(db.metadata, pipeline)
+	return nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+
+Host counts per organization (org_id) used to catch replication problems affecting a single tenant,
+which the total host count may not reveal as long as the difference stays within the validation threshold.
+
+*/
+
+// Host sources able to count hosts per organization
+type OrgCountSource interface {
+	// Returns the host counts of the given organizations or, if none are given, of the limit organizations with the most hosts
+	CountHostsByOrg(table string, insightsOnly bool, additionalFilters []map[string]string, orgIds []string, limit int64) (map[string]int64, error)
+}
+
+func (db *BaseDatabase) orgCountQuery(table string, insightsOnly bool, additionalFilters []map[string]string, orgIds []string, limit int64) string {
+	filter := "org_id IS NOT NULL"
+	if len(orgIds) > 0 {
+		quoted := make([]string, len(orgIds))
+		for n, orgId := range orgIds {
+			quoted[n] = quoteLiteral(orgId)
+		}
+
+		filter = fmt.Sprintf("org_id IN (%s)", strings.Join(quoted, ", "))
+	}
+
+	filters := append(append([]map[string]string{}, additionalFilters...), map[string]string{"where": filter})
+	query := fmt.Sprintf(`SELECT org_id, count(*) FROM %s %s GROUP BY org_id`, table, db.getWhereClause(insightsOnly, filters))
+
+	if len(orgIds) == 0 {
+		query = fmt.Sprintf("%s ORDER BY count(*) DESC, org_id LIMIT %d", query, limit)
+	}
+
+	return query
+}
+
+func (db *BaseDatabase) CountHostsByOrg(table string, insightsOnly bool, additionalFilters []map[string]string, orgIds []string, limit int64) (map[string]int64, error) {
+	rows, err := db.runQuery(QueryTypeCount, db.orgCountQuery(table, insightsOnly, additionalFilters, orgIds, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var (
+			orgId string
+			count int64
+		)
+
+		if err = rows.Scan(&orgId, &count); err != nil {
+			return nil, err
+		}
+
+		counts[orgId] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// The organizations with the most hosts on the individual shards are only candidates - their counts are added up
+func (db *ShardedDatabase) CountHostsByOrg(table string, insightsOnly bool, additionalFilters []map[string]string, orgIds []string, limit int64) (map[string]int64, error) {
+	var shardCounts []map[string]int64
+	for _, shard := range db.Shards {
+		source, ok := shard.(OrgCountSource)
+		if !ok {
+			return nil, fmt.Errorf("shard does not support host counts per organization")
+		}
+
+		counts, err := source.CountHostsByOrg(table, insightsOnly, additionalFilters, orgIds, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		shardCounts = append(shardCounts, counts)
+	}
+
+	return MergeOrgCounts(shardCounts...), nil
+}
+
+// Adds up the host counts of the same organization. Used for HBI sharded across databases or topics
+func MergeOrgCounts(countSets ...map[string]int64) map[string]int64 {
+	merged := map[string]int64{}
+	for _, counts := range countSets {
+		for orgId, count := range counts {
+			merged[orgId] += count
+		}
+	}
+
+	return merged
+}
+
+// Returns (up to) the given number of organizations with the most hosts, ordered by the number of hosts
+func TopOrgs(counts map[string]int64, limit int64) []string {
+	orgIds := make([]string, 0, len(counts))
+	for orgId := range counts {
+		orgIds = append(orgIds, orgId)
+	}
+
+	sort.Slice(orgIds, func(a, b int) bool {
+		if counts[orgIds[a]] != counts[orgIds[b]] {
+			return counts[orgIds[a]] > counts[orgIds[b]]
+		}
+
+		return orgIds[a] < orgIds[b]
+	})
+
+	if int64(len(orgIds)) > limit {
+		orgIds = orgIds[:limit]
+	}
+
+	return orgIds
+}
@@ -0,0 +1,75 @@
+package database
+
+/*
+
+Ownership of the tables of the inventory schema, recorded in the inventory.cyndi_tables table so that several pipelines can share an application database.
+A pipeline only removes the tables it owns as stale. Tables created before ownership was recorded are not owned by any pipeline.
+
+*/
+
+const ownershipTableName = "cyndi_tables"
+
+var ownershipTable = AppTable(ownershipTableName)
+
+const ownershipTableScript = `
+CREATE TABLE IF NOT EXISTS inventory.cyndi_tables (
+	table_name varchar(63) PRIMARY KEY,
+	pipeline varchar(255) NOT NULL,
+	created timestamptz NOT NULL DEFAULT now()
+);
+`
+
+// Records the table as owned by the given pipeline (namespace/name) unless it is owned already
+func (db *AppDatabase) RegisterTable(tableName string, pipeline string) error {
+	exists, err := db.CheckIfTableExists(ownershipTableName)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if _, err = db.Exec(ownershipTableScript); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.exec("INSERT INTO "+ownershipTable+" (table_name, pipeline) VALUES ($1, $2) ON CONFLICT (table_name) DO NOTHING", tableName, pipeline)
+	return err
+}
+
+// Returns the owners (namespace/name of the pipeline) of the tables that have one
+func (db *AppDatabase) GetTableOwners() (map[string]string, error) {
+	owners := map[string]string{}
+
+	exists, err := db.CheckIfTableExists(ownershipTableName)
+	if err != nil || !exists {
+		return owners, err
+	}
+
+	rows, err := db.RunQuery("SELECT table_name, pipeline FROM " + ownershipTable)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, pipeline string
+		if err = rows.Scan(&table, &pipeline); err != nil {
+			return nil, err
+		}
+
+		owners[table] = pipeline
+	}
+
+	return owners, rows.Err()
+}
+
+func (db *AppDatabase) unregisterTable(tableName string) error {
+	exists, err := db.CheckIfTableExists(ownershipTableName)
+	if err != nil || !exists {
+		return err
+	}
+
+	_, err = db.exec("DELETE FROM "+ownershipTable+" WHERE table_name = $1", tableName)
+	return err
+}
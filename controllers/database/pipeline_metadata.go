@@ -0,0 +1,130 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+/*
+
+State of the pipelines syncing into the application database, kept in the inventory.cyndi_metadata table (one row per pipeline)
+so that it can be introspected by DBAs and applications without access to Kubernetes.
+
+*/
+
+const metadataTableName = "cyndi_metadata"
+
+var metadataTable = AppTable(metadataTableName)
+
+const metadataTableScript = `
+CREATE TABLE IF NOT EXISTS inventory.cyndi_metadata (
+	pipeline varchar(255) PRIMARY KEY,
+	active_table varchar(63),
+	operator_version varchar(64) NOT NULL,
+	last_cutover timestamptz,
+	config_hash varchar(64) NOT NULL,
+	updated timestamptz NOT NULL DEFAULT now()
+);
+`
+
+type PipelineMetadata struct {
+	// namespace/name of the CyndiPipeline
+	Pipeline string
+	// table backing the inventory.hosts view, empty if none
+	ActiveTable     string
+	OperatorVersion string
+	LastCutover     *time.Time
+	// hash of the effective configuration of the pipeline
+	ConfigHash string
+}
+
+// Returns true if both describe the same state of the pipeline
+func (m PipelineMetadata) Equal(other PipelineMetadata) bool {
+	if (m.LastCutover == nil) != (other.LastCutover == nil) {
+		return false
+	}
+
+	if m.LastCutover != nil && !m.LastCutover.Equal(*other.LastCutover) {
+		return false
+	}
+
+	return m.Pipeline == other.Pipeline && m.ActiveTable == other.ActiveTable && m.OperatorVersion == other.OperatorVersion && m.ConfigHash == other.ConfigHash
+}
+
+// Inserts or replaces the row of the pipeline
+func (db *AppDatabase) UpdatePipelineMetadata(metadata PipelineMetadata) error {
+	exists, err := db.CheckIfTableExists(metadataTableName)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if _, err = db.Exec(metadataTableScript); err != nil {
+			return err
+		}
+	}
+
+	var activeTable, lastCutover interface{}
+	if metadata.ActiveTable != "" {
+		activeTable = metadata.ActiveTable
+	}
+
+	if metadata.LastCutover != nil {
+		lastCutover = metadata.LastCutover.UTC()
+	}
+
+	_, err = db.exec(`INSERT INTO `+metadataTable+` (pipeline, active_table, operator_version, last_cutover, config_hash, updated)
+		VALUES ($1, $2, $3, $4, $5, now())
+		ON CONFLICT (pipeline) DO UPDATE SET active_table = EXCLUDED.active_table, operator_version = EXCLUDED.operator_version,
+			last_cutover = EXCLUDED.last_cutover, config_hash = EXCLUDED.config_hash, updated = EXCLUDED.updated`,
+		metadata.Pipeline, activeTable, metadata.OperatorVersion, lastCutover, metadata.ConfigHash,
+	)
+
+	return err
+}
+
+// Returns the row of the pipeline, nil if there is none
+func (db *AppDatabase) GetPipelineMetadata(pipeline string) (*PipelineMetadata, error) {
+	exists, err := db.CheckIfTableExists(metadataTableName)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := db.RunQuery("SELECT active_table, operator_version, last_cutover, config_hash FROM " + metadataTable + " WHERE pipeline = " + quoteLiteral(pipeline))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var (
+		activeTable sql.NullString
+		lastCutover sql.NullTime
+	)
+
+	metadata := PipelineMetadata{Pipeline: pipeline}
+	if err = rows.Scan(&activeTable, &metadata.OperatorVersion, &lastCutover, &metadata.ConfigHash); err != nil {
+		return nil, err
+	}
+
+	metadata.ActiveTable = activeTable.String
+	if lastCutover.Valid {
+		metadata.LastCutover = &lastCutover.Time
+	}
+
+	return &metadata, nil
+}
+
+func (db *AppDatabase) DeletePipelineMetadata(pipeline string) error {
+	exists, err := db.CheckIfTableExists(metadataTableName)
+	if err != nil || !exists {
+		return err
+	}
+
+	_, err = db.exec("DELETE FROM "+metadataTable+" WHERE pipeline = $1", pipeline)
+	return err
+}
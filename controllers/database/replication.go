@@ -0,0 +1,116 @@
+package database
+
+import (
+	"fmt"
+)
+
+/*
+
+Replication slots (and publications) of the Debezium source connectors in the HBI database.
+The heartbeat table the source connectors write to holds a row per replication slot, removed along with the slot.
+
+*/
+
+type ReplicationSlotStatus struct {
+	Exists bool
+	Active bool
+	// WAL the consumer of the slot has yet to confirm
+	LagBytes int64
+	// WAL the server retains for the slot
+	RetainedBytes int64
+	// "lost" once the server removed WAL the slot still needs (PostgreSQL 13+, empty on older versions)
+	WALStatus string
+}
+
+/*
+ * Drops the given replication slot and the publication of the same name.
+ * Returns false if the slot is still in use (e.g. by a connector that is being stopped).
+ */
+func DropReplicationSlot(db Database, slot string) (bool, error) {
+	literal := quoteLiteral(slot)
+
+	rows, err := db.RunQuery(fmt.Sprintf("SELECT active FROM pg_replication_slots WHERE slot_name = %s", literal))
+	if err != nil {
+		return false, err
+	}
+
+	exists := rows.Next()
+	var active bool
+	if exists {
+		err = rows.Scan(&active)
+	}
+
+	rows.Close()
+	if err != nil {
+		return false, err
+	} else if err = rows.Err(); err != nil {
+		return false, err
+	}
+
+	if active {
+		return false, nil
+	}
+
+	if exists {
+		if _, err = db.Exec(fmt.Sprintf("SELECT pg_drop_replication_slot(%s)", literal)); err != nil {
+			return false, err
+		}
+	}
+
+	_, err = db.Exec(fmt.Sprintf("DROP PUBLICATION IF EXISTS %s", QuoteIdentifier(slot)))
+	return err == nil, err
+}
+
+// Reports the lag and the WAL retention of the given replication slot. The status of a missing slot is the zero value
+func GetReplicationSlotStatus(db Database, slot string) (status ReplicationSlotStatus, err error) {
+	// wal_status is read through to_jsonb as the column does not exist before PostgreSQL 13
+	rows, err := db.RunQuery(fmt.Sprintf(`SELECT s.active,
+		COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), s.confirmed_flush_lsn), 0)::bigint,
+		COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), s.restart_lsn), 0)::bigint,
+		COALESCE(to_jsonb(s)->>'wal_status', '')
+		FROM pg_replication_slots s WHERE s.slot_name = %s`, quoteLiteral(slot)))
+
+	if err != nil {
+		return status, err
+	}
+
+	defer rows.Close()
+
+	if rows.Next() {
+		status.Exists = true
+		if err = rows.Scan(&status.Active, &status.LagBytes, &status.RetainedBytes, &status.WALStatus); err != nil {
+			return status, err
+		}
+	}
+
+	return status, rows.Err()
+}
+
+func PublicationExists(db Database, publication string) (bool, error) {
+	rows, err := db.RunQuery(fmt.Sprintf("SELECT 1 FROM pg_publication WHERE pubname = %s", quoteLiteral(publication)))
+	if err != nil {
+		return false, err
+	}
+
+	defer rows.Close()
+
+	exists := rows.Next()
+	return exists, rows.Err()
+}
+
+// Creates the heartbeat table of the source connectors unless it exists
+func CreateHeartbeatTable(db Database, schema string, table string) error {
+	_, err := db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (slot_name text PRIMARY KEY, ts timestamptz NOT NULL)", QualifiedTable(schema, table)))
+	return err
+}
+
+// Returns the statement the source connector using the given replication slot writes its heartbeat with (heartbeat.action.query)
+func HeartbeatQuery(schema string, table string, slot string) string {
+	return fmt.Sprintf("INSERT INTO %s (slot_name, ts) VALUES (%s, now()) ON CONFLICT (slot_name) DO UPDATE SET ts = EXCLUDED.ts", QualifiedTable(schema, table), quoteLiteral(slot))
+}
+
+// Removes the heartbeat of a dropped replication slot
+func DeleteHeartbeat(db Database, schema string, table string, slot string) error {
+	_, err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE slot_name = %s", QualifiedTable(schema, table), quoteLiteral(slot)))
+	return err
+}
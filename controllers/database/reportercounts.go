@@ -0,0 +1,66 @@
+package database
+
+import (
+	"fmt"
+)
+
+/*
+
+Host counts per reporter (the service that last reported the host, e.g. puptoo or rhsm-conduit), used to find out
+which reporters the hosts missing from (or lagging behind in) the application table come from.
+
+*/
+
+// Host sources able to count hosts per reporter
+type ReporterCountSource interface {
+	CountHostsByReporter(table string, insightsOnly bool, additionalFilters []map[string]string) (map[string]int64, error)
+}
+
+func (db *BaseDatabase) reporterCountQuery(table string, insightsOnly bool, additionalFilters []map[string]string) string {
+	return fmt.Sprintf(`SELECT COALESCE(reporter, ''), count(*) FROM %s %s GROUP BY 1`, table, db.getWhereClause(insightsOnly, additionalFilters))
+}
+
+func (db *BaseDatabase) CountHostsByReporter(table string, insightsOnly bool, additionalFilters []map[string]string) (map[string]int64, error) {
+	rows, err := db.runQuery(QueryTypeCount, db.reporterCountQuery(table, insightsOnly, additionalFilters))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var (
+			reporter string
+			count    int64
+		)
+
+		if err = rows.Scan(&reporter, &count); err != nil {
+			return nil, err
+		}
+
+		counts[reporter] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (db *ShardedDatabase) CountHostsByReporter(table string, insightsOnly bool, additionalFilters []map[string]string) (map[string]int64, error) {
+	var shardCounts []map[string]int64
+	for _, shard := range db.Shards {
+		source, ok := shard.(ReporterCountSource)
+		if !ok {
+			return nil, fmt.Errorf("shard does not support host counts per reporter")
+		}
+
+		counts, err := source.CountHostsByReporter(table, insightsOnly, additionalFilters)
+		if err != nil {
+			return nil, err
+		}
+
+		shardCounts = append(shardCounts, counts)
+	}
+
+	// counts are keyed by reporter rather than by organization but add up the same way
+	return MergeOrgCounts(shardCounts...), nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+Retries of database operations failing with transient errors (DBParams.Retry).
+Serialization failures, deadlocks, exhausted connection slots and reset connections usually go away within milliseconds.
+Retrying them here rather than failing the reconcile avoids warning events and Degraded conditions flapping for nothing.
+A connection found dead is re-established before the next attempt.
+
+*/
+
+// SQLSTATE codes of errors that are likely to go away if the operation is retried
+var transientSQLStates = []string{
+	"40001", // serialization_failure
+	"40P01", // deadlock_detected
+	"53300", // too_many_connections
+	"57P03", // cannot_connect_now
+}
+
+// Determines whether retrying the operation that failed with the given error is likely to succeed
+func isTransientError(err error) bool {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) {
+		return utils.ContainsString(transientSQLStates, pgErr.Code)
+	}
+
+	return errors.Is(err, pgx.ErrDeadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+const maxRetryDoublings = 16
+
+// Delay before the given retry (counted from 0): the base interval doubled with each retry, randomized by up to half of its value
+func retryDelay(baseInterval int64, retry int64) time.Duration {
+	// keeps the delay from overflowing with an excessive number of attempts
+	if retry > maxRetryDoublings {
+		retry = maxRetryDoublings
+	}
+
+	delay := time.Duration(baseInterval) * time.Millisecond << retry
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Runs the operation, retrying it while it fails with a transient error
+func (db *BaseDatabase) withRetry(operation func() error) (err error) {
+	err = operation()
+
+	for retry := int64(0); err != nil && retry < db.Config.Retry.Attempts && isTransientError(err); retry++ {
+		delay := retryDelay(db.Config.Retry.BaseInterval, retry)
+		metrics.DBRetry(db.Config.Name, classifyError(err))
+
+		if db.Log != nil {
+			db.Log.Info("Retrying database operation", "error", err.Error(), "delay", delay)
+		}
+
+		time.Sleep(delay)
+
+		if db.connection != nil && !db.connection.IsAlive() {
+			connection, connectErr := GetConnection(db.Config)
+			if connectErr != nil {
+				err = connectErr
+				continue
+			}
+
+			db.connection = connection
+		}
+
+		err = operation()
+	}
+
+	return err
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx"
+)
+
+/*
+
+HBI sharded across several (shared-nothing) databases presented as a single database.
+Host counts are summed up and host ids are merged so that validation treats the shards as one logical source.
+
+*/
+
+type ShardedDatabase struct {
+	Shards []Database
+}
+
+func NewShardedDatabase(shards ...Database) Database {
+	return &ShardedDatabase{Shards: shards}
+}
+
+func (db *ShardedDatabase) Connect() error {
+	for _, shard := range db.Shards {
+		if err := shard.Connect(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *ShardedDatabase) Close() (err error) {
+	for _, shard := range db.Shards {
+		if closeErr := shard.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+func (db *ShardedDatabase) SetContext(ctx context.Context) {
+	for _, shard := range db.Shards {
+		shard.SetContext(ctx)
+	}
+}
+
+// Results of the shards cannot be merged into a single set of rows
+func (db *ShardedDatabase) RunQuery(query string) (*pgx.Rows, error) {
+	return nil, errors.New("cannot run a query against a sharded database")
+}
+
+func (db *ShardedDatabase) Exec(query string) (result pgx.CommandTag, err error) {
+	return result, errors.New("cannot run a query against a sharded database")
+}
+
+func (db *ShardedDatabase) CountHosts(table string, insightsOnly bool, additionalFilters []map[string]string) (total int64, err error) {
+	for _, shard := range db.Shards {
+		count, err := shard.CountHosts(table, insightsOnly, additionalFilters)
+		if err != nil {
+			return -1, err
+		}
+
+		total += count
+	}
+
+	return total, nil
+}
+
+func (db *ShardedDatabase) GetHostIds(table string, insightsOnly bool, additionalFilters []map[string]string) (ids []string, err error) {
+	for _, shard := range db.Shards {
+		shardIds, err := shard.GetHostIds(table, insightsOnly, additionalFilters)
+		if err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, shardIds...)
+	}
+
+	return ids, nil
+}
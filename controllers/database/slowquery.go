@@ -0,0 +1,98 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+/*
+
+Capture of the plans of slow queries. Validation queries against a large HBI database that run for a long time usually lack an index
+(e.g. one matching the filters of the pipeline). With a threshold set (see CaptureSlowQueries), the plan of every host count or id query
+running longer is retrieved using EXPLAIN (without ANALYZE, so the query is not run again) and kept until collected by TakeSlowQueries,
+sparing DBAs reproducing the query manually.
+
+*/
+
+// A query that ran longer than the threshold along with its plan
+type SlowQuery struct {
+	Query    string
+	Duration time.Duration
+	// the output of EXPLAIN, empty if it could not be retrieved
+	Plan string
+}
+
+// Host sources that capture the plans of slow queries
+type SlowQuerySource interface {
+	// Returns the slow queries captured since the previous call
+	TakeSlowQueries() []SlowQuery
+}
+
+// Queries running longer than the given threshold have their plan captured. 0 disables the capture
+func CaptureSlowQueries(source HostSource, threshold time.Duration) {
+	switch s := source.(type) {
+	case *BaseDatabase:
+		s.slowQueryThreshold = threshold
+	case *ShardedDatabase:
+		for _, shard := range s.Shards {
+			CaptureSlowQueries(shard, threshold)
+		}
+	}
+}
+
+// Meant to be deferred before the query is run so that the plan is retrieved once the rows are closed
+func (db *BaseDatabase) checkSlowQuery(query string, start time.Time) {
+	duration := time.Since(start)
+	if db.slowQueryThreshold <= 0 || duration < db.slowQueryThreshold {
+		return
+	}
+
+	slow := SlowQuery{Query: RedactCredentials(query), Duration: duration}
+
+	plan, err := db.explain(query)
+	if err != nil {
+		// not fatal - the query is reported without its plan
+		db.Log.Error(err, "Failed to retrieve the plan of a slow query", "database", db.Role, "query", slow.Query)
+	} else {
+		slow.Plan = plan
+	}
+
+	db.Log.Info("Slow query", "database", db.Role, "query", slow.Query, "duration", duration.String(), "plan", slow.Plan)
+	db.slowQueries = append(db.slowQueries, slow)
+}
+
+func (db *BaseDatabase) explain(query string) (string, error) {
+	rows, err := db.RunQuery("EXPLAIN (ANALYZE off) " + query)
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err = rows.Scan(&line); err != nil {
+			return "", err
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+func (db *BaseDatabase) TakeSlowQueries() (queries []SlowQuery) {
+	queries, db.slowQueries = db.slowQueries, nil
+	return
+}
+
+func (db *ShardedDatabase) TakeSlowQueries() (queries []SlowQuery) {
+	for _, shard := range db.Shards {
+		if source, ok := shard.(SlowQuerySource); ok {
+			queries = append(queries, source.TakeSlowQueries()...)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+/*
+
+Host ids read in chunks so that validation does not need to hold all the ids of a table in memory.
+The ids are read in ascending order which allows the ids of HBI and of the application table to be compared using a merge join.
+Chunks are read using keyset pagination rather than a server-side cursor as iterators of topic shards share a database connection.
+
+*/
+
+// Iterates over host ids in ascending order
+type HostIdIterator interface {
+	// Returns the next id or false once all ids have been read
+	Next() (string, bool, error)
+}
+
+// Host sources able to read host ids in chunks
+type HostIdStreamer interface {
+	StreamHostIds(table string, insightsOnly bool, additionalFilters []map[string]string, chunkSize int) HostIdIterator
+}
+
+func (db *BaseDatabase) hostIdChunkQuery(table string, insightsOnly bool, additionalFilters []map[string]string, after string, limit int) string {
+	filters := additionalFilters
+	if after != "" {
+		filters = append(append([]map[string]string{}, additionalFilters...), map[string]string{"where": db.idSortKey() + " > " + quoteLiteral(after)})
+	}
+
+	return fmt.Sprintf(`SELECT id FROM %s %s ORDER BY %s LIMIT %d`, table, db.getWhereClause(insightsOnly, filters), db.idSortKey(), limit)
+}
+
+func (db *BaseDatabase) getHostIdChunk(table string, insightsOnly bool, additionalFilters []map[string]string, after string, limit int) ([]string, error) {
+	query := db.hostIdChunkQuery(table, insightsOnly, additionalFilters, after, limit)
+	defer db.checkSlowQuery(query, time.Now())
+
+	rows, err := db.runQuery(QueryTypeIds, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := make([]string, 0, limit)
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (db *BaseDatabase) StreamHostIds(table string, insightsOnly bool, additionalFilters []map[string]string, chunkSize int) HostIdIterator {
+	return &chunkedIdIterator{db: db, table: table, insightsOnly: insightsOnly, filters: additionalFilters, chunkSize: chunkSize}
+}
+
+type chunkedIdIterator struct {
+	db           *BaseDatabase
+	table        string
+	insightsOnly bool
+	filters      []map[string]string
+	chunkSize    int
+
+	chunk []string
+	pos   int
+	done  bool
+}
+
+func (it *chunkedIdIterator) Next() (string, bool, error) {
+	if it.pos >= len(it.chunk) {
+		if it.done {
+			return "", false, nil
+		}
+
+		after := ""
+		if len(it.chunk) > 0 {
+			after = it.chunk[len(it.chunk)-1]
+		}
+
+		chunk, err := it.db.getHostIdChunk(it.table, it.insightsOnly, it.filters, after, it.chunkSize)
+		if err != nil {
+			return "", false, err
+		}
+
+		it.chunk, it.pos = chunk, 0
+		it.done = len(chunk) < it.chunkSize
+
+		if len(chunk) == 0 {
+			return "", false, nil
+		}
+	}
+
+	it.pos++
+	return it.chunk[it.pos-1], true, nil
+}
+
+func (db *ShardedDatabase) StreamHostIds(table string, insightsOnly bool, additionalFilters []map[string]string, chunkSize int) HostIdIterator {
+	iterators := make([]HostIdIterator, len(db.Shards))
+	for n, shard := range db.Shards {
+		if streamer, ok := shard.(HostIdStreamer); ok {
+			iterators[n] = streamer.StreamHostIds(table, insightsOnly, additionalFilters, chunkSize)
+		} else {
+			iterators[n] = &errorIdIterator{err: fmt.Errorf("shard does not support streaming host ids")}
+		}
+	}
+
+	return MergeHostIdIterators(iterators...)
+}
+
+type errorIdIterator struct {
+	err error
+}
+
+func (it *errorIdIterator) Next() (string, bool, error) {
+	return "", false, it.err
+}
+
+// Iterates over ids already loaded in memory (e.g. fetched from the HBI API)
+func NewSliceIdIterator(ids []string) HostIdIterator {
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+	return &sliceIdIterator{ids: sorted}
+}
+
+type sliceIdIterator struct {
+	ids []string
+	pos int
+}
+
+func (it *sliceIdIterator) Next() (string, bool, error) {
+	if it.pos >= len(it.ids) {
+		return "", false, nil
+	}
+
+	it.pos++
+	return it.ids[it.pos-1], true, nil
+}
+
+// Merges ordered iterators (e.g. of HBI shards) into a single ordered iterator
+func MergeHostIdIterators(iterators ...HostIdIterator) HostIdIterator {
+	if len(iterators) == 1 {
+		return iterators[0]
+	}
+
+	return &mergedIdIterator{iterators: iterators}
+}
+
+type mergedIdIterator struct {
+	iterators []HostIdIterator
+	// the next id of each iterator, empty once the iterator is exhausted
+	heads       []string
+	initialized bool
+}
+
+func (it *mergedIdIterator) advance(n int) error {
+	id, ok, err := it.iterators[n].Next()
+	if err != nil {
+		return err
+	} else if !ok {
+		id = ""
+	}
+
+	it.heads[n] = id
+	return nil
+}
+
+func (it *mergedIdIterator) Next() (string, bool, error) {
+	if !it.initialized {
+		it.heads = make([]string, len(it.iterators))
+		for n := range it.iterators {
+			if err := it.advance(n); err != nil {
+				return "", false, err
+			}
+		}
+
+		it.initialized = true
+	}
+
+	min := -1
+	for n, head := range it.heads {
+		if head != "" && (min < 0 || head < it.heads[min]) {
+			min = n
+		}
+	}
+
+	if min < 0 {
+		return "", false, nil
+	}
+
+	id := it.heads[min]
+	if err := it.advance(min); err != nil {
+		return "", false, err
+	}
+
+	return id, true, nil
+}
@@ -1,14 +1,79 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"github.com/jackc/pgx"
 )
 
-type Database interface {
+// Source of the HBI hosts the application table is validated against
+type HostSource interface {
 	Connect() error
 	Close() error
-	RunQuery(query string) (*pgx.Rows, error)
-	Exec(query string) (result pgx.CommandTag, err error)
+	// Sets the context used as the parent of the spans created for queries
+	SetContext(ctx context.Context)
 	CountHosts(table string, insightsOnly bool, additionalFilters []map[string]string) (int64, error)
 	GetHostIds(table string, insightsOnly bool, additionalFilters []map[string]string) ([]string, error)
 }
+
+type Database interface {
+	HostSource
+	RunQuery(query string) (*pgx.Rows, error)
+	Exec(query string) (result pgx.CommandTag, err error)
+}
+
+// The application database hosts are syndicated to. Implemented by AppDatabase and, for tests, MemoryDatabase
+type ApplicationDatabase interface {
+	Database
+	BlockHashSource
+	HostIdStreamer
+	OrgCountSource
+	ReporterCountSource
+
+	// Creates the inventory schema, the given extensions and the roles of the operator unless they exist
+	Bootstrap(extensions []string) error
+	// Creates the extension unless it exists
+	EnsureExtension(extension string) error
+	CheckIfTableExists(tableName string) (bool, error)
+	CreateTable(tableName string, script string) error
+	CreatePartitionedTable(tableName string, script string, partitions int64) error
+	CreateIndexes(tableName string, script string) error
+	GetPrimaryKey(tableName string) ([]string, error)
+	AnalyzeTable(tableName string) error
+	DeleteTable(tableName string) error
+	CloneTable(tableName string, sourceTableName string) (int64, error)
+	SetTableUnlogged(tableName string) error
+	SetTableLogged(tableName string) error
+	SetStorageParameters(tableName string, parameters map[string]string) error
+	SetJSONCompression(tableName string, method string) error
+	GetServerStartTime() (time.Time, error)
+
+	// Sets the view (of the inventory schema) the methods below maintain. Clones (spec.sourcePipeline) use one other than DefaultView
+	SetView(view string)
+	// Points the view to the given table
+	UpdateView(tableName string) error
+	ReplaceView(tableName string) error
+	VerifyView() (int64, error)
+	// Returns the table the view points to, nil if there is no view
+	GetCurrentTable() (*string, error)
+	GetCyndiTables() ([]string, error)
+	IsLegacyHostsTable() (bool, error)
+	AdoptLegacyHostsTable(tableName string) error
+	GetMissingViewColumns(tableName string) ([]string, error)
+	HostExists(tableName string, id string) (bool, error)
+
+	// Records the table as owned by the given pipeline (namespace/name) unless it is owned already
+	RegisterTable(tableName string, pipeline string) error
+	// Returns the owners of the tables that have one
+	GetTableOwners() (map[string]string, error)
+
+	// Maintain the row of the pipeline in the inventory.cyndi_metadata table
+	UpdatePipelineMetadata(metadata PipelineMetadata) error
+	GetPipelineMetadata(pipeline string) (*PipelineMetadata, error)
+	DeletePipelineMetadata(pipeline string) error
+
+	CreateRole(role string) error
+	GrantSelect(roles []string, tableNames ...string) error
+	RecordAudit(record AuditRecord) error
+}
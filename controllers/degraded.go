@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/connect"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+
+Infrastructure problems (an unavailable database, a missing connector, a Connect cluster that is not ready) are reported using the Degraded condition.
+Unlike data mismatches (the Valid condition) they do not count towards the refresh threshold as a new pipeline version would not fare any better.
+The pipeline is instead retried until the infrastructure recovers.
+
+*/
+
+const (
+	reasonDatabaseUnavailable    = "DatabaseUnavailable"
+	reasonConnectorMissing       = "ConnectorMissing"
+	reasonConnectClusterNotReady = "ConnectClusterNotReady"
+	reasonTableShrunk            = "TableShrunk"
+	reasonViewVerificationFailed = "ViewVerificationFailed"
+	reasonRecovered              = "Recovered"
+)
+
+// Returns the Degraded reason of an error caused by unavailable infrastructure. Empty for other errors
+func degradedReason(err error) string {
+	if database.IsConnectionError(err) {
+		return reasonDatabaseUnavailable
+	}
+
+	return ""
+}
+
+func isDegradedBy(instance *cyndi.CyndiPipeline, reasons ...string) bool {
+	condition := instance.GetDegraded()
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		return false
+	}
+
+	for _, reason := range reasons {
+		if condition.Reason == reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Marks the given pipeline (the instance being reconciled or its fresh copy) as Degraded. Returns false if it already was for the same reason
+func (i *ReconcileIteration) markDegraded(instance *cyndi.CyndiPipeline, reason string, message string) bool {
+	if isDegradedBy(instance, reason) {
+		return false
+	}
+
+	i.eventWarning(reason, message)
+	instance.SetDegraded(metav1.ConditionTrue, reason, message)
+	metrics.PipelineDegraded(instance)
+	return true
+}
+
+// Clears the Degraded condition if it was caused by one of the given infrastructure problems
+func (i *ReconcileIteration) clearDegraded(reasons ...string) {
+	if !isDegradedBy(i.Instance, reasons...) {
+		return
+	}
+
+	previous := i.Instance.GetDegraded().Reason
+	i.eventNormal(reasonRecovered, "Pipeline recovered (%s)", previous)
+	i.Instance.SetDegraded(metav1.ConditionFalse, reasonRecovered, fmt.Sprintf("Recovered from %s", previous))
+}
+
+/*
+ * Records an infrastructure problem that prevented the validation controller from validating the pipeline.
+ * Validation is retried after the base backoff interval rather than the validation interval.
+ * Returns false if the error is not caused by unavailable infrastructure.
+ */
+func (i *ReconcileIteration) reportDegraded(err error) (result reconcile.Result, degraded bool) {
+	reason := degradedReason(err)
+	if reason == "" || i.Instance == nil {
+		return result, false
+	}
+
+	i.Log.Error(err, "Pipeline degraded", "reason", reason)
+	result = reconcile.Result{RequeueAfter: time.Duration(i.getBackoffConfig().BaseInterval) * time.Second}
+
+	// the status of the instance may have been modified by the failed attempt
+	instance, fetchErr := utils.FetchCyndiPipeline(i.Client, client.ObjectKeyFromObject(i.Instance))
+	if fetchErr != nil {
+		return result, true
+	}
+
+	if i.markDegraded(instance, reason, err.Error()) {
+		if updateErr := i.Client.Status().Update(i.ctx, instance); updateErr != nil {
+			i.Log.Error(updateErr, "Error recording degraded pipeline")
+		}
+	}
+
+	return result, true
+}
+
+/*
+ * Re-creates the connector of the current pipeline version if it disappeared.
+ * The connector resumes from the offsets committed by its predecessor so the table does not need to be seeded again.
+ * Returns true if the connector is missing.
+ */
+func (i *ReconcileIteration) recreateMissingConnector() (missing bool, err error) {
+	exists, err := connect.CheckIfConnectorExists(i.ctx, i.Client, i.Instance.Status.ConnectorName, i.connectorNamespace())
+	if err != nil {
+		return false, err
+	} else if exists {
+		// created by a reconciliation interrupted before it could record it
+		i.Instance.Status.ConnectorPending = false
+		return false, nil
+	}
+
+	if i.Instance.Status.ConnectorPending {
+		return true, i.createPendingConnector()
+	}
+
+	i.markDegraded(i.Instance, reasonConnectorMissing, fmt.Sprintf("Connector %s not found in %s", i.Instance.Status.ConnectorName, i.connectorNamespace()))
+
+	if i.skipMutation("Not re-creating missing connector", "connector", i.Instance.Status.ConnectorName) {
+		return true, nil
+	}
+
+	if _, err = i.createConnector(i.Instance.Status.ConnectorName, false); err != nil {
+		return true, err
+	}
+
+	i.probeConnectorRecreated(i.Instance.Status.ConnectorName)
+	return true, nil
+}
+
+/*
+ * Marks the pipeline as Degraded if the Kafka Connect cluster running its connectors is not Ready.
+ * The pipeline is suspended (not validated, not refreshed) until the cluster recovers.
+ * A cluster that cannot be found is left to the connector checks.
+ */
+func (i *ReconcileIteration) checkConnectCluster() (ready bool, err error) {
+	cluster, err := connect.GetConnectCluster(i.ctx, i.Client, i.config.ConnectCluster, i.connectorNamespace())
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		i.clearDegraded(reasonConnectClusterNotReady)
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if notReady, message := connect.IsNotReady(cluster); notReady {
+		i.markDegraded(i.Instance, reasonConnectClusterNotReady, fmt.Sprintf("Connect cluster %s is not ready: %s", i.config.ConnectCluster, message))
+		return false, nil
+	}
+
+	i.clearDegraded(reasonConnectClusterNotReady)
+	return true, nil
+}
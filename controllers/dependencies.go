@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+
+Ordering of cutovers across pipelines (spec.dependsOn), e.g. for applications sharing downstream jobs.
+The inventory.hosts view of a pipeline is only switched to a new table once all the pipelines it depends on (possibly in other namespaces) are valid.
+The DependenciesReady condition reports the pipelines the last switch waited for. Pipelines are reconciled when a pipeline they depend on changes.
+
+*/
+
+const (
+	reasonDependenciesValid  = "DependenciesValid"
+	reasonDependencyNotValid = "DependencyNotValid"
+)
+
+func dependencies(pipeline *cyndi.CyndiPipeline) []types.NamespacedName {
+	result := make([]types.NamespacedName, 0, len(pipeline.Spec.DependsOn))
+	for _, reference := range pipeline.Spec.DependsOn {
+		namespace := reference.Namespace
+		if namespace == "" {
+			namespace = pipeline.GetNamespace()
+		}
+
+		result = append(result, types.NamespacedName{Namespace: namespace, Name: reference.Name})
+	}
+
+	return result
+}
+
+// A pipeline depending on itself would never switch its view
+func validateDependencies(pipeline *cyndi.CyndiPipeline) error {
+	self := types.NamespacedName{Namespace: pipeline.GetNamespace(), Name: pipeline.GetName()}
+	for _, dependency := range dependencies(pipeline) {
+		if dependency == self {
+			return fmt.Errorf("A pipeline cannot depend on itself (dependsOn)")
+		}
+	}
+
+	return nil
+}
+
+// Returns true (and records the pipelines being waited for in the DependenciesReady condition) if the view cannot be switched yet
+func (i *ReconcileIteration) waitForDependencies() (bool, error) {
+	if len(i.Instance.Spec.DependsOn) == 0 {
+		i.Instance.ResetDependenciesReady()
+		return false, nil
+	}
+
+	var waiting []string
+	for _, dependency := range dependencies(i.Instance) {
+		pipeline := &cyndi.CyndiPipeline{}
+		if err := i.Client.Get(i.ctx, dependency, pipeline); errors.IsNotFound(err) {
+			waiting = append(waiting, fmt.Sprintf("%s (not found)", dependency))
+		} else if err != nil {
+			return false, err
+		} else if !pipeline.IsValid() {
+			waiting = append(waiting, dependency.String())
+		}
+	}
+
+	if len(waiting) > 0 {
+		i.Log.Info("Not updating view until the pipelines it depends on are valid", "table", i.Instance.Status.TableName, "pipelines", waiting)
+		i.Instance.SetDependenciesReady(metav1.ConditionFalse, reasonDependencyNotValid, fmt.Sprintf("Waiting for %s to become valid", strings.Join(waiting, ", ")))
+		return true, nil
+	}
+
+	i.Instance.SetDependenciesReady(metav1.ConditionTrue, reasonDependenciesValid, "")
+	return false, nil
+}
+
+// Pipelines depending on the given pipeline
+func (r *CyndiPipelineReconciler) pipelinesDependingOn(dependency client.Object) (requests []reconcile.Request) {
+	name := types.NamespacedName{Namespace: dependency.GetNamespace(), Name: dependency.GetName()}
+
+	pipelines, err := utils.FetchCyndiPipelines(r.Client, "")
+	if err != nil {
+		r.Log.Error(err, "Failed to fetch CyndiPipelines")
+		return
+	}
+
+	for idx := range pipelines.Items {
+		pipeline := &pipelines.Items[idx]
+
+		for _, reference := range dependencies(pipeline) {
+			if reference == name {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Namespace: pipeline.GetNamespace(),
+						Name:      pipeline.GetName(),
+					},
+				})
+			}
+		}
+	}
+
+	return
+}
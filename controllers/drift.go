@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/connect"
+
+	"github.com/google/go-cmp/cmp"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+/*
+
+Drift report. With drift.report.enabled set, the live state of the pipeline (its connector, table and the inventory.hosts view) is compared
+to what the current spec and configuration would render, and the differences are reported using the Drifted condition.
+Nothing is changed by the report itself, so users of GitOps tools (e.g. ArgoCD) can diff the desired and actual state without applying anything.
+The report is taken before the operator corrects the drift (or refreshes the pipeline); in read-only mode nothing is corrected at all.
+
+*/
+
+const (
+	reasonInSync               = "InSync"
+	reasonConfigurationChanged = "ConfigurationChanged"
+	reasonTableMissing         = "TableMissing"
+	reasonConnectorDrifted     = "ConnectorDrifted"
+	reasonViewDrifted          = "ViewDrifted"
+)
+
+// long diffs of connector configurations are cut so that the condition stays readable
+const maxDriftMessageLength = 4096
+
+type drift struct {
+	reason  string
+	message string
+}
+
+func (i *ReconcileIteration) reportDrift() error {
+	if !i.config.DriftReportEnabled {
+		i.Instance.ResetDrifted()
+		return nil
+	}
+
+	drifts, err := i.detectDrift()
+	if err != nil {
+		return err
+	}
+
+	if len(drifts) == 0 {
+		i.Instance.SetDrifted(metav1.ConditionFalse, reasonInSync, "The connector, table and view match the desired state")
+		return nil
+	}
+
+	messages := make([]string, len(drifts))
+	for idx, d := range drifts {
+		messages[idx] = d.message
+	}
+
+	message := strings.Join(messages, "; ")
+	if len(message) > maxDriftMessageLength {
+		message = message[:maxDriftMessageLength] + "..."
+	}
+
+	if condition := i.Instance.GetDrifted(); condition == nil || condition.Status != metav1.ConditionTrue {
+		i.eventWarning("Drifted", "Pipeline drifted from the desired state: %s", messages[0])
+	}
+
+	i.Instance.SetDrifted(metav1.ConditionTrue, drifts[0].reason, message)
+	return nil
+}
+
+// Differences between the live state of the pipeline and its desired state. Does not modify anything
+func (i *ReconcileIteration) detectDrift() (drifts []drift, err error) {
+	if i.Instance.Status.CyndiConfigVersion != i.config.ConfigMapVersion || i.Instance.Status.SpecHash != i.config.SpecHash {
+		drifts = append(drifts, drift{reasonConfigurationChanged, "the configuration changed since the table was created, a refresh is pending"})
+	}
+
+	tableName := i.Instance.Status.TableName
+	if exists, err := i.AppDb.CheckIfTableExists(tableName); err != nil {
+		return nil, err
+	} else if !exists {
+		drifts = append(drifts, drift{reasonTableMissing, fmt.Sprintf("table %s does not exist", tableName)})
+	}
+
+	if activeTable := i.Instance.Status.ActiveTableName; activeTable != "" {
+		table, err := i.AppDb.GetCurrentTable()
+		if err != nil {
+			return nil, err
+		}
+
+		if table == nil {
+			drifts = append(drifts, drift{reasonViewDrifted, fmt.Sprintf("inventory.%s view does not exist (expected to point to %s)", i.viewName(), activeTable)})
+		} else if *table != activeTable {
+			drifts = append(drifts, drift{reasonViewDrifted, fmt.Sprintf("inventory.%s view points to %s instead of %s", i.viewName(), *table, activeTable)})
+		}
+	}
+
+	connectorDrift, err := i.detectConnectorDrift()
+	if err != nil {
+		return nil, err
+	} else if connectorDrift != "" {
+		drifts = append(drifts, drift{reasonConnectorDrifted, connectorDrift})
+	}
+
+	return drifts, nil
+}
+
+func (i *ReconcileIteration) detectConnectorDrift() (string, error) {
+	name := i.Instance.Status.ConnectorName
+
+	connector, err := connect.GetConnector(i.ctx, i.Client, name, i.connectorNamespace())
+	if k8errors.IsNotFound(err) {
+		return fmt.Sprintf("connector %s does not exist", name), nil
+	} else if err != nil {
+		return "", err
+	}
+
+	desired, err := i.createConnector(name, true)
+	if err != nil {
+		return "", err
+	}
+
+	currentSpec, _, err1 := unstructured.NestedMap(connector.UnstructuredContent(), "spec")
+	desiredSpec, _, err2 := unstructured.NestedMap(desired.UnstructuredContent(), "spec")
+	if err1 != nil || err2 != nil {
+		return fmt.Sprintf("spec of connector %s cannot be compared", name), nil
+	}
+
+	if diff := cmp.Diff(currentSpec, desiredSpec, NumberNormalizer); len(diff) > 0 {
+		return fmt.Sprintf("connector %s differs from the rendered connector: %s", name, diff), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+
+Startup check of the environment-specific settings (topics, secrets, Kafka Connect cluster, connector classes, images) in effect for pipelines
+without configuration of their own, i.e. of the CyndiConfig and the cyndi ConfigMap of the cyndi namespace.
+Settings still using the defaults in code are logged separately from those configured, so that a disconnected or on-prem install
+relying on a default by mistake is noticed before the first pipeline fails.
+
+*/
+
+// Logs the environment-specific settings in effect. Fails if the global configuration is invalid
+func ReportEnvironment(c client.Client, log logr.Logger) error {
+	data, err := loadConfigData(c, "")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.BuildCyndiConfig(nil, data)
+	if err != nil {
+		return fmt.Errorf("Error parsing %s configmap in %s: %w", configMapName, globalConfigNamespace, err)
+	}
+
+	var defaults, configured []interface{}
+	for _, setting := range config.EnvironmentSettings(cfg, data) {
+		if setting.Default {
+			defaults = append(defaults, setting.Key, setting.Value)
+		} else {
+			configured = append(configured, setting.Key, setting.Value)
+		}
+	}
+
+	if len(configured) > 0 {
+		log.Info("Configured environment-specific settings", configured...)
+	}
+
+	if len(defaults) > 0 {
+		log.Info("Environment-specific settings using defaults", defaults...)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package faults
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+
+Failure points used by integration tests to verify that the operator recovers from failures in the middle of a reconciliation,
+e.g. a database error on the Nth query or a table created but not set up. A failure aborts the reconciliation at the given point
+leaving behind the same partial state (tables, connectors) a crash of the operator would.
+Faults are only injected by tests (see Inject). Unless a fault is injected a failure point only costs an atomic load.
+
+*/
+
+type Point string
+
+const (
+	// before each attempt to run a statement against a database
+	DBQuery Point = "db.query"
+	// before a connector is created
+	ConnectorCreate Point = "connector.create"
+	// before a connector is updated (its spec restored or its metadata updated)
+	ConnectorUpdate Point = "connector.update"
+	// after the table of a pipeline version is created but before it is set up (primary key check, storage parameters, grants)
+	TableCreated Point = "table.created"
+)
+
+type Fault struct {
+	// the pass through the failure point that fails first, counted from 1. 0 fails the first pass
+	Nth int64
+	// number of consecutive passes that fail, 0 for all passes starting with the Nth one
+	Times int64
+	Err   error
+}
+
+var (
+	enabled int32
+	lock    sync.Mutex
+	faults  = map[Point]Fault{}
+	passes  = map[Point]int64{}
+)
+
+// Makes the given failure point fail as described by the fault. Passes through the point are counted from the injection
+func Inject(point Point, fault Fault) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	faults[point] = fault
+	passes[point] = 0
+	atomic.StoreInt32(&enabled, 1)
+}
+
+// Removes all the injected faults
+func Reset() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	faults = map[Point]Fault{}
+	passes = map[Point]int64{}
+	atomic.StoreInt32(&enabled, 0)
+}
+
+// Returns the number of passes through the given failure point since a fault was injected into it
+func Passes(point Point) int64 {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return passes[point]
+}
+
+// Called by the operator at each failure point. Returns the error of the fault injected into the point if this pass is to fail
+func Check(point Point) error {
+	if atomic.LoadInt32(&enabled) == 0 {
+		return nil
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	fault, injected := faults[point]
+	if !injected {
+		return nil
+	}
+
+	passes[point]++
+	pass := passes[point]
+
+	first := fault.Nth
+	if first < 1 {
+		first = 1
+	}
+
+	if pass < first || (fault.Times > 0 && pass >= first+fault.Times) {
+		return nil
+	}
+
+	return fmt.Errorf("injected fault at %s (pass %d): %w", point, pass, fault.Err)
+}
@@ -0,0 +1,43 @@
+package faults
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Faults", func() {
+	var errInjected = errors.New("connection reset")
+
+	AfterEach(func() {
+		Reset()
+	})
+
+	It("Passes unless a fault is injected", func() {
+		Expect(Check(DBQuery)).To(Succeed())
+		Expect(Passes(DBQuery)).To(Equal(int64(0)))
+	})
+
+	It("Fails the Nth pass", func() {
+		Inject(DBQuery, Fault{Nth: 2, Times: 1, Err: errInjected})
+
+		Expect(Check(DBQuery)).To(Succeed())
+		Expect(errors.Is(Check(DBQuery), errInjected)).To(BeTrue())
+		Expect(Check(DBQuery)).To(Succeed())
+		Expect(Passes(DBQuery)).To(Equal(int64(3)))
+
+		// other failure points are not affected
+		Expect(Check(ConnectorUpdate)).To(Succeed())
+	})
+
+	It("Fails all passes starting with the Nth one", func() {
+		Inject(TableCreated, Fault{Err: errInjected})
+
+		Expect(Check(TableCreated)).To(HaveOccurred())
+		Expect(Check(TableCreated)).To(HaveOccurred())
+
+		Reset()
+		Expect(Check(TableCreated)).To(Succeed())
+	})
+})
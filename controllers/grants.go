@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+Read access of application roles (spec.dbGrants) to the syndicated hosts.
+Each refresh creates a new table so grants added manually to the previous table would be lost.
+The grants are therefore applied to every new table, to the view and, whenever spec.dbGrants changes, to the current tables.
+Roles removed from spec.dbGrants are not revoked.
+
+*/
+
+func (i *ReconcileIteration) dbGrantRoles() []string {
+	grants := i.Instance.Spec.DBGrants
+	if grants == nil {
+		return nil
+	}
+
+	roles := []string{}
+	for _, role := range grants.Roles {
+		roles = append(roles, string(role))
+	}
+
+	if grants.ReadOnlyRole != nil {
+		roles = append(roles, string(*grants.ReadOnlyRole))
+	}
+
+	return roles
+}
+
+// Grants SELECT on the given tables of the inventory schema to the roles of spec.dbGrants
+func (i *ReconcileIteration) applyDBGrants(tableNames ...string) error {
+	roles := i.dbGrantRoles()
+	if len(roles) == 0 {
+		return nil
+	}
+
+	if readOnlyRole := i.Instance.Spec.DBGrants.ReadOnlyRole; readOnlyRole != nil {
+		if err := i.AppDb.CreateRole(string(*readOnlyRole)); err != nil {
+			return err
+		}
+	}
+
+	i.debug("Applying grants", "roles", roles, "tables", tableNames)
+	return i.AppDb.GrantSelect(roles, tableNames...)
+}
+
+// Applies spec.dbGrants to the view and the tables backing it if spec.dbGrants changed since they were last applied
+func (i *ReconcileIteration) reconcileDBGrants() error {
+	hash, err := utils.SpecHash(i.Instance.Spec.DBGrants)
+	if err != nil {
+		return err
+	}
+
+	if hash == i.Instance.Status.DBGrantsHash {
+		return nil
+	}
+
+	if i.skipMutation("Not applying grants") {
+		return nil
+	}
+
+	tables := []string{i.Instance.Status.TableName}
+	if active := i.Instance.Status.ActiveTableName; active != "" {
+		tables = append(tables, i.viewName())
+
+		if active != i.Instance.Status.TableName {
+			tables = append(tables, active)
+		}
+	}
+
+	if err = i.applyDBGrants(tables...); err != nil {
+		return err
+	}
+
+	i.Instance.Status.DBGrantsHash = hash
+	return nil
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"github.com/go-logr/logr"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+Comparison of host ids (validation.strategy=ids) streamed from both databases in chunks.
+The ordered streams are compared using a merge join so that the operator only holds a chunk of each stream and the mismatched ids.
+Both are bound by the memory budget (validation.memory.budget): half of it is used for the chunks, a quarter for each list of mismatched ids.
+Mismatched hosts beyond the limit (or validation.mismatched.ids.max) are counted but their ids are not kept - they are logged at the debug level
+in pages of ids if validation.mismatched.ids.log is set.
+
+*/
+
+// approximate size of a host id held in memory (36 characters and the string header)
+const idMemorySize = 52
+
+// Hosts found in only one of the databases. The counts are exact while the lists of ids may be capped
+type idComparison struct {
+	hbiCount int64
+
+	inHbiOnly      []string
+	inAppOnly      []string
+	inHbiOnlyCount int64
+	inAppOnlyCount int64
+
+	// host ids have not been compared (validation.strategy=counts), the counts are the difference of the host counts
+	countsOnly bool
+
+	// number of ids of each category kept, the others are passed to the overflow log (if any)
+	maxIds   int
+	overflow *idOverflowLog
+}
+
+// Returns an empty comparison keeping as many mismatched ids as the memory budget and validation.mismatched.ids.max allow
+func (i *ReconcileIteration) newIdComparison() idComparison {
+	_, maxIds := i.idStreamLimits(1)
+
+	result := idComparison{inHbiOnly: []string{}, inAppOnly: []string{}, maxIds: maxIds}
+	if i.config.MismatchedIdsConfig.Log {
+		result.overflow = &idOverflowLog{log: i.Log.V(1), pages: map[string][]string{}, pageNumbers: map[string]int{}}
+	}
+
+	return result
+}
+
+func (c *idComparison) addInHbiOnly(id string) {
+	c.inHbiOnlyCount++
+	if len(c.inHbiOnly) < c.maxIds {
+		c.inHbiOnly = append(c.inHbiOnly, id)
+	} else {
+		c.overflow.add("inHbiOnly", id)
+	}
+}
+
+func (c *idComparison) addInAppOnly(id string) {
+	c.inAppOnlyCount++
+	if len(c.inAppOnly) < c.maxIds {
+		c.inAppOnly = append(c.inAppOnly, id)
+	} else {
+		c.overflow.add("inAppOnly", id)
+	}
+}
+
+func (c *idComparison) truncated() bool {
+	return int64(len(c.inHbiOnly)) < c.inHbiOnlyCount || int64(len(c.inAppOnly)) < c.inAppOnlyCount
+}
+
+// Summarizes the comparison for the status, with up to the given number of ids of each category. Nil if host ids have not been compared
+func (c *idComparison) sample(size int) *cyndi.MismatchedHostsStatus {
+	if c.countsOnly {
+		return nil
+	}
+
+	return &cyndi.MismatchedHostsStatus{
+		InHbiOnlyCount: c.inHbiOnlyCount,
+		InAppOnlyCount: c.inAppOnlyCount,
+		InHbiOnly:      c.inHbiOnly[:utils.Min(size, len(c.inHbiOnly))],
+		InAppOnly:      c.inAppOnly[:utils.Min(size, len(c.inAppOnly))],
+	}
+}
+
+// number of ids logged at once by idOverflowLog
+const idOverflowPageSize = 1000
+
+// Logs ids of mismatched hosts that are not kept (validation.mismatched.ids.log) in pages so that no single log record gets huge
+type idOverflowLog struct {
+	log         logr.Logger
+	pages       map[string][]string
+	pageNumbers map[string]int
+}
+
+func (l *idOverflowLog) add(category string, id string) {
+	if l == nil {
+		return
+	}
+
+	l.pages[category] = append(l.pages[category], id)
+	if len(l.pages[category]) >= idOverflowPageSize {
+		l.logPage(category)
+	}
+}
+
+// Logs the ids of incomplete pages
+func (l *idOverflowLog) flush() {
+	if l == nil {
+		return
+	}
+
+	for _, category := range []string{"inHbiOnly", "inAppOnly"} {
+		if len(l.pages[category]) > 0 {
+			l.logPage(category)
+		}
+	}
+}
+
+func (l *idOverflowLog) logPage(category string) {
+	l.pageNumbers[category]++
+	l.log.Info("Mismatched host ids", "category", category, "page", l.pageNumbers[category], "ids", l.pages[category])
+	l.pages[category] = nil
+}
+
+// Splits the memory budget between the chunks of the given number of streams and the lists of mismatched ids
+func (i *ReconcileIteration) idStreamLimits(streams int) (chunkSize int, maxMismatchedIds int) {
+	ids := i.config.ValidationMemoryBudget / idMemorySize
+
+	chunkSize = int(ids / 2 / int64(streams))
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+
+	maxMismatchedIds = int(ids / 4)
+	if maxMismatchedIds < 1 {
+		maxMismatchedIds = 1
+	}
+
+	// the configured limit applies even if the budget allows for more
+	maxMismatchedIds = utils.Min(maxMismatchedIds, int(i.config.MismatchedIdsConfig.Max))
+	return chunkSize, maxMismatchedIds
+}
+
+// Host ids of the topic shards as a single ordered stream. Sources that cannot stream ids (the HBI API) are read at once
+func (i *ReconcileIteration) streamHbiHostIds(chunkSize int) (database.HostIdIterator, error) {
+	streamer, ok := i.Inventory.(database.HostIdStreamer)
+	if !ok {
+		ids, err := i.getHbiHostIds()
+		if err != nil {
+			return nil, err
+		}
+
+		return database.NewSliceIdIterator(ids), nil
+	}
+
+	var iterators []database.HostIdIterator
+	for _, filters := range i.hbiFilters() {
+		iterators = append(iterators, streamer.StreamHostIds(inventoryTableName, i.Instance.Spec.InsightsOnly, filters, chunkSize))
+	}
+
+	return database.MergeHostIdIterators(iterators...), nil
+}
+
+func (i *ReconcileIteration) compareHostIds(appTable string) (result idComparison, err error) {
+	// each shard of each topic shard is read separately, next to the application table
+	shards := len(i.HBIDBParams)
+	if shards < 1 {
+		shards = 1
+	}
+
+	chunkSize, _ := i.idStreamLimits(len(i.hbiFilters())*shards + 1)
+
+	hbi, err := i.streamHbiHostIds(chunkSize)
+	if err != nil {
+		return result, err
+	}
+
+	app := i.AppDb.StreamHostIds(appTable, false, []map[string]string{}, chunkSize)
+
+	result = i.newIdComparison()
+	if err = mergeJoinIds(hbi, app, &result); err != nil {
+		return result, err
+	}
+
+	result.overflow.flush()
+	i.Log.Info("Compared host ids", "chunkSize", chunkSize, "hbi", result.hbiCount, "truncated", result.truncated())
+	return result, nil
+}
+
+// Walks both ordered streams at once collecting ids found in only one of them
+func mergeJoinIds(hbi database.HostIdIterator, app database.HostIdIterator, result *idComparison) (err error) {
+	hbiId, hbiOk, err := hbi.Next()
+	if err != nil {
+		return err
+	}
+
+	appId, appOk, err := app.Next()
+	if err != nil {
+		return err
+	}
+
+	for hbiOk || appOk {
+		switch {
+		case hbiOk && appOk && hbiId == appId:
+			result.hbiCount++
+			if hbiId, hbiOk, err = hbi.Next(); err != nil {
+				return err
+			}
+
+			if appId, appOk, err = app.Next(); err != nil {
+				return err
+			}
+		case hbiOk && (!appOk || hbiId < appId):
+			result.hbiCount++
+			result.addInHbiOnly(hbiId)
+			if hbiId, hbiOk, err = hbi.Next(); err != nil {
+				return err
+			}
+		default:
+			result.addInAppOnly(appId)
+			if appId, appOk, err = app.Next(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
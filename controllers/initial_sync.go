@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+Detection and remediation of an initial sync that stopped making progress.
+The throughput of the initial sync is estimated from the growth of the host count between validations.
+Together with the host count of HBI it gives the time the sync is expected to complete at.
+An initial sync that does not complete (the pipeline does not become valid) within init.timeout / spec.initialSyncTimeout
+is escalated once according to init.timeout.action.
+
+*/
+
+const reasonInitialSyncStuck = "InitialSyncStuck"
+const reasonSyncDeadlineExceeded = "SyncDeadlineExceeded"
+const reasonRefreshAborted = "RefreshAborted"
+
+// Records progress of the initial sync. Needs to be called before the new host count is stored in the status.
+func (i *ReconcileIteration) trackInitialSyncProgress(hostCount int64) {
+	if !i.Instance.Status.InitialSyncInProgress {
+		return
+	}
+
+	if i.Instance.Status.InitialSyncLastProgress == nil || hostCount > i.Instance.Status.HostCount {
+		now := metav1.Now()
+		i.Instance.Status.InitialSyncLastProgress = &now
+
+		if i.isInitialSyncStuckDetected() {
+			i.Instance.SetDegraded(metav1.ConditionFalse, "InitialSyncProgressing", "Initial sync is making progress again")
+		}
+	}
+}
+
+// counts taken closer together than this are not representative of the throughput
+const minThroughputInterval = 10 * time.Second
+
+// Records the throughput of the initial sync as counted at the given time.
+// The growth is measured against status.initialSyncThroughput only, regardless of status.hostCount.
+func (i *ReconcileIteration) trackInitialSyncThroughput(hbiHostCount int64, hostCount int64, now metav1.Time) {
+	defer metrics.InitialSyncThroughput(i.Instance)
+
+	if !i.Instance.Status.InitialSyncInProgress {
+		i.Instance.Status.InitialSyncThroughput = nil
+		return
+	}
+
+	previous := i.Instance.Status.InitialSyncThroughput
+
+	if previous == nil {
+		i.Instance.Status.InitialSyncThroughput = &cyndi.ThroughputStatus{HostCount: hostCount, CountedAt: now}
+		return
+	}
+
+	elapsed := now.Sub(previous.CountedAt.Time)
+	if elapsed < minThroughputInterval {
+		return
+	}
+
+	// hosts deleted in the meantime do not make the throughput negative
+	added := hostCount - previous.HostCount
+	if added < 0 {
+		added = 0
+	}
+
+	throughput := &cyndi.ThroughputStatus{
+		RowsPerMinute: int64(float64(added) / elapsed.Minutes()),
+		HostCount:     hostCount,
+		CountedAt:     now,
+	}
+
+	remaining := hbiHostCount - hostCount
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	if throughput.RowsPerMinute > 0 {
+		completion := metav1.NewTime(now.Add(time.Duration(float64(remaining) / float64(throughput.RowsPerMinute) * float64(time.Minute))))
+		throughput.EstimatedCompletion = &completion
+	}
+
+	i.Instance.Status.InitialSyncThroughput = throughput
+
+	// a table that caught up with HBI is not slow
+	throughput.BelowMinimum = i.config.InitialSyncThroughputMin > 0 && remaining > 0 && throughput.RowsPerMinute < i.config.InitialSyncThroughputMin
+
+	if throughput.BelowMinimum && !previous.BelowMinimum {
+		i.probeInitialSyncSlow()
+	}
+}
+
+func (i *ReconcileIteration) isInitialSyncStuck() bool {
+	if i.config.InitialSyncStuckTimeout <= 0 || i.Instance.Status.InitialSyncLastProgress == nil {
+		return false
+	}
+
+	return time.Since(i.Instance.Status.InitialSyncLastProgress.Time) > time.Duration(i.config.InitialSyncStuckTimeout)*time.Second
+}
+
+func (i *ReconcileIteration) isInitialSyncStuckDetected() bool {
+	condition := i.Instance.GetDegraded()
+	return condition != nil && condition.Status == metav1.ConditionTrue && condition.Reason == reasonInitialSyncStuck
+}
+
+/*
+ * Marks the pipeline as Degraded if the host count has not grown for too long and applies the configured remediation.
+ * Returns true if the pipeline has been transitioned to STATE_NEW as a result.
+ */
+func (i *ReconcileIteration) checkInitialSyncStuck() (refreshed bool, err error) {
+	if !i.isInitialSyncStuck() {
+		return false, nil
+	}
+
+	if !i.isInitialSyncStuckDetected() {
+		i.probeInitialSyncStuck()
+		i.Instance.SetDegraded(metav1.ConditionTrue, reasonInitialSyncStuck, "Host count has not grown since "+i.Instance.Status.InitialSyncLastProgress.Format(time.RFC3339))
+	}
+
+	if i.skipMutation("Not remediating stuck initial sync", "action", i.config.InitialSyncStuckAction) {
+		return false, nil
+	}
+
+	switch i.config.InitialSyncStuckAction {
+	case config.StuckActionRestartConnector:
+		if err = connect.RestartConnector(i.ctx, i.Client, i.Instance.Status.ConnectorName, i.connectorNamespace()); err != nil {
+			return false, err
+		}
+
+		i.probeRestartingStuckConnector()
+
+		// give the restarted connector another full timeout to make progress
+		now := metav1.Now()
+		i.Instance.Status.InitialSyncLastProgress = &now
+	case config.StuckActionRefresh:
+		i.probeStuckRefresh()
+		i.Instance.TransitionToNew()
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (i *ReconcileIteration) isInitialSyncDeadlineExceeded() bool {
+	started := i.Instance.Status.InitialSyncStarted
+	if i.config.InitialSyncTimeout <= 0 || started == nil {
+		return false
+	}
+
+	return time.Since(started.Time) > time.Duration(i.config.InitialSyncTimeout)*time.Second
+}
+
+/*
+ * Marks the pipeline with the SyncDeadlineExceeded condition if it has not become valid within the initial sync timeout and applies the configured action.
+ * Returns true if the refresh has been aborted as a result.
+ */
+func (i *ReconcileIteration) checkInitialSyncDeadline() (aborted bool, err error) {
+	if i.Instance.GetState() != cyndi.STATE_INITIAL_SYNC {
+		// kept until the next refresh (see isRefreshAborted)
+		if condition := i.Instance.GetSyncDeadlineExceeded(); condition == nil || condition.Reason != reasonRefreshAborted {
+			i.Instance.ResetSyncDeadlineExceeded()
+		}
+
+		return false, nil
+	}
+
+	// escalated once per initial sync
+	if !i.isInitialSyncDeadlineExceeded() || i.Instance.GetSyncDeadlineExceeded() != nil {
+		return false, nil
+	}
+
+	message := fmt.Sprintf("Pipeline did not become valid within %d seconds of %s", i.config.InitialSyncTimeout, i.Instance.Status.InitialSyncStarted.Format(time.RFC3339))
+
+	if i.skipMutation("Not escalating exceeded initial sync deadline", "action", i.config.InitialSyncTimeoutAction) {
+		i.probeSyncDeadlineExceeded()
+		i.Instance.SetSyncDeadlineExceeded(metav1.ConditionTrue, reasonSyncDeadlineExceeded, message)
+		return false, nil
+	}
+
+	switch i.config.InitialSyncTimeoutAction {
+	case config.TimeoutActionRestartConnector:
+		if err = connect.RestartConnector(i.ctx, i.Client, i.Instance.Status.ConnectorName, i.connectorNamespace()); err != nil {
+			return false, err
+		}
+
+		i.probeRestartingLateConnector()
+	case config.TimeoutActionAbort:
+		if aborted, err = i.abortRefresh(); err != nil {
+			return false, err
+		} else if aborted {
+			// set after the transition, which resets the condition
+			i.Instance.SetSyncDeadlineExceeded(metav1.ConditionTrue, reasonRefreshAborted, message)
+			return true, nil
+		}
+	}
+
+	i.probeSyncDeadlineExceeded()
+	i.Instance.SetSyncDeadlineExceeded(metav1.ConditionTrue, reasonSyncDeadlineExceeded, message)
+	return false, nil
+}
+
+/*
+ * Gives up on the table being seeded in favor of the table backing the view, whose pipeline version becomes current (and valid) again.
+ * The abandoned table and its connector are then removed as stale. Returns false if the view does not point to a previous table.
+ */
+func (i *ReconcileIteration) abortRefresh() (bool, error) {
+	current, err := i.AppDb.GetCurrentTable()
+	if err != nil {
+		return false, err
+	} else if current == nil || *current == i.Instance.Status.TableName {
+		i.Log.Info("Not aborting initial sync as there is no previous table to fall back to")
+		return false, nil
+	}
+
+	hostCount, err := i.AppDb.CountHosts(database.AppTable(*current), false, []map[string]string{})
+	if err != nil {
+		return false, err
+	}
+
+	abandoned := i.Instance.Status.TableName
+	i.probeSyncDeadlineExceeded()
+
+	i.Instance.TransitionToNew()
+	if err = i.Instance.TransitionToInitialSync(cyndi.TableNameToPipelineVersion(*current)); err != nil {
+		return false, err
+	}
+
+	// the table backing the view passed validation before - it is not validated against the init thresholds again
+	i.Instance.SetValid(metav1.ConditionTrue, reasonRefreshAborted, fmt.Sprintf("Fell back to %s as the refresh exceeded its deadline", *current), hostCount)
+	i.Instance.Status.InitialSyncStarted = nil
+	i.Instance.Status.InitialSyncLastProgress = nil
+
+	if err = i.recoverConsumerGroup(); err != nil {
+		return false, err
+	}
+
+	i.probeRefreshAborted(abandoned)
+	return true, nil
+}
+
+/*
+ * The connector of the pipeline version fallen back to by an aborted refresh does not reflect the configuration the refresh was started for.
+ * Refreshing the pipeline because of that would most likely exceed the deadline again, so the connector is only compared with the spec
+ * it was created from until the spec or the ConfigMap changes again or a resync is requested.
+ */
+func (i *ReconcileIteration) isRefreshAborted() bool {
+	condition := i.Instance.GetSyncDeadlineExceeded()
+	if condition == nil || condition.Reason != reasonRefreshAborted {
+		return false
+	}
+
+	return i.Instance.Status.SpecHash == i.config.SpecHash && i.Instance.Status.CyndiConfigVersion == i.config.ConfigMapVersion && !i.resyncRequested()
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// metrics are otherwise only registered by main
+var registerMetrics sync.Once
+
+// Reads the cyndi_initial_sync_rows_per_minute gauge of the given app
+func initialSyncThroughputMetric(app string) float64 {
+	registerMetrics.Do(metrics.Init)
+
+	families, err := ctrlmetrics.Registry.Gather()
+	Expect(err).ToNot(HaveOccurred())
+
+	for _, family := range families {
+		if family.GetName() != "cyndi_initial_sync_rows_per_minute" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "app" && label.GetValue() == app {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+
+	Fail("cyndi_initial_sync_rows_per_minute not reported for " + app)
+	return 0
+}
+
+var _ = Describe("Initial sync throughput", func() {
+	var (
+		i     *ReconcileIteration
+		start time.Time
+	)
+
+	// Records the host counts as counted the given time after start
+	track := func(after time.Duration, hbiHostCount int64, hostCount int64) *cyndi.ThroughputStatus {
+		i.trackInitialSyncThroughput(hbiHostCount, hostCount, metav1.NewTime(start.Add(after)))
+		return i.Instance.Status.InitialSyncThroughput
+	}
+
+	BeforeEach(func() {
+		start = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+		i = &ReconcileIteration{
+			Instance: &cyndi.CyndiPipeline{
+				ObjectMeta: metav1.ObjectMeta{Name: "advisor", Namespace: "test"},
+				Spec:       cyndi.CyndiPipelineSpec{AppName: "advisor"},
+				Status:     cyndi.CyndiPipelineStatus{InitialSyncInProgress: true, TableName: "hosts_v1_1"},
+			},
+			Recorder: record.NewFakeRecorder(10),
+			Log:      logf.Log.WithName("test"),
+			config:   &config.CyndiConfiguration{InitialSyncThroughputMin: 1000},
+		}
+	})
+
+	It("Records the first count without an estimate", func() {
+		throughput := track(0, 13000, 1000)
+		Expect(throughput.HostCount).To(Equal(int64(1000)))
+		Expect(throughput.CountedAt.Time).To(BeTemporally("==", start))
+		Expect(throughput.RowsPerMinute).To(Equal(int64(0)))
+		Expect(throughput.EstimatedCompletion).To(BeNil())
+		Expect(throughput.BelowMinimum).To(BeFalse())
+	})
+
+	It("Ignores counts taken too close to the previous one", func() {
+		track(0, 13000, 1000)
+		throughput := track(minThroughputInterval-time.Second, 13000, 2000)
+		Expect(throughput.HostCount).To(Equal(int64(1000)))
+		Expect(throughput.CountedAt.Time).To(BeTemporally("==", start))
+	})
+
+	It("Estimates the completion from the rows added per minute", func() {
+		track(0, 13000, 1000)
+		throughput := track(2*time.Minute, 13000, 5000)
+		Expect(throughput.RowsPerMinute).To(Equal(int64(2000)))
+		Expect(throughput.HostCount).To(Equal(int64(5000)))
+		Expect(throughput.CountedAt.Time).To(BeTemporally("==", start.Add(2*time.Minute)))
+		// 8000 remaining hosts at 2000 per minute
+		Expect(throughput.EstimatedCompletion.Time).To(BeTemporally("==", start.Add(6*time.Minute)))
+		Expect(throughput.BelowMinimum).To(BeFalse())
+		Expect(recordedEvents(i.Recorder)).To(BeEmpty())
+	})
+
+	It("Does not estimate the completion of a table that does not grow", func() {
+		track(0, 13000, 5000)
+		throughput := track(2*time.Minute, 13000, 4000)
+		Expect(throughput.RowsPerMinute).To(Equal(int64(0)))
+		Expect(throughput.EstimatedCompletion).To(BeNil())
+	})
+
+	It("Warns once while the throughput is below init.throughput.min", func() {
+		track(0, 13000, 1000)
+		throughput := track(2*time.Minute, 13000, 2000)
+		Expect(throughput.RowsPerMinute).To(Equal(int64(500)))
+		Expect(throughput.BelowMinimum).To(BeTrue())
+		Expect(recordedEvents(i.Recorder)).To(ConsistOf(HavePrefix("Warning InitialSyncSlow")))
+
+		throughput = track(4*time.Minute, 13000, 2500)
+		Expect(throughput.BelowMinimum).To(BeTrue())
+		Expect(recordedEvents(i.Recorder)).To(BeEmpty())
+
+		// recovered
+		throughput = track(6*time.Minute, 13000, 6500)
+		Expect(throughput.BelowMinimum).To(BeFalse())
+
+		throughput = track(8*time.Minute, 13000, 6600)
+		Expect(throughput.BelowMinimum).To(BeTrue())
+		Expect(recordedEvents(i.Recorder)).To(ConsistOf(HavePrefix("Warning InitialSyncSlow")))
+	})
+
+	It("Does not consider a table that caught up with HBI slow", func() {
+		track(0, 13000, 12900)
+		throughput := track(2*time.Minute, 13000, 13000)
+		Expect(throughput.RowsPerMinute).To(Equal(int64(50)))
+		Expect(throughput.BelowMinimum).To(BeFalse())
+		Expect(recordedEvents(i.Recorder)).To(BeEmpty())
+	})
+
+	It("Does not warn without init.throughput.min", func() {
+		i.config.InitialSyncThroughputMin = 0
+		track(0, 13000, 1000)
+		throughput := track(2*time.Minute, 13000, 1100)
+		Expect(throughput.BelowMinimum).To(BeFalse())
+		Expect(recordedEvents(i.Recorder)).To(BeEmpty())
+	})
+
+	It("Clears the throughput once the initial sync is over", func() {
+		track(0, 13000, 1000)
+		track(2*time.Minute, 13000, 5000)
+		Expect(initialSyncThroughputMetric("advisor")).To(Equal(2000.0))
+
+		i.Instance.Status.InitialSyncInProgress = false
+		Expect(track(4*time.Minute, 13000, 9000)).To(BeNil())
+		Expect(initialSyncThroughputMetric("advisor")).To(Equal(0.0))
+	})
+
+	It("Does not report the throughput of a previous pipeline version", func() {
+		track(0, 13000, 1000)
+		track(2*time.Minute, 13000, 5000)
+		Expect(initialSyncThroughputMetric("advisor")).To(Equal(2000.0))
+
+		Expect(i.Instance.TransitionToNew()).To(Succeed())
+		Expect(i.Instance.TransitionToInitialSync("2")).To(Succeed())
+		Expect(track(4*time.Minute, 13000, 0).RowsPerMinute).To(Equal(int64(0)))
+		Expect(initialSyncThroughputMetric("advisor")).To(Equal(0.0))
+	})
+})
@@ -2,13 +2,13 @@ package controllers
 
 import (
 	"context"
-	"fmt"
 	logr "github.com/go-logr/logr/testing"
 
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
 	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
 	"github.com/RedHatInsights/cyndi-operator/controllers/database"
 	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+	cynditesting "github.com/RedHatInsights/cyndi-operator/pkg/testing"
 	"github.com/RedHatInsights/cyndi-operator/test"
 
 	. "github.com/RedHatInsights/cyndi-operator/controllers/config"
@@ -47,12 +47,8 @@ var _ = Describe("Integration tests", func() {
 	}
 
 	var seedAppTable = func(db database.Database, TestTable string, ids ...string) {
-		template := `INSERT INTO %s (id, account, org_id, display_name, tags, updated, created, stale_timestamp, system_profile, reporter, per_reporter_staleness) VALUES ('%s', '000001', 'test01', 'test01', '{}', NOW(), NOW(), NOW(), '{}', 'puptoo', '{}')`
-
-		for _, id := range ids {
-			_, err := db.Exec(fmt.Sprintf(template, TestTable, id))
-			Expect(err).ToNot(HaveOccurred())
-		}
+		err := cynditesting.SeedAppTable(db, TestTable, ids...)
+		Expect(err).ToNot(HaveOccurred())
 	}
 
 	BeforeEach(func() {
@@ -139,7 +135,7 @@ var _ = Describe("Integration tests", func() {
 			}
 
 			// keeps validating while the first few hosts are replicated
-			appTable := utils.AppFullTableName(pipeline.Status.TableName)
+			appTable := database.AppTable(pipeline.Status.TableName)
 			seedAppTable(appDb, appTable, insightsHosts[0:2]...)
 
 			pipeline = reconcile(validationReconciler)
@@ -189,7 +185,7 @@ var _ = Describe("Integration tests", func() {
 			Expect(pipeline.GetValid()).To(Equal(metav1.ConditionUnknown))
 
 			appTable1 := pipeline.Status.TableName
-			seedAppTable(appDb, utils.AppFullTableName(appTable1), insightsHosts...)
+			seedAppTable(appDb, database.AppTable(appTable1), insightsHosts...)
 
 			// transitions to valid as all hosts are replicated
 			pipeline = reconcile(validationReconciler, cyndiReconciler)
@@ -217,8 +213,8 @@ var _ = Describe("Integration tests", func() {
 			Expect(pipeline.Status.TableName).ToNot(Equal(pipeline.Status.ActiveTableName))
 
 			appTable2 := pipeline.Status.TableName
-			seedAppTable(appDb, utils.AppFullTableName(appTable2), insightsHosts...)
-			seedAppTable(appDb, utils.AppFullTableName(appTable2), newHost)
+			seedAppTable(appDb, database.AppTable(appTable2), insightsHosts...)
+			seedAppTable(appDb, database.AppTable(appTable2), newHost)
 
 			pipeline = reconcile(validationReconciler, cyndiReconciler)
 			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_VALID))
@@ -245,7 +241,7 @@ var _ = Describe("Integration tests", func() {
 			Expect(pipeline.GetValid()).To(Equal(metav1.ConditionUnknown))
 
 			appTable := pipeline.Status.TableName
-			seedAppTable(appDb, utils.AppFullTableName(appTable), insightsHosts...)
+			seedAppTable(appDb, database.AppTable(appTable), insightsHosts...)
 
 			// transitions to valid as all hosts are replicated
 			pipeline = reconcile(validationReconciler, cyndiReconciler)
@@ -265,7 +261,7 @@ var _ = Describe("Integration tests", func() {
 			pipeline, err = utils.FetchCyndiPipeline(test.Client, namespacedName)
 			Expect(errors.IsNotFound(err)).To(BeTrue())
 
-			connectors, err := connect.GetConnectorsForOwner(test.Client, namespacedName.Namespace, pipeline.GetUIDString())
+			connectors, err := connect.GetConnectorsForOwner(context.TODO(), test.Client, namespacedName.Namespace, pipeline.GetUIDString())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(connectors.Items).To(BeEmpty())
 
@@ -301,7 +297,7 @@ var _ = Describe("Integration tests", func() {
 				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
 
 				appTable1 := pipeline.Status.TableName
-				seedAppTable(appDb, utils.AppFullTableName(appTable1), insightsHosts[0:1]...)
+				seedAppTable(appDb, database.AppTable(appTable1), insightsHosts[0:1]...)
 
 				// transitions to valid as all hosts are replicated
 				pipeline = reconcile(validationReconciler, cyndiReconciler)
@@ -318,7 +314,7 @@ var _ = Describe("Integration tests", func() {
 				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
 
 				appTable2 := pipeline.Status.TableName
-				seedAppTable(appDb, utils.AppFullTableName(appTable2), insightsHosts[0:3]...) // replicate 3 hosts - still not valid but better than appTable1
+				seedAppTable(appDb, database.AppTable(appTable2), insightsHosts[0:3]...) // replicate 3 hosts - still not valid but better than appTable1
 
 				// as this pipeline fails to become valid eventually it is refreshed again
 				for ; pipeline.GetState() != cyndi.STATE_NEW; pipeline = reconcile(validationReconciler, cyndiReconciler) {
@@ -353,7 +349,7 @@ var _ = Describe("Integration tests", func() {
 				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
 
 				appTable1 := pipeline.Status.TableName
-				seedAppTable(appDb, utils.AppFullTableName(appTable1), insightsHosts[0:3]...)
+				seedAppTable(appDb, database.AppTable(appTable1), insightsHosts[0:3]...)
 
 				// transitions to valid as all hosts are replicated
 				pipeline = reconcile(validationReconciler, cyndiReconciler)
@@ -370,7 +366,7 @@ var _ = Describe("Integration tests", func() {
 				Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
 
 				appTable2 := pipeline.Status.TableName
-				seedAppTable(appDb, utils.AppFullTableName(appTable2), insightsHosts[0:2]...) // replicate 2 hosts - still not valid and worse than appTable1
+				seedAppTable(appDb, database.AppTable(appTable2), insightsHosts[0:2]...) // replicate 2 hosts - still not valid and worse than appTable1
 
 				// as this pipeline fails to become valid eventually it is refreshed again
 				for ; pipeline.GetState() != cyndi.STATE_NEW; pipeline = reconcile(validationReconciler, cyndiReconciler) {
@@ -4,19 +4,24 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
 	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/connect"
 	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -34,15 +39,37 @@ type ReconcileIteration struct {
 	Client    client.Client
 	Clientset *kubernetes.Clientset
 
-	config      *config.CyndiConfiguration
-	HBIDBParams config.DBParams
-	AppDBParams config.DBParams
+	config       *config.CyndiConfiguration
+	HBIDBParams  []config.DBParams // one per HBI shard
+	HBIAPIParams config.APIParams  // used instead of HBIDBParams if hosts are fetched from the HBI API
+	AppDBParams  config.DBParams
 
-	AppDb       *database.AppDatabase
-	InventoryDb database.Database
+	AppDb     database.ApplicationDatabase
+	Inventory database.HostSource
 
 	Now string
 
+	// Remaining time for which a repeatedly failing pipeline is not reconciled
+	throttledFor time.Duration
+
+	// see CyndiPipelineReconciler.ReadOnly
+	readOnly bool
+
+	// see CyndiPipelineReconciler.OperatorNamespace
+	operatorNamespace string
+
+	// see CyndiPipelineReconciler.HostCache
+	hostCache *database.HostCache
+
+	// see CyndiPipelineReconciler.NewInventoryDatabase
+	newInventoryDatabase func(params *config.DBParams) database.Database
+
+	// application databases bootstrapped by this operator process (see bootstrapAppDb)
+	bootstrapped *sync.Map
+
+	// steps of the reconciliation that failed in this iteration (see runStep)
+	failedSteps map[cyndi.ReconcileStep]error
+
 	GetRequeueInterval func(i *ReconcileIteration) (result int64)
 }
 
@@ -51,9 +78,48 @@ func (i *ReconcileIteration) Close() {
 		i.AppDb.Close()
 	}
 
-	if i.InventoryDb != nil {
-		i.InventoryDb.Close()
+	if i.Inventory != nil {
+		i.Inventory.Close()
+	}
+}
+
+// connects to the source of HBI hosts - the HBI API or the inventory database(s) as HBI may be sharded across multiple databases
+func (i *ReconcileIteration) connectInventory() error {
+	if i.config.InventorySource == config.InventorySourceAPI {
+		i.Inventory = database.NewAPISource(&i.HBIAPIParams)
+		database.UseCache(i.Inventory, i.hostCache)
+		i.Inventory.SetContext(i.ctx)
+		return i.Inventory.Connect()
+	}
+
+	shards := make([]database.Database, len(i.HBIDBParams))
+	for idx := range i.HBIDBParams {
+		if i.newInventoryDatabase != nil {
+			shards[idx] = i.newInventoryDatabase(&i.HBIDBParams[idx])
+		} else {
+			shards[idx] = database.NewBaseDatabase(&i.HBIDBParams[idx], i.Log)
+		}
+	}
+
+	if len(shards) == 1 {
+		i.Inventory = shards[0]
+	} else {
+		i.Inventory = database.NewShardedDatabase(shards...)
+	}
+
+	database.UseCache(i.Inventory, i.hostCache)
+	database.CaptureSlowQueries(i.Inventory, time.Duration(i.config.ValidationSlowQueryThreshold)*time.Second)
+	i.Inventory.SetContext(i.ctx)
+	return i.Inventory.Connect()
+}
+
+// Returns true (and logs the skipped action) if connectors and databases must not be modified
+func (i *ReconcileIteration) skipMutation(message string, keysAndValues ...interface{}) bool {
+	if i.readOnly {
+		i.Log.Info(message+" in read-only mode", keysAndValues...)
 	}
+
+	return i.readOnly
 }
 
 // logs the error and produces an error log message
@@ -91,6 +157,20 @@ func (i *ReconcileIteration) getValidationConfig() config.ValidationConfiguratio
 	return i.config.ValidationConfig
 }
 
+// Namespace of the Kafka Connect cluster connectors of the pipeline are created in
+// Labels of every resource created for the pipeline, regardless of whether it is owned by the pipeline
+func (i *ReconcileIteration) pipelineLabels() map[string]string {
+	return map[string]string{connect.LabelPipeline: i.Instance.Name}
+}
+
+func (i *ReconcileIteration) connectorNamespace() string {
+	if i.config.ConnectClusterNamespace != "" {
+		return i.config.ConnectClusterNamespace
+	}
+
+	return i.Instance.Namespace
+}
+
 func (i *ReconcileIteration) updateStatusAndRequeue() (reconcile.Result, error) {
 	// Update Status.ActiveTableName to reflect the active table regardless of what happened in this Reconcile() invocation
 	if table, err := i.AppDb.GetCurrentTable(); err != nil {
@@ -103,12 +183,30 @@ func (i *ReconcileIteration) updateStatusAndRequeue() (reconcile.Result, error)
 		}
 	}
 
+	if err := i.reconcilePipelineMetadata(); err != nil {
+		// not fatal - the metadata table only mirrors the status of the pipeline
+		i.Log.Error(err, "Failed to update pipeline metadata")
+	}
+
+	// the pipeline has been reconciled (or validated) for its current spec
+	i.Instance.ObserveGeneration()
+
+	metrics.PipelineState(i.Instance)
+	metrics.PipelineDegraded(i.Instance)
+	metrics.SyncGeneration(i.Instance)
+	// a pipeline transitioned to NEW no longer has a throughput
+	metrics.InitialSyncThroughput(i.Instance)
+
 	// Only issue status update if Reconcile actually modified Status
 	// This prevents write conflicts between the controllers
 	if !cmp.Equal(i.Instance.Status, i.OriginalInstance.Status) {
 		i.debug("Updating status")
 
-		if err := i.Client.Status().Update(context.TODO(), i.Instance); err != nil {
+		start := time.Now()
+		err := i.Client.Status().Update(context.TODO(), i.Instance)
+		i.recordStep(cyndi.STEP_STATUS, time.Since(start), err)
+
+		if err != nil {
 			if errors.IsConflict(err) {
 				i.Log.Error(err, "Status conflict")
 				return reconcile.Result{}, err
@@ -122,3 +220,10 @@ func (i *ReconcileIteration) updateStatusAndRequeue() (reconcile.Result, error)
 	i.debug("RequeueAfter", "delay", delay)
 	return reconcile.Result{RequeueAfter: delay}, nil
 }
+
+func requestAttributes(request ctrl.Request) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("pipeline.namespace", request.Namespace),
+		attribute.String("pipeline.name", request.Name),
+	}
+}
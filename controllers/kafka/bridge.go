@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+/*
+
+Messages produced through Strimzi Kafka Bridge (the HTTP interface of a Kafka cluster) so that the operator does not need to speak the Kafka protocol.
+
+*/
+
+const bridgeContentType = "application/vnd.kafka.json.v2+json"
+
+type bridgeHeader struct {
+	Key string `json:"key"`
+	// base64-encoded
+	Value string `json:"value"`
+}
+
+type bridgeRecord struct {
+	Key     string         `json:"key"`
+	Value   interface{}    `json:"value"`
+	Headers []bridgeHeader `json:"headers,omitempty"`
+}
+
+type bridgeResponse struct {
+	Offsets []struct {
+		ErrorCode int    `json:"error_code"`
+		Message   string `json:"message"`
+	} `json:"offsets"`
+}
+
+// Produces a single JSON message with the given key and headers to the given topic
+func Produce(ctx context.Context, bridgeURL string, topic string, key string, value interface{}, headers map[string]string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	record := bridgeRecord{Key: key, Value: value}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		record.Headers = append(record.Headers, bridgeHeader{Key: name, Value: base64.StdEncoding.EncodeToString([]byte(headers[name]))})
+	}
+
+	body, err := json.Marshal(map[string][]bridgeRecord{"records": {record}})
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf("%s/topics/%s", strings.TrimSuffix(bridgeURL, "/"), url.PathEscape(topic))
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", bridgeContentType)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("Error producing message to %s: %w", topic, err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error producing message to %s: %s", topic, response.Status)
+	}
+
+	result := bridgeResponse{}
+	if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return fmt.Errorf("Error producing message to %s: %w", topic, err)
+	}
+
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != 0 {
+			return fmt.Errorf("Error producing message to %s: %s", topic, offset.Message)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type producedRequest struct {
+	path        string
+	contentType string
+	body        map[string][]bridgeRecord
+}
+
+func newBridgeServer(requests *[]producedRequest, status int, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := producedRequest{path: r.URL.Path, contentType: r.Header.Get("Content-Type")}
+		_ = json.NewDecoder(r.Body).Decode(&request.body)
+		*requests = append(*requests, request)
+
+		w.WriteHeader(status)
+		fmt.Fprint(w, response)
+	}))
+}
+
+var _ = Describe("Kafka Bridge", func() {
+	var requests []producedRequest
+
+	BeforeEach(func() {
+		requests = nil
+	})
+
+	It("Produces a message", func() {
+		server := newBridgeServer(&requests, http.StatusOK, `{"offsets":[{"partition":0,"offset":15}]}`)
+		defer server.Close()
+
+		err := Produce(context.TODO(), server.URL+"/", "platform.inventory.events", "host-1", map[string]string{"type": "created"}, map[string]string{"event_type": "created"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].path).To(Equal("/topics/platform.inventory.events"))
+		Expect(requests[0].contentType).To(Equal("application/vnd.kafka.json.v2+json"))
+		Expect(requests[0].body["records"]).To(HaveLen(1))
+		Expect(requests[0].body["records"][0].Key).To(Equal("host-1"))
+		Expect(requests[0].body["records"][0].Value).To(Equal(map[string]interface{}{"type": "created"}))
+		// base64-encoded "created"
+		Expect(requests[0].body["records"][0].Headers).To(Equal([]bridgeHeader{{Key: "event_type", Value: "Y3JlYXRlZA=="}}))
+	})
+
+	It("Fails if the message is rejected", func() {
+		server := newBridgeServer(&requests, http.StatusOK, `{"offsets":[{"error_code":404,"message":"Topic not found"}]}`)
+		defer server.Close()
+
+		err := Produce(context.TODO(), server.URL, "platform.inventory.events", "host-1", map[string]string{}, nil)
+		Expect(err).To(MatchError("Error producing message to platform.inventory.events: Topic not found"))
+	})
+
+	It("Fails on an HTTP error", func() {
+		server := newBridgeServer(&requests, http.StatusNotFound, `{"error_code":404,"message":"Not found"}`)
+		defer server.Close()
+
+		err := Produce(context.TODO(), server.URL, "platform.inventory.events", "host-1", map[string]string{}, nil)
+		Expect(err).To(MatchError("Error producing message to platform.inventory.events: 404 Not Found"))
+	})
+})
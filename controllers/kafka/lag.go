@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+
+Consumer group lag as exported by Kafka Exporter (deployed by Strimzi alongside the Kafka cluster) and scraped by Prometheus.
+
+*/
+
+const queryTimeout = 30 * time.Second
+
+// Prometheus query returning the total lag of the given consumer group across all topics and partitions
+func lagQuery(consumerGroup string) string {
+	return fmt.Sprintf(`sum(kafka_consumergroup_lag{consumergroup="%s"})`, strings.ReplaceAll(consumerGroup, `"`, `\"`))
+}
+
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			// [ <unix timestamp>, "<value>" ]
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+/*
+ * Returns the number of messages the given consumer group has yet to consume.
+ * Returns -1 without an error if Prometheus holds no lag of the consumer group (e.g. the connector has not committed any offsets yet).
+ */
+func ConsumerGroupLag(ctx context.Context, prometheusURL string, consumerGroup string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	requestURL := fmt.Sprintf("%s/api/v1/query?%s", strings.TrimSuffix(prometheusURL, "/"), url.Values{"query": {lagQuery(consumerGroup)}}.Encode())
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return -1, err
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return -1, fmt.Errorf("Error querying consumer group lag: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	result := queryResponse{}
+	if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return -1, fmt.Errorf("Error querying consumer group lag: %s", response.Status)
+	}
+
+	if result.Status != "success" {
+		return -1, fmt.Errorf("Error querying consumer group lag: %s", result.Error)
+	}
+
+	if len(result.Data.Result) == 0 || len(result.Data.Result[0].Value) != 2 {
+		return -1, nil
+	}
+
+	value, ok := result.Data.Result[0].Value[1].(string)
+	if !ok {
+		return -1, fmt.Errorf("Unexpected consumer group lag value %v", result.Data.Result[0].Value[1])
+	}
+
+	lag, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return -1, err
+	}
+
+	return int64(lag), nil
+}
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func newPrometheusServer(queries *[]string, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.Query().Get("query"))
+		fmt.Fprint(w, response)
+	}))
+}
+
+var _ = Describe("Consumer group lag", func() {
+	var queries []string
+
+	BeforeEach(func() {
+		queries = nil
+	})
+
+	It("Sums the lag of the consumer group", func() {
+		server := newPrometheusServer(&queries, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1600000000.0,"1234"]}]}}`)
+		defer server.Close()
+
+		lag, err := ConsumerGroupLag(context.TODO(), server.URL+"/", "connect-syndication.advisor.1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lag).To(Equal(int64(1234)))
+		Expect(queries).To(Equal([]string{`sum(kafka_consumergroup_lag{consumergroup="connect-syndication.advisor.1"})`}))
+	})
+
+	It("Returns -1 if the lag is not known", func() {
+		server := newPrometheusServer(&queries, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+		defer server.Close()
+
+		lag, err := ConsumerGroupLag(context.TODO(), server.URL, "connect-syndication.advisor.1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lag).To(Equal(int64(-1)))
+	})
+
+	It("Fails on a query error", func() {
+		server := newPrometheusServer(&queries, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		defer server.Close()
+
+		_, err := ConsumerGroupLag(context.TODO(), server.URL, "connect-syndication.advisor.1")
+		Expect(err).To(MatchError("Error querying consumer group lag: parse error"))
+	})
+})
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"github.com/RedHatInsights/cyndi-operator/controllers/connect"
+	"github.com/RedHatInsights/cyndi-operator/controllers/kafka"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+)
+
+/*
+
+Consumer group lag of the connector.
+A connector catching up on a backlog of messages (e.g. after HBI published a burst of updates) is behind HBI by up to the number of messages it is yet to consume.
+Validation failures that the lag accounts for are therefore not counted towards the refresh threshold.
+
+*/
+
+// Consumer group of the connector of the table being validated
+func (i *ReconcileIteration) connectorConsumerGroup() string {
+	if i.Instance.Status.ConsumerGroup != "" {
+		return i.Instance.Status.ConsumerGroup
+	}
+
+	return connect.ConsumerGroup(i.Instance.Status.ConnectorName)
+}
+
+// Reads the lag of the connector and records it in the status. Returns -1 if the lag is not known
+func (i *ReconcileIteration) updateConsumerLag() int64 {
+	lag, err := kafka.ConsumerGroupLag(i.ctx, i.config.ValidationLagPrometheusURL, i.connectorConsumerGroup())
+	if err != nil {
+		// not fatal - the pipeline is validated as if the lag was not monitored
+		i.Log.Error(err, "Failed to read consumer group lag", "consumerGroup", i.connectorConsumerGroup())
+		lag = -1
+	}
+
+	if lag < 0 {
+		i.Instance.Status.ConsumerLag = nil
+		return lag
+	}
+
+	i.Instance.Status.ConsumerLag = &lag
+	metrics.ConsumerLag(i.Instance, lag)
+	return lag
+}
+
+// The mismatched hosts may be those the connector is yet to consume
+func lagExplainsMismatch(lag int64, result validationResult) bool {
+	return lag >= 0 && result.mismatchCount >= 0 && lag >= result.mismatchCount
+}
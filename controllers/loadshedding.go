@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+)
+
+/*
+
+Load shedding: validation runs heavy queries against HBI (counting hosts, reading host ids) and is therefore postponed while HBI is under pressure,
+i.e. while more than validation.load.max.active queries are running in the HBI database or the health endpoint (validation.load.health.url) reports a problem.
+A ValidationDeferred event is emitted and validation is retried after validation.load.defer.interval seconds.
+
+*/
+
+const healthCheckTimeout = 10 * time.Second
+
+// Returns why HBI is considered to be under pressure, empty if it is not (or its load is not monitored)
+func (i *ReconcileIteration) hbiUnderPressure() (string, error) {
+	if max := i.config.ValidationLoadMaxActive; max > 0 {
+		if source, ok := i.Inventory.(database.LoadSource); ok {
+			active, err := source.ActiveQueries()
+			if err != nil {
+				return "", fmt.Errorf("Error reading the load of the HBI database: %w", err)
+			}
+
+			if active > max {
+				return fmt.Sprintf("%d queries are running in the HBI database (maximum %d)", active, max), nil
+			}
+		} else {
+			i.debug("Not checking the load of the HBI database as the HBI source does not support it")
+		}
+	}
+
+	if url := i.config.ValidationLoadHealthURL; url != "" {
+		return checkHealthEndpoint(i.ctx, url)
+	}
+
+	return "", nil
+}
+
+// Returns the status reported by the endpoint unless it reports 2xx
+func checkHealthEndpoint(ctx context.Context, url string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("Error querying the HBI health endpoint: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Sprintf("the HBI health endpoint reported %s", response.Status), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+/*
+
+Correlation fields attached to every log message produced while reconciling a pipeline.
+The same logger is handed over to the database layer so that query logs can be matched with the reconcile loop that issued them.
+
+*/
+
+const (
+	logFieldPipeline    = "Pipeline"
+	logFieldNamespace   = "Namespace"
+	logFieldReconcileID = "ReconcileID"
+	logFieldState       = "State"
+)
+
+// Returns a logger carrying the identity of the pipeline and a unique id of this reconcile loop
+func newRequestLogger(base logr.Logger, request ctrl.Request) logr.Logger {
+	return base.WithValues(
+		logFieldPipeline, request.Name,
+		logFieldNamespace, request.Namespace,
+		logFieldReconcileID, uuid.New().String(),
+	)
+}
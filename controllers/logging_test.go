@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request logger", func() {
+	It("Attaches correlation fields to every message", func() {
+		var buffer bytes.Buffer
+		base := zap.New(zap.JSONEncoder(), zap.WriteTo(&buffer))
+		request := ctrl.Request{NamespacedName: types.NamespacedName{Name: "advisor", Namespace: "advisor-prod"}}
+
+		newRequestLogger(base, request).Info("first")
+		newRequestLogger(base, request).WithValues(logFieldState, "VALID").Info("second")
+
+		var entries []map[string]interface{}
+		decoder := json.NewDecoder(&buffer)
+		for decoder.More() {
+			var entry map[string]interface{}
+			Expect(decoder.Decode(&entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+
+		Expect(entries).To(HaveLen(2))
+		for _, entry := range entries {
+			Expect(entry).To(HaveKeyWithValue(logFieldPipeline, "advisor"))
+			Expect(entry).To(HaveKeyWithValue(logFieldNamespace, "advisor-prod"))
+			Expect(entry).To(HaveKey(logFieldReconcileID))
+		}
+
+		Expect(entries[1]).To(HaveKeyWithValue(logFieldState, "VALID"))
+		// each reconcile loop is identified separately
+		Expect(entries[0][logFieldReconcileID]).ToNot(Equal(entries[1][logFieldReconcileID]))
+	})
+})
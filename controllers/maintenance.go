@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+
+	"github.com/robfig/cron/v3"
+)
+
+/*
+
+Maintenance windows during which failed validations do not count towards the refresh threshold.
+Validation windows (e.g. HBI low-traffic hours) outside of which only host counts are compared.
+
+*/
+
+// A pipeline is in maintenance until the given time (RFC3339)
+const annotationMaintenanceUntil = "cyndi.cloud.redhat.com/maintenance-until"
+
+func (i *ReconcileIteration) inMaintenanceWindow(now time.Time) (bool, error) {
+	if value, ok := i.Instance.GetAnnotations()[annotationMaintenanceUntil]; ok {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return false, fmt.Errorf(`"%s" is not a valid value for "%s"`, value, annotationMaintenanceUntil)
+		}
+
+		if now.Before(until) {
+			return true, nil
+		}
+	}
+
+	for _, window := range i.Instance.Spec.MaintenanceWindows {
+		active, err := isWithinWindow(window, now)
+		if err != nil || active {
+			return active, err
+		}
+	}
+
+	return false, nil
+}
+
+// Returns true if host ids should not be compared at the given time. Pipelines in the initial sync are always validated fully
+func (i *ReconcileIteration) outsideValidationWindows(now time.Time) (bool, error) {
+	if len(i.Instance.Spec.ValidationWindows) == 0 || i.Instance.Status.InitialSyncInProgress {
+		return false, nil
+	}
+
+	for _, window := range i.Instance.Spec.ValidationWindows {
+		active, err := isWithinWindow(window, now)
+		if err != nil || active {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func isWithinWindow(window cyndi.MaintenanceWindow, now time.Time) (bool, error) {
+	schedule, err := cron.ParseStandard(window.Schedule)
+	if err != nil {
+		return false, fmt.Errorf(`"%s" is not a valid window schedule: %w`, window.Schedule, err)
+	}
+
+	// the window is active if it started within the last <duration> seconds
+	start := schedule.Next(now.UTC().Add(-time.Duration(window.Duration) * time.Second))
+	return !start.After(now.UTC()), nil
+}
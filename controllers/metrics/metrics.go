@@ -1,6 +1,9 @@
 package metrics
 
 import (
+	"sync"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
@@ -37,17 +40,164 @@ var (
 		Name: "cyndi_refresh_total",
 		Help: "The number of times this pipeline has been refreshed",
 	}, []string{"app", "reason"})
+
+	initialSyncStuckCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_initial_sync_stuck_total",
+		Help: "The number of times the initial sync of this pipeline was detected as stuck",
+	}, []string{"app"})
+
+	initialSyncDeadlineExceededCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_initial_sync_deadline_exceeded_total",
+		Help: "The number of initial syncs of this pipeline that did not complete within the initial sync timeout",
+	}, []string{"app"})
+
+	initialSyncThroughput = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_initial_sync_rows_per_minute",
+		Help: "The number of hosts added to the table being seeded per minute, as measured between validations",
+	}, []string{"app"})
+
+	pipelineState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_pipeline_state",
+		Help: "The current state of the pipeline (1 for the current state, 0 otherwise)",
+	}, []string{"app", "state"})
+
+	connectorFailed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_connector_failed",
+		Help: "Whether the connector of the pipeline is in the FAILED state",
+	}, []string{"app"})
+
+	pipelineDegraded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_pipeline_degraded",
+		Help: "Whether the pipeline is degraded by an infrastructure problem or a stuck initial sync",
+	}, []string{"app"})
+
+	syncGeneration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_sync_generation",
+		Help: "The number of pipeline versions (initial syncs) started for the pipeline, as recorded in its status",
+	}, []string{"app"})
+
+	refreshInitiatedCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_refresh_initiated_total",
+		Help: "The number of new pipeline versions (initial syncs) started",
+	}, []string{"app"})
+
+	tableDropCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_table_drops_total",
+		Help: "The number of tables dropped from the application database",
+	}, []string{"app"})
+
+	connectorUpdateCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_connector_updates_total",
+		Help: "The number of changes made to connectors of the pipeline",
+	}, []string{"app", "operation"})
+
+	consumerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_consumer_lag",
+		Help: "The number of messages the connector of the pipeline has yet to consume",
+	}, []string{"app"})
+
+	replicationLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cyndi_replication_latency_seconds",
+		Help:    "Time it took a canary host to get from the topic to the application table",
+		Buckets: []float64{5, 10, 30, 60, 120, 300, 600, 1800},
+	}, []string{"app"})
+
+	canaryTimeoutCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_canary_timeouts_total",
+		Help: "The number of canary hosts that did not appear in the application table in time",
+	}, []string{"app"})
+
+	validationDeferredCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_validation_deferred_total",
+		Help: "The number of times validation was postponed as HBI was under pressure",
+	}, []string{"app"})
+
+	replicationSlotLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_replication_slot_lag_bytes",
+		Help: "Bytes of WAL the consumer of the replication slot has yet to confirm",
+	}, []string{"app", "slot"})
+
+	replicationSlotRetained = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_replication_slot_retained_bytes",
+		Help: "Bytes of WAL the HBI database retains for the replication slot",
+	}, []string{"app", "slot"})
+
+	replicationSlotHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_replication_slot_healthy",
+		Help: "Whether the replication slot exists, is in use and keeps up with the HBI database",
+	}, []string{"app", "slot"})
+
+	orgHostCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_org_hosts_total",
+		Help: "Number of hosts of the organizations with the most hosts in HBI, in HBI and in the application table",
+	}, []string{"app", "org_id", "source"})
+
+	orgCountMismatches = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_org_count_mismatches",
+		Help: "Number of organizations whose host counts differ by more than the validation threshold",
+	}, []string{"app"})
+
+	reporterHostCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cyndi_reporter_hosts_total",
+		Help: "Number of hosts per reporter, in HBI and in the application table",
+	}, []string{"app", "reporter", "source"})
+
+	dbErrorCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_db_errors_total",
+		Help: "The number of failed database operations",
+	}, []string{"database", "type"})
+
+	dbRetryCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_db_retries_total",
+		Help: "The number of database operations retried after a transient error",
+	}, []string{"database", "type"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cyndi_db_query_duration_seconds",
+		Help:    "Time it took a database to answer a query",
+		Buckets: []float64{0.005, 0.025, 0.1, 0.5, 1, 5, 30, 120, 600},
+	}, []string{"database", "query"})
+
+	reconcileStepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cyndi_reconcile_step_duration_seconds",
+		Help:    "Time it took a step of the reconciliation of the pipeline",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 30, 120},
+	}, []string{"app", "step"})
+
+	reconcileStepErrorCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cyndi_reconcile_step_errors_total",
+		Help: "The number of times a step of the reconciliation of the pipeline failed",
+	}, []string{"app", "step"})
 )
 
+var pipelineStates = []cyndi.PipelineState{
+	cyndi.STATE_NEW,
+	cyndi.STATE_INITIAL_SYNC,
+	cyndi.STATE_VALID,
+	cyndi.STATE_INVALID,
+	cyndi.STATE_REMOVED,
+	cyndi.STATE_UNKNOWN,
+}
+
 type RefreshReason string
 
 const (
 	REFRESH_INVALID_PIPELINE RefreshReason = "invalid"
 	REFRESH_STATE_DEVIATION  RefreshReason = "deviation"
+	REFRESH_STUCK            RefreshReason = "stuck"
+)
+
+type ConnectorOperation string
+
+const (
+	CONNECTOR_CREATED   ConnectorOperation = "create"
+	CONNECTOR_DELETED   ConnectorOperation = "delete"
+	CONNECTOR_RESTARTED ConnectorOperation = "restart"
+	CONNECTOR_RESTORED  ConnectorOperation = "restore"
 )
 
 func Init() {
-	metrics.Registry.MustRegister(hostCount, inconsistencyRatio, inconsistencyAbsolute, inconsistencyThreshold, validationFailedCount, refreshCount)
+	metrics.Registry.MustRegister(hostCount, inconsistencyRatio, inconsistencyAbsolute, inconsistencyThreshold, validationFailedCount, refreshCount, initialSyncStuckCount, initialSyncDeadlineExceededCount, initialSyncThroughput, pipelineState, connectorFailed, pipelineDegraded, syncGeneration, refreshInitiatedCount, tableDropCount, connectorUpdateCount, consumerLag, replicationLatency, canaryTimeoutCount, validationDeferredCount, replicationSlotLag, replicationSlotRetained, replicationSlotHealthy, orgHostCount, orgCountMismatches, reporterHostCount, dbErrorCount, dbRetryCount, dbQueryDuration, reconcileStepDuration, reconcileStepErrorCount)
 }
 
 func InitLabels(instance *cyndi.CyndiPipeline) {
@@ -59,6 +209,24 @@ func InitLabels(instance *cyndi.CyndiPipeline) {
 	validationFailedCount.WithLabelValues(appName)
 	refreshCount.WithLabelValues(appName, string(REFRESH_INVALID_PIPELINE))
 	refreshCount.WithLabelValues(appName, string(REFRESH_STATE_DEVIATION))
+	refreshCount.WithLabelValues(appName, string(REFRESH_STUCK))
+	initialSyncStuckCount.WithLabelValues(appName)
+	initialSyncDeadlineExceededCount.WithLabelValues(appName)
+	initialSyncThroughput.WithLabelValues(appName)
+	connectorFailed.WithLabelValues(appName)
+	pipelineDegraded.WithLabelValues(appName)
+	refreshInitiatedCount.WithLabelValues(appName)
+	tableDropCount.WithLabelValues(appName)
+	connectorUpdateCount.WithLabelValues(appName, string(CONNECTOR_CREATED))
+	connectorUpdateCount.WithLabelValues(appName, string(CONNECTOR_DELETED))
+	connectorUpdateCount.WithLabelValues(appName, string(CONNECTOR_RESTARTED))
+	connectorUpdateCount.WithLabelValues(appName, string(CONNECTOR_RESTORED))
+	canaryTimeoutCount.WithLabelValues(appName)
+	validationDeferredCount.WithLabelValues(appName)
+
+	for _, step := range cyndi.ReconcileSteps {
+		reconcileStepErrorCount.WithLabelValues(appName, string(step))
+	}
 }
 
 func AppHostCount(instance *cyndi.CyndiPipeline, value int64) {
@@ -78,3 +246,176 @@ func ValidationFinished(instance *cyndi.CyndiPipeline, threshold int64, ratio fl
 func PipelineRefreshed(instance *cyndi.CyndiPipeline, reason RefreshReason) {
 	refreshCount.WithLabelValues(instance.Spec.AppName, string(reason)).Inc()
 }
+
+func InitialSyncStuck(instance *cyndi.CyndiPipeline) {
+	initialSyncStuckCount.WithLabelValues(instance.Spec.AppName).Inc()
+}
+
+func InitialSyncDeadlineExceeded(instance *cyndi.CyndiPipeline) {
+	initialSyncDeadlineExceededCount.WithLabelValues(instance.Spec.AppName).Inc()
+}
+
+// Mirrors status.initialSyncThroughput, which is cleared once the initial sync is over
+func InitialSyncThroughput(instance *cyndi.CyndiPipeline) {
+	value := 0.0
+	if instance.Status.InitialSyncThroughput != nil {
+		value = float64(instance.Status.InitialSyncThroughput.RowsPerMinute)
+	}
+
+	initialSyncThroughput.WithLabelValues(instance.Spec.AppName).Set(value)
+}
+
+func PipelineState(instance *cyndi.CyndiPipeline) {
+	current := instance.GetState()
+
+	for _, state := range pipelineStates {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+
+		pipelineState.WithLabelValues(instance.Spec.AppName, string(state)).Set(value)
+	}
+}
+
+func ConnectorFailed(instance *cyndi.CyndiPipeline, failed bool) {
+	value := 0.0
+	if failed {
+		value = 1
+	}
+
+	connectorFailed.WithLabelValues(instance.Spec.AppName).Set(value)
+}
+
+func PipelineDegraded(instance *cyndi.CyndiPipeline) {
+	value := 0.0
+	if instance.IsDegraded() {
+		value = 1
+	}
+
+	pipelineDegraded.WithLabelValues(instance.Spec.AppName).Set(value)
+}
+
+// Unlike cyndi_refresh_initiated_total the value survives restarts of the operator
+func SyncGeneration(instance *cyndi.CyndiPipeline) {
+	syncGeneration.WithLabelValues(instance.Spec.AppName).Set(float64(instance.Status.SyncGeneration))
+}
+
+func RefreshInitiated(instance *cyndi.CyndiPipeline) {
+	refreshInitiatedCount.WithLabelValues(instance.Spec.AppName).Inc()
+}
+
+func TableDropped(instance *cyndi.CyndiPipeline) {
+	tableDropCount.WithLabelValues(instance.Spec.AppName).Inc()
+}
+
+func ConnectorUpdated(instance *cyndi.CyndiPipeline, operation ConnectorOperation) {
+	connectorUpdateCount.WithLabelValues(instance.Spec.AppName, string(operation)).Inc()
+}
+
+func ConsumerLag(instance *cyndi.CyndiPipeline, value int64) {
+	consumerLag.WithLabelValues(instance.Spec.AppName).Set(float64(value))
+}
+
+func ReplicationLatency(instance *cyndi.CyndiPipeline, latency time.Duration) {
+	replicationLatency.WithLabelValues(instance.Spec.AppName).Observe(latency.Seconds())
+}
+
+func CanaryTimedOut(instance *cyndi.CyndiPipeline) {
+	canaryTimeoutCount.WithLabelValues(instance.Spec.AppName).Inc()
+}
+
+func ValidationDeferred(instance *cyndi.CyndiPipeline) {
+	validationDeferredCount.WithLabelValues(instance.Spec.AppName).Inc()
+}
+
+func ReplicationSlotHealth(instance *cyndi.CyndiPipeline, slot string, lag int64, retained int64, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+
+	replicationSlotLag.WithLabelValues(instance.Spec.AppName, slot).Set(float64(lag))
+	replicationSlotRetained.WithLabelValues(instance.Spec.AppName, slot).Set(float64(retained))
+	replicationSlotHealthy.WithLabelValues(instance.Spec.AppName, slot).Set(value)
+}
+
+func ReplicationSlotDropped(instance *cyndi.CyndiPipeline, slot string) {
+	replicationSlotLag.DeleteLabelValues(instance.Spec.AppName, slot)
+	replicationSlotRetained.DeleteLabelValues(instance.Spec.AppName, slot)
+	replicationSlotHealthy.DeleteLabelValues(instance.Spec.AppName, slot)
+}
+
+// organizations whose host counts were last exported for each app so that series of organizations that fell out of the top can be removed
+var (
+	exportedOrgsLock sync.Mutex
+	exportedOrgs     = map[string][]string{}
+)
+
+func OrgHostCounts(instance *cyndi.CyndiPipeline, counts []cyndi.OrgHostCount, mismatched int) {
+	app := instance.Spec.AppName
+
+	exportedOrgsLock.Lock()
+	defer exportedOrgsLock.Unlock()
+
+	for _, orgId := range exportedOrgs[app] {
+		orgHostCount.DeleteLabelValues(app, orgId, "hbi")
+		orgHostCount.DeleteLabelValues(app, orgId, "app")
+	}
+
+	orgIds := make([]string, len(counts))
+	for n, count := range counts {
+		orgHostCount.WithLabelValues(app, count.OrgID, "hbi").Set(float64(count.HBI))
+		orgHostCount.WithLabelValues(app, count.OrgID, "app").Set(float64(count.App))
+		orgIds[n] = count.OrgID
+	}
+
+	exportedOrgs[app] = orgIds
+	orgCountMismatches.WithLabelValues(app).Set(float64(mismatched))
+}
+
+var (
+	exportedReportersLock sync.Mutex
+	exportedReporters     = map[string][]string{}
+)
+
+func ReporterHostCounts(instance *cyndi.CyndiPipeline, counts []cyndi.ReporterHostCount) {
+	app := instance.Spec.AppName
+
+	exportedReportersLock.Lock()
+	defer exportedReportersLock.Unlock()
+
+	for _, reporter := range exportedReporters[app] {
+		reporterHostCount.DeleteLabelValues(app, reporter, "hbi")
+		reporterHostCount.DeleteLabelValues(app, reporter, "app")
+	}
+
+	reporters := make([]string, len(counts))
+	for n, count := range counts {
+		reporterHostCount.WithLabelValues(app, count.Reporter, "hbi").Set(float64(count.HBI))
+		reporterHostCount.WithLabelValues(app, count.Reporter, "app").Set(float64(count.App))
+		reporters[n] = count.Reporter
+	}
+
+	exportedReporters[app] = reporters
+}
+
+func DBError(database string, errorType string) {
+	dbErrorCount.WithLabelValues(database, errorType).Inc()
+}
+
+func DBRetry(database string, errorType string) {
+	dbRetryCount.WithLabelValues(database, errorType).Inc()
+}
+
+func DBQuery(database string, queryType string, duration time.Duration) {
+	dbQueryDuration.WithLabelValues(database, queryType).Observe(duration.Seconds())
+}
+
+func ReconcileStep(instance *cyndi.CyndiPipeline, step cyndi.ReconcileStep, duration time.Duration, failed bool) {
+	reconcileStepDuration.WithLabelValues(instance.Spec.AppName, string(step)).Observe(duration.Seconds())
+
+	if failed {
+		reconcileStepErrorCount.WithLabelValues(instance.Spec.AppName, string(step)).Inc()
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"github.com/RedHatInsights/cyndi-operator/controllers/monitoring"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+/*
+
+Maintains the Grafana dashboard and Prometheus alerting rules of the namespace the pipeline lives in.
+The resources are shared by all pipelines in the namespace and are therefore not owned by any of them.
+
+*/
+
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=integreatly.org,resources=grafanadashboards,verbs=get;list;watch;create;update;patch;delete
+
+func (i *ReconcileIteration) reconcileMonitoring() error {
+	if !i.config.MonitoringDashboardEnabled && !i.config.MonitoringRulesEnabled {
+		return nil
+	}
+
+	pipelines, err := utils.FetchCyndiPipelines(i.Client, i.Instance.Namespace)
+	if err != nil {
+		return err
+	}
+
+	var apps []string
+	for _, pipeline := range pipelines.Items {
+		if pipeline.GetDeletionTimestamp() == nil && !utils.ContainsString(apps, pipeline.Spec.AppName) {
+			apps = append(apps, pipeline.Spec.AppName)
+		}
+	}
+
+	// the resources are not owned by any pipeline so nothing else removes them once the last pipeline is gone
+	if len(apps) == 0 {
+		return i.deleteMonitoring()
+	}
+
+	if i.config.MonitoringRulesEnabled {
+		if err = i.applyMonitoringResource(monitoring.NewPrometheusRule(i.Instance.Namespace, apps, i.config.MonitoringLabels)); err != nil {
+			return err
+		}
+	}
+
+	if i.config.MonitoringDashboardEnabled {
+		dashboard, err := monitoring.NewGrafanaDashboard(i.Instance.Namespace, apps, i.config.MonitoringLabels)
+		if err != nil {
+			return err
+		}
+
+		if err = i.applyMonitoringResource(dashboard); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *ReconcileIteration) applyMonitoringResource(resource *unstructured.Unstructured) error {
+	applied, err := monitoring.Apply(i.ctx, i.Client, resource)
+	if err != nil {
+		return err
+	}
+
+	if !applied {
+		i.debug("Monitoring resource type not installed, skipping", "kind", resource.GetKind())
+	}
+
+	return nil
+}
+
+func (i *ReconcileIteration) deleteMonitoring() error {
+	if i.config.MonitoringRulesEnabled {
+		if err := monitoring.DeletePrometheusRule(i.ctx, i.Client, i.Instance.Namespace); err != nil {
+			return err
+		}
+	}
+
+	if i.config.MonitoringDashboardEnabled {
+		if err := monitoring.DeleteGrafanaDashboard(i.ctx, i.Client, i.Instance.Namespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
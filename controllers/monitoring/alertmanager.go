@@ -0,0 +1,88 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+
+Silences created through the Alertmanager API (v2) so that refreshes initiated by the operator itself do not page on-call.
+
+*/
+
+const silenceTimeout = 30 * time.Second
+
+// Alerts firing while a pipeline is refreshed (see NewPrometheusRule)
+var DriftAlerts = []string{AlertPipelineInvalid, AlertInconsistencyAboveThreshold}
+
+type silenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+type silence struct {
+	Matchers  []silenceMatcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment"`
+}
+
+type silenceResponse struct {
+	SilenceID string `json:"silenceID"`
+}
+
+// Silences the drift alerts of the given app until the given time. Returns the id of the silence
+func SilenceDriftAlerts(ctx context.Context, alertmanagerURL string, app string, until time.Time, comment string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, silenceTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(silence{
+		Matchers: []silenceMatcher{
+			{Name: "alertname", Value: strings.Join(DriftAlerts, "|"), IsRegex: true, IsEqual: true},
+			{Name: "app", Value: app, IsEqual: true},
+		},
+		StartsAt:  time.Now().UTC(),
+		EndsAt:    until.UTC(),
+		CreatedBy: "cyndi-operator",
+		Comment:   comment,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf("%s/api/v2/silences", strings.TrimSuffix(alertmanagerURL, "/"))
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("Error creating silence: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Error creating silence: %s", response.Status)
+	}
+
+	result := silenceResponse{}
+	if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("Error creating silence: %w", err)
+	}
+
+	return result.SilenceID, nil
+}
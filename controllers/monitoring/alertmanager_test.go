@@ -0,0 +1,56 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alertmanager", func() {
+	var (
+		path    string
+		created silence
+	)
+
+	newAlertmanager := func(status int, response string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(status)
+			fmt.Fprint(w, response)
+		}))
+	}
+
+	It("Silences the drift alerts of an app", func() {
+		server := newAlertmanager(http.StatusOK, `{"silenceID":"7d2c5f8e"}`)
+		defer server.Close()
+
+		until := time.Now().Add(time.Hour)
+		id, err := SilenceDriftAlerts(context.TODO(), server.URL+"/", "advisor", until, "refreshed")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(id).To(Equal("7d2c5f8e"))
+
+		Expect(path).To(Equal("/api/v2/silences"))
+		Expect(created.Matchers).To(Equal([]silenceMatcher{
+			{Name: "alertname", Value: "CyndiPipelineInvalid|CyndiInconsistencyAboveThreshold", IsRegex: true, IsEqual: true},
+			{Name: "app", Value: "advisor", IsEqual: true},
+		}))
+		Expect(created.EndsAt).To(BeTemporally("~", until, time.Second))
+		Expect(created.CreatedBy).To(Equal("cyndi-operator"))
+		Expect(created.Comment).To(Equal("refreshed"))
+	})
+
+	It("Fails if the silence is rejected", func() {
+		server := newAlertmanager(http.StatusBadRequest, `"invalid matcher"`)
+		defer server.Close()
+
+		_, err := SilenceDriftAlerts(context.TODO(), server.URL, "advisor", time.Now().Add(time.Hour), "refreshed")
+		Expect(err).To(MatchError("Error creating silence: 400 Bad Request"))
+	})
+})
@@ -0,0 +1,238 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+/*
+
+Monitoring resources (Grafana dashboard and Prometheus alerting rules) created for each namespace with CyndiPipelines.
+
+*/
+
+const (
+	PrometheusRuleName   = "cyndi-alerts"
+	GrafanaDashboardName = "cyndi-dashboard"
+
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	managedBy      = "cyndi-operator"
+)
+
+var prometheusRuleGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Kind:    "PrometheusRule",
+	Version: "v1",
+}
+
+var serviceMonitorGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Kind:    "ServiceMonitor",
+	Version: "v1",
+}
+
+var grafanaDashboardGVK = schema.GroupVersionKind{
+	Group:   "integreatly.org",
+	Kind:    "GrafanaDashboard",
+	Version: "v1alpha1",
+}
+
+const (
+	AlertPipelineInvalid             = "CyndiPipelineInvalid"
+	AlertInconsistencyAboveThreshold = "CyndiInconsistencyAboveThreshold"
+)
+
+// Builds the set of alerting rules covering the pipelines of the given apps
+func NewPrometheusRule(namespace string, apps []string, labels map[string]string) *unstructured.Unstructured {
+	selector := appSelector(apps)
+
+	rule := func(alert string, expr string, forDuration string, severity string, summary string) interface{} {
+		return map[string]interface{}{
+			"alert": alert,
+			"expr":  expr,
+			"for":   forDuration,
+			"labels": map[string]interface{}{
+				"severity": severity,
+			},
+			"annotations": map[string]interface{}{
+				"summary": summary,
+			},
+		}
+	}
+
+	u := newResource(prometheusRuleGVK, PrometheusRuleName, namespace, labels)
+	u.Object["spec"] = map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{
+				"name": "cyndi",
+				"rules": []interface{}{
+					rule(
+						AlertPipelineInvalid,
+						fmt.Sprintf(`cyndi_pipeline_state{state="INVALID",%s} == 1`, selector),
+						"10m",
+						"warning",
+						"Cyndi pipeline {{ $labels.app }} has been invalid for more than 10 minutes",
+					),
+					rule(
+						AlertInconsistencyAboveThreshold,
+						fmt.Sprintf(`cyndi_inconsistency_ratio{%[1]s} > cyndi_inconsistency_threshold{%[1]s}`, selector),
+						"10m",
+						"warning",
+						"Data of Cyndi pipeline {{ $labels.app }} drifted above the validation threshold",
+					),
+					rule(
+						"CyndiConnectorFailed",
+						fmt.Sprintf(`cyndi_connector_failed{%s} == 1`, selector),
+						"5m",
+						"critical",
+						"Connector of Cyndi pipeline {{ $labels.app }} is in the FAILED state",
+					),
+					rule(
+						"CyndiReplicationSlotUnhealthy",
+						fmt.Sprintf(`cyndi_replication_slot_healthy{%s} == 0`, selector),
+						"15m",
+						"warning",
+						"Replication slot {{ $labels.slot }} feeding Cyndi pipeline {{ $labels.app }} is unhealthy",
+					),
+				},
+			},
+		},
+	}
+
+	return u
+}
+
+// Builds a Grafana dashboard showing the state of the pipelines of the given apps
+func NewGrafanaDashboard(namespace string, apps []string, labels map[string]string) (*unstructured.Unstructured, error) {
+	selector := appSelector(apps)
+
+	panel := func(id int, title string, expr string, y int) interface{} {
+		return map[string]interface{}{
+			"id":         id,
+			"title":      title,
+			"type":       "graph",
+			"datasource": "${datasource}",
+			"gridPos":    map[string]interface{}{"h": 8, "w": 24, "x": 0, "y": y},
+			"targets": []interface{}{
+				map[string]interface{}{
+					"expr":         expr,
+					"legendFormat": "{{app}}",
+				},
+			},
+		}
+	}
+
+	dashboard := map[string]interface{}{
+		"title":         fmt.Sprintf("Cyndi (%s)", namespace),
+		"uid":           fmt.Sprintf("cyndi-%s", namespace),
+		"schemaVersion": 16,
+		"templating": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"name":  "datasource",
+					"type":  "datasource",
+					"query": "prometheus",
+				},
+			},
+		},
+		"panels": []interface{}{
+			panel(1, "Hosts", fmt.Sprintf(`cyndi_hosts_total{%s}`, selector), 0),
+			panel(2, "Inconsistency ratio", fmt.Sprintf(`cyndi_inconsistency_ratio{%s}`, selector), 8),
+			panel(3, "Validation failures", fmt.Sprintf(`increase(cyndi_validation_failed_total{%s}[1h])`, selector), 16),
+			panel(4, "Refreshes", fmt.Sprintf(`increase(cyndi_refresh_total{%s}[1h])`, selector), 24),
+			panel(5, "Invalid pipelines", fmt.Sprintf(`cyndi_pipeline_state{state="INVALID",%s}`, selector), 32),
+			panel(6, "Refreshes per day", fmt.Sprintf(`delta(cyndi_sync_generation{%s}[1d])`, selector), 40),
+		},
+	}
+
+	dashboardJSON, err := json.Marshal(dashboard)
+	if err != nil {
+		return nil, err
+	}
+
+	u := newResource(grafanaDashboardGVK, GrafanaDashboardName, namespace, labels)
+	u.Object["spec"] = map[string]interface{}{
+		"name": fmt.Sprintf("cyndi-%s.json", namespace),
+		"json": string(dashboardJSON),
+	}
+
+	return u, nil
+}
+
+/*
+ * Creates or updates the given resource.
+ * Returns false without an error if the resource type is not known to the cluster (i.e. the CRD is not installed).
+ */
+func Apply(ctx context.Context, c client.Client, desired *unstructured.Unstructured) (bool, error) {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(desired.GroupVersionKind())
+	existing.SetName(desired.GetName())
+	existing.SetNamespace(desired.GetNamespace())
+
+	_, err := controllerutil.CreateOrUpdate(ctx, c, existing, func() error {
+		existing.SetLabels(desired.GetLabels())
+		existing.Object["spec"] = desired.Object["spec"]
+		return nil
+	})
+
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Removes the PrometheusRule of a namespace left without pipelines. A missing rule (or a missing CRD) is not an error.
+func DeletePrometheusRule(ctx context.Context, c client.Client, namespace string) error {
+	return deleteResource(ctx, c, newResource(prometheusRuleGVK, PrometheusRuleName, namespace, nil))
+}
+
+// Removes the GrafanaDashboard of a namespace left without pipelines. A missing dashboard (or a missing CRD) is not an error.
+func DeleteGrafanaDashboard(ctx context.Context, c client.Client, namespace string) error {
+	return deleteResource(ctx, c, newResource(grafanaDashboardGVK, GrafanaDashboardName, namespace, nil))
+}
+
+func deleteResource(ctx context.Context, c client.Client, resource *unstructured.Unstructured) error {
+	if err := c.Delete(ctx, resource); err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return err
+	}
+
+	return nil
+}
+
+func newResource(gvk schema.GroupVersionKind, name string, namespace string, labels map[string]string) *unstructured.Unstructured {
+	allLabels := map[string]string{LabelManagedBy: managedBy}
+	for key, value := range labels {
+		allLabels[key] = value
+	}
+
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	u.SetGroupVersionKind(gvk)
+	u.SetName(name)
+	u.SetNamespace(namespace)
+	u.SetLabels(allLabels)
+	return u
+}
+
+// PromQL label matcher selecting the given apps
+func appSelector(apps []string) string {
+	sorted := make([]string, len(apps))
+	for i, app := range apps {
+		// backslashes need to be escaped within a PromQL string
+		sorted[i] = strings.ReplaceAll(regexp.QuoteMeta(app), `\`, `\\`)
+	}
+
+	sort.Strings(sorted)
+	return fmt.Sprintf(`app=~"%s"`, strings.Join(sorted, "|"))
+}
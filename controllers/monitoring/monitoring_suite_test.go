@@ -0,0 +1,13 @@
+package monitoring
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMonitoring(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Monitoring")
+}
@@ -0,0 +1,76 @@
+package monitoring
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Monitoring", func() {
+	Describe("PrometheusRule", func() {
+		It("Defines alerts for the given apps", func() {
+			rule := NewPrometheusRule("test", []string{"patch", "advisor"}, map[string]string{"team": "inventory"})
+
+			Expect(rule.GetName()).To(Equal(PrometheusRuleName))
+			Expect(rule.GetNamespace()).To(Equal("test"))
+			Expect(rule.GetLabels()).To(HaveKeyWithValue("team", "inventory"))
+			Expect(rule.GetLabels()).To(HaveKeyWithValue(LabelManagedBy, "cyndi-operator"))
+
+			groups, _, err := unstructured.NestedSlice(rule.Object, "spec", "groups")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(groups).To(HaveLen(1))
+
+			rules := groups[0].(map[string]interface{})["rules"].([]interface{})
+			Expect(rules).To(HaveLen(4))
+
+			invalid := rules[0].(map[string]interface{})
+			Expect(invalid["alert"]).To(Equal("CyndiPipelineInvalid"))
+			Expect(invalid["expr"]).To(Equal(`cyndi_pipeline_state{state="INVALID",app=~"advisor|patch"} == 1`))
+			Expect(invalid["for"]).To(Equal("10m"))
+		})
+	})
+
+	Describe("GrafanaDashboard", func() {
+		It("Embeds valid dashboard JSON", func() {
+			dashboard, err := NewGrafanaDashboard("test", []string{"advisor"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dashboard.GetName()).To(Equal(GrafanaDashboardName))
+
+			value, _, err := unstructured.NestedString(dashboard.Object, "spec", "json")
+			Expect(err).ToNot(HaveOccurred())
+
+			var parsed map[string]interface{}
+			Expect(json.Unmarshal([]byte(value), &parsed)).To(Succeed())
+			Expect(parsed["uid"]).To(Equal("cyndi-test"))
+			Expect(parsed["panels"]).To(HaveLen(6))
+		})
+	})
+
+	Describe("ServiceMonitor", func() {
+		It("Selects the metrics Service", func() {
+			service := NewMetricsService("operator", 8080)
+			Expect(service.GetName()).To(Equal(MetricsServiceName))
+			Expect(service.Spec.Ports).To(HaveLen(1))
+			Expect(service.Spec.Ports[0].Port).To(Equal(int32(8080)))
+			Expect(service.Spec.Selector).To(HaveKeyWithValue("control-plane", "controller-manager"))
+
+			serviceMonitor := NewServiceMonitor("operator")
+			Expect(serviceMonitor.GetName()).To(Equal(ServiceMonitorName))
+			Expect(serviceMonitor.GetNamespace()).To(Equal("operator"))
+
+			selector, _, err := unstructured.NestedStringMap(serviceMonitor.Object, "spec", "selector", "matchLabels")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(selector).To(Equal(service.GetLabels()))
+
+			endpoints, _, err := unstructured.NestedSlice(serviceMonitor.Object, "spec", "endpoints")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(endpoints[0].(map[string]interface{})["port"]).To(Equal(service.Spec.Ports[0].Name))
+		})
+	})
+
+	It("Escapes app names in the selector", func() {
+		Expect(appSelector([]string{"a.b"})).To(Equal(`app=~"a\\.b"`))
+	})
+})
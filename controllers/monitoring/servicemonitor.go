@@ -0,0 +1,112 @@
+package monitoring
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+/*
+
+Service and ServiceMonitor exposing the metrics of the operator itself to the Prometheus operator.
+
+*/
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch
+
+const (
+	MetricsServiceName = "cyndi-operator-metrics"
+	ServiceMonitorName = "cyndi-operator"
+
+	metricsPortName = "metrics"
+	labelComponent  = "app.kubernetes.io/component"
+)
+
+// Pods of the operator deployment carry this label
+var OperatorSelector = map[string]string{"control-plane": "controller-manager"}
+
+func NewMetricsService(namespace string, port int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      MetricsServiceName,
+			Namespace: namespace,
+			Labels:    metricsLabels(),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: OperatorSelector,
+			Ports: []corev1.ServicePort{{
+				Name:       metricsPortName,
+				Port:       port,
+				TargetPort: intstr.FromInt(int(port)),
+				Protocol:   corev1.ProtocolTCP,
+			}},
+		},
+	}
+}
+
+func NewServiceMonitor(namespace string) *unstructured.Unstructured {
+	u := newResource(serviceMonitorGVK, ServiceMonitorName, namespace, nil)
+
+	selector := map[string]interface{}{}
+	for key, value := range metricsLabels() {
+		selector[key] = value
+	}
+
+	u.Object["spec"] = map[string]interface{}{
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"port": metricsPortName,
+				"path": "/metrics",
+			},
+		},
+		"selector": map[string]interface{}{
+			"matchLabels": selector,
+		},
+	}
+
+	return u
+}
+
+/*
+ * Creates or updates the metrics Service and the ServiceMonitor of the operator in the given namespace.
+ * Returns false without an error if the Prometheus operator CRDs are not installed.
+ */
+func ApplyServiceMonitor(ctx context.Context, c client.Client, namespace string, port int32) (bool, error) {
+	serviceMonitor := NewServiceMonitor(namespace)
+
+	// check for the CRD first so that no Service is left behind on clusters without the Prometheus operator
+	if _, err := c.RESTMapper().RESTMapping(serviceMonitorGVK.GroupKind(), serviceMonitorGVK.Version); meta.IsNoMatchError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	desired := NewMetricsService(namespace, port)
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, service, func() error {
+		service.SetLabels(desired.GetLabels())
+		service.Spec.Selector = desired.Spec.Selector
+		service.Spec.Ports = desired.Spec.Ports
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	return Apply(ctx, c, serviceMonitor)
+}
+
+// Labels of the metrics Service, also used by the ServiceMonitor to select it
+func metricsLabels() map[string]string {
+	return map[string]string{
+		LabelManagedBy: managedBy,
+		labelComponent: metricsPortName,
+	}
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/monitoring"
+
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Monitoring resources", func() {
+	var (
+		instance *cyndi.CyndiPipeline
+		c        client.Client
+	)
+
+	reconcileMonitoring := func() {
+		i := ReconcileIteration{
+			Instance: instance,
+			ctx:      context.TODO(),
+			Log:      logf.Log.WithName("test"),
+			Client:   c,
+			config: &config.CyndiConfiguration{
+				MonitoringRulesEnabled:     true,
+				MonitoringDashboardEnabled: true,
+			},
+		}
+
+		Expect(i.reconcileMonitoring()).To(Succeed())
+	}
+
+	exists := func(resource *unstructured.Unstructured) bool {
+		err := c.Get(context.TODO(), client.ObjectKeyFromObject(resource), resource.DeepCopy())
+		if k8errors.IsNotFound(err) {
+			return false
+		}
+
+		Expect(err).ToNot(HaveOccurred())
+		return true
+	}
+
+	BeforeEach(func() {
+		instance = &cyndi.CyndiPipeline{
+			ObjectMeta: metav1.ObjectMeta{Name: "advisor", Namespace: "test"},
+			Spec:       cyndi.CyndiPipelineSpec{AppName: "advisor"},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(cyndi.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).Build()
+	})
+
+	It("Creates the resources for the pipelines of the namespace", func() {
+		Expect(c.Create(context.TODO(), instance)).To(Succeed())
+		reconcileMonitoring()
+
+		dashboard, err := monitoring.NewGrafanaDashboard("test", []string{"advisor"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists(monitoring.NewPrometheusRule("test", []string{"advisor"}, nil))).To(BeTrue())
+		Expect(exists(dashboard)).To(BeTrue())
+	})
+
+	It("Removes the resources once no pipelines are left", func() {
+		Expect(c.Create(context.TODO(), instance)).To(Succeed())
+		reconcileMonitoring()
+
+		Expect(c.Delete(context.TODO(), instance)).To(Succeed())
+		reconcileMonitoring()
+
+		dashboard, err := monitoring.NewGrafanaDashboard("test", []string{"advisor"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists(monitoring.NewPrometheusRule("test", []string{"advisor"}, nil))).To(BeFalse())
+		Expect(exists(dashboard)).To(BeFalse())
+	})
+
+	It("Tolerates resources that are already gone", func() {
+		reconcileMonitoring()
+	})
+})
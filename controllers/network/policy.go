@@ -0,0 +1,167 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+
+Egress NetworkPolicies allowing pods (the operator, Kafka Connect) to reach the databases of a pipeline.
+A database host is either
+ - a Service of the cluster (name, name.namespace or name.namespace.svc[.cluster.local]), in which case traffic to the pods backing the Service is allowed
+ - an IP address or an external hostname, in which case traffic to the (resolved) addresses is allowed
+
+*/
+
+// set on namespaces by Kubernetes 1.21+
+const labelNamespaceName = "kubernetes.io/metadata.name"
+
+// replaced in tests
+var lookupIP = net.LookupIP
+
+type Destination struct {
+	Host string
+	Port int32
+}
+
+func NewEgressPolicy(name string, namespace string, podSelector map[string]string, labels map[string]string, rules []networkingv1.NetworkPolicyEgressRule) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podSelector},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      rules,
+		},
+	}
+}
+
+/*
+ * Returns the egress rule allowing traffic to the given destination.
+ * Short Service names are resolved relative to the namespace the client pods run in.
+ */
+func EgressRule(ctx context.Context, c client.Client, namespace string, destination Destination) (rule networkingv1.NetworkPolicyEgressRule, err error) {
+	if ip := net.ParseIP(destination.Host); ip != nil {
+		return addressRule([]net.IP{ip}, destination.Port), nil
+	}
+
+	if name, serviceNamespace, ok := serviceName(destination.Host, namespace); ok {
+		service := &corev1.Service{}
+		err = c.Get(ctx, client.ObjectKey{Namespace: serviceNamespace, Name: name}, service)
+		if err == nil {
+			return serviceRule(ctx, c, service, destination.Port)
+		} else if !k8errors.IsNotFound(err) {
+			return rule, err
+		}
+	}
+
+	return resolvedRule(destination.Host, destination.Port)
+}
+
+// Service name and namespace of the given host. Hosts with two labels (e.g. example.com) may be either.
+func serviceName(host string, namespace string) (name string, serviceNamespace string, ok bool) {
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+
+	switch {
+	case len(labels) == 1:
+		return labels[0], namespace, true
+	case len(labels) == 2:
+		return labels[0], labels[1], true
+	case labels[2] == "svc":
+		return labels[0], labels[1], true
+	}
+
+	return "", "", false
+}
+
+func serviceRule(ctx context.Context, c client.Client, service *corev1.Service, port int32) (rule networkingv1.NetworkPolicyEgressRule, err error) {
+	if service.Spec.Type == corev1.ServiceTypeExternalName {
+		return resolvedRule(service.Spec.ExternalName, port)
+	}
+
+	// policies apply to traffic after the Service address is translated to the address of a pod
+	targetPort := intstr.FromInt(int(port))
+	for _, servicePort := range service.Spec.Ports {
+		// the target port defaults to the port
+		if servicePort.Port == port && servicePort.TargetPort != intstr.FromInt(0) {
+			targetPort = servicePort.TargetPort
+		}
+	}
+
+	if len(service.Spec.Selector) > 0 {
+		return networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{labelNamespaceName: service.Namespace}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: service.Spec.Selector},
+			}},
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(targetPort)},
+		}, nil
+	}
+
+	// the addresses of a Service without a selector are maintained in its Endpoints
+	endpoints := &corev1.Endpoints{}
+	if err = c.Get(ctx, client.ObjectKeyFromObject(service), endpoints); err != nil {
+		return rule, err
+	}
+
+	var addresses []net.IP
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			if ip := net.ParseIP(address.IP); ip != nil {
+				addresses = append(addresses, ip)
+			}
+		}
+	}
+
+	if len(addresses) == 0 {
+		return rule, fmt.Errorf("Service %s/%s has no endpoints", service.Namespace, service.Name)
+	}
+
+	rule = addressRule(addresses, port)
+	rule.Ports = []networkingv1.NetworkPolicyPort{tcpPort(targetPort)}
+	return rule, nil
+}
+
+func resolvedRule(host string, port int32) (rule networkingv1.NetworkPolicyEgressRule, err error) {
+	addresses, err := lookupIP(host)
+	if err != nil {
+		return rule, fmt.Errorf("Error resolving %s: %w", host, err)
+	}
+
+	return addressRule(addresses, port), nil
+}
+
+func addressRule(addresses []net.IP, port int32) networkingv1.NetworkPolicyEgressRule {
+	rule := networkingv1.NetworkPolicyEgressRule{
+		Ports: []networkingv1.NetworkPolicyPort{tcpPort(intstr.FromInt(int(port)))},
+	}
+
+	for _, address := range addresses {
+		cidr := address.String() + "/32"
+		if address.To4() == nil {
+			cidr = address.String() + "/128"
+		}
+
+		rule.To = append(rule.To, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+	}
+
+	return rule
+}
+
+func tcpPort(port intstr.IntOrString) networkingv1.NetworkPolicyPort {
+	protocol := corev1.ProtocolTCP
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port}
+}
@@ -0,0 +1,105 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func peerCIDRs(rule networkingv1.NetworkPolicyEgressRule) (cidrs []string) {
+	for _, peer := range rule.To {
+		cidrs = append(cidrs, peer.IPBlock.CIDR)
+	}
+
+	return
+}
+
+var _ = Describe("Egress rules", func() {
+	var lookups []string
+
+	BeforeEach(func() {
+		lookups = nil
+		lookupIP = func(host string) ([]net.IP, error) {
+			lookups = append(lookups, host)
+			if host == "db.example.com" {
+				return []net.IP{net.ParseIP("192.0.2.10"), net.ParseIP("2001:db8::10")}, nil
+			}
+
+			return nil, errors.New("no such host")
+		}
+	})
+
+	AfterEach(func() {
+		lookupIP = net.LookupIP
+	})
+
+	It("Allows traffic to the pods backing a Service", func() {
+		c := fake.NewClientBuilder().WithObjects(&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "advisor-db", Namespace: "advisor"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "advisor-db"},
+				Ports:    []corev1.ServicePort{{Port: 5432, TargetPort: intstr.FromString("postgres")}},
+			},
+		}).Build()
+
+		for _, host := range []string{"advisor-db", "advisor-db.advisor", "advisor-db.advisor.svc.cluster.local"} {
+			rule, err := EgressRule(context.TODO(), c, "advisor", Destination{Host: host, Port: 5432})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rule.To).To(HaveLen(1))
+			Expect(rule.To[0].NamespaceSelector.MatchLabels).To(Equal(map[string]string{"kubernetes.io/metadata.name": "advisor"}))
+			Expect(rule.To[0].PodSelector.MatchLabels).To(Equal(map[string]string{"app": "advisor-db"}))
+			Expect(rule.Ports).To(HaveLen(1))
+			Expect(*rule.Ports[0].Port).To(Equal(intstr.FromString("postgres")))
+		}
+
+		Expect(lookups).To(BeEmpty())
+	})
+
+	It("Allows traffic to the endpoints of a Service without a selector", func() {
+		c := fake.NewClientBuilder().WithObjects(&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "advisor-db", Namespace: "advisor"},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Port: 5432}},
+			},
+		}, &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "advisor-db", Namespace: "advisor"},
+			Subsets: []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{IP: "10.1.0.5"}, {IP: "10.1.0.6"}},
+			}},
+		}).Build()
+
+		rule, err := EgressRule(context.TODO(), c, "kafka", Destination{Host: "advisor-db.advisor.svc", Port: 5432})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(peerCIDRs(rule)).To(Equal([]string{"10.1.0.5/32", "10.1.0.6/32"}))
+		Expect(*rule.Ports[0].Port).To(Equal(intstr.FromInt(5432)))
+	})
+
+	It("Allows traffic to the resolved addresses of an external host", func() {
+		c := fake.NewClientBuilder().Build()
+
+		rule, err := EgressRule(context.TODO(), c, "advisor", Destination{Host: "db.example.com", Port: 5432})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(peerCIDRs(rule)).To(Equal([]string{"192.0.2.10/32", "2001:db8::10/128"}))
+
+		rule, err = EgressRule(context.TODO(), c, "advisor", Destination{Host: "192.0.2.20", Port: 26257})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(peerCIDRs(rule)).To(Equal([]string{"192.0.2.20/32"}))
+		Expect(*rule.Ports[0].Port).To(Equal(intstr.FromInt(26257)))
+
+		Expect(lookups).To(Equal([]string{"db.example.com"}))
+	})
+
+	It("Fails if the host cannot be resolved", func() {
+		_, err := EgressRule(context.TODO(), fake.NewClientBuilder().Build(), "advisor", Destination{Host: "advisor-db", Port: 5432})
+		Expect(err).To(MatchError("Error resolving advisor-db: no such host"))
+	})
+})
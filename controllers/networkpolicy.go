@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/connect"
+	"github.com/RedHatInsights/cyndi-operator/controllers/monitoring"
+	"github.com/RedHatInsights/cyndi-operator/controllers/network"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+/*
+
+Egress NetworkPolicies (networkpolicy.enabled) allowing
+ - the operator to reach the application database and the HBI database (or API)
+ - the Kafka Connect cluster to reach the application database (and the HBI database if the operator manages the source connector)
+Like connectors in the namespace of a Connect cluster elsewhere, the policies are linked to the pipeline using labels and removed by its finalizer.
+
+*/
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=endpoints,verbs=get;list;watch
+
+const strimziKindLabel = "strimzi.io/kind"
+
+func (i *ReconcileIteration) networkPolicyName(client string) string {
+	return fmt.Sprintf("cyndi-%s-%s-%s", i.Instance.Namespace, i.Instance.Name, client)
+}
+
+func (i *ReconcileIteration) networkPolicyLabels() map[string]string {
+	return map[string]string{
+		connect.LabelOwner:          i.Instance.GetUIDString(),
+		connect.LabelOwnerName:      i.Instance.Name,
+		connect.LabelOwnerNamespace: i.Instance.Namespace,
+		connect.LabelPipeline:       i.Instance.Name,
+	}
+}
+
+func dbDestination(params config.DBParams) (network.Destination, error) {
+	port, err := strconv.ParseInt(params.Port, 10, 32)
+	if err != nil {
+		return network.Destination{}, fmt.Errorf("Invalid port of database %s: %s", params.Host, params.Port)
+	}
+
+	return network.Destination{Host: params.Host, Port: int32(port)}, nil
+}
+
+func apiDestination(params config.APIParams) (network.Destination, error) {
+	parsed, err := url.Parse(params.URL)
+	if err != nil {
+		return network.Destination{}, err
+	}
+
+	port := parsed.Port()
+	if port == "" && parsed.Scheme == "https" {
+		port = "443"
+	} else if port == "" {
+		port = "80"
+	}
+
+	return dbDestination(config.DBParams{Host: parsed.Hostname(), Port: port})
+}
+
+// Destinations the operator connects to when reconciling and validating the pipeline
+func (i *ReconcileIteration) operatorDestinations() ([]network.Destination, error) {
+	params := append([]config.DBParams{i.AppDBParams}, i.HBIDBParams...)
+
+	var destinations []network.Destination
+	for _, db := range params {
+		destination, err := dbDestination(db)
+		if err != nil {
+			return nil, err
+		}
+
+		destinations = append(destinations, destination)
+	}
+
+	if i.config.InventorySource == config.InventorySourceAPI || i.config.ValidationXjoinEnabled {
+		destination, err := apiDestination(i.HBIAPIParams)
+		if err != nil {
+			return nil, err
+		}
+
+		destinations = append(destinations, destination)
+	}
+
+	return destinations, nil
+}
+
+func (i *ReconcileIteration) newNetworkPolicy(name string, namespace string, podSelector map[string]string, destinations ...network.Destination) (*networkingv1.NetworkPolicy, error) {
+	var rules []networkingv1.NetworkPolicyEgressRule
+	for _, destination := range destinations {
+		rule, err := network.EgressRule(i.ctx, i.Client, namespace, destination)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return network.NewEgressPolicy(name, namespace, podSelector, i.networkPolicyLabels(), rules), nil
+}
+
+func (i *ReconcileIteration) desiredNetworkPolicies() (policies []*networkingv1.NetworkPolicy, err error) {
+	appDb, err := dbDestination(i.AppDBParams)
+	if err != nil {
+		return nil, err
+	}
+
+	connectDestinations := []network.Destination{appDb}
+
+	// the source connector reads from the HBI database
+	if i.Instance.Spec.ManageSourceConnector != nil && len(i.HBIDBParams) > 0 {
+		hbiDb, err := dbDestination(i.HBIDBParams[0])
+		if err != nil {
+			return nil, err
+		}
+
+		connectDestinations = append(connectDestinations, hbiDb)
+	}
+
+	connectSelector := map[string]string{connect.LabelStrimziCluster: i.config.ConnectCluster, strimziKindLabel: "KafkaConnect"}
+	policy, err := i.newNetworkPolicy(i.networkPolicyName("connect"), i.connectorNamespace(), connectSelector, connectDestinations...)
+	if err != nil {
+		return nil, err
+	}
+
+	policies = append(policies, policy)
+
+	if i.operatorNamespace == "" {
+		i.debug("Operator namespace not known, skipping its NetworkPolicy")
+		return policies, nil
+	}
+
+	destinations, err := i.operatorDestinations()
+	if err != nil {
+		return nil, err
+	}
+
+	if policy, err = i.newNetworkPolicy(i.networkPolicyName("operator"), i.operatorNamespace, monitoring.OperatorSelector, destinations...); err != nil {
+		return nil, err
+	}
+
+	return append(policies, policy), nil
+}
+
+// Creates or updates the NetworkPolicies of the pipeline and removes those no longer needed (e.g. after moving to a different Connect cluster)
+func (i *ReconcileIteration) reconcileNetworkPolicies() error {
+	var desired []*networkingv1.NetworkPolicy
+
+	if i.skipMutation("Not updating NetworkPolicies") {
+		return nil
+	}
+
+	if i.config.NetworkPolicyEnabled {
+		policies, err := i.desiredNetworkPolicies()
+		if err != nil {
+			return err
+		}
+
+		desired = policies
+	}
+
+	for _, policy := range desired {
+		spec := policy.Spec
+		labels := policy.Labels
+
+		if _, err := controllerutil.CreateOrUpdate(i.ctx, i.Client, policy, func() error {
+			policy.Labels = utils.Merge(policy.Labels, labels)
+			policy.Spec = spec
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	return i.deleteNetworkPolicies(desired...)
+}
+
+// Deletes NetworkPolicies of the pipeline except the given ones
+func (i *ReconcileIteration) deleteNetworkPolicies(keep ...*networkingv1.NetworkPolicy) error {
+	policies := &networkingv1.NetworkPolicyList{}
+	if err := i.Client.List(i.ctx, policies, client.MatchingLabels{connect.LabelOwner: i.Instance.GetUIDString()}); err != nil {
+		return err
+	}
+
+	kept := map[client.ObjectKey]bool{}
+	for _, policy := range keep {
+		kept[client.ObjectKeyFromObject(policy)] = true
+	}
+
+	for idx := range policies.Items {
+		policy := &policies.Items[idx]
+		if kept[client.ObjectKeyFromObject(policy)] {
+			continue
+		}
+
+		i.Log.Info("Removing NetworkPolicy", "name", policy.Name, "namespace", policy.Namespace)
+		if err := i.Client.Delete(i.ctx, policy); err != nil && !k8errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+Consumer groups of the connectors and recovery from retention gaps.
+With a consumer group template (connector.consumer.group.template or spec.consumerGroupTemplate) the connector of a new pipeline version
+consumes using the rendered consumer group rather than the default one of Kafka Connect (connect-<connector name>).
+The group is recorded in status.consumerGroup so that an offset-aware refresh resumes from it.
+
+If the connector fell behind the retention of the topic, the reset-offsets annotation (earliest or a RFC3339 timestamp) makes the connector
+of the current pipeline version consume using a new consumer group from the earliest retained offset. Events older than the timestamp are skipped.
+The table is kept as is - replayed events are upserted. A request is done once per value of the annotation (removing the annotation allows repeating it).
+
+*/
+
+// Replaces the consumer group of the connector of the current pipeline version
+const annotationResetOffsets = "cyndi.cloud.redhat.com/reset-offsets"
+
+const offsetResetEarliest = "earliest"
+
+// Returns the consumer group rendered from the consumer group template for the given pipeline version. Empty without a template
+func (i *ReconcileIteration) templatedConsumerGroup(pipelineVersion string) (string, error) {
+	if i.config.ConnectorConsumerGroupTemplate == "" {
+		return "", nil
+	}
+
+	return utils.RenderConsumerGroup(i.config.ConnectorConsumerGroupTemplate, utils.ConsumerGroupData{
+		PipelineName:    i.Instance.Name,
+		AppName:         i.Instance.Spec.AppName,
+		Namespace:       i.Instance.Namespace,
+		PipelineVersion: pipelineVersion,
+		ConnectorName:   cyndi.ConnectorName(pipelineVersion, i.Instance.Spec.AppName),
+	})
+}
+
+// Returns the offset reset of the connector of the current pipeline version if there was one
+func (i *ReconcileIteration) activeOffsetReset() *cyndi.OffsetResetStatus {
+	if reset := i.Instance.Status.OffsetReset; reset != nil && reset.ConnectorName == i.Instance.Status.ConnectorName {
+		return reset
+	}
+
+	return nil
+}
+
+// Sets how the connector consumes if its offsets were reset
+func (i *ReconcileIteration) applyOffsetReset(connectorConfig *connect.ConnectorConfiguration) {
+	reset := i.activeOffsetReset()
+	if reset == nil {
+		return
+	}
+
+	// the new consumer group has no committed offsets
+	connectorConfig.AutoOffsetReset = config.AutoOffsetResetEarliest
+	connectorConfig.MinTimestamp = 0
+	if reset.Since != nil {
+		connectorConfig.MinTimestamp = reset.Since.UnixNano() / int64(time.Millisecond)
+	}
+}
+
+/*
+ * Replaces the consumer group of the connector if requested by the reset-offsets annotation.
+ * Returns true if the connector was updated.
+ */
+func (i *ReconcileIteration) resetOffsetsIfRequested() (bool, error) {
+	request := i.Instance.GetAnnotations()[annotationResetOffsets]
+	if request == "" {
+		// the same value can be requested again once the annotation was removed
+		if reset := i.Instance.Status.OffsetReset; reset != nil {
+			reset.Request = ""
+		}
+
+		return false, nil
+	}
+
+	if reset := i.Instance.Status.OffsetReset; reset != nil && reset.Request == request {
+		return false, nil
+	}
+
+	var since *metav1.Time
+	if request != offsetResetEarliest {
+		value, err := time.Parse(time.RFC3339, request)
+		if err != nil {
+			return false, fmt.Errorf(`"%s" is not a valid value for "%s"`, request, annotationResetOffsets)
+		}
+
+		since = &metav1.Time{Time: value}
+	}
+
+	if i.skipMutation("Not resetting offsets", "request", request) {
+		return false, nil
+	}
+
+	connectorName := i.Instance.Status.ConnectorName
+	connector, err := connect.GetConnector(i.ctx, i.Client, connectorName, i.connectorNamespace())
+	if err != nil {
+		return false, err
+	}
+
+	// suffixed to the consumer group of the pipeline version rather than to a previously reset one
+	group, err := i.templatedConsumerGroup(i.Instance.Status.PipelineVersion)
+	if err != nil {
+		return false, err
+	} else if group == "" {
+		group = connect.ConsumerGroup(connectorName)
+	}
+
+	previousGroup, previousReset := i.Instance.Status.ConsumerGroup, i.Instance.Status.OffsetReset
+	consumedGroup := i.connectorConsumerGroup()
+	now := metav1.Now()
+
+	i.Instance.Status.ConsumerGroup = fmt.Sprintf("%s-reset-%d", group, now.Unix())
+	i.Instance.Status.OffsetReset = &cyndi.OffsetResetStatus{
+		Request:               request,
+		ConnectorName:         connectorName,
+		PreviousConsumerGroup: consumedGroup,
+		Time:                  now,
+		Since:                 since,
+	}
+
+	desired, err := i.createConnector(connectorName, true)
+	if err == nil {
+		err = connect.RestoreConnector(i.ctx, i.Client, connector, desired)
+	}
+
+	if err != nil {
+		i.Instance.Status.ConsumerGroup, i.Instance.Status.OffsetReset = previousGroup, previousReset
+		return false, err
+	}
+
+	i.Log.Info("Reset connector offsets", "connector", connectorName, "consumerGroup", i.Instance.Status.ConsumerGroup, "request", request)
+	i.eventNormal("OffsetsReset", "Connector %s consumes from the earliest retained offset (%s) using consumer group %s", connectorName, request, i.Instance.Status.ConsumerGroup)
+	return true, nil
+}
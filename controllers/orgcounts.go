@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"math"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+Comparison of host counts per organization (validation.org.counts.top).
+A replication problem dropping the hosts of a single tenant may keep the total host count within the validation threshold.
+The host counts of the organizations with the most hosts in HBI are therefore compared one by one, using the same threshold.
+
+*/
+
+// Returns nil if the comparison is disabled or not supported by the HBI source (e.g. the HBI API)
+func (i *ReconcileIteration) compareOrgCounts(appTable string) (*cyndi.OrgCountsStatus, error) {
+	limit := i.config.ValidationOrgCountsTop
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	source, ok := i.Inventory.(database.OrgCountSource)
+	if !ok {
+		i.Log.Info("Not comparing host counts per organization as the HBI source does not support it")
+		return nil, nil
+	}
+
+	// the organizations with the most hosts of each topic shard are candidates for the overall top
+	var candidates []map[string]int64
+	for _, filters := range i.hbiFilters() {
+		counts, err := source.CountHostsByOrg(inventoryTableName, i.Instance.Spec.InsightsOnly, filters, nil, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, counts)
+	}
+
+	orgIds := database.TopOrgs(database.MergeOrgCounts(candidates...), limit)
+	if len(orgIds) == 0 {
+		metrics.OrgHostCounts(i.Instance, nil, 0)
+		return &cyndi.OrgCountsStatus{}, nil
+	}
+
+	var hbiCounts []map[string]int64
+	for _, filters := range i.hbiFilters() {
+		counts, err := source.CountHostsByOrg(inventoryTableName, i.Instance.Spec.InsightsOnly, filters, orgIds, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		hbiCounts = append(hbiCounts, counts)
+	}
+
+	hbi := database.MergeOrgCounts(hbiCounts...)
+
+	app, err := i.AppDb.CountHostsByOrg(appTable, false, []map[string]string{}, orgIds, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	validationConfig := i.getValidationConfig()
+	result := &cyndi.OrgCountsStatus{Compared: int64(len(orgIds))}
+	counts := make([]cyndi.OrgHostCount, len(orgIds))
+
+	for n, orgId := range orgIds {
+		counts[n] = cyndi.OrgHostCount{OrgID: orgId, HBI: hbi[orgId], App: app[orgId]}
+
+		mismatch := utils.Abs(counts[n].HBI - counts[n].App)
+		if !validationConfig.IsWithinThreshold(float64(mismatch)/math.Max(float64(counts[n].HBI), 1), mismatch) {
+			result.Mismatched = append(result.Mismatched, counts[n])
+		}
+	}
+
+	metrics.OrgHostCounts(i.Instance, counts, len(result.Mismatched))
+	i.Log.Info("Compared host counts per organization", "compared", result.Compared, "mismatched", result.Mismatched)
+	return result, nil
+}
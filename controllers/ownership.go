@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+Isolation of pipelines sharing an application database.
+Tables are recorded as owned by the pipeline that created them (in the inventory.cyndi_tables table) and a pipeline only removes the tables it owns as stale.
+Tables without an owner (created before ownership was recorded) are claimed by the pipeline using them. Other tables without an owner are only
+removed if the database is not shared, i.e. no table is owned by another pipeline.
+
+*/
+
+// namespace/name of the pipeline, identifying it in the application database
+func (i *ReconcileIteration) pipelineName() string {
+	return fmt.Sprintf("%s/%s", i.Instance.Namespace, i.Instance.Name)
+}
+
+// Returns the given tables owned by the pipeline. Tables without an owner that the pipeline keeps are claimed by it
+func (i *ReconcileIteration) ownedTables(tables []string, tablesToKeep []string) (owned []string, err error) {
+	owners, err := i.AppDb.GetTableOwners()
+	if err != nil {
+		return nil, err
+	}
+
+	shared := false
+	for _, owner := range owners {
+		if owner != i.pipelineName() {
+			shared = true
+		}
+	}
+
+	for _, table := range tables {
+		owner, registered := owners[table]
+
+		switch {
+		case registered && owner == i.pipelineName():
+			owned = append(owned, table)
+		case registered:
+			i.debug("Skipping table owned by another pipeline", "table", table, "owner", owner)
+		case utils.ContainsString(tablesToKeep, table):
+			if !i.skipMutation("Not claiming table", "table", table) {
+				if err = i.AppDb.RegisterTable(table, i.pipelineName()); err != nil {
+					return nil, err
+				}
+			}
+
+			owned = append(owned, table)
+		case !shared:
+			owned = append(owned, table)
+		default:
+			i.debug("Skipping table without an owner in a shared database", "table", table)
+		}
+	}
+
+	return owned, nil
+}
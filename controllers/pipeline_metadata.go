@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+The row of the pipeline in the inventory.cyndi_metadata table of the application database, mirroring the state of the pipeline
+(active table, operator version, last cutover, configuration hash) for DBAs and applications without access to Kubernetes.
+The row is only written if the state changed and is removed along with the pipeline.
+
+*/
+
+func (i *ReconcileIteration) pipelineMetadata() (database.PipelineMetadata, error) {
+	configHash, err := utils.SpecHash(i.Instance.Status.ActiveConfig)
+	if err != nil {
+		return database.PipelineMetadata{}, err
+	}
+
+	metadata := database.PipelineMetadata{
+		Pipeline:        i.pipelineName(),
+		ActiveTable:     i.Instance.Status.ActiveTableName,
+		OperatorVersion: OperatorVersion,
+		ConfigHash:      configHash,
+	}
+
+	if cutover := i.Instance.Status.LastCutoverTime; cutover != nil {
+		// the status only keeps seconds
+		lastCutover := cutover.Time.UTC().Truncate(time.Second)
+		metadata.LastCutover = &lastCutover
+	}
+
+	return metadata, nil
+}
+
+func (i *ReconcileIteration) reconcilePipelineMetadata() error {
+	metadata, err := i.pipelineMetadata()
+	if err != nil {
+		return err
+	}
+
+	current, err := i.AppDb.GetPipelineMetadata(metadata.Pipeline)
+	if err != nil {
+		return err
+	}
+
+	if current != nil && current.Equal(metadata) {
+		return nil
+	}
+
+	if i.skipMutation("Not updating pipeline metadata") {
+		return nil
+	}
+
+	i.debug("Updating pipeline metadata", "activeTable", metadata.ActiveTable, "operatorVersion", metadata.OperatorVersion)
+	return i.AppDb.UpdatePipelineMetadata(metadata)
+}
+
+func (i *ReconcileIteration) deletePipelineMetadata() error {
+	if i.skipMutation("Not removing pipeline metadata") {
+		return nil
+	}
+
+	return i.AppDb.DeletePipelineMetadata(i.pipelineName())
+}
@@ -1,21 +1,119 @@
 package controllers
 
-import "github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+import (
+	"fmt"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+)
 
 func (i *ReconcileIteration) probeStartingInitialSync() {
 	i.Log.Info("New pipeline version", "version", i.Instance.Status.PipelineVersion)
 	i.eventNormal("InitialSync", "Starting data synchronization to %s", i.Instance.Status.TableName)
+	// also covers pipelines created with spec.resyncRequestedAt set
+	i.fulfillResyncRequest()
 	i.Log.Info("Transitioning to InitialSync")
+	metrics.RefreshInitiated(i.Instance)
 }
 
 func (i *ReconcileIteration) probeStateDeviationRefresh(reason string) {
 	i.Log.Info("Refreshing pipeline due to state deviation", "reason", reason)
 	metrics.PipelineRefreshed(i.Instance, "deviation")
+	i.Instance.Status.LastRefreshReason = "State deviation: " + reason
+	i.fulfillResyncRequest()
 	i.eventWarning("Refreshing", "Refreshing pipeline due to state deviation: %s", reason)
+	i.silenceDriftAlerts(i.Instance.Status.LastRefreshReason)
+}
+
+func (i *ReconcileIteration) probeStateDeviationReported(reason string) {
+	i.Log.Info("State deviation detected in read-only mode", "reason", reason)
+	i.eventWarning("StateDeviation", "Pipeline deviates from the desired state: %s", reason)
 }
 
 func (i *ReconcileIteration) probePipelineDidNotBecomeValid() {
 	i.Log.Info("Pipeline failed to become valid. Refreshing.")
 	i.eventWarning("Refreshing", "Pipeline failed to become valid within the given threshold")
 	metrics.PipelineRefreshed(i.Instance, "invalid")
+	i.Instance.Status.LastRefreshReason = "Pipeline failed to become valid within the given threshold"
+	i.fulfillResyncRequest()
+	i.silenceDriftAlerts(i.Instance.Status.LastRefreshReason)
+}
+
+func (i *ReconcileIteration) probeInitialSyncStuck() {
+	i.Log.Info("Initial sync is not making progress", "lastProgress", i.Instance.Status.InitialSyncLastProgress)
+	i.eventWarning("InitialSyncStuck", "Host count of %s has not grown for %d seconds", i.Instance.Status.TableName, i.config.InitialSyncStuckTimeout)
+	metrics.InitialSyncStuck(i.Instance)
+}
+
+func (i *ReconcileIteration) probeInitialSyncSlow() {
+	throughput := i.Instance.Status.InitialSyncThroughput
+	i.Log.Info("Initial sync is slow", "rowsPerMinute", throughput.RowsPerMinute, "estimatedCompletion", throughput.EstimatedCompletion)
+	i.eventWarning("InitialSyncSlow", "%d hosts per minute are added to %s, which is below the minimum of %d", throughput.RowsPerMinute, i.Instance.Status.TableName, i.config.InitialSyncThroughputMin)
+}
+
+func (i *ReconcileIteration) probeSyncDeadlineExceeded() {
+	i.Log.Info("Initial sync exceeded its deadline", "started", i.Instance.Status.InitialSyncStarted, "action", i.config.InitialSyncTimeoutAction)
+	i.eventWarning("SyncDeadlineExceeded", "Pipeline did not become valid within %d seconds", i.config.InitialSyncTimeout)
+	metrics.InitialSyncDeadlineExceeded(i.Instance)
+}
+
+func (i *ReconcileIteration) probeRestartingLateConnector() {
+	i.Log.Info("Restarting connector of a pipeline that exceeded its initial sync deadline", "connector", i.Instance.Status.ConnectorName)
+	i.eventNormal("RestartingConnector", "Restarting connector %s as the initial sync exceeded its deadline", i.Instance.Status.ConnectorName)
+	metrics.ConnectorUpdated(i.Instance, metrics.CONNECTOR_RESTARTED)
+}
+
+func (i *ReconcileIteration) probeRefreshAborted(abandoned string) {
+	i.Log.Info("Refresh aborted", "abandoned", abandoned, "table", i.Instance.Status.TableName)
+	i.eventWarning("RefreshAborted", "Abandoning %s as the initial sync exceeded its deadline. Falling back to %s", abandoned, i.Instance.Status.TableName)
+	i.Instance.Status.LastRefreshReason = "Refresh aborted as the initial sync exceeded its deadline"
+}
+
+func (i *ReconcileIteration) probeRestartingStuckConnector() {
+	i.Log.Info("Restarting connector of a stuck pipeline", "connector", i.Instance.Status.ConnectorName)
+	i.eventNormal("RestartingConnector", "Restarting connector %s as the initial sync is stuck", i.Instance.Status.ConnectorName)
+	metrics.ConnectorUpdated(i.Instance, metrics.CONNECTOR_RESTARTED)
+}
+
+func (i *ReconcileIteration) probeStuckRefresh() {
+	i.Log.Info("Initial sync is stuck. Refreshing.")
+	i.eventWarning("Refreshing", "Refreshing pipeline as the initial sync is stuck")
+	metrics.PipelineRefreshed(i.Instance, metrics.REFRESH_STUCK)
+	i.Instance.Status.LastRefreshReason = "Initial sync is stuck"
+	i.fulfillResyncRequest()
+	i.silenceDriftAlerts(i.Instance.Status.LastRefreshReason)
+}
+
+func (i *ReconcileIteration) probeConnectorCreated(name string) {
+	i.Log.Info("Connector created", "connector", name)
+	metrics.ConnectorUpdated(i.Instance, metrics.CONNECTOR_CREATED)
+}
+
+func (i *ReconcileIteration) probeConnectorRecreated(name string) {
+	i.Log.Info("Re-created missing connector", "connector", name)
+	i.eventNormal("ConnectorRecreated", "Connector %s was not found and has been re-created", name)
+	metrics.ConnectorUpdated(i.Instance, metrics.CONNECTOR_CREATED)
+}
+
+func (i *ReconcileIteration) probeConnectorRestored(name string, diff string) {
+	i.Log.Info("Restored connector modified outside of the operator", "connector", name, "diff", diff)
+	i.eventWarning("ConnectorRestored", "Connector %s was modified outside of the operator. Its configuration has been restored", name)
+	metrics.ConnectorUpdated(i.Instance, metrics.CONNECTOR_RESTORED)
+}
+
+func (i *ReconcileIteration) probeConnectorDeleted(name string, reason string) {
+	metrics.ConnectorUpdated(i.Instance, metrics.CONNECTOR_DELETED)
+	i.audit(auditConnectorDeleted, name, reason)
+}
+
+func (i *ReconcileIteration) probeTableDropped(table string, reason string) {
+	metrics.TableDropped(i.Instance)
+	i.audit(auditTableDropped, table, reason)
+}
+
+func (i *ReconcileIteration) probeViewReplaced(previous *string, reason string) {
+	if previous != nil {
+		reason = fmt.Sprintf("%s (previously %s)", reason, *previous)
+	}
+
+	i.audit(auditViewReplaced, i.Instance.Status.TableName, reason)
 }
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+Offset-aware refresh (refresh.strategy=offsets).
+Rather than consuming the topic from the beginning, the connector of the new pipeline version resumes from the offsets committed by the connector of the active table.
+This requires the new table to be cloned from the active table. The connector of the active table is therefore stopped first so that the clone contains
+everything the connector committed. Only events produced since then are replayed into the new table.
+
+The Kafka Connect cluster needs to allow the consumer.override.group.id property (connector.client.config.override.policy=All).
+
+*/
+
+func (i *ReconcileIteration) isOffsetAwareRefresh() bool {
+	return i.config.RefreshStrategy == config.RefreshStrategyOffsets && i.config.DBTableCloneEnabled && i.Instance.Status.CloneSourceTable != ""
+}
+
+/*
+ * Stops the connector of the active table so that its consumer group can be handed over to the next pipeline version.
+ * Returns false until the connector had enough time to commit its offsets.
+ */
+func (i *ReconcileIteration) handOverConsumerGroup() (done bool, err error) {
+	if !i.isOffsetAwareRefresh() {
+		return true, nil
+	}
+
+	if started := i.Instance.Status.ConsumerGroupHandoverStarted; started != nil {
+		return time.Since(started.Time) >= time.Duration(i.config.RefreshHandoverGracePeriod)*time.Second, nil
+	}
+
+	source := i.Instance.Status.CloneSourceTable
+
+	active, err := i.AppDb.GetCurrentTable()
+	if err != nil {
+		return false, err
+	} else if active == nil || *active != source {
+		// the table will not be cloned - fall back to consuming the topic from the beginning
+		return true, nil
+	}
+
+	connectorName := cyndi.TableNameToConnectorName(source, i.Instance.Spec.AppName)
+
+	// the previous connector may run on a Connect cluster in a different namespace
+	connectors, err := connect.GetConnectorsForOwner(i.ctx, i.Client, "", i.Instance.GetUIDString())
+	if err != nil {
+		return false, err
+	}
+
+	for _, connector := range connectors.Items {
+		if connector.GetName() != connectorName {
+			continue
+		}
+
+		i.Log.Info("Stopping connector to hand over its consumer group", "connector", connectorName, "namespace", connector.GetNamespace())
+
+		if err = connect.DeleteConnector(i.ctx, i.Client, connectorName, connector.GetNamespace()); err != nil {
+			return false, err
+		}
+
+		i.probeConnectorDeleted(connectorName, "consumer group handed over to the next pipeline version")
+	}
+
+	now := metav1.Now()
+	i.Instance.Status.ConsumerGroupHandoverStarted = &now
+	return false, nil
+}
+
+// Returns the consumer group the connector of the next pipeline version resumes from or an empty string if the topic should be consumed from the beginning
+func (i *ReconcileIteration) getHandedOverConsumerGroup() string {
+	if !i.isOffsetAwareRefresh() || i.Instance.Status.ConsumerGroupHandoverStarted == nil {
+		return ""
+	}
+
+	// the previous connector may itself have resumed from a consumer group of an older connector
+	if i.Instance.Status.ConsumerGroup != "" {
+		return i.Instance.Status.ConsumerGroup
+	}
+
+	return connect.ConsumerGroup(cyndi.TableNameToConnectorName(i.Instance.Status.CloneSourceTable, i.Instance.Spec.AppName))
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+Health of the replication slots (and publications) in the HBI database feeding the topic of the pipeline.
+These are the slots of the managed source connector (spec.manageSourceConnector) and the slots of a source connector managed elsewhere (source.replication.slots).
+A slot that is not consumed keeps the HBI database from recycling WAL without the pipeline noticing anything but stale data.
+The problems are reported using the ReplicationHealthy condition and metrics. They do not invalidate the pipeline.
+
+*/
+
+const (
+	reasonReplicationHealthy   = "ReplicationHealthy"
+	reasonSlotMissing          = "ReplicationSlotMissing"
+	reasonSlotLost             = "ReplicationSlotLost"
+	reasonSlotInactive         = "ReplicationSlotInactive"
+	reasonSlotLagging          = "ReplicationSlotLagging"
+	reasonWALRetentionExceeded = "WALRetentionExceeded"
+	reasonPublicationMissing   = "PublicationMissing"
+)
+
+// The slots to check and whether each is expected to be in use
+func (i *ReconcileIteration) replicationSlotsToCheck() (slots []string, expectActive map[string]bool) {
+	expectActive = make(map[string]bool)
+
+	if i.Instance.Spec.ManageSourceConnector != nil {
+		current := i.sourceSlotName(i.Instance.Status.PipelineVersion)
+
+		// slots of previous pipeline versions are being dropped but still retain WAL until then
+		for _, slot := range i.Instance.Status.SourceReplicationSlots {
+			slots = append(slots, slot)
+			expectActive[slot] = slot == current
+		}
+	}
+
+	for _, slot := range i.config.SourceReplicationSlots {
+		slots = append(slots, slot)
+		expectActive[slot] = true
+	}
+
+	return slots, expectActive
+}
+
+// Returns the reason and a description of the problem of the given slot. Empty if the slot is healthy
+func (i *ReconcileIteration) replicationSlotProblem(slot string, status database.ReplicationSlotStatus, expectActive bool) (string, string) {
+	switch {
+	case !status.Exists:
+		return reasonSlotMissing, fmt.Sprintf("replication slot %s does not exist", slot)
+	case status.WALStatus == "lost":
+		return reasonSlotLost, fmt.Sprintf("replication slot %s lost WAL it needs", slot)
+	case expectActive && !status.Active:
+		return reasonSlotInactive, fmt.Sprintf("replication slot %s is not in use", slot)
+	case status.RetainedBytes > i.config.SourceSlotRetentionThreshold:
+		return reasonWALRetentionExceeded, fmt.Sprintf("replication slot %s retains %d bytes of WAL", slot, status.RetainedBytes)
+	case status.LagBytes > i.config.SourceSlotLagThreshold:
+		return reasonSlotLagging, fmt.Sprintf("replication slot %s lags %d bytes behind", slot, status.LagBytes)
+	default:
+		return "", ""
+	}
+}
+
+// Checks the replication slots and publications and updates the ReplicationHealthy condition
+func (i *ReconcileIteration) checkReplicationHealth() error {
+	slots, expectActive := i.replicationSlotsToCheck()
+
+	// publications of the managed source connector are named after the slots
+	var publications []string
+	if i.Instance.Spec.ManageSourceConnector != nil && i.Instance.Status.PipelineVersion != "" {
+		publications = append(publications, i.sourceSlotName(i.Instance.Status.PipelineVersion))
+	}
+
+	publications = append(publications, i.config.SourcePublications...)
+
+	if len(slots) == 0 && len(publications) == 0 {
+		i.Instance.ResetReplicationHealthy()
+		return nil
+	}
+
+	// the configuration only allows slots with a single HBI database
+	db, ok := i.Inventory.(database.Database)
+	if !ok {
+		return nil
+	}
+
+	var reason string
+	var problems []string
+
+	report := func(problemReason string, problem string) {
+		if reason == "" {
+			reason = problemReason
+		}
+
+		problems = append(problems, problem)
+	}
+
+	for _, slot := range slots {
+		status, err := database.GetReplicationSlotStatus(db, slot)
+		if err != nil {
+			return err
+		}
+
+		problemReason, problem := i.replicationSlotProblem(slot, status, expectActive[slot])
+		if problemReason != "" {
+			report(problemReason, problem)
+		}
+
+		metrics.ReplicationSlotHealth(i.Instance, slot, status.LagBytes, status.RetainedBytes, problemReason == "")
+	}
+
+	for _, publication := range publications {
+		exists, err := database.PublicationExists(db, publication)
+		if err != nil {
+			return err
+		} else if !exists {
+			report(reasonPublicationMissing, fmt.Sprintf("publication %s does not exist", publication))
+		}
+	}
+
+	previous := i.Instance.GetReplicationHealthy()
+
+	if reason == "" {
+		if previous != nil && previous.Status == metav1.ConditionFalse {
+			i.eventNormal(reasonReplicationHealthy, "Replication recovered (%s)", previous.Reason)
+		}
+
+		i.Instance.SetReplicationHealthy(metav1.ConditionTrue, reasonReplicationHealthy, fmt.Sprintf("%d replication slots and %d publications are healthy", len(slots), len(publications)))
+		return nil
+	}
+
+	message := strings.Join(problems, ", ")
+	if previous == nil || previous.Status != metav1.ConditionFalse || previous.Reason != reason {
+		i.eventWarning(reason, "Replication unhealthy: %s", message)
+	}
+
+	i.Instance.SetReplicationHealthy(metav1.ConditionFalse, reason, message)
+	return nil
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"sort"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+)
+
+/*
+
+Breakdown of host counts by reporter (validation.reporter.counts.enabled).
+Hosts of some reporters may lag behind those of others (e.g. puptoo-reported hosts behind rhsm-reported ones).
+The host counts of each reporter in HBI and in the application table are recorded in the status and exported as metrics to help debugging such cases.
+The breakdown is informational only, i.e. it does not affect the validity of the pipeline.
+
+*/
+
+// Returns nil if the breakdown is disabled or not supported by the HBI source (e.g. the HBI API)
+func (i *ReconcileIteration) countHostsByReporter(appTable string) ([]cyndi.ReporterHostCount, error) {
+	if !i.config.ValidationReporterCounts {
+		return nil, nil
+	}
+
+	source, ok := i.Inventory.(database.ReporterCountSource)
+	if !ok {
+		i.Log.Info("Not counting hosts per reporter as the HBI source does not support it")
+		return nil, nil
+	}
+
+	var hbiCounts []map[string]int64
+	for _, filters := range i.hbiFilters() {
+		counts, err := source.CountHostsByReporter(inventoryTableName, i.Instance.Spec.InsightsOnly, filters)
+		if err != nil {
+			return nil, err
+		}
+
+		hbiCounts = append(hbiCounts, counts)
+	}
+
+	hbi := database.MergeOrgCounts(hbiCounts...)
+
+	app, err := i.AppDb.CountHostsByReporter(appTable, false, []map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+
+	reporters := make([]string, 0, len(hbi))
+	for reporter := range database.MergeOrgCounts(hbi, app) {
+		reporters = append(reporters, reporter)
+	}
+
+	sort.Strings(reporters)
+
+	counts := make([]cyndi.ReporterHostCount, len(reporters))
+	for n, reporter := range reporters {
+		counts[n] = cyndi.ReporterHostCount{Reporter: reporter, HBI: hbi[reporter], App: app[reporter]}
+	}
+
+	metrics.ReporterHostCounts(i.Instance, counts)
+	i.Log.Info("Counted hosts per reporter", "counts", counts)
+	return counts, nil
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+)
+
+/*
+
+Resumption of the creation of a pipeline version interrupted in the middle of a reconciliation (e.g. by a crash of the operator).
+Status changes are normally written at the end of a reconciliation. A crash between creating the table and recording its name would
+therefore leave the table behind while the next attempt starts another pipeline version. To prevent that:
+* the name of the table is recorded (status.pendingTableName) before the table is created. The next attempt adopts the table if it exists
+* the pipeline version is registered before its connector is created (status.connectorPending). A missing connector is then created
+  rather than reported as having disappeared
+The table and connector of a pending pipeline version are not removed as stale.
+
+*/
+
+// Writes the status of the pipeline before a step creating resources that would otherwise be orphaned if the reconciliation was interrupted
+func (i *ReconcileIteration) persistStatus() error {
+	return i.Client.Status().Update(i.ctx, i.Instance)
+}
+
+// Returns the pipeline version whose creation was interrupted or names (and records) a new one
+func (i *ReconcileIteration) startPipelineVersion() (pipelineVersion string, resumed bool, err error) {
+	if pending := i.Instance.Status.PendingTableName; pending != "" {
+		i.Log.Info("Resuming interrupted pipeline version", "table", pending)
+		return cyndi.TableNameToPipelineVersion(pending), true, nil
+	}
+
+	if pipelineVersion, err = i.newPipelineVersion(); err != nil {
+		return "", false, err
+	}
+
+	i.Instance.Status.PendingTableName = cyndi.TableName(pipelineVersion)
+	return pipelineVersion, false, i.persistStatus()
+}
+
+// Creates the table of a new pipeline version or adopts the table created by an interrupted attempt. Returns true if the table was adopted
+func (i *ReconcileIteration) createOrAdoptTable(name string, resumed bool) (adopted bool, err error) {
+	if resumed {
+		if adopted, err = i.AppDb.CheckIfTableExists(name); err != nil {
+			return false, err
+		}
+	}
+
+	if !adopted {
+		return false, i.createTable(name)
+	}
+
+	i.Log.Info("Adopting table left behind by an interrupted reconciliation", "table", name)
+	i.Instance.Status.UnloggedTableServerStart = nil
+	i.Instance.Status.TableIndexesPending = true
+	return true, i.setUpTable(name)
+}
+
+// Records the table of the pipeline version as created. The connector is created next
+func (i *ReconcileIteration) registerPipelineVersion() error {
+	i.Instance.Status.PendingTableName = ""
+	i.Instance.Status.ConnectorPending = true
+	return i.persistStatus()
+}
+
+// Creates the connector of a registered pipeline version if the reconciliation was interrupted before the connector was created
+func (i *ReconcileIteration) createPendingConnector() error {
+	name := i.Instance.Status.ConnectorName
+	if i.skipMutation("Not creating connector of interrupted pipeline version", "connector", name) {
+		return nil
+	}
+
+	i.Log.Info("Creating connector of interrupted pipeline version", "connector", name)
+	if _, err := i.createConnector(name, false); err != nil {
+		return err
+	}
+
+	i.Instance.Status.ConnectorPending = false
+	i.probeConnectorCreated(name)
+	return nil
+}
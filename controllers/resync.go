@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"time"
+)
+
+/*
+
+Refreshes requested declaratively (spec.resyncRequestedAt), e.g. by bumping the timestamp in a GitOps repository rather than annotating the pipeline.
+A request newer than status.lastResyncAt is a state deviation, i.e. it triggers a refresh like other deviations do.
+The request is recorded as fulfilled (status.lastResyncAt) once a refresh - for whatever reason - or a new pipeline version is started,
+so each request triggers a single refresh.
+
+*/
+
+// Returns true if spec.resyncRequestedAt has not been fulfilled by a refresh yet
+func (i *ReconcileIteration) resyncRequested() bool {
+	requested := i.Instance.Spec.ResyncRequestedAt
+	last := i.Instance.Status.LastResyncAt
+
+	return requested != nil && (last == nil || requested.After(last.Time))
+}
+
+// Records the pending resync request (if any) as fulfilled by the refresh being started
+func (i *ReconcileIteration) fulfillResyncRequest() {
+	if !i.resyncRequested() {
+		return
+	}
+
+	i.Log.Info("Resync request fulfilled", "resyncRequestedAt", i.Instance.Spec.ResyncRequestedAt.Format(time.RFC3339))
+	i.Instance.Status.LastResyncAt = i.Instance.Spec.ResyncRequestedAt.DeepCopy()
+}
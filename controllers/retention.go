@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
+
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+/*
+
+Blue/green retention of the table that backed the inventory.hosts view before a cutover.
+The previous table (and its connector, unless it was stopped to hand over its consumer group) is kept for db.table.retention seconds.
+Until then the view can be flipped back to it by setting the rollback annotation to the name of the previous table.
+The pipeline then continues with the pipeline version of the previous table, keeping the table it rolled back from for another retention period.
+
+*/
+
+// Points the view back to the given (previous) table
+const annotationRollbackTo = "cyndi.cloud.redhat.com/rollback-to"
+
+// Records that the view was pointed to the table of the current pipeline version
+func (i *ReconcileIteration) recordCutover(previous *string) {
+	now := metav1.Now()
+	i.Instance.Status.LastCutoverTime = &now
+	i.Instance.Status.PreviousTableName = ""
+
+	if previous != nil && i.config.DBTableRetention > 0 {
+		i.Instance.Status.PreviousTableName = *previous
+	}
+}
+
+// Returns the previous table if it is still retained. Empty otherwise
+func (i *ReconcileIteration) retainedTable() string {
+	cutover := i.Instance.Status.LastCutoverTime
+	if i.Instance.Status.PreviousTableName == "" || cutover == nil {
+		return ""
+	}
+
+	if time.Since(cutover.Time) >= time.Duration(i.config.DBTableRetention)*time.Second {
+		return ""
+	}
+
+	return i.Instance.Status.PreviousTableName
+}
+
+// The connector of a previous table may have resumed from the consumer group of an older connector
+func (i *ReconcileIteration) recoverConsumerGroup() error {
+	connector, err := connect.GetConnector(i.ctx, i.Client, i.Instance.Status.ConnectorName, i.connectorNamespace())
+	if err == nil {
+		i.Instance.Status.ConsumerGroup, _, _ = unstructured.NestedString(connector.Object, "spec", "config", "consumer.override.group.id")
+	} else if !k8errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+/*
+ * Points the view back to the retained previous table if requested by the rollback annotation.
+ * Returns true if the view was rolled back.
+ */
+func (i *ReconcileIteration) rollbackIfRequested() (bool, error) {
+	target := i.Instance.GetAnnotations()[annotationRollbackTo]
+	if target == "" || target == i.Instance.Status.TableName {
+		return false, nil
+	}
+
+	if target != i.retainedTable() {
+		i.debug("Not rolling back as the table is not retained", "table", target)
+		return false, nil
+	}
+
+	if i.skipMutation("Not rolling back view", "table", target) {
+		return false, nil
+	}
+
+	if exists, err := i.AppDb.CheckIfTableExists(target); err != nil {
+		return false, err
+	} else if !exists {
+		return false, fmt.Errorf("Retained table %s not found", target)
+	}
+
+	current, err := i.AppDb.GetCurrentTable()
+	if err != nil {
+		return false, err
+	}
+
+	i.Log.Info("Rolling back view", "table", target)
+	if err = i.AppDb.UpdateView(target); err != nil {
+		return false, err
+	}
+
+	if err = i.applyDBGrants(i.viewName()); err != nil {
+		return false, err
+	}
+
+	i.Instance.TransitionToNew()
+	if err = i.Instance.TransitionToInitialSync(cyndi.TableNameToPipelineVersion(target)); err != nil {
+		return false, err
+	}
+
+	if err = i.recoverConsumerGroup(); err != nil {
+		return false, err
+	}
+
+	// the table rolled back from is retained in turn
+	i.recordCutover(current)
+	i.probeViewReplaced(current, "rollback requested")
+	i.eventNormal("RolledBack", "inventory.hosts view rolled back to %s", target)
+	return true, nil
+}
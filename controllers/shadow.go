@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"fmt"
+)
+
+/*
+
+Shadow validation of refreshes. While a refresh seeds a new table (e.g. after the connector template or the columns changed),
+inventory.hosts keeps pointing to the previous table and the new one is validated in the shadow. With validation.shadow.cycles set above 1,
+the new table needs to pass that many consecutive validations before the pipeline becomes valid and the view is switched to it,
+so that a configuration upgrade is only promoted once it has proven stable. A failed validation starts the count over.
+New pipelines have no previous table to fall back to and are promoted by the first successful validation as usual.
+
+*/
+
+const reasonShadowValidation = "ShadowValidation"
+
+// true if a refresh is seeding a table while the view points to the previous one
+func (i *ReconcileIteration) inShadow() bool {
+	active := i.Instance.Status.ActiveTableName
+	return active != "" && active != i.Instance.Status.TableName
+}
+
+/*
+ * Records a successful validation of the table of a refresh.
+ * Returns true if the table has not passed enough consecutive validations to be promoted yet, in which case the pipeline is kept invalid (uncounted).
+ */
+func (i *ReconcileIteration) holdInShadow(message string, hostCount int64) bool {
+	if !i.inShadow() {
+		i.Instance.Status.ShadowValidations = 0
+		return false
+	}
+
+	i.Instance.Status.ShadowValidations++
+
+	cycles := i.config.ValidationShadowCycles
+	if i.Instance.Status.ShadowValidations >= cycles {
+		if cycles > 1 {
+			i.eventNormal("Promoted", "Table %s passed %d consecutive validations and is promoted", i.Instance.Status.TableName, i.Instance.Status.ShadowValidations)
+		}
+
+		return false
+	}
+
+	i.Log.Info("Holding table in shadow", "table", i.Instance.Status.TableName, "validations", i.Instance.Status.ShadowValidations, "cycles", cycles)
+	i.Instance.SetInvalidUncounted(reasonShadowValidation,
+		fmt.Sprintf("%s (%d of %d consecutive validations before promotion)", message, i.Instance.Status.ShadowValidations, cycles), hostCount)
+	return true
+}
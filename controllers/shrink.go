@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+)
+
+/*
+
+Protection against switching the hosts view to a table holding drastically fewer hosts than the active table.
+A table passing validation despite being partially synced (e.g. due to a validation bug) would otherwise be exposed to the application.
+The switch is refused (and the pipeline marked as Degraded) until the annotation allowing the new table is set.
+
+*/
+
+// Allows the view to be switched to the given table regardless of its host count
+const annotationAllowShrink = "cyndi.cloud.redhat.com/allow-shrink"
+
+/*
+ * Compares the host count of the table of the current pipeline version to that of the active table.
+ * Returns true (and marks the pipeline as Degraded) if the view should not be switched.
+ */
+func (i *ReconcileIteration) refuseShrunkTable(activeTable string) (bool, error) {
+	maxShrink := i.config.ViewSwitchMaxShrink
+	if maxShrink >= 100 || i.Instance.GetAnnotations()[annotationAllowShrink] == i.Instance.Status.TableName {
+		return false, nil
+	}
+
+	activeCount, err := i.AppDb.CountHosts(database.AppTable(activeTable), false, []map[string]string{})
+	if err != nil {
+		return false, fmt.Errorf("Failed to get host count from active table %w", err)
+	}
+
+	newCount, err := i.AppDb.CountHosts(database.AppTable(i.Instance.Status.TableName), false, []map[string]string{})
+	if err != nil {
+		return false, fmt.Errorf("Failed to get host count from application table %w", err)
+	}
+
+	if activeCount == 0 || (activeCount-newCount)*100 <= activeCount*maxShrink {
+		return false, nil
+	}
+
+	i.Log.Info("Not updating view as the table is much smaller than the active one", "table", i.Instance.Status.TableName, "hosts", newCount, "activeTable", activeTable, "activeHosts", activeCount)
+	i.markDegraded(i.Instance, reasonTableShrunk, fmt.Sprintf(
+		"Table %s holds %d hosts compared to %d hosts of the active table %s. Set the %s annotation to %s to switch the view anyway",
+		i.Instance.Status.TableName, newCount, activeCount, activeTable, annotationAllowShrink, i.Instance.Status.TableName))
+
+	return true, nil
+}
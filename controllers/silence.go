@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/monitoring"
+)
+
+/*
+
+Silencing of the drift alerts of a pipeline refreshed by the operator (alertmanager.url).
+The alerts of a pipeline going through the initial sync after a refresh are expected and the refresh heals the pipeline,
+so the operator silences them in Alertmanager for alertmanager.silence.duration seconds rather than paging on-call.
+
+*/
+
+// Not fatal - the refresh proceeds if the silence cannot be created
+func (i *ReconcileIteration) silenceDriftAlerts(reason string) {
+	if i.config.AlertmanagerURL == "" || i.skipMutation("Not silencing alerts of refreshed pipeline") {
+		return
+	}
+
+	duration := time.Duration(i.config.AlertmanagerSilenceDuration) * time.Second
+	comment := fmt.Sprintf("Pipeline %s refreshed by cyndi-operator: %s", i.pipelineName(), reason)
+
+	id, err := monitoring.SilenceDriftAlerts(i.ctx, i.config.AlertmanagerURL, i.Instance.Spec.AppName, time.Now().Add(duration), comment)
+	if err != nil {
+		i.Log.Error(err, "Failed to silence alerts of refreshed pipeline")
+		return
+	}
+
+	i.Log.Info("Silenced alerts of refreshed pipeline", "silence", id, "duration", duration)
+	i.eventNormal("AlertsSilenced", "Alerts of the pipeline silenced for %s while it is refreshed (silence %s)", duration, id)
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+
+Annotations skipping individual validation checks of a pipeline during known incidents (e.g. HBI deleting hosts in bulk).
+An annotation holds the time (RFC3339) until which the check is skipped so that a skip cannot be forgotten.
+Expiries further ahead than validation.skip.max.duration are ignored for the same reason.
+
+*/
+
+const annotationSkipValidationPrefix = "cyndi.cloud.redhat.com/skip-"
+
+const (
+	// host ids are not compared - only host counts are
+	validationCheckContent = "content"
+	// hosts deleted in HBI lingering in the application table do not fail the validation
+	validationCheckLingering = "lingering"
+	// host counts per organization are not compared
+	validationCheckOrgCounts = "org-counts"
+	// the application table is not cross-checked against xjoin
+	validationCheckXjoin = "xjoin"
+)
+
+var validationChecks = []string{validationCheckContent, validationCheckLingering, validationCheckOrgCounts, validationCheckXjoin}
+
+func skipValidationAnnotation(check string) string {
+	return fmt.Sprintf("%s%s-validation", annotationSkipValidationPrefix, check)
+}
+
+// Returns the validation checks skipped at the given time
+func (i *ReconcileIteration) skippedValidationChecks(now time.Time) (skipped []string, err error) {
+	maxUntil := now.Add(time.Duration(i.config.ValidationSkipMaxDuration) * time.Second)
+
+	for _, check := range validationChecks {
+		annotation := skipValidationAnnotation(check)
+
+		value, ok := i.Instance.GetAnnotations()[annotation]
+		if !ok {
+			continue
+		}
+
+		until, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf(`"%s" is not a valid value for "%s"`, value, annotation)
+		}
+
+		if until.After(maxUntil) {
+			i.eventWarning("ValidationSkipIgnored", "Ignoring %s as it expires more than %d seconds ahead", annotation, i.config.ValidationSkipMaxDuration)
+			continue
+		}
+
+		if now.Before(until) {
+			skipped = append(skipped, check)
+		}
+	}
+
+	return skipped, nil
+}
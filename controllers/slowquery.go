@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+)
+
+/*
+
+Reporting of slow validation queries. With validation.slow.query.threshold set, the plans of HBI queries running longer are captured
+(see database/slowquery.go) and reported in a SlowQuery warning event so that missing indexes can be spotted without reproducing the query.
+The query and its complete plan are logged by the database, the event carries a truncated one as the size of events is limited.
+
+*/
+
+// events longer than 1024 bytes are truncated by the recorder
+const maxSlowQueryPlanLength = 768
+
+func (i *ReconcileIteration) reportSlowQueries() {
+	source, ok := i.Inventory.(database.SlowQuerySource)
+	if !ok {
+		return
+	}
+
+	for _, query := range source.TakeSlowQueries() {
+		plan := query.Plan
+		if plan == "" {
+			plan = "(plan not available)"
+		} else if len(plan) > maxSlowQueryPlanLength {
+			plan = plan[:maxSlowQueryPlanLength] + "..."
+		}
+
+		i.eventWarning("SlowQuery", "Validation query took %s (threshold %ds), plan:\n%s", query.Duration.Round(time.Millisecond), i.config.ValidationSlowQueryThreshold, plan)
+	}
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+/*
+
+Smoke test of the application's access path to the syndicated data.
+Whenever the inventory.hosts view is switched to a new table, a one-shot Job queries the view using the credentials of the application (dbSecret).
+Its outcome and output are recorded in status.smokeTest.
+
+*/
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=pods;pods/log,verbs=get;list
+
+const (
+	labelSmokeTest = "cyndi.cloud.redhat.com/smoke-test"
+
+	smokeTestDeadlineSeconds = 300
+	smokeTestMaxMessage      = 1024
+)
+
+// row count, sample host lookup and a tag query, rendered with the view of the pipeline
+var smokeTestQueries = []string{
+	`SELECT 'hosts: ' || count(*) FROM %[1]s`,
+	`SELECT 'sample host: ' || id FROM %[1]s WHERE id = (SELECT id FROM %[1]s LIMIT 1)`,
+	`SELECT 'tagged hosts: ' || count(*) FROM (SELECT 1 FROM %[1]s WHERE tags @> '[{"namespace": "insights-client"}]' LIMIT 100) AS tagged`,
+}
+
+// the Job reads connection parameters from the application database secret
+var smokeTestSecretKeys = [][2]string{
+	{"PGHOST", "db.host"},
+	{"PGPORT", "db.port"},
+	{"PGDATABASE", "db.name"},
+	{"PGUSER", "db.user"},
+	{"PGPASSWORD", "db.password"},
+}
+
+func smokeTestJobName(pipelineName string, pipelineVersion string) string {
+	return fmt.Sprintf("%s-smoke-test-%s", pipelineName, strings.ReplaceAll(pipelineVersion, "_", "-"))
+}
+
+func (i *ReconcileIteration) newSmokeTestJob() *batchv1.Job {
+	backoffLimit := int32(0)
+	deadline := int64(smokeTestDeadlineSeconds)
+
+	args := []string{"-v", "ON_ERROR_STOP=1", "--no-align", "--tuples-only"}
+	for _, query := range smokeTestQueries {
+		args = append(args, "-c", fmt.Sprintf(query, database.AppTable(i.viewName())))
+	}
+
+	env := []corev1.EnvVar{{Name: "PGSSLMODE", Value: i.config.SSLMode}}
+	for _, key := range smokeTestSecretKeys {
+		env = append(env, corev1.EnvVar{
+			Name: key[0],
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: utils.AppDbSecretName(i.Instance.Spec)},
+					Key:                  key[1],
+				},
+			},
+		})
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      smokeTestJobName(i.Instance.Name, i.Instance.Status.PipelineVersion),
+			Namespace: i.Instance.Namespace,
+			Labels:    utils.Merge(i.pipelineLabels(), map[string]string{labelSmokeTest: i.Instance.Name}),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &deadline,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: utils.Merge(i.pipelineLabels(), map[string]string{labelSmokeTest: i.Instance.Name}),
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "smoke-test",
+						Image:   i.config.SmokeTestImage,
+						Command: []string{"psql"},
+						Args:    args,
+						Env:     env,
+					}},
+				},
+			},
+		},
+	}
+}
+
+// Starts the smoke test of the table the view has just been switched to. Jobs of previous smoke tests are removed
+func (i *ReconcileIteration) startSmokeTest() error {
+	job := i.newSmokeTestJob()
+
+	if err := i.deleteSmokeTestJobs(job.Name); err != nil {
+		return err
+	}
+
+	if err := controllerutil.SetControllerReference(i.Instance, job, i.Scheme); err != nil {
+		return err
+	}
+
+	if err := i.Client.Create(i.ctx, job); err != nil && !k8errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	i.Log.Info("Started smoke test", "job", job.Name)
+	i.Instance.Status.SmokeTest = &cyndi.SmokeTestStatus{
+		JobName:   job.Name,
+		TableName: i.Instance.Status.TableName,
+		Result:    cyndi.SmokeTestPending,
+	}
+
+	return nil
+}
+
+func (i *ReconcileIteration) deleteSmokeTestJobs(keep string) error {
+	jobs := &batchv1.JobList{}
+	if err := i.Client.List(i.ctx, jobs, client.InNamespace(i.Instance.Namespace), client.MatchingLabels{labelSmokeTest: i.Instance.Name}); err != nil {
+		return err
+	}
+
+	for idx := range jobs.Items {
+		if jobs.Items[idx].Name == keep {
+			continue
+		}
+
+		if err := i.Client.Delete(i.ctx, &jobs.Items[idx], client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Records the outcome of a pending smoke test once its Job finishes
+func (i *ReconcileIteration) checkSmokeTest() error {
+	status := i.Instance.Status.SmokeTest
+	if status == nil || status.Result != cyndi.SmokeTestPending {
+		return nil
+	}
+
+	job := &batchv1.Job{}
+	if err := i.Client.Get(i.ctx, client.ObjectKey{Namespace: i.Instance.Namespace, Name: status.JobName}, job); err != nil {
+		if k8errors.IsNotFound(err) {
+			i.completeSmokeTest(cyndi.SmokeTestFailed, fmt.Sprintf("Job %s not found", status.JobName))
+			return nil
+		}
+
+		return err
+	}
+
+	switch {
+	case job.Status.Succeeded > 0:
+		i.completeSmokeTest(cyndi.SmokeTestSucceeded, i.smokeTestOutput(job))
+	case job.Status.Failed > 0 || isJobFailed(job):
+		i.completeSmokeTest(cyndi.SmokeTestFailed, i.smokeTestOutput(job))
+	}
+
+	return nil
+}
+
+func (i *ReconcileIteration) completeSmokeTest(result cyndi.SmokeTestResult, message string) {
+	now := metav1.Now()
+	status := i.Instance.Status.SmokeTest
+	status.Result = result
+	status.Message = message
+	status.CompletedAt = &now
+
+	if result == cyndi.SmokeTestSucceeded {
+		i.eventNormal("SmokeTestSucceeded", "Smoke test of %s succeeded", status.TableName)
+	} else {
+		i.eventWarning("SmokeTestFailed", "Smoke test of %s failed: %s", status.TableName, message)
+	}
+}
+
+func isJobFailed(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Output of the smoke test queries (i.e. logs of the Job's pod), truncated
+func (i *ReconcileIteration) smokeTestOutput(job *batchv1.Job) string {
+	if i.Clientset == nil {
+		return ""
+	}
+
+	pods, err := i.Clientset.CoreV1().Pods(job.Namespace).List(i.ctx, metav1.ListOptions{LabelSelector: "job-name=" + job.Name})
+	if err != nil || len(pods.Items) == 0 {
+		return ""
+	}
+
+	logs, err := i.Clientset.CoreV1().Pods(job.Namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{}).Do(i.ctx).Raw()
+	if err != nil {
+		i.Log.Error(err, "Failed to read smoke test output", "job", job.Name)
+		return ""
+	}
+
+	output := strings.TrimSpace(string(logs))
+	return output[:utils.Min(smokeTestMaxMessage, len(output))]
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/connect"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+Debezium source connector (spec.manageSourceConnector) publishing changes of the HBI database to the topic of the pipeline.
+Each pipeline version gets a source connector with a replication slot of its own next to its sink connector.
+The source connector is named after the sink connector so that both are kept and removed together (see deleteStaleDependencies).
+The replication slot of a removed source connector is dropped once the connector released it.
+With spec.manageSourceConnector.heartbeat, the source connector writes a row of the heartbeat table (created by the operator) in the HBI database.
+On low-traffic HBI databases this keeps the replication slot advancing, as changes of other tables would otherwise make the database retain WAL for the slot.
+
+*/
+
+// Returns the schema and the name of the heartbeat table
+func (i *ReconcileIteration) heartbeatTable() (string, string) {
+	parts := strings.SplitN(i.config.SourceHeartbeatTable, ".", 2)
+	return parts[0], parts[1]
+}
+
+func (i *ReconcileIteration) sourceSlotName(pipelineVersion string) string {
+	return fmt.Sprintf("%s_%s", i.config.SourceSlotNamePrefix, pipelineVersion)
+}
+
+// Whether the slot was created for one of the given connectors. The slot name ends with the pipeline version but does not depend on the prefix, which may have changed since
+func (i *ReconcileIteration) slotOfConnectors(slot string, connectors []string) bool {
+	parts := strings.Split(slot, "_")
+	for n := 1; n < len(parts); n++ {
+		if utils.ContainsString(connectors, cyndi.ConnectorName(strings.Join(parts[n:], "_"), i.Instance.Spec.AppName)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Creates the source connector of the current pipeline version unless it exists
+func (i *ReconcileIteration) reconcileSourceConnector() error {
+	if i.Instance.Spec.ManageSourceConnector == nil || i.Instance.Status.PipelineVersion == "" {
+		return nil
+	}
+
+	name := connect.SourceConnectorName(cyndi.ConnectorName(i.Instance.Status.PipelineVersion, i.Instance.Spec.AppName))
+	if exists, err := connect.CheckIfConnectorExists(i.ctx, i.Client, name, i.connectorNamespace()); err != nil || exists {
+		return err
+	}
+
+	if i.skipMutation("Not creating source connector", "connector", name) {
+		return nil
+	}
+
+	slot := i.sourceSlotName(i.Instance.Status.PipelineVersion)
+
+	heartbeatQuery := ""
+	if i.config.SourceHeartbeatTable != "" {
+		// the publication of the connector can only include an existing table
+		if err := i.createHeartbeatTable(); err != nil {
+			return err
+		}
+
+		schema, table := i.heartbeatTable()
+		heartbeatQuery = database.HeartbeatQuery(schema, table, slot)
+	}
+
+	// recorded first so that the slot is dropped eventually even if the status update gets lost
+	if !utils.ContainsString(i.Instance.Status.SourceReplicationSlots, slot) {
+		i.Instance.Status.SourceReplicationSlots = append(i.Instance.Status.SourceReplicationSlots, slot)
+	}
+
+	_, err := connect.CreateSourceConnector(i.ctx, i.Client, name, i.connectorNamespace(), connect.SourceConnectorConfiguration{
+		AppName:              i.Instance.Spec.AppName,
+		Cluster:              i.config.ConnectCluster,
+		Topic:                strings.Split(i.config.Topic, ",")[0],
+		SlotName:             slot,
+		TableIncludeList:     i.config.SourceTables,
+		HeartbeatActionQuery: heartbeatQuery,
+		HeartbeatTable:       i.config.SourceHeartbeatTable,
+		HeartbeatInterval:    i.config.SourceHeartbeatInterval,
+		DB:                   i.HBIDBParams[0],
+		Template:             i.config.SourceConnectorTemplate,
+		Class:                i.config.SourceConnectorClass,
+		EnvPrefix:            i.config.SourceDBEnvPrefix,
+		Labels:               i.config.ConnectorLabels,
+		Annotations:          i.config.ConnectorAnnotations,
+		OperatorVersion:      OperatorVersion,
+	}, i.Instance, i.Scheme)
+
+	if err != nil {
+		return err
+	}
+
+	i.eventNormal("SourceConnectorCreated", "Created source connector %s using replication slot %s", name, slot)
+	return nil
+}
+
+func (i *ReconcileIteration) createHeartbeatTable() error {
+	db := database.NewBaseDatabase(&i.HBIDBParams[0], i.Log)
+	db.SetContext(i.ctx)
+	if err := db.Connect(); err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	schema, table := i.heartbeatTable()
+	return database.CreateHeartbeatTable(db, schema, table)
+}
+
+/*
+ * Drops replication slots of source connectors other than the given ones.
+ * Slots still held by a connector that is being stopped remain in the status and are dropped by a later reconciliation.
+ */
+func (i *ReconcileIteration) dropStaleReplicationSlots(connectorsToKeep []string) error {
+	var stale, remaining []string
+	for _, slot := range i.Instance.Status.SourceReplicationSlots {
+		if i.slotOfConnectors(slot, connectorsToKeep) {
+			remaining = append(remaining, slot)
+		} else {
+			stale = append(stale, slot)
+		}
+	}
+
+	// the HBI database is not known if the setup failed
+	if len(stale) == 0 || len(i.HBIDBParams) == 0 || i.skipMutation("Not dropping replication slots", "slots", stale) {
+		return nil
+	}
+
+	db := database.NewBaseDatabase(&i.HBIDBParams[0], i.Log)
+	db.SetContext(i.ctx)
+	if err := db.Connect(); err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	var inUse []string
+	defer func() {
+		i.Instance.Status.SourceReplicationSlots = append(remaining, inUse...)
+	}()
+
+	for idx, slot := range stale {
+		dropped, err := database.DropReplicationSlot(db, slot)
+		if err != nil {
+			inUse = append(inUse, stale[idx:]...)
+			return err
+		} else if !dropped {
+			i.debug("Replication slot still in use", "slot", slot)
+			inUse = append(inUse, slot)
+			continue
+		}
+
+		i.Log.Info("Dropped replication slot", "slot", slot)
+		metrics.ReplicationSlotDropped(i.Instance, slot)
+
+		if i.config.SourceHeartbeatTable != "" {
+			schema, table := i.heartbeatTable()
+			if err = database.DeleteHeartbeat(db, schema, table, slot); err != nil {
+				// not fatal - the row of the slot is merely left behind
+				i.Log.Error(err, "Failed to remove heartbeat of replication slot", "slot", slot)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/*
+
+Clones of pipelines (spec.sourcePipeline). A clone replicates the topics of its source pipeline into the same application database,
+however into tables of its own exposed through a view other than inventory.hosts. Teams can therefore try out a new connector template,
+column set or index against production data while the application keeps reading the view of the source pipeline.
+Settings selecting the data and where it is written to are inherited from the source pipeline unless the clone sets them.
+The tables of the clone are owned by it (see ownership.go) so neither pipeline removes tables of the other.
+
+*/
+
+// names of views of clones must not be mistaken for the tables of pipelines (hosts_*)
+const cloneViewSuffix = "_hosts"
+
+var viewNameReplacer = strings.NewReplacer("-", "_", ".", "_")
+
+// Returns the view the pipeline exposes its hosts through
+func viewName(pipeline *cyndi.CyndiPipeline) string {
+	source := pipeline.Spec.SourcePipeline
+	if source == nil {
+		return database.DefaultView
+	}
+
+	if source.ViewName != nil {
+		return *source.ViewName
+	}
+
+	// PostgreSQL truncates identifiers longer than 63 characters
+	name := viewNameReplacer.Replace(pipeline.Name)
+	if maxLength := 63 - len(cloneViewSuffix); len(name) > maxLength {
+		name = name[:maxLength]
+	}
+
+	return name + cloneViewSuffix
+}
+
+func (i *ReconcileIteration) viewName() string {
+	return viewName(i.Instance)
+}
+
+func validateSourcePipeline(pipeline *cyndi.CyndiPipeline) error {
+	source := pipeline.Spec.SourcePipeline
+	if source == nil {
+		return nil
+	}
+
+	if source.Name == pipeline.Name {
+		return fmt.Errorf("A pipeline cannot be a clone of itself (sourcePipeline)")
+	}
+
+	if view := viewName(pipeline); view == database.DefaultView || strings.HasPrefix(view, "hosts_") {
+		return fmt.Errorf("%s cannot be used as the view of a clone (sourcePipeline.viewName)", view)
+	}
+
+	return nil
+}
+
+// Fills in the settings the clone does not set with those of the source pipeline. The spec is not persisted
+func (i *ReconcileIteration) inheritSourcePipeline() error {
+	if i.Instance.Spec.SourcePipeline == nil {
+		return nil
+	}
+
+	if err := validateSourcePipeline(i.Instance); err != nil {
+		return err
+	}
+
+	name := types.NamespacedName{Namespace: i.Instance.Namespace, Name: i.Instance.Spec.SourcePipeline.Name}
+	source, err := utils.FetchCyndiPipeline(i.Client, name)
+	if k8errors.IsNotFound(err) {
+		return fmt.Errorf("Source pipeline %s does not exist", name)
+	} else if err != nil {
+		return err
+	}
+
+	if source.Spec.SourcePipeline != nil {
+		return fmt.Errorf("Source pipeline %s is a clone itself", name)
+	}
+
+	spec := &i.Instance.Spec
+	if spec.DbSecret == nil {
+		dbSecret := utils.AppDbSecretName(source.Spec)
+		spec.DbSecret = &dbSecret
+	}
+
+	if spec.DBDialect == nil {
+		spec.DBDialect = source.Spec.DBDialect
+	}
+
+	if spec.DBAuth == nil {
+		spec.DBAuth = source.Spec.DBAuth
+	}
+
+	if spec.Topic == nil && len(spec.Topics) == 0 {
+		spec.Topic = source.Spec.Topic
+		spec.Topics = source.Spec.Topics
+	}
+
+	if spec.ConnectCluster == nil {
+		spec.ConnectCluster = source.Spec.ConnectCluster
+	}
+
+	if spec.InventoryDbSecret == nil && len(spec.InventoryDbSecrets) == 0 {
+		spec.InventoryDbSecret = source.Spec.InventoryDbSecret
+		spec.InventoryDbSecrets = source.Spec.InventoryDbSecrets
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+Computed staleness column of the inventory.hosts view (spec.viewStaleness).
+The column is part of the view definition so every new table gets it once the view is switched to it.
+Changes of spec.viewStaleness are applied to the view of the active table right away instead of triggering a refresh.
+
+*/
+
+// Re-creates the view if spec.viewStaleness changed since it was last applied
+func (i *ReconcileIteration) reconcileViewStaleness() error {
+	hash := ""
+	if i.Instance.Spec.ViewStaleness != nil {
+		var err error
+		if hash, err = utils.SpecHash(i.Instance.Spec.ViewStaleness); err != nil {
+			return err
+		}
+	}
+
+	if hash == i.Instance.Status.ViewStalenessHash {
+		return nil
+	}
+
+	// the view is created with the column once a table becomes active
+	if i.Instance.Status.ActiveTableName == "" {
+		i.Instance.Status.ViewStalenessHash = hash
+		return nil
+	}
+
+	if i.skipMutation("Not updating the staleness column") {
+		return nil
+	}
+
+	i.Log.Info("Updating the staleness column of the view", "table", i.Instance.Status.ActiveTableName)
+
+	// columns cannot be renamed or removed by replacing the view so it is re-created, dropping grants of the view
+	if err := i.AppDb.ReplaceView(i.Instance.Status.ActiveTableName); err != nil {
+		return err
+	}
+
+	if err := i.applyDBGrants(i.viewName()); err != nil {
+		return err
+	}
+
+	i.Instance.Status.ViewStalenessHash = hash
+	return nil
+}
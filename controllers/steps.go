@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+The reconciliation of a pipeline is split into steps:
+* Secrets - the configuration and the database credentials are resolved and the application database is connected to
+* Table - the application database is bootstrapped, tables are created and grants applied
+* Connector - the sink connector (and the source connector) are created, re-created if missing and their metadata updated
+* View - the inventory.hosts view is pointed to a valid table and its staleness column updated
+* Status - the status of the pipeline is written
+
+Each step reports its outcome using its own condition (e.g. ConnectorReady) and metrics.
+A failing step does not prevent the steps independent of it from running so that one failure does not mask the state of the rest.
+Steps depending on a failed step are not run and their condition becomes Unknown.
+The Status step is only reported using metrics as a failed status update cannot be recorded in the status.
+
+*/
+
+const (
+	reasonStepSucceeded = "Succeeded"
+	reasonStepFailed    = "Failed"
+	reasonStepBlocked   = "Blocked"
+)
+
+// steps that need to succeed for a step to be run
+var stepDependencies = map[cyndi.ReconcileStep][]cyndi.ReconcileStep{
+	cyndi.STEP_TABLE:     {cyndi.STEP_SECRETS},
+	cyndi.STEP_CONNECTOR: {cyndi.STEP_SECRETS},
+	cyndi.STEP_VIEW:      {cyndi.STEP_SECRETS, cyndi.STEP_TABLE},
+}
+
+// Runs a step of the reconciliation unless a step it depends on failed in this iteration
+func (i *ReconcileIteration) runStep(step cyndi.ReconcileStep, fn func() error) error {
+	for _, dependency := range stepDependencies[step] {
+		if _, failed := i.failedSteps[dependency]; failed {
+			return nil
+		}
+	}
+
+	start := time.Now()
+	err := fn()
+	i.recordStep(step, time.Since(start), err)
+	return err
+}
+
+func (i *ReconcileIteration) recordStep(step cyndi.ReconcileStep, duration time.Duration, err error) {
+	metrics.ReconcileStep(i.Instance, step, duration, err != nil)
+
+	if step == cyndi.STEP_STATUS {
+		return
+	}
+
+	if err == nil {
+		delete(i.failedSteps, step)
+		i.Instance.SetStepReady(step, metav1.ConditionTrue, reasonStepSucceeded, "")
+		return
+	}
+
+	if i.failedSteps == nil {
+		i.failedSteps = map[cyndi.ReconcileStep]error{}
+	}
+
+	i.failedSteps[step] = err
+	i.Instance.SetStepReady(step, metav1.ConditionFalse, reasonStepFailed, err.Error())
+
+	for dependent, dependencies := range stepDependencies {
+		for _, dependency := range dependencies {
+			if dependency == step {
+				i.Instance.SetStepReady(dependent, metav1.ConditionUnknown, reasonStepBlocked, fmt.Sprintf("The %s step failed", step))
+			}
+		}
+	}
+}
+
+// Returns the error of the first step that failed in this iteration (if any)
+func (i *ReconcileIteration) stepError() error {
+	for _, step := range cyndi.ReconcileSteps {
+		if err, failed := i.failedSteps[step]; failed {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Copies the outcome of the steps to the given copy of the pipeline (e.g. one fetched to record a failure)
+func (i *ReconcileIteration) copyStepConditions(instance *cyndi.CyndiPipeline) {
+	for _, step := range cyndi.ReconcileSteps {
+		if condition := i.Instance.GetStepReady(step); condition != nil {
+			meta.SetStatusCondition(&instance.Status.Conditions, *condition)
+		}
+	}
+}
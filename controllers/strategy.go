@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+)
+
+/*
+
+Validation strategies (validation.strategy or spec.validationStrategy). Once the host counts of HBI and of the application table are known
+and close enough, the strategy of the pipeline determines which hosts are found in only one of them. validate() evaluates the result
+(thresholds, lingering hosts) the same way regardless of the strategy, so a new strategy only needs to implement validationStrategy
+and be registered in validationStrategies (and listed in config.ValidationStrategy).
+
+*/
+
+type validationStrategy interface {
+	// Compares the hosts of HBI and of the application table. The host counts have been determined already
+	compare(i *ReconcileIteration, appTable string, hbiHostCount int64, appHostCount int64) (idComparison, error)
+}
+
+var validationStrategies = map[config.ValidationStrategy]validationStrategy{
+	config.ValidationStrategyIds:       idValidation{},
+	config.ValidationStrategyBlockHash: blockHashValidation{},
+	config.ValidationStrategyCounts:    countValidation{},
+	config.ValidationStrategySampled:   sampledValidation{},
+}
+
+func (i *ReconcileIteration) validationStrategy() (validationStrategy, error) {
+	strategy, ok := validationStrategies[i.config.ValidationStrategy]
+	if !ok {
+		return nil, fmt.Errorf("Unknown validation strategy %s", i.config.ValidationStrategy)
+	}
+
+	return strategy, nil
+}
+
+// All host ids are streamed from both databases (see idstream.go)
+type idValidation struct{}
+
+func (idValidation) compare(i *ReconcileIteration, appTable string, hbiHostCount int64, appHostCount int64) (idComparison, error) {
+	return i.compareHostIds(appTable)
+}
+
+// Only ids of blocks whose digests differ are fetched (see blockhash.go)
+type blockHashValidation struct{}
+
+func (blockHashValidation) compare(i *ReconcileIteration, appTable string, hbiHostCount int64, appHostCount int64) (idComparison, error) {
+	source, ok := i.Inventory.(database.BlockHashSource)
+	if !ok {
+		return i.compareHostIds(appTable)
+	}
+
+	stats := &blockComparison{}
+	result := i.newIdComparison()
+	result.hbiCount = hbiHostCount
+
+	if err := i.compareIdBlocks(source, appTable, "", stats, &result); err != nil {
+		return result, err
+	}
+
+	result.overflow.flush()
+	i.Log.Info("Compared id blocks", "blocks", stats.blocksCompared, "idsFetched", stats.idsFetched, "truncated", result.truncated())
+	return result, nil
+}
+
+// The difference of the host counts is taken for the mismatch. Hosts missing in one database and extra in the other cancel out
+type countValidation struct{}
+
+func (countValidation) compare(i *ReconcileIteration, appTable string, hbiHostCount int64, appHostCount int64) (idComparison, error) {
+	result := idComparison{hbiCount: hbiHostCount, countsOnly: true}
+	if hbiHostCount > appHostCount {
+		result.inHbiOnlyCount = hbiHostCount - appHostCount
+	} else {
+		result.inAppOnlyCount = appHostCount - hbiHostCount
+	}
+
+	return result, nil
+}
+
+// number of blocks (two leading hex digits of the ids) the ids are sampled from
+const sampleBlocks = 256
+
+// Ids of randomly chosen blocks are compared, mismatches are extrapolated to the whole table
+type sampledValidation struct{}
+
+func (sampledValidation) compare(i *ReconcileIteration, appTable string, hbiHostCount int64, appHostCount int64) (idComparison, error) {
+	size := int(i.config.ValidationSamplePercentage * sampleBlocks / 100)
+	if size < 1 {
+		size = 1
+	}
+
+	result := i.newIdComparison()
+	stats := &blockComparison{}
+
+	for _, block := range rand.Perm(sampleBlocks)[:size] {
+		if err := i.compareBlockIds(appTable, fmt.Sprintf("%02x", block), stats, &result); err != nil {
+			return result, err
+		}
+	}
+
+	result.overflow.flush()
+	result.hbiCount = hbiHostCount
+	result.inHbiOnlyCount = result.inHbiOnlyCount * sampleBlocks / int64(size)
+	result.inAppOnlyCount = result.inAppOnlyCount * sampleBlocks / int64(size)
+
+	i.Log.Info("Compared a sample of host ids", "blocks", size, "idsFetched", stats.idsFetched)
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/faults"
+)
+
+/*
+
+Lifecycle of the table backing a pipeline version.
+The table is created without indexes so that the initial load is not slowed down by index maintenance.
+Indexes are created and statistics are collected once the table is about to back the hosts view.
+
+*/
+
+func (i *ReconcileIteration) createTable(name string) (err error) {
+	i.Instance.Status.UnloggedTableServerStart = nil
+	i.Instance.Status.TableIndexesPending = true
+
+	if i.config.DBDisplayNameSearch == config.DisplayNameSearchCitext {
+		if err = i.AppDb.EnsureExtension("citext"); err != nil {
+			return err
+		}
+	}
+
+	if partitioning := i.Instance.Spec.DBTablePartitioning; partitioning != nil {
+		err = i.AppDb.CreatePartitionedTable(name, i.config.DBTableInitScript, partitioning.Partitions)
+	} else {
+		err = i.AppDb.CreateTable(name, i.config.DBTableInitScript)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = faults.Check(faults.TableCreated); err != nil {
+		return err
+	}
+
+	return i.setUpTable(name)
+}
+
+// Records the pipeline as the owner of a newly created table and applies the settings of the pipeline (storage parameters, compression, grants, UNLOGGED) to it. Can be repeated safely
+func (i *ReconcileIteration) setUpTable(name string) (err error) {
+	if err = i.AppDb.RegisterTable(name, i.pipelineName()); err != nil {
+		return err
+	}
+
+	if err = i.checkPrimaryKey(name); err != nil {
+		return err
+	}
+
+	if err = i.AppDb.SetStorageParameters(name, i.config.DBTableStorageParameters); err != nil {
+		return err
+	}
+
+	if i.config.DBTableCompression != "" {
+		if err = i.AppDb.SetJSONCompression(name, i.config.DBTableCompression); err != nil {
+			return err
+		}
+	}
+
+	if err = i.applyDBGrants(name); err != nil {
+		return err
+	}
+
+	if !i.config.DBTableUnlogged {
+		return nil
+	}
+
+	return i.setTableUnlogged(name)
+}
+
+// The connector upserts (and deletes) rows identified by pk.fields so these need to form the primary key of the table
+func (i *ReconcileIteration) checkPrimaryKey(name string) error {
+	primaryKey, err := i.AppDb.GetPrimaryKey(name)
+	if err != nil {
+		return err
+	}
+
+	expected := append([]string{}, i.config.ConnectorPKFields...)
+	sort.Strings(expected)
+
+	if strings.Join(primaryKey, ",") != strings.Join(expected, ",") {
+		return fmt.Errorf("Primary key of table %s (%s) does not match pk.fields (%s)", name, strings.Join(primaryKey, ","), strings.Join(expected, ","))
+	}
+
+	return nil
+}
+
+// Must be called before the hosts view is pointed to the table
+func (i *ReconcileIteration) prepareTableForView() error {
+	table := i.Instance.Status.TableName
+
+	if i.Instance.Status.TableIndexesPending {
+		i.Log.Info("Creating indexes", "table", table)
+
+		if err := i.AppDb.CreateIndexes(table, i.config.DBTableIndexSQL); err != nil {
+			return err
+		}
+
+		i.Instance.Status.TableIndexesPending = false
+	}
+
+	if err := i.setTableLogged(); err != nil {
+		return err
+	}
+
+	// the first queries against the table should not run with empty statistics
+	return i.AppDb.AnalyzeTable(table)
+}
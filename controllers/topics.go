@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+Topic preflight check (topic.preflight.enabled). A connector subscribed to a topic that does not exist does not fail - it consumes nothing
+and the pipeline only fails validation hours later. With the check enabled, a new pipeline version is not started until the topics exist.
+The topics are looked up in the KafkaTopic resources of the Kafka cluster of the Connect cluster (see connect/topic.go). Their partition counts
+and retention are recorded in status.topics. The check is skipped if the Kafka cluster or its topics cannot be determined this way.
+Topics not managed by the Strimzi Topic Operator (i.e. without a KafkaTopic resource) are reported as missing - the Kafka cluster itself is never queried.
+A topic with fewer partitions than connector tasks does not block the pipeline but is reported by the TopicReady condition as the excess tasks stay idle.
+
+*/
+
+const (
+	reasonTopicsFound           = "TopicsFound"
+	reasonTopicNotFound         = "TopicNotFound"
+	reasonTopicUnderPartitioned = "TopicUnderPartitioned"
+)
+
+// Returns false if a topic of the pipeline does not exist
+func (i *ReconcileIteration) checkTopics() (bool, error) {
+	if !i.config.TopicPreflightEnabled {
+		i.Instance.ResetTopicReady()
+		i.Instance.Status.Topics = nil
+		return true, nil
+	}
+
+	// a missing Connect cluster is left to the connector checks
+	cluster, err := connect.GetConnectCluster(i.ctx, i.Client, i.config.ConnectCluster, i.connectorNamespace())
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	kafkaCluster, kafkaNamespace, err := connect.KafkaClusterOf(cluster)
+	if err != nil {
+		// not fatal - the topics cannot be looked up
+		i.Log.Info("Skipping topic preflight check", "reason", err.Error())
+		i.Instance.ResetTopicReady()
+		return true, nil
+	}
+
+	topics, err := connect.GetTopics(i.ctx, i.Client, kafkaCluster, kafkaNamespace)
+	if meta.IsNoMatchError(err) {
+		i.Log.Info("Skipping topic preflight check as the KafkaTopic resource is not available")
+		i.Instance.ResetTopicReady()
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var statuses []cyndi.TopicStatus
+	var missing []string
+	var underPartitioned []string
+
+	for _, name := range strings.Split(i.config.Topic, ",") {
+		topic, ok := topics[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+
+		statuses = append(statuses, cyndi.TopicStatus{Name: topic.Name, Partitions: topic.Partitions, RetentionMs: topic.RetentionMs})
+
+		if topic.Partitions > 0 && topic.Partitions < i.config.ConnectorTasksMax {
+			underPartitioned = append(underPartitioned, fmt.Sprintf("%s (%d partitions)", name, topic.Partitions))
+		}
+	}
+
+	i.Instance.Status.Topics = statuses
+
+	if len(missing) > 0 {
+		message := fmt.Sprintf("Topic %s not found in Kafka cluster %s/%s", strings.Join(missing, ", "), kafkaNamespace, kafkaCluster)
+
+		if previous := i.Instance.GetTopicReady(); previous == nil || previous.Status != metav1.ConditionFalse {
+			i.eventWarning(reasonTopicNotFound, "%s. Not starting the connector", message)
+		}
+
+		i.Instance.SetTopicReady(metav1.ConditionFalse, reasonTopicNotFound, message)
+		return false, nil
+	}
+
+	message := fmt.Sprintf("%d topics found in Kafka cluster %s/%s", len(statuses), kafkaNamespace, kafkaCluster)
+
+	if len(underPartitioned) > 0 {
+		message = fmt.Sprintf("%s. Topic %s has fewer partitions than connector tasks (%d)", message, strings.Join(underPartitioned, ", "), i.config.ConnectorTasksMax)
+
+		if previous := i.Instance.GetTopicReady(); previous == nil || previous.Reason != reasonTopicUnderPartitioned {
+			i.eventWarning(reasonTopicUnderPartitioned, "%s - the excess connector tasks stay idle", message)
+		}
+
+		i.Instance.SetTopicReady(metav1.ConditionTrue, reasonTopicUnderPartitioned, message)
+		return true, nil
+	}
+
+	i.Instance.SetTopicReady(metav1.ConditionTrue, reasonTopicsFound, message)
+	return true, nil
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func newKafkaTopic(name string, partitions int64) client.Object {
+	topic := &unstructured.Unstructured{}
+	topic.SetAPIVersion("kafka.strimzi.io/v1beta2")
+	topic.SetKind("KafkaTopic")
+	topic.SetName(name)
+	topic.SetNamespace("platform-mq")
+	topic.SetLabels(map[string]string{"strimzi.io/cluster": "platform-mq"})
+	_ = unstructured.SetNestedField(topic.Object, partitions, "spec", "partitions")
+	return topic
+}
+
+var _ = Describe("Topic preflight check", func() {
+	// Runs the check against a Connect cluster of the Strimzi Kafka cluster platform-mq/platform-mq holding the given topics
+	checkTopics := func(instance *cyndi.CyndiPipeline, recorder *record.FakeRecorder, topics ...client.Object) bool {
+		connectCluster := &unstructured.Unstructured{}
+		connectCluster.SetAPIVersion("kafka.strimzi.io/v1beta2")
+		connectCluster.SetKind("KafkaConnect")
+		connectCluster.SetName("connect")
+		connectCluster.SetNamespace("test")
+		_ = unstructured.SetNestedField(connectCluster.Object, "platform-mq-kafka-bootstrap.platform-mq.svc:9092", "spec", "bootstrapServers")
+
+		// Strimzi resources are handled as unstructured objects
+		scheme := runtime.NewScheme()
+		scheme.AddKnownTypeWithName(schema.GroupVersionKind{Group: "kafka.strimzi.io", Version: "v1beta2", Kind: "KafkaTopicList"}, &unstructured.UnstructuredList{})
+
+		i := ReconcileIteration{
+			Instance: instance,
+			ctx:      context.TODO(),
+			Recorder: recorder,
+			Log:      logf.Log.WithName("test"),
+			Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(topics, connectCluster)...).Build(),
+			config: &config.CyndiConfiguration{
+				TopicPreflightEnabled: true,
+				ConnectCluster:        "connect",
+				Topic:                 "platform.inventory.events",
+				ConnectorTasksMax:     16,
+			},
+		}
+
+		ok, err := i.checkTopics()
+		Expect(err).ToNot(HaveOccurred())
+		return ok
+	}
+
+	var (
+		instance *cyndi.CyndiPipeline
+		recorder *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		instance = &cyndi.CyndiPipeline{ObjectMeta: metav1.ObjectMeta{Name: "advisor", Namespace: "test"}}
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	It("Reports the topics found", func() {
+		Expect(checkTopics(instance, recorder, newKafkaTopic("platform.inventory.events", 32))).To(BeTrue())
+		Expect(instance.GetTopicReady().Status).To(Equal(metav1.ConditionTrue))
+		Expect(instance.GetTopicReady().Reason).To(Equal(reasonTopicsFound))
+		Expect(instance.Status.Topics).To(Equal([]cyndi.TopicStatus{{Name: "platform.inventory.events", Partitions: 32}}))
+		Expect(recordedEvents(recorder)).To(BeEmpty())
+	})
+
+	It("Holds the pipeline back while a topic is missing", func() {
+		Expect(checkTopics(instance, recorder, newKafkaTopic("platform.inventory.other", 32))).To(BeFalse())
+		Expect(instance.GetTopicReady().Status).To(Equal(metav1.ConditionFalse))
+		Expect(instance.GetTopicReady().Reason).To(Equal(reasonTopicNotFound))
+		Expect(recordedEvents(recorder)).To(ConsistOf(HavePrefix("Warning TopicNotFound")))
+	})
+
+	It("Reports a topic with fewer partitions than connector tasks", func() {
+		Expect(checkTopics(instance, recorder, newKafkaTopic("platform.inventory.events", 4))).To(BeTrue())
+		Expect(instance.GetTopicReady().Status).To(Equal(metav1.ConditionTrue))
+		Expect(instance.GetTopicReady().Reason).To(Equal(reasonTopicUnderPartitioned))
+		Expect(instance.GetTopicReady().Message).To(ContainSubstring("platform.inventory.events (4 partitions)"))
+		Expect(recordedEvents(recorder)).To(ConsistOf(HavePrefix("Warning TopicUnderPartitioned")))
+
+		// reported once
+		Expect(checkTopics(instance, recorder, newKafkaTopic("platform.inventory.events", 4))).To(BeTrue())
+		Expect(instance.GetTopicReady().Reason).To(Equal(reasonTopicUnderPartitioned))
+		Expect(recordedEvents(recorder)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "github.com/RedHatInsights/cyndi-operator"
+	serviceName = "cyndi-operator"
+)
+
+// Environment variables that enable the OTLP exporter. The exporter itself is configured
+// using the standard OTEL_EXPORTER_OTLP_* variables.
+var endpointEnvVars = []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"}
+
+/*
+ * Sets up the global tracer provider if an OTLP endpoint is configured.
+ * Otherwise, spans are not recorded.
+ * The returned function flushes pending spans and should be called before the process exits.
+ */
+func Init(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	shutdown = func(context.Context) error { return nil }
+
+	if !isEnabled() {
+		return shutdown, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return shutdown, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return shutdown, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func isEnabled() bool {
+	for _, key := range endpointEnvVars {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Starts a new span as a child of the span stored in ctx (if any)
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// Ends the span, recording the given error (if any)
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+
+UNLOGGED tables speed up the initial sync considerably as no WAL is written for them.
+However, PostgreSQL truncates UNLOGGED tables during crash recovery. Therefore, the initial sync is restarted if the database server has restarted
+since the table was created. The table is converted to LOGGED before the hosts view is pointed to it.
+
+*/
+
+func (i *ReconcileIteration) setTableUnlogged(table string) error {
+	if err := i.AppDb.SetTableUnlogged(table); err != nil {
+		return err
+	}
+
+	start, err := i.AppDb.GetServerStartTime()
+	if err != nil {
+		return err
+	}
+
+	serverStart := metav1.NewTime(start)
+	i.Instance.Status.UnloggedTableServerStart = &serverStart
+	return nil
+}
+
+// Returns a problem if the database server restarted since the UNLOGGED table was created (i.e. its data may have been lost)
+func (i *ReconcileIteration) checkUnloggedTable() (problem error, err error) {
+	recorded := i.Instance.Status.UnloggedTableServerStart
+	if recorded == nil {
+		return nil, nil
+	}
+
+	start, err := i.AppDb.GetServerStartTime()
+	if err != nil {
+		return nil, err
+	}
+
+	// the status only keeps second precision
+	if start.Unix() != recorded.Unix() {
+		return fmt.Errorf("Application database restarted at %s while table %s was UNLOGGED", start.Format(time.RFC3339), i.Instance.Status.TableName), nil
+	}
+
+	return nil, nil
+}
+
+// Converts the table being seeded to LOGGED. Must be called before the hosts view is pointed to the table.
+func (i *ReconcileIteration) setTableLogged() error {
+	if i.Instance.Status.UnloggedTableServerStart == nil {
+		return nil
+	}
+
+	if problem, err := i.checkUnloggedTable(); err != nil {
+		return err
+	} else if problem != nil {
+		// the next reconcile detects the problem as a state deviation and refreshes the pipeline
+		return problem
+	}
+
+	if err := i.AppDb.SetTableLogged(i.Instance.Status.TableName); err != nil {
+		return err
+	}
+
+	i.Instance.Status.UnloggedTableServerStart = nil
+	i.eventNormal("TableLogged", "Table %s converted to LOGGED", i.Instance.Status.TableName)
+	return nil
+}
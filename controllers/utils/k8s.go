@@ -37,6 +37,12 @@ func FetchCyndiPipeline(c client.Client, namespacedName types.NamespacedName) (*
 	return instance, err
 }
 
+func FetchCyndiConfig(c client.Client, name string) (*cyndi.CyndiConfig, error) {
+	config := &cyndi.CyndiConfig{}
+	err := c.Get(context.TODO(), client.ObjectKey{Name: name}, config)
+	return config, err
+}
+
 func FetchCyndiPipelines(c client.Client, namespace string) (*cyndi.CyndiPipelineList, error) {
 	list := &cyndi.CyndiPipelineList{}
 	err := c.List(context.TODO(), list)
@@ -1,6 +1,9 @@
 package utils
 
-import "reflect"
+import (
+	"math"
+	"reflect"
+)
 
 /*
 
@@ -77,6 +80,25 @@ func Min(x, y int) int {
 	return y
 }
 
+// Returns the mean and the (population) standard deviation of the given values
+func MeanStdDev(values []int64) (mean float64, stddev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	for _, value := range values {
+		mean += float64(value)
+	}
+
+	mean /= float64(len(values))
+
+	for _, value := range values {
+		stddev += math.Pow(float64(value)-mean, 2)
+	}
+
+	return mean, math.Sqrt(stddev / float64(len(values)))
+}
+
 func IsNumber(x interface{}) bool {
 	kind := reflect.TypeOf(x).Kind()
 	return kind >= 2 && kind <= 16
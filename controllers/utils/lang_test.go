@@ -26,6 +26,20 @@ var _ = Describe("Lang", func() {
 		})
 	})
 
+	Describe("MeanStdDev", func() {
+		It("Computes the mean and standard deviation", func() {
+			mean, stddev := MeanStdDev([]int64{2, 4, 4, 4, 5, 5, 7, 9})
+			Expect(mean).To(Equal(5.0))
+			Expect(stddev).To(Equal(2.0))
+		})
+
+		It("Returns zeros for no values", func() {
+			mean, stddev := MeanStdDev(nil)
+			Expect(mean).To(BeZero())
+			Expect(stddev).To(BeZero())
+		})
+	})
+
 	Describe("Omit", func() {
 		It("Leaves out given keys", func() {
 			value := make(map[string]string)
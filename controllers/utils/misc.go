@@ -6,12 +6,6 @@ import (
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
 )
 
-const inventorySchema = "inventory"
-
-func AppFullTableName(tableName string) string {
-	return fmt.Sprintf("%s.%s", inventorySchema, tableName)
-}
-
 func AppDefaultDbSecretName(appName string) string {
 	return fmt.Sprintf("%s-db", appName)
 }
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+/*
+
+Readable names of the tables (and therefore connectors) of pipeline versions, rendered from the naming template (naming.template).
+The rendered name is turned into a plain PostgreSQL identifier: lower-cased, other characters replaced with underscores, prefixed with hosts_ and capped at 63 characters.
+
+*/
+
+const (
+	tableNamePrefix     = "hosts_"
+	maxTableNameLength  = 63
+	tableNameHashLength = 8
+)
+
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Values available to the naming template
+type NamingData struct {
+	PipelineName string
+	AppName      string
+	Namespace    string
+	// number of pipeline versions created for the pipeline, starting at 1
+	Generation int64
+}
+
+func ParseNamingTemplate(text string) (*template.Template, error) {
+	return template.New("naming").Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(text)
+}
+
+// Renders and sanitizes the name of the table of a pipeline version
+func RenderTableName(text string, data NamingData) (string, error) {
+	tmpl, err := ParseNamingTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	if err = tmpl.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+
+	return SanitizeTableName(buffer.String()), nil
+}
+
+// Values available to the consumer group template (connector.consumer.group.template)
+type ConsumerGroupData struct {
+	PipelineName string
+	AppName      string
+	Namespace    string
+	// pipeline version the connector consumes for
+	PipelineVersion string
+	ConnectorName   string
+}
+
+// Renders the consumer group of the connector of a pipeline version. Unlike table names, consumer groups are not sanitized
+func RenderConsumerGroup(text string, data ConsumerGroupData) (string, error) {
+	tmpl, err := ParseNamingTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	if err = tmpl.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSpace(buffer.String())
+	if name == "" {
+		return "", fmt.Errorf("empty consumer group rendered")
+	}
+
+	return name, nil
+}
+
+func SanitizeTableName(name string) string {
+	name = invalidNameCharacters.ReplaceAllString(strings.ToLower(name), "_")
+	name = strings.Trim(name, "_")
+	if name == "hosts" {
+		name = ""
+	}
+
+	name = strings.TrimLeft(strings.TrimPrefix(name, tableNamePrefix), "_")
+
+	// names starting with a digit are reserved for the default naming (hosts_v1_<timestamp>)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "v" + name
+	}
+
+	name = tableNamePrefix + name
+
+	// truncated names keep a hash of the full name so that they remain unique
+	if len(name) > maxTableNameLength {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:tableNameHashLength]
+		name = strings.TrimRight(name[:maxTableNameLength-tableNameHashLength-1], "_") + "_" + hash
+	}
+
+	return name
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Naming", func() {
+	data := NamingData{PipelineName: "advisor", AppName: "advisor", Namespace: "advisor-prod", Generation: 3}
+
+	It("Renders the table name", func() {
+		name, err := RenderTableName("hosts_{{.PipelineName}}_{{.Generation}}", data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("hosts_advisor_3"))
+	})
+
+	It("Renders sprig functions", func() {
+		name, err := RenderTableName(`{{ .Namespace | replace "-prod" "" }}_{{.Generation}}`, data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("hosts_advisor_3"))
+	})
+
+	It("Fails on unknown fields", func() {
+		_, err := RenderTableName("hosts_{{.Version}}", data)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Renders the consumer group", func() {
+		group, err := RenderConsumerGroup("cyndi-{{.Namespace}}-{{.PipelineVersion}}", ConsumerGroupData{Namespace: "advisor-prod", PipelineVersion: "advisor_3"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(group).To(Equal("cyndi-advisor-prod-advisor_3"))
+
+		_, err = RenderConsumerGroup(`{{ "" }}`, ConsumerGroupData{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("Sanitizes table names",
+		func(name string, expected string) {
+			Expect(SanitizeTableName(name)).To(Equal(expected))
+		},
+		Entry("valid name", "hosts_advisor_3", "hosts_advisor_3"),
+		Entry("prefix", "advisor_3", "hosts_advisor_3"),
+		Entry("case and characters", "Hosts-Advisor.Prod 3", "hosts_advisor_prod_3"),
+		Entry("leading digit", "3_advisor", "hosts_v3_advisor"),
+		Entry("empty", "", "hosts_v"),
+	)
+
+	It("Truncates long table names keeping them unique", func() {
+		long := "hosts_a_very_long_pipeline_name_that_does_not_fit_into_a_postgres_identifier_"
+		name1, name2 := SanitizeTableName(long+"1"), SanitizeTableName(long+"2")
+
+		Expect(name1).To(HaveLen(63))
+		Expect(name1).To(HavePrefix("hosts_a_very_long_pipeline_name_that_does_not_fit_into_"))
+		Expect(name1).ToNot(Equal(name2))
+	})
+
+	DescribeTable("Maps table names to pipeline versions and connectors",
+		func(table string, version string, connector string) {
+			Expect(cyndi.TableNameToPipelineVersion(table)).To(Equal(version))
+			Expect(cyndi.TableName(version)).To(Equal(table))
+			Expect(cyndi.TableNameToConnectorName(table, "advisor")).To(Equal(connector))
+		},
+		Entry("default naming", "hosts_v1_1612345678", "1_1612345678", "cyndi-advisor-1-1612345678"),
+		Entry("naming template", "hosts_advisor_3", "advisor_3", "cyndi-advisor-3"),
+		Entry("naming template with leading digit", "hosts_v3_advisor", "3_advisor", "cyndi-advisor-3-advisor"),
+	)
+})
@@ -3,69 +3,206 @@ package controllers
 import (
 	"math"
 
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
 	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
 	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
 )
 
-const inventoryTableName = "public.hosts" // TODO: move
+var inventoryTableName = database.QualifiedTable("public", "hosts")
+
 const countMismatchThreshold = 0.5
 const idDiffMaxLength = 51
 
-func (i *ReconcileIteration) validate() (isValid bool, mismatchRatio float64, mismatchCount int64, hostCount int64, err error) {
-	appTable := utils.AppFullTableName(i.Instance.Status.TableName)
+type validationResult struct {
+	isValid       bool
+	mismatchRatio float64
+	mismatchCount int64
+	hostCount     int64
+
+	// populated only if host ids have been compared
+	inHbiOnly []string
+	inAppOnly []string
+	// counts and a sample of the ids above (validation.mismatched.ids.sample)
+	mismatchedHosts *cyndi.MismatchedHostsStatus
+
+	// hosts deleted in HBI that linger in the application table; -1 if host ids have not been compared
+	lingeringCount    int64
+	lingeringExceeded bool
+
+	// only host counts have been compared (outside of validation windows). The pipeline may still be invalid if valid
+	countOnly bool
+
+	// populated only if host counts per organization have been compared (validation.org.counts.top)
+	orgCounts *cyndi.OrgCountsStatus
+	// populated only if host counts per reporter have been determined (validation.reporter.counts.enabled)
+	reporterCounts []cyndi.ReporterHostCount
+
+	hbiHostCount int64
+	// HBI holds fewer hosts than validation.hbi.min.count - nothing has been compared
+	hbiCountTooLow bool
+}
+
+// Compares the hosts of HBI and of the application table. Host ids are not compared if countOnly is set, skipped checks are not evaluated
+func (i *ReconcileIteration) validate(countOnly bool, skipped []string) (result validationResult, err error) {
+	result = validationResult{isValid: false, mismatchRatio: -1, mismatchCount: -1, hostCount: -1, lingeringCount: -1}
+
+	appTable := database.AppTable(i.Instance.Status.TableName)
 
 	appHostCount, err := i.AppDb.CountHosts(appTable, false, []map[string]string{})
 	if err != nil {
-		return false, -1, -1, -1, err
+		return result, err
 	}
 
-	hbiHostCount, err := i.InventoryDb.CountHosts(inventoryTableName, i.Instance.Spec.InsightsOnly, i.Instance.Spec.AdditionalFilters)
+	hbiHostCount, err := i.countHbiHosts()
 	if err != nil {
-		return false, -1, -1, -1, err
+		return result, err
 	}
 
 	metrics.AppHostCount(i.Instance, appHostCount)
 
+	// an empty table would otherwise be found valid against an empty HBI
+	if hbiHostCount < i.config.ValidationHBIMinCount {
+		i.Log.Info("HBI host count is below the minimum, not validating", "hbi", hbiHostCount, "minimum", i.config.ValidationHBIMinCount)
+		return validationResult{isValid: false, mismatchRatio: -1, mismatchCount: -1, hostCount: appHostCount, lingeringCount: -1, hbiHostCount: hbiHostCount, hbiCountTooLow: true}, nil
+	}
+
 	countMismatch := utils.Abs(hbiHostCount - appHostCount)
 	countMismatchRatio := float64(countMismatch) / math.Max(float64(hbiHostCount), 1)
 
 	i.Log.Info("Fetched host counts", "hbi", hbiHostCount, "app", appHostCount, "countMismatchRatio", countMismatchRatio)
 
+	validationConfig := i.getValidationConfig()
+
 	// if the counts are way off don't even bother comparing ids
-	if countMismatchRatio > countMismatchThreshold {
+	if countMismatchRatio > countMismatchThreshold && !validationConfig.IsWithinThreshold(countMismatchRatio, countMismatch) {
 		i.Log.Info("Count mismatch ratio is above threashold, exiting early", "countMismatchRatio", countMismatchRatio)
-		metrics.ValidationFinished(i.Instance, i.getValidationConfig().PercentageThreshold, countMismatchRatio, countMismatch, false)
-		return false, countMismatchRatio, countMismatch, appHostCount, nil
+		metrics.ValidationFinished(i.Instance, validationConfig.PercentageThreshold, countMismatchRatio, countMismatch, false)
+		return validationResult{isValid: false, mismatchRatio: countMismatchRatio, mismatchCount: countMismatch, hostCount: appHostCount, lingeringCount: -1, hbiHostCount: hbiHostCount}, nil
+	}
+
+	// the count mismatch is the lower bound of the id mismatch and can therefore only prove the pipeline invalid
+	if countOnly {
+		isValid := validationConfig.IsWithinThreshold(countMismatchRatio, countMismatch)
+		if !isValid {
+			metrics.ValidationFinished(i.Instance, validationConfig.PercentageThreshold, countMismatchRatio, countMismatch, false)
+		}
+
+		i.Log.Info("Compared host counts only", "isValid", isValid)
+		return validationResult{isValid: isValid, mismatchRatio: countMismatchRatio, mismatchCount: countMismatch, hostCount: appHostCount, lingeringCount: -1, countOnly: true, hbiHostCount: hbiHostCount}, nil
 	}
 
-	hbiIds, err := i.InventoryDb.GetHostIds(inventoryTableName, i.Instance.Spec.InsightsOnly, i.Instance.Spec.AdditionalFilters)
+	strategy, err := i.validationStrategy()
 	if err != nil {
-		return false, -1, -1, -1, err
+		return result, err
 	}
 
-	appIds, err := i.AppDb.GetHostIds(appTable, false, []map[string]string{})
+	comparison, err := strategy.compare(i, appTable, hbiHostCount, appHostCount)
 	if err != nil {
-		return false, -1, -1, -1, err
+		return result, err
 	}
 
-	i.Log.Info("Fetched host ids")
-	inHbiOnly := utils.Difference(hbiIds, appIds)
-	inAppOnly := utils.Difference(appIds, hbiIds)
-	mismatchCount = int64(len(inHbiOnly) + len(inAppOnly))
+	inHbiOnly, inAppOnly := comparison.inHbiOnly, comparison.inAppOnly
+	mismatchCount := comparison.inHbiOnlyCount + comparison.inAppOnlyCount
 
-	validationThresholdPercent := i.getValidationConfig().PercentageThreshold
+	idMismatchRatio := float64(mismatchCount) / math.Max(float64(comparison.hbiCount), 1)
+	isValid := validationConfig.IsWithinThreshold(idMismatchRatio, mismatchCount)
 
-	idMismatchRatio := float64(mismatchCount) / math.Max(float64(len(hbiIds)), 1)
-	result := (idMismatchRatio * 100) <= float64(validationThresholdPercent)
+	// deleted hosts are checked separately as they may be few compared to the size of the table yet visible to users
+	lingeringCount := comparison.inAppOnlyCount
+	if comparison.countsOnly {
+		lingeringCount = -1
+	}
+
+	lingeringExceeded := validationConfig.LingeringThreshold >= 0 && lingeringCount > validationConfig.LingeringThreshold &&
+		!utils.ContainsString(skipped, validationCheckLingering)
+	isValid = isValid && !lingeringExceeded
+
+	var orgCounts *cyndi.OrgCountsStatus
+	if !utils.ContainsString(skipped, validationCheckOrgCounts) {
+		if orgCounts, err = i.compareOrgCounts(appTable); err != nil {
+			return result, err
+		}
+	}
+
+	isValid = isValid && (orgCounts == nil || len(orgCounts.Mismatched) == 0)
 
-	metrics.ValidationFinished(i.Instance, i.getValidationConfig().PercentageThreshold, idMismatchRatio, mismatchCount, result)
+	reporterCounts, err := i.countHostsByReporter(appTable)
+	if err != nil {
+		return result, err
+	}
+
+	metrics.ValidationFinished(i.Instance, validationConfig.PercentageThreshold, idMismatchRatio, mismatchCount, isValid)
 	i.Log.Info(
 		"Validation results",
-		"validationThresholdPercent", validationThresholdPercent,
+		"validationThresholdPercent", validationConfig.PercentageThreshold,
+		"validationThresholdCount", validationConfig.CountThreshold,
+		"validationThresholdMode", validationConfig.ThresholdMode,
 		"idMismatchRatio", idMismatchRatio,
+		"validationThresholdLingering", validationConfig.LingeringThreshold,
+		"lingeringCount", lingeringCount,
 		// if the list is too long truncate it to first 50 ids to avoid log polution
 		"inHbiOnly", inHbiOnly[:utils.Min(idDiffMaxLength, len(inHbiOnly))],
 		"inAppOnly", inAppOnly[:utils.Min(idDiffMaxLength, len(inAppOnly))],
 	)
-	return result, idMismatchRatio, mismatchCount, appHostCount, nil
+
+	return validationResult{
+		isValid:       isValid,
+		mismatchRatio: idMismatchRatio,
+		mismatchCount: mismatchCount,
+		hostCount:     appHostCount,
+		inHbiOnly:     inHbiOnly,
+		inAppOnly:     inAppOnly,
+
+		mismatchedHosts: comparison.sample(int(i.config.MismatchedIdsConfig.Sample)),
+
+		lingeringCount:    lingeringCount,
+		lingeringExceeded: lingeringExceeded,
+
+		orgCounts:      orgCounts,
+		reporterCounts: reporterCounts,
+		hbiHostCount:   hbiHostCount,
+	}, nil
+}
+
+// Filters selecting the HBI hosts syndicated by the pipeline, one set of filters for each topic shard
+func (i *ReconcileIteration) hbiFilters() [][]map[string]string {
+	if len(i.config.TopicFilters) == 0 {
+		return [][]map[string]string{i.Instance.Spec.AdditionalFilters}
+	}
+
+	result := make([][]map[string]string, len(i.config.TopicFilters))
+	for n, where := range i.config.TopicFilters {
+		result[n] = append(append([]map[string]string{}, i.Instance.Spec.AdditionalFilters...), map[string]string{"where": where})
+	}
+
+	return result
+}
+
+// Sums host counts of the topic shards
+func (i *ReconcileIteration) countHbiHosts() (total int64, err error) {
+	for _, filters := range i.hbiFilters() {
+		count, err := i.Inventory.CountHosts(inventoryTableName, i.Instance.Spec.InsightsOnly, filters)
+		if err != nil {
+			return -1, err
+		}
+
+		total += count
+	}
+
+	return total, nil
+}
+
+// Host ids of the topic shards, optionally narrowed down by additional filters (e.g. a block of ids)
+func (i *ReconcileIteration) getHbiHostIds(extraFilters ...map[string]string) (result []string, err error) {
+	for _, filters := range i.hbiFilters() {
+		ids, err := i.Inventory.GetHostIds(inventoryTableName, i.Instance.Spec.InsightsOnly, append(append([]map[string]string{}, filters...), extraFilters...))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, ids...)
+	}
+
+	return result, nil
 }
@@ -3,22 +3,31 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
-	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/connect"
+	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+	"github.com/RedHatInsights/cyndi-operator/controllers/tracing"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 
 	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type ValidationReconciler struct {
@@ -27,12 +36,16 @@ type ValidationReconciler struct {
 	// if true the Reconciler will check for pipeline state deviation
 	// should always be true except for tests
 	CheckResourceDeviation bool
+
+	// true in the validation binary run by the validation CronJobs (see validation.mode). The controller of the operator
+	// leaves pipelines validated by CronJobs alone
+	Standalone bool
 }
 
 func (r *ValidationReconciler) setup(reqLogger logr.Logger, request ctrl.Request, ctx context.Context) (ReconcileIteration, error) {
 	i, err := r.CyndiPipelineReconciler.setup(reqLogger, request, ctx)
 
-	if err != nil || i.Instance == nil {
+	if err != nil || i.Instance == nil || i.throttledFor > 0 {
 		return i, err
 	}
 
@@ -40,23 +53,37 @@ func (r *ValidationReconciler) setup(reqLogger logr.Logger, request ctrl.Request
 		return i.getValidationConfig().Interval
 	}
 
-	i.InventoryDb = database.NewBaseDatabase(&i.HBIDBParams, reqLogger)
+	if r.delegatedToCronJob(&i) {
+		return i, nil
+	}
 
-	if err = i.InventoryDb.Connect(); err != nil {
+	if err = i.connectInventory(); err != nil {
 		return i, err
 	}
 
 	return i, err
 }
 
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
 func (r *ValidationReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
-	reqLogger := r.Log.WithValues("Pipeline", request.Name, "Namespace", request.Namespace)
+	ctx, span := tracing.Start(ctx, "Validation.Reconcile", requestAttributes(request)...)
+	result, err := r.reconcileValidation(ctx, request)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *ValidationReconciler) reconcileValidation(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	reqLogger := newRequestLogger(r.Log, request)
 
 	i, err := r.setup(reqLogger, request, ctx)
 	defer i.Close()
 
 	if err != nil {
+		if requeue, degraded := i.reportDegraded(err); degraded {
+			return requeue, nil
+		}
+
 		return reconcile.Result{}, i.error(err)
 	}
 
@@ -65,12 +92,28 @@ func (r *ValidationReconciler) Reconcile(ctx context.Context, request ctrl.Reque
 		return reconcile.Result{}, nil
 	}
 
-	reqLogger.Info("Validating CyndiPipeline")
+	// the pipeline controller is backing off - validation would most likely fail the same way
+	if i.throttledFor > 0 {
+		return reconcile.Result{RequeueAfter: i.throttledFor}, nil
+	}
+
+	// requeued nevertheless so that validation resumes here if validation.mode is switched back to controller
+	if r.delegatedToCronJob(&i) {
+		i.debug("Pipeline validated by CronJob")
+		return reconcile.Result{RequeueAfter: time.Duration(i.getValidationConfig().Interval) * time.Second}, nil
+	}
+
+	i.Log.Info("Validating CyndiPipeline")
+
+	// all the databases are reachable at this point
+	i.clearDegraded(reasonDatabaseUnavailable)
 
 	if r.CheckResourceDeviation {
 		problem, err := i.checkForDeviation()
 		if err != nil {
 			return reconcile.Result{}, i.error(err, "Error checking for state deviation")
+		} else if problem != nil && i.readOnly {
+			i.probeStateDeviationReported(problem.Error())
 		} else if problem != nil {
 			i.probeStateDeviationRefresh(problem.Error())
 			i.Instance.TransitionToNew()
@@ -78,15 +121,135 @@ func (r *ValidationReconciler) Reconcile(ctx context.Context, request ctrl.Reque
 		}
 	}
 
-	isValid, mismatchRatio, mismatchCount, hostCount, err := i.validate()
+	if err := i.checkReplicationHealth(); err != nil {
+		// not fatal - the validation below does not depend on the source connector
+		i.Log.Error(err, "Failed to check replication health")
+	}
+
+	// the pipeline controller is re-creating the connector - the table is not being updated in the meantime
+	if isDegradedBy(i.Instance, reasonConnectorMissing) {
+		i.Log.Info("Not validating pipeline with a missing connector")
+		return i.updateStatusAndRequeue()
+	}
+
+	// failures caused by the Connect cluster being down would count towards a futile refresh
+	ready, err := i.checkConnectCluster()
+	if err != nil {
+		return reconcile.Result{}, i.error(err, "Error checking Connect cluster")
+	} else if !ready {
+		i.Log.Info("Not validating pipeline while its Connect cluster is not ready")
+		return i.updateStatusAndRequeue()
+	}
+
+	countOnly, err := i.outsideValidationWindows(time.Now())
+	if err != nil {
+		return reconcile.Result{}, i.error(err, "Error evaluating validation windows")
+	}
+
+	skipped, err := i.skippedValidationChecks(time.Now())
+	if err != nil {
+		return reconcile.Result{}, i.error(err, "Error evaluating validation skips")
+	}
+
+	i.Instance.Status.SkippedValidationChecks = skipped
+	if len(skipped) > 0 {
+		i.Log.Info("Skipping validation checks", "checks", skipped)
+	}
+
+	if pressure, err := i.hbiUnderPressure(); err != nil {
+		// not fatal - the pipeline is validated as if the load of HBI was not monitored
+		i.Log.Error(err, "Failed to check the load of HBI")
+	} else if pressure != "" {
+		i.eventNormal("ValidationDeferred", "Validation deferred by %d seconds as HBI is under pressure: %s", i.config.ValidationLoadDeferInterval, pressure)
+		metrics.ValidationDeferred(i.Instance)
+
+		result, err := i.updateStatusAndRequeue()
+		if err == nil {
+			result.RequeueAfter = time.Duration(i.config.ValidationLoadDeferInterval) * time.Second
+		}
+
+		return result, err
+	}
+
+	result, err := i.validate(countOnly || utils.ContainsString(skipped, validationCheckContent), skipped)
+	i.reportSlowQueries()
+
 	if err != nil {
+		if requeue, degraded := i.reportDegraded(err); degraded {
+			return requeue, nil
+		}
+
 		return reconcile.Result{}, i.error(err, "Error validating pipeline")
 	}
 
-	reqLogger.Info("Validation finished", "isValid", isValid)
+	i.Log.Info("Validation finished", "isValid", result.isValid)
+
+	// the pipeline must not become valid against an HBI that looks empty (e.g. wrong credentials) while a refresh would not help either
+	if result.hbiCountTooLow {
+		msg := fmt.Sprintf("HBI host count (%d) is below the minimum of %d", result.hbiHostCount, i.config.ValidationHBIMinCount)
+		i.Recorder.Event(i.Instance, corev1.EventTypeWarning, "HBIHostCountTooLow", msg)
+		i.Instance.SetInvalidUncounted("HBIHostCountTooLow", msg, result.hostCount)
+		return i.updateStatusAndRequeue()
+	}
+
+	i.detectCountAnomaly(result.hbiHostCount, result.hostCount)
+
+	// progress is measured against the host count of the previous validation
+	i.trackInitialSyncProgress(result.hostCount)
+	i.trackInitialSyncThroughput(result.hbiHostCount, result.hostCount, metav1.Now())
 
-	if isValid {
-		msg := fmt.Sprintf("%v hosts (%.2f%%) do not match", mismatchCount, mismatchRatio*100)
+	// matching counts do not prove the pipeline valid - its validity is left as is until the next validation window
+	if result.countOnly && result.isValid {
+		i.Instance.Status.HostCount = result.hostCount
+		return i.updateStatusAndRequeue()
+	}
+
+	if utils.ContainsString(skipped, validationCheckXjoin) {
+		i.Instance.Status.XjoinCrossCheck = nil
+	} else if crossCheck, err := i.crossCheckXjoin(result.hbiHostCount, result.hostCount); err != nil {
+		// not fatal - the cross-check only helps to tell the cause of a mismatch
+		i.Log.Error(err, "Failed to cross-check xjoin")
+	} else if crossCheck != nil {
+		if crossCheck.Diagnosis == cyndi.XjoinDiagnosisInventory && !isXjoinDiagnosis(i.Instance, cyndi.XjoinDiagnosisInventory) {
+			i.eventWarning("InventoryDivergence", "Both the application table and xjoin diverge from HBI (%d hosts in HBI, %d in the application table, %d in xjoin)",
+				result.hbiHostCount, result.hostCount, crossCheck.HostCount)
+		}
+
+		i.Instance.Status.XjoinCrossCheck = crossCheck
+	}
+
+	if result.lingeringCount >= 0 {
+		i.Instance.Status.LingeringHostCount = result.lingeringCount
+		i.Instance.Status.MismatchedHosts = result.mismatchedHosts
+		i.Instance.Status.OrgCounts = result.orgCounts
+		i.Instance.Status.ReporterCounts = result.reporterCounts
+	}
+
+	lag := int64(-1)
+	if i.config.ValidationLagPrometheusURL != "" {
+		lag = i.updateConsumerLag()
+	}
+
+	if i.config.ValidationDiffEnabled {
+		// a diff of an earlier validation would be mistaken for the result of this one
+		if len(result.inHbiOnly) == 0 && len(result.inAppOnly) == 0 {
+			err = i.deleteValidationDiff()
+		} else {
+			err = i.exportValidationDiff(result)
+		}
+
+		if err != nil {
+			// not fatal - the diff is only a debugging aid
+			i.Log.Error(err, "Failed to export validation diff")
+		}
+	}
+
+	if result.isValid {
+		msg := fmt.Sprintf("%v hosts (%.2f%%) do not match", result.mismatchCount, result.mismatchRatio*100)
+
+		if i.holdInShadow(fmt.Sprintf("Validation succeeded - %s", msg), result.hostCount) {
+			return i.updateStatusAndRequeue()
+		}
 
 		if i.Instance.GetState() == cyndi.STATE_INVALID {
 			i.eventNormal("Valid", "Pipeline is valid again")
@@ -96,23 +259,58 @@ func (r *ValidationReconciler) Reconcile(ctx context.Context, request ctrl.Reque
 			metav1.ConditionTrue,
 			"ValidationSucceeded",
 			fmt.Sprintf("Validation succeeded - %s", msg),
-			hostCount,
+			result.hostCount,
 		)
 	} else {
-		msg := fmt.Sprintf("Validation failed - %v hosts (%.2f%%) do not match", mismatchCount, mismatchRatio*100)
+		i.Instance.Status.ShadowValidations = 0
 
-		i.Recorder.Event(i.Instance, corev1.EventTypeWarning, "ValidationFailed", msg)
-		i.Instance.SetValid(
-			metav1.ConditionFalse,
-			"ValidationFailed",
-			msg,
-			hostCount,
-		)
+		msg := fmt.Sprintf("Validation failed - %v hosts (%.2f%%) do not match", result.mismatchCount, result.mismatchRatio*100)
+		if result.lingeringExceeded {
+			msg = fmt.Sprintf("%s, %v hosts deleted in HBI linger in the application table", msg, result.lingeringCount)
+		}
+		if result.orgCounts != nil && len(result.orgCounts.Mismatched) > 0 {
+			org := result.orgCounts.Mismatched[0]
+			msg = fmt.Sprintf("%s, host counts of %d organizations do not match (e.g. %s: %d in HBI, %d in the application table)",
+				msg, len(result.orgCounts.Mismatched), org.OrgID, org.HBI, org.App)
+		}
+
+		maintenance, err := i.inMaintenanceWindow(time.Now())
+		if err != nil {
+			return reconcile.Result{}, i.error(err, "Error evaluating maintenance windows")
+		}
+
+		if maintenance {
+			i.Recorder.Event(i.Instance, corev1.EventTypeWarning, "ValidationFailedInMaintenance", msg)
+			i.Instance.SetInvalidUncounted(
+				"ValidationFailedInMaintenance",
+				fmt.Sprintf("%s (maintenance window active)", msg),
+				result.hostCount,
+			)
+		} else if lagExplainsMismatch(lag, result) {
+			i.Recorder.Event(i.Instance, corev1.EventTypeWarning, "ValidationFailedWithLag", msg)
+			i.Instance.SetInvalidUncounted(
+				"ValidationFailedWithLag",
+				fmt.Sprintf("%s (consumer lag of %v messages)", msg, lag),
+				result.hostCount,
+			)
+		} else {
+			i.Recorder.Event(i.Instance, corev1.EventTypeWarning, "ValidationFailed", msg)
+			i.Instance.SetValid(
+				metav1.ConditionFalse,
+				"ValidationFailed",
+				msg,
+				result.hostCount,
+			)
+		}
 	}
 
 	return i.updateStatusAndRequeue()
 }
 
+func (r *ValidationReconciler) delegatedToCronJob(i *ReconcileIteration) bool {
+	return !r.Standalone && i.config != nil && i.config.ValidationMode == config.ValidationModeCronJob
+}
+
 func eventFilterPredicate() predicate.Predicate {
 	return predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
@@ -135,11 +333,37 @@ func eventFilterPredicate() predicate.Predicate {
 func (r *ValidationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("cyndi-validation").
-		For(&cyndi.CyndiPipeline{}).
-		WithEventFilter(eventFilterPredicate()).
+		For(&cyndi.CyndiPipeline{}, builder.WithPredicates(eventFilterPredicate())).
+		// resume validation of suspended pipelines as soon as their Connect cluster is ready again
+		Watches(&source.Kind{Type: connect.EmptyConnectCluster()}, handler.EnqueueRequestsFromMapFunc(r.pipelinesUsingConnectCluster), builder.WithPredicates(connectClusterBecameReady())).
 		Complete(r)
 }
 
+func connectClusterBecameReady() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldCluster, ok1 := e.ObjectOld.(*unstructured.Unstructured)
+			newCluster, ok2 := e.ObjectNew.(*unstructured.Unstructured)
+			if !ok1 || !ok2 {
+				return false
+			}
+
+			wasNotReady, _ := connect.IsNotReady(oldCluster)
+			isNotReady, _ := connect.IsNotReady(newCluster)
+			return wasNotReady && !isNotReady
+		},
+	}
+}
+
 func NewValidationReconciler(client client.Client, clientset *kubernetes.Clientset, scheme *runtime.Scheme, log logr.Logger, recorder record.EventRecorder, checkResourceDeviation bool) *ValidationReconciler {
 	return &ValidationReconciler{
 		CyndiPipelineReconciler: *NewCyndiReconciler(client, clientset, scheme, log, recorder),
@@ -4,10 +4,15 @@ import (
 	"context"
 	"fmt"
 	logr "github.com/go-logr/logr/testing"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
 
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
 	"github.com/RedHatInsights/cyndi-operator/controllers/database"
 	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+	cynditesting "github.com/RedHatInsights/cyndi-operator/pkg/testing"
 	"github.com/RedHatInsights/cyndi-operator/test"
 
 	. "github.com/onsi/ginkgo"
@@ -15,6 +20,7 @@ import (
 
 	. "github.com/RedHatInsights/cyndi-operator/controllers/config"
 
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -28,21 +34,13 @@ import (
  */
 
 func createApplicationTable(db database.Database, TestTable string) {
-	_, err := db.Exec(fmt.Sprintf("CREATE TABLE %s (id uuid PRIMARY KEY)", TestTable))
+	err := cynditesting.CreateApplicationTable(db, TestTable)
 	Expect(err).ToNot(HaveOccurred())
 }
 
 func seedTable(db database.Database, TestTable string, insights bool, ids ...string) {
-	var template = "INSERT INTO %s (id) VALUES ('%s')"
-
-	if insights {
-		template = `INSERT INTO %s (id, canonical_facts) VALUES ('%s', '{"insights_id": "7597d33e-a1a6-4fda-ad1e-b86b73c722fd"}')`
-	}
-
-	for _, id := range ids {
-		_, err := db.Exec(fmt.Sprintf(template, TestTable, id))
-		Expect(err).ToNot(HaveOccurred())
-	}
+	err := cynditesting.SeedTable(db, TestTable, insights, ids...)
+	Expect(err).ToNot(HaveOccurred())
 }
 
 var _ = Describe("Validation controller", func() {
@@ -128,7 +126,7 @@ var _ = Describe("Validation controller", func() {
 			pipeline := getPipeline(namespacedName)
 
 			seedTable(hbiDb, "public.hosts", false, hosts...)
-			appTable := utils.AppFullTableName(pipeline.Status.TableName)
+			appTable := database.AppTable(pipeline.Status.TableName)
 			createApplicationTable(appDb, appTable)
 			seedTable(appDb, appTable, false, hosts...)
 
@@ -166,7 +164,7 @@ var _ = Describe("Validation controller", func() {
 			seedTable(hbiDb, "public.hosts", true, insightsHosts...)
 			seedTable(hbiDb, "public.hosts", false, otherHosts...)
 
-			appTable := utils.AppFullTableName(pipeline.Status.TableName)
+			appTable := database.AppTable(pipeline.Status.TableName)
 			createApplicationTable(appDb, appTable)
 			seedTable(appDb, appTable, false, insightsHosts...)
 
@@ -180,6 +178,42 @@ var _ = Describe("Validation controller", func() {
 			Expect(pipeline.Status.HostCount).To(Equal(int64(3)))
 		})
 
+		It("Correctly validates table consuming sharded topics", func() {
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{Topics: []cyndi.TopicSource{
+				{Name: "platform.inventory.events.shard1", Where: "canonical_facts ? 'insights_id'"},
+				{Name: "platform.inventory.events.shard2", Where: "id = '45f639ff-f1f5-4469-9a7b-35295fdb75fc'"},
+			}})
+
+			var (
+				insightsHosts = []string{
+					"3b8c0b37-6208-4323-b7df-030fee22db0c",
+					"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+					"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+				}
+
+				otherHosts = []string{
+					"45f639ff-f1f5-4469-9a7b-35295fdb75fc",
+					"d2b58af8-fd82-4be1-83b1-1d1071b8bc95",
+				}
+			)
+
+			initializePipeline(false)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", true, insightsHosts...)
+			seedTable(hbiDb, "public.hosts", false, otherHosts...)
+
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, append(insightsHosts, otherHosts[0])...)
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeTrue())
+			Expect(pipeline.Status.Conditions[0].Message).To(Equal("Validation succeeded - 0 hosts (0.00%) do not match"))
+			Expect(pipeline.Status.HostCount).To(Equal(int64(4)))
+		})
+
 		It("Correctly validates pipeline that's slightly off", func() {
 			createPipeline(namespacedName)
 
@@ -196,7 +230,7 @@ var _ = Describe("Validation controller", func() {
 			pipeline := getPipeline(namespacedName)
 
 			seedTable(hbiDb, "public.hosts", false, hosts...)
-			appTable := utils.AppFullTableName(pipeline.Status.TableName)
+			appTable := database.AppTable(pipeline.Status.TableName)
 			createApplicationTable(appDb, appTable)
 			seedTable(appDb, appTable, false, hosts[0:5]...)
 
@@ -225,7 +259,7 @@ var _ = Describe("Validation controller", func() {
 			pipeline := getPipeline(namespacedName)
 
 			seedTable(hbiDb, "public.hosts", false, hosts...)
-			appTable := utils.AppFullTableName(pipeline.Status.TableName)
+			appTable := database.AppTable(pipeline.Status.TableName)
 			createApplicationTable(appDb, appTable)
 			seedTable(appDb, appTable, false, hosts[0:1]...)
 
@@ -255,7 +289,7 @@ var _ = Describe("Validation controller", func() {
 			pipeline := getPipeline(namespacedName)
 
 			seedTable(hbiDb, "public.hosts", false, hosts...)
-			appTable := utils.AppFullTableName(pipeline.Status.TableName)
+			appTable := database.AppTable(pipeline.Status.TableName)
 			createApplicationTable(appDb, appTable)
 			seedTable(appDb, appTable, false, hosts[0:4]...)
 
@@ -282,7 +316,7 @@ var _ = Describe("Validation controller", func() {
 			pipeline := getPipeline(namespacedName)
 
 			seedTable(hbiDb, "public.hosts", false, hosts...)
-			appTable := utils.AppFullTableName(pipeline.Status.TableName)
+			appTable := database.AppTable(pipeline.Status.TableName)
 			createApplicationTable(appDb, appTable)
 			seedTable(appDb, appTable, false, hosts[0:1]...)
 
@@ -311,7 +345,7 @@ var _ = Describe("Validation controller", func() {
 			pipeline := getPipeline(namespacedName)
 
 			seedTable(hbiDb, "public.hosts", false, hosts...)
-			appTable := utils.AppFullTableName(pipeline.Status.TableName)
+			appTable := database.AppTable(pipeline.Status.TableName)
 			createApplicationTable(appDb, appTable)
 			seedTable(appDb, appTable, false, hosts[1:6]...)
 
@@ -326,6 +360,640 @@ var _ = Describe("Validation controller", func() {
 		})
 	})
 
+	Describe("Maintenance windows", func() {
+		var hosts = []string{
+			"3b8c0b37-6208-4323-b7df-030fee22db0c",
+			"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+			"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+		}
+
+		seed := func() {
+			initializePipeline(false)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", false, hosts...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts[0:1]...)
+		}
+
+		It("Does not count failures during a maintenance window defined in spec", func() {
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{MaintenanceWindows: []cyndi.MaintenanceWindow{{
+				Schedule: "* * * * *",
+				Duration: 120,
+			}}})
+			seed()
+
+			for i := 1; i < 3; i++ {
+				reconcile()
+				pipeline := getPipeline(namespacedName)
+				Expect(pipeline.IsValid()).To(BeFalse())
+				Expect(pipeline.Status.Conditions[0].Reason).To(Equal("ValidationFailedInMaintenance"))
+				Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
+			}
+		})
+
+		It("Does not count failures while the maintenance annotation is set", func() {
+			createPipeline(namespacedName)
+			seed()
+
+			pipeline := getPipeline(namespacedName)
+			pipeline.SetAnnotations(map[string]string{annotationMaintenanceUntil: time.Now().Add(time.Hour).Format(time.RFC3339)})
+			Expect(test.Client.Update(context.TODO(), pipeline)).ToNot(HaveOccurred())
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
+		})
+
+		It("Counts failures once the maintenance annotation expires", func() {
+			createPipeline(namespacedName)
+			seed()
+
+			pipeline := getPipeline(namespacedName)
+			pipeline.SetAnnotations(map[string]string{annotationMaintenanceUntil: time.Now().Add(-time.Hour).Format(time.RFC3339)})
+			Expect(test.Client.Update(context.TODO(), pipeline)).ToNot(HaveOccurred())
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.Status.Conditions[0].Reason).To(Equal("ValidationFailed"))
+			Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(1)))
+		})
+	})
+
+	Describe("Validation windows", func() {
+		// a window that is (almost) never active
+		windows := []cyndi.MaintenanceWindow{{Schedule: "0 0 1 1 *", Duration: 1}}
+
+		It("Compares only host counts outside of validation windows", func() {
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{ValidationWindows: windows})
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			// same number of hosts with different ids
+			seedTable(hbiDb, "public.hosts", false, "3b8c0b37-6208-4323-b7df-030fee22db0c", "99d28b1e-aad8-4ac0-8d98-ef33e7d3856e")
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, "14bcbbb5-8837-4d24-8122-1d44b65680f5", "f341463d-f013-4213-91c7-824aa775283b")
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeTrue())
+			Expect(pipeline.Status.HostCount).To(Equal(int64(2)))
+		})
+
+		It("Fails validation outside of validation windows if host counts do not match", func() {
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{ValidationWindows: windows})
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", false, "3b8c0b37-6208-4323-b7df-030fee22db0c", "99d28b1e-aad8-4ac0-8d98-ef33e7d3856e")
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.Conditions[0].Reason).To(Equal("ValidationFailed"))
+		})
+
+		It("Records the progress of an initial sync validated by counts only", func() {
+			createPipeline(namespacedName)
+			initializePipeline(false)
+
+			pipeline := getPipeline(namespacedName)
+			lastProgress := metav1.NewTime(time.Now().Add(-time.Hour))
+			pipeline.Status.InitialSyncLastProgress = &lastProgress
+			Expect(test.Client.Status().Update(context.TODO(), pipeline)).To(Succeed())
+
+			pipeline = getPipeline(namespacedName)
+			pipeline.SetAnnotations(map[string]string{
+				"cyndi.cloud.redhat.com/skip-content-validation": time.Now().Add(time.Hour).Format(time.RFC3339),
+			})
+			Expect(test.Client.Update(context.TODO(), pipeline)).To(Succeed())
+
+			hosts := []string{"3b8c0b37-6208-4323-b7df-030fee22db0c", "99d28b1e-aad8-4ac0-8d98-ef33e7d3856e"}
+			seedTable(hbiDb, "public.hosts", false, hosts...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts...)
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			// matching counts alone do not complete the initial sync
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.GetState()).To(Equal(cyndi.STATE_INITIAL_SYNC))
+			Expect(pipeline.Status.HostCount).To(Equal(int64(2)))
+			Expect(pipeline.Status.InitialSyncLastProgress.Time).To(BeTemporally(">", lastProgress.Time.Add(30*time.Minute)))
+			Expect(pipeline.Status.InitialSyncThroughput).ToNot(BeNil())
+			Expect(pipeline.Status.InitialSyncThroughput.HostCount).To(Equal(int64(2)))
+		})
+	})
+
+	Describe("Minimum HBI host count", func() {
+		It("Does not validate a pipeline against an empty HBI", func() {
+			createPipeline(namespacedName)
+			initializePipeline(false)
+			pipeline := getPipeline(namespacedName)
+			createApplicationTable(appDb, database.AppTable(pipeline.Status.TableName))
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.Conditions[0].Reason).To(Equal("HBIHostCountTooLow"))
+			Expect(pipeline.Status.Conditions[0].Message).To(Equal("HBI host count (0) is below the minimum of 1"))
+			Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
+			Expect(pipeline.Status.InitialSyncInProgress).To(BeTrue())
+		})
+
+		It("Validates a pipeline against an empty HBI if the check is disabled", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.hbi.min.count"] = "0"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+			initializePipeline(false)
+			pipeline := getPipeline(namespacedName)
+			createApplicationTable(appDb, database.AppTable(pipeline.Status.TableName))
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeTrue())
+		})
+
+		It("Promotes the table of a refresh once it passed validation.shadow.cycles validations", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.shadow.cycles"] = "2"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+			initializePipeline(false)
+			pipeline := getPipeline(namespacedName)
+			// the view still points to the table of the previous pipeline version
+			pipeline.Status.ActiveTableName = "hosts_v1_1"
+			Expect(test.Client.Status().Update(context.TODO(), pipeline)).ToNot(HaveOccurred())
+
+			hosts := []string{"3b8c0b37-6208-4323-b7df-030fee22db0c"}
+			seedTable(hbiDb, "public.hosts", false, hosts...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts...)
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.GetValidCondition().Reason).To(Equal(reasonShadowValidation))
+			Expect(pipeline.Status.ShadowValidations).To(Equal(int64(1)))
+			Expect(pipeline.Status.ValidationFailedCount).To(Equal(int64(0)))
+			Expect(pipeline.Status.InitialSyncInProgress).To(BeTrue())
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeTrue())
+			Expect(pipeline.Status.ShadowValidations).To(Equal(int64(2)))
+		})
+
+		It("Reports an unusual change of the host count difference", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.anomaly.enabled"] = "true"
+			configMap.Data["validation.anomaly.window"] = "2"
+			configMap.Data["validation.anomaly.min.hosts"] = "1"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+			pipeline.Status.HostCountDeltas = []int64{0, 0}
+			Expect(test.Client.Status().Update(context.TODO(), pipeline)).ToNot(HaveOccurred())
+
+			seedTable(hbiDb, "public.hosts", false, "3b8c0b37-6208-4323-b7df-030fee22db0c", "99d28b1e-aad8-4ac0-8d98-ef33e7d3856e")
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, "3b8c0b37-6208-4323-b7df-030fee22db0c")
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.GetCountAnomaly().Status).To(Equal(metav1.ConditionTrue))
+			Expect(pipeline.GetCountAnomaly().Reason).To(Equal(reasonHostCountAnomaly))
+			Expect(pipeline.Status.HostCountDeltas).To(Equal([]int64{0, -1}))
+		})
+	})
+
+	Describe("Host counts per organization", func() {
+		It("Fails validation if the hosts of an organization are missing", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.org.counts.top"] = "2"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+
+			var hosts = []string{
+				"3b8c0b37-6208-4323-b7df-030fee22db0c",
+				"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+				"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+				"f341463d-f013-4213-91c7-824aa775283b",
+			}
+
+			initializePipeline(false)
+			pipeline := getPipeline(namespacedName)
+
+			_, err := hbiDb.Exec(`ALTER TABLE public.hosts ADD COLUMN org_id character varying(36)`)
+			Expect(err).ToNot(HaveOccurred())
+			seedTable(hbiDb, "public.hosts", false, hosts...)
+			_, err = hbiDb.Exec(fmt.Sprintf(`UPDATE public.hosts SET org_id = CASE WHEN id = '%s' THEN 'b' ELSE 'a' END`, hosts[0]))
+			Expect(err).ToNot(HaveOccurred())
+
+			// the same hosts, all of them (wrongly) replicated as hosts of the same organization
+			appTable := database.AppTable(pipeline.Status.TableName)
+			_, err = appDb.Exec(fmt.Sprintf("CREATE TABLE %s (id uuid PRIMARY KEY, org_id character varying(36))", appTable))
+			Expect(err).ToNot(HaveOccurred())
+			seedTable(appDb, appTable, false, hosts...)
+			_, err = appDb.Exec(fmt.Sprintf(`UPDATE %s SET org_id = 'a'`, appTable))
+			Expect(err).ToNot(HaveOccurred())
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.Conditions[0].Message).To(HaveSuffix("host counts of 1 organizations do not match (e.g. b: 1 in HBI, 0 in the application table)"))
+			Expect(pipeline.Status.OrgCounts.Compared).To(Equal(int64(2)))
+			Expect(pipeline.Status.OrgCounts.Mismatched).To(Equal([]cyndi.OrgHostCount{{OrgID: "b", HBI: 1, App: 0}}))
+		})
+	})
+
+	Describe("Host counts per reporter", func() {
+		It("Records the host counts of each reporter", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.reporter.counts.enabled"] = "true"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+
+			var hosts = []string{
+				"3b8c0b37-6208-4323-b7df-030fee22db0c",
+				"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+				"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+			}
+
+			initializePipeline(false)
+			pipeline := getPipeline(namespacedName)
+
+			_, err := hbiDb.Exec(`ALTER TABLE public.hosts ADD COLUMN reporter character varying(255)`)
+			Expect(err).ToNot(HaveOccurred())
+			seedTable(hbiDb, "public.hosts", false, hosts...)
+			_, err = hbiDb.Exec(fmt.Sprintf(`UPDATE public.hosts SET reporter = CASE WHEN id = '%s' THEN 'puptoo' ELSE 'rhsm-conduit' END`, hosts[0]))
+			Expect(err).ToNot(HaveOccurred())
+
+			// the puptoo-reported host is missing
+			appTable := database.AppTable(pipeline.Status.TableName)
+			_, err = appDb.Exec(fmt.Sprintf("CREATE TABLE %s (id uuid PRIMARY KEY, reporter character varying(255))", appTable))
+			Expect(err).ToNot(HaveOccurred())
+			seedTable(appDb, appTable, false, hosts[1:]...)
+			_, err = appDb.Exec(fmt.Sprintf(`UPDATE %s SET reporter = 'rhsm-conduit'`, appTable))
+			Expect(err).ToNot(HaveOccurred())
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.Status.ReporterCounts).To(Equal([]cyndi.ReporterHostCount{
+				{Reporter: "puptoo", HBI: 1, App: 0},
+				{Reporter: "rhsm-conduit", HBI: 2, App: 2},
+			}))
+		})
+	})
+
+	Describe("Validation diff", func() {
+		It("Exports ids of mismatched hosts into a ConfigMap", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.diff.enabled"] = "true"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+
+			var hosts = []string{
+				"3b8c0b37-6208-4323-b7df-030fee22db0c",
+				"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+				"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+				"f341463d-f013-4213-91c7-824aa775283b",
+			}
+
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", false, hosts[0:3]...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts[1:4]...)
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.Status.ValidationDiffConfigMap).To(Equal("test-pipeline-01-validation-diff"))
+
+			diff, err := utils.FetchConfigMap(test.Client, namespacedName.Namespace, pipeline.Status.ValidationDiffConfigMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diff.Data["inHbiOnly"]).To(Equal(hosts[0]))
+			Expect(diff.Data["inAppOnly"]).To(Equal(hosts[3]))
+			Expect(diff.Data["mismatchCount"]).To(Equal("2"))
+		})
+
+		It("Removes the diff of an earlier validation once no hosts are mismatched", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.diff.enabled"] = "true"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+
+			var hosts = []string{
+				"3b8c0b37-6208-4323-b7df-030fee22db0c",
+				"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+				"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+				"f341463d-f013-4213-91c7-824aa775283b",
+			}
+
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", false, hosts[0:3]...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts[1:4]...)
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.ValidationDiffConfigMap).To(Equal("test-pipeline-01-validation-diff"))
+
+			// the tables converge
+			seedTable(hbiDb, "public.hosts", false, hosts[3])
+			seedTable(appDb, appTable, false, hosts[0])
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeTrue())
+			Expect(pipeline.Status.ValidationDiffConfigMap).To(BeEmpty())
+
+			_, err := utils.FetchConfigMap(test.Client, namespacedName.Namespace, "test-pipeline-01-validation-diff")
+			Expect(k8errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("Invalidates pipeline with hosts lingering after deletion in HBI", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.lingering.threshold"] = "0"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+
+			var hosts = []string{
+				"3b8c0b37-6208-4323-b7df-030fee22db0c",
+				"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+				"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+				"f341463d-f013-4213-91c7-824aa775283b",
+				"10be75f7-f84a-47ad-9b31-c54fdfdbe0c7",
+				"24b8e15c-66d8-4a03-9468-432fdd28de6a",
+			}
+
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", false, hosts[0:5]...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts...)
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.Conditions[0].Message).To(Equal("Validation failed - 1 hosts (20.00%) do not match, 1 hosts deleted in HBI linger in the application table"))
+			Expect(pipeline.Status.LingeringHostCount).To(Equal(int64(1)))
+		})
+
+		It("Compares only host counts with the counts strategy of the pipeline", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.lingering.threshold"] = "0"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			strategy := "counts"
+			createPipeline(namespacedName, &cyndi.CyndiPipelineSpec{ValidationStrategy: &strategy})
+
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			// a missing host and an extra one cancel out
+			seedTable(hbiDb, "public.hosts", false, "3b8c0b37-6208-4323-b7df-030fee22db0c", "99d28b1e-aad8-4ac0-8d98-ef33e7d3856e")
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, "3b8c0b37-6208-4323-b7df-030fee22db0c", "14bcbbb5-8837-4d24-8122-1d44b65680f5")
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeTrue())
+			Expect(pipeline.Status.Conditions[0].Message).To(Equal("Validation succeeded - 0 hosts (0.00%) do not match"))
+		})
+
+		It("Compares the host ids of a sample with the sampled strategy", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.strategy"] = "sampled"
+			configMap.Data["validation.sample.percentage"] = "100"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+
+			var hosts = []string{
+				"3b8c0b37-6208-4323-b7df-030fee22db0c",
+				"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+				"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+				"f341463d-f013-4213-91c7-824aa775283b",
+			}
+
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", false, hosts...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts[0:2]...)
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.Conditions[0].Message).To(Equal("Validation failed - 2 hosts (50.00%) do not match"))
+		})
+
+		It("Records the counts and a sample of the mismatched hosts", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.mismatched.ids.max"] = "2"
+			configMap.Data["validation.mismatched.ids.sample"] = "1"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+
+			var hosts = []string{
+				"10be75f7-f84a-47ad-9b31-c54fdfdbe0c7",
+				"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+				"24b8e15c-66d8-4a03-9468-432fdd28de6a",
+				"3b8c0b37-6208-4323-b7df-030fee22db0c",
+				"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+				"f341463d-f013-4213-91c7-824aa775283b",
+			}
+
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", false, hosts...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts[3:]...)
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.MismatchedHosts).To(Equal(&cyndi.MismatchedHostsStatus{
+				InHbiOnlyCount: 3,
+				InHbiOnly:      []string{hosts[0]},
+			}))
+		})
+
+		It("Skips the lingering check while annotated", func() {
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.lingering.threshold"] = "0"
+			configMap.Data["validation.percentage.threshold"] = "50"
+			configMap.Data["init.validation.percentage.threshold"] = "50"
+			Expect(test.Client.Update(context.TODO(), configMap)).ToNot(HaveOccurred())
+
+			createPipeline(namespacedName)
+
+			var hosts = []string{
+				"3b8c0b37-6208-4323-b7df-030fee22db0c",
+				"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+				"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+				"f341463d-f013-4213-91c7-824aa775283b",
+			}
+
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", false, hosts[0:3]...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts...)
+
+			pipeline.SetAnnotations(map[string]string{
+				"cyndi.cloud.redhat.com/skip-lingering-validation": time.Now().Add(time.Hour).Format(time.RFC3339),
+				// expires too far ahead to be honored
+				"cyndi.cloud.redhat.com/skip-content-validation": time.Now().Add(30 * 24 * time.Hour).Format(time.RFC3339),
+			})
+			Expect(test.Client.Update(context.TODO(), pipeline)).To(Succeed())
+
+			reconcile()
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeTrue())
+			Expect(pipeline.Status.LingeringHostCount).To(Equal(int64(1)))
+			Expect(pipeline.Status.SkippedValidationChecks).To(Equal([]string{"lingering"}))
+		})
+	})
+
+	Describe("Xjoin cross-check", func() {
+		var hosts = []string{
+			"3b8c0b37-6208-4323-b7df-030fee22db0c",
+			"99d28b1e-aad8-4ac0-8d98-ef33e7d3856e",
+			"14bcbbb5-8837-4d24-8122-1d44b65680f5",
+			"f341463d-f013-4213-91c7-824aa775283b",
+		}
+
+		// Validates a pipeline missing all but one host of HBI against an HBI API reporting the given host count
+		crossCheck := func(xjoinHostCount int) *cyndi.CyndiPipeline {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"total": %d, "results": []}`, xjoinHostCount)
+			}))
+			defer server.Close()
+
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.xjoin.enabled"] = "true"
+			configMap.Data["inventory.api.url"] = server.URL + "/api/inventory/v1/"
+			Expect(test.Client.Update(context.TODO(), configMap)).To(Succeed())
+
+			createPipeline(namespacedName)
+			initializePipeline(true)
+			pipeline := getPipeline(namespacedName)
+
+			seedTable(hbiDb, "public.hosts", false, hosts...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts[0])
+
+			reconcile()
+			return getPipeline(namespacedName)
+		}
+
+		It("Blames the pipeline if xjoin matches HBI", func() {
+			pipeline := crossCheck(4)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.XjoinCrossCheck).To(Equal(&cyndi.XjoinCrossCheckStatus{
+				HostCount:     4,
+				AppDivergence: -3,
+				HBIDivergence: 0,
+				Diagnosis:     cyndi.XjoinDiagnosisPipeline,
+			}))
+			Expect(recordedEvents(r.Recorder)).ToNot(ContainElement(HavePrefix("Warning InventoryDivergence")))
+		})
+
+		It("Reports a divergence of both the pipeline and xjoin", func() {
+			pipeline := crossCheck(1)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.XjoinCrossCheck).To(Equal(&cyndi.XjoinCrossCheckStatus{
+				HostCount:     1,
+				AppDivergence: 0,
+				HBIDivergence: 3,
+				Diagnosis:     cyndi.XjoinDiagnosisInventory,
+			}))
+			Expect(recordedEvents(r.Recorder)).To(ContainElement(HavePrefix("Warning InventoryDivergence")))
+		})
+	})
+
+	Describe("HBI pressure", func() {
+		It("Defers validation while HBI is under pressure", func() {
+			var unhealthy int32 = 1
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.LoadInt32(&unhealthy) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			configMap := getConfigMap(namespacedName.Namespace)
+			configMap.Data["validation.load.health.url"] = server.URL
+			configMap.Data["validation.load.defer.interval"] = "120"
+			Expect(test.Client.Update(context.TODO(), configMap)).To(Succeed())
+
+			createPipeline(namespacedName)
+			initializePipeline(false)
+			pipeline := getPipeline(namespacedName)
+
+			hosts := []string{"3b8c0b37-6208-4323-b7df-030fee22db0c", "99d28b1e-aad8-4ac0-8d98-ef33e7d3856e"}
+			seedTable(hbiDb, "public.hosts", false, hosts...)
+			appTable := database.AppTable(pipeline.Status.TableName)
+			createApplicationTable(appDb, appTable)
+			seedTable(appDb, appTable, false, hosts...)
+
+			result := reconcile()
+			Expect(result.RequeueAfter).To(Equal(120 * time.Second))
+			Expect(recordedEvents(r.Recorder)).To(ContainElement(HavePrefix("Normal ValidationDeferred")))
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeFalse())
+			Expect(pipeline.Status.HostCount).To(Equal(int64(0)))
+
+			atomic.StoreInt32(&unhealthy, 0)
+
+			result = reconcile()
+			Expect(result.RequeueAfter).ToNot(Equal(120 * time.Second))
+			Expect(recordedEvents(r.Recorder)).ToNot(ContainElement(HavePrefix("Normal ValidationDeferred")))
+
+			pipeline = getPipeline(namespacedName)
+			Expect(pipeline.IsValid()).To(BeTrue())
+			Expect(pipeline.Status.HostCount).To(Equal(int64(2)))
+		})
+	})
+
 	Describe("Failures", func() {
 		It("Fails if HBI DB secret is missing", func() {
 			dbSecret, err := utils.FetchSecret(test.Client, namespacedName.Namespace, "host-inventory-db")
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/connect"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+
+Validation in CronJobs. With validation.mode set to cronjob, the validation controller leaves pipelines alone and each pipeline is instead validated
+by a CronJob running the validation binary (/validate) of validation.cronjob.image. The binary performs a single iteration of the validation
+controller and exits, so that memory spikes of the validation cannot take the operator down, and the pods can be scheduled on dedicated nodes.
+The CronJobs live in the namespace of the operator so that they can run as its service account. As owner references cannot cross namespaces,
+they are labeled with the uid of their pipeline and removed along with it.
+
+*/
+
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	labelValidation = "cyndi.cloud.redhat.com/validation"
+
+	// Job names derived from the CronJob name need to fit 63 characters
+	maxCronJobNameLength = 52
+
+	validationBinary = "/validate"
+)
+
+func validationCronJobName(namespace string, name string) string {
+	result := fmt.Sprintf("cyndi-validate-%s-%s", namespace, name)
+	if len(result) <= maxCronJobNameLength {
+		return result
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(result)))[:8]
+	return result[:maxCronJobNameLength-len(hash)-1] + "-" + hash
+}
+
+func (i *ReconcileIteration) validationCronJobNamespace() string {
+	if i.operatorNamespace != "" {
+		return i.operatorNamespace
+	}
+
+	return i.Instance.Namespace
+}
+
+func (i *ReconcileIteration) newValidationCronJob() *batchv1beta1.CronJob {
+	cfg := i.config.ValidationCronJobConfig
+
+	labels := map[string]string{
+		connect.LabelOwner:    i.Instance.GetUIDString(),
+		connect.LabelPipeline: i.Instance.Name,
+		labelValidation:       i.Instance.Namespace,
+	}
+
+	// the connector the validation compares against needs to be rendered the same way as by the operator
+	var env []corev1.EnvVar
+	if ephemeral := os.Getenv("EPHEMERAL"); ephemeral != "" {
+		env = append(env, corev1.EnvVar{Name: "EPHEMERAL", Value: ephemeral})
+	}
+
+	container := corev1.Container{
+		Name:    "validate",
+		Image:   cfg.Image,
+		Command: []string{validationBinary},
+		Args:    []string{"--namespace", i.Instance.Namespace, "--name", i.Instance.Name},
+		Env:     env,
+	}
+
+	if cfg.MemoryLimit != nil {
+		container.Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: *cfg.MemoryLimit}
+	}
+
+	backoffLimit := int32(0)
+	historyLimit := int32(1)
+
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      validationCronJobName(i.Instance.Namespace, i.Instance.Name),
+			Namespace: i.validationCronJobNamespace(),
+			Labels:    labels,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule: cfg.Schedule,
+			// a validation still running when the next one is due would only compete with it for memory
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							RestartPolicy:      corev1.RestartPolicyNever,
+							ServiceAccountName: cfg.ServiceAccount,
+							NodeSelector:       cfg.NodeSelector,
+							Tolerations:        cfg.Tolerations,
+							Containers:         []corev1.Container{container},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Creates or updates the validation CronJob of the pipeline if validation runs in CronJobs, removes it otherwise
+func (i *ReconcileIteration) reconcileValidationCronJob() error {
+	if i.skipMutation("Not updating validation CronJob") {
+		return nil
+	}
+
+	if i.config.ValidationMode != config.ValidationModeCronJob {
+		return i.deleteValidationCronJobs(nil)
+	}
+
+	desired := i.newValidationCronJob()
+
+	// the API server fills in defaults of the pod template, so changes are told apart using the hash of the desired spec
+	specHash, err := utils.SpecHash(desired.Spec)
+	if err != nil {
+		return err
+	}
+
+	cronJob := &batchv1beta1.CronJob{}
+	err = i.Client.Get(i.ctx, client.ObjectKeyFromObject(desired), cronJob)
+	if k8errors.IsNotFound(err) {
+		desired.Annotations = map[string]string{connect.AnnotationSpecHash: specHash}
+		i.Log.Info("Creating validation CronJob", "name", desired.Name, "namespace", desired.Namespace)
+		if err = i.Client.Create(i.ctx, desired); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else if cronJob.GetAnnotations()[connect.AnnotationSpecHash] != specHash {
+		cronJob.Labels = utils.Merge(cronJob.Labels, desired.Labels)
+		cronJob.Annotations = utils.Merge(cronJob.Annotations, map[string]string{connect.AnnotationSpecHash: specHash})
+		cronJob.Spec = desired.Spec
+		if err = i.Client.Update(i.ctx, cronJob); err != nil {
+			return err
+		}
+	}
+
+	return i.deleteValidationCronJobs(desired)
+}
+
+// Deletes the validation CronJobs of the pipeline except the given one (e.g. one left behind in the namespace of the pipeline)
+func (i *ReconcileIteration) deleteValidationCronJobs(keep *batchv1beta1.CronJob) error {
+	cronJobs := &batchv1beta1.CronJobList{}
+	if err := i.Client.List(i.ctx, cronJobs, client.MatchingLabels{connect.LabelOwner: i.Instance.GetUIDString()}, client.HasLabels{labelValidation}); err != nil {
+		return err
+	}
+
+	for idx := range cronJobs.Items {
+		cronJob := &cronJobs.Items[idx]
+		if keep != nil && client.ObjectKeyFromObject(cronJob) == client.ObjectKeyFromObject(keep) {
+			continue
+		}
+
+		i.Log.Info("Removing validation CronJob", "name", cronJob.Name, "namespace", cronJob.Namespace)
+		if err := i.Client.Delete(i.ctx, cronJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func validationDiffConfigMapName(pipelineName string) string {
+	return fmt.Sprintf("%s-validation-diff", pipelineName)
+}
+
+/*
+ * Stores (a capped number of) ids of hosts that did not match during validation in a ConfigMap owned by the pipeline.
+ * The name of the ConfigMap is recorded in the pipeline status.
+ */
+func (i *ReconcileIteration) exportValidationDiff(result validationResult) error {
+	maxIds := int(i.config.ValidationDiffMaxIds)
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      validationDiffConfigMapName(i.Instance.Name),
+			Namespace: i.Instance.Namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(i.ctx, i.Client, configMap, func() error {
+		configMap.Labels = utils.Merge(configMap.Labels, i.pipelineLabels())
+		configMap.Data = map[string]string{
+			"tableName":     i.Instance.Status.TableName,
+			"validatedAt":   i.Now,
+			"mismatchCount": strconv.FormatInt(result.mismatchCount, 10),
+			"inHbiOnly":     strings.Join(result.inHbiOnly[:utils.Min(maxIds, len(result.inHbiOnly))], "\n"),
+			"inAppOnly":     strings.Join(result.inAppOnly[:utils.Min(maxIds, len(result.inAppOnly))], "\n"),
+		}
+
+		return controllerutil.SetControllerReference(i.Instance, configMap, i.Scheme)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	i.Instance.Status.ValidationDiffConfigMap = configMap.GetName()
+	return nil
+}
+
+// Removes the ConfigMap of an earlier validation once a validation finds no mismatched ids
+func (i *ReconcileIteration) deleteValidationDiff() error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      validationDiffConfigMapName(i.Instance.Name),
+			Namespace: i.Instance.Namespace,
+		},
+	}
+
+	if err := i.Client.Delete(i.ctx, configMap); err != nil && !k8errors.IsNotFound(err) {
+		return err
+	}
+
+	i.Instance.Status.ValidationDiffConfigMap = ""
+	return nil
+}
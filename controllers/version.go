@@ -0,0 +1,4 @@
+package controllers
+
+// Version of the operator, set at build time (-ldflags "-X github.com/RedHatInsights/cyndi-operator/controllers.OperatorVersion=<version>")
+var OperatorVersion = "dev"
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	connect "github.com/RedHatInsights/cyndi-operator/controllers/connect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+/*
+
+Admission webhook rejecting CyndiPipelines that would fail to reconcile due to an invalid configuration
+and updates of fields that cannot be changed once a pipeline is created.
+
+*/
+
+const PipelineValidationPath = "/validate-cyndi-cloud-redhat-com-v1alpha1-cyndipipeline"
+
+// +kubebuilder:webhook:path=/validate-cyndi-cloud-redhat-com-v1alpha1-cyndipipeline,mutating=false,failurePolicy=fail,sideEffects=None,groups=cyndi.cloud.redhat.com,resources=cyndipipelines,verbs=create;update,versions=v1alpha1,name=vcyndipipeline.kb.io,admissionReviewVersions={v1,v1beta1}
+
+type PipelineValidator struct {
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+func NewPipelineValidator(client client.Client) *PipelineValidator {
+	return &PipelineValidator{client: client}
+}
+
+func (v *PipelineValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pipeline := &cyndi.CyndiPipeline{}
+	if err := v.decoder.Decode(req, pipeline); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// do not block removal of the finalizer
+	if pipeline.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	if req.Operation == admissionv1.Update {
+		previous := &cyndi.CyndiPipeline{}
+		if err := v.decoder.DecodeRaw(req.OldObject, previous); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		if changed := pipeline.Spec.ImmutableFieldChanges(previous.Spec); len(changed) > 0 {
+			return admission.Denied(fmt.Sprintf("%s cannot be changed once the pipeline is created, create a new pipeline instead", strings.Join(changed, ", ")))
+		}
+	}
+
+	if err := v.validateConnectorTemplate(pipeline); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	if err := validateDependencies(pipeline); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	if err := validateSourcePipeline(pipeline); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("")
+}
+
+func (v *PipelineValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+func (v *PipelineValidator) validateConnectorTemplate(pipeline *cyndi.CyndiPipeline) error {
+	template := pipeline.Spec.ConnectorTemplate
+	if template == nil {
+		return nil
+	}
+
+	if template.ConfigMapRef == nil {
+		return connect.ValidateTemplate(template.Inline)
+	}
+
+	value, err := loadConnectorTemplate(v.client, pipeline.Namespace, *template.ConfigMapRef)
+	if err != nil {
+		return err
+	}
+
+	return connect.ValidateTemplate(value)
+}
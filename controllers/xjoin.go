@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"math"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+	"github.com/RedHatInsights/cyndi-operator/controllers/utils"
+)
+
+/*
+
+Cross-check of the host count of the application table against the HBI API, whose host listing is served by xjoin (ElasticSearch).
+Both the pipeline and xjoin replicate HBI from its event topic. If xjoin diverges from HBI as well, the problem is likely systemic
+(e.g. events missing from the topic) rather than specific to the pipeline - refreshing the pipeline would not help then.
+
+*/
+
+/*
+ * Compares the host counts of HBI and of the application table to that of the HBI API.
+ * Returns nil if the cross-check is disabled or not applicable (the HBI API being the inventory source or filters the API cannot evaluate).
+ */
+func (i *ReconcileIteration) crossCheckXjoin(hbiHostCount int64, appHostCount int64) (*cyndi.XjoinCrossCheckStatus, error) {
+	if !i.config.ValidationXjoinEnabled || i.config.InventorySource == config.InventorySourceAPI {
+		return nil, nil
+	}
+
+	if len(i.Instance.Spec.AdditionalFilters) > 0 || len(i.config.TopicFilters) > 0 {
+		i.debug("Not cross-checking xjoin as the HBI API does not support filters")
+		return nil, nil
+	}
+
+	api := database.NewAPISource(&i.HBIAPIParams)
+	database.UseCache(api, i.hostCache)
+	api.SetContext(i.ctx)
+	if err := api.Connect(); err != nil {
+		return nil, err
+	}
+
+	defer api.Close()
+
+	xjoinHostCount, err := api.CountHosts(inventoryTableName, i.Instance.Spec.InsightsOnly, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &cyndi.XjoinCrossCheckStatus{
+		HostCount:     xjoinHostCount,
+		AppDivergence: appHostCount - xjoinHostCount,
+		HBIDivergence: hbiHostCount - xjoinHostCount,
+	}
+
+	appMatches := i.matchesHbi(hbiHostCount, appHostCount)
+	xjoinMatches := i.matchesHbi(hbiHostCount, xjoinHostCount)
+
+	switch {
+	case appMatches && xjoinMatches:
+		result.Diagnosis = cyndi.XjoinDiagnosisConsistent
+	case xjoinMatches:
+		result.Diagnosis = cyndi.XjoinDiagnosisPipeline
+	case appMatches:
+		result.Diagnosis = cyndi.XjoinDiagnosisXjoin
+	default:
+		result.Diagnosis = cyndi.XjoinDiagnosisInventory
+	}
+
+	i.Log.Info("Cross-checked xjoin", "hbi", hbiHostCount, "app", appHostCount, "xjoin", xjoinHostCount, "diagnosis", result.Diagnosis)
+	return result, nil
+}
+
+// A replica matches HBI if its host count is within the validation threshold
+func (i *ReconcileIteration) matchesHbi(hbiHostCount int64, replicaHostCount int64) bool {
+	mismatch := utils.Abs(hbiHostCount - replicaHostCount)
+	return i.getValidationConfig().IsWithinThreshold(float64(mismatch)/math.Max(float64(hbiHostCount), 1), mismatch)
+}
+
+func isXjoinDiagnosis(instance *cyndi.CyndiPipeline, diagnosis cyndi.XjoinDiagnosis) bool {
+	return instance.Status.XjoinCrossCheck != nil && instance.Status.XjoinCrossCheck.Diagnosis == diagnosis
+}
@@ -17,9 +17,19 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"expvar"
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/pprof"
 	"os"
+	goruntime "runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"strconv"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -29,10 +39,15 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
 	"github.com/RedHatInsights/cyndi-operator/controllers"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
 	"github.com/RedHatInsights/cyndi-operator/controllers/metrics"
+	"github.com/RedHatInsights/cyndi-operator/controllers/monitoring"
+	"github.com/RedHatInsights/cyndi-operator/controllers/tracing"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -52,15 +67,61 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var logFormat string
+	var createServiceMonitor bool
+	var enableWebhooks bool
+	var readOnly bool
+	var hbiCacheTTL time.Duration
+	var controllersToRun string
+	var logSQL bool
+	var logSQLRedact bool
+	var diagnosticsAddr string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&logFormat, "log-format", "",
+		"Format of the log output (json or console). "+
+			"Defaults to console in DEV_MODE and json otherwise.")
+	flag.BoolVar(&createServiceMonitor, "create-service-monitor", true,
+		"Create a ServiceMonitor for the operator metrics if the Prometheus operator is installed.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the admission webhook validating CyndiPipelines. Requires a serving certificate.")
+	flag.BoolVar(&readOnly, "read-only", false,
+		"Only report the state of pipelines without ever modifying connectors or databases "+
+			"(e.g. for a passive instance in a DR cluster).")
+	flag.DurationVar(&hbiCacheTTL, "hbi-cache-ttl", time.Minute,
+		"How long HBI host counts and ids are shared between pipelines validated against the same HBI database. "+
+			"0 disables the cache.")
+	flag.StringVar(&controllersToRun, "controllers", controllersAll,
+		"Controllers run by this instance of the operator (all, pipeline or validation). "+
+			"Allows validation to be deployed and scaled separately from the pipeline controller.")
+	flag.BoolVar(&logSQL, "log-sql", false,
+		"Log every SQL statement run against the databases, labeled by the pipeline and the purpose of the statement.")
+	flag.BoolVar(&logSQLRedact, "log-sql-redact", true,
+		"Redact credentials and statement parameters in logged SQL statements.")
+	flag.StringVar(&diagnosticsAddr, "diagnostics-addr", "",
+		"The address pprof (/debug/pprof/) and expvar (/debug/vars) endpoints are served on, e.g. 127.0.0.1:6060. "+
+			"Disabled if empty.")
 	flag.Parse()
 
-	devMode := os.Getenv("DEV_MODE") == "true"
-	ctrl.SetLogger(zap.New(zap.UseDevMode(devMode)))
+	logOpts, err := logOptions(logFormat, os.Getenv("DEV_MODE") == "true")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctrl.SetLogger(zap.New(logOpts...))
+
+	database.LogStatements = logSQL
+	database.RedactStatements = logSQLRedact
+
+	runPipeline, runValidation, err := parseControllers(controllersToRun)
+	if err != nil {
+		setupLog.Error(err, "invalid controllers")
+		os.Exit(1)
+	}
 
 	renewDeadline := 60 * time.Second
 	leaseDuration := 90 * time.Second
@@ -70,7 +131,7 @@ func main() {
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "212d6419.cloud.redhat.com",
+		LeaderElectionID:       leaderElectionID(controllersToRun),
 		HealthProbeBindAddress: probeAddr,
 		RenewDeadline:          &renewDeadline,
 		LeaseDuration:          &leaseDuration,
@@ -86,31 +147,88 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = controllers.NewValidationReconciler(
-		mgr.GetClient(),
-		clientset, mgr.GetScheme(),
-		ctrl.Log.WithName("controllers").WithName("validation"),
-		mgr.GetEventRecorderFor("validation"),
-		true,
-	).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Validation")
-		os.Exit(1)
+	if readOnly {
+		setupLog.Info("Running in read-only mode. Connectors and databases will not be modified")
 	}
 
-	if err = controllers.NewCyndiReconciler(
-		mgr.GetClient(),
-		clientset,
-		mgr.GetScheme(),
-		ctrl.Log.WithName("controllers").WithName("cyndi"),
-		mgr.GetEventRecorderFor("cyndi"),
-	).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "CyndiPipeline")
-		os.Exit(1)
+	setupLog.Info("Running controllers", "controllers", controllersToRun)
+
+	if runValidation {
+		validationReconciler := controllers.NewValidationReconciler(
+			mgr.GetClient(),
+			clientset, mgr.GetScheme(),
+			ctrl.Log.WithName("controllers").WithName("validation"),
+			mgr.GetEventRecorderFor("validation"),
+			true,
+		)
+		validationReconciler.ReadOnly = readOnly
+		validationReconciler.HostCache = database.NewHostCache(hbiCacheTTL)
+
+		if err = validationReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Validation")
+			os.Exit(1)
+		}
+	}
+
+	if runPipeline {
+		cyndiReconciler := controllers.NewCyndiReconciler(
+			mgr.GetClient(),
+			clientset,
+			mgr.GetScheme(),
+			ctrl.Log.WithName("controllers").WithName("cyndi"),
+			mgr.GetEventRecorderFor("cyndi"),
+		)
+		cyndiReconciler.ReadOnly = readOnly
+		cyndiReconciler.OperatorNamespace = operatorNamespace()
+
+		if err = cyndiReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "CyndiPipeline")
+			os.Exit(1)
+		}
+	}
+
+	// admission requests are served by the instance running the pipeline controller
+	if enableWebhooks && runPipeline {
+		mgr.GetWebhookServer().Register(controllers.PipelineValidationPath, &webhook.Admission{Handler: controllers.NewPipelineValidator(mgr.GetClient())})
 	}
 	// +kubebuilder:scaffold:builder
 
 	metrics.Init()
 
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
+	if createServiceMonitor {
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			setupServiceMonitor(ctx, mgr, metricsAddr)
+			return nil
+		})); err != nil {
+			setupLog.Error(err, "unable to set up service monitor")
+			os.Exit(1)
+		}
+	}
+
+	// not fatal - pipelines in namespaces with a cyndi ConfigMap of their own may not be affected
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		if err := controllers.ReportEnvironment(mgr.GetClient(), setupLog); err != nil {
+			setupLog.Error(err, "Invalid global configuration")
+		}
+		return nil
+	})); err != nil {
+		setupLog.Error(err, "unable to set up environment check")
+		os.Exit(1)
+	}
+
+	if diagnosticsAddr != "" {
+		if err := mgr.Add(diagnosticsServer{addr: diagnosticsAddr}); err != nil {
+			setupLog.Error(err, "unable to set up diagnostics endpoint")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -121,8 +239,138 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "problem flushing traces")
+	}
+
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+func logOptions(format string, devMode bool) ([]zap.Opts, error) {
+	opts := []zap.Opts{zap.UseDevMode(devMode)}
+
+	switch format {
+	case "":
+	case "json":
+		opts = append(opts, zap.JSONEncoder())
+	case "console":
+		opts = append(opts, zap.ConsoleEncoder())
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", format)
+	}
+
+	return opts, nil
+}
+
+const (
+	controllersAll        = "all"
+	controllersPipeline   = "pipeline"
+	controllersValidation = "validation"
+)
+
+func parseControllers(value string) (runPipeline bool, runValidation bool, err error) {
+	switch value {
+	case controllersAll:
+		return true, true, nil
+	case controllersPipeline:
+		return true, false, nil
+	case controllersValidation:
+		return false, true, nil
+	default:
+		return false, false, fmt.Errorf("%s is not a valid value for --controllers", value)
+	}
+}
+
+// Instances running different controllers (e.g. separate pipeline and validation deployments) elect their leaders independently
+func leaderElectionID(controllers string) string {
+	if controllers == controllersAll {
+		return "212d6419.cloud.redhat.com"
+	}
+
+	return controllers + ".212d6419.cloud.redhat.com"
+}
+
+// Failures are logged only as the operator works fine without the ServiceMonitor
+func setupServiceMonitor(ctx context.Context, mgr manager.Manager, metricsAddr string) {
+	namespace := operatorNamespace()
+	if namespace == "" {
+		setupLog.Info("Operator namespace not known, skipping ServiceMonitor creation")
+		return
+	}
+
+	_, portString, err := net.SplitHostPort(metricsAddr)
+	if err != nil {
+		setupLog.Error(err, "unable to parse metrics address", "address", metricsAddr)
+		return
+	}
+
+	port, err := strconv.ParseInt(portString, 10, 32)
+	if err != nil {
+		setupLog.Error(err, "unable to parse metrics port", "address", metricsAddr)
+		return
+	}
+
+	created, err := monitoring.ApplyServiceMonitor(ctx, mgr.GetClient(), namespace, int32(port))
+	if err != nil {
+		setupLog.Error(err, "unable to create ServiceMonitor", "namespace", namespace)
+	} else if !created {
+		setupLog.Info("Prometheus operator not installed, skipping ServiceMonitor creation")
+	} else {
+		setupLog.Info("ServiceMonitor created", "namespace", namespace)
+	}
+}
+
+// Serves runtime diagnostics (e.g. heap profiles captured during large validations) on a dedicated listener
+type diagnosticsServer struct {
+	addr string
+}
+
+func (s diagnosticsServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return goruntime.NumGoroutine()
+	}))
+
+	server := &http.Server{Addr: s.addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	setupLog.Info("Serving diagnostics", "address", s.addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// Every instance serves diagnostics, not just the leader
+func (s diagnosticsServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Namespace the operator runs in, taken from POD_NAMESPACE or the service account mount
+func operatorNamespace() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+
+	if data, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+
+	return ""
+}
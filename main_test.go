@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Log format", func() {
+	// Logs a single message using the given format and returns the output
+	logLine := func(format string, devMode bool) []byte {
+		opts, err := logOptions(format, devMode)
+		Expect(err).ToNot(HaveOccurred())
+
+		var buffer bytes.Buffer
+		logger := zap.New(append(opts, zap.WriteTo(&buffer))...)
+		logger.WithValues("Pipeline", "advisor").Info("Reconciling CyndiPipeline")
+		return buffer.Bytes()
+	}
+
+	DescribeTable("Selects the encoder",
+		func(format string, devMode bool, expectJSON bool) {
+			var entry map[string]interface{}
+			err := json.Unmarshal(logLine(format, devMode), &entry)
+
+			if expectJSON {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(entry).To(HaveKeyWithValue("msg", "Reconciling CyndiPipeline"))
+				Expect(entry).To(HaveKeyWithValue("Pipeline", "advisor"))
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("default", "", false, true),
+		Entry("default in dev mode", "", true, false),
+		Entry("json", "json", false, true),
+		Entry("json in dev mode", "json", true, true),
+		Entry("console", "console", false, false),
+	)
+
+	It("Rejects unknown formats", func() {
+		_, err := logOptions("xml", false)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Controllers", func() {
+	DescribeTable("Selects the controllers to run",
+		func(value string, expectPipeline bool, expectValidation bool) {
+			runPipeline, runValidation, err := parseControllers(value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runPipeline).To(Equal(expectPipeline))
+			Expect(runValidation).To(Equal(expectValidation))
+		},
+		Entry("all", "all", true, true),
+		Entry("pipeline", "pipeline", true, false),
+		Entry("validation", "validation", false, true),
+	)
+
+	It("Rejects unknown controllers", func() {
+		_, _, err := parseControllers("pipeline,validation")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Elects leaders of separate deployments independently", func() {
+		// the lease of a single deployment running all controllers is unchanged
+		Expect(leaderElectionID("all")).To(Equal("212d6419.cloud.redhat.com"))
+		Expect(leaderElectionID("pipeline")).To(Equal("pipeline.212d6419.cloud.redhat.com"))
+		Expect(leaderElectionID("validation")).To(Equal("validation.212d6419.cloud.redhat.com"))
+	})
+})
+
+var _ = Describe("Diagnostics", func() {
+	It("Serves pprof and expvar until stopped", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		addr := listener.Addr().String()
+		Expect(listener.Close()).To(Succeed())
+
+		server := diagnosticsServer{addr: addr}
+		Expect(server.NeedLeaderElection()).To(BeFalse())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- server.Start(ctx)
+		}()
+
+		get := func(path string) func() (int, error) {
+			return func() (int, error) {
+				response, err := http.Get(fmt.Sprintf("http://%s%s", addr, path))
+				if err != nil {
+					return 0, err
+				}
+				defer response.Body.Close()
+				return response.StatusCode, nil
+			}
+		}
+
+		Eventually(get("/debug/pprof/"), 5*time.Second).Should(Equal(http.StatusOK))
+		Expect(get("/debug/pprof/heap")()).To(Equal(http.StatusOK))
+
+		response, err := http.Get(fmt.Sprintf("http://%s/debug/vars", addr))
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		var vars map[string]interface{}
+		Expect(json.NewDecoder(response.Body).Decode(&vars)).To(Succeed())
+		Expect(vars).To(HaveKey("memstats"))
+		Expect(vars).To(HaveKey("goroutines"))
+
+		cancel()
+		Eventually(done, 5*time.Second).Should(Receive(BeNil()))
+	})
+})
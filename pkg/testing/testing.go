@@ -0,0 +1,145 @@
+/*
+Helpers for tests exercising cyndi pipelines and the databases they manage.
+They are used by the tests of the operator and can be reused by e2e tests of applications consuming the syndicated hosts, e.g.
+
+	namespace, err := testing.UniqueNamespace(k8sClient)
+	err = testing.CreateDbSecret(k8sClient, namespace, "advisor-db", testing.DBParamsFromEnv())
+	err = testing.CreatePipeline(k8sClient, types.NamespacedName{Namespace: namespace, Name: "advisor"})
+*/
+package testing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
+	"github.com/RedHatInsights/cyndi-operator/controllers/config"
+	"github.com/RedHatInsights/cyndi-operator/controllers/database"
+)
+
+// Connection details of the test database. Defaults to postgres:postgres@localhost:5432/test, overridden by the DBHostHBI, DBPort, DBUser, DBPass and DBName environment variables
+func DBParamsFromEnv() config.DBParams {
+	options := viper.New()
+	options.SetDefault("DBHostHBI", "localhost")
+	options.SetDefault("DBPort", "5432")
+	options.SetDefault("DBUser", "postgres")
+	options.SetDefault("DBPass", "postgres")
+	options.SetDefault("DBName", "test")
+	options.AutomaticEnv()
+
+	return config.DBParams{
+		Host:     options.GetString("DBHostHBI"),
+		Port:     options.GetString("DBPort"),
+		Name:     options.GetString("DBName"),
+		User:     options.GetString("DBUser"),
+		Password: options.GetString("DBPass"),
+	}
+}
+
+// Creates a namespace with a unique name
+func UniqueNamespace(c client.Client) (namespace string, err error) {
+	namespace = fmt.Sprintf("test-%d", time.Now().UnixNano())
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	err = c.Create(context.TODO(), ns)
+	return
+}
+
+// Creates a pipeline with the given (optional) spec. The appName of the pipeline is its name
+func CreatePipeline(c client.Client, namespacedName types.NamespacedName, specs ...*cyndi.CyndiPipelineSpec) error {
+	if len(specs) > 1 {
+		return fmt.Errorf("at most one spec expected, got %d", len(specs))
+	}
+
+	spec := &cyndi.CyndiPipelineSpec{}
+	if len(specs) == 1 {
+		spec = specs[0]
+	}
+
+	spec.AppName = namespacedName.Name
+
+	pipeline := cyndi.CyndiPipeline{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespacedName.Name,
+			Namespace: namespacedName.Namespace,
+		},
+		Spec: *spec,
+	}
+
+	return c.Create(context.TODO(), &pipeline)
+}
+
+// Creates a secret in the format of the database secrets of pipelines (dbSecret, inventory.dbSecret)
+func CreateDbSecret(c client.Client, namespace string, name string, params config.DBParams) error {
+	secret := &corev1.Secret{
+		Type: corev1.SecretTypeOpaque,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"db.host":     []byte(params.Host),
+			"db.port":     []byte(params.Port),
+			"db.name":     []byte(params.Name),
+			"db.user":     []byte(params.User),
+			"db.password": []byte(params.Password),
+		},
+	}
+
+	return c.Create(context.TODO(), secret)
+}
+
+// Creates a ConfigMap, e.g. the cyndi ConfigMap of a namespace
+func CreateConfigMap(c client.Client, namespace string, name string, data map[string]string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data: data,
+	}
+
+	return c.Create(context.TODO(), configMap)
+}
+
+// Creates a table only holding host ids
+func CreateApplicationTable(db database.Database, table string) error {
+	_, err := db.Exec(fmt.Sprintf("CREATE TABLE %s (id uuid PRIMARY KEY)", table))
+	return err
+}
+
+// Inserts the given host ids into a table with the id (and canonical_facts if insights is set) column
+func SeedTable(db database.Database, table string, insights bool, ids ...string) error {
+	var template = "INSERT INTO %s (id) VALUES ('%s')"
+
+	if insights {
+		template = `INSERT INTO %s (id, canonical_facts) VALUES ('%s', '{"insights_id": "7597d33e-a1a6-4fda-ad1e-b86b73c722fd"}')`
+	}
+
+	for _, id := range ids {
+		if _, err := db.Exec(fmt.Sprintf(template, table, id)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Inserts hosts with the given ids into a table created by a pipeline (i.e. with all the columns of the inventory.hosts view)
+func SeedAppTable(db database.Database, table string, ids ...string) error {
+	template := `INSERT INTO %s (id, account, org_id, display_name, tags, updated, created, stale_timestamp, system_profile, reporter, per_reporter_staleness) VALUES ('%s', '000001', 'test01', 'test01', '{}', NOW(), NOW(), NOW(), '{}', 'puptoo', '{}')`
+
+	for _, id := range ids {
+		if _, err := db.Exec(fmt.Sprintf(template, table, id)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}